@@ -25,8 +25,25 @@
           ./nix/devShells.nix
           ./nix/treefmt.nix
           ./framework/flake-module.nix
+          ./provider-aws/flake-module.nix
           ./provider-azure/flake-module.nix
+          ./provider-azuresas/flake-module.nix
+          ./provider-elasticsearch/flake-module.nix
+          ./provider-exec/flake-module.nix
+          ./provider-gcp/flake-module.nix
+          ./provider-jwt/flake-module.nix
+          ./provider-kafka/flake-module.nix
           ./provider-mock/flake-module.nix
+          ./provider-oauth2/flake-module.nix
+          ./provider-okta/flake-module.nix
+          ./provider-pagerduty/flake-module.nix
+          ./provider-password/flake-module.nix
+          ./provider-rabbitmq/flake-module.nix
+          ./provider-ssh/flake-module.nix
+          ./provider-tls/flake-module.nix
+          ./provider-twilio/flake-module.nix
+          ./provider-wasm/flake-module.nix
+          ./provider-webhook/flake-module.nix
         ];
 
         config.systems = [
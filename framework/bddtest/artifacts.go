@@ -0,0 +1,222 @@
+package bddtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cucumber/godog"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+)
+
+// ArtifactsDirEnv is the environment variable that sets where failed
+// scenarios dump their artifacts, unless [Env.ArtifactsDir] is set.
+const ArtifactsDirEnv = "TEST_ARTIFACTS_DIR"
+
+// artifactsDir returns the directory failed scenarios dump their artifacts
+// into.
+func (e *Env) artifactsDir() string {
+	if e.ArtifactsDir != "" {
+		return e.ArtifactsDir
+	}
+	if dir := os.Getenv(ArtifactsDirEnv); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "valet-artifacts")
+}
+
+// logBuffer collects the manager's log output of one scenario. It is written
+// by the manager's goroutines while the scenario runs.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
+
+// unsafeFileChars matches characters that are replaced in artifact directory
+// names.
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// dumpArtifacts writes the scenario's resources, output Secret metadata,
+// events and manager logs into a directory named after the scenario, so that
+// failures can be debugged without rerunning them. It returns the directory.
+func (s *Suite[O]) dumpArtifacts(sc *godog.Scenario) (string, error) {
+	name := strings.Trim(unsafeFileChars.ReplaceAllString(sc.Name, "-"), "-")
+	dir := filepath.Join(s.env.artifactsDir(), name+"-"+s.Namespace)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("creating artifacts directory: %w", err)
+	}
+
+	// The scenario context may have expired, which is a common reason for
+	// failures in the first place.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	return dir, errors.Join(
+		s.dumpObjects(ctx, filepath.Join(dir, "resources.yaml")),
+		s.dumpSecrets(ctx, filepath.Join(dir, "secrets.yaml")),
+		s.dumpEvents(ctx, filepath.Join(dir, "events.txt")),
+		s.dumpLogs(ctx, filepath.Join(dir, "manager.log")),
+	)
+}
+
+// dumpObjects writes the provider resources in the scenario's namespace.
+func (s *Suite[O]) dumpObjects(ctx context.Context, path string) error {
+	gvk, err := apiutil.GVKForObject(s.newObject(), s.env.Scheme)
+	if err != nil {
+		return fmt.Errorf("resolving resource kind: %w", err)
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := s.K8sClient.List(ctx, list, client.InNamespace(s.Namespace)); err != nil {
+		return fmt.Errorf("listing %s: %w", gvk.Kind, err)
+	}
+
+	docs := make([]any, len(list.Items))
+	for i := range list.Items {
+		list.Items[i].SetManagedFields(nil)
+		docs[i] = list.Items[i].Object
+	}
+	return writeYAML(path, docs)
+}
+
+// dumpSecrets writes the metadata and data keys of the Secrets in the
+// scenario's namespace. Values are never written.
+func (s *Suite[O]) dumpSecrets(ctx context.Context, path string) error {
+	var list corev1.SecretList
+	if err := s.K8sClient.List(ctx, &list, client.InNamespace(s.Namespace)); err != nil {
+		return fmt.Errorf("listing secrets: %w", err)
+	}
+
+	docs := make([]any, len(list.Items))
+	for i, secret := range list.Items {
+		secret.ManagedFields = nil
+		keys := make([]string, 0, len(secret.Data))
+		for k := range secret.Data {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		docs[i] = struct {
+			Metadata  metav1.ObjectMeta `json:"metadata"`
+			Type      corev1.SecretType `json:"type,omitempty"`
+			Immutable *bool             `json:"immutable,omitempty"`
+			Keys      []string          `json:"keys"`
+		}{secret.ObjectMeta, secret.Type, secret.Immutable, keys}
+	}
+	return writeYAML(path, docs)
+}
+
+// dumpEvents writes the events in the scenario's namespace, oldest first.
+func (s *Suite[O]) dumpEvents(ctx context.Context, path string) error {
+	var list corev1.EventList
+	if err := s.K8sClient.List(ctx, &list, client.InNamespace(s.Namespace)); err != nil {
+		return fmt.Errorf("listing events: %w", err)
+	}
+
+	eventTime := func(e *corev1.Event) time.Time {
+		switch {
+		case !e.LastTimestamp.IsZero():
+			return e.LastTimestamp.Time
+		case !e.EventTime.IsZero():
+			return e.EventTime.Time
+		default:
+			return e.CreationTimestamp.Time
+		}
+	}
+	slices.SortStableFunc(list.Items, func(a, b corev1.Event) int {
+		return eventTime(&a).Compare(eventTime(&b))
+	})
+
+	var buf bytes.Buffer
+	for i := range list.Items {
+		e := &list.Items[i]
+		fmt.Fprintf(&buf, "%s %s %s %s/%s: %s\n",
+			eventTime(e).Format(time.RFC3339), e.Type, e.Reason,
+			e.InvolvedObject.Kind, e.InvolvedObject.Name, e.Message)
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// dumpLogs writes the manager's log output since the scenario started: the
+// captured output of the in-process manager, or the logs of the operator
+// pods when running against an operator image.
+func (s *Suite[O]) dumpLogs(ctx context.Context, path string) error {
+	if s.env.Operator == nil {
+		if s.logs == nil {
+			return nil
+		}
+		return os.WriteFile(path, s.logs.Bytes(), 0o644)
+	}
+
+	var deploy appsv1.Deployment
+	if err := s.K8sClient.Get(ctx, *s.env.Operator, &deploy); err != nil {
+		return fmt.Errorf("getting operator deployment: %w", err)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deploy.Spec.Selector)
+	if err != nil {
+		return fmt.Errorf("parsing operator selector: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfig(s.env.Cfg)
+	if err != nil {
+		return fmt.Errorf("creating clientset: %w", err)
+	}
+	pods, err := clientset.CoreV1().Pods(s.env.Operator.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("listing operator pods: %w", err)
+	}
+
+	var buf bytes.Buffer
+	for _, pod := range pods.Items {
+		logs, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+			SinceTime: &metav1.Time{Time: s.started},
+		}).DoRaw(ctx)
+		if err != nil {
+			return fmt.Errorf("getting logs of pod %s: %w", pod.Name, err)
+		}
+		fmt.Fprintf(&buf, "==> %s <==\n", pod.Name)
+		buf.Write(logs)
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// writeYAML writes docs as a multi-document YAML file.
+func writeYAML(path string, docs []any) error {
+	var buf bytes.Buffer
+	for _, doc := range docs {
+		data, err := yaml.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("marshalling %s: %w", filepath.Base(path), err)
+		}
+		buf.WriteString("---\n")
+		buf.Write(data)
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,133 @@
+package bddtest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// ImageOptions configures [DeployImage].
+type ImageOptions struct {
+	// Image is the operator image reference, e.g.
+	// "ghcr.io/lukasngl/valet/provider-mock:dev". It must be available to the
+	// local container runtime; it is loaded into the cluster, not pulled.
+	Image string
+	// Chart is the path to the provider's Helm chart.
+	Chart string
+	// Cluster is the name of the kind cluster. Defaults to "kind".
+	Cluster string
+	// Namespace the operator is installed into. Defaults to "valet-system".
+	Namespace string
+	// Set holds additional Helm values in --set syntax, e.g. provider
+	// endpoints or credential secret names.
+	Set []string
+	// Timeout bounds the rollout of the operator. Defaults to 3 minutes.
+	Timeout time.Duration
+}
+
+// DeployImage installs the operator from its container image into a kind
+// cluster using the provider's Helm chart, and points env at that cluster.
+// Scenarios then run against the deployed operator instead of an in-process
+// manager, which exercises the shipped CRDs, RBAC, probes and flags.
+//
+// The returned function uninstalls the release. The CRDs are left in place,
+// as Helm never deletes them.
+func DeployImage(ctx context.Context, env *Env, opts ImageOptions) (func(), error) {
+	if opts.Cluster == "" {
+		opts.Cluster = "kind"
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = "valet-system"
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 3 * time.Minute
+	}
+
+	repository, tag, ok := splitImage(opts.Image)
+	if !ok {
+		return nil, fmt.Errorf("image %q has no tag", opts.Image)
+	}
+
+	kubeconfig, err := run(ctx, "kind", "get", "kubeconfig", "--name", opts.Cluster)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("parsing kubeconfig of kind cluster %s: %w", opts.Cluster, err)
+	}
+
+	if _, err := run(ctx, "kind", "load", "docker-image", opts.Image, "--name", opts.Cluster); err != nil {
+		return nil, err
+	}
+
+	// The chart depends on the valet library chart, see framework/chart.
+	if _, err := run(ctx, "helm", "dependency", "build", opts.Chart); err != nil {
+		return nil, err
+	}
+
+	release := filepath.Base(filepath.Clean(opts.Chart))
+	kubeContext := "kind-" + opts.Cluster
+	args := []string{
+		"upgrade", "--install", release, opts.Chart,
+		"--kube-context", kubeContext,
+		"--namespace", opts.Namespace,
+		"--create-namespace",
+		"--wait",
+		"--timeout", opts.Timeout.String(),
+		"--set", "fullnameOverride=" + release,
+		"--set", "image.repository=" + repository,
+		"--set", "image.tag=" + tag,
+		"--set", "image.pullPolicy=Never",
+	}
+	for _, v := range opts.Set {
+		args = append(args, "--set", v)
+	}
+	if _, err := run(ctx, "helm", args...); err != nil {
+		return nil, err
+	}
+
+	if err := appsv1.AddToScheme(env.Scheme); err != nil {
+		return nil, fmt.Errorf("registering apps/v1: %w", err)
+	}
+	env.Cfg = cfg
+	env.Operator = &types.NamespacedName{Namespace: opts.Namespace, Name: release}
+
+	return func() {
+		_, _ = run(context.Background(), "helm", "uninstall", release,
+			"--kube-context", kubeContext, "--namespace", opts.Namespace)
+	}, nil
+}
+
+// splitImage splits an image reference into repository and tag. The tag
+// separator is the last colon after the last slash, so registry ports are
+// kept in the repository.
+func splitImage(image string) (repository, tag string, ok bool) {
+	i := strings.LastIndex(image, ":")
+	if i < 0 || i < strings.LastIndex(image, "/") {
+		return "", "", false
+	}
+	return image[:i], image[i+1:], true
+}
+
+// run executes a command and returns its standard output. Standard error is
+// included in the returned error.
+func run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("running %s %s: %w: %s",
+			name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
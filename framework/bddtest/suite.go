@@ -4,6 +4,9 @@
 // steps (create/update/delete CRD, phase and secret assertions, credential
 // expiry), and controller-manager wiring. Provider-specific test packages
 // embed the suite and register their own steps via godogen on top.
+//
+// The same features can run against the provider's container image deployed
+// into a kind cluster, see [DeployImage].
 package bddtest
 
 //go:generate godogen
@@ -11,6 +14,7 @@ package bddtest
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
@@ -18,15 +22,18 @@ import (
 	"github.com/cucumber/godog"
 	"github.com/google/uuid"
 	"github.com/lukasngl/valet/framework"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 )
 
@@ -38,6 +45,16 @@ type Object = framework.Object
 type Env struct {
 	Cfg    *rest.Config
 	Scheme *runtime.Scheme
+
+	// Operator, if set, names the Deployment of an operator installed from
+	// its container image (see [DeployImage]). Scenarios then run against
+	// that operator instead of starting an in-process manager.
+	Operator *types.NamespacedName
+
+	// ArtifactsDir is where failed scenarios dump the resources, output
+	// Secret metadata, events and manager logs of their namespace. Defaults
+	// to $TEST_ARTIFACTS_DIR, or valet-artifacts in the temp directory.
+	ArtifactsDir string
 }
 
 // Suite holds per-scenario state. Create a fresh instance for each scenario
@@ -53,6 +70,9 @@ type Suite[O Object] struct {
 	env       *Env
 	newObject func() O
 	lastErr   error
+	started   time.Time
+	logs      *logBuffer
+	consumers map[string]*consumer
 }
 
 // New creates a Suite for one scenario. The provider and newObject factory
@@ -74,6 +94,7 @@ func New[O Object](
 //godogen:before
 func (s *Suite[O]) before(ctx context.Context, _ *godog.Scenario) (context.Context, error) {
 	s.Ctx, s.Cancel = context.WithTimeout(context.Background(), 2*time.Minute)
+	s.started = time.Now()
 	s.Namespace = fmt.Sprintf("test-%s", uuid.New().String()[:8])
 
 	k8sClient, err := client.New(s.env.Cfg, client.Options{Scheme: s.env.Scheme})
@@ -91,7 +112,16 @@ func (s *Suite[O]) before(ctx context.Context, _ *godog.Scenario) (context.Conte
 }
 
 //godogen:after
-func (s *Suite[O]) after(ctx context.Context, _ *godog.Scenario, _ error) (context.Context, error) {
+func (s *Suite[O]) after(ctx context.Context, sc *godog.Scenario, err error) (context.Context, error) {
+	if err != nil && s.K8sClient != nil && s.Namespace != "" {
+		dir, dumpErr := s.dumpArtifacts(sc)
+		if dumpErr != nil {
+			fmt.Fprintf(os.Stderr, "dumping artifacts of %q: %v\n", sc.Name, dumpErr)
+		}
+		if dir != "" {
+			fmt.Fprintf(os.Stderr, "artifacts of failed scenario %q: %s\n", sc.Name, dir)
+		}
+	}
 	if s.MgrCancel != nil {
 		s.MgrCancel()
 	}
@@ -121,13 +151,21 @@ func (s *Suite[O]) aKubernetesClusterIsRunning(_ context.Context) error {
 
 //godogen:given ^the CRDs are installed$
 func (s *Suite[O]) theCRDsAreInstalled(_ context.Context) error {
-	return nil // CRDs are installed by envtest.Environment via CRDDirectoryPaths.
+	// CRDs are installed by envtest.Environment via CRDDirectoryPaths, or by
+	// the Helm chart when running against an operator image.
+	return nil
 }
 
 //godogen:given ^the operator is running$
 func (s *Suite[O]) theOperatorIsRunning(_ context.Context) error {
+	if s.env.Operator != nil {
+		return s.operatorDeploymentIsAvailable()
+	}
+
+	s.logs = &logBuffer{}
 	mgr, err := ctrl.NewManager(s.env.Cfg, ctrl.Options{
 		Scheme:  s.env.Scheme,
+		Logger:  zap.New(zap.UseDevMode(true), zap.WriteTo(io.MultiWriter(os.Stderr, s.logs))),
 		Metrics: metricsserver.Options{BindAddress: "0"},
 		Cache: cache.Options{
 			DefaultNamespaces: map[string]cache.Config{
@@ -158,6 +196,21 @@ func (s *Suite[O]) theOperatorIsRunning(_ context.Context) error {
 	return nil
 }
 
+// operatorDeploymentIsAvailable checks that the operator deployed from its
+// image is available, i.e. its readiness probe passes.
+func (s *Suite[O]) operatorDeploymentIsAvailable() error {
+	var deploy appsv1.Deployment
+	if err := s.K8sClient.Get(s.Ctx, *s.env.Operator, &deploy); err != nil {
+		return fmt.Errorf("getting operator deployment %s: %w", s.env.Operator, err)
+	}
+	for _, cond := range deploy.Status.Conditions {
+		if cond.Type == appsv1.DeploymentAvailable && cond.Status == corev1.ConditionTrue {
+			return nil
+		}
+	}
+	return fmt.Errorf("operator deployment %s is not available", s.env.Operator)
+}
+
 // --- When steps ---
 
 // expandDoc expands environment variables in a godog DocString.
@@ -517,3 +570,244 @@ func (s *Suite[O]) theSecretShouldNotExist(_ context.Context, name string) error
 	}
 	return client.IgnoreNotFound(err)
 }
+
+// --- Consumer steps ---
+
+// DefaultConsumerImage is the image of consumer Deployments, unless
+// $VALET_CONSUMER_IMAGE names another one.
+const DefaultConsumerImage = "busybox:1.37"
+
+// consumerLabel labels the pods of a consumer Deployment with its name.
+const consumerLabel = "valet.ngl.cx/consumer"
+
+// consumer is a Deployment consuming an output Secret, see
+// [Suite.aConsumerDeploymentUsingTheSecret].
+type consumer struct {
+	secret string
+	// pods are the pods running when the consumer became available.
+	pods map[types.UID]bool
+}
+
+//godogen:given ^a consumer Deployment "([^"]*)" using the Secret "([^"]*)"$
+func (s *Suite[O]) aConsumerDeploymentUsingTheSecret(_ context.Context, name, secretName string) error {
+	// Pods only run on real clusters; envtest has no kubelet.
+	if s.env.Operator == nil {
+		return godog.ErrSkip
+	}
+
+	var checksum string
+	if err := Eventually(60*time.Second, func() error {
+		var err error
+		checksum, err = s.secretChecksum(secretName)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	image := os.Getenv("VALET_CONSUMER_IMAGE")
+	if image == "" {
+		image = DefaultConsumerImage
+	}
+	replicas := int32(1)
+	labels := map[string]string{consumerLabel: name}
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: s.Namespace},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      labels,
+					Annotations: map[string]string{framework.AnnotationDataChecksum: checksum},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:    "consumer",
+						Image:   image,
+						Command: []string{"sh", "-c", "while true; do sleep 3600; done"},
+						EnvFrom: []corev1.EnvFromSource{{
+							SecretRef: &corev1.SecretEnvSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+							},
+						}},
+						VolumeMounts: []corev1.VolumeMount{{Name: "secret", MountPath: "/etc/valet", ReadOnly: true}},
+					}},
+					Volumes: []corev1.Volume{{
+						Name: "secret",
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{SecretName: secretName},
+						},
+					}},
+				},
+			},
+		},
+	}
+	if err := s.K8sClient.Create(s.Ctx, deploy); err != nil {
+		return fmt.Errorf("creating consumer Deployment %q: %w", name, err)
+	}
+
+	var pods []corev1.Pod
+	if err := Eventually(90*time.Second, func() error {
+		var err error
+		pods, err = s.consumerRolledOut(name)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	c := &consumer{secret: secretName, pods: map[types.UID]bool{}}
+	for _, pod := range pods {
+		c.pods[pod.UID] = true
+	}
+	if s.consumers == nil {
+		s.consumers = map[string]*consumer{}
+	}
+	s.consumers[name] = c
+	return nil
+}
+
+//godogen:when ^I roll out the consumers of the Secret "([^"]*)"$
+func (s *Suite[O]) iRollOutTheConsumersOfTheSecret(_ context.Context, secretName string) error {
+	if s.env.Operator == nil {
+		return godog.ErrSkip
+	}
+
+	// Record the current checksum in the pod templates, as checksum
+	// annotations in Helm charts or reloaders do, which rolls out the
+	// consumers whose Secret changed.
+	checksum, err := s.secretChecksum(secretName)
+	if err != nil {
+		return err
+	}
+	for name, c := range s.consumers {
+		if c.secret != secretName {
+			continue
+		}
+		var deploy appsv1.Deployment
+		if err := s.K8sClient.Get(s.Ctx, client.ObjectKey{
+			Namespace: s.Namespace, Name: name,
+		}, &deploy); err != nil {
+			return err
+		}
+		if deploy.Spec.Template.Annotations[framework.AnnotationDataChecksum] == checksum {
+			continue
+		}
+		patch := client.MergeFrom(deploy.DeepCopy())
+		deploy.Spec.Template.Annotations[framework.AnnotationDataChecksum] = checksum
+		if err := s.K8sClient.Patch(s.Ctx, &deploy, patch); err != nil {
+			return fmt.Errorf("rolling out consumer Deployment %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+//godogen:then ^the consumer Deployment "([^"]*)" should be restarted within (\d+) seconds$
+func (s *Suite[O]) theConsumerDeploymentShouldBeRestartedWithin(
+	_ context.Context,
+	name string,
+	seconds int,
+) error {
+	if s.env.Operator == nil {
+		return godog.ErrSkip
+	}
+	c, ok := s.consumers[name]
+	if !ok {
+		return fmt.Errorf("no consumer Deployment %q", name)
+	}
+
+	return Eventually(time.Duration(seconds)*time.Second, func() error {
+		pods, err := s.consumerRolledOut(name)
+		if err != nil {
+			return err
+		}
+		for _, pod := range pods {
+			if c.pods[pod.UID] {
+				return fmt.Errorf("consumer Deployment %q still runs pod %q", name, pod.Name)
+			}
+		}
+		return nil
+	})
+}
+
+//godogen:then ^the consumer Deployment "([^"]*)" should use the current Secret data within (\d+) seconds$
+func (s *Suite[O]) theConsumerDeploymentShouldUseTheCurrentSecretDataWithin(
+	_ context.Context,
+	name string,
+	seconds int,
+) error {
+	if s.env.Operator == nil {
+		return godog.ErrSkip
+	}
+	c, ok := s.consumers[name]
+	if !ok {
+		return fmt.Errorf("no consumer Deployment %q", name)
+	}
+
+	// Environment variables are only projected when a container starts, so
+	// pods use the current data if they were created for its checksum.
+	return Eventually(time.Duration(seconds)*time.Second, func() error {
+		checksum, err := s.secretChecksum(c.secret)
+		if err != nil {
+			return err
+		}
+		pods, err := s.consumerRolledOut(name)
+		if err != nil {
+			return err
+		}
+		for _, pod := range pods {
+			if got := pod.Annotations[framework.AnnotationDataChecksum]; got != checksum {
+				return fmt.Errorf("consumer pod %q uses Secret data %q, expected %q", pod.Name, got, checksum)
+			}
+		}
+		return nil
+	})
+}
+
+// secretChecksum returns the data checksum the operator recorded on the
+// Secret name.
+func (s *Suite[O]) secretChecksum(name string) (string, error) {
+	var secret corev1.Secret
+	if err := s.K8sClient.Get(s.Ctx, client.ObjectKey{
+		Namespace: s.Namespace, Name: name,
+	}, &secret); err != nil {
+		return "", err
+	}
+	checksum := secret.Annotations[framework.AnnotationDataChecksum]
+	if checksum == "" {
+		return "", fmt.Errorf("secret %q has no %s annotation", name, framework.AnnotationDataChecksum)
+	}
+	return checksum, nil
+}
+
+// consumerRolledOut returns the pods of the consumer Deployment name if its
+// rollout is complete, i.e. all its replicas are updated and ready and no
+// old pods remain.
+func (s *Suite[O]) consumerRolledOut(name string) ([]corev1.Pod, error) {
+	var deploy appsv1.Deployment
+	if err := s.K8sClient.Get(s.Ctx, client.ObjectKey{
+		Namespace: s.Namespace, Name: name,
+	}, &deploy); err != nil {
+		return nil, err
+	}
+	status := deploy.Status
+	if status.ObservedGeneration < deploy.Generation || status.UpdatedReplicas != *deploy.Spec.Replicas ||
+		status.ReadyReplicas != *deploy.Spec.Replicas || status.Replicas != *deploy.Spec.Replicas {
+		return nil, fmt.Errorf("consumer Deployment %q is rolling out: %d of %d replicas updated and %d ready",
+			name, status.UpdatedReplicas, *deploy.Spec.Replicas, status.ReadyReplicas)
+	}
+
+	var pods corev1.PodList
+	if err := s.K8sClient.List(s.Ctx, &pods,
+		client.InNamespace(s.Namespace), client.MatchingLabels{consumerLabel: name},
+	); err != nil {
+		return nil, err
+	}
+	var running []corev1.Pod
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil {
+			return nil, fmt.Errorf("consumer pod %q is terminating", pod.Name)
+		}
+		running = append(running, pod)
+	}
+	return running, nil
+}
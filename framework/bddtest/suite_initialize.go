@@ -35,4 +35,8 @@ func InitializeSuite[T1 Object](sc *godog.ScenarioContext, r1 *Suite[T1]) {
 	sc.Then(`^the Secret "([^"]*)" should be owned by ClientSecret "([^"]*)"$`, r1.theSecretShouldBeOwnedByClientSecret)
 	sc.Then(`^the operation should have failed with "([^"]*)"$`, r1.theOperationShouldHaveFailedWith)
 	sc.Then(`^the Secret "([^"]*)" should not exist$`, r1.theSecretShouldNotExist)
+	sc.Given(`^a consumer Deployment "([^"]*)" using the Secret "([^"]*)"$`, r1.aConsumerDeploymentUsingTheSecret)
+	sc.When(`^I roll out the consumers of the Secret "([^"]*)"$`, r1.iRollOutTheConsumersOfTheSecret)
+	sc.Then(`^the consumer Deployment "([^"]*)" should be restarted within (\d+) seconds$`, r1.theConsumerDeploymentShouldBeRestartedWithin)
+	sc.Then(`^the consumer Deployment "([^"]*)" should use the current Secret data within (\d+) seconds$`, r1.theConsumerDeploymentShouldUseTheCurrentSecretDataWithin)
 }
@@ -0,0 +1,124 @@
+package framework
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// BuildInfo describes the operator binary, so that the provider versions
+// deployed across a fleet can be inventoried. Operators log it at startup,
+// serve it on the metrics server's /version endpoint and export it as the
+// valet_build_info metric.
+type BuildInfo struct {
+	// Provider is the name of the operator, e.g. provider-azure.
+	Provider string `json:"provider"`
+
+	// Version is the release the binary was built from.
+	Version string `json:"version"`
+
+	// Revision is the VCS revision the binary was built from, if known.
+	Revision string `json:"revision,omitempty"`
+
+	// GoVersion is the Go toolchain the binary was built with.
+	GoVersion string `json:"goVersion"`
+
+	// Platform is the OS and architecture the binary was built for, e.g.
+	// linux/arm64.
+	Platform string `json:"platform"`
+}
+
+// NewBuildInfo returns the [BuildInfo] of the running binary. provider,
+// version and revision are set at build time, e.g. with
+// -ldflags "-X main.version=...". Unset values, including the "dev" default
+// version, are taken from the build information Go embeds instead: the
+// provider from the main package's path, the version from the main module
+// and the revision from the VCS, so that binaries built with go build or
+// go install still identify themselves.
+func NewBuildInfo(provider, version, revision string) BuildInfo {
+	info := BuildInfo{
+		Provider:  provider,
+		Version:   version,
+		Revision:  revision,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info.withDefaults()
+	}
+
+	if info.Provider == "" && bi.Path != "" {
+		// Providers are built from their module's cmd package.
+		dir := bi.Path
+		if path.Base(dir) == "cmd" {
+			dir = path.Dir(dir)
+		}
+		info.Provider = path.Base(dir)
+	}
+	if (info.Version == "" || info.Version == "dev") &&
+		bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		info.Version = bi.Main.Version
+	}
+	if info.Revision == "" {
+		for _, s := range bi.Settings {
+			if s.Key == "vcs.revision" {
+				info.Revision = s.Value
+			}
+		}
+	}
+	return info.withDefaults()
+}
+
+// withDefaults fills in the values that could not be determined.
+func (b BuildInfo) withDefaults() BuildInfo {
+	if b.Provider == "" {
+		b.Provider = "unknown"
+	}
+	if b.Version == "" {
+		b.Version = "dev"
+	}
+	return b
+}
+
+// KeysAndValues returns the build info as key-value pairs for structured
+// logging.
+func (b BuildInfo) KeysAndValues() []any {
+	kv := []any{"provider", b.Provider, "version", b.Version}
+	if b.Revision != "" {
+		kv = append(kv, "revision", b.Revision)
+	}
+	return append(kv, "goVersion", b.GoVersion, "platform", b.Platform)
+}
+
+// Handler returns an HTTP handler serving the build info as JSON.
+func (b BuildInfo) Handler() http.Handler {
+	body, _ := json.Marshal(b)
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	})
+}
+
+// Register registers the valet_build_info metric with reg. Like Prometheus'
+// own *_build_info metrics, it is always 1 and carries the build info as
+// labels.
+func (b BuildInfo) Register(reg prometheus.Registerer) error {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "valet_build_info",
+		Help: "Build information of the operator. Always 1.",
+		ConstLabels: prometheus.Labels{
+			"provider":  b.Provider,
+			"version":   b.Version,
+			"revision":  b.Revision,
+			"goversion": b.GoVersion,
+			"platform":  b.Platform,
+		},
+	})
+	gauge.Set(1)
+	return reg.Register(gauge)
+}
@@ -0,0 +1,89 @@
+package framework_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNewBuildInfo(t *testing.T) {
+	t.Run("injected values", func(t *testing.T) {
+		info := framework.NewBuildInfo("provider-azure", "1.2.3", "abc123")
+		if info.Provider != "provider-azure" || info.Version != "1.2.3" || info.Revision != "abc123" {
+			t.Fatalf("injected values not kept: %+v", info)
+		}
+		if info.GoVersion != runtime.Version() {
+			t.Fatalf("got Go version %q, want %q", info.GoVersion, runtime.Version())
+		}
+		if want := runtime.GOOS + "/" + runtime.GOARCH; info.Platform != want {
+			t.Fatalf("got platform %q, want %q", info.Platform, want)
+		}
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		info := framework.NewBuildInfo("", "", "")
+		// Test binaries are named after the package under test.
+		if info.Provider != "framework.test" {
+			t.Fatalf("got provider %q", info.Provider)
+		}
+		if info.Version == "" {
+			t.Fatal("expected a version")
+		}
+	})
+}
+
+func TestBuildInfo_KeysAndValues(t *testing.T) {
+	kv := framework.NewBuildInfo("provider-mock", "1.2.3", "").KeysAndValues()
+	if len(kv)%2 != 0 {
+		t.Fatalf("odd number of keys and values: %v", kv)
+	}
+	for i := 0; i < len(kv); i += 2 {
+		if kv[i] == "revision" {
+			t.Fatal("expected no revision if it is unknown")
+		}
+	}
+	if kv[0] != "provider" || kv[1] != "provider-mock" || kv[2] != "version" || kv[3] != "1.2.3" {
+		t.Fatalf("unexpected keys and values: %v", kv)
+	}
+}
+
+func TestBuildInfo_Handler(t *testing.T) {
+	info := framework.NewBuildInfo("provider-mock", "1.2.3", "abc123")
+	rec := httptest.NewRecorder()
+	info.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/version", nil))
+
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("got content type %q", got)
+	}
+	var got framework.BuildInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if got != info {
+		t.Fatalf("got %+v, want %+v", got, info)
+	}
+}
+
+func TestBuildInfo_Register(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	info := framework.NewBuildInfo("provider-mock", "1.2.3", "abc123")
+	if err := info.Register(reg); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `
+# HELP valet_build_info Build information of the operator. Always 1.
+# TYPE valet_build_info gauge
+valet_build_info{goversion="` + info.GoVersion + `",platform="` + info.Platform +
+		`",provider="provider-mock",revision="abc123",version="1.2.3"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "valet_build_info"); err != nil {
+		t.Fatal(err)
+	}
+}
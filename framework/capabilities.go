@@ -0,0 +1,74 @@
+package framework
+
+import (
+	"fmt"
+	"time"
+)
+
+// Capabilities describes what a provider supports, so that the reconciler
+// can adjust its behavior and reject specs the provider cannot satisfy. See
+// [CapabilityReporter].
+type Capabilities struct {
+	// SupportsDeleteKey reports whether [Provider.DeleteKey] removes keys at
+	// the provider. If not, e.g. because credentials are only generated
+	// locally, expired keys and the keys of deleted resources are dropped
+	// without calling it.
+	SupportsDeleteKey bool `json:"supportsDeleteKey"`
+
+	// SupportsListKeys reports whether the provider can list the keys it
+	// holds for a resource, e.g. to find keys the status lost track of.
+	SupportsListKeys bool `json:"supportsListKeys"`
+
+	// SupportsVerify reports whether the provider can check that a
+	// credential is accepted, e.g. after writing it to the output secret.
+	SupportsVerify bool `json:"supportsVerify"`
+
+	// MaxValidity is the longest validity the provider issues credentials
+	// for. Resources requesting a longer one (see [ValidityRequester]) fail
+	// validation. Zero means no limit. It is encoded in nanoseconds.
+	MaxValidity time.Duration `json:"maxValidity,omitempty"`
+}
+
+// DefaultCapabilities are the capabilities of providers that do not
+// implement [CapabilityReporter]: they delete keys and support nothing else.
+var DefaultCapabilities = Capabilities{SupportsDeleteKey: true}
+
+// CapabilityReporter is optionally implemented by providers whose
+// capabilities differ from [DefaultCapabilities].
+type CapabilityReporter interface {
+	// Capabilities returns the capabilities of the provider. It must not
+	// change while the provider is in use.
+	Capabilities() Capabilities
+}
+
+// ValidityRequester is optionally implemented by objects that request a
+// validity for their credentials, so that it can be checked against
+// [Capabilities.MaxValidity] before anything is provisioned.
+type ValidityRequester interface {
+	// GetValidity returns the requested validity, or zero for the
+	// provider's default.
+	GetValidity() time.Duration
+}
+
+// Validate checks obj against the capabilities, e.g. that it requests no
+// longer validity than [Capabilities.MaxValidity].
+func (c Capabilities) Validate(obj Object) error {
+	v, ok := obj.(ValidityRequester)
+	if !ok || c.MaxValidity <= 0 {
+		return nil
+	}
+	if validity := v.GetValidity(); validity > c.MaxValidity {
+		return fmt.Errorf("validity %s exceeds the provider's maximum of %s", validity, c.MaxValidity)
+	}
+	return nil
+}
+
+// ProviderCapabilities returns the capabilities of p, looking through
+// wrappers such as [InstrumentedProvider], or [DefaultCapabilities] if it
+// does not report any.
+func ProviderCapabilities[O Object](p Provider[O]) Capabilities {
+	if c, ok := findProvider[O, CapabilityReporter](p); ok {
+		return c.Capabilities()
+	}
+	return DefaultCapabilities
+}
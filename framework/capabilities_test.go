@@ -0,0 +1,102 @@
+package framework_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/prometheus/client_golang/prometheus"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// capabilityProvider is a [framework.CapabilityReporter] counting the
+// DeleteKey calls.
+type capabilityProvider struct {
+	stubProvider
+
+	caps    framework.Capabilities
+	deletes int
+}
+
+func (p *capabilityProvider) Capabilities() framework.Capabilities { return p.caps }
+
+func (p *capabilityProvider) DeleteKey(context.Context, *testObject, string) error {
+	p.deletes++
+	return nil
+}
+
+// validityObject is a [testObject] requesting a validity.
+type validityObject struct {
+	*testObject
+
+	validity time.Duration
+}
+
+func (o validityObject) GetValidity() time.Duration { return o.validity }
+
+func TestProviderCapabilities(t *testing.T) {
+	if got := framework.ProviderCapabilities[*testObject](&stubProvider{}); got != framework.DefaultCapabilities {
+		t.Errorf("expected the default capabilities, got %+v", got)
+	}
+
+	caps := framework.Capabilities{SupportsVerify: true, MaxValidity: time.Hour}
+	p := framework.Instrument[*testObject](&capabilityProvider{caps: caps}, prometheus.NewRegistry())
+	if got := framework.ProviderCapabilities[*testObject](p); got != caps {
+		t.Errorf("expected the capabilities of the wrapped provider, got %+v", got)
+	}
+
+	nested := framework.Instrument[*testObject](&framework.ChaosProvider[*testObject]{
+		Provider: &capabilityProvider{caps: caps},
+	}, prometheus.NewRegistry())
+	if got := framework.ProviderCapabilities[*testObject](nested); got != caps {
+		t.Errorf("expected the capabilities of the provider wrapped twice, got %+v", got)
+	}
+}
+
+func TestCapabilities_Validate(t *testing.T) {
+	caps := framework.Capabilities{MaxValidity: 24 * time.Hour}
+
+	if err := caps.Validate(validityObject{newTestObject(), 24 * time.Hour}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := caps.Validate(validityObject{newTestObject(), 0}); err != nil {
+		t.Errorf("expected the provider's default to be valid, got %v", err)
+	}
+	err := caps.Validate(validityObject{newTestObject(), 48 * time.Hour})
+	if err == nil || !strings.Contains(err.Error(), "exceeds the provider's maximum of 24h0m0s") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := (framework.Capabilities{}).Validate(validityObject{newTestObject(), 48 * time.Hour}); err != nil {
+		t.Errorf("expected no limit without MaxValidity, got %v", err)
+	}
+}
+
+func TestReconcile_WithoutDeleteKey(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := objWithKey(-time.Minute)
+	obj.Finalizers = []string{framework.Finalizer}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	p := &capabilityProvider{}
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if p.deletes != 0 {
+		t.Errorf("expected DeleteKey not to be called, got %d calls", p.deletes)
+	}
+	var got testObject
+	if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range got.Status.ActiveKeys {
+		if key.KeyID == "old" {
+			t.Error("expected the expired key to be dropped")
+		}
+	}
+}
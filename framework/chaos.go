@@ -0,0 +1,146 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ErrChaos is the transient failure that chaos mode injects into provider
+// calls, see [Chaos].
+var ErrChaos = errors.New("chaos: injected transient failure")
+
+// ChaosConfig configures the faults [Chaos] injects into provider calls.
+type ChaosConfig struct {
+	// FailureProbability is the probability in [0, 1] that a provider call
+	// fails with [ErrChaos] instead of reaching the provider.
+	FailureProbability float64
+
+	// MaxDelay is the upper bound of the random delay before each provider
+	// call.
+	MaxDelay time.Duration
+}
+
+// Enabled reports whether c injects any faults.
+func (c ChaosConfig) Enabled() bool {
+	return c.FailureProbability > 0 || c.MaxDelay > 0
+}
+
+// ParseChaosConfig parses the value of the --chaos flag: a comma-separated
+// list of failure=<probability> and delay=<max delay>, e.g.
+// "failure=0.2,delay=30s". An empty value disables chaos mode. Chaos mode is
+// only available in builds with the "chaos" build tag, see [ChaosAvailable],
+// so that production images cannot inject faults; other builds reject any
+// other value.
+func ParseChaosConfig(s string) (ChaosConfig, error) {
+	var c ChaosConfig
+	if s == "" {
+		return c, nil
+	}
+	if !ChaosAvailable {
+		return c, errors.New("chaos mode is not available in this build, rebuild with -tags chaos")
+	}
+
+	for field := range strings.SplitSeq(s, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return c, fmt.Errorf("chaos setting %q is not of the form key=value", field)
+		}
+		switch key {
+		case "failure":
+			p, err := strconv.ParseFloat(value, 64)
+			if err != nil || p < 0 || p > 1 {
+				return c, fmt.Errorf("chaos failure probability %q is not between 0 and 1", value)
+			}
+			c.FailureProbability = p
+		case "delay":
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return c, fmt.Errorf("chaos delay %q is not a non-negative duration", value)
+			}
+			c.MaxDelay = d
+		default:
+			return c, fmt.Errorf("unknown chaos setting %q, want failure or delay", key)
+		}
+	}
+	return c, nil
+}
+
+// ChaosProvider wraps a [Provider] to delay its calls randomly and fail
+// some of them with [ErrChaos], so that alerts and runbooks for failing
+// rotations can be rehearsed on staging clusters. Create via [Chaos].
+type ChaosProvider[O Object] struct {
+	Provider[O]
+
+	// Config configures the injected faults.
+	Config ChaosConfig
+
+	// Rand returns pseudo-random numbers in [0, 1). It defaults to
+	// [rand.Float64].
+	Rand func() float64
+}
+
+// Chaos wraps p to inject the faults configured by c into its calls. It
+// returns p itself if c injects none. Wrap the result with [Instrument] so
+// that the injected failures show up in the metrics like real ones.
+func Chaos[O Object](p Provider[O], c ChaosConfig) Provider[O] {
+	if !c.Enabled() {
+		return p
+	}
+	return &ChaosProvider[O]{Provider: p, Config: c, Rand: rand.Float64}
+}
+
+// Provision delegates to the inner provider after the injected delay,
+// unless it injects a failure.
+func (p *ChaosProvider[O]) Provision(ctx context.Context, obj O) (*Result, error) {
+	if err := p.inject(ctx, "provision"); err != nil {
+		return nil, err
+	}
+	return p.Provider.Provision(ctx, obj)
+}
+
+// DeleteKey delegates to the inner provider after the injected delay,
+// unless it injects a failure.
+func (p *ChaosProvider[O]) DeleteKey(ctx context.Context, obj O, keyID string) error {
+	if err := p.inject(ctx, "deleteKey"); err != nil {
+		return err
+	}
+	return p.Provider.DeleteKey(ctx, obj, keyID)
+}
+
+// Unwrap returns the inner provider, so that the reconciler can detect
+// optional interfaces such as [UsageReporter] it implements.
+func (p *ChaosProvider[O]) Unwrap() Provider[O] {
+	return p.Provider
+}
+
+// inject waits for a random delay and returns [ErrChaos] if the call is to
+// fail.
+func (p *ChaosProvider[O]) inject(ctx context.Context, operation string) error {
+	random := p.Rand
+	if random == nil {
+		random = rand.Float64
+	}
+
+	if p.Config.MaxDelay > 0 {
+		delay := time.Duration(random() * float64(p.Config.MaxDelay))
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if random() < p.Config.FailureProbability {
+		log.FromContext(ctx).Info("chaos: injecting failure", "operation", operation)
+		return ErrChaos
+	}
+	return nil
+}
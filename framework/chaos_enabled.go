@@ -0,0 +1,7 @@
+//go:build chaos
+
+package framework
+
+// ChaosAvailable reports whether the binary was built with the "chaos" build
+// tag, which enables [ParseChaosConfig].
+const ChaosAvailable = true
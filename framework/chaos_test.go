@@ -0,0 +1,98 @@
+package framework_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+)
+
+func TestParseChaosConfig(t *testing.T) {
+	if c, err := framework.ParseChaosConfig(""); err != nil || c.Enabled() {
+		t.Fatalf("expected an empty value to disable chaos mode, got %+v, %v", c, err)
+	}
+
+	if !framework.ChaosAvailable {
+		_, err := framework.ParseChaosConfig("failure=0.5")
+		if err == nil || !strings.Contains(err.Error(), "-tags chaos") {
+			t.Fatalf("expected chaos mode to be rejected without the chaos tag, got %v", err)
+		}
+		return
+	}
+
+	c, err := framework.ParseChaosConfig("failure=0.2, delay=30s")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.FailureProbability != 0.2 || c.MaxDelay != 30*time.Second {
+		t.Fatalf("got %+v", c)
+	}
+
+	for _, s := range []string{"failure", "failure=2", "delay=-1s", "delay=soon", "latency=1s"} {
+		if _, err := framework.ParseChaosConfig(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
+
+func TestChaos(t *testing.T) {
+	inner := &stubProvider{}
+	ctx := context.Background()
+
+	t.Run("disabled", func(t *testing.T) {
+		if p := framework.Chaos[*testObject](inner, framework.ChaosConfig{}); p != framework.Provider[*testObject](inner) {
+			t.Fatal("expected the provider itself if no faults are configured")
+		}
+	})
+
+	t.Run("injects failures", func(t *testing.T) {
+		p := &framework.ChaosProvider[*testObject]{
+			Provider: inner,
+			Config:   framework.ChaosConfig{FailureProbability: 0.5},
+			Rand:     func() float64 { return 0.4 },
+		}
+		if _, err := p.Provision(ctx, newTestObject()); !errors.Is(err, framework.ErrChaos) {
+			t.Fatalf("expected injected failure, got %v", err)
+		}
+		if err := p.DeleteKey(ctx, newTestObject(), "old"); !errors.Is(err, framework.ErrChaos) {
+			t.Fatalf("expected injected failure, got %v", err)
+		}
+
+		p.Rand = func() float64 { return 0.6 }
+		if _, err := p.Provision(ctx, newTestObject()); err != nil {
+			t.Fatalf("expected the call to reach the provider, got %v", err)
+		}
+	})
+
+	t.Run("delays calls", func(t *testing.T) {
+		p := &framework.ChaosProvider[*testObject]{
+			Provider: inner,
+			Config:   framework.ChaosConfig{MaxDelay: 100 * time.Millisecond},
+			Rand:     func() float64 { return 0.5 },
+		}
+		start := time.Now()
+		if _, err := p.Provision(ctx, newTestObject()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Fatalf("expected a delay of 50ms, took %v", elapsed)
+		}
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		p.Config.MaxDelay = time.Hour
+		if _, err := p.Provision(canceled, newTestObject()); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected the delay to end with the context, got %v", err)
+		}
+	})
+
+	t.Run("unwrap", func(t *testing.T) {
+		p := &framework.ChaosProvider[*testObject]{Provider: inner}
+		if p.Unwrap() != framework.Provider[*testObject](inner) {
+			t.Fatal("expected Unwrap to return the inner provider")
+		}
+	})
+}
@@ -0,0 +1,131 @@
+{{/*
+Arguments of the flags shared by all providers, see framework.Options. The
+templates are included with the context of the provider chart, whose
+<name>.fullname template names the ConfigMaps.
+*/}}
+{{- define "valet.args" -}}
+- --metrics-bind-address=:{{ .Values.metrics.port }}
+{{- if .Values.metrics.secure }}
+- --metrics-secure
+{{- if .Values.metrics.debugEndpoints }}
+- --debug-endpoints
+{{- end }}
+{{- end }}
+- --health-probe-bind-address=:{{ .Values.healthProbe.port }}
+- --log-level-file=/etc/valet/log-level/level
+- --policy-dir=/etc/valet/policies
+{{- if .Values.leaderElection.enabled }}
+- --leader-elect
+{{- end }}
+{{- with .Values.forceDeleteAfter }}
+- --force-delete-after={{ . }}
+{{- end }}
+{{- if .Values.prioritizeNamespaceDeletion }}
+- --prioritize-namespace-deletion
+{{- end }}
+{{- if .Values.restartWorkloads }}
+- --restart-workloads
+{{- end }}
+{{- if .Values.splitOversizedSecrets }}
+- --split-oversized-secrets
+{{- end }}
+{{- with .Values.keyStore }}
+- --key-store={{ . }}
+{{- end }}
+{{- if .Values.allowSimulateExpiry }}
+- --allow-simulate-expiry
+{{- end }}
+{{- with .Values.propagateLabels }}
+- --propagate-labels={{ join "," . }}
+{{- end }}
+{{- with .Values.clockSkew }}
+- --clock-skew={{ . }}
+{{- end }}
+{{- with .Values.pendingTimeout }}
+- --pending-timeout={{ . }}
+{{- end }}
+{{- with .Values.stuckDeletionTimeout }}
+- --stuck-deletion-timeout={{ . }}
+{{- end }}
+{{- with .Values.slowReconcileThreshold }}
+- --slow-reconcile-threshold={{ . }}
+{{- end }}
+{{- with .Values.maintenanceWindows }}
+- --maintenance-windows={{ . }}
+{{- end }}
+{{- with .Values.pushSecretStore }}
+- --push-secret-store={{ . }}
+{{- end }}
+{{- with .Values.chaos }}
+- --chaos={{ . }}
+{{- end }}
+{{- with .Values.otlpEndpoint }}
+- --otlp-endpoint={{ . }}
+{{- end }}
+{{- if .Values.envelopeEncryption.existingSecret }}
+- --envelope-key-file=/etc/valet/envelope/{{ .Values.envelopeEncryption.existingSecretKey }}
+{{- end }}
+{{- end }}
+
+{{/*
+Mounts of the volumes read by the flags in valet.args.
+*/}}
+{{- define "valet.volumeMounts" -}}
+- name: log-level
+  mountPath: /etc/valet/log-level
+  readOnly: true
+- name: policies
+  mountPath: /etc/valet/policies
+  readOnly: true
+{{- if .Values.envelopeEncryption.existingSecret }}
+- name: envelope-key
+  mountPath: /etc/valet/envelope
+  readOnly: true
+{{- end }}
+{{- end }}
+
+{{/*
+Volumes read by the flags in valet.args.
+*/}}
+{{- define "valet.volumes" -}}
+- name: log-level
+  configMap:
+    name: {{ include (printf "%s.fullname" .Chart.Name) . }}-log-level
+- name: policies
+  configMap:
+    name: {{ include (printf "%s.fullname" .Chart.Name) . }}-policies
+{{- if .Values.envelopeEncryption.existingSecret }}
+- name: envelope-key
+  secret:
+    secretName: {{ .Values.envelopeEncryption.existingSecret }}
+{{- end }}
+{{- end }}
+
+{{/*
+RBAC rules needed by the optional features enabled in the values.
+*/}}
+{{- define "valet.rules" -}}
+{{- if eq .Values.keyStore "configmap" }}
+- apiGroups:
+  - ""
+  resources:
+  - configmaps
+  verbs:
+  - create
+  - delete
+  - get
+  - update
+{{- end }}
+{{- if .Values.restartWorkloads }}
+- apiGroups:
+  - apps
+  resources:
+  - deployments
+  - statefulsets
+  verbs:
+  - get
+  - list
+  - patch
+  - watch
+{{- end }}
+{{- end }}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/lukasngl/valet/framework/lint"
+)
+
+// preview is the rendered content of a single Secret key after rotation.
+type preview struct {
+	// Value is the template output with every variable replaced by a
+	// placeholder of the form <Name>.
+	Value string
+	// Static reports whether the template references no variables, i.e. the
+	// value does not change on rotation.
+	Static bool
+}
+
+// renderPreview evaluates every template against placeholder values. The
+// placeholders are derived from the fields referenced in the templates, so
+// any provider's template variables are supported without knowing them up
+// front.
+func renderPreview(templates map[string]string) (map[string]preview, error) {
+	out := make(map[string]preview, len(templates))
+	for key, tmpl := range templates {
+		t, err := template.New(key).Option("missingkey=zero").Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("parsing template %q: %w", key, err)
+		}
+
+		data := map[string]string{}
+		for _, field := range lint.Fields(t.Root) {
+			data[field] = "<" + field + ">"
+		}
+
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("rendering template %q: %w", key, err)
+		}
+		out[key] = preview{Value: buf.String(), Static: len(data) == 0}
+	}
+	return out, nil
+}
+
+// writeDiff prints the difference between the current Secret keys and the
+// rendered preview. Current values are never printed, since they contain live
+// credentials; only key names are compared for keys whose value depends on
+// the provisioned credential.
+func writeDiff(w io.Writer, name string, current map[string][]byte, next map[string]preview) {
+	fmt.Fprintf(w, "--- %s (current)\n", name)
+	fmt.Fprintf(w, "+++ %s (after rotation)\n", name)
+
+	keys := make([]string, 0, len(current)+len(next))
+	for k := range current {
+		keys = append(keys, k)
+	}
+	for k := range next {
+		if _, ok := current[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		old, hadOld := current[k]
+		p, hasNew := next[k]
+		switch {
+		case !hasNew:
+			fmt.Fprintf(w, "- %s\n", k)
+		case !hadOld:
+			writeLine(w, "+", k, p.Value)
+		case p.Static && string(old) == p.Value:
+			writeLine(w, " ", k, p.Value)
+		default:
+			writeLine(w, "~", k, p.Value)
+		}
+	}
+}
+
+func writeLine(w io.Writer, marker, key, value string) {
+	lines := strings.Split(value, "\n")
+	fmt.Fprintf(w, "%s %s: %s\n", marker, key, lines[0])
+	for _, l := range lines[1:] {
+		fmt.Fprintf(w, "%s %s  %s\n", marker, strings.Repeat(" ", len(key)), l)
+	}
+}
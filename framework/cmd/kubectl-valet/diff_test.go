@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRenderPreview(t *testing.T) {
+	got, err := renderPreview(map[string]string{
+		"ID":     "{{ .ClientID }}",
+		"URL":    "https://{{ .Host }}/?secret={{ .ClientSecret }}",
+		"TENANT": "static-tenant",
+		"COND":   "{{ if .Flag }}{{ .Inner }}{{ end }}",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got["ID"].Value != "<ClientID>" || got["ID"].Static {
+		t.Errorf("ID: got %+v", got["ID"])
+	}
+	if got["URL"].Value != "https://<Host>/?secret=<ClientSecret>" {
+		t.Errorf("URL: got %q", got["URL"].Value)
+	}
+	if got["TENANT"].Value != "static-tenant" || !got["TENANT"].Static {
+		t.Errorf("TENANT: got %+v", got["TENANT"])
+	}
+	if got["COND"].Value != "<Inner>" {
+		t.Errorf("COND: got %q", got["COND"].Value)
+	}
+}
+
+func TestRenderPreview_InvalidTemplate(t *testing.T) {
+	if _, err := renderPreview(map[string]string{"BAD": "{{ .Foo"}); err == nil {
+		t.Error("expected error for invalid template")
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	current := map[string][]byte{
+		"SECRET":  []byte("live-credential"),
+		"TENANT":  []byte("tenant"),
+		"REMOVED": []byte("x"),
+	}
+	next := map[string]preview{
+		"SECRET": {Value: "<ClientSecret>"},
+		"TENANT": {Value: "tenant", Static: true},
+		"ADDED":  {Value: "a\nb", Static: true},
+	}
+
+	var buf bytes.Buffer
+	writeDiff(&buf, "Secret default/app", current, next)
+
+	want := `--- Secret default/app (current)
++++ Secret default/app (after rotation)
++ ADDED: a
++        b
+- REMOVED
+~ SECRET: <ClientSecret>
+  TENANT: tenant
+`
+	if buf.String() != want {
+		t.Errorf("unexpected diff:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("live-credential")) {
+		t.Error("diff must not print current secret values")
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/lukasngl/valet/framework/lint"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+)
+
+// exportedMetadata are the metadata fields kept in an export. The others,
+// such as uid and resourceVersion, are assigned by the cluster and would
+// prevent applying the export to another one.
+var exportedMetadata = []string{"name", "namespace", "labels", "annotations"}
+
+// runExport writes all valet resources, with their spec and status, to a
+// snapshot that can be applied to rebuild the credential inventory on another
+// cluster. Resources never contain credentials, and output Secrets are not
+// exported.
+func runExport(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	kubeconfig := fs.String("kubeconfig", "", "Path to the kubeconfig file.")
+	namespace := fs.String("n", "", "Namespace to export (default: all namespaces).")
+	format := fs.String("format", "yaml", "Output format, yaml or json.")
+	output := fs.String(
+		"o",
+		"-",
+		"File to write the snapshot to, \"-\" for stdout, or an http(s) URL to upload it to with PUT, "+
+			"e.g. a presigned object store URL.",
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errors.New("usage: kubectl valet export [-n namespace] [--format yaml|json] [-o file|url|-]")
+	}
+	if *format != "yaml" && *format != "json" {
+		return fmt.Errorf("unknown format %q, want yaml or json", *format)
+	}
+
+	// Falls back to the in-cluster config, e.g. when run as a CronJob.
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{
+			ExplicitPath: *kubeconfig,
+			Precedence:   clientcmd.NewDefaultClientConfigLoadingRules().Precedence,
+		},
+		&clientcmd.ConfigOverrides{},
+	)
+	cfg, err := loader.ClientConfig()
+	if err != nil {
+		return fmt.Errorf("loading kubeconfig: %w", err)
+	}
+	disco, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("creating discovery client: %w", err)
+	}
+	dyn, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("creating dynamic client: %w", err)
+	}
+
+	ctx := context.Background()
+	items, err := listResources(ctx, disco, dyn, *namespace)
+	if err != nil {
+		return err
+	}
+	data, err := encodeSnapshot(items, *format)
+	if err != nil {
+		return err
+	}
+	if err := writeSnapshot(ctx, *output, *format, data, out); err != nil {
+		return err
+	}
+
+	if *output != "-" {
+		_, err = fmt.Fprintf(out, "exported %d resources to %s\n", len(items), redactURL(*output))
+	}
+	return err
+}
+
+// listResources lists the resources of all valet kinds served by the cluster
+// in namespace, or in all namespaces if it is empty.
+func listResources(
+	ctx context.Context,
+	disco discovery.DiscoveryInterface,
+	dyn dynamic.Interface,
+	namespace string,
+) ([]unstructured.Unstructured, error) {
+	lists, err := disco.ServerPreferredNamespacedResources()
+	if err != nil && len(lists) == 0 {
+		return nil, fmt.Errorf("discovering resources: %w", err)
+	}
+
+	var items []unstructured.Unstructured
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil || !strings.HasSuffix(gv.Group, lint.GroupSuffix) {
+			continue
+		}
+		for _, res := range list.APIResources {
+			if strings.Contains(res.Name, "/") {
+				continue
+			}
+			l, err := dyn.Resource(gv.WithResource(res.Name)).
+				Namespace(namespace).
+				List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("listing %s: %w", res.Kind, err)
+			}
+			items = append(items, l.Items...)
+		}
+	}
+
+	return items, nil
+}
+
+// encodeSnapshot encodes items as a v1 List in format, sorted by API version,
+// kind, namespace and name so that snapshots can be diffed. Only the metadata in
+// [exportedMetadata] is kept, without the last applied configuration kubectl
+// records.
+func encodeSnapshot(items []unstructured.Unstructured, format string) ([]byte, error) {
+	exported := make([]any, 0, len(items))
+	for _, item := range slices.SortedFunc(slices.Values(items), compareResources) {
+		obj := item.DeepCopy().Object
+		original, _ := obj["metadata"].(map[string]any)
+		metadata := map[string]any{}
+		for _, field := range exportedMetadata {
+			if v, ok := original[field]; ok {
+				metadata[field] = v
+			}
+		}
+		if annotations, ok := metadata["annotations"].(map[string]any); ok {
+			delete(annotations, "kubectl.kubernetes.io/last-applied-configuration")
+			if len(annotations) == 0 {
+				delete(metadata, "annotations")
+			}
+		}
+		obj["metadata"] = metadata
+		exported = append(exported, obj)
+	}
+
+	list := map[string]any{"apiVersion": "v1", "kind": "List", "items": exported}
+	if format == "json" {
+		return json.MarshalIndent(list, "", "  ")
+	}
+	return yaml.Marshal(list)
+}
+
+// compareResources orders resources by API version, kind, namespace and name.
+func compareResources(a, b unstructured.Unstructured) int {
+	for _, pair := range [][2]string{
+		{a.GetAPIVersion(), b.GetAPIVersion()},
+		{a.GetKind(), b.GetKind()},
+		{a.GetNamespace(), b.GetNamespace()},
+		{a.GetName(), b.GetName()},
+	} {
+		if c := strings.Compare(pair[0], pair[1]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// writeSnapshot writes data to output: stdout for "-", an upload with PUT for
+// http(s) URLs, and a file otherwise. The x-ms-blob-type header lets
+// presigned Azure Blob Storage URLs be used as well as S3 and GCS ones.
+func writeSnapshot(ctx context.Context, output, format string, data []byte, stdout io.Writer) error {
+	if output == "-" {
+		_, err := stdout.Write(data)
+		return err
+	}
+	if !strings.HasPrefix(output, "https://") && !strings.HasPrefix(output, "http://") {
+		if err := os.WriteFile(output, data, 0o600); err != nil {
+			return fmt.Errorf("writing snapshot: %w", err)
+		}
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, output, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("creating upload request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/"+format)
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	resp, err := http.DefaultClient.Do(req)
+	if uerr := (*url.Error)(nil); errors.As(err, &uerr) {
+		// The URL may carry a signature in its query.
+		return fmt.Errorf("uploading snapshot to %s: %w", redactURL(output), uerr.Err)
+	} else if err != nil {
+		return fmt.Errorf("uploading snapshot: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("uploading snapshot: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+// redactURL returns output without the query if it is a URL, since the
+// query of a presigned URL grants access to the object.
+func redactURL(output string) string {
+	u, err := url.Parse(output)
+	if err != nil || u.Scheme == "" || u.RawQuery == "" {
+		return output
+	}
+	u.RawQuery = ""
+	return u.String() + "?<redacted>"
+}
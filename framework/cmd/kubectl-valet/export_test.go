@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func TestEncodeSnapshot(t *testing.T) {
+	resource := func(name string) unstructured.Unstructured {
+		return unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "valet.ngl.cx/v1alpha1",
+			"kind":       "AzureClientSecret",
+			"metadata": map[string]any{
+				"name":            name,
+				"namespace":       "default",
+				"uid":             "uid-" + name,
+				"resourceVersion": "42",
+				"annotations": map[string]any{
+					"kubectl.kubernetes.io/last-applied-configuration": "{}",
+				},
+			},
+			"spec":   map[string]any{"objectId": "obj-" + name},
+			"status": map[string]any{"currentKeyId": "key-" + name},
+		}}
+	}
+	items := []unstructured.Unstructured{resource("b"), resource("a")}
+
+	data, err := encodeSnapshot(items, "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list struct {
+		Kind  string           `json:"kind"`
+		Items []map[string]any `json:"items"`
+	}
+	if err := yaml.Unmarshal(data, &list); err != nil {
+		t.Fatalf("decoding snapshot: %v\n%s", err, data)
+	}
+	if list.Kind != "List" || len(list.Items) != 2 {
+		t.Fatalf("expected a List of 2 items, got:\n%s", data)
+	}
+	first := unstructured.Unstructured{Object: list.Items[0]}
+	if first.GetName() != "a" || first.GetUID() != "" || first.GetResourceVersion() != "" ||
+		first.GetAnnotations() != nil {
+		t.Errorf("expected the first item to be a without cluster metadata, got %+v", first.Object["metadata"])
+	}
+	if id, _, _ := unstructured.NestedString(first.Object, "status", "currentKeyId"); id != "key-a" {
+		t.Errorf("expected the status to be exported, got %q", id)
+	}
+	if items[0].GetUID() != "uid-b" || items[0].GetAnnotations() == nil {
+		t.Error("expected the listed resources to be left unchanged")
+	}
+}
+
+func TestWriteSnapshot_Upload(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Query().Get("sig") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		got = string(body)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	if err := writeSnapshot(ctx, srv.URL+"/inventory.yaml?sig=secret", "yaml", []byte("items: []"), nil); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if got != "items: []" {
+		t.Errorf("got uploaded body %q", got)
+	}
+
+	err := writeSnapshot(ctx, srv.URL+"/inventory.yaml?sig=wrong", "yaml", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected the rejected upload to fail, got %v", err)
+	}
+}
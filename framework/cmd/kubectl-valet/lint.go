@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lukasngl/valet/framework/lint"
+)
+
+// runLint checks valet resources in manifest files, directories or stdin
+// ("-"). CRDs are read from the --crds paths and from the manifests
+// themselves. It fails if any problem is found, so it can gate CI pipelines.
+func runLint(args []string, stdin io.Reader, out io.Writer) error {
+	fset := flag.NewFlagSet("lint", flag.ContinueOnError)
+	var crds []string
+	fset.Func("crds", "File or directory with valet CRDs (repeatable).", func(s string) error {
+		crds = append(crds, s)
+		return nil
+	})
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if fset.NArg() == 0 {
+		return errors.New("usage: kubectl valet lint [--crds path]... <file|dir|->...")
+	}
+
+	var docs []lint.Document
+	for _, path := range append(crds, fset.Args()...) {
+		d, err := readManifests(path, stdin)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, d...)
+	}
+
+	findings, err := lint.New().Lint(docs)
+	if err != nil {
+		return err
+	}
+	for _, f := range findings {
+		fmt.Fprintln(out, f)
+	}
+	if len(findings) > 0 {
+		return fmt.Errorf("found %d problem(s)", len(findings))
+	}
+	return nil
+}
+
+// readManifests decodes the manifests at path: stdin for "-", every .yaml,
+// .yml and .json file below a directory, or a single file.
+func readManifests(path string, stdin io.Reader) ([]lint.Document, error) {
+	if path == "-" {
+		return lint.Decode("<stdin>", stdin)
+	}
+
+	var docs []lint.Document
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		// Files named explicitly are read whatever their extension.
+		if p != path {
+			switch strings.ToLower(filepath.Ext(p)) {
+			case ".yaml", ".yml", ".json":
+			default:
+				return nil
+			}
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		fileDocs, err := lint.Decode(p, f)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, fileDocs...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading manifests: %w", err)
+	}
+	return docs, nil
+}
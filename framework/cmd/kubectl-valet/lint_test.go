@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunLint(t *testing.T) {
+	crds, err := filepath.Glob("../../../provider-azure/config/crd/*.yaml")
+	if err != nil || len(crds) == 0 {
+		t.Skip("provider-azure CRD not found")
+	}
+
+	dir := t.TempDir()
+	manifest := `apiVersion: valet.ngl.cx/v1alpha1
+kind: AzureClientSecret
+metadata:
+  name: app
+  namespace: default
+spec:
+  objectId: 00000000-0000-0000-0000-000000000000
+  secretRef:
+    name: app
+  template:
+    SECRET: "{{ .ClientSecret }}"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unrelated
+`
+	if err := os.WriteFile(filepath.Join(dir, "app.yaml"), []byte(manifest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a manifest"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := run([]string{"lint", "--crds", crds[0], dir}, nil, &out); err != nil {
+		t.Fatalf("expected a clean manifest to pass, got %v:\n%s", err, out.String())
+	}
+
+	broken := strings.Replace(manifest, ".ClientSecret", ".Secret", 1)
+	out.Reset()
+	err = run([]string{"lint", "--crds", crds[0], "-"}, strings.NewReader(broken), &out)
+	if err == nil || !strings.Contains(err.Error(), "found 1 problem(s)") {
+		t.Fatalf("expected one problem, got %v", err)
+	}
+	if want := "<stdin>:1: AzureClientSecret default/app: spec.template[SECRET]: unknown variable .Secret"; !strings.Contains(out.String(), want) {
+		t.Fatalf("expected output to contain %q, got:\n%s", want, out.String())
+	}
+}
@@ -0,0 +1,231 @@
+// kubectl-valet is a kubectl plugin for inspecting valet resources.
+//
+// Usage:
+//
+//	kubectl valet diff [-n namespace] [--kind kind] <name>
+//	kubectl valet lint [--crds path]... <file|dir|->...
+//	kubectl valet export [-n namespace] [--format yaml|json] [-o file|url|-]
+//
+// The diff command renders what the output Secret of a valet resource would
+// contain after the next rotation, evaluating spec.template against
+// placeholder values. The provider is never contacted.
+//
+// The lint command checks valet resources in manifests against their CRDs
+// before they are applied, see package [lint]. It needs no cluster.
+//
+// The export command writes all valet resources, with their spec and status
+// but without cluster-assigned metadata, to a snapshot that can be applied to
+// rebuild the credential inventory on another cluster.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/lukasngl/valet/framework/lint"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var version = "dev"
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: kubectl valet <diff|lint|export|version> ...")
+	}
+
+	switch args[0] {
+	case "diff":
+		return runDiff(args[1:], out)
+	case "lint":
+		return runLint(args[1:], stdin, out)
+	case "export":
+		return runExport(args[1:], out)
+	case "version":
+		_, err := fmt.Fprintln(out, version)
+		return err
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func runDiff(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	kubeconfig := fs.String("kubeconfig", "", "Path to the kubeconfig file.")
+	namespace := fs.String(
+		"n",
+		"",
+		"Namespace of the resource (defaults to the current context).",
+	)
+	kind := fs.String(
+		"kind",
+		"",
+		"Kind, resource or short name to look up (default: all valet kinds).",
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: kubectl valet diff [-n namespace] [--kind kind] <name>")
+	}
+	name := fs.Arg(0)
+
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{
+			ExplicitPath: *kubeconfig,
+			Precedence:   clientcmd.NewDefaultClientConfigLoadingRules().Precedence,
+		},
+		&clientcmd.ConfigOverrides{},
+	)
+	cfg, err := loader.ClientConfig()
+	if err != nil {
+		return fmt.Errorf("loading kubeconfig: %w", err)
+	}
+	ns := *namespace
+	if ns == "" {
+		if ns, _, err = loader.Namespace(); err != nil {
+			return fmt.Errorf("resolving namespace: %w", err)
+		}
+	}
+
+	ctx := context.Background()
+	obj, err := findResource(ctx, cfg, ns, name, *kind)
+	if err != nil {
+		return err
+	}
+
+	templates, _, err := unstructured.NestedStringMap(obj.Object, "spec", "template")
+	if err != nil {
+		return fmt.Errorf("reading spec.template: %w", err)
+	}
+	if len(templates) == 0 {
+		return fmt.Errorf("%s %s has no spec.template to render", obj.GetKind(), name)
+	}
+	secretName, _, err := unstructured.NestedString(obj.Object, "spec", "secretRef", "name")
+	if err != nil {
+		return fmt.Errorf("reading spec.secretRef.name: %w", err)
+	}
+	if secretName == "" {
+		// The output Secret defaults to the resource's name.
+		secretName = obj.GetName()
+	}
+
+	next, err := renderPreview(templates)
+	if err != nil {
+		return err
+	}
+
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("creating client: %w", err)
+	}
+	var current map[string][]byte
+	secret, err := clientset.CoreV1().Secrets(ns).Get(ctx, secretName, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		current = secret.Data
+	case !apierrors.IsNotFound(err):
+		return fmt.Errorf("getting secret %s: %w", secretName, err)
+	}
+
+	writeDiff(out, fmt.Sprintf("Secret %s/%s", ns, secretName), current, next)
+	return nil
+}
+
+// findResource looks up name among the valet resource kinds served by the
+// cluster. If kind is set, only matching resources are searched; otherwise
+// the name must be unique across all valet kinds.
+func findResource(
+	ctx context.Context,
+	cfg *rest.Config,
+	ns, name, kind string,
+) (*unstructured.Unstructured, error) {
+	disco, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating discovery client: %w", err)
+	}
+	dyn, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating dynamic client: %w", err)
+	}
+
+	lists, err := disco.ServerPreferredNamespacedResources()
+	if err != nil && len(lists) == 0 {
+		return nil, fmt.Errorf("discovering resources: %w", err)
+	}
+
+	var found []*unstructured.Unstructured
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil || !strings.HasSuffix(gv.Group, lint.GroupSuffix) {
+			continue
+		}
+		for _, res := range list.APIResources {
+			if strings.Contains(res.Name, "/") || !matchesKind(res, kind) {
+				continue
+			}
+			obj, err := dyn.Resource(gv.WithResource(res.Name)).
+				Namespace(ns).
+				Get(ctx, name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("getting %s %s: %w", res.Kind, name, err)
+			}
+			found = append(found, obj)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no valet resource named %q in namespace %q", name, ns)
+	case 1:
+		return found[0], nil
+	default:
+		kinds := make([]string, len(found))
+		for i, obj := range found {
+			kinds[i] = obj.GetKind()
+		}
+		return nil, fmt.Errorf(
+			"%q matches several kinds (%s), use --kind to choose",
+			name, strings.Join(kinds, ", "),
+		)
+	}
+}
+
+// matchesKind reports whether res is selected by kind, compared
+// case-insensitively against the kind, plural, singular and short names.
+// An empty kind matches every resource.
+func matchesKind(res metav1.APIResource, kind string) bool {
+	if kind == "" {
+		return true
+	}
+	names := append([]string{res.Kind, res.Name, res.SingularName}, res.ShortNames...)
+	for _, n := range names {
+		if strings.EqualFold(n, kind) {
+			return true
+		}
+	}
+	return false
+}
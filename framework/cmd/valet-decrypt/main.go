@@ -0,0 +1,112 @@
+// valet-decrypt decrypts output secrets written with envelope encryption,
+// see package [envelope]. It is meant to run as an initContainer:
+//
+//	valet-decrypt --key-file /kek/key --in /encrypted --out /decrypted
+//
+// It reads the secret mounted at --in, decrypts its values with the key
+// encryption keys in the --key-file files and writes them to --out, usually
+// an in-memory emptyDir shared with the application container. Values that
+// are not encrypted are copied as they are.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lukasngl/valet/framework/envelope"
+)
+
+var version = "dev"
+
+// stringsFlag collects the values of a repeatable flag.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringsFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("valet-decrypt", flag.ContinueOnError)
+	var keyFiles stringsFlag
+	fs.Var(&keyFiles, "key-file", "File holding a key encryption key, raw or base64. Repeatable.")
+	in := fs.String("in", "", "Directory the encrypted secret is mounted at.")
+	out := fs.String("out", "", "Directory to write the decrypted values to.")
+	showVersion := fs.Bool("version", false, "Print the version and exit.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *showVersion {
+		fmt.Println(version)
+		return nil
+	}
+	if len(keyFiles) == 0 || *in == "" || *out == "" {
+		return errors.New("usage: valet-decrypt --key-file file... --in dir --out dir")
+	}
+
+	kw, err := envelope.LoadAESKeyWrapper(keyFiles...)
+	if err != nil {
+		return err
+	}
+	data, err := readSecretDir(*in)
+	if err != nil {
+		return err
+	}
+	opened, err := envelope.Open(context.Background(), kw, data)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*out, 0o700); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+	for key, value := range opened {
+		if err := os.WriteFile(filepath.Join(*out, key), value, 0o600); err != nil {
+			return fmt.Errorf("writing %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// readSecretDir reads the keys of a secret mounted at dir. The kubelet's
+// bookkeeping entries, which start with "..", are skipped.
+func readSecretDir(dir string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading secret directory: %w", err)
+	}
+	data := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "..") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", entry.Name(), err)
+		}
+		if info.IsDir() {
+			continue
+		}
+		value, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", entry.Name(), err)
+		}
+		data[entry.Name()] = value
+	}
+	return data, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lukasngl/valet/framework/envelope"
+)
+
+func TestRun(t *testing.T) {
+	key := bytes.Repeat([]byte{3}, 32)
+	kw, err := envelope.NewAESKeyWrapper(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := envelope.Seal(context.Background(), kw, map[string]string{"ClientSecret": "s3cr3t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Lay out the secret like the kubelet does: values in a timestamped
+	// directory, linked through ..data.
+	in := t.TempDir()
+	dataDir := filepath.Join(in, "..2026_01_01_00_00_00.000000000")
+	if err := os.Mkdir(dataDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	sealed["plain"] = "unencrypted"
+	for k, v := range sealed {
+		if err := os.WriteFile(filepath.Join(dataDir, k), []byte(v), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Base(dataDir), filepath.Join(in, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	for k := range sealed {
+		if err := os.Symlink(filepath.Join("..data", k), filepath.Join(in, k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, key, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(t.TempDir(), "decrypted")
+	if err := run([]string{"--key-file", keyFile, "--in", in, "--out", out}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	entries, err := os.ReadDir(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected ClientSecret and plain, got %v", entries)
+	}
+	for name, want := range map[string]string{"ClientSecret": "s3cr3t", "plain": "unencrypted"} {
+		got, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v, want %q", name, got, err, want)
+		}
+	}
+}
+
+func TestRunRequiresFlags(t *testing.T) {
+	if err := run([]string{"--in", t.TempDir()}); err == nil {
+		t.Fatal("expected usage error")
+	}
+}
@@ -0,0 +1,119 @@
+package framework
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// FieldOwner is the field manager of the reconciler's writes to output
+// secrets. Writes by other field managers are reported as conflicting, see
+// [ConditionConflictingWriter].
+const FieldOwner = "valet"
+
+// ConflictBackoff is how long the reconciler leaves an output secret that
+// another writer emptied alone before it provisions credentials for it
+// again, so that it does not thrash against the other writer.
+const ConflictBackoff = 5 * time.Minute
+
+// dataChecksum returns the checksum of the data the reconciler manages in an
+// output secret once its StringData is merged into its Data, as recorded in
+// [AnnotationDataChecksum]. Keys of other writers outside
+// [AnnotationManagedKeys] do not count.
+func dataChecksum(secret *corev1.Secret) string {
+	data := managedData(secret)
+
+	h := sha256.New()
+	for _, k := range slices.Sorted(maps.Keys(data)) {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write(data[k])
+		h.Write([]byte{0})
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// setDataChecksum records the checksum of the data of secret, which is about
+// to be written, in [AnnotationDataChecksum].
+func setDataChecksum(secret *corev1.Secret) {
+	metav1.SetMetaDataAnnotation(&secret.ObjectMeta, AnnotationDataChecksum, dataChecksum(secret))
+}
+
+// conflictingWriter reports whether the data of secret differs from the data
+// the reconciler wrote, and returns the field manager that most recently
+// changed its data otherwise, or "unknown" if the managed fields do not tell.
+// Secrets without [AnnotationDataChecksum], e.g. written by older versions,
+// are never reported.
+func conflictingWriter(secret *corev1.Secret) (string, bool) {
+	checksum, ok := secret.Annotations[AnnotationDataChecksum]
+	if !ok || checksum == dataChecksum(secret) {
+		return "", false
+	}
+
+	manager, latest := "unknown", time.Time{}
+	for _, entry := range secret.ManagedFields {
+		if entry.Manager == FieldOwner || entry.Subresource != "" || entry.FieldsV1 == nil {
+			continue
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		_, data := fields["f:data"]
+		_, stringData := fields["f:stringData"]
+		if !data && !stringData {
+			continue
+		}
+		if t := entry.Time; t == nil || !t.Time.Before(latest) {
+			manager = entry.Manager
+			if t != nil {
+				latest = t.Time
+			}
+		}
+	}
+	return manager, true
+}
+
+// checkConflictingWriter keeps [ConditionConflictingWriter] up to date with
+// whether another writer changed the data of the output secret, recording a
+// Warning event when it is set. It reports whether the reconciler backs off,
+// i.e. does not provision credentials because the secret is empty, which it
+// does for [ConflictBackoff] after the condition was set.
+func (r *Reconciler[O]) checkConflictingWriter(ctx context.Context, obj O) (bool, error) {
+	name := obj.GetSecretRef().Name
+	var secret corev1.Secret
+	if err := r.Get(ctx, client.ObjectKey{Namespace: obj.GetNamespace(), Name: name}, &secret); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+
+	status := obj.GetStatus()
+	manager, conflict := conflictingWriter(&secret)
+	if !conflict {
+		if !status.ClearConflictingWriter() {
+			return false, nil
+		}
+		log.FromContext(ctx).Info("output secret is no longer changed by another writer", "secret", name)
+		return false, r.updateStatus(ctx, obj)
+	}
+
+	if !status.SetConflictingWriter(obj.GetGeneration(), name, manager) {
+		c := meta.FindStatusCondition(status.Conditions, ConditionConflictingWriter)
+		return time.Since(c.LastTransitionTime.Time) < ConflictBackoff, nil
+	}
+	log.FromContext(ctx).Info("output secret was changed by another writer", "secret", name, "manager", manager)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, corev1.EventTypeWarning, ReasonConflictingWriter, "Reconcile",
+			"The data of Secret %s was changed by field manager %s", name, manager)
+	}
+	return true, r.updateStatus(ctx, obj)
+}
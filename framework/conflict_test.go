@@ -0,0 +1,122 @@
+package framework_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// mergeStringData has the fake client merge the StringData of the Secrets
+// written through it into their Data, as the API server does, for tests that
+// depend on the stored data.
+var mergeStringData = interceptor.Funcs{
+	Create: func(ctx context.Context, c client.WithWatch, o client.Object, opts ...client.CreateOption) error {
+		mergeSecretStringData(o)
+		return c.Create(ctx, o, opts...)
+	},
+	Update: func(ctx context.Context, c client.WithWatch, o client.Object, opts ...client.UpdateOption) error {
+		mergeSecretStringData(o)
+		return c.Update(ctx, o, opts...)
+	},
+}
+
+func mergeSecretStringData(o client.Object) {
+	secret, ok := o.(*corev1.Secret)
+	if !ok || len(secret.StringData) == 0 {
+		return
+	}
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte, len(secret.StringData))
+	}
+	for k, v := range secret.StringData {
+		secret.Data[k] = []byte(v)
+	}
+	secret.StringData = nil
+}
+
+// countingDataProvider is a [dataProvider] counting the Provision calls.
+type countingDataProvider struct {
+	dataProvider
+
+	provisions int
+}
+
+func (p *countingDataProvider) Provision(ctx context.Context, obj *testObject) (*framework.Result, error) {
+	p.provisions++
+	return p.dataProvider.Provision(ctx, obj)
+}
+
+func TestReconcile_ConflictingWriter(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).
+		WithReturnManagedFields().WithInterceptorFuncs(mergeStringData).Build()
+	recorder := events.NewFakeRecorder(10)
+	p := &countingDataProvider{dataProvider: dataProvider{data: map[string]string{"KEY": "s3cret"}}}
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p, Recorder: recorder}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+	secretKey := client.ObjectKey{Namespace: obj.Namespace, Name: obj.SecretRef.Name}
+
+	reconcile := func() *metav1.Condition {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+		var got testObject
+		if err := c.Get(ctx, req.NamespacedName, &got); err != nil {
+			t.Fatal(err)
+		}
+		return meta.FindStatusCondition(got.Status.Conditions, framework.ConditionConflictingWriter)
+	}
+	overwrite := func(data map[string][]byte) {
+		t.Helper()
+		var secret corev1.Secret
+		if err := c.Get(ctx, secretKey, &secret); err != nil {
+			t.Fatal(err)
+		}
+		secret.Data = data
+		if err := c.Update(ctx, &secret, client.FieldOwner("other-controller")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for range 2 {
+		if cond := reconcile(); cond != nil {
+			t.Fatalf("expected no conflict for the reconciler's own writes, got %v", cond)
+		}
+	}
+
+	overwrite(map[string][]byte{"KEY": []byte("overwritten")})
+	for range 2 {
+		cond := reconcile()
+		if cond == nil || cond.Status != metav1.ConditionTrue || !strings.Contains(cond.Message, "other-controller") {
+			t.Fatalf("expected a ConflictingWriter condition naming the writer, got %v", cond)
+		}
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("expected a single ConflictingWriter event, got %d", len(recorder.Events))
+	}
+
+	overwrite(nil)
+	reconcile()
+	if p.provisions != 1 {
+		t.Errorf("expected no provisioning while backing off, got %d calls", p.provisions)
+	}
+
+	overwrite(map[string][]byte{"KEY": []byte("s3cret")})
+	if cond := reconcile(); cond != nil {
+		t.Errorf("expected the condition to be cleared once the data is restored, got %v", cond)
+	}
+}
@@ -0,0 +1,76 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// CheckCRD verifies that the CRD of obj is installed, and that it serves and
+// stores the API version the operator was built against. Without it, the
+// controller would only log watch errors.
+//
+// The operator needs RBAC to get the CRD.
+func CheckCRD(
+	ctx context.Context,
+	c client.Reader,
+	mapper meta.RESTMapper,
+	scheme *runtime.Scheme,
+	obj client.Object,
+) error {
+	gvk, err := apiutil.GVKForObject(obj, scheme)
+	if err != nil {
+		return fmt.Errorf("resolving kind: %w", err)
+	}
+
+	mapping, err := mapper.RESTMapping(gvk.GroupKind())
+	if meta.IsNoMatchError(err) {
+		return fmt.Errorf("CRD for %s is not installed: install the CRDs shipped with this provider "+
+			"(the crds/ directory of its Helm chart)", gvk.GroupKind())
+	}
+	if err != nil {
+		return fmt.Errorf("looking up %s: %w", gvk.GroupKind(), err)
+	}
+
+	name := mapping.Resource.Resource + "." + gvk.Group
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(apiextensionsv1.SchemeGroupVersion.WithKind("CustomResourceDefinition"))
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, u); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("CRD %s is not installed: install the CRDs shipped with this provider "+
+				"(the crds/ directory of its Helm chart)", name)
+		}
+		return fmt.Errorf("getting CRD %s: %w", name, err)
+	}
+	var crd apiextensionsv1.CustomResourceDefinition
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &crd); err != nil {
+		return fmt.Errorf("parsing CRD %s: %w", name, err)
+	}
+
+	var served bool
+	var storage string
+	for _, v := range crd.Spec.Versions {
+		if v.Name == gvk.Version {
+			served = v.Served
+		}
+		if v.Storage {
+			storage = v.Name
+		}
+	}
+	if !served {
+		return fmt.Errorf("CRD %s does not serve version %s, which this operator uses: "+
+			"upgrade the CRDs and the operator together", name, gvk.Version)
+	}
+	if storage != gvk.Version {
+		return fmt.Errorf("CRD %s stores version %s, but this operator uses %s: "+
+			"upgrade the CRDs and the operator together", name, storage, gvk.Version)
+	}
+	return nil
+}
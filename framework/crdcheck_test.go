@@ -0,0 +1,92 @@
+package framework_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testCRD(versions ...apiextensionsv1.CustomResourceDefinitionVersion) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "testobjects." + testGV.Group},
+		Spec:       apiextensionsv1.CustomResourceDefinitionSpec{Versions: versions},
+	}
+}
+
+func TestCheckCRD(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapped  bool
+		crd     *apiextensionsv1.CustomResourceDefinition
+		wantErr string
+	}{
+		{
+			name:   "compatible",
+			mapped: true,
+			crd:    testCRD(apiextensionsv1.CustomResourceDefinitionVersion{Name: "v1", Served: true, Storage: true}),
+		},
+		{
+			name:    "kind unknown to the API server",
+			wantErr: "is not installed",
+		},
+		{
+			name:    "CRD missing",
+			mapped:  true,
+			wantErr: "CRD testobjects.test.valet.ngl.cx is not installed",
+		},
+		{
+			name:   "version not served",
+			mapped: true,
+			crd: testCRD(
+				apiextensionsv1.CustomResourceDefinitionVersion{Name: "v1"},
+				apiextensionsv1.CustomResourceDefinitionVersion{Name: "v2", Served: true, Storage: true},
+			),
+			wantErr: "does not serve version v1",
+		},
+		{
+			name:   "other storage version",
+			mapped: true,
+			crd: testCRD(
+				apiextensionsv1.CustomResourceDefinitionVersion{Name: "v1", Served: true},
+				apiextensionsv1.CustomResourceDefinitionVersion{Name: "v2", Served: true, Storage: true},
+			),
+			wantErr: "stores version v2, but this operator uses v1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := newTestScheme(t)
+			if err := apiextensionsv1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			b := fake.NewClientBuilder().WithScheme(scheme)
+			if tt.crd != nil {
+				b = b.WithObjects(tt.crd)
+			}
+
+			mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{testGV})
+			if tt.mapped {
+				mapper.Add(testGV.WithKind("testObject"), meta.RESTScopeNamespace)
+			}
+
+			err := framework.CheckCRD(context.Background(), b.Build(), mapper, scheme, newTestObject())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
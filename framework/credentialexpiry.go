@@ -0,0 +1,146 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// DefaultCredentialCheckInterval is how often a [CredentialExpiryWatcher]
+// checks the credentials of the operator by default.
+const DefaultCredentialCheckInterval = time.Hour
+
+// DefaultCredentialExpiryWarning is how long before the credentials of the
+// operator expire a [CredentialExpiryWatcher] starts logging errors by
+// default.
+const DefaultCredentialExpiryWarning = 14 * 24 * time.Hour
+
+// ErrCredentialsExpiring is logged by a [CredentialExpiryWatcher] while the
+// credentials of the operator expire within its warning period.
+var ErrCredentialsExpiring = errors.New("operator credentials expire soon")
+
+// OperatorCredentialExpiry is the time at which the credentials the operator
+// uses to access the provider expire, per kind, as of the last check of a
+// [CredentialExpiryWatcher]. Credentials that do not expire, e.g. workload
+// identities, are not reported. It is registered on the controller-runtime
+// metrics registry.
+var OperatorCredentialExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "valet_operator_credential_expiry_timestamp_seconds",
+	Help: "Unix time at which the credentials the operator uses to access the provider expire.",
+}, []string{"kind"})
+
+// OperatorCredentialCheckErrors counts the failed checks of a
+// [CredentialExpiryWatcher], per kind. Expired credentials often show up
+// here, as the provider no longer answers the check.
+var OperatorCredentialCheckErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "valet_operator_credential_check_errors_total",
+	Help: "Total number of failed checks of the expiry of the operator's credentials.",
+}, []string{"kind"})
+
+func init() {
+	metrics.Registry.MustRegister(OperatorCredentialExpiry, OperatorCredentialCheckErrors)
+}
+
+// CredentialExpiryReporter is optionally implemented by providers that can
+// tell when the credentials the operator itself uses to access the provider
+// expire, e.g. the client secret of the Azure application it authenticates
+// as. [Reconciler.SetupWithManager] then adds a [CredentialExpiryWatcher], so
+// that the operator does not silently lose its access.
+type CredentialExpiryReporter interface {
+	// CredentialExpiry returns when the credentials of the operator expire,
+	// or the zero time if they do not, e.g. for workload identities.
+	CredentialExpiry(ctx context.Context) (time.Time, error)
+}
+
+// credentialExpiryReporter returns the provider as a
+// [CredentialExpiryReporter], looking through wrappers such as
+// [InstrumentedProvider], or nil if it does not implement one.
+func (r *Reconciler[O]) credentialExpiryReporter() CredentialExpiryReporter {
+	c, _ := findProvider[O, CredentialExpiryReporter](r.Provider)
+	return c
+}
+
+// CredentialExpiryWatcher checks when the credentials of the operator expire
+// at startup and every Interval, and exposes it as
+// [OperatorCredentialExpiry]. Once they expire within Warning, every check
+// logs [ErrCredentialsExpiring]. Alert on the metric, e.g. on
+// valet_operator_credential_expiry_timestamp_seconds - time() < 7 * 86400,
+// and on [OperatorCredentialCheckErrors].
+//
+// It implements [sigs.k8s.io/controller-runtime/pkg/manager.Runnable] and
+// runs on all replicas, not only the leader, as standby replicas use the
+// same credentials once they lead.
+type CredentialExpiryWatcher struct {
+	// Reporter reports the expiry.
+	Reporter CredentialExpiryReporter
+
+	// Kind labels the metrics, e.g. AzureClientSecret.
+	Kind string
+
+	// Interval is how often the expiry is checked. Defaults to
+	// [DefaultCredentialCheckInterval].
+	Interval time.Duration
+
+	// Warning is how long before the expiry errors are logged. Defaults to
+	// [DefaultCredentialExpiryWarning].
+	Warning time.Duration
+}
+
+// NeedLeaderElection implements
+// [sigs.k8s.io/controller-runtime/pkg/manager.LeaderElectionRunnable].
+func (w *CredentialExpiryWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+// Start checks the expiry right away and then every Interval until ctx is
+// done.
+func (w *CredentialExpiryWatcher) Start(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultCredentialCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	_, _ = w.Check(ctx, time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			_, _ = w.Check(ctx, now)
+		}
+	}
+}
+
+// Check checks the expiry once, as of now, and returns it. Errors are also
+// logged and counted.
+func (w *CredentialExpiryWatcher) Check(ctx context.Context, now time.Time) (time.Time, error) {
+	log := log.FromContext(ctx).WithValues("kind", w.Kind)
+
+	expiry, err := w.Reporter.CredentialExpiry(ctx)
+	if err != nil {
+		OperatorCredentialCheckErrors.WithLabelValues(w.Kind).Inc()
+		log.Error(err, "failed to check the expiry of the operator's credentials")
+		return time.Time{}, err
+	}
+	if expiry.IsZero() {
+		OperatorCredentialExpiry.DeleteLabelValues(w.Kind)
+		return expiry, nil
+	}
+	OperatorCredentialExpiry.WithLabelValues(w.Kind).Set(float64(expiry.Unix()))
+
+	warning := w.Warning
+	if warning <= 0 {
+		warning = DefaultCredentialExpiryWarning
+	}
+	if left := expiry.Sub(now); left < warning {
+		log.Error(ErrCredentialsExpiring, "renew the operator's credentials before it loses access to the provider",
+			"expiresAt", expiry, "left", left.Round(time.Minute))
+	}
+	return expiry, nil
+}
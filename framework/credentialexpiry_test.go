@@ -0,0 +1,58 @@
+package framework_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type expiryReporter struct {
+	expiry time.Time
+	err    error
+}
+
+func (r *expiryReporter) CredentialExpiry(context.Context) (time.Time, error) {
+	return r.expiry, r.err
+}
+
+func TestCredentialExpiryWatcher(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	reporter := &expiryReporter{expiry: now.Add(72 * time.Hour)}
+	w := &framework.CredentialExpiryWatcher{Reporter: reporter, Kind: "expiryTest"}
+
+	got, err := w.Check(ctx, now)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if !got.Equal(reporter.expiry) {
+		t.Errorf("expected expiry %v, got %v", reporter.expiry, got)
+	}
+	gauge := framework.OperatorCredentialExpiry.WithLabelValues("expiryTest")
+	if got := testutil.ToFloat64(gauge); got != float64(reporter.expiry.Unix()) {
+		t.Errorf("expected the gauge to report the expiry, got %v", got)
+	}
+
+	// Credentials that do not expire are not reported.
+	reporter.expiry = time.Time{}
+	if _, err := w.Check(ctx, now); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if got := testutil.CollectAndCount(framework.OperatorCredentialExpiry); got != 0 {
+		t.Errorf("expected no expiry to be reported, got %d series", got)
+	}
+
+	reporter.err = errors.New("invalid client secret")
+	errs := framework.OperatorCredentialCheckErrors.WithLabelValues("expiryTest")
+	before := testutil.ToFloat64(errs)
+	if _, err := w.Check(ctx, now); err == nil {
+		t.Fatal("expected the check to fail")
+	}
+	if got := testutil.ToFloat64(errs) - before; got != 1 {
+		t.Errorf("expected 1 failed check to be counted, got %v", got)
+	}
+}
@@ -0,0 +1,255 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DebugPath is the path below which [Reconciler.DebugHandler] serves the
+// support bundles of single resources, as DebugPath + "<namespace>/<name>".
+const DebugPath = "/debug/clientsecrets/"
+
+// DefaultDebugHistory is the number of reconcile traces and status snapshots
+// a [DebugRecorder] keeps per resource if its Size is not set.
+const DefaultDebugHistory = 20
+
+// lastAppliedAnnotation is set by kubectl apply and holds a copy of the
+// object, which support bundles leave out.
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// ReconcileTrace describes a single reconciliation of a resource.
+type ReconcileTrace struct {
+	// ReconcileID is the ID controller-runtime assigned to the
+	// reconciliation, which its log lines carry as well.
+	ReconcileID types.UID `json:"reconcileID,omitempty"`
+	// Start is when the reconciliation started.
+	Start time.Time `json:"start"`
+	// Duration is how long it took, in nanoseconds.
+	Duration time.Duration `json:"duration"`
+	// RequeueAfter is when the resource is reconciled again, in nanoseconds,
+	// if scheduled.
+	RequeueAfter time.Duration `json:"requeueAfter,omitempty"`
+	// Error is the error the reconciliation returned, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// StatusSnapshot is the status of a resource after a reconciliation that
+// changed it. It leaves out the key IDs and identifiers of the active keys.
+type StatusSnapshot struct {
+	// Time is when the status was observed.
+	Time time.Time `json:"time"`
+	// ObservedGeneration is the generation of the spec the status is for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Phase is the lifecycle phase.
+	Phase string `json:"phase,omitempty"`
+	// LastFailureMessage is the error of the last failure.
+	LastFailureMessage string `json:"lastFailureMessage,omitempty"`
+	// Conditions are the status conditions.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ActiveKeys is the number of active keys.
+	ActiveKeys int `json:"activeKeys"`
+	// NextExpiry is when the first of the active keys expires.
+	NextExpiry *metav1.Time `json:"nextExpiry,omitempty"`
+}
+
+// newStatusSnapshot returns the snapshot of status at now.
+func newStatusSnapshot(now time.Time, status *ClientSecretStatus) StatusSnapshot {
+	s := StatusSnapshot{
+		Time:               now,
+		ObservedGeneration: status.ObservedGeneration,
+		Phase:              status.Phase,
+		LastFailureMessage: status.LastFailureMessage,
+		Conditions:         slices.Clone(status.Conditions),
+		ActiveKeys:         len(status.ActiveKeys),
+	}
+	for _, key := range status.ActiveKeys {
+		if s.NextExpiry == nil || key.ExpiresAt.Before(s.NextExpiry) {
+			s.NextExpiry = key.ExpiresAt.DeepCopy()
+		}
+	}
+	return s
+}
+
+// sameStatus reports whether a and b describe the same status, regardless of
+// when they were observed.
+func sameStatus(a, b StatusSnapshot) bool {
+	return a.ObservedGeneration == b.ObservedGeneration && a.Phase == b.Phase &&
+		a.LastFailureMessage == b.LastFailureMessage && a.ActiveKeys == b.ActiveKeys &&
+		equality.Semantic.DeepEqual(a.NextExpiry, b.NextExpiry) &&
+		equality.Semantic.DeepEqual(a.Conditions, b.Conditions)
+}
+
+// DebugRecorder keeps the recent reconcile traces and status changes of each
+// resource in memory, for the support bundles of [Reconciler.DebugHandler].
+// Resources are forgotten once they are gone. A nil DebugRecorder records
+// nothing.
+type DebugRecorder struct {
+	// Size is the number of traces and snapshots kept per resource. Zero
+	// uses [DefaultDebugHistory].
+	Size int
+
+	mu        sync.Mutex
+	resources map[types.NamespacedName]*debugHistory
+}
+
+// debugHistory is what a [DebugRecorder] keeps of a resource.
+type debugHistory struct {
+	traces   []ReconcileTrace
+	statuses []StatusSnapshot
+}
+
+// record adds a trace of the reconciliation of obj that started at start
+// and returned result and err, and a snapshot of its status if it changed.
+func (d *DebugRecorder) record(ctx context.Context, obj Object, start time.Time, result ctrl.Result, err error) {
+	if d == nil {
+		return
+	}
+	now := time.Now()
+	trace := ReconcileTrace{
+		ReconcileID:  controller.ReconcileIDFromContext(ctx),
+		Start:        start,
+		Duration:     now.Sub(start),
+		RequeueAfter: result.RequeueAfter,
+	}
+	if err != nil {
+		trace.Error = err.Error()
+	}
+	snapshot := newStatusSnapshot(now, obj.GetStatus())
+
+	size := d.Size
+	if size <= 0 {
+		size = DefaultDebugHistory
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	h, ok := d.resources[key]
+	if !ok {
+		if d.resources == nil {
+			d.resources = make(map[types.NamespacedName]*debugHistory)
+		}
+		h = &debugHistory{}
+		d.resources[key] = h
+	}
+	h.traces = trimHistory(append(h.traces, trace), size)
+	if len(h.statuses) == 0 || !sameStatus(h.statuses[len(h.statuses)-1], snapshot) {
+		h.statuses = trimHistory(append(h.statuses, snapshot), size)
+	}
+}
+
+// forget drops the history of the resource key.
+func (d *DebugRecorder) forget(key types.NamespacedName) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.resources, key)
+}
+
+// history returns copies of the traces and snapshots kept of the resource
+// key, oldest first.
+func (d *DebugRecorder) history(key types.NamespacedName) ([]ReconcileTrace, []StatusSnapshot) {
+	if d == nil {
+		return nil, nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	h, ok := d.resources[key]
+	if !ok {
+		return nil, nil
+	}
+	return slices.Clone(h.traces), slices.Clone(h.statuses)
+}
+
+// trimHistory drops the oldest entries of s beyond size.
+func trimHistory[T any](s []T, size int) []T {
+	if len(s) <= size {
+		return s
+	}
+	return slices.Clone(s[len(s)-size:])
+}
+
+// SupportBundle is what [Reconciler.DebugHandler] serves for a resource.
+type SupportBundle struct {
+	// Resource is the resource without its managed fields and
+	// last-applied-configuration annotation.
+	Resource Object `json:"resource"`
+	// Capabilities are the capabilities of the provider.
+	Capabilities Capabilities `json:"capabilities"`
+	// StatusHistory are the recent status changes, oldest first.
+	StatusHistory []StatusSnapshot `json:"statusHistory"`
+	// Traces are the recent reconciliations, oldest first.
+	Traces []ReconcileTrace `json:"traces"`
+}
+
+// DebugHandler returns a handler serving the [SupportBundle] of the resource
+// on DebugPath + "<namespace>/<name>" as JSON, for troubleshooting tooling.
+// The history is only kept if [Reconciler.Debug] is set.
+//
+// The bundle does not contain credentials, but describes the resource in
+// detail, so only serve it to authorized clients, e.g. next to the metrics
+// with authentication and authorization enabled.
+func (r *Reconciler[O]) DebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+DebugPath+"{namespace}/{name}", func(w http.ResponseWriter, req *http.Request) {
+		key := types.NamespacedName{Namespace: req.PathValue("namespace"), Name: req.PathValue("name")}
+		bundle, err := r.supportBundle(req.Context(), key)
+		if apierrors.IsNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.FromContext(req.Context()).Error(err, "building support bundle", "resource", key)
+			http.Error(w, "building support bundle failed", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(bundle)
+	})
+	return mux
+}
+
+// supportBundle returns the [SupportBundle] of the resource key.
+func (r *Reconciler[O]) supportBundle(ctx context.Context, key types.NamespacedName) (*SupportBundle, error) {
+	obj := r.Provider.NewObject()
+	if err := r.Get(ctx, key, obj); err != nil {
+		return nil, err
+	}
+	// Objects from the client lack their kind, which tooling needs to
+	// tell the resources of different providers apart.
+	if gvk, err := apiutil.GVKForObject(obj, r.Scheme); err == nil {
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+	}
+	obj.SetManagedFields(nil)
+	if annotations := obj.GetAnnotations(); annotations[lastAppliedAnnotation] != "" {
+		delete(annotations, lastAppliedAnnotation)
+		obj.SetAnnotations(annotations)
+	}
+
+	traces, statuses := r.Debug.history(key)
+	return &SupportBundle{
+		Resource:      obj,
+		Capabilities:  ProviderCapabilities(r.Provider),
+		StatusHistory: statuses,
+		Traces:        traces,
+	}, nil
+}
@@ -0,0 +1,93 @@
+package framework_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// getSupportBundle requests the support bundle of path from r.
+func getSupportBundle(t *testing.T, r *framework.Reconciler[*testObject], path string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	r.DebugHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, framework.DebugPath+path, nil))
+	return rec
+}
+
+func TestDebugHandler(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	obj.Annotations = map[string]string{"kubectl.kubernetes.io/last-applied-configuration": "{}"}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	p := &stubProvider{}
+	r := &framework.Reconciler[*testObject]{
+		Client:   c,
+		Scheme:   scheme,
+		Provider: p,
+		Debug:    &framework.DebugRecorder{Size: 2},
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	p.err = errors.New("provider down")
+	if _, err := r.Reconcile(context.Background(), req); err == nil {
+		t.Fatal("expected the provider error")
+	}
+	p.err = nil
+	for range 2 {
+		if _, err := r.Reconcile(context.Background(), req); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+	}
+
+	rec := getSupportBundle(t, r, "default/app")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body)
+	}
+	var bundle struct {
+		Resource struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Annotations   map[string]string `json:"annotations"`
+				ManagedFields []any             `json:"managedFields"`
+			} `json:"metadata"`
+		} `json:"resource"`
+		Capabilities  framework.Capabilities     `json:"capabilities"`
+		StatusHistory []framework.StatusSnapshot `json:"statusHistory"`
+		Traces        []framework.ReconcileTrace `json:"traces"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &bundle); err != nil {
+		t.Fatalf("decoding bundle: %v", err)
+	}
+
+	if bundle.Resource.Kind != "testObject" {
+		t.Errorf("expected the kind to be set, got %q", bundle.Resource.Kind)
+	}
+	if len(bundle.Resource.Metadata.Annotations) != 0 || len(bundle.Resource.Metadata.ManagedFields) != 0 {
+		t.Errorf("expected the resource to be sanitized, got %+v", bundle.Resource.Metadata)
+	}
+	if bundle.Capabilities != framework.DefaultCapabilities {
+		t.Errorf("got capabilities %+v", bundle.Capabilities)
+	}
+	if len(bundle.Traces) != 2 || bundle.Traces[0].Error != "" {
+		t.Errorf("expected the two successful reconciliations, got %+v", bundle.Traces)
+	}
+	if len(bundle.StatusHistory) != 2 {
+		t.Fatalf("expected the failed and ready status, got %+v", bundle.StatusHistory)
+	}
+	if got := bundle.StatusHistory[1]; got.Phase != framework.PhaseReady || got.ActiveKeys != 1 {
+		t.Errorf("got status %+v", got)
+	}
+
+	if rec := getSupportBundle(t, r, "default/missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing resource, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,127 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DeletionPolicy controls what happens to the keys and the output Secret of a
+// resource when it is deleted. Providers embed it in their spec as
+// deletionPolicy, see [DeletionPolicyHolder].
+// +kubebuilder:validation:Enum=Delete;Retain
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the active keys at the provider and lets
+	// the output Secret be garbage-collected with the resource. It is the
+	// default.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+
+	// DeletionPolicyRetain leaves the active keys valid at the provider and
+	// releases the output Secret from the resource, so that both outlive it,
+	// e.g. while the resource is moved to another cluster.
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+
+	// ReasonRetained is the reason of the event recorded when a resource with
+	// [DeletionPolicyRetain] was deleted.
+	ReasonRetained = "Retained"
+)
+
+// DeletionPolicyHolder is optionally implemented by objects whose spec
+// carries a [DeletionPolicy].
+type DeletionPolicyHolder interface {
+	// GetDeletionPolicy returns the deletion policy of the object, or "" for
+	// the default.
+	GetDeletionPolicy() DeletionPolicy
+}
+
+// deletionPolicy returns the [DeletionPolicy] of obj, defaulting to
+// [DeletionPolicyDelete].
+func deletionPolicy(obj Object) DeletionPolicy {
+	if h, ok := obj.(DeletionPolicyHolder); ok && h.GetDeletionPolicy() != "" {
+		return h.GetDeletionPolicy()
+	}
+	return DeletionPolicyDelete
+}
+
+// validateDeletionPolicy checks that the deletion policy of obj is known.
+func validateDeletionPolicy(obj Object) error {
+	switch p := deletionPolicy(obj); p {
+	case DeletionPolicyDelete, DeletionPolicyRetain:
+		return nil
+	default:
+		return fmt.Errorf("deletionPolicy must be %s or %s, got %q", DeletionPolicyDelete, DeletionPolicyRetain, p)
+	}
+}
+
+// retainOnDeletion releases the output secret of obj, its split parts and its
+// targets from obj instead of deleting its keys, see [DeletionPolicyRetain].
+func (r *Reconciler[O]) retainOnDeletion(ctx context.Context, obj O) error {
+	name := obj.GetSecretRef().Name
+
+	var secret corev1.Secret
+	key := client.ObjectKey{Namespace: obj.GetNamespace(), Name: name}
+	if err := r.Get(ctx, key, &secret); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	var names []string
+	for _, t := range targets(obj) {
+		if key := targetKey(obj, t); key.Namespace == obj.GetNamespace() {
+			names = append(names, key.Name)
+		}
+	}
+	// The output secret itself goes last, as its annotation lists the parts.
+	if parts := secret.Annotations[AnnotationSplitSecrets]; parts != "" {
+		names = append(names, strings.Split(parts, ",")...)
+	}
+	names = append(names, name)
+	for _, n := range names {
+		if err := r.releaseSecret(ctx, obj, n); err != nil {
+			return err
+		}
+	}
+
+	keyIDs := make([]string, len(obj.GetStatus().ActiveKeys))
+	for i, k := range obj.GetStatus().ActiveKeys {
+		keyIDs[i] = k.KeyID
+	}
+	log.FromContext(ctx).Info("retaining keys and output secret", "secret", name, "keyIds", keyIDs)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, corev1.EventTypeNormal, ReasonRetained, "Finalize",
+			"Retained secret %s and keys %s", name, strings.Join(keyIDs, ", "))
+	}
+
+	return nil
+}
+
+// releaseSecret removes the owner reference to obj from the named secret, so
+// that it is not garbage-collected with obj. Secrets that do not exist or
+// are not owned by obj are left alone.
+func (r *Reconciler[O]) releaseSecret(ctx context.Context, obj O, name string) error {
+	var secret corev1.Secret
+	key := client.ObjectKey{Namespace: obj.GetNamespace(), Name: name}
+	if err := r.Get(ctx, key, &secret); apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	owned := func(ref metav1.OwnerReference) bool { return ref.UID == obj.GetUID() }
+	if !slices.ContainsFunc(secret.OwnerReferences, owned) {
+		return nil
+	}
+	secret.OwnerReferences = slices.DeleteFunc(secret.OwnerReferences, owned)
+	if err := r.Update(ctx, &secret, client.FieldOwner(FieldOwner)); err != nil {
+		return fmt.Errorf("releasing secret %s: %w", name, err)
+	}
+
+	return nil
+}
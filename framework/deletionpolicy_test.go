@@ -0,0 +1,72 @@
+package framework_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func TestReconcile_DeletionPolicyRetain(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	obj := newDeletingObject(2)
+	obj.DeletionPolicy = framework.DeletionPolicyRetain
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-secret", Namespace: "default"},
+		Data:       map[string][]byte{"KEY": []byte("key-1")},
+	}
+	if err := controllerutil.SetControllerReference(obj, secret, scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).Build()
+	p := &deleteProvider{}
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p}
+
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if len(p.deleted) != 0 {
+		t.Errorf("expected the keys to be retained, got %v deleted", p.deleted)
+	}
+	if err := c.Get(ctx, req.NamespacedName, &testObject{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected object to be gone after finalizer removal, got %v", err)
+	}
+	var got corev1.Secret
+	if err := c.Get(ctx, client.ObjectKeyFromObject(secret), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.OwnerReferences) != 0 {
+		t.Errorf("expected the secret to be released, got %+v", got.OwnerReferences)
+	}
+}
+
+func TestReconcile_InvalidDeletionPolicy(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	obj, secret := steadyObject(nil)
+	obj.DeletionPolicy = "Orphan"
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: &countingProvider{}}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	var got testObject
+	if err := c.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != framework.PhaseFailed {
+		t.Errorf("expected an invalid deletion policy to fail the resource, got %s", got.Status.Phase)
+	}
+}
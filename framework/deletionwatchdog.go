@@ -0,0 +1,149 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// ReasonStuckDeleting is the reason of the Warning event recorded on a
+// resource whose deletion has been blocked by [Finalizer] longer than the
+// timeout of a [DeletionWatchdog].
+const ReasonStuckDeleting = "StuckDeleting"
+
+var (
+	// StuckDeletingResources is the number of resources per kind whose
+	// deletion has been blocked by [Finalizer] longer than the timeout of a
+	// [DeletionWatchdog], as of its last check. It is registered on the
+	// controller-runtime metrics registry.
+	StuckDeletingResources = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "valet_stuck_deleting_resources",
+		Help: "Number of resources whose deletion has been blocked by the finalizer for longer than the watchdog timeout.",
+	}, []string{"kind"})
+
+	// StuckDeletionSeconds is how long the deletion of each resource counted
+	// in [StuckDeletingResources] has been pending, so that they can be
+	// listed from the metrics.
+	StuckDeletionSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "valet_stuck_deletion_seconds",
+		Help: "Seconds since the deletion of a resource blocked by the finalizer was requested.",
+	}, []string{"kind", "namespace", "name"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(StuckDeletingResources, StuckDeletionSeconds)
+}
+
+// DeletionWatchdog periodically lists the resources of a provider and reports
+// the ones whose deletion has been blocked by [Finalizer] for longer than
+// Timeout, usually because their active keys cannot be deleted at the
+// provider, so that such zombie resources do not go unnoticed. Stuck
+// resources are counted in [StuckDeletingResources], listed in
+// [StuckDeletionSeconds] and get a [ReasonStuckDeleting] Warning event naming
+// what blocks them. It is added to the manager by
+// [Reconciler.SetupWithManager] if [Reconciler.StuckDeletionTimeout] is set.
+type DeletionWatchdog[O Object] struct {
+	// Reader lists the resources, preferably uncached so that a stalled
+	// informer does not hide them.
+	Reader client.Reader
+	Scheme *runtime.Scheme
+
+	// NewObject returns a zero-value instance of the CRD type.
+	NewObject func() O
+
+	// Timeout is how long the deletion of a resource may be pending.
+	Timeout time.Duration
+
+	// Recorder, if set, records the Warning events.
+	Recorder events.EventRecorder
+
+	reported map[types.UID]bool
+}
+
+// NeedLeaderElection implements
+// [sigs.k8s.io/controller-runtime/pkg/manager.LeaderElectionRunnable], so that
+// only the leader checks and reports.
+func (w *DeletionWatchdog[O]) NeedLeaderElection() bool {
+	return true
+}
+
+// Start checks the resources every half Timeout until ctx is done.
+func (w *DeletionWatchdog[O]) Start(ctx context.Context) error {
+	ticker := time.NewTicker(max(w.Timeout/2, time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			if _, err := w.Check(ctx, now); err != nil {
+				log.FromContext(ctx).Error(err, "deletion watchdog check failed")
+			}
+		}
+	}
+}
+
+// Check reports the resources whose deletion is stuck at now and returns
+// their number. Each stuck resource gets a single event until it is gone.
+func (w *DeletionWatchdog[O]) Check(ctx context.Context, now time.Time) (int, error) {
+	items, err := List(ctx, w.Reader, w.Scheme, w.NewObject)
+	if err != nil {
+		return 0, err
+	}
+
+	kind := reflect.TypeOf(w.NewObject()).Elem().Name()
+	StuckDeletionSeconds.DeletePartialMatch(prometheus.Labels{"kind": kind})
+
+	stuck := map[types.UID]bool{}
+	for _, obj := range items {
+		deletion := obj.GetDeletionTimestamp()
+		if deletion.IsZero() || !controllerutil.ContainsFinalizer(obj, Finalizer) {
+			continue
+		}
+		pendingFor := now.Sub(deletion.Time)
+		if pendingFor < w.Timeout {
+			continue
+		}
+		stuck[obj.GetUID()] = true
+		StuckDeletionSeconds.WithLabelValues(kind, obj.GetNamespace(), obj.GetName()).Set(pendingFor.Seconds())
+		if w.reported[obj.GetUID()] {
+			continue
+		}
+
+		reason := deletionBlocker(obj)
+		pendingFor = pendingFor.Round(time.Second)
+		log.FromContext(ctx).Info("resource is stuck in deletion",
+			"namespace", obj.GetNamespace(), "name", obj.GetName(), "pendingFor", pendingFor, "reason", reason)
+		if w.Recorder != nil {
+			w.Recorder.Eventf(obj, nil, corev1.EventTypeWarning, ReasonStuckDeleting, "Watchdog",
+				"Deletion pending for %s: %s", pendingFor, reason)
+		}
+	}
+	w.reported = stuck
+
+	StuckDeletingResources.WithLabelValues(kind).Set(float64(len(stuck)))
+
+	return len(stuck), nil
+}
+
+// deletionBlocker describes why the deletion of obj has not completed, from
+// the failures recorded by [ClientSecretStatus.SetDeletionFailed].
+func deletionBlocker(obj Object) string {
+	status := obj.GetStatus()
+	if status.DeletionFailures == 0 {
+		return "its keys have not been cleaned up yet; check that the operator reconciles it"
+	}
+	return fmt.Sprintf("%d failed cleanup attempts, last: %s", status.DeletionFailures, status.LastFailureMessage)
+}
@@ -0,0 +1,73 @@
+package framework_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeletionWatchdog(t *testing.T) {
+	scheme := newTestScheme(t)
+	// Timestamps are stored with second precision.
+	now := time.Now().Truncate(time.Second)
+
+	stuck := newDeletingObject(1)
+	stuck.DeletionTimestamp = &metav1.Time{Time: now.Add(-2 * time.Hour)}
+	stuck.Status.SetDeletionFailed(errors.New("key key-0: forbidden"))
+
+	recent := newDeletingObject(1)
+	recent.Name, recent.UID = "recent", "uid-2"
+	recent.DeletionTimestamp = &metav1.Time{Time: now.Add(-time.Minute)}
+
+	live := newTestObject()
+	live.Name, live.UID = "live", "uid-3"
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(stuck, recent, live).Build()
+	recorder := events.NewFakeRecorder(10)
+	w := &framework.DeletionWatchdog[*testObject]{
+		Reader:    c,
+		Scheme:    scheme,
+		NewObject: func() *testObject { return &testObject{} },
+		Timeout:   time.Hour,
+		Recorder:  recorder,
+	}
+
+	for range 2 {
+		n, err := w.Check(context.Background(), now)
+		if err != nil {
+			t.Fatalf("check: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("expected 1 stuck resource, got %d", n)
+		}
+	}
+	if got := testutil.ToFloat64(framework.StuckDeletingResources.WithLabelValues("testObject")); got != 1 {
+		t.Errorf("expected the gauge to report 1 stuck resource, got %v", got)
+	}
+	seconds := framework.StuckDeletionSeconds.WithLabelValues("testObject", "default", "app")
+	if got := testutil.ToFloat64(seconds); got != (2 * time.Hour).Seconds() {
+		t.Errorf("expected the resource to be listed as pending for 2h, got %vs", got)
+	}
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "Warning "+framework.ReasonStuckDeleting) || !strings.Contains(event, "forbidden") {
+			t.Errorf("unexpected event: %s", event)
+		}
+	default:
+		t.Error("expected a StuckDeleting event")
+	}
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("expected a single event while the resource stays stuck, got %s", event)
+	default:
+	}
+}
@@ -0,0 +1,184 @@
+package framework
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Endpoint is an external service a provider connects to.
+type Endpoint struct {
+	// Name describes the service, e.g. "Microsoft Graph".
+	Name string
+
+	// Scheme is the URL scheme the service is reached with, e.g. "https".
+	// Only "http" and "https" connections go through proxies.
+	Scheme string
+
+	// Host is the host name or address of the service.
+	Host string
+
+	// Port is the TCP port of the service.
+	Port int
+}
+
+// String returns the endpoint as host:port.
+func (e Endpoint) String() string {
+	return net.JoinHostPort(e.Host, strconv.Itoa(e.Port))
+}
+
+// EndpointFromURL returns the endpoint of an http or https URL, with the
+// default port of its scheme unless it has one.
+func EndpointFromURL(name, rawURL string) (Endpoint, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Endpoint{}, fmt.Errorf("parsing URL of %s: %w", name, err)
+	}
+
+	var port int
+	switch u.Scheme {
+	case "http":
+		port = 80
+	case "https":
+		port = 443
+	default:
+		return Endpoint{}, fmt.Errorf("URL of %s has unsupported scheme %q", name, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return Endpoint{}, fmt.Errorf("URL of %s has no host", name)
+	}
+	if p := u.Port(); p != "" {
+		if port, err = strconv.Atoi(p); err != nil {
+			return Endpoint{}, fmt.Errorf("URL of %s has invalid port %q", name, p)
+		}
+	}
+
+	return Endpoint{Name: name, Scheme: u.Scheme, Host: u.Hostname(), Port: port}, nil
+}
+
+// EndpointDeclarer is optionally implemented by providers that connect to
+// external services known before any resource is reconciled. Declaring them
+// lets the operator check its proxy configuration at startup, see
+// [CheckEndpoints], and print the NetworkPolicy the chart needs to allow
+// them, see [WriteEndpointValues].
+type EndpointDeclarer interface {
+	// Endpoints returns the endpoints the provider connects to with its
+	// current configuration, which is read from the environment where the
+	// provider does so. Endpoints named by resources, e.g. in their spec,
+	// are not included. It fails if the configuration is invalid.
+	Endpoints() ([]Endpoint, error)
+}
+
+// EndpointRoute is how the operator reaches an [Endpoint].
+type EndpointRoute struct {
+	Endpoint
+
+	// Proxy is the proxy connections go through, or nil if they are direct.
+	Proxy *url.URL
+}
+
+// Via returns the proxy URL without its password, or "direct".
+func (r EndpointRoute) Via() string {
+	if r.Proxy == nil {
+		return "direct"
+	}
+	return r.Proxy.Redacted()
+}
+
+// ProxyEndpoint returns the proxy as an [Endpoint], or the zero value if the
+// route is direct.
+func (r EndpointRoute) ProxyEndpoint() Endpoint {
+	if r.Proxy == nil {
+		return Endpoint{}
+	}
+	port, _ := strconv.Atoi(r.Proxy.Port())
+	if port == 0 {
+		port = defaultProxyPorts[r.Proxy.Scheme]
+	}
+	return Endpoint{Name: "proxy", Scheme: r.Proxy.Scheme, Host: r.Proxy.Hostname(), Port: port}
+}
+
+// defaultProxyPorts are the ports of the proxy schemes supported by
+// [http.Transport], used when the proxy URL has none.
+var defaultProxyPorts = map[string]int{
+	"http":    80,
+	"https":   443,
+	"socks5":  1080,
+	"socks5h": 1080,
+}
+
+// CheckEndpoints returns the routes to the endpoints d declares, as chosen by
+// proxy, usually [http.ProxyFromEnvironment]. It fails if d does, or if the
+// proxy configuration is invalid or uses a scheme [http.Transport] does not
+// support, which would otherwise only surface once the provider is called.
+func CheckEndpoints(
+	d EndpointDeclarer,
+	proxy func(*http.Request) (*url.URL, error),
+) ([]EndpointRoute, error) {
+	endpoints, err := d.Endpoints()
+	if err != nil {
+		return nil, fmt.Errorf("resolving endpoints: %w", err)
+	}
+
+	routes := make([]EndpointRoute, 0, len(endpoints))
+	for _, e := range endpoints {
+		route := EndpointRoute{Endpoint: e}
+		if e.Scheme == "http" || e.Scheme == "https" {
+			req := &http.Request{URL: &url.URL{Scheme: e.Scheme, Host: e.String()}}
+			p, err := proxy(req)
+			if err != nil {
+				return nil, fmt.Errorf("proxy for %s (%s): %w", e.Name, e, err)
+			}
+			if p != nil {
+				if _, ok := defaultProxyPorts[p.Scheme]; !ok || p.Hostname() == "" {
+					return nil, fmt.Errorf("proxy %s for %s (%s) is not a valid http, https or socks5 URL",
+						p.Redacted(), e.Name, e)
+				}
+			}
+			route.Proxy = p
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// WriteEndpointValues writes the networkPolicy.endpoints values of the
+// provider charts that allow the given routes: the endpoints reached
+// directly, and the proxies of the others. The output can be passed to Helm
+// with --values.
+func WriteEndpointValues(w io.Writer, routes []EndpointRoute) error {
+	var b strings.Builder
+	b.WriteString("networkPolicy:\n  endpoints:")
+	if len(routes) == 0 {
+		b.WriteString(" []")
+	}
+	b.WriteString("\n")
+
+	// Endpoints sharing a proxy need only one entry.
+	seen := make(map[string]bool)
+	for _, r := range routes {
+		e, name := r.Endpoint, r.Name+" at "+r.Host
+		if r.Proxy != nil {
+			e, name = r.ProxyEndpoint(), "proxy "+r.Proxy.Host
+		}
+		if seen[e.String()] {
+			continue
+		}
+		seen[e.String()] = true
+
+		// JSON strings are valid YAML and quote whatever the name contains.
+		quoted, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "    - name: %s\n      port: %d\n", quoted, e.Port)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,104 @@
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// AESKeyWrapper is a [KeyWrapper] that wraps data encryption keys with
+// AES-256-GCM under local key encryption keys, e.g. keys mounted from a
+// Secret managed outside the cluster or fetched from a KMS by a sidecar.
+//
+// New keys are wrapped with the first key. All keys can unwrap, so a new key
+// can be put first while secrets sealed with the previous one are still
+// being read.
+type AESKeyWrapper struct {
+	keys [][]byte
+	ids  []string
+}
+
+// NewAESKeyWrapper returns an [AESKeyWrapper] for the given 32-byte keys.
+func NewAESKeyWrapper(keys ...[]byte) (*AESKeyWrapper, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no key encryption key given")
+	}
+	w := &AESKeyWrapper{}
+	for _, key := range keys {
+		if len(key) != dekSize {
+			return nil, fmt.Errorf("key encryption key must be 32 bytes, got %d", len(key))
+		}
+		w.keys = append(w.keys, key)
+		w.ids = append(w.ids, KeyFingerprint(key))
+	}
+	return w, nil
+}
+
+// LoadAESKeyWrapper returns an [AESKeyWrapper] for the keys in the given
+// files. Each file holds 32 raw bytes or their base64 encoding.
+func LoadAESKeyWrapper(paths ...string) (*AESKeyWrapper, error) {
+	keys := make([][]byte, 0, len(paths))
+	for _, path := range paths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading key encryption key: %w", err)
+		}
+		key := raw
+		if len(raw) != dekSize {
+			trimmed := bytes.TrimSpace(raw)
+			key = make([]byte, base64.StdEncoding.DecodedLen(len(trimmed)))
+			n, err := base64.StdEncoding.Decode(key, trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("decoding key encryption key %s: %w", path, err)
+			}
+			key = key[:n]
+		}
+		keys = append(keys, key)
+	}
+	return NewAESKeyWrapper(keys...)
+}
+
+// KeyFingerprint returns the key ID of a key encryption key: the first 16
+// hex digits of its SHA-256 hash.
+func KeyFingerprint(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// WrapKey encrypts dek with the first key.
+func (w *AESKeyWrapper) WrapKey(_ context.Context, dek []byte) ([]byte, string, error) {
+	aead, err := newAEAD(w.keys[0])
+	if err != nil {
+		return nil, "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, "", fmt.Errorf("generating nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, dek, []byte(w.ids[0])), w.ids[0], nil
+}
+
+// UnwrapKey decrypts a key wrapped by the key with the given ID.
+func (w *AESKeyWrapper) UnwrapKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	for i, id := range w.ids {
+		if id != keyID {
+			continue
+		}
+		aead, err := newAEAD(w.keys[i])
+		if err != nil {
+			return nil, err
+		}
+		if len(wrapped) < aead.NonceSize() {
+			return nil, errors.New("wrapped key too short")
+		}
+		nonce, ciphertext := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+		return aead.Open(nil, nonce, ciphertext, []byte(keyID))
+	}
+	return nil, fmt.Errorf("unknown key encryption key %q", keyID)
+}
@@ -0,0 +1,171 @@
+// Package envelope implements the envelope encryption of output secrets.
+//
+// When a [github.com/lukasngl/valet/framework.Reconciler] is configured with
+// a [KeyWrapper], every value it writes to an output secret is encrypted with
+// AES-256-GCM under a fresh data encryption key (DEK). The DEK itself is
+// encrypted ("wrapped") by the KeyWrapper, typically backed by a KMS, and
+// stored next to the values under [HeaderKey]. Reading the secret from etcd,
+// a backup or the API therefore reveals nothing without access to the key
+// encryption key.
+//
+// Consumers decrypt the values with [Open], or run the valet-decrypt
+// initContainer, which writes the decrypted values of a mounted secret to a
+// shared volume. The package only depends on the standard library, so that
+// consumers can import it without pulling in the operator's dependencies.
+package envelope
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// HeaderKey is the secret key holding the JSON-encoded [Header].
+const HeaderKey = ".valet-envelope"
+
+// ValuePrefix marks encrypted values. It is followed by the base64-encoded
+// nonce and ciphertext.
+const ValuePrefix = "valet:v1:"
+
+// Version is the current envelope format version.
+const Version = 1
+
+// dekSize is the size of the data encryption keys in bytes (AES-256).
+const dekSize = 32
+
+// KeyWrapper encrypts and decrypts data encryption keys with a key
+// encryption key, e.g. a key held by a cloud KMS or a Vault transit engine.
+type KeyWrapper interface {
+	// WrapKey encrypts dek and returns the ciphertext along with the ID of
+	// the key encryption key used, which is passed to UnwrapKey again.
+	WrapKey(ctx context.Context, dek []byte) (wrapped []byte, keyID string, err error)
+
+	// UnwrapKey decrypts a data encryption key wrapped by WrapKey.
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// Header describes how the values of a secret were encrypted.
+type Header struct {
+	// Version is the envelope format version.
+	Version int `json:"version"`
+
+	// KeyID identifies the key encryption key, as returned by
+	// [KeyWrapper.WrapKey].
+	KeyID string `json:"keyId"`
+
+	// WrappedKey is the wrapped data encryption key.
+	WrappedKey []byte `json:"wrappedKey"`
+}
+
+// Seal encrypts every value of data under a new data encryption key wrapped
+// by kw. It returns the encrypted values under the same keys, plus the
+// encoded [Header] under [HeaderKey]. Each value is bound to its key, so
+// values cannot be swapped between keys unnoticed.
+func Seal(ctx context.Context, kw KeyWrapper, data map[string]string) (map[string]string, error) {
+	if _, ok := data[HeaderKey]; ok {
+		return nil, fmt.Errorf("key %q is reserved for the envelope header", HeaderKey)
+	}
+
+	dek := make([]byte, dekSize)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, fmt.Errorf("generating data encryption key: %w", err)
+	}
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, keyID, err := kw.WrapKey(ctx, dek)
+	if err != nil {
+		return nil, fmt.Errorf("wrapping data encryption key: %w", err)
+	}
+	header, err := json.Marshal(Header{Version: Version, KeyID: keyID, WrappedKey: wrapped})
+	if err != nil {
+		return nil, fmt.Errorf("encoding envelope header: %w", err)
+	}
+
+	sealed := make(map[string]string, len(data)+1)
+	for key, value := range data {
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, fmt.Errorf("generating nonce: %w", err)
+		}
+		ciphertext := aead.Seal(nonce, nonce, []byte(value), []byte(key))
+		sealed[key] = ValuePrefix + base64.StdEncoding.EncodeToString(ciphertext)
+	}
+	sealed[HeaderKey] = string(header)
+
+	return sealed, nil
+}
+
+// Open decrypts the values of a secret sealed by [Seal]. Values without
+// [ValuePrefix], e.g. keys added to the secret by someone else, are returned
+// as they are. The header itself is not returned. Data without a header is
+// returned unchanged.
+func Open(ctx context.Context, kw KeyWrapper, data map[string][]byte) (map[string][]byte, error) {
+	raw, ok := data[HeaderKey]
+	if !ok {
+		return data, nil
+	}
+
+	var header Header
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return nil, fmt.Errorf("decoding envelope header: %w", err)
+	}
+	if header.Version != Version {
+		return nil, fmt.Errorf("unsupported envelope version %d", header.Version)
+	}
+	dek, err := kw.UnwrapKey(ctx, header.KeyID, header.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("unwrapping data encryption key: %w", err)
+	}
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+
+	opened := make(map[string][]byte, len(data)-1)
+	for key, value := range data {
+		if key == HeaderKey {
+			continue
+		}
+		encoded, ok := strings.CutPrefix(string(value), ValuePrefix)
+		if !ok {
+			opened[key] = value
+			continue
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("decoding key %q: %w", key, err)
+		}
+		if len(ciphertext) < aead.NonceSize() {
+			return nil, fmt.Errorf("decrypting key %q: ciphertext too short", key)
+		}
+		nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+		plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(key))
+		if err != nil {
+			return nil, fmt.Errorf("decrypting key %q: %w", key, err)
+		}
+		opened[key] = plaintext
+	}
+
+	return opened, nil
+}
+
+// newAEAD returns AES-GCM with the given key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != dekSize {
+		return nil, errors.New("key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
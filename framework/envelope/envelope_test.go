@@ -0,0 +1,178 @@
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestWrapper(t *testing.T, fill byte) *AESKeyWrapper {
+	t.Helper()
+	w, err := NewAESKeyWrapper(bytes.Repeat([]byte{fill}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+// toBytes converts sealed data the way the API server stores StringData.
+func toBytes(data map[string]string) map[string][]byte {
+	out := make(map[string][]byte, len(data))
+	for k, v := range data {
+		out[k] = []byte(v)
+	}
+	return out
+}
+
+func TestSealOpen(t *testing.T) {
+	ctx := context.Background()
+	w := newTestWrapper(t, 1)
+	data := map[string]string{"ClientSecret": "s3cr3t", "empty": ""}
+
+	sealed, err := Seal(ctx, w, data)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if len(sealed) != 3 || sealed[HeaderKey] == "" {
+		t.Fatalf("expected both values and the header, got keys %v", sealed)
+	}
+	for key := range data {
+		if !strings.HasPrefix(sealed[key], ValuePrefix) || strings.Contains(sealed[key], "s3cr3t") {
+			t.Errorf("value of %q is not encrypted: %q", key, sealed[key])
+		}
+	}
+
+	opened, err := Open(ctx, w, toBytes(sealed))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if len(opened) != 2 || string(opened["ClientSecret"]) != "s3cr3t" || string(opened["empty"]) != "" {
+		t.Errorf("Open = %q, want the original data", opened)
+	}
+}
+
+func TestSealUsesFreshKeys(t *testing.T) {
+	ctx := context.Background()
+	w := newTestWrapper(t, 1)
+	a, err := Seal(ctx, w, map[string]string{"k": "v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Seal(ctx, w, map[string]string{"k": "v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a["k"] == b["k"] || a[HeaderKey] == b[HeaderKey] {
+		t.Error("expected a new data encryption key and nonce per seal")
+	}
+}
+
+func TestSealRejectsHeaderKey(t *testing.T) {
+	if _, err := Seal(context.Background(), newTestWrapper(t, 1), map[string]string{HeaderKey: "x"}); err == nil {
+		t.Fatal("expected error for the reserved header key")
+	}
+}
+
+func TestOpenDetectsTampering(t *testing.T) {
+	ctx := context.Background()
+	w := newTestWrapper(t, 1)
+	sealed, err := Seal(ctx, w, map[string]string{"user": "alice", "password": "hunter2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	swapped := toBytes(sealed)
+	swapped["user"], swapped["password"] = swapped["password"], swapped["user"]
+	if _, err := Open(ctx, w, swapped); err == nil {
+		t.Error("expected error for values swapped between keys")
+	}
+
+	if _, err := Open(ctx, newTestWrapper(t, 2), toBytes(sealed)); err == nil ||
+		!strings.Contains(err.Error(), "unknown key encryption key") {
+		t.Errorf("expected unknown key error, got %v", err)
+	}
+}
+
+func TestOpenPassesThroughPlainValues(t *testing.T) {
+	ctx := context.Background()
+	w := newTestWrapper(t, 1)
+
+	plain := map[string][]byte{"k": []byte("v")}
+	if opened, err := Open(ctx, w, plain); err != nil || string(opened["k"]) != "v" {
+		t.Errorf("Open without header = %q, %v", opened, err)
+	}
+
+	sealed, err := Seal(ctx, w, map[string]string{"k": "v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := toBytes(sealed)
+	data["extra"] = []byte("added by someone else")
+	opened, err := Open(ctx, w, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(opened["extra"]) != "added by someone else" {
+		t.Errorf("extra = %q, want it unchanged", opened["extra"])
+	}
+}
+
+func TestAESKeyWrapperRotation(t *testing.T) {
+	ctx := context.Background()
+	oldKey, newKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+
+	old, err := NewAESKeyWrapper(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := Seal(ctx, old, map[string]string{"k": "v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := NewAESKeyWrapper(newKey, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened, err := Open(ctx, rotated, toBytes(sealed)); err != nil || string(opened["k"]) != "v" {
+		t.Fatalf("Open with rotated keys = %q, %v", opened, err)
+	}
+	if _, keyID, err := rotated.WrapKey(ctx, make([]byte, 32)); err != nil || keyID != KeyFingerprint(newKey) {
+		t.Errorf("WrapKey used key %q, want the first key %q", keyID, KeyFingerprint(newKey))
+	}
+}
+
+func TestLoadAESKeyWrapper(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	dir := t.TempDir()
+	rawPath := filepath.Join(dir, "raw")
+	encodedPath := filepath.Join(dir, "encoded")
+	if err := os.WriteFile(rawPath, key, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(encodedPath, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{rawPath, encodedPath} {
+		w, err := LoadAESKeyWrapper(path)
+		if err != nil {
+			t.Fatalf("LoadAESKeyWrapper(%s): %v", filepath.Base(path), err)
+		}
+		if w.ids[0] != KeyFingerprint(key) {
+			t.Errorf("%s: loaded a different key", filepath.Base(path))
+		}
+	}
+
+	short := filepath.Join(dir, "short")
+	if err := os.WriteFile(short, []byte("c2hvcnQ="), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadAESKeyWrapper(short); err == nil {
+		t.Error("expected error for a short key")
+	}
+}
@@ -0,0 +1,142 @@
+package framework_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/framework/envelope"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestKeyWrapper(t *testing.T) *envelope.AESKeyWrapper {
+	t.Helper()
+	w, err := envelope.NewAESKeyWrapper(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+// openSecret decrypts the string data written to secret.
+func openSecret(t *testing.T, w envelope.KeyWrapper, secret *corev1.Secret) map[string][]byte {
+	t.Helper()
+	data := make(map[string][]byte, len(secret.StringData))
+	for k, v := range secret.StringData {
+		data[k] = []byte(v)
+	}
+	opened, err := envelope.Open(context.Background(), w, data)
+	if err != nil {
+		t.Fatalf("opening secret %s: %v", secret.Name, err)
+	}
+	return opened
+}
+
+func TestReconcile_EncryptsOutputSecret(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	w := newTestKeyWrapper(t)
+	r := &framework.Reconciler[*testObject]{
+		Client:   c,
+		Scheme:   scheme,
+		Provider: &dataProvider{data: map[string]string{"ClientSecret": "s3cr3t"}},
+		Envelope: w,
+	}
+
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	var secret corev1.Secret
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "app-secret"}, &secret); err != nil {
+		t.Fatal(err)
+	}
+	if v := secret.StringData["ClientSecret"]; !strings.HasPrefix(v, envelope.ValuePrefix) {
+		t.Errorf("expected an encrypted value, got %q", v)
+	}
+	if _, ok := secret.StringData[envelope.HeaderKey]; !ok {
+		t.Errorf("expected the envelope header, got keys %v", secret.StringData)
+	}
+	if got := openSecret(t, w, &secret); string(got["ClientSecret"]) != "s3cr3t" {
+		t.Errorf("decrypted ClientSecret = %q, want s3cr3t", got["ClientSecret"])
+	}
+}
+
+func TestReconcile_SplitEncryptedSecretsCarryHeader(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	w := newTestKeyWrapper(t)
+	value := strings.Repeat("x", 100)
+	r := &framework.Reconciler[*testObject]{
+		Client:                c,
+		Scheme:                scheme,
+		Provider:              &dataProvider{data: map[string]string{"A": value, "B": value}},
+		Envelope:              w,
+		MaxSecretSize:         400,
+		SplitOversizedSecrets: true,
+	}
+
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	for _, name := range []string{"app-secret", "app-secret-1"} {
+		var secret corev1.Secret
+		if err := c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: name}, &secret); err != nil {
+			t.Fatal(err)
+		}
+		got := openSecret(t, w, &secret)
+		if len(got) != 1 {
+			t.Errorf("expected one key in %s, got %v", name, got)
+		}
+		for k, v := range got {
+			if string(v) != value {
+				t.Errorf("%s: decrypted %s does not match", name, k)
+			}
+		}
+	}
+}
+
+func TestReconcile_RemovesStaleEnvelopeHeader(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-secret", Namespace: "default"},
+		Data:       map[string][]byte{envelope.HeaderKey: []byte(`{"version":1}`)},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{
+		Client:   c,
+		Scheme:   scheme,
+		Provider: &dataProvider{data: map[string]string{"ClientSecret": "s3cr3t"}},
+	}
+
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	var got corev1.Secret
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(secret), &got); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got.Data[envelope.HeaderKey]; ok {
+		t.Error("expected the stale envelope header to be removed")
+	}
+	if got.StringData["ClientSecret"] != "s3cr3t" {
+		t.Errorf("expected the plain value, got %q", got.StringData["ClientSecret"])
+	}
+}
@@ -9,8 +9,41 @@
     }:
     let
       valet = config.valet.lib;
+
+      kubectl-valet = valet.mkGoModule {
+        pname = "kubectl-valet";
+        subPackages = [ "framework/cmd/kubectl-valet" ];
+        meta.mainProgram = "kubectl-valet";
+      };
+
+      kubectl-valet-image = valet.mkImage {
+        name = "kubectl-valet";
+        entrypoint = "${kubectl-valet}/bin/kubectl-valet";
+        contents = [ ];
+      };
+
+      valet-decrypt = valet.mkGoModule {
+        pname = "valet-decrypt";
+        subPackages = [ "framework/cmd/valet-decrypt" ];
+        meta.mainProgram = "valet-decrypt";
+      };
+
+      valet-decrypt-image = valet.mkImage {
+        name = "valet-decrypt";
+        entrypoint = "${valet-decrypt}/bin/valet-decrypt";
+        contents = [ ];
+      };
     in
     {
+      packages = {
+        inherit
+          kubectl-valet
+          kubectl-valet-image
+          valet-decrypt
+          valet-decrypt-image
+          ;
+      };
+
       checks.framework-test = valet.withPackageEnv self'.packages.provider-mock {
         name = "framework-test";
         extraBuildInputs = [ pkgs.gotestsum ];
@@ -0,0 +1,269 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// KeyStore persists the [ActiveKeys] bookkeeping of an object outside its
+// status. Resources with long key histories can otherwise push the CRD
+// towards the etcd object size limit. When a [Reconciler] is configured
+// with a KeyStore, the keys are loaded into the in-memory status after
+// every fetch and stripped from the status before it is written back.
+type KeyStore interface {
+	// Load returns the keys stored for obj. It returns an empty list
+	// (not an error) when nothing has been stored yet.
+	Load(ctx context.Context, obj Object) (ActiveKeys, error)
+
+	// Save replaces the keys stored for obj.
+	Save(ctx context.Context, obj Object, keys ActiveKeys) error
+}
+
+// ConfigMapKeySuffix is appended to the object name and a short prefix of
+// its UID to form the name of the first ConfigMap used by
+// [ConfigMapKeyStore]. Further shards are suffixed with -1, -2 and so on.
+const ConfigMapKeySuffix = "-valet-keys"
+
+// configMapUIDLength is the length of the UID prefix in the ConfigMap names
+// of [ConfigMapKeyStore], which keeps objects of different kinds but with
+// the same name apart.
+const configMapUIDLength = 8
+
+// ErrKeyStoreConflict is returned by [ConfigMapKeyStore] when a ConfigMap it
+// would use for an object is controlled by another one. The Ready condition
+// then has reason [ReasonKeyStoreConflict].
+var ErrKeyStoreConflict = errors.New("key store conflict")
+
+// DefaultMaxKeyShardSize is the default size of the encoded keys held by
+// one ConfigMap of a [ConfigMapKeyStore], well below the 1MiB object limit.
+const DefaultMaxKeyShardSize = 512 << 10
+
+const (
+	// configMapKeysField is the ConfigMap data key holding the
+	// JSON-encoded keys of a shard.
+	configMapKeysField = "activeKeys.json"
+
+	// configMapShardsField is the data key of the first ConfigMap holding
+	// the number of shards.
+	configMapShardsField = "shards"
+)
+
+// ConfigMapKeyStore is a [KeyStore] that keeps the keys of each object in
+// ConfigMaps next to it, sharded so that no ConfigMap exceeds MaxShardSize.
+// The ConfigMaps are owned by the object and are garbage-collected together
+// with it.
+type ConfigMapKeyStore struct {
+	Client client.Client
+	Scheme *runtime.Scheme
+
+	// Reader, if set, reads the ConfigMaps instead of Client, which then
+	// only writes them. It should be uncached, e.g. the API reader of the
+	// manager, so that keys saved just before are not read stale and lost,
+	// and so that not all ConfigMaps of the cluster are cached.
+	Reader client.Reader
+
+	// Provider names the provider in the [LabelProvider] label of the
+	// ConfigMaps. Unset, the label is left out.
+	Provider string
+
+	// MaxShardSize limits the size of the encoded keys held by one
+	// ConfigMap. It defaults to [DefaultMaxKeyShardSize].
+	MaxShardSize int
+}
+
+// Load reads the keys from the object's ConfigMaps. It returns
+// [ErrKeyStoreConflict] if one of them is controlled by another object,
+// rather than taking over that object's keys.
+func (s *ConfigMapKeyStore) Load(ctx context.Context, obj Object) (ActiveKeys, error) {
+	var keys ActiveKeys
+	shards := 1
+	for i := 0; i < shards; i++ {
+		var cm corev1.ConfigMap
+		if err := s.reader().Get(ctx, s.key(obj, i), &cm); err != nil {
+			if apierrors.IsNotFound(err) && i == 0 {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("getting key store configmap: %w", err)
+		}
+		if !metav1.IsControlledBy(&cm, obj) {
+			return nil, fmt.Errorf("%w: configmap %s is not controlled by %s", ErrKeyStoreConflict, cm.Name, obj.GetName())
+		}
+		if i == 0 {
+			if n, ok := cm.Data[configMapShardsField]; ok {
+				var err error
+				if shards, err = strconv.Atoi(n); err != nil || shards < 1 {
+					return nil, fmt.Errorf("key store configmap %s: invalid shard count %q", cm.Name, n)
+				}
+			}
+		}
+
+		raw, ok := cm.Data[configMapKeysField]
+		if !ok || raw == "" {
+			continue
+		}
+		var shard ActiveKeys
+		if err := json.Unmarshal([]byte(raw), &shard); err != nil {
+			return nil, fmt.Errorf("decoding key store configmap %s: %w", cm.Name, err)
+		}
+		keys = append(keys, shard...)
+	}
+	return keys, nil
+}
+
+// Save writes the keys to the object's ConfigMaps, creating them if needed,
+// and deletes the shards no longer needed.
+func (s *ConfigMapKeyStore) Save(ctx context.Context, obj Object, keys ActiveKeys) error {
+	shards, err := s.shard(keys)
+	if err != nil {
+		return err
+	}
+
+	// The later shards are written first, so that the count in the first
+	// one never refers to shards that do not exist yet.
+	for i := len(shards) - 1; i >= 0; i-- {
+		data := map[string]string{configMapKeysField: string(shards[i])}
+		if i == 0 {
+			data[configMapShardsField] = strconv.Itoa(len(shards))
+		}
+		if err := s.write(ctx, obj, i, data); err != nil {
+			return err
+		}
+	}
+	return s.deleteStale(ctx, obj, len(shards))
+}
+
+// shard encodes keys into as few JSON lists of at most MaxShardSize bytes as
+// filling them in order allows. There is always at least one list.
+func (s *ConfigMapKeyStore) shard(keys ActiveKeys) ([][]byte, error) {
+	limit := s.MaxShardSize
+	if limit <= 0 {
+		limit = DefaultMaxKeyShardSize
+	}
+	var shards [][]byte
+	current := []byte("[")
+	for _, k := range keys {
+		raw, err := json.Marshal(k)
+		if err != nil {
+			return nil, fmt.Errorf("encoding keys: %w", err)
+		}
+		if len(current) > 1 && len(current)+len(raw)+2 > limit {
+			shards = append(shards, append(current, ']'))
+			current = []byte("[")
+		}
+		if len(current) > 1 {
+			current = append(current, ',')
+		}
+		current = append(current, raw...)
+	}
+	return append(shards, append(current, ']')), nil
+}
+
+func (s *ConfigMapKeyStore) write(ctx context.Context, obj Object, i int, data map[string]string) error {
+	key := s.key(obj, i)
+	cm := &corev1.ConfigMap{}
+	if err := s.reader().Get(ctx, key, cm); apierrors.IsNotFound(err) {
+		cm.ObjectMeta = metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}
+	} else if err != nil {
+		return fmt.Errorf("getting key store configmap %s: %w", key.Name, err)
+	}
+
+	existing := cm.DeepCopy()
+	err := controllerutil.SetControllerReference(obj, cm, s.Scheme)
+	if alreadyOwned := (*controllerutil.AlreadyOwnedError)(nil); errors.As(err, &alreadyOwned) {
+		return fmt.Errorf("%w: configmap %s is controlled by %s %s",
+			ErrKeyStoreConflict, key.Name, alreadyOwned.Owner.Kind, alreadyOwned.Owner.Name)
+	} else if err != nil {
+		return err
+	}
+	if cm.Labels == nil {
+		cm.Labels = map[string]string{}
+	}
+	maps.Copy(cm.Labels, StandardLabels(obj, s.Scheme, s.Provider))
+	cm.Data = data
+
+	switch {
+	case cm.ResourceVersion == "":
+		err = s.Client.Create(ctx, cm)
+	case !equality.Semantic.DeepEqual(existing, cm):
+		err = s.Client.Update(ctx, cm)
+	}
+	if err != nil {
+		return fmt.Errorf("writing key store configmap %s: %w", key.Name, err)
+	}
+	return nil
+}
+
+// deleteStale deletes the shards of obj from the given index on, left over
+// from when the keys needed more of them. It stops at the first shard that
+// does not exist or is not controlled by obj.
+func (s *ConfigMapKeyStore) deleteStale(ctx context.Context, obj Object, from int) error {
+	for i := from; ; i++ {
+		var cm corev1.ConfigMap
+		if err := s.reader().Get(ctx, s.key(obj, i), &cm); apierrors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("getting key store configmap: %w", err)
+		}
+		if !metav1.IsControlledBy(&cm, obj) {
+			return nil
+		}
+		if err := s.Client.Delete(ctx, &cm); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("deleting stale key store configmap %s: %w", cm.Name, err)
+		}
+	}
+}
+
+// reader returns the client reading the ConfigMaps, see Reader.
+func (s *ConfigMapKeyStore) reader() client.Reader {
+	if s.Reader != nil {
+		return s.Reader
+	}
+	return s.Client
+}
+
+// key returns the name of the i-th ConfigMap of obj. The object name is
+// truncated so that the name stays a valid DNS subdomain; the UID prefix
+// keeps truncated names apart.
+func (s *ConfigMapKeyStore) key(obj Object, i int) client.ObjectKey {
+	uid := string(obj.GetUID())
+	suffix := "-" + uid[:min(len(uid), configMapUIDLength)] + ConfigMapKeySuffix
+	if i > 0 {
+		suffix += "-" + strconv.Itoa(i)
+	}
+	name := obj.GetName()
+	if limit := validation.DNS1123SubdomainMaxLength - len(suffix); len(name) > limit {
+		name = strings.TrimRight(name[:limit], ".-")
+	}
+	return client.ObjectKey{Namespace: obj.GetNamespace(), Name: name + suffix}
+}
+
+// mergeKeys appends the keys from extra that are not already present in
+// keys (by KeyID). It is used to migrate keys recorded in the status before a
+// [KeyStore] was configured.
+func mergeKeys(keys, extra ActiveKeys) ActiveKeys {
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		seen[k.KeyID] = true
+	}
+	for _, k := range extra {
+		if !seen[k.KeyID] {
+			keys = append(keys, k)
+			seen[k.KeyID] = true
+		}
+	}
+	return keys
+}
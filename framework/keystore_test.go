@@ -0,0 +1,374 @@
+package framework_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+var testGV = schema.GroupVersion{Group: "test.valet.ngl.cx", Version: "v1"}
+
+// testObject is a minimal [framework.Object] for exercising framework code
+// against a fake client.
+type testObject struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	SecretRef      framework.SecretReference    `json:"secretRef"`
+	Rotation       framework.RotationPolicy     `json:"rotation,omitzero"`
+	Suspend        bool                         `json:"suspend,omitempty"`
+	DeletionPolicy framework.DeletionPolicy     `json:"deletionPolicy,omitempty"`
+	SecretTemplate framework.SecretTemplate     `json:"secretTemplate,omitzero"`
+	Targets        framework.OutputTargets      `json:"targets,omitempty"`
+	Replication    *framework.Replication       `json:"replication,omitempty"`
+	Status         framework.ClientSecretStatus `json:"status,omitempty"`
+}
+
+func (o *testObject) GetSecretRef() framework.SecretReference     { return o.SecretRef }
+func (o *testObject) GetStatus() *framework.ClientSecretStatus    { return &o.Status }
+func (o *testObject) Validate() error                             { return nil }
+func (o *testObject) GetRotationPolicy() framework.RotationPolicy { return o.Rotation }
+func (o *testObject) IsSuspended() bool                           { return o.Suspend }
+func (o *testObject) GetDeletionPolicy() framework.DeletionPolicy { return o.DeletionPolicy }
+func (o *testObject) GetSecretTemplate() framework.SecretTemplate { return o.SecretTemplate }
+func (o *testObject) GetTargets() framework.OutputTargets         { return o.Targets }
+func (o *testObject) GetReplication() *framework.Replication      { return o.Replication }
+
+func (o *testObject) DeepCopyObject() runtime.Object {
+	out := *o
+	o.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Status = o.Status.DeepCopy()
+	out.SecretTemplate = o.SecretTemplate.DeepCopy()
+	out.Targets = o.Targets.DeepCopy()
+	out.Replication = o.Replication.DeepCopy()
+	return &out
+}
+
+// testObjectList is the list type of [testObject].
+type testObjectList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []testObject `json:"items"`
+}
+
+func (l *testObjectList) DeepCopyObject() runtime.Object {
+	out := *l
+	out.Items = make([]testObject, len(l.Items))
+	for i := range l.Items {
+		out.Items[i] = *l.Items[i].DeepCopyObject().(*testObject)
+	}
+	return &out
+}
+
+// otherTestObject is a [testObject] of another kind, for objects of
+// different kinds with the same name.
+type otherTestObject struct {
+	testObject `json:",inline"`
+}
+
+func (o *otherTestObject) DeepCopyObject() runtime.Object {
+	return &otherTestObject{testObject: *o.testObject.DeepCopyObject().(*testObject)}
+}
+
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	scheme.AddKnownTypes(testGV, &testObject{}, &testObjectList{}, &otherTestObject{})
+	metav1.AddToGroupVersion(scheme, testGV)
+	return scheme
+}
+
+func newTestObject() *testObject {
+	return &testObject{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", UID: "uid-1"},
+		SecretRef:  framework.SecretReference{Name: "app-secret"},
+	}
+}
+
+func TestConfigMapKeyStore_LoadMissing(t *testing.T) {
+	scheme := newTestScheme(t)
+	store := &framework.ConfigMapKeyStore{
+		Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+		Scheme: scheme,
+	}
+
+	keys, err := store.Load(context.Background(), newTestObject())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("expected no keys, got %d", len(keys))
+	}
+}
+
+func TestConfigMapKeyStore_SaveLoad(t *testing.T) {
+	scheme := newTestScheme(t)
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	store := &framework.ConfigMapKeyStore{Client: c, Scheme: scheme}
+	obj := newTestObject()
+	ctx := context.Background()
+
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	want := framework.ActiveKeys{
+		{KeyID: "k1", CreatedAt: now, ExpiresAt: metav1.NewTime(now.Add(time.Hour))},
+		{KeyID: "k2", CreatedAt: now, ExpiresAt: metav1.NewTime(now.Add(2 * time.Hour))},
+	}
+	if err := store.Save(ctx, obj, want); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	// Saving again must update rather than fail on the existing ConfigMap.
+	if err := store.Save(ctx, obj, want[1:]); err != nil {
+		t.Fatalf("second save: %v", err)
+	}
+
+	got, err := store.Load(ctx, obj)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(got) != 1 || got[0].KeyID != "k2" || !got[0].ExpiresAt.Equal(&want[1].ExpiresAt) {
+		t.Errorf("unexpected keys: %+v", got)
+	}
+
+	var cm corev1.ConfigMap
+	key := client.ObjectKey{Namespace: "default", Name: "app-uid-1" + framework.ConfigMapKeySuffix}
+	if err := c.Get(ctx, key, &cm); err != nil {
+		t.Fatalf("get configmap: %v", err)
+	}
+	owner := metav1.GetControllerOf(&cm)
+	if owner == nil || owner.UID != obj.UID {
+		t.Errorf("expected configmap to be controlled by the object, got %+v", owner)
+	}
+}
+
+func TestConfigMapKeyStore_Shards(t *testing.T) {
+	scheme := newTestScheme(t)
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	store := &framework.ConfigMapKeyStore{Client: c, Scheme: scheme, MaxShardSize: 300}
+	obj := newTestObject()
+	ctx := context.Background()
+
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	var want framework.ActiveKeys
+	for _, id := range []string{"k1", "k2", "k3", "k4", "k5"} {
+		want = append(want, framework.ActiveKey{KeyID: id, CreatedAt: now, ExpiresAt: metav1.NewTime(now.Add(time.Hour))})
+	}
+	if err := store.Save(ctx, obj, want); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	var cms corev1.ConfigMapList
+	if err := c.List(ctx, &cms, client.InNamespace("default")); err != nil {
+		t.Fatal(err)
+	}
+	if len(cms.Items) < 2 {
+		t.Fatalf("expected the keys to be sharded, got %d configmaps", len(cms.Items))
+	}
+	for _, cm := range cms.Items {
+		if n := len(cm.Data["activeKeys.json"]); n > 300 {
+			t.Errorf("configmap %s holds %d bytes of keys, want at most 300", cm.Name, n)
+		}
+	}
+
+	got, err := store.Load(ctx, obj)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d keys, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].KeyID != want[i].KeyID {
+			t.Errorf("key %d: got %q, want %q", i, got[i].KeyID, want[i].KeyID)
+		}
+	}
+
+	// Fewer keys need fewer shards; the others are deleted.
+	if err := store.Save(ctx, obj, want[:1]); err != nil {
+		t.Fatalf("second save: %v", err)
+	}
+	if err := c.List(ctx, &cms, client.InNamespace("default")); err != nil {
+		t.Fatal(err)
+	}
+	if len(cms.Items) != 1 || cms.Items[0].Name != "app-uid-1"+framework.ConfigMapKeySuffix {
+		t.Fatalf("expected only the first shard to be left, got %d configmaps", len(cms.Items))
+	}
+	if got, err := store.Load(ctx, obj); err != nil || len(got) != 1 || got[0].KeyID != "k1" {
+		t.Fatalf("got keys %+v, err %v after shrinking", got, err)
+	}
+}
+
+func TestConfigMapKeyStore_LongName(t *testing.T) {
+	scheme := newTestScheme(t)
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	store := &framework.ConfigMapKeyStore{Client: c, Scheme: scheme, MaxShardSize: 100}
+	obj := newTestObject()
+	// Truncated for the first ConfigMap, the name would end in a dot.
+	obj.Name = strings.Repeat("a", 235) + "." + strings.Repeat("b", 17)
+	ctx := context.Background()
+
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	var want framework.ActiveKeys
+	for _, id := range []string{"k1", "k2", "k3"} {
+		want = append(want, framework.ActiveKey{KeyID: id, CreatedAt: now})
+	}
+	if err := store.Save(ctx, obj, want); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	var cms corev1.ConfigMapList
+	if err := c.List(ctx, &cms, client.InNamespace("default")); err != nil {
+		t.Fatal(err)
+	}
+	if len(cms.Items) < 2 {
+		t.Fatalf("expected the keys to be sharded, got %d configmaps", len(cms.Items))
+	}
+	for _, cm := range cms.Items {
+		if errs := validation.IsDNS1123Subdomain(cm.Name); len(errs) != 0 {
+			t.Errorf("invalid configmap name %s: %v", cm.Name, errs)
+		}
+	}
+	if got, err := store.Load(ctx, obj); err != nil || len(got) != len(want) {
+		t.Fatalf("got keys %+v, err %v", got, err)
+	}
+}
+
+func TestConfigMapKeyStore_Reader(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	ctx := context.Background()
+
+	// The keys are read with the reader, not with the (stale) client.
+	reader := fake.NewClientBuilder().WithScheme(scheme).Build()
+	stored := &framework.ConfigMapKeyStore{Client: reader, Scheme: scheme}
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	if err := stored.Save(ctx, obj, framework.ActiveKeys{{KeyID: "k1", CreatedAt: now}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	store := &framework.ConfigMapKeyStore{
+		Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+		Reader: reader,
+		Scheme: scheme,
+	}
+	if got, err := store.Load(ctx, obj); err != nil || len(got) != 1 || got[0].KeyID != "k1" {
+		t.Fatalf("got keys %+v, err %v, want k1 from the reader", got, err)
+	}
+}
+
+func TestConfigMapKeyStore_SameNameOtherKind(t *testing.T) {
+	scheme := newTestScheme(t)
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	store := &framework.ConfigMapKeyStore{Client: c, Scheme: scheme}
+	obj := newTestObject()
+	other := &otherTestObject{testObject: *newTestObject()}
+	other.UID = "uid-2"
+	ctx := context.Background()
+
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	if err := store.Save(ctx, obj, framework.ActiveKeys{{KeyID: "k1", CreatedAt: now}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := store.Save(ctx, other, framework.ActiveKeys{{KeyID: "k2", CreatedAt: now}}); err != nil {
+		t.Fatalf("save of the other kind: %v", err)
+	}
+
+	for _, tc := range []struct {
+		obj  framework.Object
+		want string
+	}{{obj, "k1"}, {other, "k2"}} {
+		got, err := store.Load(ctx, tc.obj)
+		if err != nil {
+			t.Fatalf("load: %v", err)
+		}
+		if len(got) != 1 || got[0].KeyID != tc.want {
+			t.Errorf("%T: expected only key %s, got %+v", tc.obj, tc.want, got)
+		}
+	}
+}
+
+func TestConfigMapKeyStore_Conflict(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	other := &otherTestObject{testObject: *newTestObject()}
+	other.UID = "uid-2"
+	ctx := context.Background()
+
+	// The ConfigMap the keys of obj would be stored in is controlled by
+	// another object.
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-uid-1" + framework.ConfigMapKeySuffix},
+		Data:       map[string]string{"activeKeys.json": `[{"keyId":"foreign"}]`},
+	}
+	if err := controllerutil.SetControllerReference(other, cm, scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm).Build()
+	store := &framework.ConfigMapKeyStore{Client: c, Scheme: scheme}
+
+	if keys, err := store.Load(ctx, obj); !errors.Is(err, framework.ErrKeyStoreConflict) {
+		t.Errorf("expected a conflict on load, got keys %+v, err %v", keys, err)
+	}
+	if err := store.Save(ctx, obj, nil); !errors.Is(err, framework.ErrKeyStoreConflict) {
+		t.Errorf("expected a conflict on save, got %v", err)
+	}
+}
+
+func TestReconcile_KeyStoreConflict(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	other := &otherTestObject{testObject: *newTestObject()}
+	other.UID = "uid-2"
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-uid-1" + framework.ConfigMapKeySuffix},
+	}
+	if err := controllerutil.SetControllerReference(other, cm, scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, cm).
+		WithStatusSubresource(obj).WithInterceptorFuncs(mergeStringData).Build()
+	provider := &countingProvider{}
+	r := &framework.Reconciler[*testObject]{
+		Client: c, Scheme: scheme, Provider: provider,
+		KeyStore: &framework.ConfigMapKeyStore{Client: c, Scheme: scheme},
+	}
+
+	result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+	if err != nil {
+		t.Fatalf("expected the conflict to be reported in the status, got %v", err)
+	}
+	if result.RequeueAfter != framework.ConflictBackoff {
+		t.Errorf("expected a requeue after %s, got %+v", framework.ConflictBackoff, result)
+	}
+	if provider.provisions != 0 {
+		t.Errorf("expected no provisioning, got %d calls", provider.provisions)
+	}
+
+	var got testObject
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), &got); err != nil {
+		t.Fatal(err)
+	}
+	ready := meta.FindStatusCondition(got.Status.Conditions, framework.ConditionReady)
+	if ready == nil || ready.Reason != framework.ReasonKeyStoreConflict {
+		t.Errorf("expected reason %s, got %+v", framework.ReasonKeyStoreConflict, ready)
+	}
+}
@@ -0,0 +1,91 @@
+package framework
+
+import (
+	"maps"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// StandardLabels returns the labels valet sets on all objects it creates for
+// obj, so that they can be listed with
+// -l app.kubernetes.io/managed-by=valet: [LabelManagedBy], [LabelProvider]
+// unless provider is empty, and the labels identifying obj. The kind is
+// looked up in scheme and left out if it is not found, as is the name if it
+// is too long for a label value.
+func StandardLabels(obj Object, scheme *runtime.Scheme, provider string) map[string]string {
+	labels := map[string]string{
+		LabelManagedBy:      ManagedBy,
+		LabelOwnerNamespace: obj.GetNamespace(),
+	}
+	if provider != "" {
+		labels[LabelProvider] = provider
+	}
+	if gvk, err := apiutil.GVKForObject(obj, scheme); err == nil {
+		labels[LabelOwnerKind] = gvk.Kind
+	}
+	if len(validation.IsValidLabelValue(obj.GetName())) == 0 {
+		labels[LabelOwnerName] = obj.GetName()
+	}
+	return labels
+}
+
+// setStandardLabels sets the [StandardLabels] of obj on the object with the
+// metadata m.
+func (r *Reconciler[O]) setStandardLabels(obj O, m metav1.Object) {
+	labels := m.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	maps.Copy(labels, StandardLabels(obj, r.Scheme, r.ProviderName))
+	m.SetLabels(labels)
+}
+
+// labelMatcher matches label keys against the entries of
+// [Reconciler.PropagateLabels].
+type labelMatcher []string
+
+// matches reports whether key is listed, or starts with the prefix of an
+// entry ending in "*". Empty entries never match.
+func (m labelMatcher) matches(key string) bool {
+	for _, entry := range m {
+		if prefix, ok := strings.CutSuffix(entry, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if entry != "" && key == entry {
+			return true
+		}
+	}
+	return false
+}
+
+// propagateLabels copies the labels of obj selected by
+// [Reconciler.PropagateLabels] to secret. Selected labels the object no
+// longer has are removed from the secret; other labels are left alone.
+func (r *Reconciler[O]) propagateLabels(obj O, secret *corev1.Secret) {
+	m := labelMatcher(r.PropagateLabels)
+	if len(m) == 0 {
+		return
+	}
+
+	labels := obj.GetLabels()
+	for key := range secret.Labels {
+		if _, ok := labels[key]; !ok && m.matches(key) {
+			delete(secret.Labels, key)
+		}
+	}
+	for key, value := range labels {
+		if !m.matches(key) {
+			continue
+		}
+		if secret.Labels == nil {
+			secret.Labels = map[string]string{}
+		}
+		secret.Labels[key] = value
+	}
+}
@@ -0,0 +1,89 @@
+package framework_test
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcile_PropagatesLabels(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	obj.Labels = map[string]string{
+		"team":                    "payments",
+		"cost.example.com/center": "42",
+		"app":                     "web",
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app-secret",
+			Namespace: "default",
+			Labels: map[string]string{
+				"cost.example.com/stale": "x",
+				"managed-by":             "gitops",
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{
+		Client:          c,
+		Scheme:          scheme,
+		Provider:        &dataProvider{data: map[string]string{"KEY": "value"}},
+		PropagateLabels: []string{"team", "cost.example.com/*", ""},
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	var got corev1.Secret
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(secret), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"team":                    "payments",
+		"cost.example.com/center": "42",
+		"managed-by":              "gitops",
+
+		framework.LabelManagedBy:      framework.ManagedBy,
+		framework.LabelOwnerKind:      "testObject",
+		framework.LabelOwnerNamespace: "default",
+		framework.LabelOwnerName:      "app",
+	}
+	if !maps.Equal(got.Labels, want) {
+		t.Errorf("expected labels %v, got %v", want, got.Labels)
+	}
+}
+
+func TestStandardLabels(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+
+	got := framework.StandardLabels(obj, scheme, "provider-mock")
+	want := map[string]string{
+		framework.LabelManagedBy:      framework.ManagedBy,
+		framework.LabelProvider:       "provider-mock",
+		framework.LabelOwnerKind:      "testObject",
+		framework.LabelOwnerNamespace: "default",
+		framework.LabelOwnerName:      "app",
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("expected labels %v, got %v", want, got)
+	}
+
+	// Names too long for a label value are left out.
+	obj.Name = strings.Repeat("a", 64)
+	if _, ok := framework.StandardLabels(obj, scheme, "")[framework.LabelOwnerName]; ok {
+		t.Error("expected a long name to be left out")
+	}
+}
@@ -0,0 +1,47 @@
+package framework
+
+import (
+	"fmt"
+	"maps"
+)
+
+// LayoutFiles is the value of [AnnotationOutputLayout] that adds the
+// [Result.Fields] of the credentials to the output secret, next to the
+// rendered templates.
+const LayoutFiles = "files"
+
+// Well-known credential fields of [Result.Fields]. They are named after the
+// file-style keys that common charts, e.g. Bitnami's and StackGres', read
+// from existing Secrets.
+const (
+	// FieldUsername is the username or other identifier to authenticate as.
+	FieldUsername = "username"
+	// FieldPassword is the password or other secret to authenticate with.
+	FieldPassword = "password"
+	// FieldCACert is the PEM-encoded CA certificate to verify the server or
+	// issued certificates with.
+	FieldCACert = "ca.crt"
+)
+
+// validateLayout checks the [AnnotationOutputLayout] of obj.
+func validateLayout(obj Object) error {
+	switch layout := obj.GetAnnotations()[AnnotationOutputLayout]; layout {
+	case "", LayoutFiles:
+		return nil
+	default:
+		return fmt.Errorf("unknown output layout %q in annotation %s, expected %q",
+			layout, AnnotationOutputLayout, LayoutFiles)
+	}
+}
+
+// outputData returns the data of the output secret of obj for result: the
+// rendered templates and, with [LayoutFiles], the [Result.Fields]. Rendered
+// templates take precedence over fields with the same key.
+func outputData(obj Object, result *Result) map[string]string {
+	if obj.GetAnnotations()[AnnotationOutputLayout] != LayoutFiles || len(result.Fields) == 0 {
+		return result.StringData
+	}
+	data := maps.Clone(result.Fields)
+	maps.Copy(data, result.StringData)
+	return data
+}
@@ -0,0 +1,101 @@
+package framework_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fieldsProvider is a [stubProvider] whose results have a rendered template
+// and well-known fields.
+type fieldsProvider struct {
+	stubProvider
+}
+
+func (p *fieldsProvider) Provision(context.Context, *testObject) (*framework.Result, error) {
+	now := time.Now()
+	return &framework.Result{
+		StringData: map[string]string{"DSN": "app:s3cret@db", framework.FieldPassword: "from-template"},
+		Fields: map[string]string{
+			framework.FieldUsername: "app",
+			framework.FieldPassword: "s3cret",
+		},
+		KeyID:         "new",
+		ProvisionedAt: now,
+		ValidUntil:    now.Add(time.Hour),
+	}, nil
+}
+
+func TestReconcile_OutputLayout(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		layout   string
+		want     map[string]string
+		wantFail bool
+	}{
+		{
+			name: "templates only",
+			want: map[string]string{"DSN": "app:s3cret@db", "password": "from-template"},
+		},
+		{
+			name:   "files",
+			layout: framework.LayoutFiles,
+			want:   map[string]string{"DSN": "app:s3cret@db", "password": "from-template", "username": "app"},
+		},
+		{name: "unknown", layout: "bitnami", wantFail: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := newTestScheme(t)
+			obj := newTestObject()
+			obj.Finalizers = []string{framework.Finalizer}
+			if tt.layout != "" {
+				obj.Annotations = map[string]string{framework.AnnotationOutputLayout: tt.layout}
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+			r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: &fieldsProvider{}}
+
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+			if _, err := r.Reconcile(context.Background(), req); err != nil {
+				t.Fatalf("reconcile: %v", err)
+			}
+
+			if tt.wantFail {
+				var got testObject
+				if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+					t.Fatal(err)
+				}
+				if got.Status.Phase != framework.PhaseFailed {
+					t.Errorf("expected phase %s, got %s", framework.PhaseFailed, got.Status.Phase)
+				}
+				return
+			}
+
+			var secret corev1.Secret
+			key := client.ObjectKey{Namespace: obj.Namespace, Name: obj.SecretRef.Name}
+			if err := c.Get(context.Background(), key, &secret); err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]string{}
+			for k, v := range secret.Data {
+				got[k] = string(v)
+			}
+			for k, v := range secret.StringData {
+				got[k] = v
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("got data %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("got %s=%q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Category is the CRD category every valet kind belongs to, so that
+// `kubectl get valet` lists the resources of all providers.
+const Category = "valet"
+
+// ShortNames assigns the CRD short names to the plural names of the kinds
+// that own them. kubectl resolves short names across all API groups, so a
+// short name claimed by two providers installed side by side resolves to
+// whichever CRD discovery happens to return first. Providers claim theirs
+// here, where a duplicate does not compile.
+var ShortNames = map[string]string{
+	"awsak":      "awsaccesskeys",
+	"acs":        "azureclientsecrets",
+	"azsas":      "azuresaskeys",
+	"esak":       "elasticsearchapikeys",
+	"execcred":   "execcredentials",
+	"gcpsak":     "gcpserviceaccountkeys",
+	"jwtsk":      "jwtsigningkeys",
+	"kafkascram": "kafkascramusers",
+	"oauth2c":    "oauth2clients",
+	"oktacs":     "oktaclientsecrets",
+	"pdtoken":    "pagerdutyapitokens",
+	"randpw":     "randompasswords",
+	"rmquser":    "rabbitmqusers",
+	"sshkp":      "sshkeypairs",
+	"tlscc":      "tlsclientcertificates",
+	"twilioak":   "twilioapikeys",
+	"wasmcred":   "wasmcredentials",
+	"whcred":     "webhookcredentials",
+}
+
+// builtinNames are the short names and plurals of built-in resources, which
+// take precedence over CRD names in kubectl.
+var builtinNames = []string{
+	"cm", "configmaps", "cs", "componentstatuses", "csr", "certificatesigningrequests",
+	"crd", "crds", "customresourcedefinitions", "cronjobs", "cj", "daemonsets", "ds",
+	"deployments", "deploy", "endpoints", "ep", "events", "ev", "horizontalpodautoscalers",
+	"hpa", "ingresses", "ing", "jobs", "limitranges", "limits", "namespaces", "ns",
+	"networkpolicies", "netpol", "nodes", "no", "persistentvolumeclaims", "pvc",
+	"persistentvolumes", "pv", "poddisruptionbudgets", "pdb", "pods", "po",
+	"priorityclasses", "pc", "replicasets", "rs", "replicationcontrollers", "rc",
+	"resourcequotas", "quota", "secrets", "serviceaccounts", "sa", "services", "svc",
+	"statefulsets", "sts", "storageclasses", "sc",
+}
+
+// LoadCRDs reads the CustomResourceDefinitions from the files matching
+// pattern, e.g. a provider's config/crd/*.yaml.
+func LoadCRDs(pattern string) ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no CRDs match %s", pattern)
+	}
+
+	var crds []*apiextensionsv1.CustomResourceDefinition
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		docs, err := Decode(file, f)
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			var crd apiextensionsv1.CustomResourceDefinition
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(doc.Object.Object, &crd); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", doc.Source, doc.Index, err)
+			}
+			crds = append(crds, &crd)
+		}
+	}
+	return crds, nil
+}
+
+// CheckCRDFiles loads the CRDs from the files matching pattern, see
+// [LoadCRDs], and checks them with [CheckCRDNames] and [CheckCRDSchemas]. It
+// is meant to be called from each provider's tests:
+//
+//	for _, err := range lint.CheckCRDFiles("../../config/crd/*.yaml") {
+//		t.Error(err)
+//	}
+func CheckCRDFiles(pattern string) []error {
+	crds, err := LoadCRDs(pattern)
+	if err != nil {
+		return []error{err}
+	}
+	return append(CheckCRDNames(crds), CheckCRDSchemas(crds)...)
+}
+
+// CheckCRDNames checks that the CRDs of a provider can be installed next to
+// those of the other providers: each kind is in [Category], and its short
+// names are claimed in [ShortNames] and, like its plural and singular, do
+// not shadow a built-in resource. It returns one error per problem.
+func CheckCRDNames(crds []*apiextensionsv1.CustomResourceDefinition) []error {
+	var errs []error
+	for _, crd := range crds {
+		names := crd.Spec.Names
+		if !slices.Contains(names.Categories, Category) {
+			errs = append(errs, fmt.Errorf("%s: missing category %q, so `kubectl get %s` does not list it",
+				crd.Name, Category, Category))
+		}
+		for _, name := range append([]string{names.Plural, names.Singular}, names.ShortNames...) {
+			if slices.Contains(builtinNames, name) {
+				errs = append(errs, fmt.Errorf("%s: name %q shadows a built-in resource", crd.Name, name))
+			}
+		}
+		for _, short := range names.ShortNames {
+			if owner, ok := ShortNames[short]; !ok {
+				errs = append(errs, fmt.Errorf("%s: short name %q is not claimed in lint.ShortNames", crd.Name, short))
+			} else if owner != names.Plural {
+				errs = append(errs, fmt.Errorf("%s: short name %q is claimed by %s", crd.Name, short, owner))
+			}
+		}
+	}
+	return errs
+}
+
+// CheckCRDSchemas checks that the schemas of a provider's CRDs can be loaded
+// by a [Linter], and that each spec.template documents the template
+// variables available to it, see [TemplateVariables]. It returns one error
+// per problem.
+func CheckCRDSchemas(crds []*apiextensionsv1.CustomResourceDefinition) []error {
+	var errs []error
+	l := New()
+	for _, crd := range crds {
+		if err := l.AddCRD(crd); err != nil {
+			errs = append(errs, err)
+		}
+		for _, v := range crd.Spec.Versions {
+			if v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+				continue
+			}
+			tmpl, ok := v.Schema.OpenAPIV3Schema.Properties["spec"].Properties["template"]
+			if ok && TemplateVariables(tmpl.Description) == nil {
+				errs = append(errs, fmt.Errorf("%s/%s: spec.template does not list the available template variables",
+					crd.Name, v.Name))
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,360 @@
+// Package lint checks valet resource manifests before they reach a cluster,
+// e.g. rendered GitOps manifests in CI.
+//
+// A [Linter] knows the valet kinds from their CustomResourceDefinitions. Each
+// resource is checked against the CRD's OpenAPI schema, including fields the
+// API server would silently prune, and its spec.template against the template
+// variables the CRD documents. Providers can additionally register their Go
+// types with [Linter.Register] to run [framework.Object.Validate], the checks
+// the reconciler performs before provisioning.
+//
+// CEL validation rules (x-kubernetes-validations) are not evaluated.
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/lukasngl/valet/framework"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/schema/pruning"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// GroupSuffix identifies the API groups served by valet providers.
+const GroupSuffix = "valet.ngl.cx"
+
+var (
+	// templateVariablesRe matches the list of template variables in the
+	// description of spec.template, e.g. "Available template variables:
+	// .ClientID, .ClientSecret", including continuation lines.
+	templateVariablesRe = regexp.MustCompile(`Available template variables:([^\n]*(?:\n\.[^\n]*)*)`)
+	remarkRe            = regexp.MustCompile(`\([^)]*\)`)
+	variableRe          = regexp.MustCompile(`\.([A-Za-z_][A-Za-z0-9_]*)`)
+)
+
+// Document is a single manifest read by [Decode].
+type Document struct {
+	// Source is the name of the file the document was read from.
+	Source string
+	// Index is the 1-based position of the document in its source.
+	Index int
+	// Object is the decoded manifest.
+	Object *unstructured.Unstructured
+}
+
+// Finding is a problem found in a valet resource.
+type Finding struct {
+	Source    string
+	Index     int
+	Kind      string
+	Namespace string
+	Name      string
+	Message   string
+}
+
+// String formats the finding as "source:index: Kind namespace/name: message".
+func (f Finding) String() string {
+	name := f.Name
+	if f.Namespace != "" {
+		name = f.Namespace + "/" + name
+	}
+	return fmt.Sprintf("%s:%d: %s %s: %s", f.Source, f.Index, f.Kind, name, f.Message)
+}
+
+// kind holds what is known about a valet resource kind.
+type kind struct {
+	validator  validation.SchemaValidator
+	structural *structuralschema.Structural
+	// variables are the documented template variables, or nil if unknown.
+	variables []string
+	newObject func() framework.Object
+}
+
+// Linter checks valet resources. The zero value is not usable; create one
+// with [New].
+type Linter struct {
+	kinds map[schema.GroupVersionKind]*kind
+}
+
+// New returns a [Linter] that knows no kinds yet.
+func New() *Linter {
+	return &Linter{kinds: map[schema.GroupVersionKind]*kind{}}
+}
+
+// AddCRD makes the served versions of a CustomResourceDefinition known to the
+// linter.
+func (l *Linter) AddCRD(crd *apiextensionsv1.CustomResourceDefinition) error {
+	for _, v := range crd.Spec.Versions {
+		if !v.Served || v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+			continue
+		}
+
+		var props apiextensions.JSONSchemaProps
+		if err := apiextensionsv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(
+			v.Schema.OpenAPIV3Schema, &props, nil,
+		); err != nil {
+			return fmt.Errorf("converting schema of %s/%s: %w", crd.Name, v.Name, err)
+		}
+		validator, _, err := validation.NewSchemaValidator(&props)
+		if err != nil {
+			return fmt.Errorf("building validator for %s/%s: %w", crd.Name, v.Name, err)
+		}
+		structural, err := structuralschema.NewStructural(&props)
+		if err != nil {
+			return fmt.Errorf("building structural schema for %s/%s: %w", crd.Name, v.Name, err)
+		}
+
+		k := l.kind(schema.GroupVersionKind{Group: crd.Spec.Group, Version: v.Name, Kind: crd.Spec.Names.Kind})
+		k.validator = validator
+		k.structural = structural
+		k.variables = TemplateVariables(props.Properties["spec"].Properties["template"].Description)
+	}
+	return nil
+}
+
+// Register makes the Go type of a kind known to the linter, so that
+// [framework.Object.Validate] is run on its resources.
+func (l *Linter) Register(gvk schema.GroupVersionKind, newObject func() framework.Object) {
+	l.kind(gvk).newObject = newObject
+}
+
+func (l *Linter) kind(gvk schema.GroupVersionKind) *kind {
+	k, ok := l.kinds[gvk]
+	if !ok {
+		k = &kind{}
+		l.kinds[gvk] = k
+	}
+	return k
+}
+
+// Lint checks the valet resources among docs. CustomResourceDefinitions of
+// valet kinds among docs are added first, so that manifests rendered together
+// with their CRDs (e.g. by helm template) need no separate schema. Documents
+// of other groups are ignored.
+func (l *Linter) Lint(docs []Document) ([]Finding, error) {
+	for _, doc := range docs {
+		if doc.Object.GroupVersionKind() != apiextensionsv1.SchemeGroupVersion.WithKind("CustomResourceDefinition") {
+			continue
+		}
+		var crd apiextensionsv1.CustomResourceDefinition
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(doc.Object.Object, &crd); err != nil {
+			return nil, fmt.Errorf("%s:%d: decoding CustomResourceDefinition: %w", doc.Source, doc.Index, err)
+		}
+		if !strings.HasSuffix(crd.Spec.Group, GroupSuffix) {
+			continue
+		}
+		if err := l.AddCRD(&crd); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", doc.Source, doc.Index, err)
+		}
+	}
+
+	var findings []Finding
+	for _, doc := range docs {
+		obj := doc.Object
+		if !strings.HasSuffix(obj.GroupVersionKind().Group, GroupSuffix) {
+			continue
+		}
+		for _, msg := range l.LintObject(obj) {
+			findings = append(findings, Finding{
+				Source:    doc.Source,
+				Index:     doc.Index,
+				Kind:      obj.GetKind(),
+				Namespace: obj.GetNamespace(),
+				Name:      obj.GetName(),
+				Message:   msg,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// LintObject checks a single valet resource and returns the problems found.
+func (l *Linter) LintObject(obj *unstructured.Unstructured) []string {
+	gvk := obj.GroupVersionKind()
+	k, ok := l.kinds[gvk]
+	if !ok {
+		return []string{fmt.Sprintf("unknown kind %s, add its CustomResourceDefinition", gvk)}
+	}
+
+	var msgs []string
+	if obj.GetName() == "" && obj.GetGenerateName() == "" {
+		msgs = append(msgs, "metadata.name is required")
+	}
+
+	if k.validator != nil {
+		for _, err := range validation.ValidateCustomResource(nil, obj.UnstructuredContent(), k.validator) {
+			msgs = append(msgs, err.Error())
+		}
+		pruned := pruning.PruneWithOptions(
+			runtime.DeepCopyJSON(obj.UnstructuredContent()), k.structural, true,
+			structuralschema.UnknownFieldPathOptions{TrackUnknownFieldPaths: true},
+		)
+		for _, path := range pruned {
+			msgs = append(msgs, fmt.Sprintf("unknown field %q would be dropped by the API server", path))
+		}
+	}
+
+	templates, _, err := unstructured.NestedStringMap(obj.Object, "spec", "template")
+	if err != nil {
+		msgs = append(msgs, fmt.Sprintf("spec.template: %v", err))
+	}
+	msgs = append(msgs, checkTemplates(templates, k.variables)...)
+
+	if k.newObject != nil {
+		typed := k.newObject()
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, typed); err != nil {
+			msgs = append(msgs, fmt.Sprintf("decoding spec: %v", err))
+		} else if err := typed.Validate(); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+
+	return msgs
+}
+
+// checkTemplates parses every template and, if the available variables are
+// known, reports references to other variables, which would render as
+// "<no value>".
+func checkTemplates(templates map[string]string, variables []string) []string {
+	keys := make([]string, 0, len(templates))
+	for key := range templates {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var msgs []string
+	for _, key := range keys {
+		t, err := template.New(key).Parse(templates[key])
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("spec.template[%s]: %v", key, err))
+			continue
+		}
+		if variables == nil {
+			continue
+		}
+		for _, field := range Fields(t.Root) {
+			if !slices.Contains(variables, field) {
+				msgs = append(msgs, fmt.Sprintf("spec.template[%s]: unknown variable .%s, available: .%s",
+					key, field, strings.Join(variables, ", .")))
+			}
+		}
+	}
+	return msgs
+}
+
+// TemplateVariables extracts the template variables listed in the
+// description of a spec.template field after "Available template
+// variables:". It returns nil if the description lists none.
+func TemplateVariables(description string) []string {
+	m := templateVariablesRe.FindStringSubmatch(description)
+	if m == nil {
+		return nil
+	}
+	// Drop parenthesized remarks, e.g. ".Encoded (base64 of id:api_key)".
+	list := remarkRe.ReplaceAllString(m[1], "")
+	var vars []string
+	for _, v := range variableRe.FindAllStringSubmatch(list, -1) {
+		if !slices.Contains(vars, v[1]) {
+			vars = append(vars, v[1])
+		}
+	}
+	return vars
+}
+
+// Fields returns the top-level fields referenced in the template parse tree
+// rooted at node, e.g. "ClientID" for {{ .ClientID }}, sorted and without
+// duplicates.
+func Fields(node parse.Node) []string {
+	seen := map[string]bool{}
+	collectFields(node, seen)
+	fields := make([]string, 0, len(seen))
+	for f := range seen {
+		fields = append(fields, f)
+	}
+	slices.Sort(fields)
+	return fields
+}
+
+func collectFields(node parse.Node, seen map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			collectFields(c, seen)
+		}
+	case *parse.ActionNode:
+		collectFields(n.Pipe, seen)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Cmds {
+			collectFields(c, seen)
+		}
+	case *parse.CommandNode:
+		for _, a := range n.Args {
+			collectFields(a, seen)
+		}
+	case *parse.FieldNode:
+		seen[n.Ident[0]] = true
+	case *parse.IfNode:
+		collectBranch(&n.BranchNode, seen)
+	case *parse.RangeNode:
+		collectBranch(&n.BranchNode, seen)
+	case *parse.WithNode:
+		collectBranch(&n.BranchNode, seen)
+	}
+}
+
+func collectBranch(n *parse.BranchNode, seen map[string]bool) {
+	collectFields(n.Pipe, seen)
+	collectFields(n.List, seen)
+	collectFields(n.ElseList, seen)
+}
+
+// Decode reads the YAML or JSON documents of a manifest stream. Empty
+// documents are skipped; List objects are expanded into their items.
+func Decode(source string, r io.Reader) ([]Document, error) {
+	dec := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	var docs []Document
+	for index := 1; ; index++ {
+		var obj map[string]any
+		if err := dec.Decode(&obj); err != nil {
+			if errors.Is(err, io.EOF) {
+				return docs, nil
+			}
+			return nil, fmt.Errorf("%s:%d: %w", source, index, err)
+		}
+		if len(obj) == 0 {
+			continue
+		}
+
+		u := &unstructured.Unstructured{Object: obj}
+		if !u.IsList() {
+			docs = append(docs, Document{Source: source, Index: index, Object: u})
+			continue
+		}
+		list, err := u.ToList()
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", source, index, err)
+		}
+		for i := range list.Items {
+			docs = append(docs, Document{Source: source, Index: index, Object: &list.Items[i]})
+		}
+	}
+}
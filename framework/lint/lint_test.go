@@ -0,0 +1,315 @@
+package lint_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/framework/lint"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const testCRD = `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgetsecrets.valet.ngl.cx
+spec:
+  group: valet.ngl.cx
+  names:
+    kind: WidgetSecret
+    plural: widgetsecrets
+  scope: Namespaced
+  versions:
+    - name: v1alpha1
+      served: true
+      storage: true
+      schema:
+        openAPIV3Schema:
+          type: object
+          properties:
+            apiVersion:
+              type: string
+            kind:
+              type: string
+            metadata:
+              type: object
+            spec:
+              type: object
+              required: [secretRef, template]
+              properties:
+                secretRef:
+                  type: object
+                  required: [name]
+                  properties:
+                    name:
+                      type: string
+                      minLength: 1
+                validity:
+                  type: string
+                template:
+                  type: object
+                  additionalProperties:
+                    type: string
+                  description: |-
+                    Template maps output secret keys to Go template strings.
+                    Available template variables: .ID, .Secret (base64 of id:secret),
+                    .URL
+`
+
+func decode(t *testing.T, manifests ...string) []lint.Document {
+	t.Helper()
+	docs, err := lint.Decode("test.yaml", strings.NewReader(strings.Join(manifests, "\n---\n")))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return docs
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     []string
+	}{
+		{
+			name: "valid",
+			manifest: `
+apiVersion: valet.ngl.cx/v1alpha1
+kind: WidgetSecret
+metadata: {name: app, namespace: default}
+spec:
+  secretRef: {name: app}
+  template:
+    ID: "{{ .ID }}"
+    DSN: "https://{{ .ID }}:{{ .Secret }}@{{ .URL }}"`,
+		},
+		{
+			name: "schema violation",
+			manifest: `
+apiVersion: valet.ngl.cx/v1alpha1
+kind: WidgetSecret
+metadata: {name: app}
+spec:
+  secretRef: {name: ""}
+  template: {ID: "{{ .ID }}"}`,
+			want: []string{"spec.secretRef.name"},
+		},
+		{
+			name: "unknown field",
+			manifest: `
+apiVersion: valet.ngl.cx/v1alpha1
+kind: WidgetSecret
+metadata: {name: app}
+spec:
+  secretRef: {name: app}
+  validty: 24h
+  template: {ID: "{{ .ID }}"}`,
+			want: []string{`unknown field "spec.validty"`},
+		},
+		{
+			name: "unknown template variable",
+			manifest: `
+apiVersion: valet.ngl.cx/v1alpha1
+kind: WidgetSecret
+metadata: {name: app}
+spec:
+  secretRef: {name: app}
+  template: {SECRET: "{{ .ClientSecret }}"}`,
+			want: []string{"spec.template[SECRET]: unknown variable .ClientSecret, available: .ID, .Secret, .URL"},
+		},
+		{
+			name: "template syntax",
+			manifest: `
+apiVersion: valet.ngl.cx/v1alpha1
+kind: WidgetSecret
+metadata: {name: app}
+spec:
+  secretRef: {name: app}
+  template: {BAD: "{{ .ID"}`,
+			want: []string{"spec.template[BAD]"},
+		},
+		{
+			name: "unknown kind",
+			manifest: `
+apiVersion: valet.ngl.cx/v1alpha1
+kind: GadgetSecret
+metadata: {name: app}`,
+			want: []string{"unknown kind"},
+		},
+		{
+			name: "other groups are ignored",
+			manifest: `
+apiVersion: v1
+kind: ConfigMap
+metadata: {name: app}
+data: {unknown: field}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings, err := lint.New().Lint(decode(t, testCRD, tt.manifest))
+			if err != nil {
+				t.Fatalf("lint: %v", err)
+			}
+			if len(findings) != len(tt.want) {
+				t.Fatalf("got findings %v, want %d", findings, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got := findings[i].String(); !strings.Contains(got, want) {
+					t.Errorf("finding %q does not contain %q", got, want)
+				}
+				if findings[i].Source != "test.yaml" || findings[i].Index != 2 {
+					t.Errorf("unexpected location %s:%d", findings[i].Source, findings[i].Index)
+				}
+			}
+		})
+	}
+}
+
+// widgetSecret is a minimal [framework.Object] whose Validate rejects the
+// name "invalid".
+type widgetSecret struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+}
+
+func (w *widgetSecret) GetSecretRef() framework.SecretReference { return framework.SecretReference{} }
+func (w *widgetSecret) GetStatus() *framework.ClientSecretStatus {
+	return &framework.ClientSecretStatus{}
+}
+func (w *widgetSecret) DeepCopyObject() runtime.Object { cp := *w; return &cp }
+
+func (w *widgetSecret) Validate() error {
+	if w.Name == "invalid" {
+		return errors.New("name must not be invalid")
+	}
+	return nil
+}
+
+func TestLint_Register(t *testing.T) {
+	l := lint.New()
+	gvk := schema.GroupVersionKind{Group: "valet.ngl.cx", Version: "v1alpha1", Kind: "WidgetSecret"}
+	l.Register(gvk, func() framework.Object { return &widgetSecret{} })
+
+	findings, err := l.Lint(decode(t, `
+apiVersion: valet.ngl.cx/v1alpha1
+kind: WidgetSecret
+metadata: {name: invalid}`))
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	if len(findings) != 1 || findings[0].Message != "name must not be invalid" {
+		t.Fatalf("expected the Validate error, got %v", findings)
+	}
+}
+
+func TestDecode_List(t *testing.T) {
+	docs := decode(t, `
+apiVersion: v1
+kind: List
+items:
+  - {apiVersion: v1, kind: ConfigMap, metadata: {name: a}}
+  - {apiVersion: v1, kind: ConfigMap, metadata: {name: b}}`, ``)
+	if len(docs) != 2 || docs[1].Object.GetName() != "b" {
+		t.Fatalf("expected the list items, got %v", docs)
+	}
+}
+
+func TestFields(t *testing.T) {
+	tmpl := template.Must(template.New("").Parse(
+		`{{ .B }}{{ if .A }}{{ .C | printf "%s" }}{{ else }}{{ .B }}{{ end }}{{ range $x := .D }}{{ $x }}{{ end }}`,
+	))
+	if got := strings.Join(lint.Fields(tmpl.Root), ","); got != "A,B,C,D" {
+		t.Fatalf("got fields %s, want A,B,C,D", got)
+	}
+}
+
+func TestCheckCRDSchemas(t *testing.T) {
+	crd := func(t *testing.T, manifest string) *apiextensionsv1.CustomResourceDefinition {
+		t.Helper()
+		var crd apiextensionsv1.CustomResourceDefinition
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(decode(t, manifest)[0].Object.Object, &crd); err != nil {
+			t.Fatal(err)
+		}
+		return &crd
+	}
+
+	if errs := lint.CheckCRDSchemas([]*apiextensionsv1.CustomResourceDefinition{crd(t, testCRD)}); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+
+	undocumented := strings.Replace(testCRD, "Available template variables", "Variables", 1)
+	errs := lint.CheckCRDSchemas([]*apiextensionsv1.CustomResourceDefinition{crd(t, undocumented)})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "does not list the available template variables") {
+		t.Errorf("got errors %v, want one for the undocumented template variables", errs)
+	}
+}
+
+func TestCheckCRDFiles(t *testing.T) {
+	dir := t.TempDir()
+	if errs := lint.CheckCRDFiles(filepath.Join(dir, "*.yaml")); len(errs) != 1 ||
+		!strings.Contains(errs[0].Error(), "no CRDs match") {
+		t.Errorf("got errors %v, want one for the missing files", errs)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "crd.yaml"), []byte(testCRD), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	errs := lint.CheckCRDFiles(filepath.Join(dir, "*.yaml"))
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "missing category") {
+		t.Errorf("got errors %v, want one for the missing category", errs)
+	}
+}
+
+func TestCheckCRDNames(t *testing.T) {
+	crd := func(plural string, categories []string, shortNames ...string) *apiextensionsv1.CustomResourceDefinition {
+		return &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: plural + ".valet.ngl.cx"},
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Names: apiextensionsv1.CustomResourceDefinitionNames{
+					Plural:     plural,
+					Singular:   strings.TrimSuffix(plural, "s"),
+					ShortNames: shortNames,
+					Categories: categories,
+				},
+			},
+		}
+	}
+	valet := []string{lint.Category}
+
+	tests := []struct {
+		name string
+		crd  *apiextensionsv1.CustomResourceDefinition
+		want string
+	}{
+		{name: "claimed", crd: crd("oktaclientsecrets", valet, "oktacs")},
+		{name: "no short name", crd: crd("clientsecrets", valet)},
+		{name: "no category", crd: crd("oktaclientsecrets", nil, "oktacs"), want: "missing category"},
+		{name: "unclaimed", crd: crd("widgetsecrets", valet, "ws"), want: "not claimed"},
+		{name: "claimed by another", crd: crd("widgetsecrets", valet, "oktacs"), want: "claimed by oktaclientsecrets"},
+		{name: "built-in", crd: crd("secrets", valet), want: "shadows a built-in resource"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := lint.CheckCRDNames([]*apiextensionsv1.CustomResourceDefinition{tt.crd})
+			if tt.want == "" {
+				if len(errs) != 0 {
+					t.Fatalf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.want) {
+				t.Fatalf("got errors %v, want one containing %q", errs, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// DefaultListPageSize is the number of resources [List] requests per page,
+// unless a [client.Limit] option is given.
+const DefaultListPageSize = 500
+
+// List returns the resources of a provider's kind that match opts, typed and
+// including their status, so that consumers do not need to deal with list
+// types and unstructured objects. newObject returns a zero-value instance of
+// the kind, e.g. [Provider.NewObject]; its list type is looked up in scheme.
+//
+// The resources are requested in pages of [DefaultListPageSize], following
+// the continue tokens of the API server. The controller-runtime cache does
+// not hand out continue tokens and silently returns the first page only, so
+// pass an uncached reader, or [client.Limit] of 0 to list from a cache at
+// once.
+func List[O Object](
+	ctx context.Context,
+	reader client.Reader,
+	scheme *runtime.Scheme,
+	newObject func() O,
+	opts ...client.ListOption,
+) ([]O, error) {
+	gvk, err := apiutil.GVKForObject(newObject(), scheme)
+	if err != nil {
+		return nil, fmt.Errorf("looking up the resource kind: %w", err)
+	}
+	listGVK := gvk.GroupVersion().WithKind(gvk.Kind + "List")
+	opts = append([]client.ListOption{client.Limit(DefaultListPageSize)}, opts...)
+
+	var items []O
+	for cont := ""; ; {
+		listObj, err := scheme.New(listGVK)
+		if err != nil {
+			return nil, fmt.Errorf("creating %s list: %w", gvk.Kind, err)
+		}
+		list, ok := listObj.(client.ObjectList)
+		if !ok {
+			return nil, fmt.Errorf("%s list is not a client.ObjectList", gvk.Kind)
+		}
+		if err := reader.List(ctx, list, append(opts, client.Continue(cont))...); err != nil {
+			return nil, fmt.Errorf("listing %s resources: %w", gvk.Kind, err)
+		}
+		page, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, fmt.Errorf("extracting %s resources: %w", gvk.Kind, err)
+		}
+		for _, item := range page {
+			obj, ok := item.(O)
+			if !ok {
+				return nil, fmt.Errorf("unexpected %s list item of type %T", gvk.Kind, item)
+			}
+			items = append(items, obj)
+		}
+
+		if cont = list.GetContinue(); cont == "" {
+			return items, nil
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package framework_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// pagedReader serves lists ordered by name in pages of at most the requested
+// limit, with the offset of the next page as continue token, like the API
+// server does.
+type pagedReader struct {
+	client.Reader
+
+	requests int
+}
+
+func (r *pagedReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	r.requests++
+	listOpts := (&client.ListOptions{}).ApplyOptions(opts)
+
+	// Drop the paging options, the fake client does not paginate.
+	if err := r.Reader.List(ctx, list, &client.ListOptions{Namespace: listOpts.Namespace}); err != nil {
+		return err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+
+	slices.SortFunc(items, func(a, b runtime.Object) int {
+		return strings.Compare(a.(client.Object).GetName(), b.(client.Object).GetName())
+	})
+
+	offset := 0
+	if listOpts.Continue != "" {
+		if offset, err = strconv.Atoi(listOpts.Continue); err != nil {
+			return fmt.Errorf("invalid continue token %q", listOpts.Continue)
+		}
+	}
+	end := len(items)
+	if listOpts.Limit > 0 {
+		end = min(offset+int(listOpts.Limit), len(items))
+	}
+	if end < len(items) {
+		list.SetContinue(strconv.Itoa(end))
+	} else {
+		list.SetContinue("")
+	}
+	return meta.SetList(list, items[offset:end])
+}
+
+func TestList(t *testing.T) {
+	scheme := newTestScheme(t)
+	builder := fake.NewClientBuilder().WithScheme(scheme)
+	for i := range 5 {
+		obj := newTestObject()
+		obj.Name = fmt.Sprintf("app-%d", i)
+		obj.Status.Phase = framework.PhaseReady
+		builder = builder.WithObjects(obj)
+	}
+	other := newTestObject()
+	other.Namespace = "other"
+	builder = builder.WithObjects(other)
+	reader := &pagedReader{Reader: builder.Build()}
+
+	objs, err := framework.List(context.Background(), reader, scheme,
+		func() *testObject { return &testObject{} },
+		client.InNamespace("default"), client.Limit(2))
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+
+	names := make([]string, len(objs))
+	for i, obj := range objs {
+		names[i] = obj.Name
+		if obj.Status.Phase != framework.PhaseReady {
+			t.Errorf("expected %s to come with its status, got %+v", obj.Name, obj.Status)
+		}
+	}
+	want := []string{"app-0", "app-1", "app-2", "app-3", "app-4"}
+	if !slices.Equal(names, want) {
+		t.Errorf("expected %v, got %v", want, names)
+	}
+	if reader.requests != 3 {
+		t.Errorf("expected 3 pages of 2, got %d requests", reader.requests)
+	}
+}
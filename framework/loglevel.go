@@ -0,0 +1,159 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	crzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// DefaultLogLevelInterval is how often [LogLevelWatcher] re-reads its file by
+// default. Mounted ConfigMaps are updated by the kubelet within about a
+// minute, so shorter intervals only help with files changed otherwise.
+const DefaultLogLevelInterval = 10 * time.Second
+
+// NewLogLevel replaces the level of opts with an atomic level starting at
+// the one configured with --zap-log-level, or the default of the mode, and
+// returns it. Changing it changes the verbosity of loggers built from opts
+// afterwards, see [LogLevelWatcher].
+func NewLogLevel(opts *crzap.Options) zap.AtomicLevel {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	if opts.Development {
+		level.SetLevel(zapcore.DebugLevel)
+	}
+	if opts.Level != nil {
+		level.SetLevel(zapcore.LevelOf(opts.Level))
+	}
+	opts.Level = level
+	return level
+}
+
+// ParseLogLevel parses a log level the way --zap-log-level does: "debug",
+// "info", "error" or "panic", or a positive integer for that verbosity of
+// V-logs, e.g. "3" to include log.V(3).
+func ParseLogLevel(s string) (zapcore.Level, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	case "panic":
+		return zapcore.PanicLevel, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v <= 0 || v > 127 {
+		return 0, fmt.Errorf("invalid log level %q", s)
+	}
+	return zapcore.Level(-v), nil
+}
+
+// LogLevelWatcher applies the log level in a file, usually a key of a mounted
+// ConfigMap, to a level returned by [NewLogLevel], so that the verbosity can
+// be raised while debugging without a restart, which would change the state
+// being debugged. The file is re-read periodically and on SIGHUP. An empty or
+// missing file restores the level the watcher started with, and invalid
+// content is logged and ignored.
+//
+// It implements [sigs.k8s.io/controller-runtime/pkg/manager.Runnable] and
+// runs on all replicas, not only the leader.
+type LogLevelWatcher struct {
+	// Path is the file holding the level, in the format of [ParseLogLevel].
+	Path string
+
+	// Level is the level to set.
+	Level zap.AtomicLevel
+
+	// Interval is how often the file is re-read. Defaults to
+	// [DefaultLogLevelInterval].
+	Interval time.Duration
+
+	// Log receives level changes and errors reading the file.
+	Log logr.Logger
+
+	// initial is the level the watcher started with.
+	initial zapcore.Level
+	// last is the file content last applied.
+	last string
+}
+
+// Start watches the file until ctx is done.
+func (w *LogLevelWatcher) Start(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultLogLevelInterval
+	}
+	w.initial = w.Level.Level()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	w.reload()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			w.Log.Info("reloading log level", "path", w.Path, "trigger", "SIGHUP")
+			w.last = ""
+			w.reload()
+		case <-ticker.C:
+			w.reload()
+		}
+	}
+}
+
+// NeedLeaderElection implements
+// [sigs.k8s.io/controller-runtime/pkg/manager.LeaderElectionRunnable], so that
+// standby replicas can be debugged too.
+func (w *LogLevelWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+// reload applies the content of the file if it changed.
+func (w *LogLevelWatcher) reload() {
+	b, err := os.ReadFile(w.Path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		w.Log.Error(err, "reading log level", "path", w.Path)
+		return
+	}
+	content := strings.TrimSpace(string(b))
+	if content == w.last {
+		return
+	}
+	w.last = content
+
+	level := w.initial
+	if content != "" {
+		level, err = ParseLogLevel(content)
+		if err != nil {
+			w.Log.Error(err, "ignoring log level", "path", w.Path)
+			return
+		}
+	}
+	if level == w.Level.Level() {
+		return
+	}
+
+	// Logged before the change, so that it is seen when lowering the
+	// verbosity.
+	w.Log.Info("changing log level", "from", w.Level.Level().String(), "to", level.String())
+	w.Level.SetLevel(level)
+}
@@ -0,0 +1,127 @@
+package framework_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/lukasngl/valet/framework"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	crzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    zapcore.Level
+		wantErr bool
+	}{
+		{in: "debug", want: zapcore.DebugLevel},
+		{in: " Info\n", want: zapcore.InfoLevel},
+		{in: "error", want: zapcore.ErrorLevel},
+		{in: "3", want: zapcore.Level(-3)},
+		{in: "0", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "verbose", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := framework.ParseLogLevel(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLogLevel(t *testing.T) {
+	opts := crzap.Options{Development: true}
+	if got := framework.NewLogLevel(&opts).Level(); got != zapcore.DebugLevel {
+		t.Fatalf("got %v in development mode, want debug", got)
+	}
+
+	// --zap-log-level=2 sets an atomic level.
+	opts = crzap.Options{Level: zap.NewAtomicLevelAt(zapcore.Level(-2))}
+	level := framework.NewLogLevel(&opts)
+	if got := level.Level(); got != zapcore.Level(-2) {
+		t.Fatalf("got %v, want the configured level", got)
+	}
+
+	level.SetLevel(zapcore.ErrorLevel)
+	if opts.Level.Enabled(zapcore.InfoLevel) {
+		t.Fatal("expected the options to follow the returned level")
+	}
+}
+
+func TestLogLevelWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "level")
+	if err := os.WriteFile(path, []byte("debug\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := crzap.Options{}
+	level := framework.NewLogLevel(&opts)
+	w := &framework.LogLevelWatcher{
+		Path:     path,
+		Level:    level,
+		Interval: 10 * time.Millisecond,
+		Log:      logr.Discard(),
+	}
+	if w.NeedLeaderElection() {
+		t.Fatal("expected the watcher to run on all replicas")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Start(ctx) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}()
+
+	waitForLevel := func(want zapcore.Level) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for level.Level() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("got level %v, want %v", level.Level(), want)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	waitForLevel(zapcore.DebugLevel)
+
+	if err := os.WriteFile(path, []byte("4"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	waitForLevel(zapcore.Level(-4))
+
+	// Invalid content keeps the current level.
+	if err := os.WriteFile(path, []byte("loud"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	waitForLevel(zapcore.Level(-4))
+
+	// Removing the file restores the initial level.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	waitForLevel(zapcore.InfoLevel)
+}
@@ -0,0 +1,133 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// MaintenanceWindow is a recurring period in which the provider is expected
+// to be unavailable, e.g. the nightly backup window of its API or database.
+// Renewals that fall into it are deferred until it ends instead of failing,
+// see [Reconciler.MaintenanceWindows].
+type MaintenanceWindow struct {
+	// Days are the days the window starts on, in UTC. Empty means every day.
+	Days []time.Weekday
+
+	// Start is when the window starts, as the time since midnight UTC.
+	Start time.Duration
+
+	// Duration is how long the window lasts. It may extend into the next day.
+	Duration time.Duration
+}
+
+// End returns the end of the occurrence of w that t falls into, and whether
+// there is one.
+func (w MaintenanceWindow) End(t time.Time) (time.Time, bool) {
+	t = t.UTC()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	// An occurrence that started the day before may still last.
+	for _, day := range []time.Time{midnight, midnight.AddDate(0, 0, -1)} {
+		if len(w.Days) > 0 && !slices.Contains(w.Days, day.Weekday()) {
+			continue
+		}
+		start := day.Add(w.Start)
+		if end := start.Add(w.Duration); !t.Before(start) && t.Before(end) {
+			return end, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// weekdays are the abbreviations of the days accepted by
+// [ParseMaintenanceWindows].
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ParseMaintenanceWindows parses the value of the --maintenance-windows flag:
+// a comma-separated list of windows of the form [<day>] HH:MM-HH:MM in UTC,
+// e.g. "Sun 02:00-04:00,23:30-00:30" for a window on Sunday night and one
+// around every midnight. An empty value means no windows.
+func ParseMaintenanceWindows(s string) ([]MaintenanceWindow, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	var windows []MaintenanceWindow
+	for field := range strings.SplitSeq(s, ",") {
+		var w MaintenanceWindow
+		span := strings.TrimSpace(field)
+		if day, rest, ok := strings.Cut(span, " "); ok {
+			d, known := weekdays[strings.ToLower(day)]
+			if !known {
+				return nil, fmt.Errorf("maintenance window %q: unknown day %q, want Mon to Sun", field, day)
+			}
+			w.Days = []time.Weekday{d}
+			span = strings.TrimSpace(rest)
+		}
+
+		from, to, ok := strings.Cut(span, "-")
+		if !ok {
+			return nil, fmt.Errorf("maintenance window %q is not of the form [<day>] HH:MM-HH:MM", field)
+		}
+		start, err := parseTimeOfDay(from)
+		if err != nil {
+			return nil, fmt.Errorf("maintenance window %q: %w", field, err)
+		}
+		end, err := parseTimeOfDay(to)
+		if err != nil {
+			return nil, fmt.Errorf("maintenance window %q: %w", field, err)
+		}
+		if end <= start {
+			end += 24 * time.Hour
+		}
+		w.Start, w.Duration = start, end-start
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// parseTimeOfDay parses HH:MM as the time since midnight.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("time %q is not of the form HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// maintenanceEnd returns the end of the maintenance window t falls into, the
+// latest one if several overlap, and whether there is one.
+func (r *Reconciler[O]) maintenanceEnd(t time.Time) (time.Time, bool) {
+	var until time.Time
+	for _, w := range r.MaintenanceWindows {
+		if end, ok := w.End(t); ok && end.After(until) {
+			until = end
+		}
+	}
+	return until, !until.IsZero()
+}
+
+// handleMaintenance defers the renewal of obj until the end of the provider
+// maintenance window, marking it with [ConditionMaintenance] meanwhile.
+func (r *Reconciler[O]) handleMaintenance(ctx context.Context, obj O, until time.Time) (ctrl.Result, error) {
+	if obj.GetStatus().SetMaintenance(obj.GetGeneration(), until) {
+		log.FromContext(ctx).Info("deferring provisioning during provider maintenance window", "until", until)
+		if err := r.updateStatus(ctx, obj); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{RequeueAfter: time.Until(until)}, nil
+}
@@ -0,0 +1,121 @@
+package framework_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"k8s.io/apimachinery/pkg/api/meta"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseMaintenanceWindows(t *testing.T) {
+	windows, err := framework.ParseMaintenanceWindows("Sun 02:00-04:00, 23:30-00:30")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(windows) != 2 {
+		t.Fatalf("expected 2 windows, got %d", len(windows))
+	}
+	sunday, nightly := windows[0], windows[1]
+	if len(sunday.Days) != 1 || sunday.Days[0] != time.Sunday ||
+		sunday.Start != 2*time.Hour || sunday.Duration != 2*time.Hour {
+		t.Errorf("unexpected Sunday window %+v", sunday)
+	}
+	if len(nightly.Days) != 0 || nightly.Start != 23*time.Hour+30*time.Minute || nightly.Duration != time.Hour {
+		t.Errorf("unexpected nightly window %+v", nightly)
+	}
+
+	if windows, err := framework.ParseMaintenanceWindows(""); err != nil || windows != nil {
+		t.Errorf("expected no windows, got %v, %v", windows, err)
+	}
+	for _, s := range []string{"Someday 02:00-04:00", "02:00", "2am-4am", "02:00-25:00"} {
+		if _, err := framework.ParseMaintenanceWindows(s); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+}
+
+func TestMaintenanceWindow_End(t *testing.T) {
+	// 2026-01-04 is a Sunday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+	sunday := framework.MaintenanceWindow{
+		Days:     []time.Weekday{time.Sunday},
+		Start:    23 * time.Hour,
+		Duration: 2 * time.Hour,
+	}
+
+	tests := []struct {
+		t       time.Time
+		wantEnd time.Time
+		wantOK  bool
+	}{
+		{t: at(4, 22, 59)},
+		{t: at(4, 23, 0), wantEnd: at(5, 1, 0), wantOK: true},
+		{t: at(5, 0, 30), wantEnd: at(5, 1, 0), wantOK: true},
+		{t: at(5, 1, 0)},
+		{t: at(5, 23, 30)},
+	}
+	for _, tt := range tests {
+		end, ok := sunday.End(tt.t)
+		if ok != tt.wantOK || !end.Equal(tt.wantEnd) {
+			t.Errorf("End(%s) = %s, %v, want %s, %v", tt.t, end, ok, tt.wantEnd, tt.wantOK)
+		}
+	}
+}
+
+func TestReconcile_MaintenanceWindow(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	p := &countingProvider{}
+	now := time.Now().UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	r := &framework.Reconciler[*testObject]{
+		Client:   c,
+		Scheme:   scheme,
+		Provider: p,
+		MaintenanceWindows: []framework.MaintenanceWindow{
+			{Start: now.Sub(midnight) - time.Minute, Duration: time.Hour},
+		},
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	res, err := r.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if p.provisions != 0 {
+		t.Errorf("expected no provisioning during the maintenance window, got %d calls", p.provisions)
+	}
+	if res.RequeueAfter <= 0 || res.RequeueAfter > time.Hour {
+		t.Errorf("expected a requeue at the end of the window, got %v", res.RequeueAfter)
+	}
+	var got testObject
+	if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, framework.ConditionMaintenance) {
+		t.Fatalf("expected a ProviderMaintenance condition, got %v", got.Status.Conditions)
+	}
+
+	r.MaintenanceWindows = nil
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if p.provisions != 1 {
+		t.Errorf("expected provisioning after the window, got %d calls", p.provisions)
+	}
+	if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if meta.FindStatusCondition(got.Status.Conditions, framework.ConditionMaintenance) != nil {
+		t.Errorf("expected the ProviderMaintenance condition to be cleared, got %v", got.Status.Conditions)
+	}
+}
@@ -0,0 +1,64 @@
+package framework
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// managedKeys returns the data keys of secret listed in
+// [AnnotationManagedKeys], and whether the annotation is set. Secrets
+// written by older versions do not have it.
+func managedKeys(secret *corev1.Secret) ([]string, bool) {
+	value, ok := secret.Annotations[AnnotationManagedKeys]
+	if !ok {
+		return nil, false
+	}
+	if value == "" {
+		return nil, true
+	}
+	return strings.Split(value, ","), true
+}
+
+// setManagedKeys records the keys of the StringData of secret, which is about
+// to be written, in [AnnotationManagedKeys], and removes the keys the
+// reconciler wrote before but no longer writes. Other keys are left alone, so
+// that users can keep static values in the output secret.
+func setManagedKeys(secret *corev1.Secret) {
+	previous, _ := managedKeys(secret)
+	for _, key := range previous {
+		if _, ok := secret.StringData[key]; !ok {
+			delete(secret.Data, key)
+		}
+	}
+	keys := slices.Sorted(maps.Keys(secret.StringData))
+	metav1.SetMetaDataAnnotation(&secret.ObjectMeta, AnnotationManagedKeys, strings.Join(keys, ","))
+}
+
+// managedData returns the data of secret the reconciler manages, with its
+// StringData merged in. All data counts as managed on secrets without
+// [AnnotationManagedKeys].
+func managedData(secret *corev1.Secret) map[string][]byte {
+	data := make(map[string][]byte, len(secret.Data)+len(secret.StringData))
+	for k, v := range secret.Data {
+		data[k] = v
+	}
+	for k, v := range secret.StringData {
+		data[k] = []byte(v)
+	}
+
+	keys, ok := managedKeys(secret)
+	if !ok {
+		return data
+	}
+	managed := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		if v, ok := data[k]; ok {
+			managed[k] = v
+		}
+	}
+	return managed
+}
@@ -0,0 +1,89 @@
+package framework_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcile_KeepsUnmanagedKeys(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app-secret",
+			Namespace:   "default",
+			Annotations: map[string]string{framework.AnnotationManagedKeys: "OLD"},
+		},
+		Data: map[string][]byte{"STATIC": []byte("static"), "OLD": []byte("old")},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: &countingProvider{}}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	var got corev1.Secret
+	if err := c.Get(ctx, client.ObjectKeyFromObject(secret), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.StringData["KEY"] != "key-1" {
+		t.Errorf("expected the new credentials, got %v", got.StringData)
+	}
+	if string(got.Data["STATIC"]) != "static" {
+		t.Errorf("expected the unmanaged key to be kept, got %v", got.Data)
+	}
+	if _, ok := got.Data["OLD"]; ok {
+		t.Error("expected the key the reconciler no longer writes to be removed")
+	}
+	if keys := got.Annotations[framework.AnnotationManagedKeys]; keys != "KEY" {
+		t.Errorf("expected the managed keys to be KEY, got %q", keys)
+	}
+}
+
+func TestReconcile_UnmanagedKeyIsNoConflict(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).
+		WithReturnManagedFields().Build()
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: &countingProvider{}}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	var secret corev1.Secret
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "app-secret"}, &secret); err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data["STATIC"] = []byte("static")
+	if err := c.Update(ctx, &secret, client.FieldOwner("other-controller")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	var got testObject
+	if err := c.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if cond := meta.FindStatusCondition(got.Status.Conditions, framework.ConditionConflictingWriter); cond != nil {
+		t.Errorf("expected an unmanaged key not to be reported as conflict, got %v", cond)
+	}
+}
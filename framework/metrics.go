@@ -2,10 +2,18 @@ package framework
 
 import (
 	"context"
+	"net/http"
+	"reflect"
+	"strconv"
 	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 )
 
 // InstrumentedProvider wraps a [Provider] with Prometheus metrics and
@@ -21,6 +29,26 @@ type InstrumentedProvider[O Object] struct {
 	DeleteKeyDuration *prometheus.HistogramVec
 	// DeleteKeyTotal counts DeleteKey calls by result.
 	DeleteKeyTotal *prometheus.CounterVec
+	// RotationTotal counts renewals of an existing key by kind and whether
+	// they completed before the previous key expired. This is the SLI for
+	// credential rotation SLOs, recorded by the reconciler once the new key
+	// is persisted, see [InstrumentedProvider.RecordRotation].
+	RotationTotal *prometheus.CounterVec
+	// RotationMargin observes how long before the previous key's expiry a
+	// rotation completed. Late rotations observe a negative margin.
+	RotationMargin *prometheus.HistogramVec
+	// ActiveKeysExcess counts Provision calls for objects that already track
+	// more than [ExpectedActiveKeys] keys, e.g. after flapping.
+	ActiveKeysExcess *prometheus.CounterVec
+
+	// Exemplar returns the exemplar labels attached to observations made
+	// within ctx, or nil for none. It defaults to a "trace_id" label with
+	// the trace ID of the OpenTelemetry span in ctx, linking metrics to
+	// traces, see [Reconciler.Tracer]. Exemplars are only exposed by
+	// [OpenMetricsHandler].
+	Exemplar func(ctx context.Context) prometheus.Labels
+
+	kind string
 }
 
 // Instrument wraps a provider with Prometheus metrics collection and
@@ -45,27 +73,107 @@ func Instrument[O Object](p Provider[O], reg prometheus.Registerer) *Instrumente
 			Name: "valet_delete_key_total",
 			Help: "Total number of provider DeleteKey calls.",
 		}, []string{"result"}),
+		RotationTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "valet_rotation_total",
+			Help: "Total number of key rotations, by whether they completed before the previous key expired.",
+		}, []string{"kind", "on_time"}),
+		RotationMargin: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "valet_rotation_expiry_margin_seconds",
+			Help: "Time between a completed rotation and the expiry of the previous key in seconds.",
+			Buckets: []float64{
+				0,
+				(1 * time.Hour).Seconds(),
+				(6 * time.Hour).Seconds(),
+				(24 * time.Hour).Seconds(),
+				(3 * 24 * time.Hour).Seconds(),
+				(7 * 24 * time.Hour).Seconds(),
+				(14 * 24 * time.Hour).Seconds(),
+				(30 * 24 * time.Hour).Seconds(),
+			},
+		}, []string{"kind"}),
+		ActiveKeysExcess: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "valet_active_keys_excess_total",
+			Help: "Total number of Provision calls for objects already tracking more than the expected number of keys.",
+		}, []string{"kind"}),
+		Exemplar: traceIDExemplar,
+		kind:     reflect.TypeOf(p.NewObject()).Elem().Name(),
 	}
 	reg.MustRegister(
 		ip.ProvisionDuration, ip.ProvisionTotal,
 		ip.DeleteKeyDuration, ip.DeleteKeyTotal,
+		ip.RotationTotal, ip.RotationMargin, ip.ActiveKeysExcess,
 	)
 	return ip
 }
 
+// OpenMetricsHandler returns a metrics handler for g that negotiates the
+// OpenMetrics format, which is required to expose exemplars. The default
+// controller-runtime metrics endpoint only serves the classic text format.
+func OpenMetricsHandler(g prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(g, promhttp.HandlerOpts{
+		ErrorHandling:     promhttp.HTTPErrorOnError,
+		EnableOpenMetrics: true,
+	})
+}
+
+// metricsPath is the path of the controller-runtime metrics endpoint.
+const metricsPath = "/metrics"
+
+// WithOpenMetrics returns a filter provider for the controller-runtime
+// metrics server that serves its /metrics endpoint from g with
+// [OpenMetricsHandler], since the builtin handler only serves the classic
+// text format and the endpoint cannot be replaced otherwise. The filter of
+// next, if set, e.g.
+// [sigs.k8s.io/controller-runtime/pkg/metrics/filters.WithAuthenticationAndAuthorization],
+// still guards all handlers.
+func WithOpenMetrics(
+	g prometheus.Gatherer,
+	next func(*rest.Config, *http.Client) (metricsserver.Filter, error),
+) func(*rest.Config, *http.Client) (metricsserver.Filter, error) {
+	return func(config *rest.Config, httpClient *http.Client) (metricsserver.Filter, error) {
+		var filter metricsserver.Filter
+		if next != nil {
+			f, err := next(config, httpClient)
+			if err != nil {
+				return nil, err
+			}
+			filter = f
+		}
+		openMetrics := OpenMetricsHandler(g)
+		return func(log logr.Logger, handler http.Handler) (http.Handler, error) {
+			h := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == metricsPath {
+					openMetrics.ServeHTTP(w, r)
+					return
+				}
+				handler.ServeHTTP(w, r)
+			}))
+			if filter == nil {
+				return h, nil
+			}
+			return filter(log, h)
+		}, nil
+	}
+}
+
 // Provision delegates to the inner provider and records duration and outcome.
 // The context logger is enriched with operation and duration fields.
 func (p *InstrumentedProvider[O]) Provision(ctx context.Context, obj O) (*Result, error) {
 	ctx = log.IntoContext(ctx,
 		log.FromContext(ctx).WithValues("operation", "provision"))
 
+	keys := obj.GetStatus().ActiveKeys
 	start := time.Now()
 	result, err := p.Provider.Provision(ctx, obj)
 	duration := time.Since(start)
 
+	exemplar := p.exemplar(ctx)
 	label := resultLabel(err)
-	p.ProvisionDuration.WithLabelValues(label).Observe(duration.Seconds())
-	p.ProvisionTotal.WithLabelValues(label).Inc()
+	observe(p.ProvisionDuration.WithLabelValues(label), duration.Seconds(), exemplar)
+	inc(p.ProvisionTotal.WithLabelValues(label), exemplar)
+	if len(keys) > ExpectedActiveKeys {
+		inc(p.ActiveKeysExcess.WithLabelValues(p.kind), exemplar)
+	}
 
 	l := log.FromContext(ctx).WithValues("duration", duration)
 	if err != nil {
@@ -76,6 +184,25 @@ func (p *InstrumentedProvider[O]) Provision(ctx context.Context, obj O) (*Result
 	return result, err
 }
 
+// RecordRotation records the rotation SLI for a renewal of previous that
+// completed at the given time.
+func (p *InstrumentedProvider[O]) RecordRotation(ctx context.Context, previous ActiveKey, completedAt time.Time) {
+	exemplar := p.exemplar(ctx)
+	margin := previous.ExpiresAt.Sub(completedAt)
+	inc(p.RotationTotal.WithLabelValues(p.kind, strconv.FormatBool(margin > 0)), exemplar)
+	observe(p.RotationMargin.WithLabelValues(p.kind), margin.Seconds(), exemplar)
+}
+
+// recordRotation records the rotation SLI with p or the first provider it
+// wraps that records it, see [InstrumentedProvider.RecordRotation].
+func recordRotation[O Object](ctx context.Context, p Provider[O], previous ActiveKey, completedAt time.Time) {
+	if r, ok := findProvider[O, interface {
+		RecordRotation(context.Context, ActiveKey, time.Time)
+	}](p); ok {
+		r.RecordRotation(ctx, previous, completedAt)
+	}
+}
+
 // DeleteKey delegates to the inner provider and records duration and outcome.
 // The context logger is enriched with operation, keyId, and duration fields.
 func (p *InstrumentedProvider[O]) DeleteKey(ctx context.Context, obj O, keyID string) error {
@@ -86,9 +213,10 @@ func (p *InstrumentedProvider[O]) DeleteKey(ctx context.Context, obj O, keyID st
 	err := p.Provider.DeleteKey(ctx, obj, keyID)
 	duration := time.Since(start)
 
+	exemplar := p.exemplar(ctx)
 	label := resultLabel(err)
-	p.DeleteKeyDuration.WithLabelValues(label).Observe(duration.Seconds())
-	p.DeleteKeyTotal.WithLabelValues(label).Inc()
+	observe(p.DeleteKeyDuration.WithLabelValues(label), duration.Seconds(), exemplar)
+	inc(p.DeleteKeyTotal.WithLabelValues(label), exemplar)
 
 	l := log.FromContext(ctx).WithValues("duration", duration)
 	if err != nil {
@@ -99,9 +227,49 @@ func (p *InstrumentedProvider[O]) DeleteKey(ctx context.Context, obj O, keyID st
 	return err
 }
 
+// Unwrap returns the inner provider, so that the reconciler can detect
+// optional interfaces such as [UsageReporter] it implements.
+func (p *InstrumentedProvider[O]) Unwrap() Provider[O] {
+	return p.Provider
+}
+
 func resultLabel(err error) string {
 	if err != nil {
 		return "error"
 	}
 	return "success"
 }
+
+func (p *InstrumentedProvider[O]) exemplar(ctx context.Context) prometheus.Labels {
+	if p.Exemplar == nil {
+		return nil
+	}
+	return p.Exemplar(ctx)
+}
+
+// traceIDExemplar labels observations with the trace ID of the span in ctx.
+func traceIDExemplar(ctx context.Context) prometheus.Labels {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return nil
+	}
+	return prometheus.Labels{"trace_id": sc.TraceID().String()}
+}
+
+// observe records v on o, attaching the exemplar if one is given.
+func observe(o prometheus.Observer, v float64, exemplar prometheus.Labels) {
+	if eo, ok := o.(prometheus.ExemplarObserver); ok && len(exemplar) > 0 {
+		eo.ObserveWithExemplar(v, exemplar)
+		return
+	}
+	o.Observe(v)
+}
+
+// inc increments c, attaching the exemplar if one is given.
+func inc(c prometheus.Counter, exemplar prometheus.Labels) {
+	if ea, ok := c.(prometheus.ExemplarAdder); ok && len(exemplar) > 0 {
+		ea.AddWithExemplar(1, exemplar)
+		return
+	}
+	c.Inc()
+}
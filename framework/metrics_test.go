@@ -0,0 +1,243 @@
+package framework_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+// stubProvider is a [framework.Provider] returning canned results.
+type stubProvider struct {
+	err error
+}
+
+func (s *stubProvider) NewObject() *testObject { return &testObject{} }
+
+func (s *stubProvider) Provision(context.Context, *testObject) (*framework.Result, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	now := time.Now()
+	return &framework.Result{KeyID: "new", ProvisionedAt: now, ValidUntil: now.Add(time.Hour)}, nil
+}
+
+func (s *stubProvider) DeleteKey(context.Context, *testObject, string) error { return s.err }
+
+func objWithKey(expiresIn time.Duration) *testObject {
+	obj := newTestObject()
+	now := time.Now()
+	obj.Status.ActiveKeys = framework.ActiveKeys{{
+		KeyID:     "old",
+		CreatedAt: metav1.NewTime(now.Add(-time.Hour)),
+		ExpiresAt: metav1.NewTime(now.Add(expiresIn)),
+	}}
+	return obj
+}
+
+func TestInstrument_RotationSLI(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	ip := framework.Instrument[*testObject](&stubProvider{}, reg)
+	ctx := context.Background()
+
+	// Provisioning alone is not a rotation; the reconciler records it once
+	// the new key is persisted.
+	if _, err := ip.Provision(ctx, objWithKey(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.CollectAndCount(ip.RotationTotal); got != 0 {
+		t.Fatalf("expected no rotation series after provisioning, got %d", got)
+	}
+
+	now := time.Now()
+	ip.RecordRotation(ctx, objWithKey(time.Hour).Status.ActiveKeys[0], now)
+	ip.RecordRotation(ctx, objWithKey(-time.Minute).Status.ActiveKeys[0], now)
+
+	onTime := testutil.ToFloat64(ip.RotationTotal.WithLabelValues("testObject", "true"))
+	late := testutil.ToFloat64(ip.RotationTotal.WithLabelValues("testObject", "false"))
+	if onTime != 1 || late != 1 {
+		t.Errorf("expected 1 on-time and 1 late rotation, got %v and %v", onTime, late)
+	}
+	if got := testutil.CollectAndCount(ip.RotationMargin); got != 1 {
+		t.Errorf("expected one margin histogram series, got %d", got)
+	}
+}
+
+func TestReconcile_RotationSLI(t *testing.T) {
+	tests := []struct {
+		name              string
+		generation        int64
+		failStatusUpdates bool
+		want              int
+	}{
+		{name: "renewal", generation: 1, want: 1},
+		{name: "spec change", generation: 2},
+		{name: "status update fails", generation: 1, failStatusUpdates: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := newTestScheme(t)
+			obj := objWithKey(-time.Minute)
+			obj.Finalizers = []string{framework.Finalizer}
+			obj.Generation = tt.generation
+			obj.Status.ObservedGeneration = 1
+			obj.Status.CurrentKeyID = "old"
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).
+				WithInterceptorFuncs(interceptor.Funcs{
+					SubResourceUpdate: func(
+						ctx context.Context, c client.Client, sub string, o client.Object, opts ...client.SubResourceUpdateOption,
+					) error {
+						if tt.failStatusUpdates {
+							return errors.New("etcd unavailable")
+						}
+						return c.SubResource(sub).Update(ctx, o, opts...)
+					},
+				}).Build()
+			ip := framework.Instrument[*testObject](&stubProvider{}, prometheus.NewRegistry())
+			r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: ip}
+
+			_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+			if (err != nil) != tt.failStatusUpdates {
+				t.Fatalf("reconcile: %v", err)
+			}
+			if got := testutil.ToFloat64(ip.RotationTotal.WithLabelValues("testObject", "false")); got != float64(tt.want) {
+				t.Errorf("got %v late rotations, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstrument_ActiveKeysExcess(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	ip := framework.Instrument[*testObject](&stubProvider{}, reg)
+
+	obj := objWithKey(time.Hour)
+	for range framework.ExpectedActiveKeys + 1 {
+		if _, err := ip.Provision(context.Background(), obj); err != nil {
+			t.Fatal(err)
+		}
+		obj.Status.ActiveKeys = append(obj.Status.ActiveKeys, obj.Status.ActiveKeys[0])
+	}
+	if got := testutil.ToFloat64(ip.ActiveKeysExcess.WithLabelValues("testObject")); got != 1 {
+		t.Errorf("expected 1 provision beyond the expected number of keys, got %v", got)
+	}
+}
+
+func TestInstrument_Exemplars(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	ip := framework.Instrument[*testObject](&stubProvider{}, reg)
+
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	}))
+	ip.RecordRotation(ctx, objWithKey(time.Hour).Status.ActiveKeys[0], time.Now())
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	rec := httptest.NewRecorder()
+	framework.OpenMetricsHandler(reg).ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `valet_rotation_total{kind="testObject",on_time="true"} 1.0 # {trace_id="4bf92f3577b34da6a3ce929d0e0e4736"}`) {
+		t.Errorf("expected rotation counter with exemplar, got:\n%s", body)
+	}
+}
+
+func TestMetricsServer_Exemplars(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	scheme := newTestScheme(t)
+	obj := objWithKey(-time.Minute)
+	obj.Finalizers = []string{framework.Finalizer}
+	obj.Generation = 1
+	obj.Status.ObservedGeneration = 1
+	obj.Status.CurrentKeyID = "old"
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{
+		Client:   c,
+		Scheme:   scheme,
+		Provider: framework.Instrument[*testObject](&stubProvider{}, reg),
+		Tracer:   tp.Tracer("test"),
+	}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	ended := spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("expected one reconcile span, got %d", len(ended))
+	}
+	traceID := ended[0].SpanContext().TraceID().String()
+
+	srv, err := metricsserver.NewServer(metricsserver.Options{
+		BindAddress:    "127.0.0.1:0",
+		FilterProvider: framework.WithOpenMetrics(reg, nil),
+	}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = srv.Start(ctx) }()
+
+	var body string
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("metrics server did not start")
+		}
+		addr := srv.(interface{ GetBindAddr() string }).GetBindAddr()
+		if addr == "" {
+			continue
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", "http://"+addr+"/metrics", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			continue
+		}
+		b, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body = string(b)
+		break
+	}
+
+	for _, series := range []string{
+		`valet_provision_total{result="success"} 1.0 # {trace_id="` + traceID + `"}`,
+		`valet_rotation_total{kind="testObject",on_time="false"} 1.0 # {trace_id="` + traceID + `"}`,
+	} {
+		if !strings.Contains(body, series) {
+			t.Errorf("expected %s, got:\n%s", series, body)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package framework
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/priorityqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// NamespaceDeletionPriority is the work queue priority of resources in a
+// terminating namespace, see [Reconciler.PrioritizeNamespaceDeletion]. Other
+// requests have priority 0, or lower if nothing changed.
+const NamespaceDeletionPriority = 100
+
+// namespaceDeletionHandler returns an event handler that enqueues the
+// resources in a namespace with [NamespaceDeletionPriority] whenever the
+// namespace is seen terminating. The resources are listed with reader, which
+// should be uncached: namespaces are rarely deleted, so keeping an informer
+// just for this is not worth it.
+func (r *Reconciler[O]) namespaceDeletionHandler(reader client.Reader, scheme *runtime.Scheme) handler.EventHandler {
+	enqueue := func(ctx context.Context, obj client.Object, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+		if obj.GetDeletionTimestamp().IsZero() {
+			return
+		}
+		log := log.FromContext(ctx).WithValues("namespace", obj.GetName())
+
+		gvk, err := apiutil.GVKForObject(r.Provider.NewObject(), scheme)
+		if err != nil {
+			log.Error(err, "failed to look up the resource kind")
+			return
+		}
+		list := &metav1.PartialObjectMetadataList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := reader.List(ctx, list, client.InNamespace(obj.GetName())); err != nil {
+			log.Error(err, "failed to list resources in terminating namespace")
+			return
+		}
+		if len(list.Items) == 0 {
+			return
+		}
+
+		log.Info("namespace is terminating, prioritizing cleanup", "count", len(list.Items))
+		reqs := make([]reconcile.Request, len(list.Items))
+		for i := range list.Items {
+			reqs[i] = reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])}
+		}
+		if pq, ok := q.(priorityqueue.PriorityQueue[reconcile.Request]); ok {
+			priority := NamespaceDeletionPriority
+			pq.AddWithOpts(priorityqueue.AddOpts{Priority: &priority}, reqs...)
+			return
+		}
+		for _, req := range reqs {
+			q.Add(req)
+		}
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(
+			ctx context.Context,
+			e event.CreateEvent,
+			q workqueue.TypedRateLimitingInterface[reconcile.Request],
+		) {
+			enqueue(ctx, e.Object, q)
+		},
+		UpdateFunc: func(
+			ctx context.Context,
+			e event.UpdateEvent,
+			q workqueue.TypedRateLimitingInterface[reconcile.Request],
+		) {
+			enqueue(ctx, e.ObjectNew, q)
+		},
+	}
+}
+
+// namespaceHandler is an event handler for namespace events behind its own
+// predicate, see [namespaceHandlers].
+type namespaceHandler struct {
+	handler.EventHandler
+	predicate.Predicate
+}
+
+// namespaceHandlers dispatches the events of a single namespace watch to
+// several handlers, so that replication and namespace deletion do not each
+// keep their own.
+type namespaceHandlers []namespaceHandler
+
+// Create implements [handler.EventHandler].
+func (hs namespaceHandlers) Create(
+	ctx context.Context,
+	e event.CreateEvent,
+	q workqueue.TypedRateLimitingInterface[reconcile.Request],
+) {
+	for _, h := range hs {
+		if h.Predicate.Create(e) {
+			h.EventHandler.Create(ctx, e, q)
+		}
+	}
+}
+
+// Update implements [handler.EventHandler].
+func (hs namespaceHandlers) Update(
+	ctx context.Context,
+	e event.UpdateEvent,
+	q workqueue.TypedRateLimitingInterface[reconcile.Request],
+) {
+	for _, h := range hs {
+		if h.Predicate.Update(e) {
+			h.EventHandler.Update(ctx, e, q)
+		}
+	}
+}
+
+// Delete implements [handler.EventHandler].
+func (hs namespaceHandlers) Delete(
+	ctx context.Context,
+	e event.DeleteEvent,
+	q workqueue.TypedRateLimitingInterface[reconcile.Request],
+) {
+	for _, h := range hs {
+		if h.Predicate.Delete(e) {
+			h.EventHandler.Delete(ctx, e, q)
+		}
+	}
+}
+
+// Generic implements [handler.EventHandler].
+func (hs namespaceHandlers) Generic(
+	ctx context.Context,
+	e event.GenericEvent,
+	q workqueue.TypedRateLimitingInterface[reconcile.Request],
+) {
+	for _, h := range hs {
+		if h.Predicate.Generic(e) {
+			h.EventHandler.Generic(ctx, e, q)
+		}
+	}
+}
+
+// recordNamespaceCleanupPending records a Warning event on the namespace of
+// obj if it is terminating, as the namespace cannot finalize until the
+// undeleted keys are cleaned up. The event is recorded on the namespace rather
+// than on obj, because a terminating namespace accepts no new events.
+func (r *Reconciler[O]) recordNamespaceCleanupPending(ctx context.Context, obj O, undeleted []string) {
+	if !r.PrioritizeNamespaceDeletion || r.Recorder == nil {
+		return
+	}
+
+	var ns corev1.Namespace
+	if err := r.Get(ctx, client.ObjectKey{Name: obj.GetNamespace()}, &ns); err != nil {
+		log.FromContext(ctx).Error(err, "failed to get namespace")
+		return
+	}
+	if ns.DeletionTimestamp.IsZero() {
+		return
+	}
+
+	r.Recorder.Eventf(&ns, obj, corev1.EventTypeWarning, "CleanupPending", "Finalize",
+		"Namespace deletion waits for %s to delete keys %s at the provider",
+		obj.GetName(), strings.Join(undeleted, ", "))
+}
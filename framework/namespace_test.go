@@ -0,0 +1,69 @@
+package framework_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcile_NamespaceCleanupPending(t *testing.T) {
+	tests := []struct {
+		name        string
+		enabled     bool
+		terminating bool
+		wantEvent   bool
+	}{
+		{name: "terminating namespace", enabled: true, terminating: true, wantEvent: true},
+		{name: "active namespace", enabled: true},
+		{name: "disabled", terminating: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := newTestScheme(t)
+			obj := newDeletingObject(1)
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: obj.Namespace}}
+			if tt.terminating {
+				ns.Finalizers = []string{"kubernetes"}
+				ns.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, ns).WithStatusSubresource(obj).Build()
+			recorder := events.NewFakeRecorder(10)
+			r := &framework.Reconciler[*testObject]{
+				Client:                      c,
+				Scheme:                      scheme,
+				Provider:                    &deleteProvider{fail: map[string]bool{"key-0": true}},
+				Recorder:                    recorder,
+				PrioritizeNamespaceDeletion: tt.enabled,
+			}
+
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+			if _, err := r.Reconcile(context.Background(), req); err == nil {
+				t.Fatal("expected deletion to be blocked")
+			}
+
+			select {
+			case event := <-recorder.Events:
+				if !tt.wantEvent {
+					t.Fatalf("unexpected event: %s", event)
+				}
+				if !strings.Contains(event, "Warning CleanupPending") || !strings.Contains(event, "key-0") {
+					t.Errorf("unexpected event: %s", event)
+				}
+			default:
+				if tt.wantEvent {
+					t.Error("expected a CleanupPending event")
+				}
+			}
+		})
+	}
+}
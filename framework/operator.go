@@ -0,0 +1,440 @@
+package framework
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lukasngl/valet/framework/envelope"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// Options are the command line options shared by all provider binaries.
+// Each field is set by the flag of the same name, see [Options.BindFlags].
+type Options struct {
+	MetricsAddr                 string
+	ProbeAddr                   string
+	SecureMetrics               bool
+	DebugEndpoints              bool
+	LeaderElection              bool
+	EnableHTTP2                 bool
+	ForceDeleteAfter            time.Duration
+	PrioritizeNamespaceDeletion bool
+	RestartWorkloads            bool
+	SplitOversizedSecrets       bool
+	KeyStore                    string
+	AllowSimulateExpiry         bool
+	PropagateLabels             string
+	ClockSkew                   time.Duration
+	PendingTimeout              time.Duration
+	StuckDeletionTimeout        time.Duration
+	SlowReconcileThreshold      time.Duration
+	MaintenanceWindows          string
+	PolicyDir                   string
+	PushSecretStore             string
+	EnvelopeKeyFile             string
+	PrintEndpoints              bool
+	LogLevelFile                string
+	Chaos                       string
+	OTLPEndpoint                string
+
+	// Zap configures logging with the --zap-* flags.
+	Zap zap.Options
+}
+
+// BindFlags registers the options as flags of fs. The provider charts pass
+// them from their values with the valet.args template of the
+// framework/chart/valet library chart.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.MetricsAddr, "metrics-bind-address", ":8080", "Metrics endpoint bind address.")
+	fs.StringVar(&o.ProbeAddr, "health-probe-bind-address", ":8081", "Health probe bind address.")
+	fs.BoolVar(&o.SecureMetrics, "metrics-secure", false,
+		"Serve metrics over HTTPS and only to authenticated and authorized clients.")
+	fs.BoolVar(&o.DebugEndpoints, "debug-endpoints", false,
+		"Serve support bundles of single resources on /debug/clientsecrets/<namespace>/<name> next to the "+
+			"metrics. Requires --metrics-secure.")
+	fs.BoolVar(&o.LeaderElection, "leader-elect", false, "Enable leader election.")
+	fs.BoolVar(&o.EnableHTTP2, "enable-http2", false, "Enable HTTP/2 for metrics and webhooks.")
+	fs.DurationVar(&o.ForceDeleteAfter, "force-delete-after", 0,
+		"Remove the finalizer of resources whose keys still could not be deleted this long after "+
+			"deletion was requested. Zero disables this.")
+	fs.BoolVar(&o.PrioritizeNamespaceDeletion, "prioritize-namespace-deletion", false,
+		"Watch namespaces and clean up the resources in terminating namespaces before other work.")
+	fs.BoolVar(&o.RestartWorkloads, "restart-workloads", false,
+		"Restart the Deployments and StatefulSets referencing an output Secret, as kubectl rollout restart "+
+			"does, once its data changes.")
+	fs.BoolVar(&o.SplitOversizedSecrets, "split-oversized-secrets", false,
+		"Split rendered data larger than the Secret size limit across several Secrets instead of failing.")
+	fs.StringVar(&o.KeyStore, "key-store", "",
+		"Where to keep the bookkeeping of active keys: empty for the resource status, or \"configmap\" for "+
+			"<name>-<uid>-valet-keys ConfigMaps next to the resources, for long key histories.")
+	fs.BoolVar(&o.AllowSimulateExpiry, "allow-simulate-expiry", false,
+		"Honor the valet.ngl.cx/simulate-expiry annotation, which rotates a resource's key on demand.")
+	fs.StringVar(&o.PropagateLabels, "propagate-labels", "",
+		"Comma-separated labels to copy from the resources to their output Secrets. "+
+			"Entries ending in * select all labels with that prefix.")
+	fs.DurationVar(&o.ClockSkew, "clock-skew", 0,
+		"Allowance for the provider's clock differing from ours: keys are renewed this much earlier "+
+			"and deleted this much later than their expiry.")
+	fs.DurationVar(&o.PendingTimeout, "pending-timeout", 0,
+		"Report resources that have been Pending without status updates for this long. Zero disables this.")
+	fs.DurationVar(&o.StuckDeletionTimeout, "stuck-deletion-timeout", 0,
+		"Report resources whose deletion has been blocked by the finalizer for this long. Zero disables this.")
+	fs.DurationVar(&o.SlowReconcileThreshold, "slow-reconcile-threshold", 0,
+		"Report reconciliations that take longer than this, e.g. because the provider hangs. Zero disables this.")
+	fs.StringVar(&o.MaintenanceWindows, "maintenance-windows", "",
+		"Comma-separated provider maintenance windows in UTC, e.g. \"Sun 02:00-04:00,23:30-00:30\", "+
+			"during which renewals are deferred.")
+	fs.StringVar(&o.PolicyDir, "policy-dir", "",
+		"Directory of CEL policies, one per file, that resources must satisfy to be provisioned, e.g. a mounted "+
+			"ConfigMap. It is re-read periodically. Empty disables this.")
+	fs.StringVar(&o.PushSecretStore, "push-secret-store", "",
+		"Name of an external-secrets ClusterSecretStore to publish the output Secrets of resources annotated "+
+			"with valet.ngl.cx/push-remote-key to. Empty disables this.")
+	fs.StringVar(&o.EnvelopeKeyFile, "envelope-key-file", "",
+		"File holding a 32-byte key encryption key, raw or base64. If set, the values of output Secrets are "+
+			"envelope-encrypted with it.")
+	fs.BoolVar(&o.PrintEndpoints, "print-endpoints", false,
+		"Print the chart's networkPolicy.endpoints values for the endpoints the provider connects to, and exit.")
+	fs.StringVar(&o.LogLevelFile, "log-level-file", "",
+		"File holding the log level, e.g. a key of a mounted ConfigMap. It is re-read periodically and on "+
+			"SIGHUP, and overrides --zap-log-level while it is set.")
+	fs.StringVar(&o.Chaos, "chaos", "",
+		"Inject faults into provider calls to rehearse alerts, e.g. \"failure=0.2,delay=30s\" fails 20% of the "+
+			"calls and delays each by up to 30s. Only available in builds with the chaos tag; never use in production.")
+	fs.StringVar(&o.OTLPEndpoint, "otlp-endpoint", "",
+		"OTLP gRPC endpoint of an OpenTelemetry collector to export a trace of each reconciliation to, e.g. "+
+			"\"http://otel-collector:4317\"; http disables TLS. The metrics then carry the trace IDs as exemplars. "+
+			"Empty disables tracing.")
+	o.Zap.BindFlags(fs)
+}
+
+// PropagatedLabels returns the labels selected with --propagate-labels, with
+// surrounding spaces trimmed and empty entries skipped, so that the default
+// empty flag selects none.
+func (o *Options) PropagatedLabels() []string {
+	var labels []string
+	for _, l := range strings.Split(o.PropagateLabels, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			labels = append(labels, l)
+		}
+	}
+	return labels
+}
+
+// NewKeyStore returns the [KeyStore] selected with --key-store for the
+// manager's clients, or nil to keep the keys in the status. Components
+// reading the keys besides the reconciler, e.g. garbage collectors, use the
+// same key store.
+func (o *Options) NewKeyStore(mgr ctrl.Manager, provider string) (KeyStore, error) {
+	switch o.KeyStore {
+	case "":
+		return nil, nil
+	case "configmap":
+		return &ConfigMapKeyStore{
+			Client:   mgr.GetClient(),
+			Reader:   mgr.GetAPIReader(),
+			Scheme:   mgr.GetScheme(),
+			Provider: provider,
+		}, nil
+	default:
+		return nil, fmt.Errorf("--key-store: unknown key store %q", o.KeyStore)
+	}
+}
+
+// Operator describes a provider binary started by [Run].
+type Operator[O Object] struct {
+	// Name is the name of the binary, e.g. provider-azure. It names the
+	// source of events and, suffixed with .valet.ngl.cx, the leader
+	// election lease.
+	Name string
+
+	// BuildInfo describes the binary, see [NewBuildInfo].
+	BuildInfo BuildInfo
+
+	// AddToScheme registers the provider's API types.
+	AddToScheme func(*runtime.Scheme) error
+
+	// Endpoints, if set, declares the endpoints the provider connects to.
+	// They are checked and logged at startup, and printed with
+	// --print-endpoints, see [CheckEndpoints].
+	Endpoints EndpointDeclarer
+
+	// Proxy chooses the proxy of the Endpoints. It defaults to
+	// [http.ProxyFromEnvironment].
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// Provider is the provider the reconciler calls, unless NewProvider is
+	// set.
+	Provider Provider[O]
+
+	// NewProvider, if set, returns the provider once the manager is created,
+	// so that it can use the manager's clients.
+	NewProvider func(ctx context.Context, mgr ctrl.Manager) (Provider[O], error)
+
+	// Setup, if set, adds further runnables of the provider to the manager,
+	// e.g. garbage collectors.
+	Setup func(mgr ctrl.Manager) error
+
+	// LogValues are logged with the build info when the manager starts.
+	LogValues []any
+}
+
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get
+// +kubebuilder:rbac:groups=external-secrets.io,resources=pushsecrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// Run starts the controller manager of a provider binary configured by opts,
+// which must have been parsed, and blocks until ctx is done.
+func Run[O Object](ctx context.Context, opts *Options, op Operator[O]) error {
+	// Logging
+	logLevel := NewLogLevel(&opts.Zap)
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts.Zap)))
+
+	setupLog := ctrl.Log.WithName("setup")
+
+	chaosConfig, err := ParseChaosConfig(opts.Chaos)
+	if err != nil {
+		return fmt.Errorf("parsing --chaos: %w", err)
+	}
+	if chaosConfig.Enabled() {
+		setupLog.Info("chaos mode enabled, provider calls will be delayed and fail randomly",
+			"failureProbability", chaosConfig.FailureProbability, "maxDelay", chaosConfig.MaxDelay)
+	}
+
+	windows, err := ParseMaintenanceWindows(opts.MaintenanceWindows)
+	if err != nil {
+		return fmt.Errorf("parsing --maintenance-windows: %w", err)
+	}
+
+	var policies *PolicySet
+	if opts.PolicyDir != "" {
+		loaded, err := LoadPolicies(opts.PolicyDir)
+		if err != nil {
+			return fmt.Errorf("loading --policy-dir: %w", err)
+		}
+		policies = &PolicySet{}
+		policies.Set(loaded)
+	}
+
+	if opts.DebugEndpoints && !opts.SecureMetrics {
+		return errors.New("--debug-endpoints requires --metrics-secure")
+	}
+
+	// Endpoints
+	var routes []EndpointRoute
+	if op.Endpoints != nil {
+		proxy := op.Proxy
+		if proxy == nil {
+			proxy = http.ProxyFromEnvironment
+		}
+		routes, err = CheckEndpoints(op.Endpoints, proxy)
+		if err != nil {
+			return fmt.Errorf("checking endpoints: %w", err)
+		}
+	}
+	if opts.PrintEndpoints {
+		return WriteEndpointValues(os.Stdout, routes)
+	}
+	for _, r := range routes {
+		setupLog.Info("endpoint", "name", r.Name, "address", r.String(), "via", r.Via())
+	}
+
+	// Scheme
+	scheme := runtime.NewScheme()
+	utilruntime.Must(corev1.AddToScheme(scheme))
+	utilruntime.Must(op.AddToScheme(scheme))
+
+	// TLS
+	tlsOpts := []func(*tls.Config){}
+	if !opts.EnableHTTP2 {
+		tlsOpts = append(tlsOpts, func(c *tls.Config) {
+			c.NextProtos = []string{"http/1.1"}
+		})
+	}
+
+	// Manager
+	mgrOpts := ctrl.Options{
+		Scheme: scheme,
+		Metrics: metricsserver.Options{
+			BindAddress:   opts.MetricsAddr,
+			SecureServing: opts.SecureMetrics,
+			TLSOpts:       tlsOpts,
+			ExtraHandlers: map[string]http.Handler{
+				"/version": op.BuildInfo.Handler(),
+			},
+		},
+		WebhookServer:          webhook.NewServer(webhook.Options{TLSOpts: tlsOpts}),
+		HealthProbeBindAddress: opts.ProbeAddr,
+		LeaderElection:         opts.LeaderElection,
+		LeaderElectionID:       op.Name + ".valet.ngl.cx",
+	}
+
+	// Clients of the secure metrics endpoint are authenticated with
+	// TokenReviews and authorized with SubjectAccessReviews. The metrics
+	// are served in OpenMetrics format to clients accepting it, which
+	// carries the exemplars.
+	var metricsFilter func(*rest.Config, *http.Client) (metricsserver.Filter, error)
+	if opts.SecureMetrics {
+		metricsFilter = filters.WithAuthenticationAndAuthorization
+	}
+	mgrOpts.Metrics.FilterProvider = WithOpenMetrics(metrics.Registry, metricsFilter)
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), mgrOpts)
+	if err != nil {
+		return fmt.Errorf("creating manager: %w", err)
+	}
+	if err := op.BuildInfo.Register(metrics.Registry); err != nil {
+		return fmt.Errorf("registering build info metric: %w", err)
+	}
+
+	// Envelope encryption
+	var keyWrapper envelope.KeyWrapper
+	if opts.EnvelopeKeyFile != "" {
+		kw, err := envelope.LoadAESKeyWrapper(opts.EnvelopeKeyFile)
+		if err != nil {
+			return fmt.Errorf("loading envelope key: %w", err)
+		}
+		keyWrapper = kw
+	}
+
+	// Tracing
+	var tracer trace.Tracer
+	if opts.OTLPEndpoint != "" {
+		exporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithEndpointURL(opts.OTLPEndpoint))
+		if err != nil {
+			return fmt.Errorf("creating OTLP exporter: %w", err)
+		}
+		tp := sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(exporter),
+			sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", op.Name))),
+		)
+		defer func() {
+			if err := tp.Shutdown(context.Background()); err != nil {
+				setupLog.Error(err, "flushing traces")
+			}
+		}()
+		tracer = tp.Tracer("github.com/lukasngl/valet/framework")
+	}
+
+	// Provider
+	provider := op.Provider
+	if op.NewProvider != nil {
+		provider, err = op.NewProvider(ctx, mgr)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Controller
+	keyStore, err := opts.NewKeyStore(mgr, op.BuildInfo.Provider)
+	if err != nil {
+		return err
+	}
+	reconciler := &Reconciler[O]{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Provider: Instrument(Chaos(provider, chaosConfig), metrics.Registry),
+		Recorder: mgr.GetEventRecorder(op.Name),
+
+		ForceDeleteAfter:            opts.ForceDeleteAfter,
+		PrioritizeNamespaceDeletion: opts.PrioritizeNamespaceDeletion,
+		RestartWorkloads:            opts.RestartWorkloads,
+		SplitOversizedSecrets:       opts.SplitOversizedSecrets,
+		KeyStore:                    keyStore,
+		AllowSimulateExpiry:         opts.AllowSimulateExpiry,
+		PropagateLabels:             opts.PropagatedLabels(),
+		ClockSkew:                   opts.ClockSkew,
+		PendingTimeout:              opts.PendingTimeout,
+		StuckDeletionTimeout:        opts.StuckDeletionTimeout,
+		SlowReconcileThreshold:      opts.SlowReconcileThreshold,
+		MaintenanceWindows:          windows,
+		Policies:                    policies,
+		PushSecretStore:             opts.PushSecretStore,
+		Envelope:                    keyWrapper,
+		ProviderName:                op.BuildInfo.Provider,
+		Tracer:                      tracer,
+	}
+
+	if err := RegisterProviderMetrics(reconciler.Provider, op.BuildInfo.Provider, metrics.Registry); err != nil {
+		return fmt.Errorf("registering provider metrics: %w", err)
+	}
+	if err := reconciler.SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("setting up controller: %w", err)
+	}
+	if op.Setup != nil {
+		if err := op.Setup(mgr); err != nil {
+			return err
+		}
+	}
+
+	// Debug endpoints
+	if opts.DebugEndpoints {
+		reconciler.Debug = &DebugRecorder{}
+		if err := mgr.AddMetricsServerExtraHandler(DebugPath, reconciler.DebugHandler()); err != nil {
+			return fmt.Errorf("setting up debug endpoints: %w", err)
+		}
+	}
+
+	// Policies
+	if policies != nil {
+		if err := mgr.Add(&PolicyWatcher{
+			Dir: opts.PolicyDir,
+			Set: policies,
+			Log: ctrl.Log.WithName("policies"),
+		}); err != nil {
+			return fmt.Errorf("setting up policy watcher: %w", err)
+		}
+	}
+
+	// Log level
+	if opts.LogLevelFile != "" {
+		if err := mgr.Add(&LogLevelWatcher{
+			Path:  opts.LogLevelFile,
+			Level: logLevel,
+			Log:   ctrl.Log.WithName("log-level"),
+		}); err != nil {
+			return fmt.Errorf("setting up log level watcher: %w", err)
+		}
+	}
+
+	// Health probes
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		return fmt.Errorf("setting up health check: %w", err)
+	}
+
+	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		return fmt.Errorf("setting up ready check: %w", err)
+	}
+
+	setupLog.Info("starting manager", append(op.BuildInfo.KeysAndValues(), op.LogValues...)...)
+
+	return mgr.Start(ctx)
+}
@@ -0,0 +1,90 @@
+package framework_test
+
+import (
+	"flag"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+)
+
+func TestOptionsBindFlags(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		var opts framework.Options
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		opts.BindFlags(fs)
+		if err := fs.Parse(nil); err != nil {
+			t.Fatal(err)
+		}
+		if opts.MetricsAddr != ":8080" || opts.ProbeAddr != ":8081" {
+			t.Fatalf("got addresses %q and %q, want :8080 and :8081", opts.MetricsAddr, opts.ProbeAddr)
+		}
+		if opts.LeaderElection || opts.RestartWorkloads || opts.ClockSkew != 0 {
+			t.Fatalf("optional features enabled by default: %+v", opts)
+		}
+	})
+
+	t.Run("chart arguments", func(t *testing.T) {
+		var opts framework.Options
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		opts.BindFlags(fs)
+		err := fs.Parse([]string{
+			"--metrics-bind-address=:9443",
+			"--metrics-secure",
+			"--leader-elect",
+			"--restart-workloads",
+			"--propagate-labels=team,app.kubernetes.io/*",
+			"--clock-skew=2m",
+			"--policy-dir=/etc/valet/policies",
+			"--zap-log-level=debug",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opts.MetricsAddr != ":9443" || !opts.SecureMetrics || !opts.LeaderElection || !opts.RestartWorkloads {
+			t.Fatalf("flags not bound: %+v", opts)
+		}
+		if opts.PropagateLabels != "team,app.kubernetes.io/*" {
+			t.Fatalf("got propagated labels %q", opts.PropagateLabels)
+		}
+		if opts.ClockSkew != 2*time.Minute {
+			t.Fatalf("got clock skew %s, want 2m", opts.ClockSkew)
+		}
+		if opts.PolicyDir != "/etc/valet/policies" {
+			t.Fatalf("got policy dir %q", opts.PolicyDir)
+		}
+		if opts.Zap.Level == nil {
+			t.Fatal("zap flags not bound")
+		}
+	})
+}
+
+func TestOptionsPropagatedLabels(t *testing.T) {
+	for _, tt := range []struct {
+		flag string
+		want []string
+	}{
+		{flag: "", want: nil},
+		{flag: "team", want: []string{"team"}},
+		{flag: "team, app.kubernetes.io/* ,", want: []string{"team", "app.kubernetes.io/*"}},
+	} {
+		opts := framework.Options{PropagateLabels: tt.flag}
+		if got := opts.PropagatedLabels(); !slices.Equal(got, tt.want) {
+			t.Errorf("--propagate-labels=%q: got %q, want %q", tt.flag, got, tt.want)
+		}
+	}
+}
+
+func TestOptionsNewKeyStore(t *testing.T) {
+	opts := framework.Options{}
+	if store, err := opts.NewKeyStore(nil, "provider-test"); err != nil || store != nil {
+		t.Fatalf("got key store %v, err %v without --key-store, want none", store, err)
+	}
+	opts.KeyStore = "etcd"
+	if _, err := opts.NewKeyStore(nil, "provider-test"); err == nil {
+		t.Fatal("expected an error for an unknown key store")
+	}
+}
@@ -0,0 +1,88 @@
+package framework
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxOverrideWindow is how far ahead [AnnotationOverrideUntil] may be, so
+// that overrides meant to be temporary cannot silently become permanent.
+const MaxOverrideWindow = 30 * 24 * time.Hour
+
+// rotationOverrides are the rotation parameters a resource overrides with
+// annotations.
+type rotationOverrides struct {
+	// renewBefore is the [AnnotationRenewBefore], or zero.
+	renewBefore time.Duration
+	// until is the [AnnotationOverrideUntil].
+	until time.Time
+}
+
+// parseOverrides returns the rotation parameters obj overrides, which are
+// zero if it has no override annotations.
+func parseOverrides(obj Object) (rotationOverrides, error) {
+	annotations := obj.GetAnnotations()
+	var o rotationOverrides
+	if v, ok := annotations[AnnotationRenewBefore]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return o, fmt.Errorf("annotation %s: %q is not a positive duration", AnnotationRenewBefore, v)
+		}
+		o.renewBefore = d
+	}
+	if o.renewBefore == 0 {
+		return o, nil
+	}
+
+	v, ok := annotations[AnnotationOverrideUntil]
+	if !ok {
+		return o, fmt.Errorf("annotation %s requires %s, the time until which it applies",
+			AnnotationRenewBefore, AnnotationOverrideUntil)
+	}
+	until, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return o, fmt.Errorf("annotation %s: %w", AnnotationOverrideUntil, err)
+	}
+	o.until = until
+	return o, nil
+}
+
+// validateOverrides checks the override annotations of obj at now.
+func validateOverrides(obj Object, now time.Time) error {
+	o, err := parseOverrides(obj)
+	if err != nil {
+		return err
+	}
+	if o.until.Sub(now) > MaxOverrideWindow {
+		return fmt.Errorf("annotation %s: %s is more than %s ahead",
+			AnnotationOverrideUntil, o.until.Format(time.RFC3339), MaxOverrideWindow)
+	}
+	return nil
+}
+
+// active reports whether the overrides apply at now.
+func (o rotationOverrides) active(now time.Time) bool {
+	return o.renewBefore > 0 && now.Before(o.until)
+}
+
+// renewalSkew returns the skew to check and schedule the renewal of obj with
+// at now: [Reconciler.ClockSkew], moved by an active [AnnotationRenewBefore]
+// so that the renewal window of the newest key becomes as long as it asks.
+// Widening the renewal window by the skew is the same as starting it earlier.
+func (r *Reconciler[O]) renewalSkew(obj O, now time.Time) time.Duration {
+	o, err := parseOverrides(obj)
+	newest := obj.GetStatus().ActiveKeys.Newest()
+	if err != nil || !o.active(now) || newest == nil {
+		return r.ClockSkew
+	}
+	return r.ClockSkew + o.renewBefore - newest.RenewalWindow()
+}
+
+// overrideEnd returns when the active overrides of obj end at now, if any.
+func overrideEnd(obj Object, now time.Time) (time.Time, bool) {
+	o, err := parseOverrides(obj)
+	if err != nil || !o.active(now) {
+		return time.Time{}, false
+	}
+	return o.until, true
+}
@@ -0,0 +1,133 @@
+package framework_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// steadyObject returns a resource whose current key is valid for another 30
+// days and written to its output secret, so it is not due for renewal.
+func steadyObject(annotations map[string]string) (*testObject, *corev1.Secret) {
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	obj.Annotations = annotations
+	now := time.Now()
+	obj.Status.ActiveKeys = framework.ActiveKeys{{
+		KeyID:     "current",
+		CreatedAt: metav1.NewTime(now.Add(-60 * 24 * time.Hour)),
+		ExpiresAt: metav1.NewTime(now.Add(30 * 24 * time.Hour)),
+	}}
+	obj.Status.CurrentKeyID = "current"
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-secret", Namespace: "default"},
+		Data:       map[string][]byte{"KEY": []byte("current")},
+	}
+	return obj, secret
+}
+
+func TestReconcile_RenewBeforeOverride(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name          string
+		until         time.Time
+		wantProvision bool
+	}{
+		{name: "active", until: now.Add(time.Hour), wantProvision: true},
+		{name: "ended", until: now.Add(-time.Hour), wantProvision: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := newTestScheme(t)
+			obj, secret := steadyObject(map[string]string{
+				framework.AnnotationRenewBefore:   "840h",
+				framework.AnnotationOverrideUntil: tt.until.Format(time.RFC3339),
+			})
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).WithStatusSubresource(obj).Build()
+			p := &countingProvider{}
+			r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p}
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+			if _, err := r.Reconcile(context.Background(), req); err != nil {
+				t.Fatalf("reconcile: %v", err)
+			}
+			if got := p.provisions == 1; got != tt.wantProvision {
+				t.Errorf("got %d provisions, want provisioning %v", p.provisions, tt.wantProvision)
+			}
+		})
+	}
+}
+
+func TestReconcile_OverrideRequeuesAtEnd(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj, secret := steadyObject(map[string]string{
+		framework.AnnotationRenewBefore:   "1h",
+		framework.AnnotationOverrideUntil: time.Now().Add(2 * time.Hour).Format(time.RFC3339),
+	})
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: &countingProvider{}}
+
+	res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if res.RequeueAfter <= 0 || res.RequeueAfter > 2*time.Hour {
+		t.Errorf("expected a requeue when the override ends, got %v", res.RequeueAfter)
+	}
+}
+
+func TestReconcile_InvalidOverride(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantErr     string
+	}{
+		{
+			name:        "without end",
+			annotations: map[string]string{framework.AnnotationRenewBefore: "24h"},
+			wantErr:     "requires " + framework.AnnotationOverrideUntil,
+		},
+		{
+			name:        "not a duration",
+			annotations: map[string]string{framework.AnnotationRenewBefore: "1d"},
+			wantErr:     "is not a positive duration",
+		},
+		{
+			name: "too far ahead",
+			annotations: map[string]string{
+				framework.AnnotationRenewBefore:   "24h",
+				framework.AnnotationOverrideUntil: time.Now().Add(2 * framework.MaxOverrideWindow).Format(time.RFC3339),
+			},
+			wantErr: "ahead",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := newTestScheme(t)
+			obj, secret := steadyObject(tt.annotations)
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).WithStatusSubresource(obj).Build()
+			r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: &countingProvider{}}
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+			if _, err := r.Reconcile(context.Background(), req); err != nil {
+				t.Fatalf("reconcile: %v", err)
+			}
+			var got testObject
+			if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.Phase != framework.PhaseFailed || !strings.Contains(got.Status.LastFailureMessage, tt.wantErr) {
+				t.Errorf("expected a failed status mentioning %q, got %s: %s",
+					tt.wantErr, got.Status.Phase, got.Status.LastFailureMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/cel-go/cel"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ErrPolicyViolation is returned when a resource violates a policy of
+// [Reconciler.Policies].
+var ErrPolicyViolation = errors.New("policy violation")
+
+// DefaultPolicyInterval is how often [PolicyWatcher] re-reads its directory
+// by default.
+const DefaultPolicyInterval = 10 * time.Second
+
+// Policy is a CEL expression that a resource must satisfy before it is
+// provisioned, e.g. to limit the validity of credentials in some namespaces.
+// The expression must evaluate to a bool and can use:
+//
+//   - object: the resource, as it is encoded in JSON
+//   - kind: the kind of the resource, e.g. "AzureClientSecret"
+//   - validity: the requested validity as a duration, see
+//     [ValidityRequester], or zero for the provider's default
+//
+// For example, `object.metadata.namespace != "prod" || validity <=
+// duration("720h")` denies validities of more than 30 days in namespace prod.
+type Policy struct {
+	// Name identifies the policy in violations.
+	Name string
+
+	// Expression is the CEL expression.
+	Expression string
+
+	program cel.Program
+}
+
+// policyEnv declares the variables available to policies.
+var policyEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("object", cel.DynType),
+		cel.Variable("kind", cel.StringType),
+		cel.Variable("validity", cel.DurationType),
+	)
+})
+
+// NewPolicy compiles expression into a [Policy].
+func NewPolicy(name, expression string) (Policy, error) {
+	env, err := policyEnv()
+	if err != nil {
+		return Policy{}, fmt.Errorf("creating CEL environment: %w", err)
+	}
+	ast, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return Policy{}, fmt.Errorf("compiling policy %s: %w", name, issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return Policy{}, fmt.Errorf("policy %s must evaluate to a bool, got %s", name, ast.OutputType())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return Policy{}, fmt.Errorf("compiling policy %s: %w", name, err)
+	}
+	return Policy{Name: name, Expression: expression, program: program}, nil
+}
+
+// LoadPolicies compiles the policies in dir, one per file named after the
+// policy, e.g. the keys of a mounted ConfigMap. Hidden files, such as the
+// links the kubelet creates in ConfigMap volumes, are skipped.
+func LoadPolicies(dir string) ([]Policy, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading policies: %w", err)
+	}
+
+	var policies []Policy
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") || e.IsDir() {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading policy %s: %w", e.Name(), err)
+		}
+		p, err := NewPolicy(e.Name(), strings.TrimSpace(string(b)))
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+// PolicySet holds the policies the reconciler evaluates, see
+// [Reconciler.Policies]. It is safe for concurrent use, so that a
+// [PolicyWatcher] can replace them while resources are reconciled.
+type PolicySet struct {
+	mu       sync.RWMutex
+	policies []Policy
+}
+
+// Set replaces the policies.
+func (s *PolicySet) Set(policies []Policy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policies = policies
+}
+
+// Evaluate returns the violations of the policies by obj of the given kind,
+// in the order of the policies. Policies that fail to evaluate, e.g. because
+// a field they use is not set, count as violated, so that they fail closed.
+func (s *PolicySet) Evaluate(obj Object, kind string) ([]string, error) {
+	s.mu.RLock()
+	policies := s.policies
+	s.mu.RUnlock()
+	if len(policies) == 0 {
+		return nil, nil
+	}
+
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, fmt.Errorf("converting resource: %w", err)
+	}
+	var validity time.Duration
+	if v, ok := obj.(ValidityRequester); ok {
+		validity = v.GetValidity()
+	}
+	vars := map[string]any{"object": object, "kind": kind, "validity": validity}
+
+	var violations []string
+	for _, p := range policies {
+		out, _, err := p.program.Eval(vars)
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("policy %s failed to evaluate: %v", p.Name, err))
+			continue
+		}
+		if allowed, ok := out.Value().(bool); !ok || !allowed {
+			violations = append(violations, fmt.Sprintf("denied by policy %s: %s", p.Name, p.Expression))
+		}
+	}
+	return violations, nil
+}
+
+// checkPolicies evaluates [Reconciler.Policies] for obj and records the
+// outcome in the PolicyViolation condition. It returns an
+// [ErrPolicyViolation] if obj violates a policy, which is persisted with the
+// failed status.
+func (r *Reconciler[O]) checkPolicies(ctx context.Context, obj O) error {
+	if r.Policies == nil {
+		return nil
+	}
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return fmt.Errorf("looking up the resource kind: %w", err)
+	}
+	violations, err := r.Policies.Evaluate(obj, gvk.Kind)
+	if err != nil {
+		return err
+	}
+
+	status := obj.GetStatus()
+	if len(violations) == 0 {
+		status.ClearPolicyViolation()
+		return nil
+	}
+
+	message := strings.Join(violations, "; ")
+	if status.SetPolicyViolation(obj.GetGeneration(), message) {
+		log.FromContext(ctx).Info("resource violates policies", "violations", violations)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(obj, nil, corev1.EventTypeWarning, ReasonPolicyDenied, "Reconcile",
+				"Not provisioning credentials: %s", message)
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrPolicyViolation, message)
+}
+
+// PolicyWatcher loads the policies in a directory, usually a mounted
+// ConfigMap, into a [PolicySet] and re-reads them periodically, so that
+// policies can be changed without a restart. If the directory holds an
+// invalid policy, the error is logged and the previous policies are kept.
+//
+// It implements [sigs.k8s.io/controller-runtime/pkg/manager.Runnable] and
+// runs on all replicas, not only the leader.
+type PolicyWatcher struct {
+	// Dir is the directory holding the policies, see [LoadPolicies].
+	Dir string
+
+	// Set receives the policies.
+	Set *PolicySet
+
+	// Interval is how often the directory is re-read. Defaults to
+	// [DefaultPolicyInterval].
+	Interval time.Duration
+
+	// Log receives policy changes and errors loading them.
+	Log logr.Logger
+
+	// last are the expressions last loaded, by name.
+	last map[string]string
+}
+
+// Start watches the directory until ctx is done.
+func (w *PolicyWatcher) Start(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultPolicyInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	w.reload()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			w.reload()
+		}
+	}
+}
+
+// NeedLeaderElection implements
+// [sigs.k8s.io/controller-runtime/pkg/manager.LeaderElectionRunnable], so that
+// standby replicas enforce the same policies once they lead.
+func (w *PolicyWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+// reload loads the policies if they changed.
+func (w *PolicyWatcher) reload() {
+	policies, err := LoadPolicies(w.Dir)
+	if err != nil {
+		w.Log.Error(err, "keeping previous policies", "dir", w.Dir)
+		return
+	}
+
+	expressions := make(map[string]string, len(policies))
+	names := make([]string, 0, len(policies))
+	for _, p := range policies {
+		expressions[p.Name] = p.Expression
+		names = append(names, p.Name)
+	}
+	if w.last != nil && maps.Equal(expressions, w.last) {
+		return
+	}
+	w.last = expressions
+
+	w.Log.Info("loaded policies", "dir", w.Dir, "policies", names)
+	w.Set.Set(policies)
+}
@@ -0,0 +1,121 @@
+package framework_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	"k8s.io/apimachinery/pkg/api/meta"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNewPolicy_Invalid(t *testing.T) {
+	for _, expr := range []string{`object.metadata.name +`, `object.metadata.name`} {
+		if _, err := framework.NewPolicy("p", expr); err == nil {
+			t.Errorf("expected an error for %q", expr)
+		}
+	}
+}
+
+func TestPolicySet_Evaluate(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		wantDenied bool
+	}{
+		{name: "allowed", expression: `object.metadata.namespace != "prod"`, wantDenied: false},
+		{name: "denied", expression: `!(kind == "testObject" && object.metadata.namespace == "default")`, wantDenied: true},
+		{name: "validity", expression: `validity <= duration("720h")`, wantDenied: false},
+		{name: "missing field", expression: `object.spec.validity == "1h"`, wantDenied: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := framework.NewPolicy(tt.name, tt.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var set framework.PolicySet
+			set.Set([]framework.Policy{p})
+
+			violations, err := set.Evaluate(newTestObject(), "testObject")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(violations) > 0; got != tt.wantDenied {
+				t.Errorf("got violations %v, want denied %v", violations, tt.wantDenied)
+			}
+		})
+	}
+}
+
+func TestLoadPolicies(t *testing.T) {
+	dir := t.TempDir()
+	expr := `object.metadata.namespace != "prod"`
+	if err := os.WriteFile(filepath.Join(dir, "no-prod"), []byte(expr), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// Mounted ConfigMaps link their keys to hidden directories.
+	if err := os.Mkdir(filepath.Join(dir, "..data"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	policies, err := framework.LoadPolicies(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policies) != 1 || policies[0].Name != "no-prod" {
+		t.Errorf("expected the policy no-prod, got %+v", policies)
+	}
+}
+
+func TestReconcile_PolicyViolation(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	p := &countingProvider{}
+	policy, err := framework.NewPolicy("no-default", `object.metadata.namespace != "default"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies := &framework.PolicySet{}
+	policies.Set([]framework.Policy{policy})
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p, Policies: policies}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(ctx, req); err == nil {
+		t.Fatal("expected the policy violation to fail the reconciliation")
+	}
+	if p.provisions != 0 {
+		t.Errorf("expected nothing to be provisioned, got %d provisions", p.provisions)
+	}
+	var got testObject
+	if err := c.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, framework.ConditionPolicyViolation)
+	if cond == nil || cond.Reason != framework.ReasonPolicyDenied || !strings.Contains(cond.Message, "no-default") {
+		t.Errorf("expected a PolicyViolation condition naming the policy, got %+v", cond)
+	}
+
+	// Once the policy is lifted, the resource is provisioned.
+	policies.Set(nil)
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if p.provisions != 1 {
+		t.Errorf("expected the resource to be provisioned, got %d provisions", p.provisions)
+	}
+	if err := c.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if meta.FindStatusCondition(got.Status.Conditions, framework.ConditionPolicyViolation) != nil {
+		t.Error("expected the PolicyViolation condition to be cleared")
+	}
+}
@@ -0,0 +1,54 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrPrecheckFailed is returned when the [Prechecker] of the provider rejects
+// a resource.
+var ErrPrecheckFailed = errors.New("precheck failed")
+
+// Prechecker is optionally implemented by providers that can check whether a
+// resource can be provisioned without provisioning anything, e.g. that the
+// application it names exists and the operator may read it. The reconciler
+// runs the precheck once for each generation of the spec, before its first
+// renewal, and records the outcome in the TargetReachable condition. A failed
+// precheck fails the resource instead of provisioning it, and is repeated
+// with backoff until it passes. Renewals of a generation that passed are not
+// checked again. This surfaces mistakes in the spec even while renewals are
+// deferred, e.g. during a provider maintenance window.
+type Prechecker[O Object] interface {
+	// Precheck returns why obj cannot be provisioned, or nil. It must not
+	// change anything at the provider.
+	Precheck(ctx context.Context, obj O) error
+}
+
+// prechecker returns the provider as a [Prechecker], looking through wrappers
+// such as [InstrumentedProvider], or nil if it does not implement one.
+func (r *Reconciler[O]) prechecker() Prechecker[O] {
+	c, _ := findProvider[O, Prechecker[O]](r.Provider)
+	return c
+}
+
+// precheck runs the provider's precheck of obj's generation unless it passed
+// already, and records its outcome. It returns an [ErrPrecheckFailed] if the
+// precheck failed, which is persisted with the failed status.
+func (r *Reconciler[O]) precheck(ctx context.Context, obj O) error {
+	c := r.prechecker()
+	status := obj.GetStatus()
+	if c == nil || status.PrecheckPassed(obj.GetGeneration()) {
+		return nil
+	}
+
+	if err := c.Precheck(ctx, obj); err != nil {
+		err = fmt.Errorf("%w: %w", ErrPrecheckFailed, err)
+		status.SetPrecheck(obj.GetGeneration(), err)
+		return err
+	}
+	if status.SetPrecheck(obj.GetGeneration(), nil) {
+		return r.updateStatus(ctx, obj)
+	}
+	return nil
+}
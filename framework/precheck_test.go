@@ -0,0 +1,94 @@
+package framework_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// precheckProvider is a [framework.Prechecker] counting its prechecks and
+// provisions.
+type precheckProvider struct {
+	stubProvider
+
+	precheckErr error
+	prechecks   int
+	provisions  int
+}
+
+func (p *precheckProvider) Precheck(context.Context, *testObject) error {
+	p.prechecks++
+	return p.precheckErr
+}
+
+func (p *precheckProvider) Provision(ctx context.Context, obj *testObject) (*framework.Result, error) {
+	p.provisions++
+	return p.stubProvider.Provision(ctx, obj)
+}
+
+func TestReconcile_Precheck(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	p := &precheckProvider{precheckErr: errors.New("application not found")}
+	r := &framework.Reconciler[*testObject]{
+		Client:   c,
+		Scheme:   scheme,
+		Provider: framework.Instrument[*testObject](p, prometheus.NewRegistry()),
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+	condition := func(typ string) *metav1.Condition {
+		t.Helper()
+		var got testObject
+		if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+			t.Fatal(err)
+		}
+		return meta.FindStatusCondition(got.Status.Conditions, typ)
+	}
+
+	if _, err := r.Reconcile(context.Background(), req); !errors.Is(err, framework.ErrPrecheckFailed) {
+		t.Fatalf("expected a failed precheck, got %v", err)
+	}
+	if p.provisions != 0 {
+		t.Fatal("expected nothing to be provisioned")
+	}
+	if got := condition(framework.ConditionTargetReachable); got == nil || got.Status != metav1.ConditionFalse {
+		t.Errorf("expected TargetReachable to be false, got %+v", got)
+	}
+	if got := condition(framework.ConditionReady); got == nil || got.Reason != framework.ReasonPrecheckFailed {
+		t.Errorf("expected Ready to be false with reason PrecheckFailed, got %+v", got)
+	}
+
+	p.precheckErr = nil
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if p.prechecks != 2 || p.provisions != 1 {
+		t.Fatalf("got %d prechecks and %d provisions, want 2 and 1", p.prechecks, p.provisions)
+	}
+	if got := condition(framework.ConditionTargetReachable); got == nil || got.Status != metav1.ConditionTrue {
+		t.Errorf("expected TargetReachable to be true, got %+v", got)
+	}
+
+	// Renewals of a generation that passed are not checked again.
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-secret"}}
+	if err := c.Delete(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if p.prechecks != 2 || p.provisions != 2 {
+		t.Errorf("got %d prechecks and %d provisions, want 2 and 2", p.prechecks, p.provisions)
+	}
+}
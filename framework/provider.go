@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -13,21 +14,53 @@ type Provider[O Object] interface {
 	// NewObject returns a zero-value instance of the CRD type.
 	NewObject() O
 
-	// Provision creates or renews credentials.
+	// Provision creates or renews credentials. If it fails after creating a
+	// key at the provider, it can return a Result with the KeyID and
+	// ValidUntil of that key alongside the error, so that the key is tracked
+	// and deleted once it expires instead of being orphaned.
 	Provision(ctx context.Context, obj O) (*Result, error)
 
 	// DeleteKey removes a credential by its KeyID.
-	// Providers that don't support key deletion can return nil.
+	// Providers that don't support key deletion can return nil. It may be
+	// called concurrently for keys of the same object during finalization.
 	DeleteKey(ctx context.Context, obj O, keyID string) error
 }
 
+// UsageReporter is optionally implemented by providers that can tell when a
+// credential was last used, e.g. from sign-in or audit logs. The reconciler
+// then records it for each active key, see [ActiveKey.LastUsedAt], so that
+// unused credentials can be told apart from ones that are still in use.
+type UsageReporter[O Object] interface {
+	// LastUsed returns when the key was last used, or the zero time if it
+	// has not been used as far as the provider knows.
+	LastUsed(ctx context.Context, obj O, keyID string) (time.Time, error)
+}
+
+// findProvider returns p, or the first provider it wraps, e.g. through an
+// [InstrumentedProvider], that implements T.
+func findProvider[O Object, T any](p Provider[O]) (T, bool) {
+	for {
+		if t, ok := p.(T); ok {
+			return t, true
+		}
+		w, ok := p.(interface{ Unwrap() Provider[O] })
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		p = w.Unwrap()
+	}
+}
+
 // Object is the constraint for provider CRD types. Each provider's CRD struct
 // must implement client.Object (for Kubernetes API operations) plus the shared
 // accessors that the framework reconciler needs.
 type Object interface {
 	client.Object
 
-	// GetSecretRef returns the reference to the target output Secret.
+	// GetSecretRef returns the reference to the target output Secret. If
+	// secretRef is omitted, it names the resource, see
+	// [SecretReference.OrDefault].
 	GetSecretRef() SecretReference
 
 	// GetStatus returns a pointer to the shared status embedded in the CRD.
@@ -42,6 +75,12 @@ type Result struct {
 	// StringData contains the rendered secret data.
 	StringData map[string]string
 
+	// Fields contains the credentials by well-known field, e.g.
+	// [FieldUsername] and [FieldPassword], as far as the provider has them.
+	// They are added to the output secret for resources requesting
+	// [LayoutFiles].
+	Fields map[string]string
+
 	// ValidUntil is when the credentials expire.
 	ValidUntil time.Time
 
@@ -50,4 +89,24 @@ type Result struct {
 
 	// KeyID is the identifier for the created credential.
 	KeyID string
+
+	// Identifier is what the credential authenticates as, e.g. a username,
+	// for providers that create a new one with each rotation, so that
+	// identifier and secret form a pair. It is recorded in the [ActiveKey],
+	// where [Provider.DeleteKey] can look it up to drop the whole pair. Empty
+	// if the identifier does not change.
+	Identifier string
+
+	// Hint is a non-secret hint of the credential that the provider shows,
+	// e.g. the first characters of a client secret in its console, so that
+	// the output secret can be matched to it. It is recorded in the
+	// [ActiveKey]. Empty if the provider shows none.
+	Hint string
+
+	// SecretType is the type of the output secret, e.g.
+	// [corev1.SecretTypeTLS], for providers whose data follows one of the
+	// built-in formats. It only applies when the secret is created, as the
+	// type of an existing secret cannot be changed, and not to secrets split
+	// by [Reconciler.SplitOversizedSecrets]. Empty means Opaque.
+	SecretType corev1.SecretType
 }
@@ -0,0 +1,41 @@
+package framework
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsRegisterer is optionally implemented by providers that export
+// metrics of their own, e.g. of the API they call. [RegisterProviderMetrics]
+// calls RegisterMetrics once at setup.
+type MetricsRegisterer interface {
+	// RegisterMetrics registers the provider's metrics with reg. reg
+	// prefixes their names with "valet_<provider>_", so a counter named
+	// "graph_throttled_total" is exported as
+	// valet_azure_graph_throttled_total.
+	RegisterMetrics(reg prometheus.Registerer) error
+}
+
+// invalidMetricNameChars are the characters not allowed in metric names.
+var invalidMetricNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// ProviderMetricsPrefix returns the prefix of the metrics of the provider
+// named name, e.g. "valet_azure_" for provider-azure.
+func ProviderMetricsPrefix(name string) string {
+	name = strings.TrimPrefix(name, "provider-")
+	return "valet_" + invalidMetricNameChars.ReplaceAllString(name, "_") + "_"
+}
+
+// RegisterProviderMetrics lets p register its metrics with reg if it, or a
+// provider it wraps such as the one of an [InstrumentedProvider], implements
+// [MetricsRegisterer]. name is the name of the provider, usually
+// [BuildInfo.Provider], from which the metrics' prefix is derived with
+// [ProviderMetricsPrefix].
+func RegisterProviderMetrics[O Object](p Provider[O], name string, reg prometheus.Registerer) error {
+	if m, ok := findProvider[O, MetricsRegisterer](p); ok {
+		return m.RegisterMetrics(prometheus.WrapRegistererWithPrefix(ProviderMetricsPrefix(name), reg))
+	}
+	return nil
+}
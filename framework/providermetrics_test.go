@@ -0,0 +1,61 @@
+package framework_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// metricsProvider is a [framework.MetricsRegisterer] exporting a counter.
+type metricsProvider struct {
+	stubProvider
+
+	calls prometheus.Counter
+}
+
+func (p *metricsProvider) RegisterMetrics(reg prometheus.Registerer) error {
+	p.calls = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "api_calls_total",
+		Help: "Total number of API calls.",
+	})
+	return reg.Register(p.calls)
+}
+
+func TestProviderMetricsPrefix(t *testing.T) {
+	for name, want := range map[string]string{
+		"provider-azure":    "valet_azure_",
+		"provider-azuresas": "valet_azuresas_",
+		"my.provider":       "valet_my_provider_",
+	} {
+		if got := framework.ProviderMetricsPrefix(name); got != want {
+			t.Errorf("ProviderMetricsPrefix(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRegisterProviderMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	p := &metricsProvider{}
+	wrapped := framework.Instrument[*testObject](p, reg)
+
+	if err := framework.RegisterProviderMetrics[*testObject](wrapped, "provider-azure", reg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.calls.Inc()
+
+	expected := `
+# HELP valet_azure_api_calls_total Total number of API calls.
+# TYPE valet_azure_api_calls_total counter
+valet_azure_api_calls_total 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "valet_azure_api_calls_total"); err != nil {
+		t.Error(err)
+	}
+
+	if err := framework.RegisterProviderMetrics[*testObject](&stubProvider{}, "provider-mock", reg); err != nil {
+		t.Errorf("expected providers without metrics to be skipped, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// PushSecretGVK is the kind of the external-secrets resources that publish
+// output secrets, see [Reconciler.PushSecretStore]. It is handled as
+// unstructured, so neither the external-secrets API nor its CRDs are needed
+// unless publishing is enabled.
+var PushSecretGVK = schema.GroupVersionKind{
+	Group:   "external-secrets.io",
+	Version: "v1alpha1",
+	Kind:    "PushSecret",
+}
+
+// pushSecretRefreshInterval is how often external-secrets pushes the output
+// secret again, which bounds how long a rotated key takes to reach the store.
+const pushSecretRefreshInterval = "1m"
+
+// reconcilePushSecret publishes the output secret of obj through a PushSecret
+// to [Reconciler.PushSecretStore] if obj is annotated with
+// [AnnotationPushRemoteKey], so that consumers in other clusters or clouds
+// read the rotated credentials from the store. The PushSecret is named after
+// the output secret and owned by obj; it replaces the remote secret on each
+// rotation and deletes it when obj is deleted or the annotation removed.
+//
+// The whole output secret is pushed as is, so with [Reconciler.Envelope] the
+// store holds the encrypted values. Only the first of split secrets is
+// published.
+//
+// Publishing is best effort: failures are logged and recorded as
+// [ReasonPushSecretFailed] events, but do not hold up rotation.
+func (r *Reconciler[O]) reconcilePushSecret(ctx context.Context, obj O) {
+	if r.PushSecretStore == "" {
+		return
+	}
+
+	var err error
+	if remoteKey := obj.GetAnnotations()[AnnotationPushRemoteKey]; remoteKey != "" {
+		err = r.writePushSecret(ctx, obj, remoteKey)
+	} else {
+		err = r.deletePushSecret(ctx, obj)
+	}
+	if err == nil {
+		return
+	}
+
+	log.FromContext(ctx).Error(err, "failed to reconcile PushSecret", "store", r.PushSecretStore)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, corev1.EventTypeWarning, ReasonPushSecretFailed, "Reconcile",
+			"Publishing the output secret to store %s failed: %v", r.PushSecretStore, err)
+	}
+}
+
+// newPushSecret returns an empty PushSecret for the output secret of obj.
+func newPushSecret(obj Object) *unstructured.Unstructured {
+	ps := &unstructured.Unstructured{}
+	ps.SetGroupVersionKind(PushSecretGVK)
+	ps.SetNamespace(obj.GetNamespace())
+	ps.SetName(obj.GetSecretRef().Name)
+	return ps
+}
+
+// writePushSecret creates or updates the PushSecret of obj. A PushSecret of
+// the same name that obj does not own is left alone.
+func (r *Reconciler[O]) writePushSecret(ctx context.Context, obj O, remoteKey string) error {
+	ps := newPushSecret(obj)
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, ps, func() error {
+		if ps.GetResourceVersion() != "" && !metav1.IsControlledBy(ps, obj) {
+			return errors.New("it exists and is not owned by this resource")
+		}
+		if err := controllerutil.SetControllerReference(obj, ps, r.Scheme); err != nil {
+			return err
+		}
+		r.setStandardLabels(obj, ps)
+		// Fields defaulted by external-secrets are kept, so that an
+		// unchanged PushSecret is not updated again.
+		spec, _, err := unstructured.NestedMap(ps.Object, "spec")
+		if err != nil {
+			return err
+		}
+		if spec == nil {
+			spec = map[string]any{}
+		}
+		spec["refreshInterval"] = pushSecretRefreshInterval
+		spec["updatePolicy"] = "Replace"
+		spec["deletionPolicy"] = "Delete"
+		spec["secretStoreRefs"] = []any{map[string]any{
+			"kind": "ClusterSecretStore",
+			"name": r.PushSecretStore,
+		}}
+		spec["selector"] = map[string]any{
+			"secret": map[string]any{"name": obj.GetSecretRef().Name},
+		}
+		// A match without a secretKey pushes the whole secret.
+		spec["data"] = []any{map[string]any{
+			"match": map[string]any{
+				"remoteRef": map[string]any{"remoteKey": remoteKey},
+			},
+		}}
+		return unstructured.SetNestedMap(ps.Object, spec, "spec")
+	})
+	if err != nil {
+		return fmt.Errorf("writing PushSecret %s: %w", ps.GetName(), err)
+	}
+	return nil
+}
+
+// deletePushSecret deletes the PushSecret of obj, if obj owns one. It is a
+// no-op if the PushSecret CRD is not installed.
+func (r *Reconciler[O]) deletePushSecret(ctx context.Context, obj O) error {
+	ps := newPushSecret(obj)
+	if err := r.Get(ctx, client.ObjectKeyFromObject(ps), ps); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil
+		}
+		return fmt.Errorf("getting PushSecret %s: %w", ps.GetName(), err)
+	}
+	if !metav1.IsControlledBy(ps, obj) {
+		return nil
+	}
+	if err := r.Delete(ctx, ps); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("deleting PushSecret %s: %w", ps.GetName(), err)
+	}
+	return nil
+}
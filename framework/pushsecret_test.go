@@ -0,0 +1,130 @@
+package framework_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// getPushSecret returns the PushSecret of the test object's output secret.
+func getPushSecret(t *testing.T, c client.Client) (*unstructured.Unstructured, error) {
+	t.Helper()
+	ps := &unstructured.Unstructured{}
+	ps.SetGroupVersionKind(framework.PushSecretGVK)
+	err := c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "app-secret"}, ps)
+	return ps, err
+}
+
+func TestReconcile_PushSecret(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	obj.Annotations = map[string]string{framework.AnnotationPushRemoteKey: "team-a/app"}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{
+		Client:          c,
+		Scheme:          scheme,
+		Provider:        &stubProvider{},
+		PushSecretStore: "vault",
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	ps, err := getPushSecret(t, c)
+	if err != nil {
+		t.Fatalf("expected a PushSecret: %v", err)
+	}
+	if !metav1.IsControlledBy(ps, obj) {
+		t.Error("expected the PushSecret to be owned by the resource")
+	}
+	store, _, _ := unstructured.NestedSlice(ps.Object, "spec", "secretStoreRefs")
+	if len(store) != 1 || store[0].(map[string]any)["name"] != "vault" ||
+		store[0].(map[string]any)["kind"] != "ClusterSecretStore" {
+		t.Errorf("got secretStoreRefs %v", store)
+	}
+	if name, _, _ := unstructured.NestedString(ps.Object, "spec", "selector", "secret", "name"); name != "app-secret" {
+		t.Errorf("got selected secret %q", name)
+	}
+	data, _, _ := unstructured.NestedSlice(ps.Object, "spec", "data")
+	if len(data) != 1 {
+		t.Fatalf("got data %v", data)
+	}
+	if key, _, _ := unstructured.NestedString(data[0].(map[string]any), "match", "remoteRef", "remoteKey"); key != "team-a/app" {
+		t.Errorf("got remote key %q", key)
+	}
+
+	// Removing the annotation stops publishing.
+	if err := c.Get(context.Background(), req.NamespacedName, obj); err != nil {
+		t.Fatal(err)
+	}
+	obj.Annotations = nil
+	if err := c.Update(context.Background(), obj); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if _, err := getPushSecret(t, c); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the PushSecret to be deleted, got %v", err)
+	}
+}
+
+func TestReconcile_PushSecretNotOwned(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	obj.Annotations = map[string]string{framework.AnnotationPushRemoteKey: "team-a/app"}
+	foreign := &unstructured.Unstructured{}
+	foreign.SetGroupVersionKind(framework.PushSecretGVK)
+	foreign.SetNamespace("default")
+	foreign.SetName("app-secret")
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, foreign).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{
+		Client:          c,
+		Scheme:          scheme,
+		Provider:        &stubProvider{},
+		PushSecretStore: "vault",
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	ps, err := getPushSecret(t, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps.GetOwnerReferences()) != 0 || ps.Object["spec"] != nil {
+		t.Errorf("expected the foreign PushSecret to be left alone, got %v", ps.Object)
+	}
+
+	// Failing to publish does not hold up rotation.
+	var got testObject
+	if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.CurrentKeyID == "" {
+		t.Error("expected credentials to be provisioned")
+	}
+
+	// Without the annotation, the foreign PushSecret is not deleted either.
+	got.Annotations = nil
+	if err := c.Update(context.Background(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if _, err := getPushSecret(t, c); err != nil {
+		t.Errorf("expected the foreign PushSecret to be kept: %v", err)
+	}
+}
@@ -2,19 +2,59 @@ package framework
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/lukasngl/valet/framework/envelope"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
+// DefaultDeleteConcurrency is the default number of concurrent
+// [Provider.DeleteKey] calls while finalizing a resource.
+const DefaultDeleteConcurrency = 4
+
+// DefaultMaxActiveKeys is the default upper bound of the keys tracked per
+// object, see [Reconciler.MaxActiveKeys].
+const DefaultMaxActiveKeys = 10
+
+// DefaultMaxDeleteAttempts is the default number of failed attempts to delete
+// an expired key before giving up, see [Reconciler.MaxDeleteAttempts].
+const DefaultMaxDeleteAttempts = 5
+
+// DefaultMaxFailedCleanups is the default number of keys kept in the
+// status' FailedCleanups, see [Reconciler.MaxFailedCleanups].
+const DefaultMaxFailedCleanups = 10
+
+// DefaultUsageInterval is the default interval at which the usage of active
+// keys is refreshed, see [Reconciler.UsageInterval].
+const DefaultUsageInterval = time.Hour
+
+// DefaultForceDeleteAttempts is the default number of failed cleanup attempts
+// after which a resource annotated with [AnnotationForceDelete] is finalized.
+const DefaultForceDeleteAttempts = 3
+
 // Option configures the controller builder in [Reconciler.SetupWithManager].
 type Option func(*builder.Builder)
 
@@ -24,15 +64,237 @@ type Reconciler[O Object] struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Provider Provider[O]
+
+	// KeyStore, if set, holds the active key bookkeeping instead of the
+	// object status. See [KeyStore].
+	KeyStore KeyStore
+
+	// DeleteConcurrency bounds the number of concurrent DeleteKey calls
+	// while finalizing a resource. Zero means [DefaultDeleteConcurrency];
+	// providers must tolerate concurrent calls for the same object.
+	DeleteConcurrency int
+
+	// DeleteLimiter, if set, rate-limits DeleteKey calls during finalization
+	// across all resources handled by this reconciler, e.g. to stay within
+	// the provider's API quota.
+	DeleteLimiter *rate.Limiter
+
+	// MaxActiveKeys bounds the number of keys tracked per object. Beyond it,
+	// the oldest keys other than the current one are deleted at the provider
+	// before they expire. Zero means [DefaultMaxActiveKeys].
+	MaxActiveKeys int
+
+	// MaxDeleteAttempts is the number of failed attempts to delete an
+	// expired key after which it is moved to the status' FailedCleanups
+	// instead of being retried on every reconciliation. Zero means
+	// [DefaultMaxDeleteAttempts].
+	MaxDeleteAttempts int
+
+	// MaxFailedCleanups bounds the number of keys listed in the status'
+	// FailedCleanups. Beyond it, the oldest entries are dropped; their
+	// CleanupFailed events remain. Zero means [DefaultMaxFailedCleanups].
+	MaxFailedCleanups int
+
+	// ForceDeleteAttempts is the number of failed cleanup attempts after
+	// which a resource annotated with [AnnotationForceDelete] is finalized
+	// anyway. Zero means [DefaultForceDeleteAttempts].
+	ForceDeleteAttempts int
+
+	// ForceDeleteAfter, if positive, finalizes any resource whose cleanup
+	// has still not succeeded this long after its deletion was requested,
+	// whether or not it is annotated. Zero disables this.
+	ForceDeleteAfter time.Duration
+
+	// MaxSecretSize bounds the total size of the values written to an
+	// output secret. Zero means [DefaultMaxSecretSize], the limit enforced by
+	// the API server.
+	MaxSecretSize int
+
+	// SplitOversizedSecrets, if set, splits rendered data larger than
+	// [Reconciler.MaxSecretSize] across the output secret and additional
+	// secrets named after it with the suffixes -1, -2 and so on, instead of
+	// failing. The output secret lists them in [AnnotationSplitSecrets]. Each
+	// key is kept whole, so a single value must still fit into one secret.
+	SplitOversizedSecrets bool
+
+	// Envelope, if set, encrypts the values written to output secrets with
+	// a fresh data encryption key wrapped by it, e.g. with a KMS key, for
+	// clusters whose etcd encryption is not trusted. Consumers decrypt them
+	// with [envelope.Open] or the valet-decrypt initContainer.
+	Envelope envelope.KeyWrapper
+
+	// PropagateLabels lists the labels of the reconciled objects that are
+	// copied to their output secrets, e.g. so that cost-allocation or
+	// ownership label policies apply to the secrets. Entries ending in "*"
+	// select all labels with that prefix, e.g. "example.com/*". A selected
+	// label that is removed from an object is also removed from its secrets.
+	PropagateLabels []string
+
+	// ClockSkew is the allowance for the provider's clock differing from
+	// ours. Keys are renewed this much earlier and only count as expired, for
+	// cleanup and for deletion, this long after their expiry, so that keys
+	// are neither used nor deleted around their expiry while the clocks
+	// disagree. Zero means no allowance.
+	ClockSkew time.Duration
+
+	// UsageInterval is how often the usage of the active keys is refreshed
+	// if the provider implements [UsageReporter]. Zero means
+	// [DefaultUsageInterval].
+	UsageInterval time.Duration
+
+	// Recorder, if set, records events on the reconciled objects, e.g. when
+	// a resource is force-deleted.
+	Recorder events.EventRecorder
+
+	// PrioritizeNamespaceDeletion, if set, watches namespaces and reconciles
+	// the resources in a terminating namespace with
+	// [NamespaceDeletionPriority], so that their keys are deleted at the
+	// provider before routine renewals are handled. While their cleanup is
+	// blocked, a CleanupPending Warning event is recorded on the namespace.
+	PrioritizeNamespaceDeletion bool
+
+	// RestartWorkloads, if set, restarts the Deployments and StatefulSets
+	// whose pod template references an output secret, a target or a
+	// replica, as kubectl rollout restart does, once its
+	// [AnnotationDataChecksum] changes, so that rotated credentials reach
+	// pods that only read them at startup. The workloads are found in the
+	// namespace of the secret with the [IndexSecretNames] field index.
+	RestartWorkloads bool
+
+	// PendingTimeout, if positive, runs a [PendingWatchdog] that reports
+	// resources which have been Pending without status updates for this
+	// long, e.g. because the provider hangs.
+	PendingTimeout time.Duration
+
+	// StuckDeletionTimeout, if positive, runs a [DeletionWatchdog] that
+	// reports resources whose deletion has been blocked by [Finalizer] for
+	// this long, e.g. because their keys cannot be deleted at the provider.
+	StuckDeletionTimeout time.Duration
+
+	// SlowReconcileThreshold, if positive, reports reconciliations that take
+	// longer, e.g. because the provider hangs, in [SlowReconciles] and with a
+	// [ReasonSlowReconcile] Warning event.
+	SlowReconcileThreshold time.Duration
+
+	// MaintenanceWindows are the recurring periods in which the provider is
+	// expected to be unavailable. Renewals that fall into one are deferred
+	// until it ends, with a [ConditionMaintenance], instead of failing.
+	// Cleanup of expired keys is not deferred.
+	MaintenanceWindows []MaintenanceWindow
+
+	// Policies, if set, are evaluated before credentials are provisioned.
+	// Resources violating one are failed with a [ConditionPolicyViolation]
+	// instead, and retried with backoff. Cleanup is not affected.
+	Policies *PolicySet
+
+	// Tracer, if set, starts an OpenTelemetry span for each reconciliation.
+	// Provider calls made within it carry its trace ID, e.g. as exemplars of
+	// the [InstrumentedProvider] metrics.
+	Tracer trace.Tracer
+
+	// Debug, if set, keeps the recent reconciliations and status changes of
+	// each resource for the support bundles of [Reconciler.DebugHandler].
+	Debug *DebugRecorder
+
+	// AllowSimulateExpiry, if set, honors [AnnotationSimulateExpiry], so
+	// that the rotation of a resource can be tested on demand, e.g. in
+	// staging clusters. Unset, the annotation is ignored.
+	AllowSimulateExpiry bool
+
+	// PushSecretStore, if set, is the name of an external-secrets
+	// ClusterSecretStore that the output secrets of resources annotated with
+	// [AnnotationPushRemoteKey] are published to with PushSecrets, so that
+	// consumers in other clusters or clouds receive the rotated credentials.
+	PushSecretStore string
+
+	// ProviderName names the provider in the [LabelProvider] label of the
+	// objects the reconciler creates, usually [BuildInfo.Provider]. Unset,
+	// the label is left out.
+	ProviderName string
+
+	// retained holds results whose output secret could not be written, by
+	// object UID. See [Reconciler.retain].
+	retained sync.Map
 }
 
 // SetupWithManager sets up the controller with the Manager.
 // Options can be used to further configure the controller builder,
 // for example to set a custom controller name via [builder.Builder.Named].
+//
+// It fails if the provider's CRD is not installed or incompatible (see
+// [CheckCRD]), and adds a "crd" readyz check that keeps verifying it.
+// Namespaces are watched once, for [Replication] and, with
+// [Reconciler.PrioritizeNamespaceDeletion], for their deletion. With
+// [Reconciler.PendingTimeout] it adds a [PendingWatchdog], and with
+// [Reconciler.StuckDeletionTimeout] a [DeletionWatchdog]. If the provider
+// implements [CredentialExpiryReporter], it adds a [CredentialExpiryWatcher].
+// With [Reconciler.RestartWorkloads], it registers the [IndexSecretNames]
+// field index.
 func (r *Reconciler[O]) SetupWithManager(mgr ctrl.Manager, opts ...Option) error {
+	checkCRD := func(ctx context.Context) error {
+		return CheckCRD(ctx, mgr.GetAPIReader(), mgr.GetRESTMapper(), mgr.GetScheme(), r.Provider.NewObject())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := checkCRD(ctx); err != nil {
+		return err
+	}
+	if err := mgr.AddReadyzCheck("crd", func(req *http.Request) error {
+		return checkCRD(req.Context())
+	}); err != nil {
+		return fmt.Errorf("adding CRD ready check: %w", err)
+	}
+
+	if r.PendingTimeout > 0 {
+		if err := mgr.Add(&PendingWatchdog[O]{
+			Reader:    mgr.GetAPIReader(),
+			Scheme:    mgr.GetScheme(),
+			NewObject: r.Provider.NewObject,
+			Timeout:   r.PendingTimeout,
+			Recorder:  r.Recorder,
+		}); err != nil {
+			return fmt.Errorf("adding pending watchdog: %w", err)
+		}
+	}
+	if r.StuckDeletionTimeout > 0 {
+		if err := mgr.Add(&DeletionWatchdog[O]{
+			Reader:    mgr.GetAPIReader(),
+			Scheme:    mgr.GetScheme(),
+			NewObject: r.Provider.NewObject,
+			Timeout:   r.StuckDeletionTimeout,
+			Recorder:  r.Recorder,
+		}); err != nil {
+			return fmt.Errorf("adding deletion watchdog: %w", err)
+		}
+	}
+	if reporter := r.credentialExpiryReporter(); reporter != nil {
+		gvk, err := apiutil.GVKForObject(r.Provider.NewObject(), mgr.GetScheme())
+		if err != nil {
+			return fmt.Errorf("looking up the resource kind: %w", err)
+		}
+		if err := mgr.Add(&CredentialExpiryWatcher{Reporter: reporter, Kind: gvk.Kind}); err != nil {
+			return fmt.Errorf("adding credential expiry watcher: %w", err)
+		}
+	}
+
+	if r.RestartWorkloads {
+		if err := indexSecretNames(ctx, mgr); err != nil {
+			return err
+		}
+	}
+
 	b := ctrl.NewControllerManagedBy(mgr).
 		For(r.Provider.NewObject()).
 		Owns(&corev1.Secret{})
+	namespaces := namespaceHandlers{
+		{r.replicationHandler(mgr.GetClient(), mgr.GetScheme()), namespaceReplicationChanged},
+	}
+	if r.PrioritizeNamespaceDeletion {
+		namespaces = append(namespaces, namespaceHandler{
+			r.namespaceDeletionHandler(mgr.GetAPIReader(), mgr.GetScheme()), predicate.Funcs{},
+		})
+	}
+	b = b.Watches(&corev1.Namespace{}, namespaces)
 	for _, opt := range opts {
 		opt(b)
 	}
@@ -42,11 +304,44 @@ func (r *Reconciler[O]) SetupWithManager(mgr ctrl.Manager, opts ...Option) error
 // Reconcile handles the reconciliation loop. It fetches the CRD, ensures
 // a finalizer, validates the spec, cleans up expired keys, and provisions
 // or renews credentials when needed.
-func (r *Reconciler[O]) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *Reconciler[O]) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	start := time.Now()
+	if r.Tracer != nil {
+		var span trace.Span
+		ctx, span = r.Tracer.Start(ctx, "Reconcile", trace.WithAttributes(
+			attribute.String("namespace", req.Namespace),
+			attribute.String("name", req.Name),
+		))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
 	obj := r.Provider.NewObject()
 	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Debug.forget(req.NamespacedName)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	defer func() {
+		r.reportSlowReconcile(ctx, obj, time.Since(start))
+		r.Debug.record(ctx, obj, start, result, err)
+	}()
+
+	return r.reconcile(ctx, obj)
+}
+
+// reconcile reconciles the fetched obj, see [Reconciler.Reconcile].
+func (r *Reconciler[O]) reconcile(ctx context.Context, obj O) (ctrl.Result, error) {
+	if err := r.loadKeys(ctx, obj); errors.Is(err, ErrKeyStoreConflict) {
+		return r.handleKeyStoreConflict(ctx, obj, err)
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
 
 	// Handle deletion.
 	if !obj.GetDeletionTimestamp().IsZero() {
@@ -63,52 +358,250 @@ func (r *Reconciler[O]) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	}
 
 	// Validate before any work — don't retry, wait for spec change.
-	if err := obj.Validate(); err != nil {
+	if err := r.validate(obj); err != nil {
 		log.FromContext(ctx).Error(err, "validation failed")
 		obj.GetStatus().SetFailed(obj.GetGeneration(), fmt.Errorf("invalid config: %w", err))
-		if updateErr := r.Status().Update(ctx, obj); updateErr != nil {
+		if updateErr := r.updateStatus(ctx, obj); updateErr != nil {
 			return ctrl.Result{}, updateErr
 		}
 		return ctrl.Result{}, nil
 	}
 
-	// Cleanup expired keys.
+	// Freeze rotation and cleanup while suspended.
+	if why := suspension(obj); why != "" {
+		return r.handleSuspended(ctx, obj, why)
+	}
+	if obj.GetStatus().ClearSuspended() {
+		log.FromContext(ctx).Info("rotation resumed")
+		if err := r.updateStatus(ctx, obj); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Keep the keys while the output secret is handed over, and take over
+	// the one handed over to obj.
+	if target := obj.GetAnnotations()[AnnotationTransferTo]; target != "" {
+		return r.handleTransfer(ctx, obj, target)
+	}
+	if obj.GetStatus().ClearTransferred() {
+		if err := r.updateStatus(ctx, obj); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if err := r.adoptSecret(ctx, obj); err != nil {
+		return r.failStatus(ctx, obj, fmt.Errorf("adopting secret: %w", err))
+	}
+
+	// Cleanup expired keys. The current key is noted before, so that a late
+	// renewal is still recorded as rotation of it.
+	previous := renewedKey(obj)
 	if err := r.handleCleanup(ctx, obj); err != nil {
 		return ctrl.Result{}, err
 	}
 
+	r.refreshUsage(ctx, obj)
+
+	// Leave the output secret alone while it is marked unmanaged.
+	if r.secretUnmanaged(ctx, obj) {
+		return r.handleUnmanaged(ctx, obj)
+	}
+	if obj.GetStatus().ClearUnmanaged() {
+		log.FromContext(ctx).Info("output secret is managed again", "secret", obj.GetSecretRef().Name)
+		if err := r.updateStatus(ctx, obj); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	r.reconcilePushSecret(ctx, obj)
+
+	backOff, err := r.checkConflictingWriter(ctx, obj)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// Check if renewal is needed and handle it.
-	secretHasData := r.secretHasData(ctx, obj)
-	if obj.GetStatus().NeedsRenewal(obj.GetGeneration(), secretHasData) {
-		return r.handleRenewal(ctx, obj)
+	secretHasData := backOff || r.secretHasData(ctx, obj)
+	simulated := r.simulatesExpiry(ctx, obj)
+	requested := rotationRequested(obj)
+	if simulated || requested ||
+		obj.GetStatus().NeedsRenewal(obj.GetGeneration(), secretHasData, r.renewalSkew(obj, time.Now())) {
+		if err := r.checkPolicies(ctx, obj); errors.Is(err, ErrPolicyViolation) {
+			return r.failStatus(ctx, obj, err)
+		} else if err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.precheck(ctx, obj); errors.Is(err, ErrPrecheckFailed) {
+			return r.failStatus(ctx, obj, err)
+		} else if err != nil {
+			return ctrl.Result{}, err
+		}
+		if until, ok := r.maintenanceEnd(time.Now()); ok {
+			return r.handleMaintenance(ctx, obj, until)
+		}
+		// Cleared with the status update of the renewal.
+		obj.GetStatus().ClearMaintenance()
+		result, err := r.handleRenewal(ctx, obj, previous)
+		if err == nil && simulated {
+			err = r.endSimulatedExpiry(ctx, obj)
+		}
+		if err == nil && requested {
+			err = r.endRotationRequest(ctx, obj)
+		}
+		return result, err
 	}
 
-	return r.scheduleNext(obj), nil
+	// Replicas left over from a removed replication are deleted with the
+	// renewal that follows the spec change, so resources without one need
+	// not list secrets on every reconcile.
+	written := make(secretChecksums)
+	if replication(obj) != nil {
+		if err := r.reconcileReplicas(ctx, obj, written); err != nil {
+			return ctrl.Result{}, fmt.Errorf("replication: %w", err)
+		}
+	}
+	if err := r.restartWorkloads(ctx, obj, written); err != nil {
+		return ctrl.Result{}, fmt.Errorf("restarting workloads: %w", err)
+	}
+
+	result := r.scheduleNext(obj)
+	if backOff && result.RequeueAfter > ConflictBackoff {
+		result.RequeueAfter = ConflictBackoff
+	}
+	return result, nil
 }
 
 // handleRenewal provisions new credentials, writes them to the output secret,
 // updates the CRD status to Ready, and schedules the next reconciliation.
-func (r *Reconciler[O]) handleRenewal(ctx context.Context, obj O) (ctrl.Result, error) {
-	result, err := r.Provider.Provision(ctx, obj)
-	if err != nil {
-		return r.failStatus(ctx, obj, fmt.Errorf("provisioning failed: %w", err))
+// If only the secret write, or the verification of the credentials of a
+// self-managed resource (see [AnnotationSelfManaged]), fails, the credentials
+// are retained and the next attempt retries it instead of provisioning
+// another key. Once the status is written, the renewal of previous, if not
+// nil, is recorded as rotation, see [InstrumentedProvider.RecordRotation].
+func (r *Reconciler[O]) handleRenewal(ctx context.Context, obj O, previous *ActiveKey) (ctrl.Result, error) {
+	result := r.takeRetained(obj)
+	if result == nil {
+		start := time.Now()
+		var err error
+		result, err = r.Provider.Provision(ctx, obj)
+		obj.GetStatus().RecordProvision(start, time.Since(start))
+		if err != nil {
+			if result != nil {
+				obj.GetStatus().TrackKey(result)
+			}
+			return r.failStatus(ctx, obj, fmt.Errorf("provisioning failed: %w", err))
+		}
+		r.warnDuplicateKeys(ctx, obj, result.StringData)
+	} else {
+		log.FromContext(ctx).Info("retrying output secret with retained credentials", "keyId", result.KeyID)
 	}
 
-	if err := r.reconcileOutputSecret(ctx, obj, result); err != nil {
+	if err := r.verifySelfManaged(ctx, obj, result); err != nil {
+		r.retain(obj, result)
+		obj.GetStatus().TrackKey(result)
+		return r.failStatus(ctx, obj, err)
+	}
+
+	written := make(secretChecksums)
+	if err := r.reconcileOutputSecret(ctx, obj, result, written); err != nil {
+		r.retain(obj, result)
+		obj.GetStatus().TrackKey(result)
 		return r.failStatus(ctx, obj, fmt.Errorf("output secret: %w", err))
 	}
 
 	obj.GetStatus().SetReady(obj.GetGeneration(), result)
-	if err := r.Status().Update(ctx, obj); err != nil {
+	if err := r.updateStatus(ctx, obj); err != nil {
 		return ctrl.Result{}, err
 	}
+	if previous != nil {
+		recordRotation(ctx, r.Provider, *previous, result.ProvisionedAt)
+	}
+	if err := r.reconcileReplicas(ctx, obj, written); err != nil {
+		return ctrl.Result{}, fmt.Errorf("replication: %w", err)
+	}
+	if err := r.restartWorkloads(ctx, obj, written); err != nil {
+		return ctrl.Result{}, fmt.Errorf("restarting workloads: %w", err)
+	}
 
 	return r.scheduleNext(obj), nil
 }
 
+// renewedKey returns the current key of obj if provisioning another one
+// renews it, or nil for the initial provisioning and for re-provisioning
+// after a spec change, which do not count as rotations.
+func renewedKey(obj Object) *ActiveKey {
+	status := obj.GetStatus()
+	if status.CurrentKeyID == "" || status.ObservedGeneration != obj.GetGeneration() {
+		return nil
+	}
+	for _, k := range status.ActiveKeys {
+		if k.KeyID == status.CurrentKeyID {
+			return &k
+		}
+	}
+	return nil
+}
+
+// handleUnmanaged marks obj Degraded because its output secret is annotated
+// with [AnnotationUnmanaged], and skips renewal. No requeue is scheduled:
+// removing the annotation from the owned secret triggers the next
+// reconciliation.
+func (r *Reconciler[O]) handleUnmanaged(ctx context.Context, obj O) (ctrl.Result, error) {
+	name := obj.GetSecretRef().Name
+	if !obj.GetStatus().SetUnmanaged(obj.GetGeneration(), name) {
+		return ctrl.Result{}, nil
+	}
+
+	log.FromContext(ctx).Info("output secret is unmanaged, skipping renewal", "secret", name)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, corev1.EventTypeWarning, ReasonSecretUnmanaged, "Reconcile",
+			"Not rotating credentials while secret %s is annotated with %s", name, AnnotationUnmanaged)
+	}
+	if err := r.updateStatus(ctx, obj); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// retainedResult is a provisioning result whose output secret could not be
+// written, kept for the next attempt.
+type retainedResult struct {
+	generation int64
+	result     *Result
+}
+
+// retain keeps result for the next reconciliation of obj. Results are only
+// held in memory, so that credentials are never persisted outside the output
+// secret; after a restart the key is provisioned again.
+func (r *Reconciler[O]) retain(obj O, result *Result) {
+	r.retained.Store(obj.GetUID(), retainedResult{generation: obj.GetGeneration(), result: result})
+}
+
+// takeRetained returns and forgets the result retained for obj, or nil if
+// there is none or it is no longer usable: the spec changed, the key expired,
+// or the key is no longer tracked in the status.
+func (r *Reconciler[O]) takeRetained(obj O) *Result {
+	v, ok := r.retained.LoadAndDelete(obj.GetUID())
+	if !ok {
+		return nil
+	}
+	retained := v.(retainedResult)
+	if retained.generation != obj.GetGeneration() || !time.Now().Before(retained.result.ValidUntil) {
+		return nil
+	}
+	if id := retained.result.KeyID; id != "" &&
+		!slices.ContainsFunc(obj.GetStatus().ActiveKeys, func(k ActiveKey) bool { return k.KeyID == id }) {
+		return nil
+	}
+	return retained.result
+}
+
 // handleDeletion cleans up all managed keys and removes the finalizer.
 // Active (non-expired) keys that fail to delete block deletion to prevent
-// orphaning usable credentials. Expired keys are best-effort.
+// orphaning usable credentials, unless [Reconciler.forceDelete] allows it.
+// Expired keys are best-effort. Keys adopted by another resource (see
+// [AnnotationTransferTo]) are left to it, and all keys are left to the
+// provider with [DeletionPolicyRetain] or [AnnotationSelfManaged].
 func (r *Reconciler[O]) handleDeletion(ctx context.Context, obj O) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 
@@ -116,23 +609,66 @@ func (r *Reconciler[O]) handleDeletion(ctx context.Context, obj O) (ctrl.Result,
 		return ctrl.Result{}, nil
 	}
 
+	r.retained.Delete(obj.GetUID())
+
+	if deletionPolicy(obj) == DeletionPolicyRetain || selfManaged(obj) {
+		if err := r.retainOnDeletion(ctx, obj); err != nil {
+			return ctrl.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(obj, Finalizer)
+		return ctrl.Result{}, r.Update(ctx, obj)
+	}
+
 	log.Info("cleaning up managed keys before deletion")
 	now := time.Now()
-	var activeFailures int
-	for _, key := range obj.GetStatus().ActiveKeys {
-		if err := r.Provider.DeleteKey(ctx, obj, key.KeyID); err != nil {
-			log.Error(err, "failed to delete key", "keyId", key.KeyID)
-			if !key.ExpiresAt.Time.Before(now) {
-				activeFailures++
-			}
+	keys, err := r.ownKeys(ctx, obj)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	var activeErrs []error
+	var undeleted []string
+	for i, err := range r.deleteKeys(ctx, obj, keys) {
+		if err == nil {
+			continue
+		}
+		log.Error(err, "failed to delete key", "keyId", keys[i].KeyID)
+		if !keys[i].ExpiresAt.Time.Before(now.Add(-r.ClockSkew)) {
+			activeErrs = append(activeErrs, fmt.Errorf("key %s: %w", keys[i].KeyID, err))
+			undeleted = append(undeleted, keys[i].KeyID)
 		}
 	}
 
-	if activeFailures > 0 {
-		return ctrl.Result{}, fmt.Errorf(
-			"failed to delete %d active key(s), will retry",
-			activeFailures,
+	if len(activeErrs) > 0 {
+		err := fmt.Errorf(
+			"failed to delete %d active key(s), will retry: %w",
+			len(activeErrs), errors.Join(activeErrs...),
 		)
+		obj.GetStatus().SetDeletionFailed(err)
+
+		reason, force := r.forceDelete(obj, now)
+		if !force {
+			r.recordNamespaceCleanupPending(ctx, obj, undeleted)
+			if updateErr := r.updateStatus(ctx, obj); updateErr != nil {
+				log.Error(updateErr, "failed to record deletion failure")
+			}
+			return ctrl.Result{}, err
+		}
+
+		log.Info("force-deleting despite undeleted keys", "reason", reason, "keyIds", undeleted)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(obj, nil, corev1.EventTypeWarning, "ForceDeleted", "Finalize",
+				"Removed finalizer %s; keys %s may still be valid at the provider and must be revoked manually",
+				reason, strings.Join(undeleted, ", "))
+		}
+	}
+
+	// Targets and replicas in other namespaces are not garbage-collected
+	// with obj.
+	if err := r.deleteTargets(ctx, obj, nil); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.deleteLabelled(ctx, obj, LabelReplicaOf, "replica", nil); err != nil {
+		return ctrl.Result{}, err
 	}
 
 	controllerutil.RemoveFinalizer(obj, Finalizer)
@@ -140,23 +676,124 @@ func (r *Reconciler[O]) handleDeletion(ctx context.Context, obj O) (ctrl.Result,
 	return ctrl.Result{}, r.Update(ctx, obj)
 }
 
-// handleCleanup attempts to delete expired keys at the provider and removes
+// forceDelete reports whether the finalizer of obj may be removed although
+// some of its active keys could not be deleted, and why. This is the case
+// after [Reconciler.ForceDeleteAttempts] failed attempts if obj is annotated
+// with [AnnotationForceDelete], or once deletion has been pending for
+// [Reconciler.ForceDeleteAfter].
+func (r *Reconciler[O]) forceDelete(obj O, now time.Time) (string, bool) {
+	failures := obj.GetStatus().DeletionFailures
+
+	attempts := r.ForceDeleteAttempts
+	if attempts <= 0 {
+		attempts = DefaultForceDeleteAttempts
+	}
+	if obj.GetAnnotations()[AnnotationForceDelete] == "true" && failures >= attempts {
+		return fmt.Sprintf("after %d failed cleanup attempts (%s annotation)", failures, AnnotationForceDelete), true
+	}
+
+	if r.ForceDeleteAfter > 0 {
+		if pending := now.Sub(obj.GetDeletionTimestamp().Time); pending >= r.ForceDeleteAfter {
+			return fmt.Sprintf("after %d failed cleanup attempts over %s", failures, pending.Round(time.Second)), true
+		}
+	}
+
+	return "", false
+}
+
+// deleteKeys deletes the given keys at the provider with bounded concurrency
+// and returns the error for each key, in order. Providers without
+// [Capabilities.SupportsDeleteKey] are not called and the keys count as
+// deleted.
+func (r *Reconciler[O]) deleteKeys(ctx context.Context, obj O, keys ActiveKeys) []error {
+	limit := r.DeleteConcurrency
+	if limit <= 0 {
+		limit = DefaultDeleteConcurrency
+	}
+
+	errs := make([]error, len(keys))
+	if !ProviderCapabilities(r.Provider).SupportsDeleteKey {
+		return errs
+	}
+	var g errgroup.Group
+	g.SetLimit(limit)
+	for i, key := range keys {
+		g.Go(func() error {
+			if r.DeleteLimiter != nil {
+				if err := r.DeleteLimiter.Wait(ctx); err != nil {
+					errs[i] = err
+					return nil
+				}
+			}
+			errs[i] = r.Provider.DeleteKey(ctx, obj, key.KeyID)
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return errs
+}
+
+// handleCleanup attempts to delete expired keys, and superseded keys whose
+// grace period passed (see [RotationPolicy]), at the provider and removes
 // successfully deleted keys from the status. Keys that fail to delete are
-// retained for retry on the next reconciliation.
+// retained for retry on the next reconciliation, up to
+// [Reconciler.MaxDeleteAttempts] times. Providers without
+// [Capabilities.SupportsDeleteKey] are not called. It then enforces
+// [Reconciler.MaxActiveKeys].
 func (r *Reconciler[O]) handleCleanup(ctx context.Context, obj O) error {
 	log := log.FromContext(ctx)
 
-	expired := obj.GetStatus().ActiveKeys.DropExpired(time.Now(), func(key ActiveKey) bool {
-		if err := r.Provider.DeleteKey(ctx, obj, key.KeyID); err != nil {
-			log.Error(err, "failed to delete expired key", "keyId", key.KeyID)
+	maxAttempts := r.MaxDeleteAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxDeleteAttempts
+	}
+	maxFailed := r.MaxFailedCleanups
+	if maxFailed <= 0 {
+		maxFailed = DefaultMaxFailedCleanups
+	}
+
+	status := obj.GetStatus()
+	failed := map[string]int{} // KeyID -> attempts, for keys kept to retry
+	canDelete := ProviderCapabilities(r.Provider).SupportsDeleteKey
+	expired := status.ActiveKeys.DropRevoked(time.Now().Add(-r.ClockSkew), gracePeriod(obj), func(key ActiveKey) bool {
+		if !canDelete {
+			return false
+		}
+		err := r.Provider.DeleteKey(ctx, obj, key.KeyID)
+		if err == nil {
+			return false
+		}
+
+		attempts := key.DeleteAttempts + 1
+		log.Error(err, "failed to delete expired key", "keyId", key.KeyID, "attempts", attempts)
+		if attempts < maxAttempts {
+			failed[key.KeyID] = attempts
 			return true // keep in status to retry later
 		}
 
+		status.AddFailedCleanup(FailedCleanup{
+			KeyID:      key.KeyID,
+			Identifier: key.Identifier,
+			ExpiresAt:  key.ExpiresAt,
+			Attempts:   attempts,
+			LastError:  err.Error(),
+		}, maxFailed)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(obj, nil, corev1.EventTypeWarning, "CleanupFailed", "Cleanup",
+				"Gave up deleting expired key %s after %d attempts, delete it manually: %v",
+				key.KeyID, attempts, err)
+		}
 		return false
 	})
+	for i := range status.ActiveKeys {
+		if attempts, ok := failed[status.ActiveKeys[i].KeyID]; ok {
+			status.ActiveKeys[i].DeleteAttempts = attempts
+		}
+	}
+	excess := r.dropExcessKeys(ctx, obj)
 
-	if len(expired) > 0 {
-		if err := r.Status().Update(ctx, obj); err != nil {
+	if len(expired) > 0 || len(failed) > 0 || len(excess) > 0 {
+		if err := r.updateStatus(ctx, obj); err != nil {
 			log.Error(err, "failed to update status after key cleanup")
 		}
 	}
@@ -164,50 +801,466 @@ func (r *Reconciler[O]) handleCleanup(ctx context.Context, obj O) error {
 	return nil
 }
 
+// refreshUsage records when each active key was last used, if the provider
+// implements [UsageReporter] and the usage was not refreshed within
+// [Reconciler.UsageInterval]. Usage is informational, so errors are only
+// logged and retried on the next reconciliation.
+func (r *Reconciler[O]) refreshUsage(ctx context.Context, obj O) {
+	reporter := r.usageReporter()
+	if reporter == nil {
+		return
+	}
+
+	status := obj.GetStatus()
+	now := time.Now()
+	if status.UsageReportedAt != nil && now.Sub(status.UsageReportedAt.Time) < r.usageInterval() {
+		return
+	}
+
+	log := log.FromContext(ctx)
+	lastUsed := make([]*metav1.Time, len(status.ActiveKeys))
+	for i, key := range status.ActiveKeys {
+		at, err := reporter.LastUsed(ctx, obj, key.KeyID)
+		if err != nil {
+			log.Error(err, "failed to get key usage", "keyId", key.KeyID)
+			return
+		}
+		if !at.IsZero() {
+			lastUsed[i] = &metav1.Time{Time: at}
+		}
+	}
+	for i := range status.ActiveKeys {
+		status.ActiveKeys[i].LastUsedAt = lastUsed[i]
+	}
+	status.UsageReportedAt = &metav1.Time{Time: now}
+
+	if err := r.updateStatus(ctx, obj); err != nil {
+		log.Error(err, "failed to update status after refreshing key usage")
+	}
+}
+
+// validate validates obj and checks it against the provider's
+// [Capabilities].
+func (r *Reconciler[O]) validate(obj O) error {
+	if err := obj.Validate(); err != nil {
+		return err
+	}
+	if err := validateLayout(obj); err != nil {
+		return err
+	}
+	if err := validateOverrides(obj, time.Now()); err != nil {
+		return err
+	}
+	if err := validateRotationPolicy(obj); err != nil {
+		return err
+	}
+	if err := validateDeletionPolicy(obj); err != nil {
+		return err
+	}
+	if err := validateSecretTemplate(obj); err != nil {
+		return err
+	}
+	if err := validateTargets(obj); err != nil {
+		return err
+	}
+	if err := validateReplication(obj); err != nil {
+		return err
+	}
+	if err := r.validateSelfManaged(obj); err != nil {
+		return err
+	}
+	return ProviderCapabilities(r.Provider).Validate(obj)
+}
+
+// usageReporter returns the provider as a [UsageReporter], looking through
+// wrappers such as [InstrumentedProvider], or nil if it does not report usage.
+func (r *Reconciler[O]) usageReporter() UsageReporter[O] {
+	u, _ := findProvider[O, UsageReporter[O]](r.Provider)
+	return u
+}
+
+func (r *Reconciler[O]) usageInterval() time.Duration {
+	if r.UsageInterval <= 0 {
+		return DefaultUsageInterval
+	}
+	return r.UsageInterval
+}
+
+// dropExcessKeys deletes the oldest keys at the provider while obj tracks more
+// than [Reconciler.MaxActiveKeys] keys, and removes them from the status.
+// It returns the removed keys; keys that fail to delete are kept. Keys of
+// self-managed resources are never deleted early, see [AnnotationSelfManaged].
+func (r *Reconciler[O]) dropExcessKeys(ctx context.Context, obj O) []ActiveKey {
+	if selfManaged(obj) {
+		return nil
+	}
+	limit := r.MaxActiveKeys
+	if limit <= 0 {
+		limit = DefaultMaxActiveKeys
+	}
+
+	status := obj.GetStatus()
+	excess := status.ActiveKeys.Excess(limit, status.CurrentKeyID)
+	if len(excess) == 0 {
+		return nil
+	}
+
+	log := log.FromContext(ctx)
+	log.Info("too many active keys, deleting the oldest before expiry",
+		"count", len(status.ActiveKeys), "limit", limit)
+
+	var dropped []ActiveKey
+	for i, err := range r.deleteKeys(ctx, obj, excess) {
+		if err != nil {
+			log.Error(err, "failed to delete excess key", "keyId", excess[i].KeyID)
+			continue
+		}
+		dropped = append(dropped, excess[i])
+	}
+	if len(dropped) == 0 {
+		return nil
+	}
+
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, corev1.EventTypeWarning, "KeyLimitExceeded", "Cleanup",
+			"Deleted the %d oldest of %d active keys before expiry to stay within the limit of %d",
+			len(dropped), len(status.ActiveKeys), limit)
+	}
+	status.ActiveKeys = slices.DeleteFunc(status.ActiveKeys, func(k ActiveKey) bool {
+		return slices.ContainsFunc(dropped, func(d ActiveKey) bool { return d.KeyID == k.KeyID })
+	})
+
+	return dropped
+}
+
 // reconcileOutputSecret creates or updates the Kubernetes Secret that holds
 // the provisioned credentials. The secret is owned by the CRD so it gets
-// garbage-collected on deletion.
-func (r *Reconciler[O]) reconcileOutputSecret(ctx context.Context, obj O, result *Result) error {
-	ref := obj.GetSecretRef()
+// garbage-collected on deletion. Data larger than [Reconciler.MaxSecretSize]
+// fails with [ErrSecretTooLarge], unless [Reconciler.SplitOversizedSecrets]
+// allows splitting it across several secrets. With [Reconciler.Envelope], the
+// encrypted data is written, and each split secret carries the envelope
+// header. The checksums of the written secrets are added to written.
+func (r *Reconciler[O]) reconcileOutputSecret(ctx context.Context, obj O, result *Result, written secretChecksums) error {
+	name := obj.GetSecretRef().Name
+	limit := r.maxSecretSize()
+
+	plain := outputData(obj, result)
+	data := plain
+	if r.Envelope != nil {
+		sealed, err := envelope.Seal(ctx, r.Envelope, data)
+		if err != nil {
+			return fmt.Errorf("encrypting secret data: %w", err)
+		}
+		data = sealed
+	}
+
+	if !r.SplitOversizedSecrets {
+		if size := dataSize(data); size > limit {
+			return fmt.Errorf("%w: rendered data has %d bytes, the limit is %d", ErrSecretTooLarge, size, limit)
+		}
+		err := r.writeOutputSecret(ctx, obj, written, name, func(secret *corev1.Secret) {
+			setSecretType(secret, result.SecretType)
+			secret.StringData = data
+		})
+		if err != nil {
+			return err
+		}
+		return r.reconcileTargets(ctx, obj, plain, result.SecretType, written)
+	}
+
+	header := data[envelope.HeaderKey]
+	if r.Envelope != nil {
+		data = maps.Clone(data)
+		delete(data, envelope.HeaderKey)
+		limit -= len(header)
+	}
+	parts, err := splitData(data, limit)
+	if err != nil {
+		return err
+	}
+	if r.Envelope != nil {
+		for _, part := range parts {
+			part[envelope.HeaderKey] = header
+		}
+	}
+	names := make([]string, len(parts))
+	for i := range parts {
+		names[i] = splitSecretName(name, i)
+	}
+	for i, part := range parts {
+		err := r.writeOutputSecret(ctx, obj, written, names[i], func(secret *corev1.Secret) {
+			// Drop keys that moved to another part.
+			for key := range data {
+				if _, ok := part[key]; !ok {
+					delete(secret.Data, key)
+				}
+			}
+			secret.StringData = part
+			if len(parts) == 1 {
+				setSecretType(secret, result.SecretType)
+			}
+			if i == 0 && len(parts) > 1 {
+				metav1.SetMetaDataAnnotation(&secret.ObjectMeta, AnnotationSplitSecrets, strings.Join(names[1:], ","))
+			} else {
+				delete(secret.Annotations, AnnotationSplitSecrets)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("secret %s: %w", names[i], err)
+		}
+	}
+
+	if err := r.deleteStaleSplits(ctx, obj, name, len(parts)); err != nil {
+		return err
+	}
+	return r.reconcileTargets(ctx, obj, plain, result.SecretType, written)
+}
+
+// setSecretType sets the type of an output secret that is about to be
+// created. The type of an existing secret is immutable and kept as is.
+func setSecretType(secret *corev1.Secret, typ corev1.SecretType) {
+	if typ != "" && secret.ResourceVersion == "" {
+		secret.Type = typ
+	}
+}
+
+// writeOutputSecret creates or updates the named output secret as
+// [FieldOwner], applying mutate to set its data, copying the labels selected
+// by [Reconciler.PropagateLabels], applying the [SecretTemplate] of obj,
+// setting the [StandardLabels] and recording [AnnotationManagedKeys] and
+// [AnnotationDataChecksum], which is also added to written. Keys the
+// reconciler did not write are kept. An immutable secret, or one whose type
+// differs from the template's, is recreated, see
+// [Reconciler.recreateOutputSecret].
+func (r *Reconciler[O]) writeOutputSecret(
+	ctx context.Context,
+	obj O,
+	written secretChecksums,
+	name string,
+	mutate func(*corev1.Secret),
+) error {
+	return r.writeSecret(ctx, obj, written, client.ObjectKey{Namespace: obj.GetNamespace(), Name: name}, mutate)
+}
+
+// writeSecret is [Reconciler.writeOutputSecret] for a secret in any
+// namespace, such as a target, see [OutputTarget]. Only secrets in the
+// namespace of obj are owned by it, as owner references cannot cross
+// namespaces.
+func (r *Reconciler[O]) writeSecret(
+	ctx context.Context,
+	obj O,
+	written secretChecksums,
+	key client.ObjectKey,
+	mutate func(*corev1.Secret),
+) error {
+	setData := mutate
+	mutate = func(secret *corev1.Secret) {
+		r.propagateLabels(obj, secret)
+		if r.Envelope == nil {
+			// A header left from before encryption was disabled would
+			// make consumers try to decrypt the plain values.
+			delete(secret.Data, envelope.HeaderKey)
+		}
+		setData(secret)
+		setManagedKeys(secret)
+		applySecretTemplate(obj, secret)
+		r.setStandardLabels(obj, secret)
+		setDataChecksum(secret)
+		written.add(secret)
+	}
+
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      ref.Name,
-			Namespace: obj.GetNamespace(),
+			Name:      key.Name,
+			Namespace: key.Namespace,
 		},
 	}
 
-	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
-		if err := controllerutil.SetControllerReference(obj, secret, r.Scheme); err != nil {
+	var existing corev1.Secret
+	if err := r.Get(ctx, client.ObjectKeyFromObject(secret), &existing); client.IgnoreNotFound(err) != nil {
+		return err
+	} else if err == nil && existing.Immutable != nil && *existing.Immutable {
+		return r.recreateOutputSecret(ctx, obj, &existing, "it is immutable", mutate)
+	} else if err == nil && secretTypeChanged(obj, &existing) {
+		return r.recreateOutputSecret(ctx, obj, &existing, "its type changed", mutate)
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, client.WithFieldOwner(r.Client, FieldOwner), secret, func() error {
+		if err := r.ownSecret(obj, secret); err != nil {
 			return err
 		}
-		secret.StringData = result.StringData
+		mutate(secret)
 		return nil
 	})
 
 	return err
 }
 
+// recreateOutputSecret replaces an output secret the API server refuses to
+// update, because it is immutable or its type is to change, by deleting it
+// and creating it again with the new credentials. Other data keys, labels,
+// annotations, the type unless mutate changes it and the immutability itself
+// are carried over, so policies requiring immutable secrets stay satisfied.
+func (r *Reconciler[O]) recreateOutputSecret(
+	ctx context.Context,
+	obj O,
+	existing *corev1.Secret,
+	why string,
+	mutate func(*corev1.Secret),
+) error {
+	log.FromContext(ctx).Info("recreating output secret because "+why, "secret", existing.Name)
+
+	if err := r.Delete(ctx, existing, client.Preconditions{
+		UID:             &existing.UID,
+		ResourceVersion: &existing.ResourceVersion,
+	}); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("deleting secret %s: %w", existing.Name, err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        existing.Name,
+			Namespace:   existing.Namespace,
+			Labels:      existing.Labels,
+			Annotations: existing.Annotations,
+		},
+		Type:      existing.Type,
+		Immutable: existing.Immutable,
+		Data:      existing.Data,
+	}
+	mutate(secret)
+	if err := r.ownSecret(obj, secret); err != nil {
+		return err
+	}
+	if err := r.Create(ctx, secret, client.FieldOwner(FieldOwner)); err != nil {
+		return fmt.Errorf("recreating immutable secret: %w", err)
+	}
+
+	return nil
+}
+
+// ownSecret makes obj the controller of secret if it is in the namespace of
+// obj, so that it is garbage-collected with obj. Targets in other namespaces
+// are deleted by [Reconciler.deleteTargets] instead.
+func (r *Reconciler[O]) ownSecret(obj O, secret *corev1.Secret) error {
+	if secret.Namespace != obj.GetNamespace() {
+		return nil
+	}
+	return controllerutil.SetControllerReference(obj, secret, r.Scheme)
+}
+
 // failStatus persists a failed status and returns the error for backoff retry.
 func (r *Reconciler[O]) failStatus(ctx context.Context, obj O, err error) (ctrl.Result, error) {
 	obj.GetStatus().SetFailed(obj.GetGeneration(), err)
-	if updateErr := r.Status().Update(ctx, obj); updateErr != nil {
+	if updateErr := r.updateStatus(ctx, obj); updateErr != nil {
 		return ctrl.Result{}, updateErr
 	}
 
 	return ctrl.Result{}, err
 }
 
-// scheduleNext returns a ctrl.Result that requeues at the next renewal time.
-// If no active keys exist, it triggers an immediate requeue.
+// loadKeys populates the status with the keys held by the [KeyStore], if
+// one is configured. Keys still recorded in the status (from before the store
+// was configured) are merged in so they are not orphaned.
+func (r *Reconciler[O]) loadKeys(ctx context.Context, obj O) error {
+	if r.KeyStore == nil {
+		return nil
+	}
+
+	keys, err := r.KeyStore.Load(ctx, obj)
+	if err != nil {
+		return fmt.Errorf("loading keys: %w", err)
+	}
+
+	status := obj.GetStatus()
+	status.ActiveKeys = mergeKeys(keys, status.ActiveKeys)
+
+	return nil
+}
+
+// handleKeyStoreConflict fails obj without touching its keys when its
+// [KeyStore] is taken by another object, see [ErrKeyStoreConflict]. The
+// status is written without the store, and the reconciler checks again after
+// [ConflictBackoff] rather than retrying with backoff.
+func (r *Reconciler[O]) handleKeyStoreConflict(ctx context.Context, obj O, err error) (ctrl.Result, error) {
+	log.FromContext(ctx).Error(err, "key store is taken by another object")
+	r.recordKeyStoreConflict(obj, err)
+	obj.GetStatus().SetFailed(obj.GetGeneration(), err)
+	if err := r.Status().Update(ctx, obj); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: ConflictBackoff}, nil
+}
+
+// recordKeyStoreConflict records a Warning event for an [ErrKeyStoreConflict].
+func (r *Reconciler[O]) recordKeyStoreConflict(obj O, err error) {
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, corev1.EventTypeWarning, ReasonKeyStoreConflict, "Reconcile",
+			"The key store is taken by another object: %v", err)
+	}
+}
+
+// updateStatus writes the status subresource. With a [KeyStore] configured,
+// the active keys are saved to the store first and omitted from the status.
+func (r *Reconciler[O]) updateStatus(ctx context.Context, obj O) error {
+	if r.KeyStore == nil {
+		return r.Status().Update(ctx, obj)
+	}
+
+	status := obj.GetStatus()
+	keys := status.ActiveKeys
+	if err := r.KeyStore.Save(ctx, obj, keys); err != nil {
+		if errors.Is(err, ErrKeyStoreConflict) {
+			r.recordKeyStoreConflict(obj, err)
+		}
+		return fmt.Errorf("saving keys: %w", err)
+	}
+
+	status.ActiveKeys = nil
+	defer func() { status.ActiveKeys = keys }()
+
+	return r.Status().Update(ctx, obj)
+}
+
+// scheduleNext returns a ctrl.Result that requeues at the next renewal time,
+// or earlier to refresh the key usage (see [UsageReporter]), when rotation
+// overrides (see [AnnotationRenewBefore]) end, or when the grace period of a
+// superseded key passes (see [RotationPolicy]). If no active keys exist, it
+// triggers an immediate requeue.
 func (r *Reconciler[O]) scheduleNext(obj O) ctrl.Result {
-	if d := obj.GetStatus().RenewalDuration(); d > 0 {
+	now := time.Now()
+	if d := obj.GetStatus().RenewalDuration(now, r.renewalSkew(obj, now)); d > 0 {
+		if r.usageReporter() != nil {
+			d = min(d, r.usageInterval())
+		}
+		// The renewal is due at another time once the overrides end.
+		if end, ok := overrideEnd(obj, now); ok {
+			d = min(d, max(end.Sub(now), time.Second))
+		}
+		if at, ok := nextRevocation(obj.GetStatus().ActiveKeys, gracePeriod(obj)); ok {
+			d = min(d, max(at.Add(r.ClockSkew).Sub(now), time.Second))
+		}
 		return ctrl.Result{RequeueAfter: d}
 	}
 
 	return ctrl.Result{Requeue: true}
 }
 
-// secretHasData checks whether the output secret exists and contains data.
+// secretUnmanaged reports whether the output secret is annotated with
+// [AnnotationUnmanaged].
+func (r *Reconciler[O]) secretUnmanaged(ctx context.Context, obj O) bool {
+	var secret corev1.Secret
+	key := client.ObjectKey{Namespace: obj.GetNamespace(), Name: obj.GetSecretRef().Name}
+	if err := r.Get(ctx, key, &secret); err != nil {
+		return false
+	}
+
+	return secret.Annotations[AnnotationUnmanaged] == "true"
+}
+
+// secretHasData checks whether the output secret exists and contains data,
+// and all keys of [AnnotationManagedKeys] if it is set, so that credentials
+// removed from a secret that also holds other keys are provisioned again.
 func (r *Reconciler[O]) secretHasData(ctx context.Context, obj O) bool {
 	var secret corev1.Secret
 	key := client.ObjectKey{Namespace: obj.GetNamespace(), Name: obj.GetSecretRef().Name}
@@ -215,5 +1268,14 @@ func (r *Reconciler[O]) secretHasData(ctx context.Context, obj O) bool {
 		return false
 	}
 
-	return len(secret.Data) > 0
+	keys, ok := managedKeys(&secret)
+	if !ok {
+		return len(secret.Data) > 0
+	}
+	for _, k := range keys {
+		if _, ok := secret.Data[k]; !ok {
+			return false
+		}
+	}
+	return len(keys) > 0
 }
@@ -0,0 +1,710 @@
+package framework_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// deleteProvider is a [framework.Provider] that records DeleteKey
+// concurrency and fails for selected keys.
+type deleteProvider struct {
+	stubProvider
+
+	mu       sync.Mutex
+	inFlight int
+	maxSeen  int
+	deleted  []string
+	fail     map[string]bool
+}
+
+func (p *deleteProvider) DeleteKey(_ context.Context, _ *testObject, keyID string) error {
+	p.mu.Lock()
+	p.inFlight++
+	p.maxSeen = max(p.maxSeen, p.inFlight)
+	p.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inFlight--
+	if p.fail[keyID] {
+		return errors.New("boom")
+	}
+	p.deleted = append(p.deleted, keyID)
+	return nil
+}
+
+// newDeletingObject returns an object marked for deletion that tracks n
+// active keys named key-0 to key-(n-1).
+func newDeletingObject(n int) *testObject {
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	obj.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	now := time.Now()
+	for i := range n {
+		obj.Status.ActiveKeys = append(obj.Status.ActiveKeys, framework.ActiveKey{
+			KeyID:     fmt.Sprintf("key-%d", i),
+			CreatedAt: metav1.NewTime(now),
+			ExpiresAt: metav1.NewTime(now.Add(time.Hour)),
+		})
+	}
+	return obj
+}
+
+func TestReconcile_DeletionIsBoundedParallel(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newDeletingObject(10)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).Build()
+	p := &deleteProvider{}
+	r := &framework.Reconciler[*testObject]{
+		Client:            c,
+		Scheme:            scheme,
+		Provider:          p,
+		DeleteConcurrency: 3,
+	}
+
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if len(p.deleted) != 10 {
+		t.Errorf("expected all 10 keys deleted, got %v", p.deleted)
+	}
+	if p.maxSeen != 3 {
+		t.Errorf("expected at most 3 concurrent deletions to be reached, got %d", p.maxSeen)
+	}
+	if err := c.Get(context.Background(), req.NamespacedName, &testObject{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected object to be gone after finalizer removal, got %v", err)
+	}
+}
+
+func TestReconcile_DeletionAggregatesErrors(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newDeletingObject(4)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).Build()
+	p := &deleteProvider{fail: map[string]bool{"key-1": true, "key-3": true}}
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p}
+
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+	_, err := r.Reconcile(context.Background(), req)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{"failed to delete 2 active key(s)", "key key-1: boom", "key key-3: boom"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to contain %q, got: %v", want, err)
+		}
+	}
+
+	var got testObject
+	if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatalf("expected object to be kept while active keys remain: %v", err)
+	}
+}
+
+func TestReconcile_ForceDeleteAnnotation(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newDeletingObject(2)
+	obj.Annotations = map[string]string{framework.AnnotationForceDelete: "true"}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	recorder := events.NewFakeRecorder(10)
+	r := &framework.Reconciler[*testObject]{
+		Client:              c,
+		Scheme:              scheme,
+		Provider:            &deleteProvider{fail: map[string]bool{"key-1": true}},
+		ForceDeleteAttempts: 2,
+		Recorder:            recorder,
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(context.Background(), req); err == nil {
+		t.Fatal("expected the first failed attempt to block deletion")
+	}
+	var got testObject
+	if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatalf("expected object to be kept after the first attempt: %v", err)
+	}
+	if got.Status.DeletionFailures != 1 {
+		t.Errorf("expected 1 recorded deletion failure, got %d", got.Status.DeletionFailures)
+	}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("expected the second failed attempt to force deletion, got: %v", err)
+	}
+	if err := c.Get(context.Background(), req.NamespacedName, &testObject{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected object to be gone after force deletion, got %v", err)
+	}
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "Warning ForceDeleted") || !strings.Contains(event, "key-1") {
+			t.Errorf("unexpected event: %s", event)
+		}
+	default:
+		t.Error("expected a ForceDeleted event")
+	}
+}
+
+func TestReconcile_ForceDeleteAfter(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newDeletingObject(1)
+	obj.DeletionTimestamp = &metav1.Time{Time: time.Now().Add(-2 * time.Hour)}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{
+		Client:   c,
+		Scheme:   scheme,
+		Provider: &deleteProvider{fail: map[string]bool{"key-0": true}},
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(context.Background(), req); err == nil {
+		t.Fatal("expected deletion to be blocked without ForceDeleteAfter")
+	}
+
+	r.ForceDeleteAfter = time.Hour
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("expected deletion pending for 2h to be forced, got: %v", err)
+	}
+	if err := c.Get(context.Background(), req.NamespacedName, &testObject{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected object to be gone after force deletion, got %v", err)
+	}
+}
+
+// countingProvider is a [framework.Provider] that counts Provision calls.
+type countingProvider struct {
+	stubProvider
+
+	provisions int
+}
+
+func (p *countingProvider) Provision(ctx context.Context, obj *testObject) (*framework.Result, error) {
+	p.provisions++
+	result, err := p.stubProvider.Provision(ctx, obj)
+	if err == nil {
+		result.KeyID = fmt.Sprintf("key-%d", p.provisions)
+		result.StringData = map[string]string{"KEY": result.KeyID}
+	}
+	return result, err
+}
+
+func TestReconcile_SecretWriteFailureRetainsResult(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	failWrites := true
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, o client.Object, opts ...client.CreateOption) error {
+				if _, ok := o.(*corev1.Secret); ok && failWrites {
+					return errors.New("admission webhook denied the request")
+				}
+				return c.Create(ctx, o, opts...)
+			},
+		}).Build()
+	p := &countingProvider{}
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	for range 2 {
+		if _, err := r.Reconcile(context.Background(), req); err == nil {
+			t.Fatal("expected secret write to fail")
+		}
+	}
+	var got testObject
+	if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Status.ActiveKeys) != 1 || got.Status.ActiveKeys[0].KeyID != "key-1" {
+		t.Errorf("expected the unwritten key to be tracked once, got %v", got.Status.ActiveKeys)
+	}
+
+	failWrites = false
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if p.provisions != 1 {
+		t.Errorf("expected a single Provision call across retries, got %d", p.provisions)
+	}
+	var secret corev1.Secret
+	secretKey := client.ObjectKey{Namespace: "default", Name: "app-secret"}
+	if err := c.Get(context.Background(), secretKey, &secret); err != nil {
+		t.Fatalf("expected output secret: %v", err)
+	}
+	if got := secret.StringData["KEY"]; got != "key-1" {
+		t.Errorf("expected retained credentials in secret, got %q", got)
+	}
+}
+
+// partialProvider is a [framework.Provider] that creates a key and then
+// fails, returning the key with the error.
+type partialProvider struct {
+	stubProvider
+}
+
+func (p *partialProvider) Provision(context.Context, *testObject) (*framework.Result, error) {
+	now := time.Now()
+	return &framework.Result{KeyID: "created", ProvisionedAt: now, ValidUntil: now.Add(time.Hour)},
+		errors.New("reading application failed")
+}
+
+func TestReconcile_TracksKeyOfFailedProvision(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: &partialProvider{}}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(context.Background(), req); err == nil {
+		t.Fatal("expected provisioning to fail")
+	}
+	var got testObject
+	if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Status.ActiveKeys) != 1 || got.Status.ActiveKeys[0].KeyID != "created" {
+		t.Errorf("expected the created key to be tracked for cleanup, got %+v", got.Status.ActiveKeys)
+	}
+	if got.Status.CurrentKeyID != "" {
+		t.Errorf("expected no current key, got %q", got.Status.CurrentKeyID)
+	}
+}
+
+func TestReconcile_DropsExcessKeys(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newDeletingObject(5)
+	obj.DeletionTimestamp = nil
+	for i := range obj.Status.ActiveKeys {
+		obj.Status.ActiveKeys[i].CreatedAt = metav1.NewTime(time.Now().Add(time.Duration(i) * time.Minute))
+	}
+	obj.Status.CurrentKeyID = "key-4"
+	obj.Status.ObservedGeneration = obj.Generation
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-secret", Namespace: "default"},
+		Data:       map[string][]byte{"KEY": []byte("key-4")},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).WithStatusSubresource(obj).Build()
+	p := &deleteProvider{fail: map[string]bool{"key-1": true}}
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p, MaxActiveKeys: 2}
+
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	var got testObject
+	if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, k := range got.Status.ActiveKeys {
+		ids = append(ids, k.KeyID)
+	}
+	// key-1 fails to delete and is kept; key-3 is not old enough to be dropped.
+	if strings.Join(ids, ",") != "key-1,key-3,key-4" {
+		t.Errorf("expected the 3 oldest keys except key-1 to be dropped, got %v", ids)
+	}
+}
+
+func TestReconcile_ExpiredKeyRetryBudget(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	now := time.Now()
+	obj.Status.ActiveKeys = framework.ActiveKeys{
+		{KeyID: "stuck", CreatedAt: metav1.NewTime(now.Add(-2 * time.Hour)), ExpiresAt: metav1.NewTime(now.Add(-time.Hour))},
+		{KeyID: "current", CreatedAt: metav1.NewTime(now), ExpiresAt: metav1.NewTime(now.Add(time.Hour))},
+	}
+	obj.Status.CurrentKeyID = "current"
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-secret", Namespace: "default"},
+		Data:       map[string][]byte{"KEY": []byte("current")},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).WithStatusSubresource(obj).Build()
+	recorder := events.NewFakeRecorder(10)
+	r := &framework.Reconciler[*testObject]{
+		Client:            c,
+		Scheme:            scheme,
+		Provider:          &deleteProvider{fail: map[string]bool{"stuck": true}},
+		MaxDeleteAttempts: 2,
+		Recorder:          recorder,
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	var got testObject
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Status.ActiveKeys) != 2 || got.Status.ActiveKeys[0].DeleteAttempts != 1 {
+		t.Fatalf("expected stuck key to be kept with 1 attempt, got %+v", got.Status.ActiveKeys)
+	}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Status.ActiveKeys) != 1 || got.Status.ActiveKeys[0].KeyID != "current" {
+		t.Errorf("expected stuck key to be dropped from active keys, got %+v", got.Status.ActiveKeys)
+	}
+	failed := got.Status.FailedCleanups
+	if len(failed) != 1 || failed[0].KeyID != "stuck" || failed[0].Attempts != 2 || failed[0].LastError != "boom" {
+		t.Errorf("expected stuck key in failed cleanups, got %+v", failed)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "Warning CleanupFailed") || !strings.Contains(event, "stuck") {
+			t.Errorf("unexpected event: %s", event)
+		}
+	default:
+		t.Error("expected a CleanupFailed event")
+	}
+}
+
+func TestReconcile_ClockSkewDelaysCleanup(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	now := time.Now()
+	obj.Status.ActiveKeys = framework.ActiveKeys{
+		{KeyID: "just-expired", CreatedAt: metav1.NewTime(now.Add(-time.Hour)), ExpiresAt: metav1.NewTime(now.Add(-time.Minute))},
+		{KeyID: "current", CreatedAt: metav1.NewTime(now), ExpiresAt: metav1.NewTime(now.Add(time.Hour))},
+	}
+	obj.Status.CurrentKeyID = "current"
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-secret", Namespace: "default"},
+		Data:       map[string][]byte{"KEY": []byte("current")},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).WithStatusSubresource(obj).Build()
+	p := &deleteProvider{}
+	r := &framework.Reconciler[*testObject]{
+		Client:    c,
+		Scheme:    scheme,
+		Provider:  p,
+		ClockSkew: 5 * time.Minute,
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if len(p.deleted) != 0 {
+		t.Errorf("expected no key to be deleted within the skew allowance, got %v", p.deleted)
+	}
+
+	r.ClockSkew = 0
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if len(p.deleted) != 1 || p.deleted[0] != "just-expired" {
+		t.Errorf("expected just-expired to be deleted without skew, got %v", p.deleted)
+	}
+}
+
+// pairProvider is a [framework.Provider] that creates a new user with each
+// key and records the users dropped along with deleted keys.
+type pairProvider struct {
+	stubProvider
+
+	dropped []string
+}
+
+func (p *pairProvider) Provision(context.Context, *testObject) (*framework.Result, error) {
+	now := time.Now()
+	return &framework.Result{
+		KeyID: "new", Identifier: "user-new", ProvisionedAt: now, ValidUntil: now.Add(time.Hour),
+	}, nil
+}
+
+func (p *pairProvider) DeleteKey(_ context.Context, obj *testObject, keyID string) error {
+	p.dropped = append(p.dropped, obj.Status.ActiveKeys.Identifier(keyID))
+	return nil
+}
+
+func TestReconcile_RotatesPairs(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	now := time.Now()
+	obj.Status.ActiveKeys = framework.ActiveKeys{{
+		KeyID:      "old",
+		Identifier: "user-old",
+		CreatedAt:  metav1.NewTime(now.Add(-2 * time.Hour)),
+		ExpiresAt:  metav1.NewTime(now.Add(-time.Hour)),
+	}}
+	obj.Status.CurrentKeyID = "old"
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	p := &pairProvider{}
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if len(p.dropped) != 1 || p.dropped[0] != "user-old" {
+		t.Errorf("expected user-old to be dropped with its key, got %v", p.dropped)
+	}
+
+	var got testObject
+	if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	keys := got.Status.ActiveKeys
+	if len(keys) != 1 || keys[0].KeyID != "new" || keys[0].Identifier != "user-new" {
+		t.Errorf("expected the new pair to be tracked, got %+v", keys)
+	}
+	if got := got.Status.CurrentIdentifier(); got != "user-new" {
+		t.Errorf("expected current identifier user-new, got %q", got)
+	}
+}
+
+func TestReconcile_RecreatesImmutableSecret(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	immutable := true
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app-secret",
+			Namespace: "default",
+			Labels:    map[string]string{"team": "payments"},
+		},
+		Immutable: &immutable,
+		Data:      map[string][]byte{"KEY": []byte("old"), "OTHER": []byte("kept")},
+	}
+	// The fake client does not enforce immutability, so reject updates like
+	// the API server does.
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).WithStatusSubresource(obj).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Update: func(ctx context.Context, c client.WithWatch, o client.Object, opts ...client.UpdateOption) error {
+				if s, ok := o.(*corev1.Secret); ok && s.Immutable != nil && *s.Immutable {
+					return apierrors.NewInvalid(corev1.SchemeGroupVersion.WithKind("Secret").GroupKind(), s.Name, nil)
+				}
+				return c.Update(ctx, o, opts...)
+			},
+		}).Build()
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: &countingProvider{}}
+
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	var got corev1.Secret
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(secret), &got); err != nil {
+		t.Fatalf("expected recreated secret: %v", err)
+	}
+	if got.StringData["KEY"] != "key-1" || string(got.Data["OTHER"]) != "kept" {
+		t.Errorf("expected new credentials and other keys, got data %v and string data %v", got.Data, got.StringData)
+	}
+	if got.Immutable == nil || !*got.Immutable || got.Labels["team"] != "payments" {
+		t.Errorf("expected immutability and labels to be carried over, got %v and %v", got.Immutable, got.Labels)
+	}
+	if len(got.OwnerReferences) != 1 || got.OwnerReferences[0].Name != obj.Name {
+		t.Errorf("expected recreated secret to be owned by the object, got %v", got.OwnerReferences)
+	}
+}
+
+func TestReconcile_SecretType(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{
+		Client: c,
+		Scheme: scheme,
+		Provider: &dataProvider{
+			data:       map[string]string{corev1.TLSCertKey: "cert", corev1.TLSPrivateKeyKey: "key"},
+			secretType: corev1.SecretTypeTLS,
+		},
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	var got corev1.Secret
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "app-secret"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != corev1.SecretTypeTLS {
+		t.Errorf("expected a new secret of type %s, got %q", corev1.SecretTypeTLS, got.Type)
+	}
+
+	// The type of an existing secret cannot be changed.
+	opaque := newTestObject()
+	opaque.Name, opaque.UID = "opaque", "uid-2"
+	opaque.Finalizers = []string{framework.Finalizer}
+	opaque.SecretRef.Name = "opaque-secret"
+	existing := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "opaque-secret", Namespace: "default"},
+		Type:       corev1.SecretTypeOpaque,
+	}
+	if err := c.Create(context.Background(), opaque); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create(context.Background(), existing); err != nil {
+		t.Fatal(err)
+	}
+	req = ctrl.Request{NamespacedName: client.ObjectKeyFromObject(opaque)}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(existing), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != corev1.SecretTypeOpaque {
+		t.Errorf("expected the existing secret to keep its type, got %q", got.Type)
+	}
+}
+
+// usageProvider is a [framework.UsageReporter] with canned last-used times.
+type usageProvider struct {
+	stubProvider
+
+	lastUsed map[string]time.Time
+	calls    int
+}
+
+func (p *usageProvider) LastUsed(_ context.Context, _ *testObject, keyID string) (time.Time, error) {
+	p.calls++
+	return p.lastUsed[keyID], nil
+}
+
+func TestReconcile_RefreshesKeyUsage(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newDeletingObject(2)
+	obj.DeletionTimestamp = nil
+	obj.Status.CurrentKeyID = "key-1"
+	obj.Status.ObservedGeneration = obj.Generation
+	obj.Status.ActiveKeys[1].CreatedAt = metav1.NewTime(time.Now().Add(time.Minute))
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-secret", Namespace: "default"},
+		Data:       map[string][]byte{"KEY": []byte("key-1")},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).WithStatusSubresource(obj).Build()
+	used := time.Now().Add(-time.Minute).Truncate(time.Second)
+	p := &usageProvider{lastUsed: map[string]time.Time{"key-1": used}}
+	r := &framework.Reconciler[*testObject]{
+		Client:        c,
+		Scheme:        scheme,
+		Provider:      framework.Instrument(p, prometheus.NewRegistry()),
+		UsageInterval: 10 * time.Minute,
+	}
+
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+	result, err := r.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if result.RequeueAfter != 10*time.Minute {
+		t.Errorf("expected a requeue after the usage interval, got %v", result.RequeueAfter)
+	}
+
+	var got testObject
+	if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.UsageReportedAt == nil {
+		t.Fatal("expected the usage report time to be recorded")
+	}
+	if k := got.Status.ActiveKeys[0]; k.LastUsedAt != nil {
+		t.Errorf("expected unused key-0 to have no last use, got %v", k.LastUsedAt)
+	}
+	if k := got.Status.ActiveKeys[1]; k.LastUsedAt == nil || !k.LastUsedAt.Time.Equal(used) {
+		t.Errorf("expected key-1 to be last used at %v, got %v", used, k.LastUsedAt)
+	}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if p.calls != 2 {
+		t.Errorf("expected usage to be reported once per key within the interval, got %d calls", p.calls)
+	}
+}
+
+func TestReconcile_UnmanagedSecret(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app-secret",
+			Namespace:   "default",
+			Annotations: map[string]string{framework.AnnotationUnmanaged: "true"},
+		},
+		Data: map[string][]byte{"KEY": []byte("hand-rotated")},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).WithStatusSubresource(obj).Build()
+	recorder := events.NewFakeRecorder(10)
+	p := &countingProvider{}
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p, Recorder: recorder}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	for range 2 {
+		if _, err := r.Reconcile(context.Background(), req); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+	}
+	if p.provisions != 0 {
+		t.Errorf("expected no provisioning while unmanaged, got %d calls", p.provisions)
+	}
+	var got corev1.Secret
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(secret), &got); err != nil {
+		t.Fatal(err)
+	}
+	if string(got.Data["KEY"]) != "hand-rotated" || len(got.StringData) != 0 {
+		t.Errorf("expected the unmanaged secret to be left alone, got %v", got.Data)
+	}
+	var status testObject
+	if err := c.Get(context.Background(), req.NamespacedName, &status); err != nil {
+		t.Fatal(err)
+	}
+	cond := meta.FindStatusCondition(status.Status.Conditions, framework.ConditionDegraded)
+	if cond == nil || cond.Status != metav1.ConditionTrue || cond.Reason != framework.ReasonSecretUnmanaged {
+		t.Fatalf("expected a Degraded condition, got %v", status.Status.Conditions)
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("expected a single SecretUnmanaged event, got %d", len(recorder.Events))
+	}
+
+	delete(got.Annotations, framework.AnnotationUnmanaged)
+	if err := c.Update(context.Background(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if p.provisions != 1 {
+		t.Errorf("expected provisioning to resume, got %d calls", p.provisions)
+	}
+	if err := c.Get(context.Background(), req.NamespacedName, &status); err != nil {
+		t.Fatal(err)
+	}
+	if meta.FindStatusCondition(status.Status.Conditions, framework.ConditionDegraded) != nil {
+		t.Errorf("expected the Degraded condition to be cleared, got %v", status.Status.Conditions)
+	}
+}
@@ -0,0 +1,257 @@
+package framework
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Replication copies the output Secret of a resource, under the same name,
+// into all namespaces matching a label selector, and keeps the copies in sync
+// with it. Providers embed it in their spec as replication, see
+// [ReplicationHolder].
+type Replication struct {
+	// NamespaceSelector selects the namespaces the output Secret is copied
+	// to. They must allow it with the valet.ngl.cx/allow-targets-from
+	// annotation; others are skipped with a Warning event.
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector"`
+}
+
+// DeepCopy returns a deep copy of the replication, or nil.
+func (in *Replication) DeepCopy() *Replication {
+	if in == nil {
+		return nil
+	}
+	out := new(Replication)
+	in.NamespaceSelector.DeepCopyInto(&out.NamespaceSelector)
+	return out
+}
+
+// ReplicationHolder is optionally implemented by objects whose spec carries
+// a [Replication].
+type ReplicationHolder interface {
+	// GetReplication returns the replication of the object, or nil.
+	GetReplication() *Replication
+}
+
+// replication returns the [Replication] of obj, or nil.
+func replication(obj Object) *Replication {
+	if h, ok := obj.(ReplicationHolder); ok {
+		return h.GetReplication()
+	}
+	return nil
+}
+
+// validateReplication checks that the namespace selector of obj's
+// replication is valid.
+func validateReplication(obj Object) error {
+	repl := replication(obj)
+	if repl == nil {
+		return nil
+	}
+	if _, err := metav1.LabelSelectorAsSelector(&repl.NamespaceSelector); err != nil {
+		return fmt.Errorf("replication.namespaceSelector: %w", err)
+	}
+	return nil
+}
+
+// reconcileReplicas copies the output secret of obj, and its split secrets,
+// into the namespaces its [Replication] selects, and deletes the replicas in
+// namespaces it no longer selects or that no longer allow them. Unlike
+// targets, replicas are copied from the output secret rather than written
+// with the credentials, so that namespaces created or labelled later get
+// theirs without a rotation. Secrets written by a target are left to it.
+// Without a [Replication], it deletes the replicas left over from one, so it
+// only runs for those resources on renewal. The checksums of the written
+// replicas are added to written.
+func (r *Reconciler[O]) reconcileReplicas(ctx context.Context, obj O, written secretChecksums) error {
+	want := make(map[client.ObjectKey]bool)
+	if repl := replication(obj); repl != nil {
+		sources, err := r.replicaSources(ctx, obj)
+		if err != nil || len(sources) == 0 {
+			// Keep the replicas until there is something to copy.
+			return err
+		}
+		selector, err := metav1.LabelSelectorAsSelector(&repl.NamespaceSelector)
+		if err != nil {
+			return err
+		}
+		var namespaces corev1.NamespaceList
+		if err := r.List(ctx, &namespaces, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return fmt.Errorf("listing namespaces: %w", err)
+		}
+
+		targeted := make(map[client.ObjectKey]bool)
+		for _, t := range targets(obj) {
+			targeted[targetKey(obj, t)] = true
+		}
+		for _, ns := range namespaces.Items {
+			if ns.Name == obj.GetNamespace() || !ns.DeletionTimestamp.IsZero() {
+				continue
+			}
+			for i := range sources {
+				key := client.ObjectKey{Namespace: ns.Name, Name: sources[i].Name}
+				if targeted[key] {
+					continue
+				}
+				err := r.writeReplica(ctx, obj, written, key, &sources[i])
+				if errors.Is(err, ErrTargetNotAllowed) {
+					r.recordReplicationNotAllowed(ctx, obj, err)
+					break
+				} else if err != nil {
+					return fmt.Errorf("replica %s: %w", key, err)
+				}
+				want[key] = true
+			}
+		}
+	}
+
+	return r.deleteLabelled(ctx, obj, LabelReplicaOf, "replica", want)
+}
+
+// replicaSources returns the output secret of obj and its split secrets, or
+// none if the output secret does not exist yet.
+func (r *Reconciler[O]) replicaSources(ctx context.Context, obj O) ([]corev1.Secret, error) {
+	var secret corev1.Secret
+	key := client.ObjectKey{Namespace: obj.GetNamespace(), Name: obj.GetSecretRef().Name}
+	if err := r.Get(ctx, key, &secret); apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("getting output secret: %w", err)
+	}
+
+	sources := []corev1.Secret{secret}
+	if parts := secret.Annotations[AnnotationSplitSecrets]; parts != "" {
+		for _, name := range strings.Split(parts, ",") {
+			var part corev1.Secret
+			if err := r.Get(ctx, client.ObjectKey{Namespace: key.Namespace, Name: name}, &part); err != nil {
+				return nil, fmt.Errorf("getting split secret %s: %w", name, err)
+			}
+			sources = append(sources, part)
+		}
+	}
+	return sources, nil
+}
+
+// writeReplica copies the managed data, type and split annotation of source
+// to the replica key of obj, labelled with [LabelReplicaOf], unless it is up
+// to date already. The namespace must allow it, see
+// [Reconciler.checkTargetAllowed].
+func (r *Reconciler[O]) writeReplica(
+	ctx context.Context,
+	obj O,
+	written secretChecksums,
+	key client.ObjectKey,
+	source *corev1.Secret,
+) error {
+	if err := r.checkTargetAllowed(ctx, obj, key, LabelReplicaOf); err != nil {
+		return err
+	}
+
+	data := managedData(source)
+	parts := source.Annotations[AnnotationSplitSecrets]
+	var existing corev1.Secret
+	if err := r.Get(ctx, key, &existing); client.IgnoreNotFound(err) != nil {
+		return err
+	} else if err == nil && existing.Type == source.Type &&
+		existing.Annotations[AnnotationSplitSecrets] == parts &&
+		maps.EqualFunc(managedData(&existing), data, bytes.Equal) {
+		return nil
+	}
+
+	log.FromContext(ctx).Info("writing replica", "secret", key)
+	return r.writeSecret(ctx, obj, written, key, func(secret *corev1.Secret) {
+		setSecretType(secret, source.Type)
+		metav1.SetMetaDataLabel(&secret.ObjectMeta, LabelReplicaOf, string(obj.GetUID()))
+		if parts != "" {
+			metav1.SetMetaDataAnnotation(&secret.ObjectMeta, AnnotationSplitSecrets, parts)
+		} else {
+			delete(secret.Annotations, AnnotationSplitSecrets)
+		}
+		secret.StringData = make(map[string]string, len(data))
+		for k, v := range data {
+			secret.StringData[k] = string(v)
+		}
+	})
+}
+
+// recordReplicationNotAllowed logs and records that err kept the output
+// secret of obj from being replicated into a namespace.
+func (r *Reconciler[O]) recordReplicationNotAllowed(ctx context.Context, obj O, err error) {
+	log.FromContext(ctx).Info("skipping replica", "reason", err.Error())
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, corev1.EventTypeWarning, ReasonReplicationNotAllowed, "Reconcile",
+			"Not replicating secret %s: %v", obj.GetSecretRef().Name, err)
+	}
+}
+
+// replicationHandler returns an event handler that enqueues the resources
+// with a [Replication] for namespace events, so that namespaces get or lose
+// their replicas without waiting for a rotation. The resources are listed
+// with reader, which should be the cached client of the controller.
+func (r *Reconciler[O]) replicationHandler(reader client.Reader, scheme *runtime.Scheme) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, ns client.Object) []reconcile.Request {
+		log := log.FromContext(ctx).WithValues("namespace", ns.GetName())
+
+		gvk, err := apiutil.GVKForObject(r.Provider.NewObject(), scheme)
+		if err != nil {
+			log.Error(err, "failed to look up the resource kind")
+			return nil
+		}
+		list, err := scheme.New(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err != nil {
+			log.Error(err, "failed to create the resource list")
+			return nil
+		}
+		objList, ok := list.(client.ObjectList)
+		if !ok {
+			return nil
+		}
+		if err := reader.List(ctx, objList); err != nil {
+			log.Error(err, "failed to list resources for replication")
+			return nil
+		}
+		items, err := meta.ExtractList(objList)
+		if err != nil {
+			log.Error(err, "failed to list resources for replication")
+			return nil
+		}
+
+		var reqs []reconcile.Request
+		for _, item := range items {
+			if obj, ok := item.(Object); ok && replication(obj) != nil {
+				reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+			}
+		}
+		return reqs
+	})
+}
+
+// namespaceReplicationChanged filters the namespace events of
+// [Reconciler.replicationHandler] to those that can change where replicas
+// belong: created namespaces, and changes to the labels or to
+// [AnnotationAllowTargetsFrom]. Replicas in deleted namespaces go with them.
+var namespaceReplicationChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return !maps.Equal(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels()) ||
+			e.ObjectOld.GetAnnotations()[AnnotationAllowTargetsFrom] !=
+				e.ObjectNew.GetAnnotations()[AnnotationAllowTargetsFrom]
+	},
+	DeleteFunc: func(event.DeleteEvent) bool { return false },
+}
@@ -0,0 +1,188 @@
+package framework_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func replicaNamespace(name string, labels map[string]string, allowed bool) *corev1.Namespace {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	if allowed {
+		ns.Annotations = map[string]string{framework.AnnotationAllowTargetsFrom: "*"}
+	}
+	return ns
+}
+
+func TestReconcile_Replication(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	obj.Replication = &framework.Replication{
+		NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+	}
+	prod := replicaNamespace("prod", map[string]string{"team": "a"}, true)
+	closed := replicaNamespace("closed", map[string]string{"team": "a"}, false)
+	other := replicaNamespace("other", nil, true)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, prod, closed, other).
+		WithStatusSubresource(obj).WithInterceptorFuncs(mergeStringData).Build()
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: &countingProvider{}}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	replica := func(namespace string) (*corev1.Secret, error) {
+		var secret corev1.Secret
+		err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "app-secret"}, &secret)
+		return &secret, err
+	}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	got, err := replica("prod")
+	if err != nil {
+		t.Fatalf("expected a replica in the selected namespace: %v", err)
+	}
+	if string(got.Data["KEY"]) != "key-1" || got.Labels[framework.LabelReplicaOf] != string(obj.UID) {
+		t.Errorf("expected a labelled copy of the credentials, got %v and %v", got.Data, got.Labels)
+	}
+	if _, err := replica("closed"); !apierrors.IsNotFound(err) {
+		t.Errorf("expected no replica in the namespace not allowing it, got %v", err)
+	}
+	if _, err := replica("other"); !apierrors.IsNotFound(err) {
+		t.Errorf("expected no replica in the namespace not selected, got %v", err)
+	}
+
+	// Namespaces selected later get a replica without a rotation, and
+	// namespaces no longer selected lose theirs.
+	other.Labels = map[string]string{"team": "a"}
+	prod.Labels = nil
+	for _, ns := range []*corev1.Namespace{other, prod} {
+		if err := c.Update(ctx, ns); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got, err := replica("other"); err != nil || string(got.Data["KEY"]) != "key-1" {
+		t.Errorf("expected a replica of the current credentials in the newly selected namespace, got %v", err)
+	}
+	if _, err := replica("prod"); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the replica in the namespace no longer selected to be deleted, got %v", err)
+	}
+
+	// Replicas are deleted with the resource.
+	if err := c.Delete(ctx, obj); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if _, err := replica("other"); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the replica to be deleted with the resource, got %v", err)
+	}
+}
+
+func TestReconcile_ReplicationKeepsForeignSecrets(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	obj.Replication = &framework.Replication{}
+	ns := replicaNamespace("prod", nil, true)
+	foreign := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "app-secret"},
+		StringData: map[string]string{"KEY": "theirs"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, ns, foreign).
+		WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: &countingProvider{}}
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	var got corev1.Secret
+	if err := c.Get(ctx, client.ObjectKeyFromObject(foreign), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.StringData["KEY"] != "theirs" {
+		t.Errorf("expected the secret not written by the resource to be left alone, got %v", got.StringData)
+	}
+}
+
+func TestReconcile_ReplicationRemoved(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	obj.Replication = &framework.Replication{}
+	ns := replicaNamespace("prod", nil, true)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, ns).
+		WithStatusSubresource(obj).WithInterceptorFuncs(mergeStringData).Build()
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: &countingProvider{}}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+	replicaKey := client.ObjectKey{Namespace: "prod", Name: "app-secret"}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if err := c.Get(ctx, replicaKey, &corev1.Secret{}); err != nil {
+		t.Fatalf("expected a replica: %v", err)
+	}
+
+	// Removing the replication changes the spec, and the renewal that
+	// follows deletes the replicas.
+	if err := c.Get(ctx, req.NamespacedName, obj); err != nil {
+		t.Fatal(err)
+	}
+	obj.Replication = nil
+	obj.Generation++
+	if err := c.Update(ctx, obj); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if err := c.Get(ctx, replicaKey, &corev1.Secret{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the replica to be deleted once replication is removed, got %v", err)
+	}
+}
+
+func TestReconcile_WithoutReplicationListsNothing(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	lists := 0
+	funcs := mergeStringData
+	funcs.List = func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+		switch list.(type) {
+		case *corev1.NamespaceList, *corev1.SecretList:
+			lists++
+		}
+		return c.List(ctx, list, opts...)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).
+		WithStatusSubresource(obj).WithInterceptorFuncs(funcs).Build()
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: &countingProvider{}}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	lists = 0
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if lists != 0 {
+		t.Errorf("expected no namespaces or secrets to be listed without replication, got %d lists", lists)
+	}
+}
@@ -0,0 +1,288 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// IndexSecretNames is the field index of Deployments and StatefulSets by the
+// names of the Secrets their pod template references, see
+// [Reconciler.RestartWorkloads].
+const IndexSecretNames = "valet.ngl.cx/secret-names"
+
+// RestartedAtAnnotation is set on the pod template of a workload to restart
+// its pods, as kubectl rollout restart does.
+const RestartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// workloadLists returns empty lists of the kinds of workloads restarted by
+// [Reconciler.RestartWorkloads].
+func workloadLists() []client.ObjectList {
+	return []client.ObjectList{&appsv1.DeploymentList{}, &appsv1.StatefulSetList{}}
+}
+
+// podTemplate returns the pod template of a Deployment or StatefulSet, or
+// nil for other objects.
+func podTemplate(obj client.Object) *corev1.PodTemplateSpec {
+	switch w := obj.(type) {
+	case *appsv1.Deployment:
+		return &w.Spec.Template
+	case *appsv1.StatefulSet:
+		return &w.Spec.Template
+	}
+	return nil
+}
+
+// workloadKind returns the kind of a Deployment or StatefulSet.
+func workloadKind(obj client.Object) string {
+	if _, ok := obj.(*appsv1.StatefulSet); ok {
+		return "StatefulSet"
+	}
+	return "Deployment"
+}
+
+// WorkloadSecretNames returns the sorted names of the Secrets the pod template
+// of a Deployment or StatefulSet references in volumes, projected volumes,
+// envFrom and env. It is the extractor of [IndexSecretNames], e.g. for fake
+// clients in tests.
+func WorkloadSecretNames(obj client.Object) []string {
+	tmpl := podTemplate(obj)
+	if tmpl == nil {
+		return nil
+	}
+
+	names := make(map[string]bool)
+	for _, v := range tmpl.Spec.Volumes {
+		if v.Secret != nil {
+			names[v.Secret.SecretName] = true
+		}
+		if v.Projected != nil {
+			for _, s := range v.Projected.Sources {
+				if s.Secret != nil {
+					names[s.Secret.Name] = true
+				}
+			}
+		}
+	}
+	containers := slices.Concat(tmpl.Spec.InitContainers, tmpl.Spec.Containers)
+	for _, c := range containers {
+		for _, e := range c.EnvFrom {
+			if e.SecretRef != nil {
+				names[e.SecretRef.Name] = true
+			}
+		}
+		for _, e := range c.Env {
+			if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil {
+				names[e.ValueFrom.SecretKeyRef.Name] = true
+			}
+		}
+	}
+	delete(names, "")
+	return slices.Sorted(maps.Keys(names))
+}
+
+// indexSecretNames registers [IndexSecretNames] for the workload kinds of
+// [workloadLists].
+func indexSecretNames(ctx context.Context, mgr ctrl.Manager) error {
+	for _, obj := range []client.Object{&appsv1.Deployment{}, &appsv1.StatefulSet{}} {
+		if err := mgr.GetFieldIndexer().IndexField(ctx, obj, IndexSecretNames, WorkloadSecretNames); err != nil {
+			return fmt.Errorf("indexing %s by secret names: %w", workloadKind(obj), err)
+		}
+	}
+	return nil
+}
+
+// secretChecksums holds the [AnnotationDataChecksum] of secrets by namespace
+// and name.
+type secretChecksums map[string]map[string]string
+
+// add records the checksum of secret, if it has one. It does nothing on a nil
+// secretChecksums.
+func (c secretChecksums) add(secret *corev1.Secret) {
+	if checksum := secret.Annotations[AnnotationDataChecksum]; c != nil && checksum != "" {
+		c.set(secret.Namespace, secret.Name, checksum)
+	}
+}
+
+// set records checksum for the secret name in namespace.
+func (c secretChecksums) set(namespace, name, checksum string) {
+	if c[namespace] == nil {
+		c[namespace] = make(map[string]string)
+	}
+	c[namespace][name] = checksum
+}
+
+// restartWorkloads restarts the Deployments and StatefulSets whose pod
+// template references the output secret of obj, a secret it was split into,
+// or one of its targets or replicas, in the namespace of that secret, once
+// its [AnnotationDataChecksum] changes, so that rotated credentials reach
+// pods that only read them at startup. The
+// checksums of the data the pods of a workload were started with are
+// recorded in its [AnnotationSecretChecksums]; workloads seen for the first
+// time are not restarted, as their pods already have the current data.
+//
+// The checksums of the secrets written in this reconcile are taken from
+// written rather than read back, as the cache may not have seen the writes
+// yet. Targets and replicas in other namespaces are not watched, so a stale
+// read would delay their restart until the next scheduled reconcile.
+func (r *Reconciler[O]) restartWorkloads(ctx context.Context, obj O, written secretChecksums) error {
+	if !r.RestartWorkloads {
+		return nil
+	}
+	current, err := r.readSecretChecksums(ctx, obj)
+	if err != nil {
+		return err
+	}
+	for namespace, checksums := range written {
+		for name, checksum := range checksums {
+			current.set(namespace, name, checksum)
+		}
+	}
+
+	for _, namespace := range slices.Sorted(maps.Keys(current)) {
+		checksums := current[namespace]
+		for _, list := range workloadLists() {
+			seen := make(map[string]bool)
+			for _, name := range slices.Sorted(maps.Keys(checksums)) {
+				if err := r.List(ctx, list, client.InNamespace(namespace),
+					client.MatchingFields{IndexSecretNames: name}); err != nil {
+					return fmt.Errorf("listing workloads: %w", err)
+				}
+				items, err := meta.ExtractList(list)
+				if err != nil {
+					return fmt.Errorf("listing workloads: %w", err)
+				}
+				for _, item := range items {
+					w, ok := item.(client.Object)
+					if !ok || seen[w.GetName()] {
+						continue
+					}
+					seen[w.GetName()] = true
+					if err := r.restartWorkload(ctx, obj, w, checksums); err != nil {
+						return fmt.Errorf("%s %s: %w", workloadKind(w), client.ObjectKeyFromObject(w), err)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// readSecretChecksums returns the [AnnotationDataChecksum] of the secrets
+// written for obj: its output secret and the secrets it was split into, and its
+// targets and replicas, which may be in other namespaces. The labelled
+// secrets are only listed if obj has targets or a [Replication].
+func (r *Reconciler[O]) readSecretChecksums(ctx context.Context, obj O) (secretChecksums, error) {
+	secrets, err := r.replicaSources(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	var labels []string
+	if len(targets(obj)) > 0 {
+		labels = append(labels, LabelTargetOf)
+	}
+	if replication(obj) != nil {
+		labels = append(labels, LabelReplicaOf)
+	}
+	for _, label := range labels {
+		var list corev1.SecretList
+		if err := r.List(ctx, &list, client.MatchingLabels{label: string(obj.GetUID())}); err != nil {
+			return nil, fmt.Errorf("listing secrets: %w", err)
+		}
+		secrets = append(secrets, list.Items...)
+	}
+
+	checksums := make(secretChecksums)
+	for i := range secrets {
+		checksums.add(&secrets[i])
+	}
+	return checksums, nil
+}
+
+// restartWorkload records checksums, by the names of the secrets in the
+// namespace of w, of the secrets w references in its
+// [AnnotationSecretChecksums], and restarts w with [RestartedAtAnnotation] if
+// one of them differs from the recorded one.
+func (r *Reconciler[O]) restartWorkload(
+	ctx context.Context,
+	obj O,
+	w client.Object,
+	checksums map[string]string,
+) error {
+	var recorded map[string]string
+	if v := w.GetAnnotations()[AnnotationSecretChecksums]; v != "" {
+		if err := json.Unmarshal([]byte(v), &recorded); err != nil {
+			log.FromContext(ctx).Info("ignoring invalid annotation", "annotation", AnnotationSecretChecksums,
+				"workload", w.GetName(), "error", err.Error())
+			recorded = nil
+		}
+	}
+
+	// Checksums of secrets the workload no longer references are dropped,
+	// those of secrets of other resources kept.
+	want := make(map[string]string)
+	var changed []string
+	for _, name := range WorkloadSecretNames(w) {
+		old, hasOld := recorded[name]
+		c, ok := checksums[name]
+		switch {
+		case ok:
+			want[name] = c
+			if hasOld && old != c {
+				changed = append(changed, name)
+			}
+		case hasOld:
+			want[name] = old
+		}
+	}
+	if maps.Equal(recorded, want) {
+		return nil
+	}
+
+	value, err := json.Marshal(want)
+	if err != nil {
+		return err
+	}
+	patch := client.MergeFrom(w.DeepCopyObject().(client.Object))
+	annotations := w.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[AnnotationSecretChecksums] = string(value)
+	w.SetAnnotations(annotations)
+	if len(changed) > 0 {
+		metav1.SetMetaDataAnnotation(&podTemplate(w).ObjectMeta, RestartedAtAnnotation,
+			time.Now().Format(time.RFC3339))
+	}
+	if err := r.Patch(ctx, w, patch); err != nil {
+		return err
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+	kind := workloadKind(w)
+	name := w.GetName()
+	if w.GetNamespace() != obj.GetNamespace() {
+		name = w.GetNamespace() + "/" + name
+	}
+	log.FromContext(ctx).Info("restarted workload for rotated secrets",
+		"kind", kind, "workload", name, "secrets", changed)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, corev1.EventTypeNormal, ReasonWorkloadRestarted, "Reconcile",
+			"Restarted %s %s for rotated secret %s", kind, name, strings.Join(changed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package framework_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func templateWithEnvFrom(secret string) corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+		Name: "app",
+		EnvFrom: []corev1.EnvFromSource{{
+			SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: secret}},
+		}},
+	}}}}
+}
+
+func TestWorkloadSecretNames(t *testing.T) {
+	deploy := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+		Volumes: []corev1.Volume{
+			{VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "volume"}}},
+			{VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{{Secret: &corev1.SecretProjection{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "projected"},
+				}}},
+			}}},
+		},
+		InitContainers: []corev1.Container{{Env: []corev1.EnvVar{{
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "env"},
+			}},
+		}}}},
+		Containers: templateWithEnvFrom("volume").Spec.Containers,
+	}}}}
+
+	got := framework.WorkloadSecretNames(deploy)
+	want := []string{"env", "projected", "volume"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+	if got := framework.WorkloadSecretNames(&corev1.Pod{}); got != nil {
+		t.Errorf("expected no names for other objects, got %v", got)
+	}
+}
+
+func TestReconcile_RestartWorkloads(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	consumer := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "consumer"},
+		Spec:       appsv1.DeploymentSpec{Template: templateWithEnvFrom("app-secret")},
+	}
+	stateful := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "stateful"},
+		Spec:       appsv1.StatefulSetSpec{Template: templateWithEnvFrom("app-secret")},
+	}
+	unrelated := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "unrelated"},
+		Spec:       appsv1.DeploymentSpec{Template: templateWithEnvFrom("other-secret")},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, consumer, stateful, unrelated).
+		WithStatusSubresource(obj).WithInterceptorFuncs(mergeStringData).
+		WithIndex(&appsv1.Deployment{}, framework.IndexSecretNames, framework.WorkloadSecretNames).
+		WithIndex(&appsv1.StatefulSet{}, framework.IndexSecretNames, framework.WorkloadSecretNames).
+		Build()
+	r := &framework.Reconciler[*testObject]{
+		Client: c, Scheme: scheme, Provider: &countingProvider{}, RestartWorkloads: true,
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	restartedAt := func(w client.Object) string {
+		t.Helper()
+		if err := c.Get(ctx, client.ObjectKeyFromObject(w), w); err != nil {
+			t.Fatal(err)
+		}
+		switch w := w.(type) {
+		case *appsv1.Deployment:
+			return w.Spec.Template.Annotations[framework.RestartedAtAnnotation]
+		case *appsv1.StatefulSet:
+			return w.Spec.Template.Annotations[framework.RestartedAtAnnotation]
+		}
+		return ""
+	}
+
+	// The first credentials are already what the pods read at startup.
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := restartedAt(consumer); got != "" {
+		t.Errorf("expected no restart for the first credentials, got %q", got)
+	}
+	if consumer.Annotations[framework.AnnotationSecretChecksums] == "" {
+		t.Error("expected the checksum of the secret to be recorded on the workload")
+	}
+
+	// A rotation restarts the workloads referencing the secret.
+	var got testObject
+	if err := c.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	got.Annotations = map[string]string{framework.AnnotationRotateNow: "true"}
+	if err := c.Update(ctx, &got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	for _, w := range []client.Object{consumer, stateful} {
+		if restartedAt(w) == "" {
+			t.Errorf("expected %s to be restarted after the rotation", w.GetName())
+		}
+	}
+	if got := restartedAt(unrelated); got != "" {
+		t.Errorf("expected the workload not referencing the secret to be left alone, got %q", got)
+	}
+
+	// The new checksum is recorded, so that they are not restarted again
+	// until the next rotation.
+	var secret corev1.Secret
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "app-secret"}, &secret); err != nil {
+		t.Fatal(err)
+	}
+	checksum := secret.Annotations[framework.AnnotationDataChecksum]
+	if got := consumer.Annotations[framework.AnnotationSecretChecksums]; !strings.Contains(got, checksum) {
+		t.Errorf("expected the checksum %s to be recorded, got %s", checksum, got)
+	}
+}
+
+func TestReconcile_RestartWorkloadsOfTargetsAndReplicas(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	obj.Targets = framework.OutputTargets{{SecretRef: framework.SecretReference{Name: "shared"}, Namespace: "other"}}
+	obj.Replication = &framework.Replication{
+		NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+	}
+	other := replicaNamespace("other", nil, true)
+	prod := replicaNamespace("prod", map[string]string{"team": "a"}, true)
+	targetConsumer := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "target-consumer"},
+		Spec:       appsv1.DeploymentSpec{Template: templateWithEnvFrom("shared")},
+	}
+	replicaConsumer := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "replica-consumer"},
+		Spec:       appsv1.StatefulSetSpec{Template: templateWithEnvFrom("app-secret")},
+	}
+	unrelated := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "unrelated"},
+		Spec:       appsv1.DeploymentSpec{Template: templateWithEnvFrom("shared")},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(obj, other, prod, targetConsumer, replicaConsumer, unrelated).
+		WithStatusSubresource(obj).WithInterceptorFuncs(mergeStringData).
+		WithIndex(&appsv1.Deployment{}, framework.IndexSecretNames, framework.WorkloadSecretNames).
+		WithIndex(&appsv1.StatefulSet{}, framework.IndexSecretNames, framework.WorkloadSecretNames).
+		Build()
+	r := &framework.Reconciler[*testObject]{
+		Client: c, Scheme: scheme, Provider: &countingProvider{}, RestartWorkloads: true,
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	restartedAt := func(w client.Object) string {
+		t.Helper()
+		if err := c.Get(ctx, client.ObjectKeyFromObject(w), w); err != nil {
+			t.Fatal(err)
+		}
+		switch w := w.(type) {
+		case *appsv1.Deployment:
+			return w.Spec.Template.Annotations[framework.RestartedAtAnnotation]
+		case *appsv1.StatefulSet:
+			return w.Spec.Template.Annotations[framework.RestartedAtAnnotation]
+		}
+		return ""
+	}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	var got testObject
+	if err := c.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	got.Annotations = map[string]string{framework.AnnotationRotateNow: "true"}
+	if err := c.Update(ctx, &got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	for _, w := range []client.Object{targetConsumer, replicaConsumer} {
+		if restartedAt(w) == "" {
+			t.Errorf("expected %s/%s to be restarted after the rotation", w.GetNamespace(), w.GetName())
+		}
+	}
+	if got := restartedAt(unrelated); got != "" {
+		t.Errorf("expected the workload referencing a secret not written there to be left alone, got %q", got)
+	}
+}
+
+func TestReconcile_RestartWorkloadsWithStaleCache(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	obj.Targets = framework.OutputTargets{{SecretRef: framework.SecretReference{Name: "shared"}, Namespace: "other"}}
+	other := replicaNamespace("other", nil, true)
+	consumer := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "consumer"},
+		Spec:       appsv1.DeploymentSpec{Template: templateWithEnvFrom("shared")},
+	}
+
+	// Once stale is set, listing secrets returns them as they were then, as
+	// a cache that has not seen the writes of the reconcile yet would.
+	var stale map[client.ObjectKey]corev1.Secret
+	funcs := mergeStringData
+	funcs.List = func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+		if err := c.List(ctx, list, opts...); err != nil {
+			return err
+		}
+		if secrets, ok := list.(*corev1.SecretList); ok && stale != nil {
+			for i, s := range secrets.Items {
+				if old, ok := stale[client.ObjectKeyFromObject(&s)]; ok {
+					secrets.Items[i] = old
+				}
+			}
+		}
+		return nil
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, other, consumer).
+		WithStatusSubresource(obj).WithInterceptorFuncs(funcs).
+		WithIndex(&appsv1.Deployment{}, framework.IndexSecretNames, framework.WorkloadSecretNames).
+		WithIndex(&appsv1.StatefulSet{}, framework.IndexSecretNames, framework.WorkloadSecretNames).
+		Build()
+	r := &framework.Reconciler[*testObject]{
+		Client: c, Scheme: scheme, Provider: &countingProvider{}, RestartWorkloads: true,
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	var secrets corev1.SecretList
+	if err := c.List(ctx, &secrets); err != nil {
+		t.Fatal(err)
+	}
+	stale = make(map[client.ObjectKey]corev1.Secret)
+	for _, s := range secrets.Items {
+		stale[client.ObjectKeyFromObject(&s)] = s
+	}
+
+	var got testObject
+	if err := c.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	got.Annotations = map[string]string{framework.AnnotationRotateNow: "true"}
+	if err := c.Update(ctx, &got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	// The target is neither owned nor watched, so the restart must not wait
+	// for another reconcile.
+	if err := c.Get(ctx, client.ObjectKeyFromObject(consumer), consumer); err != nil {
+		t.Fatal(err)
+	}
+	if consumer.Spec.Template.Annotations[framework.RestartedAtAnnotation] == "" {
+		t.Error("expected the consumer of the target to be restarted in the reconcile of the rotation")
+	}
+}
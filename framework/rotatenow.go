@@ -0,0 +1,30 @@
+package framework
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// rotationRequested reports whether obj is annotated with
+// [AnnotationRotateNow].
+func rotationRequested(obj Object) bool {
+	return obj.GetAnnotations()[AnnotationRotateNow] == "true"
+}
+
+// endRotationRequest removes [AnnotationRotateNow] from obj after its key was
+// rotated, so that the next reconciliation does not rotate it again.
+func (r *Reconciler[O]) endRotationRequest(ctx context.Context, obj O) error {
+	if err := r.removeAnnotation(ctx, obj, AnnotationRotateNow); err != nil {
+		return err
+	}
+
+	log.FromContext(ctx).Info("rotated key on demand", "keyId", obj.GetStatus().CurrentKeyID)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, corev1.EventTypeNormal, ReasonRotatedOnDemand, "Reconcile",
+			"Rotated to key %s as requested by %s", obj.GetStatus().CurrentKeyID, AnnotationRotateNow)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package framework_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcile_RotateNow(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	obj, secret := steadyObject(map[string]string{framework.AnnotationRotateNow: "true"})
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).WithStatusSubresource(obj).Build()
+	p := &countingProvider{}
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if p.provisions != 1 {
+		t.Fatalf("expected the valid key to be rotated, got %d provisions", p.provisions)
+	}
+	var got testObject
+	if err := c.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got.Annotations[framework.AnnotationRotateNow]; ok {
+		t.Error("expected the annotation to be removed after rotating")
+	}
+	if got.Status.CurrentKeyID != "key-1" {
+		t.Errorf("expected the new key to be current, got %q", got.Status.CurrentKeyID)
+	}
+
+	// The request applies to one rotation only.
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if p.provisions != 1 {
+		t.Errorf("got %d provisions after the second reconciliation", p.provisions)
+	}
+}
@@ -0,0 +1,73 @@
+package framework
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RotationPolicy configures how the keys of a resource are rotated.
+// Providers embed it in their spec as rotation, see [RotationPolicyHolder].
+type RotationPolicy struct {
+	// GracePeriod is how long a key stays valid after a newer one was
+	// written to the output Secret, so that consumers caching the Secret
+	// pick up the new key before the old one is deleted. Defaults to keeping
+	// superseded keys until they expire.
+	// +optional
+	GracePeriod *metav1.Duration `json:"gracePeriod,omitempty"`
+}
+
+// RotationPolicyHolder is optionally implemented by objects whose spec
+// carries a [RotationPolicy].
+type RotationPolicyHolder interface {
+	// GetRotationPolicy returns the rotation policy of the object.
+	GetRotationPolicy() RotationPolicy
+}
+
+// gracePeriod returns the [RotationPolicy.GracePeriod] of obj, or zero if it
+// has none or is self-managed, see [AnnotationSelfManaged].
+func gracePeriod(obj Object) time.Duration {
+	// The operator may still use the superseded key of a self-managed
+	// resource until it is restarted.
+	if selfManaged(obj) {
+		return 0
+	}
+	h, ok := obj.(RotationPolicyHolder)
+	if !ok {
+		return 0
+	}
+	if g := h.GetRotationPolicy().GracePeriod; g != nil {
+		return g.Duration
+	}
+	return 0
+}
+
+// validateRotationPolicy checks that the grace period of obj, if set, is
+// positive.
+func validateRotationPolicy(obj Object) error {
+	h, ok := obj.(RotationPolicyHolder)
+	if !ok {
+		return nil
+	}
+	if g := h.GetRotationPolicy().GracePeriod; g != nil && g.Duration <= 0 {
+		return fmt.Errorf("rotation.gracePeriod must be positive, got %s", g.Duration)
+	}
+	return nil
+}
+
+// nextRevocation returns when the first of the superseded keys is to be
+// deleted before it expires because its grace period passed, if any.
+func nextRevocation(keys ActiveKeys, grace time.Duration) (time.Time, bool) {
+	var next time.Time
+	for _, k := range keys {
+		at := k.RevokeAt(grace)
+		if k.SupersededAt == nil || !at.Before(k.ExpiresAt.Time) {
+			continue
+		}
+		if next.IsZero() || at.Before(next) {
+			next = at
+		}
+	}
+	return next, !next.IsZero()
+}
@@ -0,0 +1,114 @@
+package framework_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcile_GracePeriod(t *testing.T) {
+	tests := []struct {
+		name        string
+		grace       time.Duration
+		wantDeleted bool
+	}{
+		{name: "passed", grace: time.Hour, wantDeleted: true},
+		{name: "running", grace: 3 * time.Hour, wantDeleted: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := newTestScheme(t)
+			obj, secret := steadyObject(nil)
+			obj.Rotation.GracePeriod = &metav1.Duration{Duration: tt.grace}
+			superseded := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+			obj.Status.ActiveKeys = append(obj.Status.ActiveKeys, framework.ActiveKey{
+				KeyID:        "previous",
+				CreatedAt:    metav1.NewTime(time.Now().Add(-80 * 24 * time.Hour)),
+				ExpiresAt:    metav1.NewTime(time.Now().Add(10 * 24 * time.Hour)),
+				SupersededAt: &superseded,
+			})
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).WithStatusSubresource(obj).Build()
+			p := &deleteProvider{}
+			r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p}
+
+			res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+			if err != nil {
+				t.Fatalf("reconcile: %v", err)
+			}
+			if got := slices.Contains(p.deleted, "previous"); got != tt.wantDeleted {
+				t.Errorf("got deleted %v, want %v", got, tt.wantDeleted)
+			}
+			if slices.Contains(p.deleted, "current") {
+				t.Error("expected the current key to be kept")
+			}
+			if !tt.wantDeleted && (res.RequeueAfter <= 0 || res.RequeueAfter > time.Hour) {
+				t.Errorf("expected a requeue when the grace period passes, got %v", res.RequeueAfter)
+			}
+		})
+	}
+}
+
+func TestReconcile_RenewalSupersedesKeys(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj, _ := steadyObject(nil)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: &countingProvider{}}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	var got testObject
+	if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range got.Status.ActiveKeys {
+		if superseded := k.SupersededAt != nil; superseded != (k.KeyID == "current") {
+			t.Errorf("key %s: got superseded %v", k.KeyID, superseded)
+		}
+	}
+}
+
+func TestReconcile_InvalidGracePeriod(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj, secret := steadyObject(nil)
+	obj.Rotation.GracePeriod = &metav1.Duration{}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: &countingProvider{}}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	var got testObject
+	if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got.Status.LastFailureMessage, "gracePeriod must be positive") {
+		t.Errorf("expected an invalid config failure, got %q", got.Status.LastFailureMessage)
+	}
+}
+
+func TestActiveKey_RevokeAt(t *testing.T) {
+	now := time.Now()
+	superseded := metav1.NewTime(now)
+	key := framework.ActiveKey{ExpiresAt: metav1.NewTime(now.Add(2 * time.Hour)), SupersededAt: &superseded}
+
+	if got := key.RevokeAt(0); !got.Equal(key.ExpiresAt.Time) {
+		t.Errorf("without a grace period: got %v, want expiry", got)
+	}
+	if got := key.RevokeAt(time.Hour); !got.Equal(now.Add(time.Hour)) {
+		t.Errorf("with a grace period: got %v, want %v", got, now.Add(time.Hour))
+	}
+	if got := key.RevokeAt(3 * time.Hour); !got.Equal(key.ExpiresAt.Time) {
+		t.Errorf("with a grace period beyond expiry: got %v, want expiry", got)
+	}
+}
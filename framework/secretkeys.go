@@ -0,0 +1,57 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ValidateTemplateKeys returns an error if template has one of the reserved
+// keys. Providers that write keys of their own next to the rendered
+// templates call it from Validate, so that a template cannot overwrite them.
+func ValidateTemplateKeys(template map[string]string, reserved ...string) error {
+	for _, key := range slices.Sorted(maps.Keys(template)) {
+		if slices.Contains(reserved, key) {
+			return fmt.Errorf("template key %q is reserved", key)
+		}
+	}
+	return nil
+}
+
+// caseInsensitiveDuplicates returns the groups of keys of data that differ
+// only in case, sorted. Such keys are distinct Secret keys, but collide when
+// the Secret is projected into environment variables on platforms with
+// case-insensitive environments, e.g. Windows.
+func caseInsensitiveDuplicates(data map[string]string) [][]string {
+	groups := map[string][]string{}
+	for _, key := range slices.Sorted(maps.Keys(data)) {
+		folded := strings.ToLower(key)
+		groups[folded] = append(groups[folded], key)
+	}
+
+	var dups [][]string
+	for _, folded := range slices.Sorted(maps.Keys(groups)) {
+		if len(groups[folded]) > 1 {
+			dups = append(dups, groups[folded])
+		}
+	}
+	return dups
+}
+
+// warnDuplicateKeys logs and records a Warning event for each group of
+// output secret keys that differ only in case. The secret is still written.
+func (r *Reconciler[O]) warnDuplicateKeys(ctx context.Context, obj O, data map[string]string) {
+	for _, keys := range caseInsensitiveDuplicates(data) {
+		log.FromContext(ctx).Info("output secret keys differ only in case", "keys", keys)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(obj, nil, corev1.EventTypeWarning, "DuplicateSecretKeys", "Reconcile",
+				"Output secret keys %s differ only in case and collide as environment variables on some platforms",
+				strings.Join(keys, ", "))
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package framework_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestValidateTemplateKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		template map[string]string
+		wantErr  string
+	}{
+		{name: "no template"},
+		{name: "free keys", template: map[string]string{"config.json": "{}", "URL": "x"}},
+		{name: "reserved key", template: map[string]string{"URL": "x", "tls.crt": "x"}, wantErr: `"tls.crt" is reserved`},
+		{name: "reserved keys are case-sensitive", template: map[string]string{"TLS.CRT": "x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := framework.ValidateTemplateKeys(tt.template, "tls.crt", "tls.key")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReconcile_WarnsOnCaseInsensitiveDuplicateKeys(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	recorder := events.NewFakeRecorder(10)
+	r := &framework.Reconciler[*testObject]{
+		Client: c,
+		Scheme: scheme,
+		Provider: &dataProvider{data: map[string]string{
+			"db_url": "a", "DB_URL": "b", "Token": "c", "TOKEN": "d", "other": "e",
+		}},
+		Recorder: recorder,
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	var secret corev1.Secret
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "app-secret"}, &secret); err != nil {
+		t.Fatalf("expected the output secret to be written: %v", err)
+	}
+	if len(secret.StringData) != 5 {
+		t.Errorf("expected all 5 keys in the output secret, got %v", secret.StringData)
+	}
+
+	for _, want := range []string{"DB_URL, db_url", "TOKEN, Token"} {
+		select {
+		case event := <-recorder.Events:
+			if !strings.Contains(event, "Warning DuplicateSecretKeys") || !strings.Contains(event, want) {
+				t.Errorf("expected DuplicateSecretKeys event for %s, got %s", want, event)
+			}
+		default:
+			t.Fatalf("expected DuplicateSecretKeys event for %s", want)
+		}
+	}
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("unexpected event: %s", event)
+	default:
+	}
+}
@@ -0,0 +1,90 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultMaxSecretSize is the maximum total size of the values of a Secret
+// accepted by the API server, see [Reconciler.MaxSecretSize].
+const DefaultMaxSecretSize = 1 << 20
+
+// ErrSecretTooLarge is returned when the rendered data does not fit into the
+// output secret. The Ready condition then has reason [ReasonSecretTooLarge].
+var ErrSecretTooLarge = errors.New("output secret too large")
+
+// dataSize returns the size of data as counted against the Secret size limit,
+// which only covers the values.
+func dataSize(data map[string]string) int {
+	size := 0
+	for _, v := range data {
+		size += len(v)
+	}
+	return size
+}
+
+// splitData distributes data across as few parts of at most limit bytes as
+// filling them in key order allows. It fails if a single value exceeds limit.
+func splitData(data map[string]string, limit int) ([]map[string]string, error) {
+	parts := []map[string]string{{}}
+	size := 0
+	for _, key := range slices.Sorted(maps.Keys(data)) {
+		n := len(data[key])
+		if n > limit {
+			return nil, fmt.Errorf("%w: key %q has %d bytes, the limit is %d", ErrSecretTooLarge, key, n, limit)
+		}
+		if size+n > limit {
+			parts = append(parts, map[string]string{})
+			size = 0
+		}
+		parts[len(parts)-1][key] = data[key]
+		size += n
+	}
+	return parts, nil
+}
+
+// splitSecretName returns the name of the i-th part of the output secret
+// name; the first part is the output secret itself.
+func splitSecretName(name string, i int) string {
+	if i == 0 {
+		return name
+	}
+	return name + "-" + strconv.Itoa(i)
+}
+
+// deleteStaleSplits deletes the parts of the output secret name from the
+// given index on, left over from when the data needed more parts. It stops at
+// the first part that does not exist or is not controlled by obj.
+func (r *Reconciler[O]) deleteStaleSplits(ctx context.Context, obj O, name string, from int) error {
+	for i := from; ; i++ {
+		var secret corev1.Secret
+		key := client.ObjectKey{Namespace: obj.GetNamespace(), Name: splitSecretName(name, i)}
+		if err := r.Get(ctx, key, &secret); apierrors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !metav1.IsControlledBy(&secret, obj) {
+			return nil
+		}
+		if err := r.Delete(ctx, &secret); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("deleting stale secret %s: %w", secret.Name, err)
+		}
+	}
+}
+
+func (r *Reconciler[O]) maxSecretSize() int {
+	if r.MaxSecretSize <= 0 {
+		return DefaultMaxSecretSize
+	}
+	return r.MaxSecretSize
+}
@@ -0,0 +1,157 @@
+package framework_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// dataProvider is a [framework.Provider] that renders the given data.
+type dataProvider struct {
+	stubProvider
+
+	data       map[string]string
+	secretType corev1.SecretType
+}
+
+func (p *dataProvider) Provision(context.Context, *testObject) (*framework.Result, error) {
+	now := time.Now()
+	return &framework.Result{
+		KeyID:         "key",
+		StringData:    p.data,
+		ProvisionedAt: now,
+		ValidUntil:    now.Add(time.Hour),
+		SecretType:    p.secretType,
+	}, nil
+}
+
+func TestReconcile_SecretTooLarge(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{
+		Client:        c,
+		Scheme:        scheme,
+		Provider:      &dataProvider{data: map[string]string{"A": "1234", "B": "5678"}},
+		MaxSecretSize: 6,
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(context.Background(), req); !errors.Is(err, framework.ErrSecretTooLarge) {
+		t.Fatalf("expected ErrSecretTooLarge, got %v", err)
+	}
+
+	var got testObject
+	if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, framework.ConditionReady)
+	if cond == nil || cond.Reason != framework.ReasonSecretTooLarge {
+		t.Errorf("expected Ready condition with reason %s, got %+v", framework.ReasonSecretTooLarge, cond)
+	}
+	if len(got.Status.ActiveKeys) != 1 {
+		t.Errorf("expected the provisioned key to be tracked for cleanup, got %v", got.Status.ActiveKeys)
+	}
+	secretKey := client.ObjectKey{Namespace: "default", Name: "app-secret"}
+	if err := c.Get(context.Background(), secretKey, &corev1.Secret{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected no output secret, got %v", err)
+	}
+}
+
+func TestReconcile_SplitsOversizedSecrets(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	p := &dataProvider{data: map[string]string{"A": "1234", "B": "5678", "C": "9012"}}
+	r := &framework.Reconciler[*testObject]{
+		Client:                c,
+		Scheme:                scheme,
+		Provider:              p,
+		MaxSecretSize:         8,
+		SplitOversizedSecrets: true,
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+	getSecret := func(name string) (*corev1.Secret, error) {
+		var secret corev1.Secret
+		err := c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: name}, &secret)
+		return &secret, err
+	}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	primary, err := getSecret("app-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := primary.Annotations[framework.AnnotationSplitSecrets]; got != "app-secret-1" {
+		t.Errorf("expected the output secret to list app-secret-1, got %q", got)
+	}
+	if len(primary.StringData) != 2 || primary.StringData["A"] != "1234" || primary.StringData["B"] != "5678" {
+		t.Errorf("expected A and B in the output secret, got %v", primary.StringData)
+	}
+	overflow, err := getSecret("app-secret-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(overflow.StringData) != 1 || overflow.StringData["C"] != "9012" {
+		t.Errorf("expected C in app-secret-1, got %v", overflow.StringData)
+	}
+	if !metav1.IsControlledBy(overflow, obj) {
+		t.Error("expected app-secret-1 to be controlled by the object")
+	}
+
+	// Once the data fits again, the additional secret is deleted.
+	p.data = map[string]string{"A": "1234"}
+	var current testObject
+	if err := c.Get(context.Background(), req.NamespacedName, &current); err != nil {
+		t.Fatal(err)
+	}
+	current.Generation++
+	if err := c.Update(context.Background(), &current); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if primary, err = getSecret("app-secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := primary.Annotations[framework.AnnotationSplitSecrets]; ok {
+		t.Errorf("expected the split annotation to be removed, got %v", primary.Annotations)
+	}
+	if _, err := getSecret("app-secret-1"); !apierrors.IsNotFound(err) {
+		t.Errorf("expected app-secret-1 to be deleted, got %v", err)
+	}
+}
+
+func TestReconcile_SplitRejectsOversizedValue(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{
+		Client:                c,
+		Scheme:                scheme,
+		Provider:              &dataProvider{data: map[string]string{"BUNDLE": "123456789"}},
+		MaxSecretSize:         8,
+		SplitOversizedSecrets: true,
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(context.Background(), req); !errors.Is(err, framework.ErrSecretTooLarge) {
+		t.Fatalf("expected ErrSecretTooLarge for a value that cannot be split, got %v", err)
+	}
+}
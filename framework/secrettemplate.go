@@ -0,0 +1,97 @@
+package framework
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// SecretTemplate configures the metadata and type of the output Secret. It is
+// applied whenever the Secret is written, so changes take effect with the next
+// rotation. Providers embed it in their spec as secretTemplate, see
+// [SecretTemplateHolder].
+type SecretTemplate struct {
+	// Labels are set on the output Secret, e.g. for tools selecting Secrets
+	// by label. Labels removed from the template are left on the Secret.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are set on the output Secret, e.g. to have Reloader
+	// restart the workloads using it. Annotations removed from the template
+	// are left on the Secret.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Type is the type of the output Secret. The template must produce the
+	// keys the type requires, e.g. .dockerconfigjson for
+	// kubernetes.io/dockerconfigjson. Changing it recreates the Secret, as
+	// the type of a Secret cannot be updated. Defaults to the provider's
+	// type, usually Opaque.
+	// +kubebuilder:validation:Enum=Opaque;kubernetes.io/dockerconfigjson;kubernetes.io/tls
+	// +optional
+	Type corev1.SecretType `json:"type,omitempty"`
+}
+
+// SecretTemplateHolder is optionally implemented by objects whose spec
+// carries a [SecretTemplate].
+type SecretTemplateHolder interface {
+	// GetSecretTemplate returns the secret template of the object.
+	GetSecretTemplate() SecretTemplate
+}
+
+// DeepCopy returns a deep copy of the template.
+func (t SecretTemplate) DeepCopy() SecretTemplate {
+	t.Labels = maps.Clone(t.Labels)
+	t.Annotations = maps.Clone(t.Annotations)
+	return t
+}
+
+// secretTemplate returns the [SecretTemplate] of obj, or an empty one.
+func secretTemplate(obj Object) SecretTemplate {
+	if h, ok := obj.(SecretTemplateHolder); ok {
+		return h.GetSecretTemplate()
+	}
+	return SecretTemplate{}
+}
+
+// validateSecretTemplate checks that the secret template of obj does not set
+// labels or annotations of valet, which the reconciler manages itself.
+func validateSecretTemplate(obj Object) error {
+	t := secretTemplate(obj)
+	for _, m := range []map[string]string{t.Labels, t.Annotations} {
+		for key := range m {
+			if strings.HasPrefix(key, "valet.ngl.cx/") {
+				return fmt.Errorf("secretTemplate may not set %s, the valet.ngl.cx/ prefix is reserved", key)
+			}
+		}
+	}
+	return nil
+}
+
+// applySecretTemplate sets the labels and annotations of the secret template
+// of obj on secret, and its type if secret is about to be created.
+func applySecretTemplate(obj Object, secret *corev1.Secret) {
+	t := secretTemplate(obj)
+	for key, value := range t.Labels {
+		if secret.Labels == nil {
+			secret.Labels = map[string]string{}
+		}
+		secret.Labels[key] = value
+	}
+	for key, value := range t.Annotations {
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[key] = value
+	}
+	setSecretType(secret, t.Type)
+}
+
+// secretTypeChanged reports whether the secret template of obj asks for
+// another type than the one existing has.
+func secretTypeChanged(obj Object, existing *corev1.Secret) bool {
+	typ := secretTemplate(obj).Type
+	return typ != "" && typ != existing.Type
+}
@@ -0,0 +1,96 @@
+package framework_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcile_SecretTemplate(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	obj.SecretTemplate = framework.SecretTemplate{
+		Labels:      map[string]string{"team": "payments"},
+		Annotations: map[string]string{"reloader.stakater.com/match": "true"},
+		Type:        corev1.SecretTypeTLS,
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: &countingProvider{}}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	var got corev1.Secret
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "app-secret"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != corev1.SecretTypeTLS {
+		t.Errorf("expected type %s, got %q", corev1.SecretTypeTLS, got.Type)
+	}
+	if got.Labels["team"] != "payments" || got.Annotations["reloader.stakater.com/match"] != "true" {
+		t.Errorf("expected the template metadata, got labels %v and annotations %v", got.Labels, got.Annotations)
+	}
+	if got.Annotations[framework.AnnotationDataChecksum] == "" {
+		t.Error("expected the annotations of valet to be kept")
+	}
+}
+
+func TestReconcile_SecretTemplateTypeChange(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	obj.SecretTemplate.Type = corev1.SecretTypeDockerConfigJson
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-secret", Namespace: "default"},
+		Type:       corev1.SecretTypeOpaque,
+		Data:       map[string][]byte{"OTHER": []byte("kept")},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: &countingProvider{}}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	var got corev1.Secret
+	if err := c.Get(ctx, client.ObjectKeyFromObject(secret), &got); err != nil {
+		t.Fatalf("expected recreated secret: %v", err)
+	}
+	if got.Type != corev1.SecretTypeDockerConfigJson {
+		t.Errorf("expected the secret to be recreated as %s, got %q", corev1.SecretTypeDockerConfigJson, got.Type)
+	}
+	if string(got.Data["OTHER"]) != "kept" {
+		t.Errorf("expected other keys to be carried over, got %v", got.Data)
+	}
+}
+
+func TestReconcile_SecretTemplateReservedKey(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	obj, secret := steadyObject(nil)
+	obj.SecretTemplate.Annotations = map[string]string{framework.AnnotationDataChecksum: "forged"}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: &countingProvider{}}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	var got testObject
+	if err := c.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != framework.PhaseFailed {
+		t.Errorf("expected a reserved annotation to fail the resource, got %s", got.Status.Phase)
+	}
+}
@@ -0,0 +1,83 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ErrCredentialUnverified is returned when the credentials provisioned for a
+// resource annotated with [AnnotationSelfManaged] fail verification, see
+// [CredentialVerifier]. They are not written to the output secret, which
+// keeps the credentials the operator uses, and verification is retried.
+var ErrCredentialUnverified = errors.New("new credentials failed verification")
+
+// CredentialVerifier is optionally implemented by providers that can check
+// that freshly provisioned credentials work before they are written to the
+// output secret, e.g. by authenticating with them. Resources annotated with
+// [AnnotationSelfManaged] require it, as the operator would lock itself out
+// by switching to credentials that do not work.
+type CredentialVerifier[O Object] interface {
+	// VerifyCredential returns an error unless the credentials of result,
+	// just provisioned for obj, work. Credentials that are still
+	// propagating at the provider may fail and are verified again later.
+	VerifyCredential(ctx context.Context, obj O, result *Result) error
+}
+
+// selfManaged reports whether obj manages the credentials of the operator
+// itself, see [AnnotationSelfManaged].
+func selfManaged(obj Object) bool {
+	return obj.GetAnnotations()[AnnotationSelfManaged] == "true"
+}
+
+// credentialVerifier returns the provider as a [CredentialVerifier], looking
+// through wrappers such as [InstrumentedProvider], or nil if it cannot verify
+// credentials.
+func (r *Reconciler[O]) credentialVerifier() CredentialVerifier[O] {
+	v, _ := findProvider[O, CredentialVerifier[O]](r.Provider)
+	return v
+}
+
+// validateSelfManaged checks that the provider can verify the credentials of
+// obj if it is self-managed.
+func (r *Reconciler[O]) validateSelfManaged(obj O) error {
+	if selfManaged(obj) && r.credentialVerifier() == nil {
+		return fmt.Errorf("annotation %s requires a provider that verifies new credentials", AnnotationSelfManaged)
+	}
+	return nil
+}
+
+// verifySelfManaged verifies the credentials of result before they replace
+// those of a self-managed obj, see [AnnotationSelfManaged]. Other resources
+// are not verified.
+func (r *Reconciler[O]) verifySelfManaged(ctx context.Context, obj O, result *Result) error {
+	if !selfManaged(obj) {
+		return nil
+	}
+	verifier := r.credentialVerifier()
+	if verifier == nil {
+		return fmt.Errorf("annotation %s requires a provider that verifies new credentials", AnnotationSelfManaged)
+	}
+	if err := verifier.VerifyCredential(ctx, obj, result); err != nil {
+		return fmt.Errorf("%w: key %s: %w", ErrCredentialUnverified, result.KeyID, err)
+	}
+
+	log.FromContext(ctx).Info("verified new credentials of the operator", "keyId", result.KeyID)
+	if r.Recorder == nil {
+		return nil
+	}
+	if current := obj.GetStatus().CurrentKeyID; current != "" {
+		r.Recorder.Eventf(obj, nil, corev1.EventTypeNormal, ReasonCredentialVerified, "Reconcile",
+			"Verified key %s before writing it to secret %s; restart the operator to use it before key %s expires",
+			result.KeyID, obj.GetSecretRef().Name, current)
+	} else {
+		r.Recorder.Eventf(obj, nil, corev1.EventTypeNormal, ReasonCredentialVerified, "Reconcile",
+			"Verified key %s before writing it to secret %s; restart the operator to use it, "+
+				"then revoke the bootstrap credentials by hand",
+			result.KeyID, obj.GetSecretRef().Name)
+	}
+	return nil
+}
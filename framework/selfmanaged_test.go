@@ -0,0 +1,108 @@
+package framework_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// verifyingProvider is a [countingProvider] that verifies credentials with
+// verify.
+type verifyingProvider struct {
+	countingProvider
+
+	verify func(*framework.Result) error
+}
+
+func (p *verifyingProvider) VerifyCredential(_ context.Context, _ *testObject, result *framework.Result) error {
+	return p.verify(result)
+}
+
+func newSelfManagedObject() *testObject {
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	obj.Annotations = map[string]string{framework.AnnotationSelfManaged: "true"}
+	return obj
+}
+
+func TestReconcile_SelfManagedVerifiesBeforeWriting(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	obj := newSelfManagedObject()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	verifyErr := errors.New("invalid client secret")
+	p := &verifyingProvider{verify: func(*framework.Result) error { return verifyErr }}
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(ctx, req); !errors.Is(err, framework.ErrCredentialUnverified) {
+		t.Fatalf("expected the verification to fail, got %v", err)
+	}
+	var secret corev1.Secret
+	err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "app-secret"}, &secret)
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected unverified credentials not to be written, got %v", err)
+	}
+
+	// The same credentials are verified again instead of provisioning more.
+	verifyErr = nil
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if p.provisions != 1 {
+		t.Errorf("expected the retained credentials to be written, got %d provisions", p.provisions)
+	}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "app-secret"}, &secret); err != nil {
+		t.Fatal(err)
+	}
+	if secret.StringData["KEY"] != "key-1" {
+		t.Errorf("expected the verified credentials, got %v", secret.StringData)
+	}
+}
+
+func TestReconcile_SelfManagedRequiresVerifier(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newSelfManagedObject()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	p := &countingProvider{}
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if p.provisions != 0 {
+		t.Errorf("expected nothing to be provisioned without verification, got %d provisions", p.provisions)
+	}
+	var got testObject
+	if err := c.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != framework.PhaseFailed {
+		t.Errorf("expected the resource to fail validation, got %s", got.Status.Phase)
+	}
+}
+
+func TestReconcile_SelfManagedRetainsKeysOnDeletion(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newDeletingObject(2)
+	obj.Annotations = map[string]string{framework.AnnotationSelfManaged: "true"}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).Build()
+	p := &deleteProvider{}
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if len(p.deleted) != 0 {
+		t.Errorf("expected the keys of the operator to be retained, got %v deleted", p.deleted)
+	}
+}
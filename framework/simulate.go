@@ -0,0 +1,55 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// simulatesExpiry reports whether the newest key of obj is to be treated as
+// near expiry because obj is annotated with [AnnotationSimulateExpiry] and
+// [Reconciler.AllowSimulateExpiry] is set.
+func (r *Reconciler[O]) simulatesExpiry(ctx context.Context, obj O) bool {
+	if obj.GetAnnotations()[AnnotationSimulateExpiry] != "true" {
+		return false
+	}
+	if !r.AllowSimulateExpiry {
+		log.FromContext(ctx).Info("ignoring annotation, simulating expiry is not allowed",
+			"annotation", AnnotationSimulateExpiry)
+		return false
+	}
+	return true
+}
+
+// endSimulatedExpiry removes [AnnotationSimulateExpiry] from obj after its
+// key was rotated, so that the next reconciliation does not rotate it again.
+func (r *Reconciler[O]) endSimulatedExpiry(ctx context.Context, obj O) error {
+	if err := r.removeAnnotation(ctx, obj, AnnotationSimulateExpiry); err != nil {
+		return err
+	}
+
+	log.FromContext(ctx).Info("rotated key after simulated expiry", "keyId", obj.GetStatus().CurrentKeyID)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, corev1.EventTypeNormal, ReasonSimulatedExpiry, "Reconcile",
+			"Rotated to key %s after simulated expiry", obj.GetStatus().CurrentKeyID)
+	}
+
+	return nil
+}
+
+// removeAnnotation removes the named annotation from obj. The patch is
+// applied to a copy, so that the in-memory status of obj, which may hold keys
+// omitted from the API object (see [KeyStore]), is kept.
+func (r *Reconciler[O]) removeAnnotation(ctx context.Context, obj O, name string) error {
+	patched := obj.DeepCopyObject().(O)
+	annotations := patched.GetAnnotations()
+	delete(annotations, name)
+	patched.SetAnnotations(annotations)
+	if err := r.Patch(ctx, patched, client.MergeFrom(obj)); err != nil {
+		return fmt.Errorf("removing annotation %s: %w", name, err)
+	}
+	return nil
+}
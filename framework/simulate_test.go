@@ -0,0 +1,64 @@
+package framework_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcile_SimulateExpiry(t *testing.T) {
+	tests := []struct {
+		name          string
+		allow         bool
+		wantProvision bool
+	}{
+		{name: "allowed", allow: true, wantProvision: true},
+		{name: "not allowed", allow: false, wantProvision: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := newTestScheme(t)
+			obj, secret := steadyObject(map[string]string{framework.AnnotationSimulateExpiry: "true"})
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, secret).WithStatusSubresource(obj).Build()
+			p := &countingProvider{}
+			r := &framework.Reconciler[*testObject]{
+				Client:              c,
+				Scheme:              scheme,
+				Provider:            p,
+				AllowSimulateExpiry: tt.allow,
+			}
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+			if _, err := r.Reconcile(ctx, req); err != nil {
+				t.Fatalf("reconcile: %v", err)
+			}
+			if got := p.provisions == 1; got != tt.wantProvision {
+				t.Fatalf("got %d provisions, want provisioning %v", p.provisions, tt.wantProvision)
+			}
+			var got testObject
+			if err := c.Get(ctx, req.NamespacedName, &got); err != nil {
+				t.Fatal(err)
+			}
+			_, annotated := got.Annotations[framework.AnnotationSimulateExpiry]
+			if annotated == tt.wantProvision {
+				t.Errorf("got annotation present %v after reconciling", annotated)
+			}
+			if tt.wantProvision && got.Status.CurrentKeyID != "key-1" {
+				t.Errorf("expected the new key to be current, got %q", got.Status.CurrentKeyID)
+			}
+
+			// The simulation applies to one rotation only.
+			if _, err := r.Reconcile(ctx, req); err != nil {
+				t.Fatalf("reconcile: %v", err)
+			}
+			if got := p.provisions == 1; got != tt.wantProvision {
+				t.Errorf("got %d provisions after the second reconciliation", p.provisions)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package framework
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// ReasonSlowReconcile is the reason of the Warning event recorded on a
+// resource whose reconciliation took longer than
+// [Reconciler.SlowReconcileThreshold].
+const ReasonSlowReconcile = "SlowReconcile"
+
+// SlowReconciles is the number of reconciliations per kind that took longer
+// than [Reconciler.SlowReconcileThreshold]. It is registered on the
+// controller-runtime metrics registry.
+var SlowReconciles = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "valet_slow_reconciles_total",
+	Help: "Number of reconciliations that took longer than the slow reconcile threshold.",
+}, []string{"kind"})
+
+func init() {
+	metrics.Registry.MustRegister(SlowReconciles)
+}
+
+// reportSlowReconcile counts and reports the reconciliation of obj if it
+// took longer than [Reconciler.SlowReconcileThreshold], which usually means
+// that the provider hangs. A single slow reconciliation stalls only its own
+// resource, while a starved queue delays all of them, which shows in
+// controller-runtime's workqueue_queue_duration_seconds instead.
+func (r *Reconciler[O]) reportSlowReconcile(ctx context.Context, obj O, elapsed time.Duration) {
+	if r.SlowReconcileThreshold <= 0 || elapsed <= r.SlowReconcileThreshold {
+		return
+	}
+
+	SlowReconciles.WithLabelValues(reflect.TypeOf(obj).Elem().Name()).Inc()
+	elapsed = elapsed.Round(time.Millisecond)
+	log.FromContext(ctx).Info("reconcile exceeded the slow reconcile threshold",
+		"duration", elapsed, "threshold", r.SlowReconcileThreshold)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, corev1.EventTypeWarning, ReasonSlowReconcile, "Reconcile",
+			"Reconcile took %s, longer than %s; check that the provider responds", elapsed, r.SlowReconcileThreshold)
+	}
+}
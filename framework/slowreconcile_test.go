@@ -0,0 +1,79 @@
+package framework_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// slowProvider is a [stubProvider] whose Provision takes delay.
+type slowProvider struct {
+	stubProvider
+
+	delay time.Duration
+}
+
+func (p *slowProvider) Provision(ctx context.Context, obj *testObject) (*framework.Result, error) {
+	time.Sleep(p.delay)
+	return p.stubProvider.Provision(ctx, obj)
+}
+
+func TestReconcile_SlowReconcile(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		threshold time.Duration
+		wantEvent bool
+	}{
+		{name: "slow", threshold: 10 * time.Millisecond, wantEvent: true},
+		{name: "within threshold", threshold: time.Hour},
+		{name: "disabled"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := newTestScheme(t)
+			obj := newTestObject()
+			obj.Finalizers = []string{framework.Finalizer}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+			recorder := events.NewFakeRecorder(10)
+			r := &framework.Reconciler[*testObject]{
+				Client:                 c,
+				Scheme:                 scheme,
+				Provider:               &slowProvider{delay: 20 * time.Millisecond},
+				SlowReconcileThreshold: tt.threshold,
+				Recorder:               recorder,
+			}
+			counter := framework.SlowReconciles.WithLabelValues("testObject")
+			before := testutil.ToFloat64(counter)
+
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+			if _, err := r.Reconcile(context.Background(), req); err != nil {
+				t.Fatalf("reconcile: %v", err)
+			}
+
+			want := 0.0
+			if tt.wantEvent {
+				want = 1
+			}
+			if got := testutil.ToFloat64(counter) - before; got != want {
+				t.Errorf("expected %v slow reconciles to be counted, got %v", want, got)
+			}
+			select {
+			case event := <-recorder.Events:
+				if !tt.wantEvent || !strings.Contains(event, "Warning "+framework.ReasonSlowReconcile) {
+					t.Errorf("unexpected event: %s", event)
+				}
+			default:
+				if tt.wantEvent {
+					t.Error("expected a SlowReconcile event")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package framework
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Suspender is optionally implemented by objects whose spec can suspend their
+// rotation, e.g. with a suspend field. [AnnotationPaused] suspends any
+// object.
+type Suspender interface {
+	// IsSuspended reports whether the rotation is suspended.
+	IsSuspended() bool
+}
+
+// suspension returns why the rotation of obj is suspended, or "" if it is not.
+func suspension(obj Object) string {
+	if s, ok := obj.(Suspender); ok && s.IsSuspended() {
+		return "spec.suspend is set"
+	}
+	if obj.GetAnnotations()[AnnotationPaused] == "true" {
+		return "the resource is annotated with " + AnnotationPaused
+	}
+	return ""
+}
+
+// handleSuspended marks obj Degraded because its rotation is suspended for the
+// reason why, and skips provisioning and cleanup. Deletion is not suspended.
+// No requeue is scheduled: resuming changes obj, which triggers the next
+// reconciliation.
+func (r *Reconciler[O]) handleSuspended(ctx context.Context, obj O, why string) (ctrl.Result, error) {
+	if !obj.GetStatus().SetSuspended(obj.GetGeneration(), why) {
+		return ctrl.Result{}, nil
+	}
+
+	log.FromContext(ctx).Info("rotation is suspended, skipping renewal and cleanup", "reason", why)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, corev1.EventTypeWarning, ReasonSuspended, "Reconcile",
+			"Not rotating credentials because %s", why)
+	}
+	if err := r.updateStatus(ctx, obj); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,91 @@
+package framework_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcile_Suspended(t *testing.T) {
+	tests := []struct {
+		name    string
+		suspend func(*testObject)
+		resume  func(*testObject)
+	}{
+		{
+			name:    "spec",
+			suspend: func(o *testObject) { o.Suspend = true },
+			resume:  func(o *testObject) { o.Suspend = false },
+		},
+		{
+			name:    "annotation",
+			suspend: func(o *testObject) { o.Annotations = map[string]string{framework.AnnotationPaused: "true"} },
+			resume:  func(o *testObject) { o.Annotations = nil },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := newTestScheme(t)
+			// Without output secret and with an expired key, the resource
+			// needs both renewal and cleanup.
+			obj, _ := steadyObject(nil)
+			obj.Status.ActiveKeys = append(obj.Status.ActiveKeys, framework.ActiveKey{
+				KeyID:     "expired",
+				CreatedAt: metav1.NewTime(time.Now().Add(-100 * 24 * time.Hour)),
+				ExpiresAt: metav1.NewTime(time.Now().Add(-time.Hour)),
+			})
+			tt.suspend(obj)
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+			p := &deleteProvider{}
+			cp := &countingProvider{}
+			r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p}
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+			get := func() *testObject {
+				t.Helper()
+				var got testObject
+				if err := c.Get(ctx, req.NamespacedName, &got); err != nil {
+					t.Fatal(err)
+				}
+				return &got
+			}
+
+			if _, err := r.Reconcile(ctx, req); err != nil {
+				t.Fatalf("reconcile: %v", err)
+			}
+			if len(p.deleted) != 0 {
+				t.Errorf("expected no cleanup while suspended, got %v", p.deleted)
+			}
+			got := get()
+			if got.Status.CurrentKeyID != "current" {
+				t.Errorf("expected no rotation while suspended, got current key %q", got.Status.CurrentKeyID)
+			}
+			cond := meta.FindStatusCondition(got.Status.Conditions, framework.ConditionDegraded)
+			if cond == nil || cond.Reason != framework.ReasonSuspended {
+				t.Errorf("expected Degraded with reason Suspended, got %+v", cond)
+			}
+
+			tt.resume(got)
+			if err := c.Update(ctx, got); err != nil {
+				t.Fatal(err)
+			}
+			r.Provider = cp
+			if _, err := r.Reconcile(ctx, req); err != nil {
+				t.Fatalf("reconcile: %v", err)
+			}
+			if cp.provisions != 1 {
+				t.Errorf("expected a rotation once resumed, got %d provisions", cp.provisions)
+			}
+			if cond := meta.FindStatusCondition(get().Status.Conditions, framework.ConditionDegraded); cond != nil {
+				t.Errorf("expected Degraded to be cleared once resumed, got %+v", cond)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/lukasngl/valet/framework/envelope"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ErrTargetNotAllowed is returned when a target is in a namespace that does
+// not allow targets from the namespace of the resource, see
+// [AnnotationAllowTargetsFrom].
+var ErrTargetNotAllowed = errors.New("target not allowed")
+
+// OutputTarget is a further Secret the credentials of a resource are written
+// to, e.g. for a consumer expecting only some of the keys of the output
+// Secret, or living in another namespace. Providers embed them in their spec
+// as targets, see [TargetHolder].
+type OutputTarget struct {
+	// SecretRef names the Secret.
+	SecretRef SecretReference `json:"secretRef"`
+
+	// Namespace of the Secret. Defaults to the namespace of the resource.
+	// Other namespaces must allow targets from it with the
+	// valet.ngl.cx/allow-targets-from annotation.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Keys selects the keys of the output Secret written to the target.
+	// Defaults to all keys, or none if Template is set.
+	// +optional
+	Keys []string `json:"keys,omitempty"`
+
+	// Template renders further keys of the target as Go templates of the
+	// keys of the output Secret, e.g. "{{ .AZURE_CLIENT_SECRET }}", so that
+	// each target can have its own shape. Keys with other characters than
+	// letters, digits and underscores are read with index, e.g.
+	// `{{ index . "tls.crt" }}`.
+	// +optional
+	Template map[string]string `json:"template,omitempty"`
+}
+
+// OutputTargets are the targets of a resource.
+type OutputTargets []OutputTarget
+
+// DeepCopy returns a deep copy of the targets.
+func (targets OutputTargets) DeepCopy() OutputTargets {
+	if targets == nil {
+		return nil
+	}
+	cp := make(OutputTargets, len(targets))
+	copy(cp, targets)
+	for i := range cp {
+		cp[i].Keys = slices.Clone(cp[i].Keys)
+		cp[i].Template = maps.Clone(cp[i].Template)
+	}
+	return cp
+}
+
+// TargetHolder is optionally implemented by objects whose spec carries
+// [OutputTargets].
+type TargetHolder interface {
+	// GetTargets returns the targets of the object.
+	GetTargets() OutputTargets
+}
+
+// targets returns the [OutputTargets] of obj, or none.
+func targets(obj Object) OutputTargets {
+	if h, ok := obj.(TargetHolder); ok {
+		return h.GetTargets()
+	}
+	return nil
+}
+
+// targetKey returns the key of the Secret t writes for obj.
+func targetKey(obj Object, t OutputTarget) client.ObjectKey {
+	namespace := t.Namespace
+	if namespace == "" {
+		namespace = obj.GetNamespace()
+	}
+	return client.ObjectKey{Namespace: namespace, Name: t.SecretRef.Name}
+}
+
+// validateTargets checks that the targets of obj name distinct Secrets other
+// than its output Secret, and that their templates parse.
+func validateTargets(obj Object) error {
+	seen := map[client.ObjectKey]bool{
+		{Namespace: obj.GetNamespace(), Name: obj.GetSecretRef().Name}: true,
+	}
+	for i, t := range targets(obj) {
+		if t.SecretRef.Name == "" {
+			return fmt.Errorf("targets[%d].secretRef.name must be set", i)
+		}
+		key := targetKey(obj, t)
+		if seen[key] {
+			return fmt.Errorf("targets[%d] writes to %s, which is already written", i, key)
+		}
+		seen[key] = true
+		for k, tmpl := range t.Template {
+			if _, err := parseTargetTemplate(tmpl); err != nil {
+				return fmt.Errorf("targets[%d].template.%s: %w", i, k, err)
+			}
+		}
+	}
+	return nil
+}
+
+// parseTargetTemplate parses the template of a key of a target. Keys of the
+// output Secret it refers to must exist.
+func parseTargetTemplate(tmpl string) (*template.Template, error) {
+	return template.New("").Option("missingkey=error").Parse(tmpl)
+}
+
+// targetData returns the data of t rendered from data, the plain data of the
+// output secret: the keys t selects, or all of them if it selects none and
+// has no template, plus the rendered template.
+func targetData(data map[string]string, t OutputTarget) (map[string]string, error) {
+	if len(t.Keys) == 0 && len(t.Template) == 0 {
+		return data, nil
+	}
+
+	out := make(map[string]string, len(t.Keys)+len(t.Template))
+	for _, key := range t.Keys {
+		value, ok := data[key]
+		if !ok {
+			return nil, fmt.Errorf("key %s is not in the output secret", key)
+		}
+		out[key] = value
+	}
+	for key, tmpl := range t.Template {
+		parsed, err := parseTargetTemplate(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("parsing template %s: %w", key, err)
+		}
+		var buf strings.Builder
+		if err := parsed.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("rendering template %s: %w", key, err)
+		}
+		out[key] = buf.String()
+	}
+	return out, nil
+}
+
+// reconcileTargets writes data, the plain data of the output secret, to the
+// targets of obj, adding their checksums to written, and deletes the targets
+// obj no longer has. With [Reconciler.Envelope], each target is encrypted on
+// its own.
+func (r *Reconciler[O]) reconcileTargets(
+	ctx context.Context,
+	obj O,
+	data map[string]string,
+	typ corev1.SecretType,
+	written secretChecksums,
+) error {
+	want := make(map[client.ObjectKey]bool)
+	for _, t := range targets(obj) {
+		key := targetKey(obj, t)
+		want[key] = true
+
+		selected, err := targetData(data, t)
+		if err != nil {
+			return fmt.Errorf("target %s: %w", key, err)
+		}
+		if r.Envelope != nil {
+			if selected, err = envelope.Seal(ctx, r.Envelope, selected); err != nil {
+				return fmt.Errorf("target %s: encrypting secret data: %w", key, err)
+			}
+		}
+		if size, limit := dataSize(selected), r.maxSecretSize(); size > limit {
+			return fmt.Errorf("target %s: %w: data has %d bytes, the limit is %d", key, ErrSecretTooLarge, size, limit)
+		}
+		if err := r.writeTarget(ctx, obj, written, key, selected, typ); err != nil {
+			return fmt.Errorf("target %s: %w", key, err)
+		}
+	}
+
+	return r.deleteTargets(ctx, obj, want)
+}
+
+// writeTarget writes data to the target secret key of obj, labelled with
+// [LabelTargetOf]. Targets in other namespaces must be allowed there, see
+// [Reconciler.checkTargetAllowed].
+func (r *Reconciler[O]) writeTarget(
+	ctx context.Context,
+	obj O,
+	written secretChecksums,
+	key client.ObjectKey,
+	data map[string]string,
+	typ corev1.SecretType,
+) error {
+	if key.Namespace != obj.GetNamespace() {
+		if err := r.checkTargetAllowed(ctx, obj, key, LabelTargetOf); err != nil {
+			return err
+		}
+	}
+
+	return r.writeSecret(ctx, obj, written, key, func(secret *corev1.Secret) {
+		setSecretType(secret, typ)
+		metav1.SetMetaDataLabel(&secret.ObjectMeta, LabelTargetOf, string(obj.GetUID()))
+		secret.StringData = data
+	})
+}
+
+// checkTargetAllowed returns an [ErrTargetNotAllowed] unless the namespace of
+// key lists the namespace of obj, or "*", in [AnnotationAllowTargetsFrom],
+// and the secret does not exist yet or carries label, [LabelTargetOf] or
+// [LabelReplicaOf], for obj already. This keeps resources from taking over
+// secrets of other namespaces, which they cannot own.
+func (r *Reconciler[O]) checkTargetAllowed(ctx context.Context, obj O, key client.ObjectKey, label string) error {
+	var ns corev1.Namespace
+	if err := r.Get(ctx, client.ObjectKey{Name: key.Namespace}, &ns); err != nil {
+		return fmt.Errorf("getting namespace %s: %w", key.Namespace, err)
+	}
+	allowed := strings.Split(ns.Annotations[AnnotationAllowTargetsFrom], ",")
+	if !slices.Contains(allowed, "*") && !slices.Contains(allowed, obj.GetNamespace()) {
+		return fmt.Errorf("%w: namespace %s does not allow targets from %s, see the %s annotation",
+			ErrTargetNotAllowed, key.Namespace, obj.GetNamespace(), AnnotationAllowTargetsFrom)
+	}
+
+	var existing corev1.Secret
+	if err := r.Get(ctx, key, &existing); apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if existing.Labels[label] != string(obj.GetUID()) {
+		return fmt.Errorf("%w: secret %s exists and was not written by this resource", ErrTargetNotAllowed, key)
+	}
+	return nil
+}
+
+// deleteTargets deletes the secrets labelled as targets of obj whose keys
+// are not in want, e.g. all of them once obj is deleted.
+func (r *Reconciler[O]) deleteTargets(ctx context.Context, obj O, want map[client.ObjectKey]bool) error {
+	return r.deleteLabelled(ctx, obj, LabelTargetOf, "target", want)
+}
+
+// deleteLabelled deletes the secrets whose label is set to the UID of obj and
+// whose keys are not in want. what names them in logs and errors.
+func (r *Reconciler[O]) deleteLabelled(
+	ctx context.Context,
+	obj O,
+	label, what string,
+	want map[client.ObjectKey]bool,
+) error {
+	var secrets corev1.SecretList
+	if err := r.List(ctx, &secrets, client.MatchingLabels{label: string(obj.GetUID())}); err != nil {
+		return fmt.Errorf("listing %ss: %w", what, err)
+	}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if want[client.ObjectKeyFromObject(secret)] {
+			continue
+		}
+		log.FromContext(ctx).Info("deleting stale "+what, "secret", client.ObjectKeyFromObject(secret))
+		if err := r.Delete(ctx, secret); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("deleting %s %s: %w", what, client.ObjectKeyFromObject(secret), err)
+		}
+	}
+	return nil
+}
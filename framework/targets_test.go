@@ -0,0 +1,142 @@
+package framework_test
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcile_Targets(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	obj.Targets = framework.OutputTargets{
+		{SecretRef: framework.SecretReference{Name: "user-only"}, Keys: []string{"USER"}},
+		{SecretRef: framework.SecretReference{Name: "shared"}, Namespace: "other"},
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "other",
+		Annotations: map[string]string{framework.AnnotationAllowTargetsFrom: "default"},
+	}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, ns).WithStatusSubresource(obj).Build()
+	p := &dataProvider{data: map[string]string{"USER": "app", "PASSWORD": "s3cret"}}
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	var userOnly corev1.Secret
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "user-only"}, &userOnly); err != nil {
+		t.Fatal(err)
+	}
+	if len(userOnly.StringData) != 1 || userOnly.StringData["USER"] != "app" {
+		t.Errorf("expected only the selected key, got %v", userOnly.StringData)
+	}
+	if !metav1.IsControlledBy(&userOnly, obj) {
+		t.Errorf("expected a target in the same namespace to be owned, got %v", userOnly.OwnerReferences)
+	}
+
+	var shared corev1.Secret
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "other", Name: "shared"}, &shared); err != nil {
+		t.Fatal(err)
+	}
+	if len(shared.StringData) != 2 || len(shared.OwnerReferences) != 0 {
+		t.Errorf("expected all keys and no owner, got %v and %v", shared.StringData, shared.OwnerReferences)
+	}
+	if shared.Labels[framework.LabelTargetOf] != string(obj.UID) {
+		t.Errorf("expected the target to be labelled, got %v", shared.Labels)
+	}
+
+	// Targets in other namespaces are deleted with the resource.
+	if err := c.Delete(ctx, obj); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	err := c.Get(ctx, client.ObjectKeyFromObject(&shared), &shared)
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected the target in the other namespace to be deleted, got %v", err)
+	}
+}
+
+func TestReconcile_TargetNotAllowed(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	obj.Targets = framework.OutputTargets{
+		{SecretRef: framework.SecretReference{Name: "shared"}, Namespace: "kube-system"},
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, ns).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: &countingProvider{}}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(ctx, req); err == nil {
+		t.Fatal("expected the target to be refused")
+	}
+	var secret corev1.Secret
+	err := c.Get(ctx, client.ObjectKey{Namespace: "kube-system", Name: "shared"}, &secret)
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected no secret in the namespace not allowing targets, got %v", err)
+	}
+}
+
+func TestReconcile_TargetTemplate(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	obj.Targets = framework.OutputTargets{{
+		SecretRef: framework.SecretReference{Name: "dsn"},
+		Keys:      []string{"USER"},
+		Template:  map[string]string{"DSN": "postgres://{{ .USER }}:{{ .PASSWORD }}@db"},
+	}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	p := &dataProvider{data: map[string]string{"USER": "app", "PASSWORD": "s3cret"}}
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	var got corev1.Secret
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "dsn"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"USER": "app", "DSN": "postgres://app:s3cret@db"}
+	if !maps.Equal(got.StringData, want) {
+		t.Errorf("got data %v, want %v", got.StringData, want)
+	}
+}
+
+func TestReconcile_TargetTemplateMissingKey(t *testing.T) {
+	scheme := newTestScheme(t)
+	obj := newTestObject()
+	obj.Finalizers = []string{framework.Finalizer}
+	obj.Targets = framework.OutputTargets{{
+		SecretRef: framework.SecretReference{Name: "dsn"},
+		Template:  map[string]string{"DSN": "{{ .MISSING }}"},
+	}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).WithStatusSubresource(obj).Build()
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: &countingProvider{}}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+
+	_, err := r.Reconcile(context.Background(), req)
+	if err == nil || !strings.Contains(err.Error(), "MISSING") {
+		t.Errorf("expected a template referring to a missing key to fail, got %v", err)
+	}
+}
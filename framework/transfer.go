@@ -0,0 +1,161 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// adoptSecret hands the output secret of the resource named by
+// [AnnotationAdoptFrom] over to obj, once that resource consents with
+// [AnnotationTransferTo]. obj takes over its keys first, then the resource
+// releases them, and finally the controller reference of the secret and of
+// its split parts is moved to obj. Each step is repeated until the secret is
+// controlled by obj, so an interrupted handover is completed on the next
+// attempt. Nothing is adopted if the secret does not exist.
+func (r *Reconciler[O]) adoptSecret(ctx context.Context, obj O) error {
+	source := obj.GetAnnotations()[AnnotationAdoptFrom]
+	if source == "" {
+		return nil
+	}
+
+	var secret corev1.Secret
+	key := client.ObjectKey{Namespace: obj.GetNamespace(), Name: obj.GetSecretRef().Name}
+	if err := r.Get(ctx, key, &secret); apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if metav1.IsControlledBy(&secret, obj) {
+		return nil
+	}
+
+	from := r.Provider.NewObject()
+	if err := r.Get(ctx, client.ObjectKey{Namespace: obj.GetNamespace(), Name: source}, from); err != nil {
+		return fmt.Errorf("getting %s: %w", source, err)
+	}
+	if !metav1.IsControlledBy(&secret, from) {
+		return fmt.Errorf("secret %s is not controlled by %s", key.Name, source)
+	}
+	if target := from.GetAnnotations()[AnnotationTransferTo]; target != obj.GetName() {
+		return fmt.Errorf("%s must consent to the handover with the annotation %s=%s",
+			source, AnnotationTransferTo, obj.GetName())
+	}
+	if err := r.loadKeys(ctx, from); err != nil {
+		return err
+	}
+
+	obj.GetStatus().AdoptKeys(obj.GetGeneration(), source, from.GetStatus())
+	if err := r.updateStatus(ctx, obj); err != nil {
+		return err
+	}
+
+	if status := from.GetStatus(); len(status.ActiveKeys) > 0 || status.CurrentKeyID != "" {
+		status.ActiveKeys = nil
+		status.CurrentKeyID = ""
+		if err := r.updateStatus(ctx, from); err != nil {
+			return fmt.Errorf("releasing keys of %s: %w", source, err)
+		}
+	}
+
+	// The output secret itself goes last, as it marks the handover done.
+	names := []string{key.Name}
+	if parts := secret.Annotations[AnnotationSplitSecrets]; parts != "" {
+		names = append(strings.Split(parts, ","), key.Name)
+	}
+	for _, name := range names {
+		if err := r.transferSecret(ctx, from, obj, name); err != nil {
+			return err
+		}
+	}
+
+	log.FromContext(ctx).Info("adopted output secret", "secret", key.Name, "from", source)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, corev1.EventTypeNormal, ReasonSecretAdopted, "Reconcile",
+			"Adopted secret %s and its keys from %s", key.Name, source)
+		r.Recorder.Eventf(from, nil, corev1.EventTypeNormal, ReasonSecretTransferred, "Reconcile",
+			"Handed secret %s and its keys over to %s", key.Name, obj.GetName())
+	}
+
+	return nil
+}
+
+// transferSecret moves the controller reference of the named secret from
+// from to to. Secrets that do not exist or are not controlled by from are
+// left alone.
+func (r *Reconciler[O]) transferSecret(ctx context.Context, from, to O, name string) error {
+	var secret corev1.Secret
+	key := client.ObjectKey{Namespace: to.GetNamespace(), Name: name}
+	if err := r.Get(ctx, key, &secret); apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(&secret, from) {
+		return nil
+	}
+
+	secret.OwnerReferences = slices.DeleteFunc(secret.OwnerReferences, func(ref metav1.OwnerReference) bool {
+		return ref.UID == from.GetUID()
+	})
+	if err := controllerutil.SetControllerReference(to, &secret, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.Update(ctx, &secret, client.FieldOwner(FieldOwner)); err != nil {
+		return fmt.Errorf("transferring secret %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// handleTransfer marks obj Degraded because it is annotated with
+// [AnnotationTransferTo] target, and skips renewal and cleanup, as the keys
+// may already be tracked by target. No requeue is scheduled: removing the
+// annotation triggers the next reconciliation.
+func (r *Reconciler[O]) handleTransfer(ctx context.Context, obj O, target string) (ctrl.Result, error) {
+	if !obj.GetStatus().SetTransferred(obj.GetGeneration(), target) {
+		return ctrl.Result{}, nil
+	}
+
+	log.FromContext(ctx).Info("output secret is handed over, skipping renewal", "target", target)
+	if err := r.updateStatus(ctx, obj); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// ownKeys returns the active keys of obj that are to be deleted with it. If
+// obj is annotated with [AnnotationTransferTo], the keys that the target
+// already adopted are left to the target.
+func (r *Reconciler[O]) ownKeys(ctx context.Context, obj O) (ActiveKeys, error) {
+	keys := obj.GetStatus().ActiveKeys
+	target := obj.GetAnnotations()[AnnotationTransferTo]
+	if target == "" || len(keys) == 0 {
+		return keys, nil
+	}
+
+	to := r.Provider.NewObject()
+	if err := r.Get(ctx, client.ObjectKey{Namespace: obj.GetNamespace(), Name: target}, to); apierrors.IsNotFound(err) {
+		return keys, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("getting %s: %w", target, err)
+	}
+	if err := r.loadKeys(ctx, to); err != nil {
+		return nil, err
+	}
+
+	adopted := to.GetStatus().ActiveKeys
+	return slices.DeleteFunc(slices.Clone(keys), func(k ActiveKey) bool {
+		return slices.ContainsFunc(adopted, func(a ActiveKey) bool { return a.KeyID == k.KeyID })
+	}), nil
+}
@@ -0,0 +1,134 @@
+package framework_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// handoverObjects returns a resource "old" controlling its output secret and a
+// resource "new" annotated to adopt it.
+func handoverObjects(t *testing.T) (oldObj, newObj *testObject, secret *corev1.Secret) {
+	t.Helper()
+	oldObj, secret = steadyObject(nil)
+	oldObj.Name, oldObj.UID = "old", "uid-1"
+	if err := controllerutil.SetControllerReference(oldObj, secret, newTestScheme(t)); err != nil {
+		t.Fatal(err)
+	}
+	newObj = newTestObject()
+	newObj.Name, newObj.UID = "new", "uid-2"
+	newObj.Finalizers = []string{framework.Finalizer}
+	newObj.Annotations = map[string]string{framework.AnnotationAdoptFrom: "old"}
+	return oldObj, newObj, secret
+}
+
+func TestReconcile_SecretHandover(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	oldObj, newObj, secret := handoverObjects(t)
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(oldObj, newObj, secret).WithStatusSubresource(oldObj, newObj).Build()
+	p := &countingProvider{}
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p}
+	oldReq := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(oldObj)}
+	newReq := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(newObj)}
+	get := func(key client.ObjectKey) *testObject {
+		t.Helper()
+		var got testObject
+		if err := c.Get(ctx, key, &got); err != nil {
+			t.Fatal(err)
+		}
+		return &got
+	}
+
+	// Without consent of the old resource, nothing is handed over.
+	if _, err := r.Reconcile(ctx, newReq); err == nil {
+		t.Fatal("expected the handover to wait for consent")
+	}
+	msg := get(newReq.NamespacedName).Status.LastFailureMessage
+	if !strings.Contains(msg, framework.AnnotationTransferTo) {
+		t.Errorf("expected the failure to ask for consent, got %q", msg)
+	}
+
+	old := get(oldReq.NamespacedName)
+	old.Annotations = map[string]string{framework.AnnotationTransferTo: "new"}
+	if err := c.Update(ctx, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, oldReq); err != nil {
+		t.Fatalf("reconcile old: %v", err)
+	}
+	cond := meta.FindStatusCondition(get(oldReq.NamespacedName).Status.Conditions, framework.ConditionDegraded)
+	if cond == nil || cond.Reason != framework.ReasonSecretTransferred {
+		t.Errorf("expected old to be degraded with reason SecretTransferred, got %+v", cond)
+	}
+
+	if _, err := r.Reconcile(ctx, newReq); err != nil {
+		t.Fatalf("reconcile new: %v", err)
+	}
+	if p.provisions != 0 {
+		t.Errorf("expected no rotation, got %d provisions", p.provisions)
+	}
+	adopted := get(newReq.NamespacedName)
+	if adopted.Status.CurrentKeyID != "current" || len(adopted.Status.ActiveKeys) != 1 {
+		t.Errorf("expected the key to be adopted, got %q %+v", adopted.Status.CurrentKeyID, adopted.Status.ActiveKeys)
+	}
+	if cond := meta.FindStatusCondition(adopted.Status.Conditions, framework.ConditionReady); cond == nil ||
+		cond.Reason != framework.ReasonSecretAdopted {
+		t.Errorf("expected Ready with reason SecretAdopted, got %+v", cond)
+	}
+	if keys := get(oldReq.NamespacedName).Status.ActiveKeys; len(keys) != 0 {
+		t.Errorf("expected old to release its keys, got %+v", keys)
+	}
+	var got corev1.Secret
+	if err := c.Get(ctx, client.ObjectKeyFromObject(secret), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !metav1.IsControlledBy(&got, adopted) || len(got.OwnerReferences) != 1 {
+		t.Errorf("expected the secret to be controlled by new only, got %+v", got.OwnerReferences)
+	}
+
+	// Later reconciliations of the adopting resource leave the key alone.
+	if _, err := r.Reconcile(ctx, newReq); err != nil {
+		t.Fatalf("reconcile new: %v", err)
+	}
+	if p.provisions != 0 {
+		t.Errorf("expected no rotation, got %d provisions", p.provisions)
+	}
+}
+
+func TestHandleDeletion_KeepsAdoptedKeys(t *testing.T) {
+	ctx := context.Background()
+	scheme := newTestScheme(t)
+	oldObj, newObj, _ := handoverObjects(t)
+	oldObj.Annotations = map[string]string{framework.AnnotationTransferTo: "new"}
+	oldObj.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	oldObj.Status.ActiveKeys = append(oldObj.Status.ActiveKeys, framework.ActiveKey{
+		KeyID:     "own",
+		CreatedAt: metav1.NewTime(time.Now()),
+		ExpiresAt: metav1.NewTime(time.Now().Add(time.Hour)),
+	})
+	// The handover was interrupted after new adopted the current key.
+	newObj.Status.ActiveKeys = oldObj.Status.ActiveKeys[:1]
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(oldObj, newObj).WithStatusSubresource(oldObj, newObj).Build()
+	p := &deleteProvider{}
+	r := &framework.Reconciler[*testObject]{Client: c, Scheme: scheme, Provider: p}
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(oldObj)}); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if len(p.deleted) != 1 || p.deleted[0] != "own" {
+		t.Errorf("expected only the own key to be deleted, got %v", p.deleted)
+	}
+}
@@ -2,6 +2,8 @@
 package framework
 
 import (
+	"errors"
+	"slices"
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -12,15 +14,241 @@ const (
 	// Finalizer is applied to all managed CRDs to ensure key cleanup on deletion.
 	Finalizer = "valet.ngl.cx/finalizer"
 
+	// AnnotationForceDelete, set to "true" on a resource, lets the finalizer
+	// be removed after repeated failures to delete its active keys, e.g. once
+	// the provider tenant has been decommissioned. The undeleted keys are
+	// reported in a Warning event and must be revoked manually if they still
+	// exist.
+	AnnotationForceDelete = "valet.ngl.cx/force-delete"
+
+	// AnnotationSplitSecrets is set on an output Secret whose data was split
+	// across several Secrets, see [Reconciler.SplitOversizedSecrets]. It
+	// lists the names of the additional Secrets, comma-separated and in order.
+	AnnotationSplitSecrets = "valet.ngl.cx/split-secrets"
+
+	// AnnotationUnmanaged, set to "true" on an output Secret, stops the
+	// reconciler from provisioning credentials for it and writing to it, e.g.
+	// as an emergency brake during an incident. The resource reports a true
+	// [ConditionDegraded] until the annotation is removed.
+	AnnotationUnmanaged = "valet.ngl.cx/unmanaged"
+
+	// AnnotationPushRemoteKey, set on a resource, publishes its output Secret
+	// under this key of the external-secrets store configured with
+	// [Reconciler.PushSecretStore]. Removing it stops the publishing.
+	AnnotationPushRemoteKey = "valet.ngl.cx/push-remote-key"
+
+	// AnnotationDataChecksum is set on output Secrets to the checksum of the
+	// data the reconciler wrote, so that changes by other writers are
+	// detected, see [ConditionConflictingWriter].
+	AnnotationDataChecksum = "valet.ngl.cx/data-checksum"
+
+	// AnnotationManagedKeys is set on output Secrets to the comma-separated
+	// data keys the reconciler wrote. Only these keys are updated or removed
+	// on later writes, so that other keys can be kept in the same Secret.
+	AnnotationManagedKeys = "valet.ngl.cx/managed-keys"
+
+	// AnnotationAllowTargetsFrom, set on a namespace to a comma-separated
+	// list of namespaces or "*", allows resources in those namespaces to
+	// write targets into it, see [OutputTarget].
+	AnnotationAllowTargetsFrom = "valet.ngl.cx/allow-targets-from"
+
+	// LabelTargetOf is set on the targets of a resource to its UID, so that
+	// targets it no longer has, and all of them once it is deleted, are
+	// found and deleted.
+	LabelTargetOf = "valet.ngl.cx/target-of"
+
+	// AnnotationSelfManaged, set to "true" on a resource whose output
+	// Secret holds the credentials the operator itself authenticates to the
+	// provider with, guards against the operator locking itself out: new
+	// credentials are verified before they are written (see
+	// [CredentialVerifier]), superseded keys are kept until they expire
+	// regardless of the grace period, keys are not deleted early to stay
+	// within [Reconciler.MaxActiveKeys], and deleting the resource retains
+	// its keys as with [DeletionPolicyRetain].
+	AnnotationSelfManaged = "valet.ngl.cx/self-managed"
+
+	// LabelReplicaOf is set on the replicas of a resource to its UID, see
+	// [Replication].
+	LabelReplicaOf = "valet.ngl.cx/replica-of"
+
+	// AnnotationSecretChecksums is set on the Deployments and StatefulSets
+	// restarted by [Reconciler.RestartWorkloads] to a JSON object mapping
+	// the names of the Secrets they reference to the [AnnotationDataChecksum]
+	// of the data their pods were started with.
+	AnnotationSecretChecksums = "valet.ngl.cx/secret-checksums"
+
+	// LabelManagedBy is set to [ManagedBy] on all objects valet creates, see
+	// [StandardLabels].
+	LabelManagedBy = "app.kubernetes.io/managed-by"
+
+	// ManagedBy is the value of [LabelManagedBy].
+	ManagedBy = "valet"
+
+	// LabelProvider is set on the objects valet creates to the name of the
+	// provider, e.g. provider-azure.
+	LabelProvider = "valet.ngl.cx/provider"
+
+	// LabelOwnerKind, LabelOwnerNamespace and LabelOwnerName are set on the
+	// objects valet creates to identify the resource they were created for.
+	LabelOwnerKind      = "valet.ngl.cx/owner-kind"
+	LabelOwnerNamespace = "valet.ngl.cx/owner-namespace"
+	LabelOwnerName      = "valet.ngl.cx/owner-name"
+
+	// AnnotationOutputLayout, set on a resource to [LayoutFiles], also writes
+	// the well-known fields of its credentials, e.g. [FieldPassword], to the
+	// output Secret, so that it can be used by charts expecting those keys
+	// without templates.
+	AnnotationOutputLayout = "valet.ngl.cx/output-layout"
+
+	// AnnotationRenewBefore, set on a resource to a duration such as "24h",
+	// renews its credentials that long before they expire instead of the
+	// default (see [ActiveKey.RenewalWindow]), until [AnnotationOverrideUntil].
+	AnnotationRenewBefore = "valet.ngl.cx/renew-before"
+
+	// AnnotationOverrideUntil is the time in RFC 3339 format until which the
+	// rotation parameters a resource overrides with annotations, e.g.
+	// [AnnotationRenewBefore], apply. It is required with them and may be at
+	// most [MaxOverrideWindow] ahead.
+	AnnotationOverrideUntil = "valet.ngl.cx/override-until"
+
+	// AnnotationAdoptFrom, set on a resource to the name of another resource
+	// in its namespace, takes over the output Secret and the keys of that
+	// resource without rotating, once it consents with
+	// [AnnotationTransferTo]. Both must have the same secretRef.
+	AnnotationAdoptFrom = "valet.ngl.cx/adopt-from"
+
+	// AnnotationTransferTo, set on a resource to the name of the resource
+	// adopting its output Secret (see [AnnotationAdoptFrom]), consents to the
+	// handover. Renewal and cleanup of the resource stop while it is set.
+	AnnotationTransferTo = "valet.ngl.cx/transfer-to"
+
+	// AnnotationSimulateExpiry, set to "true" on a resource, treats its
+	// newest key as near expiry on the next reconciliation, so that the
+	// rotation can be tested without waiting for it. It is only honored with
+	// [Reconciler.AllowSimulateExpiry] and removed once the key was rotated.
+	AnnotationSimulateExpiry = "valet.ngl.cx/simulate-expiry"
+
+	// AnnotationRotateNow, set to "true" on a resource, provisions a new key
+	// on the next reconciliation regardless of the expiry of the current one,
+	// e.g. after it was compromised. It is removed once the key was rotated.
+	AnnotationRotateNow = "valet.ngl.cx/rotate-now"
+
+	// AnnotationPaused, set to "true" on a resource, suspends its rotation
+	// like spec.suspend (see [Suspender]), e.g. where the spec is managed by
+	// GitOps.
+	AnnotationPaused = "valet.ngl.cx/paused"
+
 	// RenewalThreshold is the maximum time before expiry to trigger renewal.
 	// For keys with shorter validity, a dynamic threshold of 10% of the
 	// validity period is used instead.
 	RenewalThreshold = 7 * 24 * time.Hour
 
+	// ExpectedActiveKeys is the number of active keys an object normally
+	// tracks: the current key and, while rotating, the previous one. More
+	// indicate flapping reconciliations or keys that cannot be deleted.
+	ExpectedActiveKeys = 2
+
 	// ConditionReady is the condition type indicating whether credentials
 	// are provisioned and up to date.
 	ConditionReady = "Ready"
 
+	// ConditionDegraded is the condition type indicating that the reconciler
+	// deliberately does not keep the output secret up to date.
+	ConditionDegraded = "Degraded"
+
+	// ConditionConflictingWriter is the condition type indicating that
+	// another writer changed the data of the output secret after the
+	// reconciler wrote it. Its message names the field manager of the writer.
+	ConditionConflictingWriter = "ConflictingWriter"
+
+	// ConditionMaintenance is the condition type indicating that a renewal
+	// is deferred until a provider maintenance window ends, see
+	// [Reconciler.MaintenanceWindows].
+	ConditionMaintenance = "ProviderMaintenance"
+
+	// ConditionTargetReachable is the condition type indicating whether the
+	// provider's precheck of the spec's generation passed, see [Prechecker].
+	ConditionTargetReachable = "TargetReachable"
+
+	// ConditionPolicyViolation is the condition type indicating that the
+	// resource violates a policy of [Reconciler.Policies]. Its message lists
+	// the violations.
+	ConditionPolicyViolation = "PolicyViolation"
+
+	// ReasonSecretUnmanaged is the reason of a true Degraded condition while
+	// the output secret is annotated with [AnnotationUnmanaged].
+	ReasonSecretUnmanaged = "SecretUnmanaged"
+
+	// ReasonSecretTooLarge is the reason of a false Ready condition when the
+	// rendered data does not fit into the output Secret, see
+	// [ErrSecretTooLarge].
+	ReasonSecretTooLarge = "SecretTooLarge"
+
+	// ReasonPushSecretFailed is the reason of the Warning event recorded when
+	// the PushSecret publishing an output Secret could not be written.
+	ReasonPushSecretFailed = "PushSecretFailed"
+
+	// ReasonConflictingWriter is the reason of a true
+	// [ConditionConflictingWriter] and of the Warning event recorded when it
+	// is set.
+	ReasonConflictingWriter = "ConflictingWriter"
+
+	// ReasonMaintenanceWindow is the reason of a true [ConditionMaintenance].
+	ReasonMaintenanceWindow = "MaintenanceWindow"
+
+	// ReasonPrecheckPassed is the reason of a true [ConditionTargetReachable].
+	ReasonPrecheckPassed = "PrecheckPassed"
+
+	// ReasonPrecheckFailed is the reason of a false [ConditionTargetReachable]
+	// and of the false Ready condition set with it.
+	ReasonPrecheckFailed = "PrecheckFailed"
+
+	// ReasonPolicyDenied is the reason of a true [ConditionPolicyViolation],
+	// of the false Ready condition set with it and of the Warning event
+	// recorded when it is set.
+	ReasonPolicyDenied = "PolicyDenied"
+
+	// ReasonSecretTransferred is the reason of a true Degraded condition
+	// while a resource is annotated with [AnnotationTransferTo], and of the
+	// event recorded when another resource adopts its output Secret.
+	ReasonSecretTransferred = "SecretTransferred"
+
+	// ReasonSuspended is the reason of a true Degraded condition while the
+	// rotation of a resource is suspended, see [Suspender].
+	ReasonSuspended = "Suspended"
+
+	// ReasonSimulatedExpiry is the reason of the event recorded when a key
+	// was rotated because of [AnnotationSimulateExpiry].
+	ReasonSimulatedExpiry = "SimulatedExpiry"
+
+	// ReasonRotatedOnDemand is the reason of the event recorded when a key
+	// was rotated because of [AnnotationRotateNow].
+	ReasonRotatedOnDemand = "RotatedOnDemand"
+
+	// ReasonSecretAdopted is the reason of the true Ready condition of a
+	// resource that adopted the output Secret of another one.
+	ReasonSecretAdopted = "SecretAdopted"
+
+	// ReasonReplicationNotAllowed is the reason of the Warning event recorded
+	// when a namespace selected by [Replication] does not allow replicas from
+	// the namespace of the resource.
+	ReasonReplicationNotAllowed = "ReplicationNotAllowed"
+
+	// ReasonCredentialVerified is the reason of the event recorded when new
+	// credentials of a resource annotated with [AnnotationSelfManaged] were
+	// verified.
+	ReasonCredentialVerified = "CredentialVerified"
+
+	// ReasonWorkloadRestarted is the reason of the event recorded when a
+	// workload was restarted for a rotated secret, see
+	// [Reconciler.RestartWorkloads].
+	ReasonWorkloadRestarted = "WorkloadRestarted"
+
+	// ReasonKeyStoreConflict is the reason of the false Ready condition and
+	// of the Warning event recorded when the [KeyStore] of a resource is
+	// taken by another one, see [ErrKeyStoreConflict].
+	ReasonKeyStoreConflict = "KeyStoreConflict"
+
 	// PhasePending indicates the resource has been created but not yet reconciled.
 	PhasePending = "Pending"
 	// PhaseReady indicates credentials are provisioned and the output secret is up to date.
@@ -37,27 +265,94 @@ type SecretReference struct {
 	Name string `json:"name"`
 }
 
+// OrDefault returns r, or a reference to the Secret named name if r names
+// none. Providers return it from [Object.GetSecretRef] with the name of the
+// resource, so that secretRef may be omitted for a Secret named after the
+// resource.
+func (r SecretReference) OrDefault(name string) SecretReference {
+	if r.Name == "" {
+		return SecretReference{Name: name}
+	}
+	return r
+}
+
 // ActiveKey represents a provisioned credential key tracked by the operator.
 type ActiveKey struct {
 	// KeyID is the provider-specific identifier for this key.
 	KeyID string `json:"keyId"`
+	// Identifier is the identifier paired with this key, e.g. a username,
+	// for providers that rotate both together. See [Result.Identifier].
+	// +optional
+	Identifier string `json:"identifier,omitempty"`
+	// Hint is the provider's hint of this key, see [Result.Hint].
+	// +optional
+	Hint string `json:"hint,omitempty"`
 	// CreatedAt is when this key was provisioned.
 	CreatedAt metav1.Time `json:"createdAt"`
 	// ExpiresAt is when this key will expire.
 	ExpiresAt metav1.Time `json:"expiresAt"`
+	// DeleteAttempts counts failed attempts to delete this key at the
+	// provider after it expired.
+	// +optional
+	DeleteAttempts int `json:"deleteAttempts,omitempty"`
+	// LastUsedAt is when this key was last used, as reported by providers
+	// implementing [UsageReporter]. Unset if it has not been used by the
+	// status' UsageReportedAt.
+	// +optional
+	LastUsedAt *metav1.Time `json:"lastUsedAt,omitempty"`
+	// SupersededAt is when a newer key was written to the output secret in
+	// place of this one. See [RotationPolicy.GracePeriod].
+	// +optional
+	SupersededAt *metav1.Time `json:"supersededAt,omitempty"`
 }
 
-// NearExpiry reports whether the key is expired or within its renewal window.
-// The renewal window is the smaller of 10% of the key's validity period and
-// [RenewalThreshold].
-func (k *ActiveKey) NearExpiry() bool {
-	now := time.Now()
+// FailedCleanup is an expired key that could not be deleted at the provider
+// within the retry budget. The operator no longer tries to delete it, so it
+// has to be deleted manually if it still exists.
+type FailedCleanup struct {
+	// KeyID is the provider-specific identifier of the key.
+	KeyID string `json:"keyId"`
+	// Identifier is the identifier paired with the key, if any.
+	// +optional
+	Identifier string `json:"identifier,omitempty"`
+	// ExpiresAt is when the key expired.
+	ExpiresAt metav1.Time `json:"expiresAt"`
+	// Attempts is the number of failed deletion attempts.
+	Attempts int `json:"attempts"`
+	// LastError is the error of the last deletion attempt.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+}
+
+// RevokeAt returns when the key is to be deleted: when it expires or, with a
+// positive grace period (see [RotationPolicy.GracePeriod]), once the grace
+// period has passed since it was superseded, whichever is earlier.
+func (k *ActiveKey) RevokeAt(grace time.Duration) time.Time {
+	if grace <= 0 || k.SupersededAt == nil {
+		return k.ExpiresAt.Time
+	}
+	if end := k.SupersededAt.Add(grace); end.Before(k.ExpiresAt.Time) {
+		return end
+	}
+	return k.ExpiresAt.Time
+}
+
+// NearExpiry reports whether the key is expired or within its renewal window
+// (see [ActiveKey.RenewalWindow]), widened by skew to allow for the
+// provider's clock running ahead of ours.
+func (k *ActiveKey) NearExpiry(skew time.Duration) bool {
+	now := time.Now().Add(skew)
 	if k.ExpiresAt.Time.Before(now) {
 		return true
 	}
+	return k.ExpiresAt.Sub(now) < k.RenewalWindow()
+}
+
+// RenewalWindow returns how long before its expiry the key is renewed: the
+// smaller of 10% of its validity period and [RenewalThreshold].
+func (k *ActiveKey) RenewalWindow() time.Duration {
 	validity := k.ExpiresAt.Sub(k.CreatedAt.Time)
-	threshold := min(validity/10, RenewalThreshold)
-	return time.Until(k.ExpiresAt.Time) < threshold
+	return min(validity/10, RenewalThreshold)
 }
 
 // ActiveKeys is a list of provisioned credential keys.
@@ -79,10 +374,16 @@ func (keys ActiveKeys) Newest() *ActiveKey {
 // (e.g. when provider deletion fails), false to drop it. The backing array is
 // reused to avoid allocations.
 func (keys *ActiveKeys) DropExpired(now time.Time, keep func(ActiveKey) bool) []ActiveKey {
+	return keys.DropRevoked(now, 0, keep)
+}
+
+// DropRevoked is like [ActiveKeys.DropExpired], but also drops the keys whose
+// grace period after being superseded has passed, see [ActiveKey.RevokeAt].
+func (keys *ActiveKeys) DropRevoked(now time.Time, grace time.Duration, keep func(ActiveKey) bool) []ActiveKey {
 	idx := 0
 	var dropped []ActiveKey
 	for _, k := range *keys {
-		if !k.ExpiresAt.Time.Before(now) || keep(k) {
+		if !k.RevokeAt(grace).Before(now) || keep(k) {
 			(*keys)[idx] = k
 			idx++
 		} else {
@@ -93,6 +394,39 @@ func (keys *ActiveKeys) DropExpired(now time.Time, keep func(ActiveKey) bool) []
 	return dropped
 }
 
+// Excess returns the oldest keys that must be removed to bring the list down
+// to limit entries. The current key and the newest key are never returned, so
+// fewer keys may be returned than needed.
+func (keys ActiveKeys) Excess(limit int, current string) []ActiveKey {
+	n := len(keys) - limit
+	if n <= 0 {
+		return nil
+	}
+	newest := keys.Newest()
+	candidates := make([]ActiveKey, 0, len(keys))
+	for _, k := range keys {
+		if k.KeyID != current && k.KeyID != newest.KeyID {
+			candidates = append(candidates, k)
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b ActiveKey) int {
+		return a.CreatedAt.Compare(b.CreatedAt.Time)
+	})
+	return candidates[:min(n, len(candidates))]
+}
+
+// Identifier returns the identifier paired with the key with the given ID,
+// or "" if the key is not tracked or has none. Providers rotating pairs use it
+// in [Provider.DeleteKey] to find the identifier to drop along with the key.
+func (keys ActiveKeys) Identifier(keyID string) string {
+	for _, k := range keys {
+		if k.KeyID == keyID {
+			return k.Identifier
+		}
+	}
+	return ""
+}
+
 // DeepCopy returns a deep copy of the keys.
 func (keys ActiveKeys) DeepCopy() ActiveKeys {
 	if keys == nil {
@@ -100,6 +434,16 @@ func (keys ActiveKeys) DeepCopy() ActiveKeys {
 	}
 	cp := make(ActiveKeys, len(keys))
 	copy(cp, keys)
+	for i := range cp {
+		if cp[i].LastUsedAt != nil {
+			t := *cp[i].LastUsedAt
+			cp[i].LastUsedAt = &t
+		}
+		if cp[i].SupersededAt != nil {
+			t := *cp[i].SupersededAt
+			cp[i].SupersededAt = &t
+		}
+	}
 	return cp
 }
 
@@ -125,6 +469,21 @@ type ClientSecretStatus struct {
 	// FailureCount tracks consecutive failures for observability.
 	FailureCount int `json:"failureCount,omitempty"`
 
+	// FailedCleanups lists the most recent expired keys the operator gave
+	// up deleting.
+	// +optional
+	FailedCleanups []FailedCleanup `json:"failedCleanups,omitempty"`
+
+	// DeletionFailures counts failed attempts to delete the active keys
+	// while the resource is being deleted.
+	// +optional
+	DeletionFailures int `json:"deletionFailures,omitempty"`
+
+	// UsageReportedAt is when the provider last reported the usage of the
+	// active keys. Unset if the provider does not report usage.
+	// +optional
+	UsageReportedAt *metav1.Time `json:"usageReportedAt,omitempty"`
+
 	// LastFailure is the timestamp of the last failure.
 	// +optional
 	LastFailure *metav1.Time `json:"lastFailure,omitempty"`
@@ -133,6 +492,16 @@ type ClientSecretStatus struct {
 	// +optional
 	LastFailureMessage string `json:"lastFailureMessage,omitempty"`
 
+	// LastProvisionAt is when the provider was last called to provision
+	// credentials, whether or not the call succeeded.
+	// +optional
+	LastProvisionAt *metav1.Time `json:"lastProvisionAt,omitempty"`
+
+	// LastProvisionDuration is how long the last provider call to provision
+	// credentials took.
+	// +optional
+	LastProvisionDuration *metav1.Duration `json:"lastProvisionDuration,omitempty"`
+
 	// Conditions represent the latest available observations.
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
@@ -140,8 +509,10 @@ type ClientSecretStatus struct {
 
 // NeedsRenewal reports whether credentials need to be provisioned or renewed.
 // It returns true when there are no active keys, the spec generation changed,
-// the output secret is missing or empty, or the newest key is near expiry.
-func (s *ClientSecretStatus) NeedsRenewal(currentGeneration int64, secretHasData bool) bool {
+// the output secret is missing or empty, the newest key was never written to
+// the output secret, or the newest key is near expiry, allowing for clock skew
+// (see [ActiveKey.NearExpiry]).
+func (s *ClientSecretStatus) NeedsRenewal(currentGeneration int64, secretHasData bool, skew time.Duration) bool {
 	if len(s.ActiveKeys) == 0 {
 		return true
 	}
@@ -155,25 +526,28 @@ func (s *ClientSecretStatus) NeedsRenewal(currentGeneration int64, secretHasData
 	if newest == nil {
 		return true
 	}
-	return newest.NearExpiry()
+	if s.CurrentKeyID != "" && newest.KeyID != s.CurrentKeyID {
+		return true
+	}
+	return newest.NearExpiry(skew)
 }
 
-// RenewalDuration returns how long to wait before the next renewal check.
-// Returns 0 when there are no active keys, signaling an immediate requeue.
-func (s *ClientSecretStatus) RenewalDuration() time.Duration {
+// RenewalDuration returns how long to wait from now before the next renewal
+// check, which is when the newest key enters its renewal window widened by
+// skew. Returns 0 when there are no active keys, signaling an immediate
+// requeue.
+func (s *ClientSecretStatus) RenewalDuration(now time.Time, skew time.Duration) time.Duration {
 	newest := s.ActiveKeys.Newest()
 	if newest == nil {
 		return 0
 	}
-	validity := newest.ExpiresAt.Sub(newest.CreatedAt.Time)
-	threshold := min(validity/10, RenewalThreshold)
-	d := time.Until(newest.ExpiresAt.Time) - threshold
+	d := newest.ExpiresAt.Sub(now) - newest.RenewalWindow() - skew
 	return max(d, time.Minute)
 }
 
 // SetReady transitions the status to Ready after successful provisioning.
-// It clears failure counters, appends the new key to ActiveKeys, and sets
-// the Ready condition to true.
+// It clears failure counters, marks the other keys superseded, appends the
+// new key to ActiveKeys, and sets the Ready condition to true.
 func (s *ClientSecretStatus) SetReady(generation int64, result *Result) {
 	s.Phase = PhaseReady
 	s.ObservedGeneration = generation
@@ -182,13 +556,13 @@ func (s *ClientSecretStatus) SetReady(generation int64, result *Result) {
 	s.LastFailure = nil
 	s.LastFailureMessage = ""
 
-	if result.KeyID != "" {
-		s.ActiveKeys = append(s.ActiveKeys, ActiveKey{
-			KeyID:     result.KeyID,
-			CreatedAt: metav1.NewTime(result.ProvisionedAt),
-			ExpiresAt: metav1.NewTime(result.ValidUntil),
-		})
+	now := metav1.Now()
+	for i := range s.ActiveKeys {
+		if k := &s.ActiveKeys[i]; k.KeyID != result.KeyID && k.SupersededAt == nil {
+			k.SupersededAt = &now
+		}
 	}
+	s.TrackKey(result)
 
 	meta.SetStatusCondition(&s.Conditions, metav1.Condition{
 		Type:               ConditionReady,
@@ -199,9 +573,72 @@ func (s *ClientSecretStatus) SetReady(generation int64, result *Result) {
 	})
 }
 
+// TrackKey adds the key of a provisioning result to ActiveKeys, unless it has
+// no KeyID or is already tracked. Keys are tracked as soon as they exist at
+// the provider, even if they never make it into the output secret, so that
+// they are cleaned up on expiry.
+func (s *ClientSecretStatus) TrackKey(result *Result) {
+	if result.KeyID == "" {
+		return
+	}
+	for _, k := range s.ActiveKeys {
+		if k.KeyID == result.KeyID {
+			return
+		}
+	}
+	s.ActiveKeys = append(s.ActiveKeys, ActiveKey{
+		KeyID:      result.KeyID,
+		Identifier: result.Identifier,
+		Hint:       result.Hint,
+		CreatedAt:  metav1.NewTime(result.ProvisionedAt),
+		ExpiresAt:  metav1.NewTime(result.ValidUntil),
+	})
+}
+
+// AddFailedCleanup records a key the operator gave up deleting in
+// FailedCleanups, dropping the oldest entries beyond limit.
+func (s *ClientSecretStatus) AddFailedCleanup(cleanup FailedCleanup, limit int) {
+	s.FailedCleanups = append(s.FailedCleanups, cleanup)
+	if n := len(s.FailedCleanups) - limit; n > 0 {
+		s.FailedCleanups = slices.Delete(s.FailedCleanups, 0, n)
+	}
+}
+
+// CurrentIdentifier returns the identifier paired with the current key, or ""
+// if there is none. During [Provider.Provision] this is the identifier about
+// to be replaced, which providers rotating pairs expose to templates so that
+// consumers can handle both identifiers while the old key is still valid.
+func (s *ClientSecretStatus) CurrentIdentifier() string {
+	return s.ActiveKeys.Identifier(s.CurrentKeyID)
+}
+
+// RecordProvision records the start time and duration of a provider
+// Provision call.
+func (s *ClientSecretStatus) RecordProvision(start time.Time, duration time.Duration) {
+	at := metav1.NewTime(start)
+	s.LastProvisionAt = &at
+	s.LastProvisionDuration = &metav1.Duration{Duration: duration}
+}
+
 // SetFailed transitions the status to Failed. It increments the failure
-// counter, records the error, and sets the Ready condition to false.
+// counter, records the error, and sets the Ready condition to false, with
+// reason [ReasonSecretTooLarge] for an [ErrSecretTooLarge],
+// [ReasonPrecheckFailed] for an [ErrPrecheckFailed], [ReasonPolicyDenied]
+// for an [ErrPolicyViolation] and [ReasonKeyStoreConflict] for an
+// [ErrKeyStoreConflict].
 func (s *ClientSecretStatus) SetFailed(generation int64, err error) {
+	reason := "ProvisioningFailed"
+	switch {
+	case errors.Is(err, ErrSecretTooLarge):
+		reason = ReasonSecretTooLarge
+	case errors.Is(err, ErrPrecheckFailed):
+		reason = ReasonPrecheckFailed
+	case errors.Is(err, ErrPolicyViolation):
+		reason = ReasonPolicyDenied
+	case errors.Is(err, ErrKeyStoreConflict):
+		reason = ReasonKeyStoreConflict
+	}
+
 	s.Phase = PhaseFailed
 	s.FailureCount++
 	now := metav1.Now()
@@ -211,20 +648,225 @@ func (s *ClientSecretStatus) SetFailed(generation int64, err error) {
 	meta.SetStatusCondition(&s.Conditions, metav1.Condition{
 		Type:               ConditionReady,
 		Status:             metav1.ConditionFalse,
-		Reason:             "ProvisioningFailed",
+		Reason:             reason,
 		Message:            err.Error(),
 		ObservedGeneration: generation,
 	})
 }
 
+// SetUnmanaged sets the Degraded condition to true because the output secret
+// is annotated with [AnnotationUnmanaged]. It reports whether the condition
+// changed. The phase and the Ready condition are left as is.
+func (s *ClientSecretStatus) SetUnmanaged(generation int64, secretName string) bool {
+	return meta.SetStatusCondition(&s.Conditions, metav1.Condition{
+		Type:   ConditionDegraded,
+		Status: metav1.ConditionTrue,
+		Reason: ReasonSecretUnmanaged,
+		Message: "Secret " + secretName + " is annotated with " + AnnotationUnmanaged +
+			"; credentials are not rotated until the annotation is removed",
+		ObservedGeneration: generation,
+	})
+}
+
+// ClearUnmanaged removes the Degraded condition set by
+// [ClientSecretStatus.SetUnmanaged] and reports whether it was present.
+func (s *ClientSecretStatus) ClearUnmanaged() bool {
+	c := meta.FindStatusCondition(s.Conditions, ConditionDegraded)
+	if c == nil || c.Reason != ReasonSecretUnmanaged {
+		return false
+	}
+	return meta.RemoveStatusCondition(&s.Conditions, ConditionDegraded)
+}
+
+// SetSuspended sets the Degraded condition to true because the rotation is
+// suspended for the reason why. It reports whether the condition changed. The
+// phase and the Ready condition are left as is.
+func (s *ClientSecretStatus) SetSuspended(generation int64, why string) bool {
+	return meta.SetStatusCondition(&s.Conditions, metav1.Condition{
+		Type:               ConditionDegraded,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonSuspended,
+		Message:            "Rotation and cleanup are suspended because " + why,
+		ObservedGeneration: generation,
+	})
+}
+
+// ClearSuspended removes the Degraded condition set by
+// [ClientSecretStatus.SetSuspended] and reports whether it was present.
+func (s *ClientSecretStatus) ClearSuspended() bool {
+	c := meta.FindStatusCondition(s.Conditions, ConditionDegraded)
+	if c == nil || c.Reason != ReasonSuspended {
+		return false
+	}
+	return meta.RemoveStatusCondition(&s.Conditions, ConditionDegraded)
+}
+
+// AdoptKeys takes over the active keys and the current key of from, the
+// status of the resource named source whose output secret is handed over, and
+// sets the status to Ready for generation, so that the adoption does not
+// trigger a renewal. Keys that are already tracked are skipped.
+func (s *ClientSecretStatus) AdoptKeys(generation int64, source string, from *ClientSecretStatus) {
+	s.ActiveKeys = mergeKeys(s.ActiveKeys, from.ActiveKeys)
+	if from.CurrentKeyID != "" {
+		s.CurrentKeyID = from.CurrentKeyID
+	}
+	s.Phase = PhaseReady
+	s.ObservedGeneration = generation
+	s.FailureCount = 0
+	s.LastFailure = nil
+	s.LastFailureMessage = ""
+
+	meta.SetStatusCondition(&s.Conditions, metav1.Condition{
+		Type:               ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonSecretAdopted,
+		Message:            "Credentials adopted from " + source,
+		ObservedGeneration: generation,
+	})
+}
+
+// SetTransferred sets the Degraded condition to true because the resource
+// is annotated with [AnnotationTransferTo] target. It reports whether the
+// condition changed. The phase and the Ready condition are left as is.
+func (s *ClientSecretStatus) SetTransferred(generation int64, target string) bool {
+	return meta.SetStatusCondition(&s.Conditions, metav1.Condition{
+		Type:   ConditionDegraded,
+		Status: metav1.ConditionTrue,
+		Reason: ReasonSecretTransferred,
+		Message: "The output secret is handed over to " + target +
+			"; credentials are not rotated until " + AnnotationTransferTo + " is removed",
+		ObservedGeneration: generation,
+	})
+}
+
+// ClearTransferred removes the Degraded condition set by
+// [ClientSecretStatus.SetTransferred] and reports whether it was present.
+func (s *ClientSecretStatus) ClearTransferred() bool {
+	c := meta.FindStatusCondition(s.Conditions, ConditionDegraded)
+	if c == nil || c.Reason != ReasonSecretTransferred {
+		return false
+	}
+	return meta.RemoveStatusCondition(&s.Conditions, ConditionDegraded)
+}
+
+// SetConflictingWriter sets the ConflictingWriter condition to true because
+// the field manager named manager changed the data of the output secret. It
+// reports whether the condition changed. The phase and the Ready condition are
+// left as is.
+func (s *ClientSecretStatus) SetConflictingWriter(generation int64, secretName, manager string) bool {
+	return meta.SetStatusCondition(&s.Conditions, metav1.Condition{
+		Type:   ConditionConflictingWriter,
+		Status: metav1.ConditionTrue,
+		Reason: ReasonConflictingWriter,
+		Message: "The data of Secret " + secretName + " was changed by field manager " + manager +
+			"; make sure no other controller writes to it",
+		ObservedGeneration: generation,
+	})
+}
+
+// ClearConflictingWriter removes the ConflictingWriter condition set by
+// [ClientSecretStatus.SetConflictingWriter] and reports whether it was
+// present.
+func (s *ClientSecretStatus) ClearConflictingWriter() bool {
+	return meta.RemoveStatusCondition(&s.Conditions, ConditionConflictingWriter)
+}
+
+// SetMaintenance sets the ProviderMaintenance condition to true because a
+// renewal is deferred until until, the end of a provider maintenance window.
+// It reports whether the condition changed. The phase and the Ready condition
+// are left as is.
+func (s *ClientSecretStatus) SetMaintenance(generation int64, until time.Time) bool {
+	return meta.SetStatusCondition(&s.Conditions, metav1.Condition{
+		Type:   ConditionMaintenance,
+		Status: metav1.ConditionTrue,
+		Reason: ReasonMaintenanceWindow,
+		Message: "Provisioning is deferred until the provider maintenance window ends at " +
+			until.UTC().Format(time.RFC3339),
+		ObservedGeneration: generation,
+	})
+}
+
+// ClearMaintenance removes the ProviderMaintenance condition set by
+// [ClientSecretStatus.SetMaintenance] and reports whether it was present.
+func (s *ClientSecretStatus) ClearMaintenance() bool {
+	return meta.RemoveStatusCondition(&s.Conditions, ConditionMaintenance)
+}
+
+// SetPrecheck sets the TargetReachable condition to the outcome err of the
+// provider's precheck of generation, see [Prechecker]. It reports whether the
+// condition changed.
+func (s *ClientSecretStatus) SetPrecheck(generation int64, err error) bool {
+	c := metav1.Condition{
+		Type:               ConditionTargetReachable,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonPrecheckPassed,
+		Message:            "The provider can reach the target of the resource",
+		ObservedGeneration: generation,
+	}
+	if err != nil {
+		c.Status = metav1.ConditionFalse
+		c.Reason = ReasonPrecheckFailed
+		c.Message = err.Error()
+	}
+	return meta.SetStatusCondition(&s.Conditions, c)
+}
+
+// PrecheckPassed reports whether the TargetReachable condition records a
+// passed precheck of generation.
+func (s *ClientSecretStatus) PrecheckPassed(generation int64) bool {
+	c := meta.FindStatusCondition(s.Conditions, ConditionTargetReachable)
+	return c != nil && c.Status == metav1.ConditionTrue && c.ObservedGeneration == generation
+}
+
+// SetPolicyViolation sets the PolicyViolation condition to true with the
+// violations in message, see [Reconciler.Policies]. It reports whether the
+// condition changed.
+func (s *ClientSecretStatus) SetPolicyViolation(generation int64, message string) bool {
+	return meta.SetStatusCondition(&s.Conditions, metav1.Condition{
+		Type:               ConditionPolicyViolation,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonPolicyDenied,
+		Message:            message,
+		ObservedGeneration: generation,
+	})
+}
+
+// ClearPolicyViolation removes the PolicyViolation condition set by
+// [ClientSecretStatus.SetPolicyViolation] and reports whether it was present.
+func (s *ClientSecretStatus) ClearPolicyViolation() bool {
+	return meta.RemoveStatusCondition(&s.Conditions, ConditionPolicyViolation)
+}
+
+// SetDeletionFailed records a failed attempt to delete the active keys of a
+// resource that is being deleted. The phase and conditions are left as is.
+func (s *ClientSecretStatus) SetDeletionFailed(err error) {
+	s.DeletionFailures++
+	now := metav1.Now()
+	s.LastFailure = &now
+	s.LastFailureMessage = err.Error()
+}
+
 // DeepCopy returns a deep copy of the status.
 func (s *ClientSecretStatus) DeepCopy() ClientSecretStatus {
 	out := *s
 	out.ActiveKeys = s.ActiveKeys.DeepCopy()
+	out.FailedCleanups = slices.Clone(s.FailedCleanups)
 	if s.LastFailure != nil {
 		t := *s.LastFailure
 		out.LastFailure = &t
 	}
+	if s.UsageReportedAt != nil {
+		t := *s.UsageReportedAt
+		out.UsageReportedAt = &t
+	}
+	if s.LastProvisionAt != nil {
+		t := *s.LastProvisionAt
+		out.LastProvisionAt = &t
+	}
+	if s.LastProvisionDuration != nil {
+		d := *s.LastProvisionDuration
+		out.LastProvisionDuration = &d
+	}
 	if s.Conditions != nil {
 		out.Conditions = make([]metav1.Condition, len(s.Conditions))
 		copy(out.Conditions, s.Conditions)
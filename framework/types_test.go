@@ -87,13 +87,38 @@ func TestActiveKeys_DropExpired_KeepOnFailure(t *testing.T) {
 	}
 }
 
+func TestActiveKeys_Excess(t *testing.T) {
+	now := time.Now()
+	keys := framework.ActiveKeys{}
+	for i, id := range []string{"c", "current", "a", "b", "newest"} {
+		keys = append(keys, framework.ActiveKey{
+			KeyID:     id,
+			CreatedAt: metav1.NewTime(now.Add(time.Duration(i) * time.Minute)),
+		})
+	}
+	keys[1].CreatedAt = metav1.NewTime(now.Add(-time.Hour))   // current is the oldest
+	keys[2].CreatedAt = metav1.NewTime(now.Add(-time.Minute)) // a is older than c
+
+	got := keys.Excess(2, "current")
+	if len(got) != 3 || got[0].KeyID != "a" || got[1].KeyID != "c" || got[2].KeyID != "b" {
+		t.Errorf("expected a, c, b to be in excess, got %v", got)
+	}
+
+	if got := keys.Excess(4, "current"); len(got) != 1 || got[0].KeyID != "a" {
+		t.Errorf("expected only a to be in excess, got %v", got)
+	}
+	if got := keys.Excess(5, "current"); got != nil {
+		t.Errorf("expected no excess within limit, got %v", got)
+	}
+}
+
 func TestActiveKey_NearExpiry_Fresh(t *testing.T) {
 	now := time.Now()
 	k := framework.ActiveKey{
 		CreatedAt: metav1.NewTime(now),
 		ExpiresAt: metav1.NewTime(now.Add(24 * time.Hour)),
 	}
-	if k.NearExpiry() {
+	if k.NearExpiry(0) {
 		t.Error("expected fresh key to not be near expiry")
 	}
 }
@@ -104,7 +129,7 @@ func TestActiveKey_NearExpiry_Expired(t *testing.T) {
 		CreatedAt: metav1.NewTime(now.Add(-25 * time.Hour)),
 		ExpiresAt: metav1.NewTime(now.Add(-1 * time.Hour)),
 	}
-	if !k.NearExpiry() {
+	if !k.NearExpiry(0) {
 		t.Error("expected expired key to be near expiry")
 	}
 }
@@ -116,14 +141,30 @@ func TestActiveKey_NearExpiry_WithinThreshold(t *testing.T) {
 		CreatedAt: metav1.NewTime(now.Add(-23 * time.Hour)),
 		ExpiresAt: metav1.NewTime(now.Add(1 * time.Hour)),
 	}
-	if !k.NearExpiry() {
+	if !k.NearExpiry(0) {
 		t.Error("expected key within threshold to be near expiry")
 	}
 }
 
+func TestActiveKey_NearExpiry_ClockSkew(t *testing.T) {
+	now := time.Now()
+	// 24h validity, 10% threshold = 2.4h, key expires in 3h → near expiry
+	// only if the provider's clock may be an hour ahead.
+	k := framework.ActiveKey{
+		CreatedAt: metav1.NewTime(now.Add(-21 * time.Hour)),
+		ExpiresAt: metav1.NewTime(now.Add(3 * time.Hour)),
+	}
+	if k.NearExpiry(0) {
+		t.Error("expected key outside threshold to not be near expiry")
+	}
+	if !k.NearExpiry(time.Hour) {
+		t.Error("expected key within threshold plus skew to be near expiry")
+	}
+}
+
 func TestClientSecretStatus_NeedsRenewal_NoKeys(t *testing.T) {
 	s := framework.ClientSecretStatus{}
-	if !s.NeedsRenewal(1, true) {
+	if !s.NeedsRenewal(1, true, 0) {
 		t.Error("expected renewal when no active keys")
 	}
 }
@@ -140,7 +181,7 @@ func TestClientSecretStatus_NeedsRenewal_GenerationChanged(t *testing.T) {
 			},
 		},
 	}
-	if !s.NeedsRenewal(2, true) {
+	if !s.NeedsRenewal(2, true, 0) {
 		t.Error("expected renewal when generation changed")
 	}
 }
@@ -157,7 +198,7 @@ func TestClientSecretStatus_NeedsRenewal_SecretMissing(t *testing.T) {
 			},
 		},
 	}
-	if !s.NeedsRenewal(1, false) {
+	if !s.NeedsRenewal(1, false, 0) {
 		t.Error("expected renewal when secret has no data")
 	}
 }
@@ -174,11 +215,34 @@ func TestClientSecretStatus_NeedsRenewal_NotNeeded(t *testing.T) {
 			},
 		},
 	}
-	if s.NeedsRenewal(1, true) {
+	if s.NeedsRenewal(1, true, 0) {
 		t.Error("expected no renewal when key is fresh and generation matches")
 	}
 }
 
+func TestClientSecretStatus_NeedsRenewal_NewestNotWritten(t *testing.T) {
+	now := time.Now()
+	s := framework.ClientSecretStatus{
+		ObservedGeneration: 1,
+		CurrentKeyID:       "old",
+		ActiveKeys: framework.ActiveKeys{
+			{
+				KeyID:     "old",
+				CreatedAt: metav1.NewTime(now.Add(-time.Hour)),
+				ExpiresAt: metav1.NewTime(now.Add(24 * time.Hour)),
+			},
+			{
+				KeyID:     "unwritten",
+				CreatedAt: metav1.NewTime(now),
+				ExpiresAt: metav1.NewTime(now.Add(25 * time.Hour)),
+			},
+		},
+	}
+	if !s.NeedsRenewal(1, true, 0) {
+		t.Error("expected renewal when the newest key was never written to the secret")
+	}
+}
+
 func TestClientSecretStatus_RenewalDuration(t *testing.T) {
 	now := time.Now()
 	s := framework.ClientSecretStatus{
@@ -190,7 +254,7 @@ func TestClientSecretStatus_RenewalDuration(t *testing.T) {
 			},
 		},
 	}
-	d := s.RenewalDuration()
+	d := s.RenewalDuration(now, 0)
 	if d <= 0 {
 		t.Fatal("expected positive duration")
 	}
@@ -202,9 +266,25 @@ func TestClientSecretStatus_RenewalDuration(t *testing.T) {
 	}
 }
 
+func TestClientSecretStatus_RenewalDuration_ClockSkew(t *testing.T) {
+	now := time.Now()
+	s := framework.ClientSecretStatus{
+		ActiveKeys: framework.ActiveKeys{
+			{
+				KeyID:     "k",
+				CreatedAt: metav1.NewTime(now),
+				ExpiresAt: metav1.NewTime(now.Add(24 * time.Hour)),
+			},
+		},
+	}
+	if diff := s.RenewalDuration(now, 0) - s.RenewalDuration(now, time.Hour); diff != time.Hour {
+		t.Errorf("expected skew to bring renewal forward by 1h, got %v", diff)
+	}
+}
+
 func TestClientSecretStatus_RenewalDuration_NoKeys(t *testing.T) {
 	s := framework.ClientSecretStatus{}
-	if d := s.RenewalDuration(); d != 0 {
+	if d := s.RenewalDuration(time.Now(), 0); d != 0 {
 		t.Errorf("expected 0 for no keys, got %v", d)
 	}
 }
@@ -244,6 +324,48 @@ func TestClientSecretStatus_SetReady(t *testing.T) {
 	}
 }
 
+func TestClientSecretStatus_TrackKey(t *testing.T) {
+	now := time.Now()
+	s := &framework.ClientSecretStatus{}
+	result := &framework.Result{KeyID: "k", ProvisionedAt: now, ValidUntil: now.Add(time.Hour)}
+
+	s.TrackKey(result)
+	s.TrackKey(result)
+	s.TrackKey(&framework.Result{})
+
+	if len(s.ActiveKeys) != 1 || s.ActiveKeys[0].KeyID != "k" {
+		t.Errorf("expected key k to be tracked once, got %v", s.ActiveKeys)
+	}
+	if s.CurrentKeyID != "" {
+		t.Errorf("expected currentKeyID to be unchanged, got %s", s.CurrentKeyID)
+	}
+}
+
+func TestClientSecretStatus_CurrentIdentifier(t *testing.T) {
+	now := time.Now()
+	s := &framework.ClientSecretStatus{}
+	if got := s.CurrentIdentifier(); got != "" {
+		t.Errorf("expected no identifier without keys, got %q", got)
+	}
+
+	s.SetReady(1, &framework.Result{
+		KeyID: "k1", Identifier: "app-1", ProvisionedAt: now, ValidUntil: now.Add(time.Hour),
+	})
+	s.TrackKey(&framework.Result{
+		KeyID: "k2", Identifier: "app-2", ProvisionedAt: now, ValidUntil: now.Add(time.Hour),
+	})
+
+	if got := s.CurrentIdentifier(); got != "app-1" {
+		t.Errorf("expected identifier of the current key, got %q", got)
+	}
+	if got := s.ActiveKeys.Identifier("k2"); got != "app-2" {
+		t.Errorf("expected identifier of k2 to be tracked, got %q", got)
+	}
+	if got := s.ActiveKeys.Identifier("unknown"); got != "" {
+		t.Errorf("expected no identifier for an unknown key, got %q", got)
+	}
+}
+
 func TestClientSecretStatus_SetFailed(t *testing.T) {
 	s := &framework.ClientSecretStatus{}
 
@@ -271,3 +393,42 @@ func TestClientSecretStatus_SetFailed(t *testing.T) {
 		t.Errorf("expected Ready=False condition, got %v", s.Conditions)
 	}
 }
+
+func TestClientSecretStatus_RecordProvision(t *testing.T) {
+	s := &framework.ClientSecretStatus{}
+	start := time.Now()
+
+	s.RecordProvision(start, 1500*time.Millisecond)
+
+	if s.LastProvisionAt == nil || !s.LastProvisionAt.Time.Equal(start) {
+		t.Errorf("expected LastProvisionAt %v, got %v", start, s.LastProvisionAt)
+	}
+	if s.LastProvisionDuration == nil || s.LastProvisionDuration.Duration != 1500*time.Millisecond {
+		t.Errorf("expected LastProvisionDuration 1.5s, got %v", s.LastProvisionDuration)
+	}
+
+	cp := s.DeepCopy()
+	cp.LastProvisionDuration.Duration = time.Second
+	if s.LastProvisionDuration.Duration != 1500*time.Millisecond {
+		t.Error("DeepCopy did not copy LastProvisionDuration")
+	}
+}
+
+func TestSecretReference_OrDefault(t *testing.T) {
+	if got := (framework.SecretReference{}).OrDefault("app"); got.Name != "app" {
+		t.Errorf("expected the default name, got %q", got.Name)
+	}
+	if got := (framework.SecretReference{Name: "out"}).OrDefault("app"); got.Name != "out" {
+		t.Errorf("expected the set name to be kept, got %q", got.Name)
+	}
+}
+
+func TestClientSecretStatus_AddFailedCleanup(t *testing.T) {
+	var s framework.ClientSecretStatus
+	for _, id := range []string{"k1", "k2", "k3"} {
+		s.AddFailedCleanup(framework.FailedCleanup{KeyID: id}, 2)
+	}
+	if len(s.FailedCleanups) != 2 || s.FailedCleanups[0].KeyID != "k2" || s.FailedCleanups[1].KeyID != "k3" {
+		t.Errorf("expected the two most recent failed cleanups, got %+v", s.FailedCleanups)
+	}
+}
@@ -0,0 +1,145 @@
+package framework
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// ReasonStuckPending is the reason of the Warning event recorded on a
+// resource that stayed [PhasePending] longer than the timeout of a
+// [PendingWatchdog].
+const ReasonStuckPending = "StuckPending"
+
+// StuckPendingResources is the number of resources per kind that stayed
+// [PhasePending] longer than the timeout of a [PendingWatchdog], as of its
+// last check. It is registered on the controller-runtime metrics registry.
+var StuckPendingResources = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "valet_stuck_pending_resources",
+	Help: "Number of resources that have been Pending without status updates for longer than the watchdog timeout.",
+}, []string{"kind"})
+
+func init() {
+	metrics.Registry.MustRegister(StuckPendingResources)
+}
+
+// PendingWatchdog periodically lists the resources of a provider and reports
+// the ones that have been Pending without status updates for longer than
+// Timeout, e.g. because the provider hangs or the resources are not picked up
+// at all, so that such stalls do not go unnoticed. Stuck resources are
+// counted in [StuckPendingResources] and get a [ReasonStuckPending] Warning
+// event. It is added to the manager by [Reconciler.SetupWithManager] if
+// [Reconciler.PendingTimeout] is set.
+//
+// As the watchdog runs in the operator, alert on the absence of the metric to
+// also catch an operator that is not running.
+type PendingWatchdog[O Object] struct {
+	// Reader lists the resources, preferably uncached so that a stalled
+	// informer does not hide them.
+	Reader client.Reader
+	Scheme *runtime.Scheme
+
+	// NewObject returns a zero-value instance of the CRD type.
+	NewObject func() O
+
+	// Timeout is how long a resource may be Pending without status updates.
+	Timeout time.Duration
+
+	// Recorder, if set, records the Warning events.
+	Recorder events.EventRecorder
+
+	reported map[types.UID]bool
+}
+
+// NeedLeaderElection implements
+// [sigs.k8s.io/controller-runtime/pkg/manager.LeaderElectionRunnable], so that
+// only the leader checks and reports.
+func (w *PendingWatchdog[O]) NeedLeaderElection() bool {
+	return true
+}
+
+// Start checks the resources every half Timeout until ctx is done.
+func (w *PendingWatchdog[O]) Start(ctx context.Context) error {
+	ticker := time.NewTicker(max(w.Timeout/2, time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			if _, err := w.Check(ctx, now); err != nil {
+				log.FromContext(ctx).Error(err, "pending watchdog check failed")
+			}
+		}
+	}
+}
+
+// Check reports the resources that are stuck in Pending at now and returns
+// their number. Each stuck resource gets a single event until it leaves
+// Pending.
+func (w *PendingWatchdog[O]) Check(ctx context.Context, now time.Time) (int, error) {
+	items, err := List(ctx, w.Reader, w.Scheme, w.NewObject)
+	if err != nil {
+		return 0, err
+	}
+
+	stuck := map[types.UID]bool{}
+	for _, obj := range items {
+		since, pending := pendingSince(obj)
+		if !pending || now.Sub(since) < w.Timeout {
+			continue
+		}
+		stuck[obj.GetUID()] = true
+		if w.reported[obj.GetUID()] {
+			continue
+		}
+
+		pendingFor := now.Sub(since).Round(time.Second)
+		log.FromContext(ctx).Info("resource is stuck in Pending",
+			"namespace", obj.GetNamespace(), "name", obj.GetName(), "pendingFor", pendingFor)
+		if w.Recorder != nil {
+			w.Recorder.Eventf(obj, nil, corev1.EventTypeWarning, ReasonStuckPending, "Watchdog",
+				"Pending without status updates for %s; check that the operator reconciles it and the provider responds",
+				pendingFor)
+		}
+	}
+	w.reported = stuck
+
+	StuckPendingResources.WithLabelValues(reflect.TypeOf(w.NewObject()).Elem().Name()).Set(float64(len(stuck)))
+
+	return len(stuck), nil
+}
+
+// pendingSince reports whether obj is Pending, i.e. has not been reconciled
+// to Ready or Failed yet and is not being deleted, and since when its status
+// was last updated.
+func pendingSince(obj Object) (time.Time, bool) {
+	status := obj.GetStatus()
+	if status.Phase != "" && status.Phase != PhasePending {
+		return time.Time{}, false
+	}
+	if !obj.GetDeletionTimestamp().IsZero() {
+		return time.Time{}, false
+	}
+
+	since := obj.GetCreationTimestamp().Time
+	if status.LastProvisionAt != nil && status.LastProvisionAt.After(since) {
+		since = status.LastProvisionAt.Time
+	}
+	for _, c := range status.Conditions {
+		if c.LastTransitionTime.After(since) {
+			since = c.LastTransitionTime.Time
+		}
+	}
+	return since, true
+}
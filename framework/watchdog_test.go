@@ -0,0 +1,75 @@
+package framework_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPendingWatchdog(t *testing.T) {
+	scheme := newTestScheme(t)
+	now := time.Now()
+	created := metav1.NewTime(now.Add(-time.Hour))
+
+	stuck := newTestObject()
+	stuck.CreationTimestamp = created
+
+	fresh := newTestObject()
+	fresh.Name, fresh.UID = "fresh", "uid-2"
+	fresh.CreationTimestamp = metav1.NewTime(now.Add(-time.Minute))
+
+	recent := newTestObject()
+	recent.Name, recent.UID = "recent", "uid-3"
+	recent.CreationTimestamp = created
+	recent.Status.Phase = framework.PhasePending
+	recent.Status.LastProvisionAt = &metav1.Time{Time: now.Add(-time.Minute)}
+
+	ready := newTestObject()
+	ready.Name, ready.UID = "ready", "uid-4"
+	ready.CreationTimestamp = created
+	ready.Status.Phase = framework.PhaseReady
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(stuck, fresh, recent, ready).Build()
+	recorder := events.NewFakeRecorder(10)
+	w := &framework.PendingWatchdog[*testObject]{
+		Reader:    c,
+		Scheme:    scheme,
+		NewObject: func() *testObject { return &testObject{} },
+		Timeout:   15 * time.Minute,
+		Recorder:  recorder,
+	}
+
+	for range 2 {
+		n, err := w.Check(context.Background(), now)
+		if err != nil {
+			t.Fatalf("check: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("expected 1 stuck resource, got %d", n)
+		}
+	}
+	if got := testutil.ToFloat64(framework.StuckPendingResources.WithLabelValues("testObject")); got != 1 {
+		t.Errorf("expected the gauge to report 1 stuck resource, got %v", got)
+	}
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "Warning "+framework.ReasonStuckPending) {
+			t.Errorf("unexpected event: %s", event)
+		}
+	default:
+		t.Error("expected a StuckPending event")
+	}
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("expected a single event while the resource stays stuck, got %s", event)
+	default:
+	}
+}
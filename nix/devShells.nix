@@ -25,6 +25,7 @@
             operator-sdk
             golangci-lint
             kubernetes-controller-tools
+            kind
             kubernetes-helm
             kustomize
             skopeo
@@ -3,8 +3,10 @@
 # Contributes to perSystem valet.lib: packageChart.
 #
 # The derivation packages the chart (.tgz) in the build phase and runs
-# helm lint + kubeconform validation in the check phase.
-{ ... }:
+# helm lint + kubeconform validation in the check phase. The valet library
+# chart from framework/chart/valet is placed in the chart's charts/ directory
+# first, as helm dependency build would.
+{ inputs, ... }:
 {
   perSystem =
     { pkgs, lib, ... }:
@@ -27,11 +29,15 @@
               ];
               doCheck = true;
               buildPhase = ''
-                helm package ${src} -d $TMPDIR
+                cp -r ${src} chart
+                chmod -R u+w chart
+                mkdir -p chart/charts
+                cp -r ${inputs.self}/framework/chart/valet chart/charts/valet
+                helm package chart -d $TMPDIR
               '';
               checkPhase = ''
-                helm lint ${src}
-                helm template test ${src} -f ${src}/${valuesFile} \
+                helm lint chart
+                helm template test chart -f chart/${valuesFile} \
                   | kubeconform -strict -summary \
                       -schema-location '${kubernetesSchemas}/{{.ResourceKind}}{{.KindSuffix}}.json'
               '';
@@ -1,12 +1,15 @@
 # Go build helpers for the workspace.
 #
-# Contributes to perSystem valet.lib: version, vendorHash, mkGoModule,
+# Contributes to perSystem valet.lib: version, vendorHash, mkGoModule, mkImage,
 # workspaceVendor, withPackageEnv, envtestBinaries.
 { config, inputs, ... }:
 let
   # Project version, read from version.txt at the repo root.
   version = builtins.replaceStrings [ "\n" ] [ "" ] (builtins.readFile ../version.txt);
 
+  # Git revision of the flake, empty for trees that are not under git.
+  revision = inputs.self.rev or inputs.self.dirtyRev or "";
+
   # Override a Go package derivation to run a check instead of producing a binary.
   # Reuses the package's build environment (vendor, source, nativeBuildInputs)
   # but replaces the build and install phases.
@@ -52,7 +55,9 @@ in
           installPhase = "mkdir -p $out";
         }).goModules;
 
-      # Build a Go binary from the workspace using the shared vendor.
+      # Build a Go binary from the workspace using the shared vendor. The
+      # default ldflags embed pname, version and revision for
+      # framework.NewBuildInfo.
       #
       # { pname, subPackages, tags?, ldflags?, ... } -> derivation
       mkGoModule =
@@ -64,6 +69,8 @@ in
             "-s"
             "-w"
             "-X main.version=${version}"
+            "-X main.provider=${pname}"
+            "-X main.revision=${revision}"
           ],
           ...
         }@args:
@@ -90,6 +97,35 @@ in
           ]
         );
 
+      # Build a distroless image for the host platform: it contains only the
+      # entrypoint's closure and contents, and runs as a non-root user. The
+      # x86_64-linux and aarch64-linux images are combined into a multi-arch
+      # manifest by CI.
+      #
+      # { name, entrypoint, contents? } -> derivation
+      mkImage =
+        {
+          name,
+          entrypoint,
+          contents ? [ pkgs.dockerTools.caCertificates ],
+        }:
+        pkgs.dockerTools.streamLayeredImage {
+          inherit name contents;
+          tag = version;
+          config = {
+            Entrypoint = [ entrypoint ];
+            User = "65532:65532";
+            WorkingDir = "/";
+            Labels = {
+              "org.opencontainers.image.title" = name;
+              "org.opencontainers.image.version" = version;
+              "org.opencontainers.image.revision" = revision;
+              "org.opencontainers.image.source" = "https://github.com/lukasngl/valet";
+              "org.opencontainers.image.licenses" = "Apache-2.0";
+            };
+          };
+        };
+
       # etcd + kube-apiserver for envtest. Set KUBEBUILDER_ASSETS to this.
       envtestBinaries = pkgs.linkFarm "envtest-binaries" {
         etcd = "${pkgs.etcd}/bin/etcd";
@@ -102,6 +138,7 @@ in
           version
           withPackageEnv
           mkGoModule
+          mkImage
           workspaceVendor
           envtestBinaries
           ;
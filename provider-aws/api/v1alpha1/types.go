@@ -0,0 +1,207 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	SchemeBuilder.Register(&AWSAccessKey{}, &AWSAccessKeyList{})
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=awsak,categories=valet
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// AWSAccessKey provisions and rotates access keys for AWS IAM users.
+type AWSAccessKey struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	Spec AWSAccessKeySpec `json:"spec,omitzero"`
+	// +optional
+	Status framework.ClientSecretStatus `json:"status,omitzero"`
+}
+
+// AWSAccessKeySpec defines the desired state.
+type AWSAccessKeySpec struct {
+	// SecretRef is the Kubernetes Secret to create/update with the provisioned credentials.
+	// Defaults to a Secret named after the resource.
+	// +optional
+	SecretRef framework.SecretReference `json:"secretRef,omitzero"`
+
+	// Suspend stops the rotation and cleanup of the credentials while set,
+	// e.g. during incidents or migrations. The output Secret keeps its
+	// current credentials.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DeletionPolicy controls what happens when the resource is deleted:
+	// Delete deletes its keys at the provider and the output Secret with it,
+	// Retain keeps both, e.g. to move the resource to another cluster.
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy framework.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// SecretTemplate sets labels, annotations and the type of the output
+	// Secret, e.g. for Reloader, External Secrets or Argo CD to match on.
+	// +optional
+	SecretTemplate framework.SecretTemplate `json:"secretTemplate,omitzero"`
+
+	// Targets are further Secrets the credentials are written to, each
+	// with a subset of the keys, its own template or in another namespace,
+	// so that consumers expecting different shapes share one credential.
+	// +optional
+	Targets framework.OutputTargets `json:"targets,omitempty"`
+
+	// Replication copies the output Secret into all namespaces matching a
+	// label selector that allow it, and keeps the copies in sync.
+	// +optional
+	Replication *framework.Replication `json:"replication,omitempty"`
+
+	// UserName is the name of the IAM user to create access keys for.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	UserName string `json:"userName"`
+
+	// Validity is how long each access key is used before it is rotated and
+	// deleted. IAM access keys do not expire on their own, so the operator
+	// enforces this lifetime. Defaults to 90 days (2160h).
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// Rotation configures how credentials are rotated, e.g. how long a
+	// superseded credential stays valid.
+	// +optional
+	Rotation framework.RotationPolicy `json:"rotation,omitzero"`
+
+	// Template maps output secret keys to Go template strings.
+	// Available template variables: .AccessKeyID, .SecretAccessKey, .UserName
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinProperties=1
+	Template map[string]string `json:"template"`
+}
+
+// GetSecretRef returns the reference to the target output Secret, defaulting
+// to a Secret named after the resource.
+func (a *AWSAccessKey) GetSecretRef() framework.SecretReference {
+	return a.Spec.SecretRef.OrDefault(a.Name)
+}
+
+// IsSuspended reports whether the rotation of the credentials is suspended.
+// It implements [framework.Suspender].
+func (a *AWSAccessKey) IsSuspended() bool {
+	return a.Spec.Suspend
+}
+
+// GetDeletionPolicy returns what happens to the keys and the output Secret
+// when the resource is deleted. It implements [framework.DeletionPolicyHolder].
+func (a *AWSAccessKey) GetDeletionPolicy() framework.DeletionPolicy {
+	return a.Spec.DeletionPolicy
+}
+
+// GetSecretTemplate returns the labels, annotations and type of the output
+// Secret. It implements [framework.SecretTemplateHolder].
+func (a *AWSAccessKey) GetSecretTemplate() framework.SecretTemplate {
+	return a.Spec.SecretTemplate
+}
+
+// GetTargets returns the further Secrets the credentials are written to.
+// It implements [framework.TargetHolder].
+func (a *AWSAccessKey) GetTargets() framework.OutputTargets {
+	return a.Spec.Targets
+}
+
+// GetReplication returns the replication of the output Secret, or nil.
+// It implements [framework.ReplicationHolder].
+func (a *AWSAccessKey) GetReplication() *framework.Replication {
+	return a.Spec.Replication
+}
+
+// GetValidity returns the requested validity of the credentials, or zero for
+// the provider's default. It implements [framework.ValidityRequester].
+func (a *AWSAccessKey) GetValidity() time.Duration {
+	if a.Spec.Validity == nil {
+		return 0
+	}
+	return a.Spec.Validity.Duration
+}
+
+// GetRotationPolicy returns the rotation policy of the credentials. It
+// implements [framework.RotationPolicyHolder].
+func (a *AWSAccessKey) GetRotationPolicy() framework.RotationPolicy {
+	return a.Spec.Rotation
+}
+
+// GetStatus returns a pointer to the shared status.
+func (a *AWSAccessKey) GetStatus() *framework.ClientSecretStatus {
+	return &a.Status
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (a *AWSAccessKey) DeepCopyObject() runtime.Object {
+	cp := *a
+	cp.ObjectMeta = *a.DeepCopy()
+	cp.Status = a.Status.DeepCopy()
+	cp.Spec.SecretTemplate = a.Spec.SecretTemplate.DeepCopy()
+	cp.Spec.Targets = a.Spec.Targets.DeepCopy()
+	cp.Spec.Replication = a.Spec.Replication.DeepCopy()
+	if a.Spec.Template != nil {
+		cp.Spec.Template = make(map[string]string, len(a.Spec.Template))
+		for k, v := range a.Spec.Template {
+			cp.Spec.Template[k] = v
+		}
+	}
+	if a.Spec.Validity != nil {
+		v := *a.Spec.Validity
+		cp.Spec.Validity = &v
+	}
+	return &cp
+}
+
+// Validate performs structural validation of the spec.
+func (a *AWSAccessKey) Validate() error {
+	if a.Spec.UserName == "" {
+		return fmt.Errorf("userName is required")
+	}
+	if a.Spec.Validity != nil && a.Spec.Validity.Duration <= 0 {
+		return fmt.Errorf("validity must be positive")
+	}
+	if len(a.Spec.Template) == 0 {
+		return fmt.Errorf("template must have at least one entry")
+	}
+	for key, tmpl := range a.Spec.Template {
+		if _, err := template.New(key).Parse(tmpl); err != nil {
+			return fmt.Errorf("template %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:object:root=true
+
+// AWSAccessKeyList contains a list of AWSAccessKey resources.
+type AWSAccessKeyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AWSAccessKey `json:"items"`
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (a *AWSAccessKeyList) DeepCopyObject() runtime.Object {
+	cp := *a
+	if a.Items != nil {
+		cp.Items = make([]AWSAccessKey, len(a.Items))
+		for i := range a.Items {
+			cp.Items[i] = *a.Items[i].DeepCopyObject().(*AWSAccessKey)
+		}
+	}
+	return &cp
+}
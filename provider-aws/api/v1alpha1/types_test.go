@@ -0,0 +1,114 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	valid := &AWSAccessKey{
+		Spec: AWSAccessKeySpec{
+			SecretRef: framework.SecretReference{Name: "out"},
+			UserName:  "svc-user",
+			Template:  map[string]string{"KEY": "{{ .SecretAccessKey }}"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*AWSAccessKey)
+		wantErr string
+	}{
+		{name: "valid", modify: func(_ *AWSAccessKey) {}},
+		{
+			name:   "omitted secretRef",
+			modify: func(a *AWSAccessKey) { a.Spec.SecretRef.Name = "" },
+		},
+		{
+			name:    "missing userName",
+			modify:  func(a *AWSAccessKey) { a.Spec.UserName = "" },
+			wantErr: "userName",
+		},
+		{
+			name: "non-positive validity",
+			modify: func(a *AWSAccessKey) {
+				a.Spec.Validity = &metav1.Duration{Duration: -time.Hour}
+			},
+			wantErr: "validity",
+		},
+		{
+			name:    "empty template",
+			modify:  func(a *AWSAccessKey) { a.Spec.Template = nil },
+			wantErr: "template",
+		},
+		{
+			name:    "invalid template syntax",
+			modify:  func(a *AWSAccessKey) { a.Spec.Template = map[string]string{"bad": "{{ .Foo"} },
+			wantErr: "template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := valid.DeepCopyObject().(*AWSAccessKey)
+			tt.modify(obj)
+			err := obj.Validate()
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if got := err.Error(); !strings.Contains(got, tt.wantErr) {
+				t.Fatalf("error %q does not contain %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeepCopyObject(t *testing.T) {
+	validity := metav1.Duration{Duration: 48 * time.Hour}
+	obj := &AWSAccessKey{
+		Spec: AWSAccessKeySpec{
+			SecretRef: framework.SecretReference{Name: "s"},
+			UserName:  "u",
+			Template:  map[string]string{"K": "V"},
+			Validity:  &validity,
+		},
+	}
+	obj.Status.Phase = framework.PhaseReady
+
+	cp := obj.DeepCopyObject().(*AWSAccessKey)
+
+	cp.Spec.Template["K"] = "changed"
+	if obj.Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not copy template map")
+	}
+
+	cp.Spec.Validity.Duration = time.Hour
+	if obj.Spec.Validity.Duration != 48*time.Hour {
+		t.Fatal("DeepCopyObject did not copy validity")
+	}
+}
+
+func TestDeepCopyObjectList(t *testing.T) {
+	list := &AWSAccessKeyList{
+		Items: []AWSAccessKey{
+			{Spec: AWSAccessKeySpec{UserName: "a"}},
+		},
+	}
+
+	cp := list.DeepCopyObject().(*AWSAccessKeyList)
+	cp.Items[0].Spec.UserName = "changed"
+	if list.Items[0].Spec.UserName != "a" {
+		t.Fatal("DeepCopyObject did not deep copy list items")
+	}
+}
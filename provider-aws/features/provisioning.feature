@@ -0,0 +1,109 @@
+Feature: AWS Access Key Provisioning
+  As a platform operator
+  I want the AWS provider to provision and rotate IAM access keys
+  So that applications can authenticate with AWS services
+
+  Background:
+    Given a Kubernetes cluster is running
+    And the CRDs are installed
+    And the operator is running
+
+  Scenario: Provision an access key successfully
+    When I create a ClientSecret "test-key" with:
+      """yaml
+      spec:
+        secretRef:
+          name: test-key
+        userName: "$TEST_AWS_USER_NAME"
+        template:
+          AWS_ACCESS_KEY_ID: "{{ .AccessKeyID }}"
+          AWS_SECRET_ACCESS_KEY: "{{ .SecretAccessKey }}"
+      """
+    Then the ClientSecret "test-key" should have phase "Ready" within 60 seconds
+    And a Secret "test-key" should exist
+    And the Secret "test-key" should contain key "AWS_ACCESS_KEY_ID"
+    And the Secret "test-key" should contain key "AWS_SECRET_ACCESS_KEY"
+
+  Scenario: Invalid template syntax is rejected
+    When I create a ClientSecret "bad-template" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-template
+        userName: "$TEST_AWS_USER_NAME"
+        template:
+          SECRET: "{{ .Invalid"
+      """
+    Then the ClientSecret "bad-template" should have phase "Failed" within 60 seconds
+    And the ClientSecret "bad-template" status should contain message "template"
+    And the Secret "bad-template" should not exist
+
+  Scenario: Delete AWSAccessKey cleans up resources
+    When I create a ClientSecret "delete-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: delete-test
+        userName: "$TEST_AWS_USER_NAME"
+        template:
+          AWS_SECRET_ACCESS_KEY: "{{ .SecretAccessKey }}"
+      """
+    Then the ClientSecret "delete-test" should have phase "Ready" within 60 seconds
+    And a Secret "delete-test" should exist
+    When I delete the ClientSecret "delete-test"
+    Then the ClientSecret "delete-test" should not exist within 60 seconds
+
+  Scenario: Expired access keys are rotated
+    When I create a ClientSecret "rotation-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rotation-test
+        userName: "$TEST_AWS_USER_NAME"
+        template:
+          AWS_SECRET_ACCESS_KEY: "{{ .SecretAccessKey }}"
+      """
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the ClientSecret "rotation-test" should have 1 active keys
+    When I expire the credentials for ClientSecret "rotation-test"
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+
+  Scenario: Missing userName is rejected
+    When I try to create a ClientSecret "no-user" with:
+      """yaml
+      spec:
+        secretRef:
+          name: no-user
+        template:
+          AWS_SECRET_ACCESS_KEY: "{{ .SecretAccessKey }}"
+      """
+    Then the operation should have failed with "userName"
+
+  Scenario: Secret is owned by the AWSAccessKey
+    When I create a ClientSecret "ownership-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: ownership-test
+        userName: "$TEST_AWS_USER_NAME"
+        template:
+          AWS_SECRET_ACCESS_KEY: "{{ .SecretAccessKey }}"
+      """
+    Then the ClientSecret "ownership-test" should have phase "Ready" within 60 seconds
+    And the Secret "ownership-test" should be owned by ClientSecret "ownership-test"
+
+  @mock
+  Scenario: Template renders with correct values
+    When I create a ClientSecret "value-check" with:
+      """yaml
+      spec:
+        secretRef:
+          name: value-check
+        userName: "$TEST_AWS_USER_NAME"
+        template:
+          AWS_SECRET_ACCESS_KEY: "{{ .SecretAccessKey }}"
+          USER: "{{ .UserName }}"
+      """
+    Then the ClientSecret "value-check" should have phase "Ready" within 60 seconds
+    And the Secret "value-check" should contain key "AWS_SECRET_ACCESS_KEY" with value "fake-secret-access-key"
+    And the Secret "value-check" should contain key "USER" with value "$TEST_AWS_USER_NAME"
@@ -0,0 +1,95 @@
+{ inputs, ... }:
+{
+  perSystem =
+    { config, pkgs, ... }:
+    let
+      valet = config.valet.lib;
+
+      provider-aws = valet.mkGoModule {
+        pname = "provider-aws";
+        subPackages = [ "provider-aws/cmd" ];
+        postInstall = ''
+          mv $out/bin/cmd $out/bin/provider-aws
+        '';
+        meta.mainProgram = "provider-aws";
+      };
+
+      provider-aws-compressed = pkgs.stdenvNoCC.mkDerivation {
+        inherit (provider-aws) pname version meta;
+        dontUnpack = true;
+        nativeBuildInputs = [ pkgs.upx ];
+        buildPhase = ''
+          mkdir -p $out/bin
+          upx -o $out/bin/provider-aws ${provider-aws}/bin/provider-aws
+        '';
+      };
+
+      image = valet.mkImage {
+        name = "provider-aws";
+        entrypoint = "${provider-aws-compressed}/bin/provider-aws";
+      };
+      e2e-test-aws = pkgs.writeShellApplication {
+        name = "e2e-test-aws";
+        runtimeInputs = [
+          pkgs.go
+          pkgs.gotestsum
+        ];
+        text = ''
+          export GOFLAGS="-mod=vendor"
+          if [ ! -d vendor ]; then
+            ln -sfn ${valet.workspaceVendor} vendor
+          fi
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum \
+            --format "''${GOTESTSUM_FORMAT:-short-verbose}" \
+            -- -run TestE2E -timeout 10m \
+            -coverpkg=github.com/lukasngl/valet/framework/...,./... \
+            -coverprofile="''${COVERAGE_FILE:-coverage-aws-e2e.txt}" \
+            ./provider-aws/...
+        '';
+      };
+    in
+    {
+      packages = {
+        inherit provider-aws provider-aws-compressed;
+        provider-aws-image = image;
+      };
+
+      apps.e2e-test-aws = {
+        type = "app";
+        program = "${e2e-test-aws}/bin/e2e-test-aws";
+      };
+
+      checks.provider-aws-helm = valet.packageChart {
+        name = "provider-aws";
+        src = "${inputs.self}/provider-aws/charts/provider-aws";
+      };
+
+      checks.provider-aws-lint = valet.withPackageEnv provider-aws {
+        name = "provider-aws-lint";
+        extraBuildInputs = [ pkgs.golangci-lint ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          golangci-lint run --timeout 10m ./provider-aws/...
+        '';
+      };
+
+      checks.provider-aws-test = valet.withPackageEnv provider-aws {
+        name = "provider-aws-test";
+        extraBuildInputs = [
+          pkgs.gotestsum
+          pkgs.etcd
+          pkgs.kubernetes
+        ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum --format short-verbose -- -short -coverpkg=github.com/lukasngl/valet/framework/...,./... -coverprofile=coverage.txt ./provider-aws/...
+        '';
+        installPhase = ''
+          mkdir -p $out
+          cp coverage.txt $out/
+        '';
+      };
+    };
+}
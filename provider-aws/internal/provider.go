@@ -0,0 +1,239 @@
+// Package internal contains the AWS provider implementation.
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-aws/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultValidity is the default access key rotation period (90 days).
+const DefaultValidity = 90 * 24 * time.Hour
+
+// IAMClient is the subset of the IAM API used by the provider.
+// It is satisfied by [*iam.Client].
+type IAMClient interface {
+	CreateAccessKey(
+		ctx context.Context,
+		params *iam.CreateAccessKeyInput,
+		optFns ...func(*iam.Options),
+	) (*iam.CreateAccessKeyOutput, error)
+	DeleteAccessKey(
+		ctx context.Context,
+		params *iam.DeleteAccessKeyInput,
+		optFns ...func(*iam.Options),
+	) (*iam.DeleteAccessKeyOutput, error)
+	GetAccessKeyLastUsed(
+		ctx context.Context,
+		params *iam.GetAccessKeyLastUsedInput,
+		optFns ...func(*iam.Options),
+	) (*iam.GetAccessKeyLastUsedOutput, error)
+}
+
+// Provider provisions AWS IAM access keys.
+// It implements [framework.Provider] and [framework.UsageReporter] for
+// [*v1alpha1.AWSAccessKey].
+type Provider struct {
+	client   IAMClient
+	initOnce sync.Once
+	initErr  error
+}
+
+// Option configures a [Provider].
+type Option func(*Provider)
+
+// WithIAMClient sets a custom IAM client, skipping AWS config loading.
+// Useful for testing with a fake client.
+func WithIAMClient(c IAMClient) Option {
+	return func(p *Provider) { p.client = c }
+}
+
+// New creates a [Provider] with the given options.
+func New(opts ...Option) *Provider {
+	p := &Provider{}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// NewObject returns a zero-value AWSAccessKey.
+func (p *Provider) NewObject() *v1alpha1.AWSAccessKey {
+	return &v1alpha1.AWSAccessKey{}
+}
+
+// Provision creates a new access key for an IAM user.
+func (p *Provider) Provision(
+	ctx context.Context,
+	obj *v1alpha1.AWSAccessKey,
+) (*framework.Result, error) {
+	if err := p.initClient(ctx); err != nil {
+		return nil, err
+	}
+
+	validity := DefaultValidity
+	if obj.Spec.Validity != nil {
+		validity = obj.Spec.Validity.Duration
+	}
+
+	out, err := p.client.CreateAccessKey(ctx, &iam.CreateAccessKeyInput{
+		UserName: aws.String(obj.Spec.UserName),
+	})
+	if err != nil {
+		var limitErr *types.LimitExceededException
+		if errors.As(err, &limitErr) {
+			return nil, fmt.Errorf(
+				"creating access key for user %s: user already has the maximum number "+
+					"of access keys, delete an unmanaged key or wait for the next cleanup: %w",
+				obj.Spec.UserName, err)
+		}
+		return nil, fmt.Errorf("creating access key for user %s: %w", obj.Spec.UserName, err)
+	}
+
+	if out.AccessKey == nil || aws.ToString(out.AccessKey.SecretAccessKey) == "" {
+		return nil, errors.New("no secret access key returned from IAM API")
+	}
+
+	now := time.Now()
+	accessKeyID := aws.ToString(out.AccessKey.AccessKeyId)
+
+	templateData := map[string]string{
+		"AccessKeyID":     accessKeyID,
+		"SecretAccessKey": aws.ToString(out.AccessKey.SecretAccessKey),
+		"UserName":        obj.Spec.UserName,
+	}
+
+	data := make(map[string]string, len(obj.Spec.Template))
+	for key, tmpl := range obj.Spec.Template {
+		rendered, err := renderTemplate(tmpl, templateData)
+		if err != nil {
+			return nil, fmt.Errorf("rendering template %q: %w", key, err)
+		}
+		data[key] = rendered
+	}
+
+	return &framework.Result{
+		StringData:    data,
+		ProvisionedAt: now,
+		ValidUntil:    now.Add(validity),
+		KeyID:         accessKeyID,
+	}, nil
+}
+
+// DeleteKey deletes an access key from an IAM user.
+// Returns nil if the key has already been deleted (idempotent).
+func (p *Provider) DeleteKey(
+	ctx context.Context,
+	obj *v1alpha1.AWSAccessKey,
+	keyID string,
+) error {
+	if keyID == "" {
+		return nil
+	}
+
+	if err := p.initClient(ctx); err != nil {
+		return err
+	}
+
+	_, err := p.client.DeleteAccessKey(ctx, &iam.DeleteAccessKeyInput{
+		UserName:    aws.String(obj.Spec.UserName),
+		AccessKeyId: aws.String(keyID),
+	})
+	if err != nil {
+		// Key already deleted at the provider — not an error.
+		var notFound *types.NoSuchEntityException
+		if errors.As(err, &notFound) {
+			log.FromContext(ctx).
+				Info("key already deleted", "keyId", keyID, "userName", obj.Spec.UserName)
+			return nil
+		}
+		return fmt.Errorf("deleting access key %s of user %s: %w",
+			keyID, obj.Spec.UserName, err)
+	}
+
+	return nil
+}
+
+// LastUsed returns when an access key was last used to sign a request, as
+// tracked by IAM, or the zero time if it has not been used.
+func (p *Provider) LastUsed(
+	ctx context.Context,
+	_ *v1alpha1.AWSAccessKey,
+	keyID string,
+) (time.Time, error) {
+	if err := p.initClient(ctx); err != nil {
+		return time.Time{}, err
+	}
+
+	out, err := p.client.GetAccessKeyLastUsed(ctx, &iam.GetAccessKeyLastUsedInput{
+		AccessKeyId: aws.String(keyID),
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("getting last use of access key %s: %w", keyID, err)
+	}
+	if out.AccessKeyLastUsed == nil {
+		return time.Time{}, nil
+	}
+
+	return aws.ToTime(out.AccessKeyLastUsed.LastUsedDate), nil
+}
+
+// Endpoints returns the IAM endpoint and the regional STS endpoint, which
+// IRSA credentials are exchanged at. It implements
+// [framework.EndpointDeclarer].
+func (p *Provider) Endpoints() ([]framework.Endpoint, error) {
+	sts := "sts.amazonaws.com"
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		sts = "sts." + region + ".amazonaws.com"
+	}
+	return []framework.Endpoint{
+		{Name: "AWS IAM", Scheme: "https", Host: "iam.amazonaws.com", Port: 443},
+		{Name: "AWS STS", Scheme: "https", Host: sts, Port: 443},
+	}, nil
+}
+
+// initClient loads the default AWS config and creates the IAM client on
+// first use. If the client was pre-configured via [WithIAMClient],
+// initialization is skipped (no AWS credentials required).
+func (p *Provider) initClient(ctx context.Context) error {
+	p.initOnce.Do(func() {
+		if p.client != nil {
+			return // pre-configured, e.g. for testing
+		}
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			p.initErr = fmt.Errorf("loading AWS config: %w", err)
+			return
+		}
+		p.client = iam.NewFromConfig(cfg)
+	})
+	return p.initErr
+}
+
+// renderTemplate renders a Go template string with the given data.
+func renderTemplate(tmpl string, data map[string]string) (string, error) {
+	t, err := template.New("").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
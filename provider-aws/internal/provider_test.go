@@ -0,0 +1,280 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/lukasngl/valet/provider-aws/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeIAM is an in-memory [IAMClient] for tests.
+type fakeIAM struct {
+	createOut *iam.CreateAccessKeyOutput
+	createErr error
+	deleteErr error
+	lastUsed  *types.AccessKeyLastUsed
+	usedErr   error
+
+	createdFor []string
+	deleted    []string
+}
+
+func (f *fakeIAM) CreateAccessKey(
+	_ context.Context,
+	in *iam.CreateAccessKeyInput,
+	_ ...func(*iam.Options),
+) (*iam.CreateAccessKeyOutput, error) {
+	f.createdFor = append(f.createdFor, aws.ToString(in.UserName))
+	return f.createOut, f.createErr
+}
+
+func (f *fakeIAM) DeleteAccessKey(
+	_ context.Context,
+	in *iam.DeleteAccessKeyInput,
+	_ ...func(*iam.Options),
+) (*iam.DeleteAccessKeyOutput, error) {
+	f.deleted = append(f.deleted, aws.ToString(in.AccessKeyId))
+	return &iam.DeleteAccessKeyOutput{}, f.deleteErr
+}
+
+func (f *fakeIAM) GetAccessKeyLastUsed(
+	_ context.Context,
+	_ *iam.GetAccessKeyLastUsedInput,
+	_ ...func(*iam.Options),
+) (*iam.GetAccessKeyLastUsedOutput, error) {
+	return &iam.GetAccessKeyLastUsedOutput{AccessKeyLastUsed: f.lastUsed}, f.usedErr
+}
+
+func accessKeyOutput(id, secret string) *iam.CreateAccessKeyOutput {
+	return &iam.CreateAccessKeyOutput{
+		AccessKey: &types.AccessKey{
+			AccessKeyId:     aws.String(id),
+			SecretAccessKey: aws.String(secret),
+			UserName:        aws.String("svc-user"),
+		},
+	}
+}
+
+func newObj(template map[string]string) *v1alpha1.AWSAccessKey {
+	return &v1alpha1.AWSAccessKey{
+		Spec: v1alpha1.AWSAccessKeySpec{
+			UserName: "svc-user",
+			Template: template,
+		},
+	}
+}
+
+func TestProvision(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		fake := &fakeIAM{createOut: accessKeyOutput("AKIA123", "s3cret")}
+		p := New(WithIAMClient(fake))
+
+		result, err := p.Provision(context.Background(), newObj(map[string]string{
+			"AWS_ACCESS_KEY_ID":     "{{ .AccessKeyID }}",
+			"AWS_SECRET_ACCESS_KEY": "{{ .SecretAccessKey }}",
+			"USER":                  "{{ .UserName }}",
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.KeyID != "AKIA123" {
+			t.Fatalf("got keyID %q, want %q", result.KeyID, "AKIA123")
+		}
+		if got := result.StringData["AWS_ACCESS_KEY_ID"]; got != "AKIA123" {
+			t.Fatalf("got AWS_ACCESS_KEY_ID %q, want %q", got, "AKIA123")
+		}
+		if got := result.StringData["AWS_SECRET_ACCESS_KEY"]; got != "s3cret" {
+			t.Fatalf("got AWS_SECRET_ACCESS_KEY %q, want %q", got, "s3cret")
+		}
+		if got := result.StringData["USER"]; got != "svc-user" {
+			t.Fatalf("got USER %q, want %q", got, "svc-user")
+		}
+		if len(fake.createdFor) != 1 || fake.createdFor[0] != "svc-user" {
+			t.Fatalf("expected one CreateAccessKey call for svc-user, got %v", fake.createdFor)
+		}
+	})
+
+	t.Run("default validity", func(t *testing.T) {
+		p := New(WithIAMClient(&fakeIAM{createOut: accessKeyOutput("AKIA123", "s3cret")}))
+		result, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != DefaultValidity {
+			t.Fatalf("got validity %v, want %v", got, DefaultValidity)
+		}
+	})
+
+	t.Run("custom validity", func(t *testing.T) {
+		p := New(WithIAMClient(&fakeIAM{createOut: accessKeyOutput("AKIA123", "s3cret")}))
+		obj := newObj(map[string]string{"K": "v"})
+		obj.Spec.Validity = &metav1.Duration{Duration: 48 * time.Hour}
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != 48*time.Hour {
+			t.Fatalf("got validity %v, want %v", got, 48*time.Hour)
+		}
+	})
+
+	t.Run("empty secret", func(t *testing.T) {
+		p := New(WithIAMClient(&fakeIAM{createOut: accessKeyOutput("AKIA123", "")}))
+		_, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if err == nil {
+			t.Fatal("expected error for empty secret")
+		}
+		if !strings.Contains(err.Error(), "no secret access key") {
+			t.Fatalf("expected 'no secret access key' error, got: %v", err)
+		}
+	})
+
+	t.Run("limit exceeded", func(t *testing.T) {
+		p := New(WithIAMClient(&fakeIAM{
+			createErr: &types.LimitExceededException{Message: aws.String("limit")},
+		}))
+		_, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "maximum number of access keys") {
+			t.Fatalf("expected limit error, got: %v", err)
+		}
+	})
+
+	t.Run("API error propagates", func(t *testing.T) {
+		p := New(WithIAMClient(&fakeIAM{createErr: errors.New("access denied")}))
+		_, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "creating access key for user svc-user") {
+			t.Fatalf("expected wrapped error, got: %v", err)
+		}
+	})
+
+	t.Run("bad template", func(t *testing.T) {
+		p := New(WithIAMClient(&fakeIAM{createOut: accessKeyOutput("AKIA123", "s3cret")}))
+		_, err := p.Provision(context.Background(), newObj(map[string]string{
+			"BAD": "{{ .Unclosed",
+		}))
+		if err == nil {
+			t.Fatal("expected template error")
+		}
+		if !strings.Contains(err.Error(), "rendering template") {
+			t.Fatalf("expected 'rendering template' error, got: %v", err)
+		}
+	})
+}
+
+func TestDeleteKey(t *testing.T) {
+	t.Run("empty keyID is a no-op", func(t *testing.T) {
+		fake := &fakeIAM{}
+		p := New(WithIAMClient(fake))
+		if err := p.DeleteKey(context.Background(), newObj(nil), ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(fake.deleted) != 0 {
+			t.Fatalf("expected no DeleteAccessKey calls, got %v", fake.deleted)
+		}
+	})
+
+	t.Run("happy path", func(t *testing.T) {
+		fake := &fakeIAM{}
+		p := New(WithIAMClient(fake))
+		if err := p.DeleteKey(context.Background(), newObj(nil), "AKIA123"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(fake.deleted) != 1 || fake.deleted[0] != "AKIA123" {
+			t.Fatalf("expected AKIA123 to be deleted, got %v", fake.deleted)
+		}
+	})
+
+	t.Run("already deleted is idempotent", func(t *testing.T) {
+		p := New(WithIAMClient(&fakeIAM{
+			deleteErr: &types.NoSuchEntityException{Message: aws.String("not found")},
+		}))
+		if err := p.DeleteKey(context.Background(), newObj(nil), "gone-key"); err != nil {
+			t.Fatalf("expected nil for already-deleted key, got: %v", err)
+		}
+	})
+
+	t.Run("other error propagates", func(t *testing.T) {
+		p := New(WithIAMClient(&fakeIAM{deleteErr: errors.New("internal")}))
+		err := p.DeleteKey(context.Background(), newObj(nil), "AKIA123")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "deleting access key AKIA123") {
+			t.Fatalf("expected 'deleting access key' error, got: %v", err)
+		}
+	})
+}
+
+func TestLastUsed(t *testing.T) {
+	t.Run("used key", func(t *testing.T) {
+		used := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+		p := New(WithIAMClient(&fakeIAM{lastUsed: &types.AccessKeyLastUsed{LastUsedDate: aws.Time(used)}}))
+		got, err := p.LastUsed(context.Background(), newObj(nil), "AKIA123")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !got.Equal(used) {
+			t.Fatalf("got last use %v, want %v", got, used)
+		}
+	})
+
+	t.Run("unused key", func(t *testing.T) {
+		p := New(WithIAMClient(&fakeIAM{lastUsed: &types.AccessKeyLastUsed{ServiceName: aws.String("N/A")}}))
+		got, err := p.LastUsed(context.Background(), newObj(nil), "AKIA123")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !got.IsZero() {
+			t.Fatalf("expected zero time for unused key, got %v", got)
+		}
+	})
+
+	t.Run("error propagates", func(t *testing.T) {
+		p := New(WithIAMClient(&fakeIAM{usedErr: errors.New("internal")}))
+		_, err := p.LastUsed(context.Background(), newObj(nil), "AKIA123")
+		if err == nil || !strings.Contains(err.Error(), "getting last use of access key AKIA123") {
+			t.Fatalf("expected wrapped error, got: %v", err)
+		}
+	})
+}
+
+func TestInitClient(t *testing.T) {
+	t.Run("skips when client is pre-configured", func(t *testing.T) {
+		fake := &fakeIAM{}
+		p := New(WithIAMClient(fake))
+		if err := p.initClient(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p.client != fake {
+			t.Fatal("expected pre-configured client to be kept")
+		}
+	})
+
+	t.Run("loads config from environment", func(t *testing.T) {
+		t.Setenv("AWS_ACCESS_KEY_ID", "fake-id")
+		t.Setenv("AWS_SECRET_ACCESS_KEY", "fake-secret")
+		t.Setenv("AWS_REGION", "us-east-1")
+
+		p := New()
+		if err := p.initClient(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p.client == nil {
+			t.Fatal("expected IAM client to be created")
+		}
+	})
+}
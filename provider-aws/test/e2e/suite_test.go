@@ -0,0 +1,156 @@
+package e2e
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/cucumber/godog"
+	"github.com/cucumber/godog/colors"
+	"github.com/google/uuid"
+	"github.com/lukasngl/valet/framework/bddtest"
+	"github.com/lukasngl/valet/provider-aws/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-aws/internal"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var godogOpts = godog.Options{
+	Format:      "pretty",
+	Output:      colors.Colored(os.Stdout),
+	Paths:       []string{"../../features"},
+	Concurrency: 1,
+	Strict:      true,
+}
+
+func init() {
+	godog.BindFlags("godog.", flag.CommandLine, &godogOpts)
+}
+
+var testEnvCfg bddtest.Env
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	if len(flag.Args()) > 0 {
+		godogOpts.Paths = flag.Args()
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	testEnvCfg.Scheme = runtime.NewScheme()
+	_ = corev1.AddToScheme(testEnvCfg.Scheme)
+	_ = v1alpha1.AddToScheme(testEnvCfg.Scheme)
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths: []string{"../../config/crd"},
+		Scheme:            testEnvCfg.Scheme,
+	}
+	env.ControlPlane.GetAPIServer().Configure().
+		Append("advertise-address", "127.0.0.1").
+		Append("bind-address", "127.0.0.1")
+
+	cfg, err := env.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start envtest: %v\n", err)
+		os.Exit(1)
+	}
+	testEnvCfg.Cfg = cfg
+
+	code := m.Run()
+
+	_ = env.Stop()
+	os.Exit(code)
+}
+
+// TestMock runs all scenarios against an in-memory IAM client.
+func TestMock(t *testing.T) {
+	t.Setenv("TEST_AWS_USER_NAME", "valet-test-user")
+
+	opts := godogOpts
+	status := godog.TestSuite{
+		Name: "provider-aws-mock",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New(internal.WithIAMClient(&iamMock{}))
+			shared := bddtest.New[*v1alpha1.AWSAccessKey](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// TestE2E runs non-mock scenarios against a real AWS account.
+func TestE2E(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e tests in short mode")
+	}
+
+	if os.Getenv("TEST_AWS_USER_NAME") == "" {
+		t.Skip("skipping e2e tests: TEST_AWS_USER_NAME not set")
+	}
+
+	opts := godogOpts
+	opts.Tags = "~@mock"
+	status := godog.TestSuite{
+		Name: "provider-aws-e2e",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New()
+			shared := bddtest.New[*v1alpha1.AWSAccessKey](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// iamMock is an [internal.IAMClient] that returns canned IAM responses.
+// Each CreateAccessKey call returns a unique access key ID and a fixed
+// secret; DeleteAccessKey always succeeds and no key is ever used.
+type iamMock struct{}
+
+func (m *iamMock) CreateAccessKey(
+	_ context.Context,
+	in *iam.CreateAccessKeyInput,
+	_ ...func(*iam.Options),
+) (*iam.CreateAccessKeyOutput, error) {
+	id := "AKIA" + strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", "")[:16])
+	return &iam.CreateAccessKeyOutput{
+		AccessKey: &types.AccessKey{
+			AccessKeyId:     aws.String(id),
+			SecretAccessKey: aws.String("fake-secret-access-key"),
+			UserName:        in.UserName,
+		},
+	}, nil
+}
+
+func (m *iamMock) DeleteAccessKey(
+	_ context.Context,
+	_ *iam.DeleteAccessKeyInput,
+	_ ...func(*iam.Options),
+) (*iam.DeleteAccessKeyOutput, error) {
+	return &iam.DeleteAccessKeyOutput{}, nil
+}
+
+func (m *iamMock) GetAccessKeyLastUsed(
+	_ context.Context,
+	_ *iam.GetAccessKeyLastUsedInput,
+	_ ...func(*iam.Options),
+) (*iam.GetAccessKeyLastUsedOutput, error) {
+	return &iam.GetAccessKeyLastUsedOutput{AccessKeyLastUsed: &types.AccessKeyLastUsed{}}, nil
+}
@@ -3,7 +3,9 @@ package v1alpha1
 import (
 	"fmt"
 	"text/template"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/lukasngl/valet/framework"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -15,7 +17,7 @@ func init() {
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
-// +kubebuilder:resource:shortName=acs
+// +kubebuilder:resource:shortName=acs,categories=valet
 // +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
 
@@ -32,28 +34,205 @@ type AzureClientSecret struct {
 // AzureClientSecretSpec defines the desired state.
 type AzureClientSecretSpec struct {
 	// SecretRef is the Kubernetes Secret to create/update with the provisioned credentials.
-	SecretRef framework.SecretReference `json:"secretRef"`
+	// Defaults to a Secret named after the resource.
+	// +optional
+	SecretRef framework.SecretReference `json:"secretRef,omitzero"`
 
-	// ObjectID is the Azure AD application Object ID.
-	// +kubebuilder:validation:Required
-	// +kubebuilder:validation:MinLength=1
-	ObjectID string `json:"objectId"`
+	// Suspend stops the rotation and cleanup of the credentials while set,
+	// e.g. during incidents or migrations. The output Secret keeps its
+	// current credentials.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DeletionPolicy controls what happens when the resource is deleted:
+	// Delete deletes its keys at the provider and the output Secret with it,
+	// Retain keeps both, e.g. to move the resource to another cluster.
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy framework.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// SecretTemplate sets labels, annotations and the type of the output
+	// Secret, e.g. for Reloader, External Secrets or Argo CD to match on.
+	// +optional
+	SecretTemplate framework.SecretTemplate `json:"secretTemplate,omitzero"`
+
+	// Targets are further Secrets the credentials are written to, each
+	// with a subset of the keys, its own template or in another namespace,
+	// so that consumers expecting different shapes share one credential.
+	// +optional
+	Targets framework.OutputTargets `json:"targets,omitempty"`
+
+	// Replication copies the output Secret into all namespaces matching a
+	// label selector that allow it, and keeps the copies in sync.
+	// +optional
+	Replication *framework.Replication `json:"replication,omitempty"`
+
+	// ObjectID is the Object ID of the Azure AD application, or of the
+	// service principal if TargetType is ServicePrincipal. Exactly one of
+	// ObjectID and ClientID must be set.
+	// +optional
+	ObjectID string `json:"objectId,omitempty"`
+
+	// ClientID is the Application (client) ID of the Azure AD application,
+	// which the operator resolves to the Object ID of the application, or of
+	// its service principal if TargetType is ServicePrincipal. Exactly one of
+	// ObjectID and ClientID must be set.
+	// +optional
+	ClientID string `json:"clientId,omitempty"`
+
+	// TargetType is the kind of object credentials are added to:
+	// Application adds them to the app registration, ServicePrincipal to a
+	// service principal of it, e.g. in a tenant where the app registration
+	// cannot be managed. Defaults to Application.
+	// +kubebuilder:validation:Enum=Application;ServicePrincipal
+	// +optional
+	TargetType TargetType `json:"targetType,omitempty"`
+
+	// Cloud is the Azure cloud the application lives in: AzurePublic,
+	// AzureGovernment or AzureChina. It selects the Microsoft Graph endpoint
+	// and the Microsoft Entra authority tokens are requested from. Defaults
+	// to the operator's configuration, which is the public cloud unless
+	// changed.
+	// +kubebuilder:validation:Enum=AzurePublic;AzureGovernment;AzureChina
+	// +optional
+	Cloud Cloud `json:"cloud,omitempty"`
+
+	// CredentialsRef is a Secret in the same namespace holding the
+	// credentials of the service principal that manages the application, so
+	// that applications in other tenants than the operator's can be managed.
+	// Without it, the operator's own credentials from its environment are
+	// used.
+	// +optional
+	CredentialsRef *CredentialsReference `json:"credentialsRef,omitempty"`
 
 	// Validity is how long each provisioned credential should be valid.
 	// Defaults to 90 days (2160h).
 	// +optional
 	Validity *metav1.Duration `json:"validity,omitempty"`
 
+	// Rotation configures how credentials are rotated, e.g. how long a
+	// superseded credential stays valid.
+	// +optional
+	Rotation framework.RotationPolicy `json:"rotation,omitzero"`
+
+	// CredentialType is the kind of credential added to the application:
+	// Password adds client secrets, Certificate adds certificates for a key
+	// pair the operator generates. Certificates are written to the output
+	// Secret as tls.crt and tls.key, from where the next rotation reads them
+	// to prove possession to Microsoft Graph. Defaults to Password.
+	// +kubebuilder:validation:Enum=Password;Certificate
+	// +optional
+	CredentialType CredentialType `json:"credentialType,omitempty"`
+
 	// Template maps output secret keys to Go template strings.
-	// Available template variables: .ClientID, .ClientSecret
+	// Available template variables: .ClientID, .ClientSecret, .TenantID,
+	// .ObjectID, .ExpiresAt (RFC 3339), .KeyID, .Hint, and for certificates
+	// .Certificate, .PrivateKey (both PEM) and .Thumbprint.
+	// KeyID and Hint are the credential's ID and, for client secrets, the
+	// first characters of its value, as shown in the Azure portal.
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:MinProperties=1
 	Template map[string]string `json:"template"`
 }
 
-// GetSecretRef returns the reference to the target output Secret.
+// CredentialsReference selects a Secret in the namespace of the resource
+// holding the credentials of a service principal: its tenant ID under
+// "tenantId", its client ID under "clientId", and either a client secret
+// under "clientSecret" or a federated token under "federatedToken". Without
+// either, the operator's workload identity token is presented to the
+// service principal, which needs a federated credential trusting it.
+type CredentialsReference struct {
+	// Name of the Secret.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// Keys of the Secret referenced by [CredentialsReference].
+const (
+	CredentialsTenantIDKey       = "tenantId"
+	CredentialsClientIDKey       = "clientId"
+	CredentialsClientSecretKey   = "clientSecret"
+	CredentialsFederatedTokenKey = "federatedToken"
+)
+
+// TargetType is the kind of object credentials are added to.
+type TargetType string
+
+// Target types.
+const (
+	TargetTypeApplication      TargetType = "Application"
+	TargetTypeServicePrincipal TargetType = "ServicePrincipal"
+)
+
+// Cloud is an Azure cloud.
+type Cloud string
+
+// Azure clouds.
+const (
+	CloudAzurePublic     Cloud = "AzurePublic"
+	CloudAzureGovernment Cloud = "AzureGovernment"
+	CloudAzureChina      Cloud = "AzureChina"
+)
+
+// CredentialType is the kind of credential added to an application.
+type CredentialType string
+
+// Credential types.
+const (
+	CredentialTypePassword    CredentialType = "Password"
+	CredentialTypeCertificate CredentialType = "Certificate"
+)
+
+// GetSecretRef returns the reference to the target output Secret, defaulting
+// to a Secret named after the resource.
 func (a *AzureClientSecret) GetSecretRef() framework.SecretReference {
-	return a.Spec.SecretRef
+	return a.Spec.SecretRef.OrDefault(a.Name)
+}
+
+// IsSuspended reports whether the rotation of the credentials is suspended.
+// It implements [framework.Suspender].
+func (a *AzureClientSecret) IsSuspended() bool {
+	return a.Spec.Suspend
+}
+
+// GetDeletionPolicy returns what happens to the keys and the output Secret
+// when the resource is deleted. It implements [framework.DeletionPolicyHolder].
+func (a *AzureClientSecret) GetDeletionPolicy() framework.DeletionPolicy {
+	return a.Spec.DeletionPolicy
+}
+
+// GetSecretTemplate returns the labels, annotations and type of the output
+// Secret. It implements [framework.SecretTemplateHolder].
+func (a *AzureClientSecret) GetSecretTemplate() framework.SecretTemplate {
+	return a.Spec.SecretTemplate
+}
+
+// GetTargets returns the further Secrets the credentials are written to.
+// It implements [framework.TargetHolder].
+func (a *AzureClientSecret) GetTargets() framework.OutputTargets {
+	return a.Spec.Targets
+}
+
+// GetReplication returns the replication of the output Secret, or nil.
+// It implements [framework.ReplicationHolder].
+func (a *AzureClientSecret) GetReplication() *framework.Replication {
+	return a.Spec.Replication
+}
+
+// GetValidity returns the requested validity of the credentials, or zero for
+// the provider's default. It implements [framework.ValidityRequester].
+func (a *AzureClientSecret) GetValidity() time.Duration {
+	if a.Spec.Validity == nil {
+		return 0
+	}
+	return a.Spec.Validity.Duration
+}
+
+// GetRotationPolicy returns the rotation policy of the credentials. It
+// implements [framework.RotationPolicyHolder].
+func (a *AzureClientSecret) GetRotationPolicy() framework.RotationPolicy {
+	return a.Spec.Rotation
 }
 
 // GetStatus returns a pointer to the shared status.
@@ -66,12 +245,19 @@ func (a *AzureClientSecret) DeepCopyObject() runtime.Object {
 	cp := *a
 	cp.ObjectMeta = *a.DeepCopy()
 	cp.Status = a.Status.DeepCopy()
+	cp.Spec.SecretTemplate = a.Spec.SecretTemplate.DeepCopy()
+	cp.Spec.Targets = a.Spec.Targets.DeepCopy()
+	cp.Spec.Replication = a.Spec.Replication.DeepCopy()
 	if a.Spec.Template != nil {
 		cp.Spec.Template = make(map[string]string, len(a.Spec.Template))
 		for k, v := range a.Spec.Template {
 			cp.Spec.Template[k] = v
 		}
 	}
+	if a.Spec.CredentialsRef != nil {
+		ref := *a.Spec.CredentialsRef
+		cp.Spec.CredentialsRef = &ref
+	}
 	if a.Spec.Validity != nil {
 		v := *a.Spec.Validity
 		cp.Spec.Validity = &v
@@ -81,11 +267,33 @@ func (a *AzureClientSecret) DeepCopyObject() runtime.Object {
 
 // Validate performs structural validation of the spec.
 func (a *AzureClientSecret) Validate() error {
-	if a.Spec.SecretRef.Name == "" {
-		return fmt.Errorf("secretRef.name is required")
+	switch {
+	case a.Spec.ObjectID == "" && a.Spec.ClientID == "":
+		return fmt.Errorf("objectId or clientId is required")
+	case a.Spec.ObjectID != "" && a.Spec.ClientID != "":
+		return fmt.Errorf("objectId and clientId are mutually exclusive")
+	case a.Spec.ClientID != "":
+		if err := uuid.Validate(a.Spec.ClientID); err != nil {
+			return fmt.Errorf("clientId must be a GUID: %w", err)
+		}
+	}
+	if a.Spec.CredentialsRef != nil && a.Spec.CredentialsRef.Name == "" {
+		return fmt.Errorf("credentialsRef.name is required")
+	}
+	switch a.Spec.TargetType {
+	case "", TargetTypeApplication, TargetTypeServicePrincipal:
+	default:
+		return fmt.Errorf("targetType must be Application or ServicePrincipal, got %q", a.Spec.TargetType)
+	}
+	switch a.Spec.Cloud {
+	case "", CloudAzurePublic, CloudAzureGovernment, CloudAzureChina:
+	default:
+		return fmt.Errorf("cloud must be AzurePublic, AzureGovernment or AzureChina, got %q", a.Spec.Cloud)
 	}
-	if a.Spec.ObjectID == "" {
-		return fmt.Errorf("objectId is required")
+	switch a.Spec.CredentialType {
+	case "", CredentialTypePassword, CredentialTypeCertificate:
+	default:
+		return fmt.Errorf("credentialType must be Password or Certificate, got %q", a.Spec.CredentialType)
 	}
 	if len(a.Spec.Template) == 0 {
 		return fmt.Errorf("template must have at least one entry")
@@ -24,15 +24,70 @@ func TestValidate(t *testing.T) {
 	}{
 		{name: "valid", modify: func(_ *AzureClientSecret) {}},
 		{
-			name:    "missing secretRef",
-			modify:  func(a *AzureClientSecret) { a.Spec.SecretRef.Name = "" },
-			wantErr: "secretRef.name",
+			name:   "omitted secretRef",
+			modify: func(a *AzureClientSecret) { a.Spec.SecretRef.Name = "" },
 		},
 		{
 			name:    "missing objectId",
 			modify:  func(a *AzureClientSecret) { a.Spec.ObjectID = "" },
 			wantErr: "objectId",
 		},
+		{
+			name: "clientId",
+			modify: func(a *AzureClientSecret) {
+				a.Spec.ObjectID, a.Spec.ClientID = "", "00000000-0000-0000-0000-000000000001"
+			},
+		},
+		{
+			name:    "objectId and clientId",
+			modify:  func(a *AzureClientSecret) { a.Spec.ClientID = "00000000-0000-0000-0000-000000000001" },
+			wantErr: "mutually exclusive",
+		},
+		{
+			name: "clientId not a GUID",
+			modify: func(a *AzureClientSecret) {
+				a.Spec.ObjectID, a.Spec.ClientID = "", "my-app' or 1 eq 1"
+			},
+			wantErr: "clientId must be a GUID",
+		},
+		{
+			name: "credentialsRef",
+			modify: func(a *AzureClientSecret) {
+				a.Spec.CredentialsRef = &CredentialsReference{Name: "tenant-b"}
+			},
+		},
+		{
+			name:    "credentialsRef without name",
+			modify:  func(a *AzureClientSecret) { a.Spec.CredentialsRef = &CredentialsReference{} },
+			wantErr: "credentialsRef.name",
+		},
+		{
+			name:   "service principal",
+			modify: func(a *AzureClientSecret) { a.Spec.TargetType = TargetTypeServicePrincipal },
+		},
+		{
+			name:    "unknown targetType",
+			modify:  func(a *AzureClientSecret) { a.Spec.TargetType = "Group" },
+			wantErr: "targetType",
+		},
+		{
+			name:   "government cloud",
+			modify: func(a *AzureClientSecret) { a.Spec.Cloud = CloudAzureGovernment },
+		},
+		{
+			name:    "unknown cloud",
+			modify:  func(a *AzureClientSecret) { a.Spec.Cloud = "AzureGermany" },
+			wantErr: "cloud",
+		},
+		{
+			name:   "certificate",
+			modify: func(a *AzureClientSecret) { a.Spec.CredentialType = CredentialTypeCertificate },
+		},
+		{
+			name:    "unknown credentialType",
+			modify:  func(a *AzureClientSecret) { a.Spec.CredentialType = "FederatedIdentity" },
+			wantErr: "credentialType",
+		},
 		{
 			name:    "empty template",
 			modify:  func(a *AzureClientSecret) { a.Spec.Template = nil },
@@ -90,10 +145,11 @@ func TestDeepCopyObject(t *testing.T) {
 	validity := metav1.Duration{Duration: 48 * time.Hour}
 	obj := &AzureClientSecret{
 		Spec: AzureClientSecretSpec{
-			SecretRef: framework.SecretReference{Name: "s"},
-			ObjectID:  "id",
-			Template:  map[string]string{"K": "V"},
-			Validity:  &validity,
+			SecretRef:      framework.SecretReference{Name: "s"},
+			ObjectID:       "id",
+			Template:       map[string]string{"K": "V"},
+			Validity:       &validity,
+			CredentialsRef: &CredentialsReference{Name: "tenant-b"},
 		},
 	}
 	obj.Status.Phase = framework.PhaseReady
@@ -110,6 +166,11 @@ func TestDeepCopyObject(t *testing.T) {
 	if obj.Spec.Validity.Duration != 48*time.Hour {
 		t.Fatal("DeepCopyObject did not copy validity")
 	}
+
+	cp.Spec.CredentialsRef.Name = "changed"
+	if obj.Spec.CredentialsRef.Name != "tenant-b" {
+		t.Fatal("DeepCopyObject did not copy credentialsRef")
+	}
 }
 
 func TestDeepCopyObjectList(t *testing.T) {
@@ -2,44 +2,71 @@
 package main
 
 import (
-	"crypto/tls"
+	"context"
+	"crypto/x509"
 	"flag"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
 
 	"github.com/lukasngl/valet/framework"
 	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
 	"github.com/lukasngl/valet/provider-azure/internal"
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/runtime"
-	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"golang.org/x/net/http/httpproxy"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/healthz"
-	"sigs.k8s.io/controller-runtime/pkg/log/zap"
-	"sigs.k8s.io/controller-runtime/pkg/metrics"
-	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
-	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
-var version = "dev"
+// Build metadata, set with -ldflags "-X main.version=..." at build time.
+// See [framework.NewBuildInfo] for the values used if they are not.
+var (
+	version  = "dev"
+	provider = ""
+	revision = ""
+)
 
 var (
-	metricsAddr = flag.String(
-		"metrics-bind-address",
-		":8080",
-		"Metrics endpoint bind address.",
+	graphScopes = flag.String(
+		"graph-scopes",
+		internal.DefaultScope,
+		"Comma-separated scopes of the Microsoft Graph access token.",
+	)
+	graphQPS = flag.Float64(
+		"graph-qps",
+		internal.DefaultQPS,
+		"Rate of Microsoft Graph requests per tenant, lowered while Graph throttles. Zero disables the limit.",
+	)
+	maxClientSecrets = flag.Int(
+		"max-client-secrets",
+		internal.DefaultMaxPasswords,
+		"Number of client secrets an application may hold before a rotation deletes the oldest ones the "+
+			"resource added, or fails if there are none. Zero disables the check.",
+	)
+	orphanSweepInterval = flag.Duration(
+		"orphan-sweep-interval",
+		0,
+		"Delete client secrets named valet-* that no resource tracks anymore this often. Zero disables this; "+
+			"only enable it if no other valet installation manages the same applications.",
+	)
+	authorityHost = flag.String(
+		"authority-host",
+		"",
+		"Microsoft Entra authority host that issues tokens, e.g. https://login.microsoftonline.us/. "+
+			"Defaults to AZURE_AUTHORITY_HOST or the public cloud.",
 	)
-	probeAddr = flag.String(
-		"health-probe-bind-address",
-		":8081",
-		"Health probe bind address.",
+	httpsProxy = flag.String(
+		"https-proxy",
+		"",
+		"Proxy URL of requests to Microsoft Graph and the Microsoft Entra authority. Defaults to HTTPS_PROXY; "+
+			"NO_PROXY applies either way.",
 	)
-	enableLeaderElection = flag.Bool("leader-elect", false, "Enable leader election.")
-	enableHTTP2          = flag.Bool(
-		"enable-http2",
-		false,
-		"Enable HTTP/2 for metrics and webhooks.",
+	caFile = flag.String(
+		"ca-file",
+		"",
+		"PEM file of certificate authorities trusted by requests to Microsoft Graph and the Microsoft Entra "+
+			"authority in addition to the system's, e.g. that of a TLS-inspecting proxy.",
 	)
 )
 
@@ -53,70 +80,103 @@ func main() {
 // +kubebuilder:rbac:groups=valet.ngl.cx,resources=azureclientsecrets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=valet.ngl.cx,resources=azureclientsecrets/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=valet.ngl.cx,resources=azureclientsecrets/finalizers,verbs=update
-// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch
-// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func run() error {
-	// Logging
-	opts := zap.Options{Development: false}
+	var opts framework.Options
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	setupLog := ctrl.Log.WithName("setup")
-
-	// Scheme
-	scheme := runtime.NewScheme()
-	utilruntime.Must(corev1.AddToScheme(scheme))
-	utilruntime.Must(v1alpha1.AddToScheme(scheme))
-
-	// TLS
-	tlsOpts := []func(*tls.Config){}
-	if !*enableHTTP2 {
-		tlsOpts = append(tlsOpts, func(c *tls.Config) {
-			c.NextProtos = []string{"http/1.1"}
-		})
+	// Provider
+	providerOpts := []internal.Option{
+		internal.WithScopes(splitList(*graphScopes)...),
+		internal.WithAuthorityHost(*authorityHost),
+		internal.WithQPS(*graphQPS),
+		internal.WithMaxPasswords(*maxClientSecrets),
+	}
+	proxy := http.ProxyFromEnvironment
+	if *httpsProxy != "" {
+		proxy = proxyFunc(*httpsProxy)
+		providerOpts = append(providerOpts, internal.WithProxy(proxy))
+	}
+	if *caFile != "" {
+		rootCAs, err := loadRootCAs(*caFile)
+		if err != nil {
+			return fmt.Errorf("loading --ca-file: %w", err)
+		}
+		providerOpts = append(providerOpts, internal.WithRootCAs(rootCAs))
 	}
 
-	// Manager
-	mgrOpts := ctrl.Options{
-		Scheme: scheme,
-		Metrics: metricsserver.Options{
-			BindAddress: *metricsAddr,
-			TLSOpts:     tlsOpts,
+	info := framework.NewBuildInfo(provider, version, revision)
+	var azure *internal.Provider
+	return framework.Run(ctrl.SetupSignalHandler(), &opts, framework.Operator[*v1alpha1.AzureClientSecret]{
+		Name:        "provider-azure",
+		BuildInfo:   info,
+		AddToScheme: v1alpha1.AddToScheme,
+		Endpoints:   internal.New(providerOpts...),
+		Proxy:       proxy,
+		NewProvider: func(_ context.Context, mgr ctrl.Manager) (framework.Provider[*v1alpha1.AzureClientSecret], error) {
+			azure = internal.New(append(providerOpts,
+				internal.WithEventRecorder(mgr.GetEventRecorder("provider-azure")),
+				internal.WithSecretReader(mgr.GetClient()),
+			)...)
+			return azure, nil
 		},
-		WebhookServer:          webhook.NewServer(webhook.Options{TLSOpts: tlsOpts}),
-		HealthProbeBindAddress: *probeAddr,
-		LeaderElection:         *enableLeaderElection,
-		LeaderElectionID:       "provider-azure.valet.ngl.cx",
-	}
+		Setup: func(mgr ctrl.Manager) error {
+			// Orphaned client secrets
+			if *orphanSweepInterval <= 0 {
+				return nil
+			}
+			keyStore, err := opts.NewKeyStore(mgr, info.Provider)
+			if err != nil {
+				return err
+			}
+			if err := mgr.Add(&internal.OrphanCollector{
+				Provider: azure,
+				Reader:   mgr.GetAPIReader(),
+				Scheme:   mgr.GetScheme(),
+				KeyStore: keyStore,
+				Interval: *orphanSweepInterval,
+			}); err != nil {
+				return fmt.Errorf("setting up orphaned client secret collector: %w", err)
+			}
+			return nil
+		},
+	})
+}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), mgrOpts)
-	if err != nil {
-		return fmt.Errorf("creating manager: %w", err)
-	}
+// proxyFunc returns the proxy of requests that sends HTTPS requests through
+// httpsProxy, and the others as configured by the environment.
+func proxyFunc(httpsProxy string) func(*http.Request) (*url.URL, error) {
+	cfg := httpproxy.FromEnvironment()
+	cfg.HTTPSProxy = httpsProxy
+	proxy := cfg.ProxyFunc()
+	return func(r *http.Request) (*url.URL, error) { return proxy(r.URL) }
+}
 
-	// Controller
-	reconciler := &framework.Reconciler[*v1alpha1.AzureClientSecret]{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Provider: framework.Instrument(internal.New(), metrics.Registry),
+// loadRootCAs returns the system's certificate authorities plus those in the
+// PEM file path.
+func loadRootCAs(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
 	}
-
-	if err := reconciler.SetupWithManager(mgr); err != nil {
-		return fmt.Errorf("setting up controller: %w", err)
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
 	}
-
-	// Health probes
-	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
-		return fmt.Errorf("setting up health check: %w", err)
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
 	}
+	return pool, nil
+}
 
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
-		return fmt.Errorf("setting up ready check: %w", err)
+// splitList splits a comma-separated flag value, dropping empty elements.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
 	}
-
-	setupLog.Info("starting manager", "version", version)
-
-	return mgr.Start(ctrl.SetupSignalHandler())
+	return out
 }
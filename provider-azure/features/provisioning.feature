@@ -92,15 +92,15 @@ Feature: Azure Secret Provisioning
       """
     Then the Secret "spec-update" should contain key "CLIENT_ID" within 60 seconds
 
-  Scenario: Missing secretRef is rejected
-    When I try to create a ClientSecret "no-ref" with:
+  Scenario: Omitted secretRef defaults to the resource name
+    When I create a ClientSecret "no-ref" with:
       """yaml
       spec:
         objectId: "$TEST_AZURE_OWNED_APP_OBJECT_ID"
         template:
           CLIENT_SECRET: "{{ .ClientSecret }}"
       """
-    Then the operation should have failed with "secretRef.name"
+    Then the Secret "no-ref" should contain key "CLIENT_SECRET" within 60 seconds
 
   Scenario: Secret is owned by the ClientSecret
     When I create a ClientSecret "ownership-test" with:
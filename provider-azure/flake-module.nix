@@ -24,15 +24,9 @@
         '';
       };
 
-      image = pkgs.dockerTools.streamLayeredImage {
+      image = valet.mkImage {
         name = "provider-azure";
-        tag = valet.version;
-        contents = [ pkgs.dockerTools.caCertificates ];
-        config = {
-          Entrypoint = [ "${provider-azure-compressed}/bin/provider-azure" ];
-          User = "65532:65532";
-          WorkingDir = "/";
-        };
+        entrypoint = "${provider-azure-compressed}/bin/provider-azure";
       };
       e2e-test-azure = pkgs.writeShellApplication {
         name = "e2e-test-azure";
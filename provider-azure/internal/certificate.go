@@ -0,0 +1,389 @@
+package internal
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1" //nolint:gosec // Certificate thumbprints are SHA-1 by definition.
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// certificateKeyBits is the size of the generated RSA keys.
+	certificateKeyBits = 2048
+
+	// proofAudience is the audience of the proof of possession that addKey
+	// and removeKey require.
+	proofAudience = "00000002-0000-0000-c000-000000000000"
+
+	// proofLifetime is how long a proof of possession is valid. Graph accepts
+	// at most 10 minutes.
+	proofLifetime = 5 * time.Minute
+)
+
+// certificate is a generated certificate credential.
+type certificate struct {
+	keyID       string
+	key         *rsa.PrivateKey
+	der         []byte
+	certPEM     string
+	keyPEM      string
+	thumbprint  string
+	displayName string
+}
+
+// newCertificate generates a key pair and a self-signed certificate for it,
+// valid until notAfter.
+func newCertificate(displayName string, notBefore, notAfter time.Time) (*certificate, error) {
+	key, err := rsa.GenerateKey(rand.Reader, certificateKeyBits)
+	if err != nil {
+		return nil, fmt.Errorf("generating key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("generating serial number: %w", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: displayName},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("creating certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("encoding key: %w", err)
+	}
+
+	thumbprint := sha1.Sum(der) //nolint:gosec // See import.
+	return &certificate{
+		keyID:       uuid.New().String(),
+		key:         key,
+		der:         der,
+		certPEM:     string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		keyPEM:      string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})),
+		thumbprint:  strings.ToUpper(hex.EncodeToString(thumbprint[:])),
+		displayName: displayName,
+	}, nil
+}
+
+// keyCredential returns the certificate as a Graph keyCredential.
+func (c *certificate) keyCredential() keyCredential {
+	return keyCredential{
+		KeyID:       c.keyID,
+		Type:        "AsymmetricX509Cert",
+		Usage:       "Verify",
+		Key:         base64.StdEncoding.EncodeToString(c.der),
+		DisplayName: c.displayName,
+	}
+}
+
+// proof returns a proof of possession of the certificate's key for the
+// application or service principal objectID, as addKey and removeKey
+// require.
+func (c *certificate) proof(objectID string, now time.Time) (string, error) {
+	thumbprint := sha1.Sum(c.der) //nolint:gosec // See import.
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"x5t": base64.RawURLEncoding.EncodeToString(thumbprint[:]),
+	})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"aud": proofAudience,
+		"iss": objectID,
+		"nbf": now.Unix(),
+		"exp": now.Add(proofLifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, c.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("signing proof: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// parseCertificate parses a PEM-encoded certificate and the matching RSA key,
+// as written to the output Secret.
+func parseCertificate(certPEM, keyPEM []byte) (*certificate, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil || certBlock.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM certificate")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing certificate: %w", err)
+	}
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, errors.New("no PEM private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok || !key.PublicKey.Equal(cert.PublicKey) {
+		return nil, errors.New("private key does not match the certificate")
+	}
+	if time.Now().After(cert.NotAfter) {
+		return nil, errors.New("certificate expired")
+	}
+	return &certificate{key: key, der: cert.Raw}, nil
+}
+
+// currentCertificate returns the certificate in the output Secret of obj,
+// which signs the proof of possession for the next one. It returns nil if
+// there is none, e.g. before the first rotation or without a Secret reader.
+func (p *Provider) currentCertificate(
+	ctx context.Context,
+	obj *v1alpha1.AzureClientSecret,
+) *certificate {
+	if p.secrets == nil {
+		return nil
+	}
+
+	var secret corev1.Secret
+	key := types.NamespacedName{Namespace: obj.Namespace, Name: obj.GetSecretRef().Name}
+	if err := p.secrets.Get(ctx, key, &secret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.FromContext(ctx).Error(err, "reading current certificate", "secret", key.Name)
+		}
+		return nil
+	}
+	cert, err := parseCertificate(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		log.FromContext(ctx).V(1).Info("no usable certificate in output secret",
+			"secret", key.Name, "reason", err.Error())
+		return nil
+	}
+	return cert
+}
+
+// provisionCertificate adds a new certificate to the application. It uses
+// addKey with a proof of possession signed by the current certificate if
+// there is one, and otherwise updates the application's keyCredentials,
+// which addKey does not allow for applications without a certificate.
+func (p *Provider) provisionCertificate(
+	ctx context.Context,
+	obj *v1alpha1.AzureClientSecret,
+	now, endDateTime time.Time,
+	displayName string,
+) (*certificate, error) {
+	cert, err := newCertificate(displayName, now.Add(-time.Minute), endDateTime)
+	if err != nil {
+		return nil, err
+	}
+
+	current := p.currentCertificate(ctx, obj)
+
+	defer p.lockObject(obj)()
+
+	if current != nil {
+		proof, err := current.proof(obj.Spec.ObjectID, now)
+		if err != nil {
+			return nil, err
+		}
+		// Graph assigns the key ID.
+		kc := cert.keyCredential()
+		kc.KeyID = ""
+		respBody, err := withRetry(ctx, func() ([]byte, error) {
+			return p.graphRequest(ctx, "POST", objectPath(obj)+"/addKey",
+				addKeyRequest{KeyCredential: kc, Proof: proof})
+		})
+		if err != nil {
+			p.recordGraphError(obj, "Provision", err)
+			return nil, fmt.Errorf("adding key to %s: %w", objectName(obj), err)
+		}
+		var added keyCredential
+		if err := json.Unmarshal(respBody, &added); err != nil {
+			return nil, fmt.Errorf("parsing addKey response: %w", err)
+		}
+		if added.KeyID == "" {
+			return nil, errors.New("no keyId returned from Graph API")
+		}
+		cert.keyID = added.KeyID
+		return cert, nil
+	}
+
+	log.FromContext(ctx).Info("no current certificate to prove possession, updating keyCredentials",
+		"objectId", obj.Spec.ObjectID)
+	err = p.updateKeyCredentials(ctx, obj, "Provision", func(creds []keyCredential) []keyCredential {
+		return append(creds, cert.keyCredential())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("adding key to %s: %w", objectName(obj), err)
+	}
+	return cert, nil
+}
+
+// deleteCertificate removes a certificate from the application. Like
+// [Provider.provisionCertificate], it uses removeKey if the output Secret
+// holds a certificate to prove possession with, and otherwise updates the
+// application's keyCredentials. Keys that are not certificates, e.g. client
+// secrets from before credentialType was changed, are removed as passwords.
+func (p *Provider) deleteCertificate(
+	ctx context.Context,
+	obj *v1alpha1.AzureClientSecret,
+	keyID string,
+) error {
+	creds, err := p.keyCredentials(ctx, obj, "DeleteKey")
+	if err != nil {
+		return fmt.Errorf("removing key %s from %s: %w", keyID, objectName(obj), err)
+	}
+	if !slices.ContainsFunc(creds, func(c keyCredential) bool { return c.KeyID == keyID }) {
+		return p.deletePassword(ctx, obj, keyID)
+	}
+
+	current := p.currentCertificate(ctx, obj)
+
+	defer p.lockObject(obj)()
+
+	if current != nil {
+		proof, err := current.proof(obj.Spec.ObjectID, time.Now())
+		if err != nil {
+			return err
+		}
+		err = withRetryNoResult(ctx, func() error {
+			_, err := p.graphRequest(ctx, "POST", objectPath(obj)+"/removeKey",
+				removeKeyRequest{KeyID: keyID, Proof: proof})
+			return err
+		})
+		if err != nil {
+			p.recordGraphError(obj, "DeleteKey", err)
+			return fmt.Errorf("removing key %s from %s: %w", keyID, objectName(obj), err)
+		}
+		return nil
+	}
+
+	err = p.updateKeyCredentials(ctx, obj, "DeleteKey", func(creds []keyCredential) []keyCredential {
+		return slices.DeleteFunc(creds, func(c keyCredential) bool { return c.KeyID == keyID })
+	})
+	if err != nil {
+		return fmt.Errorf("removing key %s from %s: %w", keyID, objectName(obj), err)
+	}
+	return nil
+}
+
+// keyCredentials returns the application's keyCredentials, including their
+// keys, which Graph only returns when they are selected explicitly.
+func (p *Provider) keyCredentials(
+	ctx context.Context,
+	obj *v1alpha1.AzureClientSecret,
+	action string,
+) ([]keyCredential, error) {
+	body, err := withRetry(ctx, func() ([]byte, error) {
+		return p.graphRequest(ctx, "GET", objectPath(obj)+"?$select=keyCredentials", nil)
+	})
+	if err != nil {
+		p.recordGraphError(obj, action, err)
+		return nil, fmt.Errorf("getting keyCredentials: %w", err)
+	}
+	var app applicationKeyCredentials
+	if err := json.Unmarshal(body, &app); err != nil {
+		return nil, fmt.Errorf("parsing keyCredentials: %w", err)
+	}
+	return app.KeyCredentials, nil
+}
+
+// updateKeyCredentials replaces the application's keyCredentials with the
+// result of modify. The caller must hold the lock of obj, see
+// [Provider.lockObject].
+func (p *Provider) updateKeyCredentials(
+	ctx context.Context,
+	obj *v1alpha1.AzureClientSecret,
+	action string,
+	modify func([]keyCredential) []keyCredential,
+) error {
+	creds, err := p.keyCredentials(ctx, obj, action)
+	if err != nil {
+		return err
+	}
+	err = withRetryNoResult(ctx, func() error {
+		_, err := p.graphRequest(ctx, "PATCH", objectPath(obj),
+			applicationKeyCredentials{KeyCredentials: modify(creds)})
+		return err
+	})
+	if err != nil {
+		p.recordGraphError(obj, action, err)
+		return fmt.Errorf("updating keyCredentials: %w", err)
+	}
+	return nil
+}
+
+// certificateData returns the template data and the Secret data of a
+// certificate credential.
+func certificateData(cert *certificate) (templateData, data map[string]string) {
+	templateData = map[string]string{
+		"Certificate": cert.certPEM,
+		"PrivateKey":  cert.keyPEM,
+		"Thumbprint":  cert.thumbprint,
+	}
+	data = map[string]string{
+		corev1.TLSCertKey:       cert.certPEM,
+		corev1.TLSPrivateKeyKey: cert.keyPEM,
+	}
+	return templateData, data
+}
+
+// Graph API request/response types for certificates.
+
+type keyCredential struct {
+	KeyID               string     `json:"keyId,omitempty"`
+	Type                string     `json:"type,omitempty"`
+	Usage               string     `json:"usage,omitempty"`
+	Key                 string     `json:"key,omitempty"`
+	DisplayName         string     `json:"displayName,omitempty"`
+	CustomKeyIdentifier string     `json:"customKeyIdentifier,omitempty"`
+	StartDateTime       *time.Time `json:"startDateTime,omitempty"`
+	EndDateTime         *time.Time `json:"endDateTime,omitempty"`
+}
+
+type applicationKeyCredentials struct {
+	KeyCredentials []keyCredential `json:"keyCredentials"`
+}
+
+type addKeyRequest struct {
+	KeyCredential      keyCredential          `json:"keyCredential"`
+	PasswordCredential *newPasswordCredential `json:"passwordCredential"`
+	Proof              string                 `json:"proof"`
+}
+
+type removeKeyRequest struct {
+	KeyID string `json:"keyId"`
+	Proof string `json:"proof"`
+}
@@ -0,0 +1,281 @@
+package internal
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeGraph is a Graph API server for one application with key credentials.
+type fakeGraph struct {
+	creds   []keyCredential
+	patched bool
+	added   *addKeyRequest
+	removed *removeKeyRequest
+	pwd     bool
+}
+
+func (g *fakeGraph) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Query().Get("$select") == "keyCredentials":
+		_ = json.NewEncoder(w).Encode(applicationKeyCredentials{KeyCredentials: g.creds})
+	case r.Method == http.MethodGet:
+		_ = json.NewEncoder(w).Encode(applicationResponse{AppID: "app-123"})
+	case r.Method == http.MethodPatch:
+		var app applicationKeyCredentials
+		_ = json.NewDecoder(r.Body).Decode(&app)
+		g.creds, g.patched = app.KeyCredentials, true
+		w.WriteHeader(http.StatusNoContent)
+	case strings.HasSuffix(r.URL.Path, "/addKey"):
+		g.added = &addKeyRequest{}
+		_ = json.NewDecoder(r.Body).Decode(g.added)
+		_ = json.NewEncoder(w).Encode(keyCredential{KeyID: "key-2"})
+	case strings.HasSuffix(r.URL.Path, "/removeKey"):
+		g.removed = &removeKeyRequest{}
+		_ = json.NewDecoder(r.Body).Decode(g.removed)
+		w.WriteHeader(http.StatusNoContent)
+	case strings.HasSuffix(r.URL.Path, "/removePassword"):
+		g.pwd = true
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// newCertificateObject returns a resource provisioning certificates into the
+// Secret "out".
+func newCertificateObject() *v1alpha1.AzureClientSecret {
+	return &v1alpha1.AzureClientSecret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "web"},
+		Spec: v1alpha1.AzureClientSecretSpec{
+			SecretRef:      framework.SecretReference{Name: "out"},
+			ObjectID:       "obj-1",
+			CredentialType: v1alpha1.CredentialTypeCertificate,
+			Template: map[string]string{
+				"AZURE_CLIENT_ID":   "{{ .ClientID }}",
+				"AZURE_THUMBPRINT":  "{{ .Thumbprint }}",
+				"AZURE_CERTIFICATE": "{{ .Certificate }}{{ .PrivateKey }}",
+			},
+		},
+	}
+}
+
+// outputSecret returns the output Secret holding cert.
+func outputSecret(cert *certificate) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "out"},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       []byte(cert.certPEM),
+			corev1.TLSPrivateKeyKey: []byte(cert.keyPEM),
+		},
+	}
+}
+
+// verifyProof checks that proof is signed by cert for objectID.
+func verifyProof(t *testing.T, proof string, cert *certificate, objectID string) {
+	t.Helper()
+	parts := strings.Split(proof, ".")
+	if len(parts) != 3 {
+		t.Fatalf("proof has %d parts, want 3", len(parts))
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("decoding signature: %v", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&cert.key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		t.Fatalf("proof not signed by the current certificate: %v", err)
+	}
+
+	raw, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims struct {
+		Aud string `json:"aud"`
+		Iss string `json:"iss"`
+		Nbf int64  `json:"nbf"`
+		Exp int64  `json:"exp"`
+	}
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		t.Fatalf("parsing claims: %v", err)
+	}
+	if claims.Aud != proofAudience || claims.Iss != objectID {
+		t.Fatalf("got aud %q and iss %q", claims.Aud, claims.Iss)
+	}
+	if claims.Exp-claims.Nbf > int64((10 * time.Minute).Seconds()) {
+		t.Fatalf("proof valid for %ds, Graph accepts at most 10 minutes", claims.Exp-claims.Nbf)
+	}
+}
+
+func TestNewCertificate(t *testing.T) {
+	now := time.Now()
+	cert, err := newCertificate("valet-test", now, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cert.thumbprint) != 40 || strings.ToUpper(cert.thumbprint) != cert.thumbprint {
+		t.Fatalf("got thumbprint %q, want 40 uppercase hex digits", cert.thumbprint)
+	}
+
+	parsed, err := parseCertificate([]byte(cert.certPEM), []byte(cert.keyPEM))
+	if err != nil {
+		t.Fatalf("parsing generated certificate: %v", err)
+	}
+	if !parsed.key.Equal(cert.key) {
+		t.Fatal("parsed key differs from the generated one")
+	}
+
+	other, _ := newCertificate("valet-other", now, now.Add(time.Hour))
+	if _, err := parseCertificate([]byte(cert.certPEM), []byte(other.keyPEM)); err == nil {
+		t.Fatal("expected error for a key not matching the certificate")
+	}
+}
+
+func TestProvisionCertificate(t *testing.T) {
+	t.Run("without current certificate", func(t *testing.T) {
+		existing := keyCredential{KeyID: "key-0", Type: "AsymmetricX509Cert", Key: "a2V5"}
+		graph := &fakeGraph{creds: []keyCredential{existing}}
+		srv := httptest.NewServer(graph)
+		defer srv.Close()
+
+		p := New(
+			WithHTTPClient(srv.Client()),
+			WithBaseURL(srv.URL),
+			WithSecretReader(fake.NewClientBuilder().Build()),
+		)
+		result, err := p.Provision(context.Background(), newCertificateObject())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !graph.patched || graph.added != nil {
+			t.Fatal("expected keyCredentials to be updated instead of addKey")
+		}
+		if len(graph.creds) != 2 || graph.creds[0].KeyID != "key-0" || graph.creds[1].KeyID != result.KeyID {
+			t.Fatalf("got keyCredentials %+v, want key-0 and %s", graph.creds, result.KeyID)
+		}
+		if result.SecretType != corev1.SecretTypeTLS {
+			t.Fatalf("got secret type %q, want %q", result.SecretType, corev1.SecretTypeTLS)
+		}
+		if result.StringData["AZURE_CLIENT_ID"] != "app-123" {
+			t.Fatalf("got AZURE_CLIENT_ID %q", result.StringData["AZURE_CLIENT_ID"])
+		}
+
+		cert, err := parseCertificate(
+			[]byte(result.StringData[corev1.TLSCertKey]),
+			[]byte(result.StringData[corev1.TLSPrivateKeyKey]),
+		)
+		if err != nil {
+			t.Fatalf("parsing provisioned certificate: %v", err)
+		}
+		if got := base64.StdEncoding.EncodeToString(cert.der); got != graph.creds[1].Key {
+			t.Fatal("certificate in the secret differs from the one added to the application")
+		}
+		if got := result.StringData["AZURE_CERTIFICATE"]; !strings.Contains(got, "BEGIN PRIVATE KEY") {
+			t.Fatalf("expected the private key in the template output, got %q", got)
+		}
+		if len(result.StringData["AZURE_THUMBPRINT"]) != 40 {
+			t.Fatalf("got thumbprint %q", result.StringData["AZURE_THUMBPRINT"])
+		}
+	})
+
+	t.Run("with current certificate", func(t *testing.T) {
+		now := time.Now()
+		current, err := newCertificate("valet-current", now, now.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		graph := &fakeGraph{}
+		srv := httptest.NewServer(graph)
+		defer srv.Close()
+
+		p := New(
+			WithHTTPClient(srv.Client()),
+			WithBaseURL(srv.URL),
+			WithSecretReader(fake.NewClientBuilder().WithObjects(outputSecret(current)).Build()),
+		)
+		result, err := p.Provision(context.Background(), newCertificateObject())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if graph.patched || graph.added == nil {
+			t.Fatal("expected addKey instead of updating keyCredentials")
+		}
+		if result.KeyID != "key-2" {
+			t.Fatalf("got keyID %q, want the one assigned by Graph", result.KeyID)
+		}
+		if graph.added.KeyCredential.Type != "AsymmetricX509Cert" || graph.added.KeyCredential.Key == "" {
+			t.Fatalf("got keyCredential %+v", graph.added.KeyCredential)
+		}
+		verifyProof(t, graph.added.Proof, current, "obj-1")
+	})
+}
+
+func TestDeleteCertificate(t *testing.T) {
+	t.Run("removeKey with current certificate", func(t *testing.T) {
+		now := time.Now()
+		current, err := newCertificate("valet-current", now, now.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		graph := &fakeGraph{creds: []keyCredential{{KeyID: "key-1"}}}
+		srv := httptest.NewServer(graph)
+		defer srv.Close()
+
+		p := New(
+			WithHTTPClient(srv.Client()),
+			WithBaseURL(srv.URL),
+			WithSecretReader(fake.NewClientBuilder().WithObjects(outputSecret(current)).Build()),
+		)
+		if err := p.DeleteKey(context.Background(), newCertificateObject(), "key-1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if graph.removed == nil || graph.removed.KeyID != "key-1" {
+			t.Fatalf("expected removeKey for key-1, got %+v", graph.removed)
+		}
+		verifyProof(t, graph.removed.Proof, current, "obj-1")
+	})
+
+	t.Run("without current certificate", func(t *testing.T) {
+		graph := &fakeGraph{creds: []keyCredential{{KeyID: "key-0"}, {KeyID: "key-1"}}}
+		srv := httptest.NewServer(graph)
+		defer srv.Close()
+
+		p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL))
+		if err := p.DeleteKey(context.Background(), newCertificateObject(), "key-1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !graph.patched || len(graph.creds) != 1 || graph.creds[0].KeyID != "key-0" {
+			t.Fatalf("got keyCredentials %+v, want only key-0", graph.creds)
+		}
+	})
+
+	t.Run("client secret from before the switch", func(t *testing.T) {
+		graph := &fakeGraph{}
+		srv := httptest.NewServer(graph)
+		defer srv.Close()
+
+		p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL))
+		if err := p.DeleteKey(context.Background(), newCertificateObject(), "pwd-1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !graph.pwd || graph.patched || graph.removed != nil {
+			t.Fatal("expected the unknown key to be removed as a password")
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// objectIDKey identifies a resolved client ID: the same client ID resolves
+// to different objects for applications and service principals, and in the
+// tenants of different credentials and clouds.
+type objectIDKey struct {
+	cloud       v1alpha1.Cloud
+	credentials types.NamespacedName
+	targetType  v1alpha1.TargetType
+	clientID    string
+}
+
+// resolveObjectID returns obj itself if it has an objectId, and otherwise a
+// copy with the Object ID of the application or service principal with its
+// clientId, so that the rest of the provider only deals with Object IDs.
+// Object IDs never change, so each client ID is looked up once and cached.
+func (p *Provider) resolveObjectID(
+	ctx context.Context,
+	obj *v1alpha1.AzureClientSecret,
+) (*v1alpha1.AzureClientSecret, error) {
+	if obj.Spec.ObjectID != "" || obj.Spec.ClientID == "" {
+		return obj, nil
+	}
+
+	key := objectIDKey{cloud: obj.Spec.Cloud, targetType: obj.Spec.TargetType, clientID: obj.Spec.ClientID}
+	if key.targetType == "" {
+		key.targetType = v1alpha1.TargetTypeApplication
+	}
+	if ref := obj.Spec.CredentialsRef; ref != nil {
+		key.credentials = types.NamespacedName{Namespace: obj.Namespace, Name: ref.Name}
+	}
+
+	p.objectIDMu.Lock()
+	objectID, ok := p.objectIDs[key]
+	p.objectIDMu.Unlock()
+	if !ok {
+		var err error
+		if objectID, err = p.lookupObjectID(ctx, obj); err != nil {
+			return nil, err
+		}
+		p.objectIDMu.Lock()
+		if p.objectIDs == nil {
+			p.objectIDs = make(map[objectIDKey]string)
+		}
+		p.objectIDs[key] = objectID
+		p.objectIDMu.Unlock()
+	}
+
+	resolved := obj.DeepCopyObject().(*v1alpha1.AzureClientSecret)
+	resolved.Spec.ObjectID = objectID
+	return resolved, nil
+}
+
+// lookupObjectID finds the Object ID of the application or service
+// principal with the clientId of obj.
+func (p *Provider) lookupObjectID(ctx context.Context, obj *v1alpha1.AzureClientSecret) (string, error) {
+	collection, kind := "/applications", "application"
+	if obj.Spec.TargetType == v1alpha1.TargetTypeServicePrincipal {
+		collection, kind = "/servicePrincipals", "service principal"
+	}
+	// The client ID is validated to be a GUID, so it needs no escaping in
+	// the filter.
+	query := url.Values{
+		"$filter": {fmt.Sprintf("appId eq '%s'", obj.Spec.ClientID)},
+		"$select": {"id"},
+	}
+
+	respBody, err := withRetry(ctx, func() ([]byte, error) {
+		return p.graphRequest(ctx, "GET", collection+"?"+query.Encode(), nil)
+	})
+	if err != nil {
+		p.recordGraphError(obj, "ResolveClientID", err)
+		return "", fmt.Errorf("looking up %s with client ID %s: %w", kind, obj.Spec.ClientID, err)
+	}
+
+	var list objectList
+	if err := json.Unmarshal(respBody, &list); err != nil {
+		return "", fmt.Errorf("parsing %s lookup response: %w", kind, err)
+	}
+	if len(list.Value) == 0 || list.Value[0].ID == "" {
+		return "", fmt.Errorf("no %s with client ID %s", kind, obj.Spec.ClientID)
+	}
+	return list.Value[0].ID, nil
+}
+
+// objectList is a Graph API collection of applications or service
+// principals.
+type objectList struct {
+	Value []struct {
+		ID string `json:"id"`
+	} `json:"value"`
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testClientID = "00000000-0000-0000-0000-000000000001"
+
+// newClientIDObject returns a resource selecting its application by client
+// ID.
+func newClientIDObject() *v1alpha1.AzureClientSecret {
+	return &v1alpha1.AzureClientSecret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "web"},
+		Spec: v1alpha1.AzureClientSecretSpec{
+			SecretRef: framework.SecretReference{Name: "out"},
+			ClientID:  testClientID,
+			Template:  map[string]string{"SECRET": "{{ .ClientSecret }}"},
+		},
+	}
+}
+
+func TestResolveObjectID(t *testing.T) {
+	var lookups, paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch {
+		case r.URL.Query().Has("$filter"):
+			lookups = append(lookups, r.URL.Path+" "+r.URL.Query().Get("$filter"))
+			id := "app-obj"
+			if r.URL.Path == "/servicePrincipals" {
+				id = "sp-obj"
+			}
+			_, _ = w.Write([]byte(`{"value":[{"id":"` + id + `"}]}`))
+		case strings.HasSuffix(r.URL.Path, "/addPassword"):
+			_ = json.NewEncoder(w).Encode(addPasswordResponse{KeyID: "key-1", SecretText: "s3cret"})
+		default:
+			_ = json.NewEncoder(w).Encode(applicationResponse{AppID: testClientID})
+		}
+	}))
+	defer srv.Close()
+
+	p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL))
+	for range 2 {
+		if _, err := p.Provision(context.Background(), newClientIDObject()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(lookups) != 1 || lookups[0] != "/applications appId eq '"+testClientID+"'" {
+		t.Fatalf("expected a single lookup of the application, got %v", lookups)
+	}
+	if !strings.Contains(strings.Join(paths, ","), "/applications/app-obj/addPassword") {
+		t.Fatalf("expected the password to be added to the resolved application, got %v", paths)
+	}
+
+	// Service principals are looked up separately.
+	sp := newClientIDObject()
+	sp.Spec.TargetType = v1alpha1.TargetTypeServicePrincipal
+	resolved, err := p.resolveObjectID(context.Background(), sp)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved.Spec.ObjectID != "sp-obj" || sp.Spec.ObjectID != "" {
+		t.Fatalf("got object ID %q, want sp-obj on a copy", resolved.Spec.ObjectID)
+	}
+}
+
+func TestResolveObjectID_NotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"value":[]}`))
+	}))
+	defer srv.Close()
+
+	p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL))
+	_, err := p.resolveObjectID(context.Background(), newClientIDObject())
+	if err == nil || !strings.Contains(err.Error(), "no application with client ID "+testClientID) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.objectIDs) != 0 {
+		t.Fatal("expected a failed lookup not to be cached")
+	}
+}
@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+)
+
+// cloudKey is the context key of the [cloudEndpoints] that
+// [Provider.graphRequest] uses instead of the operator's configuration.
+type cloudKey struct{}
+
+// cloudEndpoints are the endpoints of an Azure cloud.
+type cloudEndpoints struct {
+	// graphURL is the Microsoft Graph API base URL.
+	graphURL string
+	// scope is the token scope requested for Microsoft Graph.
+	scope string
+	// config configures the Microsoft Entra authority of credentials.
+	config cloud.Configuration
+}
+
+// clouds are the endpoints of the clouds a resource can select.
+var clouds = map[v1alpha1.Cloud]cloudEndpoints{
+	v1alpha1.CloudAzurePublic: {
+		graphURL: graphBaseURL,
+		scope:    DefaultScope,
+		config:   cloud.AzurePublic,
+	},
+	v1alpha1.CloudAzureGovernment: {
+		graphURL: "https://graph.microsoft.us/v1.0",
+		scope:    "https://graph.microsoft.us/.default",
+		config:   cloud.AzureGovernment,
+	},
+	v1alpha1.CloudAzureChina: {
+		graphURL: "https://microsoftgraph.chinacloudapi.cn/v1.0",
+		scope:    "https://microsoftgraph.chinacloudapi.cn/.default",
+		config:   cloud.AzureChina,
+	},
+}
+
+// withCloud returns ctx carrying the endpoints of the cloud obj selects and
+// the operator's own credential for that cloud, or ctx itself if obj selects
+// none and the operator's configuration is used.
+func (p *Provider) withCloud(ctx context.Context, obj *v1alpha1.AzureClientSecret) (context.Context, error) {
+	if obj.Spec.Cloud == "" {
+		return ctx, nil
+	}
+	endpoints, ok := clouds[obj.Spec.Cloud]
+	if !ok {
+		return nil, fmt.Errorf("unknown cloud %q", obj.Spec.Cloud)
+	}
+	ctx = context.WithValue(ctx, cloudKey{}, &endpoints)
+
+	// Skip auth when pre-configured via WithHTTPClient (e.g. tests).
+	if p.cred == nil {
+		return ctx, nil
+	}
+	cred, err := p.cloudCredential(obj.Spec.Cloud, endpoints)
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, credentialKey{}, cred), nil
+}
+
+// cloudCredential returns the operator's own credential for a cloud, taken
+// from its environment like the default one. It is created on first use and
+// reused, with its tokens, afterwards.
+func (p *Provider) cloudCredential(name v1alpha1.Cloud, endpoints cloudEndpoints) (azcore.TokenCredential, error) {
+	p.credMu.Lock()
+	defer p.credMu.Unlock()
+
+	if cred, ok := p.cloudCreds[name]; ok {
+		return cred, nil
+	}
+	cred, err := azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{
+		ClientOptions: policy.ClientOptions{Cloud: endpoints.config, Transport: p.tokenTransport()},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating Azure credential for cloud %s: %w", name, err)
+	}
+	if p.cloudCreds == nil {
+		p.cloudCreds = make(map[v1alpha1.Cloud]azcore.TokenCredential)
+	}
+	p.cloudCreds[name] = cred
+	return cred, nil
+}
+
+// clientOptions returns the options of credentials created for obj, which
+// select the authority of its cloud.
+func (p *Provider) clientOptions(obj *v1alpha1.AzureClientSecret) policy.ClientOptions {
+	if endpoints, ok := clouds[obj.Spec.Cloud]; ok {
+		return policy.ClientOptions{Cloud: endpoints.config, Transport: p.tokenTransport()}
+	}
+	if opts := p.credentialOptions(); opts != nil {
+		return opts.ClientOptions
+	}
+	return policy.ClientOptions{}
+}
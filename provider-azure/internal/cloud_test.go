@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// recordingTransport answers every request with an empty JSON object and
+// records the requested URLs.
+type recordingTransport struct {
+	urls []string
+}
+
+func (t *recordingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.urls = append(t.urls, r.URL.String())
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`{}`)),
+		Request:    r,
+	}, nil
+}
+
+func TestWithCloud(t *testing.T) {
+	tests := []struct {
+		cloud   v1alpha1.Cloud
+		wantURL string
+	}{
+		{cloud: "", wantURL: "https://graph.example.test/test"},
+		{cloud: v1alpha1.CloudAzurePublic, wantURL: "https://graph.microsoft.com/v1.0/test"},
+		{cloud: v1alpha1.CloudAzureGovernment, wantURL: "https://graph.microsoft.us/v1.0/test"},
+		{cloud: v1alpha1.CloudAzureChina, wantURL: "https://microsoftgraph.chinacloudapi.cn/v1.0/test"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.cloud), func(t *testing.T) {
+			transport := &recordingTransport{}
+			p := New(WithHTTPClient(&http.Client{Transport: transport}), WithBaseURL("https://graph.example.test"))
+
+			obj := newCredentialsObject()
+			obj.Spec.CredentialsRef = nil
+			obj.Spec.Cloud = tt.cloud
+			ctx, err := p.withCloud(context.Background(), obj)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := p.graphRequest(ctx, "GET", "/test", nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(transport.urls) != 1 || transport.urls[0] != tt.wantURL {
+				t.Fatalf("got requests %v, want %s", transport.urls, tt.wantURL)
+			}
+		})
+	}
+
+	t.Run("scope of the cloud", func(t *testing.T) {
+		transport := &recordingTransport{}
+		p := New(WithHTTPClient(&http.Client{Transport: transport}))
+		obj := newCredentialsObject()
+		obj.Spec.Cloud = v1alpha1.CloudAzureGovernment
+
+		ctx, err := p.withCloud(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		cred := &fakeCredential{}
+		ctx = context.WithValue(ctx, credentialKey{}, cred)
+		if _, err := p.graphRequest(ctx, "GET", "/test", nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(cred.scopes) != 1 || cred.scopes[0] != "https://graph.microsoft.us/.default" {
+			t.Fatalf("got scopes %v", cred.scopes)
+		}
+	})
+}
+
+func TestWithCredential_Cloud(t *testing.T) {
+	data := map[string]string{"tenantId": "tenant-b", "clientId": "client-b", "clientSecret": "s3cret"}
+	p := New(WithSecretReader(fake.NewClientBuilder().WithObjects(credentialsSecret(data)).Build()))
+
+	obj := newCredentialsObject()
+	ctx, err := p.withCredential(context.Background(), obj)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	public := ctx.Value(credentialKey{})
+
+	obj.Spec.Cloud = v1alpha1.CloudAzureChina
+	ctx, err = p.withCredential(context.Background(), obj)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ctx.Value(credentialKey{}) == public {
+		t.Fatal("expected a new credential for another cloud")
+	}
+}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// credentialKey is the context key of the credential that
+// [Provider.graphRequest] authenticates with instead of the operator's own.
+type credentialKey struct{}
+
+// secretCredential is a credential built from a credentials Secret for a
+// cloud. It is reused, and so are the tokens it caches, until the Secret or
+// the cloud changes.
+type secretCredential struct {
+	resourceVersion string
+	cloud           v1alpha1.Cloud
+	tenantID        string
+	cred            azcore.TokenCredential
+}
+
+// withCredential returns ctx carrying the credential of the service
+// principal in obj's credentialsRef and the ID of its tenant, or ctx itself
+// if obj has none and the operator's own credential is used.
+func (p *Provider) withCredential(
+	ctx context.Context,
+	obj *v1alpha1.AzureClientSecret,
+) (context.Context, error) {
+	ref := obj.Spec.CredentialsRef
+	if ref == nil {
+		return ctx, nil
+	}
+	if p.secrets == nil {
+		return nil, errors.New("credentialsRef requires a Secret reader")
+	}
+
+	var secret corev1.Secret
+	key := types.NamespacedName{Namespace: obj.Namespace, Name: ref.Name}
+	if err := p.secrets.Get(ctx, key, &secret); err != nil {
+		return nil, fmt.Errorf("getting credentials secret %s: %w", ref.Name, err)
+	}
+
+	p.credMu.Lock()
+	defer p.credMu.Unlock()
+
+	cached, ok := p.creds[key]
+	if !ok || cached.resourceVersion != secret.ResourceVersion || cached.cloud != obj.Spec.Cloud {
+		cred, err := p.newSecretCredential(&secret, p.clientOptions(obj))
+		if err != nil {
+			return nil, fmt.Errorf("credentials secret %s: %w", ref.Name, err)
+		}
+		cached = secretCredential{
+			resourceVersion: secret.ResourceVersion,
+			cloud:           obj.Spec.Cloud,
+			tenantID:        strings.TrimSpace(string(secret.Data[v1alpha1.CredentialsTenantIDKey])),
+			cred:            cred,
+		}
+		if p.creds == nil {
+			p.creds = make(map[types.NamespacedName]secretCredential)
+		}
+		p.creds[key] = cached
+	}
+	ctx = context.WithValue(ctx, tenantKey{}, cached.tenantID)
+	return context.WithValue(ctx, credentialKey{}, cached.cred), nil
+}
+
+// newSecretCredential returns the credential of the service principal in a
+// credentials Secret: a client secret credential if the Secret has a client
+// secret, and otherwise a client assertion credential presenting the
+// Secret's federated token or the operator's workload identity token.
+func (p *Provider) newSecretCredential(
+	secret *corev1.Secret,
+	clientOptions policy.ClientOptions,
+) (azcore.TokenCredential, error) {
+	value := func(key string) string {
+		return strings.TrimSpace(string(secret.Data[key]))
+	}
+	tenantID := value(v1alpha1.CredentialsTenantIDKey)
+	clientID := value(v1alpha1.CredentialsClientIDKey)
+	if tenantID == "" || clientID == "" {
+		return nil, fmt.Errorf("keys %q and %q are required",
+			v1alpha1.CredentialsTenantIDKey, v1alpha1.CredentialsClientIDKey)
+	}
+
+	if clientSecret := value(v1alpha1.CredentialsClientSecretKey); clientSecret != "" {
+		return azidentity.NewClientSecretCredential(tenantID, clientID, clientSecret,
+			&azidentity.ClientSecretCredentialOptions{ClientOptions: clientOptions})
+	}
+
+	var assertion func(context.Context) (string, error)
+	if token := value(v1alpha1.CredentialsFederatedTokenKey); token != "" {
+		assertion = func(context.Context) (string, error) { return token, nil }
+	} else {
+		// The token file of the operator's workload identity, which is
+		// rotated by the kubelet and so read for each assertion.
+		file := os.Getenv("AZURE_FEDERATED_TOKEN_FILE")
+		if file == "" {
+			return nil, fmt.Errorf("no %q or %q, and the operator has no workload identity token",
+				v1alpha1.CredentialsClientSecretKey, v1alpha1.CredentialsFederatedTokenKey)
+		}
+		assertion = func(context.Context) (string, error) {
+			token, err := os.ReadFile(file)
+			if err != nil {
+				return "", fmt.Errorf("reading workload identity token: %w", err)
+			}
+			return strings.TrimSpace(string(token)), nil
+		}
+	}
+	return azidentity.NewClientAssertionCredential(tenantID, clientID, assertion,
+		&azidentity.ClientAssertionCredentialOptions{ClientOptions: clientOptions})
+}
+
+// tenantID returns the ID of the tenant that requests with ctx go to: that of
+// the credentials Secret, or of the operator's own credential, which
+// azidentity reads from AZURE_TENANT_ID.
+func tenantID(ctx context.Context) string {
+	if id, ok := ctx.Value(tenantKey{}).(string); ok {
+		return id
+	}
+	return os.Getenv("AZURE_TENANT_ID")
+}
@@ -0,0 +1,206 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// credentialsSecret returns a credentials Secret "tenant-b" with data.
+func credentialsSecret(data map[string]string) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "tenant-b"},
+		Data:       make(map[string][]byte, len(data)),
+	}
+	for k, v := range data {
+		secret.Data[k] = []byte(v)
+	}
+	return secret
+}
+
+// newCredentialsObject returns a resource using the credentials Secret
+// "tenant-b".
+func newCredentialsObject() *v1alpha1.AzureClientSecret {
+	return &v1alpha1.AzureClientSecret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "web"},
+		Spec: v1alpha1.AzureClientSecretSpec{
+			SecretRef:      framework.SecretReference{Name: "out"},
+			ObjectID:       "obj-1",
+			CredentialsRef: &v1alpha1.CredentialsReference{Name: "tenant-b"},
+			Template:       map[string]string{"SECRET": "{{ .ClientSecret }}"},
+		},
+	}
+}
+
+func TestNewSecretCredential(t *testing.T) {
+	t.Run("client secret", func(t *testing.T) {
+		cred, err := New().newSecretCredential(credentialsSecret(map[string]string{
+			"tenantId": "tenant-b", "clientId": "client-b", "clientSecret": "s3cret",
+		}), policy.ClientOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := cred.(*azidentity.ClientSecretCredential); !ok {
+			t.Fatalf("got %T, want a client secret credential", cred)
+		}
+	})
+
+	t.Run("federated token", func(t *testing.T) {
+		cred, err := New().newSecretCredential(credentialsSecret(map[string]string{
+			"tenantId": "tenant-b", "clientId": "client-b", "federatedToken": "eyJ",
+		}), policy.ClientOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := cred.(*azidentity.ClientAssertionCredential); !ok {
+			t.Fatalf("got %T, want a client assertion credential", cred)
+		}
+	})
+
+	t.Run("workload identity token", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "token")
+		if err := os.WriteFile(file, []byte("eyJ"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("AZURE_FEDERATED_TOKEN_FILE", file)
+
+		cred, err := New().newSecretCredential(credentialsSecret(map[string]string{
+			"tenantId": "tenant-b", "clientId": "client-b",
+		}), policy.ClientOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := cred.(*azidentity.ClientAssertionCredential); !ok {
+			t.Fatalf("got %T, want a client assertion credential", cred)
+		}
+	})
+
+	errorTests := []struct {
+		name    string
+		data    map[string]string
+		wantErr string
+	}{
+		{
+			name:    "missing tenant",
+			data:    map[string]string{"clientId": "client-b", "clientSecret": "s3cret"},
+			wantErr: `keys "tenantId" and "clientId" are required`,
+		},
+		{
+			name:    "missing client",
+			data:    map[string]string{"tenantId": "tenant-b", "clientSecret": "s3cret"},
+			wantErr: `keys "tenantId" and "clientId" are required`,
+		},
+		{
+			name:    "no secret or token",
+			data:    map[string]string{"tenantId": "tenant-b", "clientId": "client-b"},
+			wantErr: "no workload identity token",
+		},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AZURE_FEDERATED_TOKEN_FILE", "")
+
+			_, err := New().newSecretCredential(credentialsSecret(tt.data), policy.ClientOptions{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error %v does not contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithCredential(t *testing.T) {
+	data := map[string]string{"tenantId": "tenant-b", "clientId": "client-b", "clientSecret": "s3cret"}
+
+	t.Run("without credentialsRef", func(t *testing.T) {
+		obj := newCredentialsObject()
+		obj.Spec.CredentialsRef = nil
+
+		ctx, err := New().withCredential(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ctx.Value(credentialKey{}) != nil {
+			t.Fatal("expected the operator's own credential")
+		}
+	})
+
+	t.Run("reuses credential until the secret changes", func(t *testing.T) {
+		kube := fake.NewClientBuilder().WithObjects(credentialsSecret(data)).Build()
+		p := New(WithSecretReader(kube))
+
+		ctx, err := p.withCredential(context.Background(), newCredentialsObject())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		first := ctx.Value(credentialKey{})
+		if first == nil {
+			t.Fatal("expected the credential from the secret")
+		}
+
+		ctx, _ = p.withCredential(context.Background(), newCredentialsObject())
+		if ctx.Value(credentialKey{}) != first {
+			t.Fatal("expected the credential to be reused")
+		}
+
+		secret := &corev1.Secret{}
+		if err := kube.Get(context.Background(), client.ObjectKey{Namespace: "apps", Name: "tenant-b"}, secret); err != nil {
+			t.Fatal(err)
+		}
+		secret.Data["clientSecret"] = []byte("rotated")
+		if err := kube.Update(context.Background(), secret); err != nil {
+			t.Fatal(err)
+		}
+		ctx, _ = p.withCredential(context.Background(), newCredentialsObject())
+		if ctx.Value(credentialKey{}) == first {
+			t.Fatal("expected a new credential after the secret changed")
+		}
+	})
+
+	t.Run("missing secret", func(t *testing.T) {
+		p := New(WithSecretReader(fake.NewClientBuilder().Build()))
+		_, err := p.withCredential(context.Background(), newCredentialsObject())
+		if err == nil || !strings.Contains(err.Error(), "getting credentials secret tenant-b") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("no secret reader", func(t *testing.T) {
+		_, err := New().withCredential(context.Background(), newCredentialsObject())
+		if err == nil || !strings.Contains(err.Error(), "requires a Secret reader") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestGraphRequestCredential(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer fake-token" {
+			t.Errorf("got Authorization %q", got)
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL))
+	cred := &fakeCredential{}
+	ctx := context.WithValue(context.Background(), credentialKey{}, cred)
+	if _, err := p.graphRequest(ctx, "GET", "/test", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cred.scopes) != 1 || cred.scopes[0] != DefaultScope {
+		t.Fatalf("expected the resource's credential to be used, got scopes %v", cred.scopes)
+	}
+}
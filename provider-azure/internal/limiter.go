@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"context"
+	"sync"
+
+	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+	"golang.org/x/time/rate"
+)
+
+// DefaultQPS is the default rate of Microsoft Graph requests per tenant, see
+// [WithQPS].
+const DefaultQPS = 10
+
+// tenantKey is the context key of the ID of the tenant that
+// [Provider.graphRequest] sends requests to, if not the operator's own.
+type tenantKey struct{}
+
+// limiterKey identifies the request budget of a tenant. Graph throttles
+// requests per tenant, and tenants of different clouds are distinct.
+type limiterKey struct {
+	graphURL string
+	tenantID string
+}
+
+// limiter returns the rate limiter of the tenant that requests with ctx go
+// to, or nil if requests are not limited.
+func (p *Provider) limiter(ctx context.Context) *rate.Limiter {
+	if p.qps <= 0 {
+		return nil
+	}
+	key := limiterKey{graphURL: p.baseURL}
+	if endpoints, ok := ctx.Value(cloudKey{}).(*cloudEndpoints); ok {
+		key.graphURL = endpoints.graphURL
+	}
+	if tenantID, ok := ctx.Value(tenantKey{}).(string); ok {
+		key.tenantID = tenantID
+	}
+
+	p.limiterMu.Lock()
+	defer p.limiterMu.Unlock()
+
+	lim, ok := p.limiters[key]
+	if !ok {
+		lim = rate.NewLimiter(p.qps, max(int(p.qps), 1))
+		if p.limiters == nil {
+			p.limiters = make(map[limiterKey]*rate.Limiter)
+		}
+		p.limiters[key] = lim
+	}
+	return lim
+}
+
+// adapt adjusts the rate of lim to the responses of its tenant: a throttled
+// response halves it, down to a tenth of the configured rate, and each other
+// response raises it by a tenth of the configured rate until that is reached
+// again. Requests thus slow down while Graph throttles and recover afterwards.
+func (p *Provider) adapt(lim *rate.Limiter, throttled bool) {
+	p.limiterMu.Lock()
+	defer p.limiterMu.Unlock()
+
+	limit := lim.Limit()
+	if throttled {
+		limit = max(limit/2, p.qps/10)
+	} else {
+		limit = min(limit+p.qps/10, p.qps)
+	}
+	lim.SetLimit(limit)
+}
+
+// objectLock serializes the changes to an application or service principal.
+type objectLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lockObject waits until no other change to the application or service
+// principal obj adds credentials to is in progress, and returns the function
+// that ends this change. Graph rejects concurrent changes to the same
+// object, and keyCredentials are updated by reading and replacing them all.
+// Changes to unrelated objects proceed in parallel.
+func (p *Provider) lockObject(obj *v1alpha1.AzureClientSecret) (unlock func()) {
+	key := string(obj.Spec.Cloud) + objectPath(obj)
+
+	p.objectLocksMu.Lock()
+	l, ok := p.objectLocks[key]
+	if !ok {
+		l = &objectLock{}
+		if p.objectLocks == nil {
+			p.objectLocks = make(map[string]*objectLock)
+		}
+		p.objectLocks[key] = l
+	}
+	l.refs++
+	p.objectLocksMu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+
+		p.objectLocksMu.Lock()
+		defer p.objectLocksMu.Unlock()
+		if l.refs--; l.refs == 0 {
+			delete(p.objectLocks, key)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+	"golang.org/x/time/rate"
+)
+
+func TestLimiter(t *testing.T) {
+	p := New()
+	ctxA := context.WithValue(context.Background(), tenantKey{}, "tenant-a")
+	ctxB := context.WithValue(context.Background(), tenantKey{}, "tenant-b")
+
+	if p.limiter(ctxA) != p.limiter(ctxA) {
+		t.Error("expected requests to the same tenant to share a limiter")
+	}
+	if p.limiter(ctxA) == p.limiter(ctxB) || p.limiter(ctxA) == p.limiter(context.Background()) {
+		t.Error("expected a limiter per tenant")
+	}
+	if got := p.limiter(ctxA).Limit(); got != DefaultQPS {
+		t.Errorf("got limit %v, want %v", got, rate.Limit(DefaultQPS))
+	}
+
+	if New(WithQPS(0)).limiter(ctxA) != nil {
+		t.Error("expected no limiter with a QPS of zero")
+	}
+}
+
+func TestAdapt(t *testing.T) {
+	p := New(WithQPS(10))
+	lim := p.limiter(context.Background())
+
+	p.adapt(lim, true)
+	if got := lim.Limit(); got != 5 {
+		t.Fatalf("got limit %v after a throttled response, want 5", got)
+	}
+	for range 10 {
+		p.adapt(lim, true)
+	}
+	if got := lim.Limit(); got != 1 {
+		t.Fatalf("got limit %v after repeated throttling, want the floor of 1", got)
+	}
+	for range 20 {
+		p.adapt(lim, false)
+	}
+	if got := lim.Limit(); got != 10 {
+		t.Fatalf("got limit %v after recovering, want 10", got)
+	}
+}
+
+func TestGraphRequest_AdaptsToThrottling(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL), WithQPS(10))
+	if _, err := p.graphRequest(context.Background(), "GET", "/test", nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := p.limiter(context.Background()).Limit(); got != 5 {
+		t.Fatalf("got limit %v after a throttled request, want 5", got)
+	}
+}
+
+func TestLockObject(t *testing.T) {
+	p := New()
+	objA := &v1alpha1.AzureClientSecret{Spec: v1alpha1.AzureClientSecretSpec{ObjectID: "obj-a"}}
+	objB := &v1alpha1.AzureClientSecret{Spec: v1alpha1.AzureClientSecretSpec{ObjectID: "obj-b"}}
+
+	unlockA := p.lockObject(objA)
+	done := make(chan struct{})
+	go func() {
+		p.lockObject(objB)()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected an unrelated object not to wait for the lock")
+	}
+
+	locked := make(chan struct{})
+	go func() {
+		p.lockObject(objA)()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("expected the same object to wait for the lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlockA()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("expected the lock to be released")
+	}
+
+	p.objectLocksMu.Lock()
+	defer p.objectLocksMu.Unlock()
+	if len(p.objectLocks) != 0 {
+		t.Errorf("expected released locks to be removed, got %d", len(p.objectLocks))
+	}
+}
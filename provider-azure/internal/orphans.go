@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultOrphanMinAge is how old an untracked client secret must be before an
+// [OrphanCollector] deletes it, so that secrets being provisioned, whose key
+// ID is not in the status yet, are left alone.
+const DefaultOrphanMinAge = time.Hour
+
+// OrphanCollector periodically deletes the client secrets the provider added
+// to applications and service principals but no resource tracks anymore, e.g.
+// because the status update after provisioning failed or a resource was
+// deleted with its finalizer removed by hand. Only password credentials whose
+// display name starts with the provider's "valet-" prefix are considered.
+//
+// The collector only knows the resources of its own cluster, so it must not
+// run while other valet installations manage the same applications.
+type OrphanCollector struct {
+	// Provider lists and deletes the client secrets.
+	Provider *Provider
+
+	// Reader lists the resources, preferably uncached so that a stalled
+	// informer does not hide the keys they track.
+	Reader client.Reader
+	Scheme *runtime.Scheme
+
+	// KeyStore, if set, holds the tracked keys instead of the resource
+	// status. It must match [framework.Reconciler.KeyStore].
+	KeyStore framework.KeyStore
+
+	// Interval is the time between sweeps.
+	Interval time.Duration
+
+	// MinAge is how old an untracked client secret must be to be deleted.
+	// Zero uses [DefaultOrphanMinAge].
+	MinAge time.Duration
+}
+
+// NeedLeaderElection implements
+// [sigs.k8s.io/controller-runtime/pkg/manager.LeaderElectionRunnable], so that
+// only the leader deletes client secrets.
+func (c *OrphanCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// Start sweeps every Interval until ctx is done.
+func (c *OrphanCollector) Start(ctx context.Context) error {
+	ticker := time.NewTicker(max(c.Interval, time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			if _, err := c.Sweep(ctx, now); err != nil {
+				log.FromContext(ctx).Error(err, "orphaned client secret sweep failed")
+			}
+		}
+	}
+}
+
+// orphanTarget is an application or service principal that resources add
+// client secrets to.
+type orphanTarget struct {
+	// ctx carries the cloud and credential of the first resource targeting
+	// it.
+	ctx context.Context
+	// obj is the first resource targeting it, with its Object ID resolved.
+	obj *v1alpha1.AzureClientSecret
+	// tracked are the key IDs of all resources targeting it.
+	tracked map[string]bool
+}
+
+// Sweep deletes the orphaned client secrets at now and returns their number.
+// If the keys of any resource cannot be determined, e.g. because its client
+// ID does not resolve, nothing is deleted, as they might be among the
+// secrets of another resource's application.
+func (c *OrphanCollector) Sweep(ctx context.Context, now time.Time) (int, error) {
+	items, err := framework.List(ctx, c.Reader, c.Scheme, c.Provider.NewObject)
+	if err != nil {
+		return 0, err
+	}
+
+	targets := map[string]*orphanTarget{}
+	for _, item := range items {
+		keys := item.Status.ActiveKeys
+		if c.KeyStore != nil {
+			if keys, err = c.KeyStore.Load(ctx, item); err != nil {
+				return 0, fmt.Errorf("loading keys of %s/%s: %w", item.Namespace, item.Name, err)
+			}
+		}
+		targetCtx, obj, err := c.Provider.prepare(ctx, item)
+		if err != nil {
+			return 0, fmt.Errorf("resolving the target of %s/%s: %w", item.Namespace, item.Name, err)
+		}
+
+		key := string(obj.Spec.Cloud) + objectPath(obj)
+		target, ok := targets[key]
+		if !ok {
+			target = &orphanTarget{ctx: targetCtx, obj: obj, tracked: map[string]bool{}}
+			targets[key] = target
+		}
+		for _, k := range keys {
+			target.tracked[k.KeyID] = true
+		}
+	}
+
+	minAge := c.MinAge
+	if minAge <= 0 {
+		minAge = DefaultOrphanMinAge
+	}
+
+	deleted := 0
+	for _, target := range targets {
+		creds, err := c.Provider.listPasswords(target.ctx, target.obj)
+		if err != nil {
+			return deleted, err
+		}
+		for _, cred := range creds {
+			if !strings.HasPrefix(cred.DisplayName, displayNamePrefix) || target.tracked[cred.KeyID] ||
+				now.Sub(cred.StartDateTime) < minAge {
+				continue
+			}
+			if err := c.Provider.deletePassword(target.ctx, target.obj, cred.KeyID); err != nil {
+				return deleted, err
+			}
+			log.FromContext(ctx).Info("deleted orphaned client secret",
+				"keyId", cred.KeyID, "displayName", cred.DisplayName, "target", objectName(target.obj))
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// passwordCredential is a client secret of an application or service
+// principal, without its value.
+type passwordCredential struct {
+	KeyID         string    `json:"keyId"`
+	DisplayName   string    `json:"displayName"`
+	StartDateTime time.Time `json:"startDateTime"`
+	EndDateTime   time.Time `json:"endDateTime"`
+	Hint          string    `json:"hint"`
+}
+
+// listPasswords returns the client secrets of the application or service
+// principal of obj.
+func (p *Provider) listPasswords(ctx context.Context, obj *v1alpha1.AzureClientSecret) ([]passwordCredential, error) {
+	respBody, err := withRetry(ctx, func() ([]byte, error) {
+		return p.graphRequest(ctx, "GET", objectPath(obj)+"?$select=passwordCredentials", nil)
+	})
+	if err != nil {
+		p.recordGraphError(obj, "ListKeys", err)
+		return nil, fmt.Errorf("listing client secrets of %s: %w", objectName(obj), err)
+	}
+
+	var resp struct {
+		PasswordCredentials []passwordCredential `json:"passwordCredentials"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("parsing %s response: %w", objectName(obj), err)
+	}
+	return resp.PasswordCredentials, nil
+}
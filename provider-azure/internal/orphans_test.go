@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// orphanGraph serves the given client secrets for every application and
+// records the removed key IDs.
+func orphanGraph(t *testing.T, creds []passwordCredential) (*httptest.Server, *[]string) {
+	t.Helper()
+	var removed []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/removePassword"):
+			var req removePasswordRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			removed = append(removed, req.KeyID)
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/applications":
+			_ = json.NewEncoder(w).Encode(objectList{})
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]any{"passwordCredentials": creds})
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &removed
+}
+
+func newOrphanCollector(t *testing.T, srv *httptest.Server, objs ...client.Object) *OrphanCollector {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return &OrphanCollector{
+		Provider: New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL)),
+		Reader:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		Scheme:   scheme,
+	}
+}
+
+func trackingObject(name string, keyIDs ...string) *v1alpha1.AzureClientSecret {
+	obj := &v1alpha1.AzureClientSecret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: name},
+		Spec:       v1alpha1.AzureClientSecretSpec{ObjectID: "obj-1"},
+	}
+	for _, id := range keyIDs {
+		obj.Status.ActiveKeys = append(obj.Status.ActiveKeys, framework.ActiveKey{KeyID: id})
+	}
+	return obj
+}
+
+func TestOrphanCollector_Sweep(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-2 * DefaultOrphanMinAge)
+
+	t.Run("deletes untracked valet secrets", func(t *testing.T) {
+		srv, removed := orphanGraph(t, []passwordCredential{
+			{KeyID: "tracked-a", DisplayName: "valet-2026-01-01", StartDateTime: old},
+			{KeyID: "tracked-b", DisplayName: "valet-2026-01-01", StartDateTime: old},
+			{KeyID: "orphan", DisplayName: "valet-2026-01-01", StartDateTime: old},
+			{KeyID: "manual", DisplayName: "ci", StartDateTime: old},
+			{KeyID: "fresh", DisplayName: "valet-2026-01-02", StartDateTime: now},
+		})
+		c := newOrphanCollector(t, srv, trackingObject("a", "tracked-a"), trackingObject("b", "tracked-b"))
+
+		deleted, err := c.Sweep(context.Background(), now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if deleted != 1 || len(*removed) != 1 || (*removed)[0] != "orphan" {
+			t.Fatalf("got %d deleted, removed %v, want only orphan", deleted, *removed)
+		}
+	})
+
+	t.Run("deletes nothing if a resource does not resolve", func(t *testing.T) {
+		srv, removed := orphanGraph(t, []passwordCredential{
+			{KeyID: "orphan", DisplayName: "valet-2026-01-01", StartDateTime: old},
+		})
+		unresolved := trackingObject("b", "maybe-orphan")
+		unresolved.Spec.ObjectID = ""
+		unresolved.Spec.ClientID = "00000000-0000-0000-0000-000000000001"
+		c := newOrphanCollector(t, srv, trackingObject("a"), unresolved)
+
+		if _, err := c.Sweep(context.Background(), now); err == nil {
+			t.Fatal("expected an error")
+		}
+		if len(*removed) != 0 {
+			t.Fatalf("expected no deletions, got %v", *removed)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+// CredentialExpiry implements [framework.CredentialExpiryReporter]. It
+// returns when the credential the operator authenticates to Microsoft Graph
+// with expires, as configured for the environment credential of azidentity:
+//
+//   - with AZURE_CLIENT_SECRET, the end of the client secret of the
+//     application AZURE_CLIENT_ID, which requires the operator to be allowed
+//     to read it, e.g. as its owner;
+//   - with AZURE_CLIENT_CERTIFICATE_PATH, the end of the certificate;
+//   - otherwise, e.g. for workload and managed identities, the zero time, as
+//     they do not expire.
+func (p *Provider) CredentialExpiry(ctx context.Context) (time.Time, error) {
+	if err := p.initClient(); err != nil {
+		return time.Time{}, err
+	}
+
+	clientID := os.Getenv("AZURE_CLIENT_ID")
+	if secret := os.Getenv("AZURE_CLIENT_SECRET"); secret != "" && clientID != "" {
+		return p.clientSecretExpiry(ctx, clientID, secret)
+	}
+	if path := os.Getenv("AZURE_CLIENT_CERTIFICATE_PATH"); path != "" {
+		return certificateFileExpiry(path, os.Getenv("AZURE_CLIENT_CERTIFICATE_PASSWORD"))
+	}
+	return time.Time{}, nil
+}
+
+// clientSecretExpiry returns the end of the client secret of the application
+// clientID. Graph only tells the first characters of client secrets, their
+// hint, so if several share the hint of secret the earliest end is returned.
+func (p *Provider) clientSecretExpiry(ctx context.Context, clientID, secret string) (time.Time, error) {
+	respBody, err := withRetry(ctx, func() ([]byte, error) {
+		return p.graphRequest(ctx, "GET", "/applications(appId='"+clientID+"')?$select=passwordCredentials", nil)
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("listing client secrets of the operator's application %s: %w", clientID, err)
+	}
+
+	var resp struct {
+		PasswordCredentials []passwordCredential `json:"passwordCredentials"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return time.Time{}, fmt.Errorf("parsing application %s response: %w", clientID, err)
+	}
+
+	var expiry time.Time
+	for _, cred := range resp.PasswordCredentials {
+		if cred.Hint == "" || !strings.HasPrefix(secret, cred.Hint) {
+			continue
+		}
+		if expiry.IsZero() || cred.EndDateTime.Before(expiry) {
+			expiry = cred.EndDateTime
+		}
+	}
+	if expiry.IsZero() {
+		return time.Time{}, fmt.Errorf("application %s has no client secret matching AZURE_CLIENT_SECRET", clientID)
+	}
+	return expiry, nil
+}
+
+// certificateFileExpiry returns the end of the certificate in the PEM or
+// PKCS#12 file at path.
+func certificateFileExpiry(path, password string) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("reading client certificate: %w", err)
+	}
+	certs, _, err := azidentity.ParseCertificates(data, []byte(password))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing client certificate: %w", err)
+	}
+	if len(certs) == 0 {
+		return time.Time{}, errors.New("client certificate file has no certificate")
+	}
+	return certs[0].NotAfter, nil
+}
@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProvider_CredentialExpiry(t *testing.T) {
+	end := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("client secret", func(t *testing.T) {
+		var path string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			_ = json.NewEncoder(w).Encode(map[string]any{"passwordCredentials": []passwordCredential{
+				{KeyID: "other", Hint: "xyz", EndDateTime: end.Add(-time.Hour)},
+				{KeyID: "in-use", Hint: "abc", EndDateTime: end},
+			}})
+		}))
+		defer srv.Close()
+		t.Setenv("AZURE_CLIENT_ID", "client-1")
+		t.Setenv("AZURE_CLIENT_SECRET", "abcdefgh")
+
+		p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL))
+		got, err := p.CredentialExpiry(context.Background())
+		if err != nil {
+			t.Fatalf("CredentialExpiry: %v", err)
+		}
+		if !got.Equal(end) {
+			t.Errorf("expected the end of the matching client secret %v, got %v", end, got)
+		}
+		if want := "/applications(appId='client-1')"; path != want {
+			t.Errorf("expected a request for %s, got %s", want, path)
+		}
+	})
+
+	t.Run("no matching client secret", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{"passwordCredentials": []passwordCredential{
+				{KeyID: "other", Hint: "xyz", EndDateTime: end},
+			}})
+		}))
+		defer srv.Close()
+		t.Setenv("AZURE_CLIENT_ID", "client-1")
+		t.Setenv("AZURE_CLIENT_SECRET", "abcdefgh")
+
+		p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL))
+		if _, err := p.CredentialExpiry(context.Background()); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("client certificate", func(t *testing.T) {
+		cert, err := newCertificate("operator", time.Now(), end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := filepath.Join(t.TempDir(), "cert.pem")
+		if err := os.WriteFile(file, []byte(cert.certPEM+cert.keyPEM), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("AZURE_CLIENT_SECRET", "")
+		t.Setenv("AZURE_CLIENT_CERTIFICATE_PATH", file)
+
+		p := New(WithHTTPClient(http.DefaultClient))
+		got, err := p.CredentialExpiry(context.Background())
+		if err != nil {
+			t.Fatalf("CredentialExpiry: %v", err)
+		}
+		if !got.Equal(end) {
+			t.Errorf("expected the end of the certificate %v, got %v", end, got)
+		}
+	})
+
+	t.Run("workload identity", func(t *testing.T) {
+		t.Setenv("AZURE_CLIENT_SECRET", "")
+		t.Setenv("AZURE_CLIENT_CERTIFICATE_PATH", "")
+
+		p := New(WithHTTPClient(http.DefaultClient))
+		got, err := p.CredentialExpiry(context.Background())
+		if err != nil || !got.IsZero() {
+			t.Errorf("expected no expiry, got %v, %v", got, err)
+		}
+	})
+}
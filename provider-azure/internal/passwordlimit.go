@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultMaxPasswords is the default number of client secrets an application
+// or service principal may hold, see [WithMaxPasswords]. Microsoft Entra ID
+// rejects new credentials once their total size exceeds the limit of the
+// application object, which valet's secrets stay well below at this number.
+const DefaultMaxPasswords = 20
+
+// ensurePasswordCapacity makes room for another client secret on the
+// application or service principal of obj if it already holds the maximum,
+// by deleting the oldest secrets obj added other than its current one. It
+// fails if that is not enough, instead of letting Microsoft Graph reject the
+// new secret in the middle of a rotation.
+func (p *Provider) ensurePasswordCapacity(ctx context.Context, obj *v1alpha1.AzureClientSecret) error {
+	if p.maxPasswords <= 0 {
+		return nil
+	}
+	creds, err := p.listPasswords(ctx, obj)
+	if err != nil {
+		return err
+	}
+	excess := len(creds) - p.maxPasswords + 1
+	if excess <= 0 {
+		return nil
+	}
+
+	own := map[string]bool{}
+	for _, key := range obj.Status.ActiveKeys {
+		if key.KeyID != obj.Status.CurrentKeyID {
+			own[key.KeyID] = true
+		}
+	}
+	candidates := slices.DeleteFunc(creds, func(c passwordCredential) bool {
+		return !own[c.KeyID] || !strings.HasPrefix(c.DisplayName, displayNamePrefix)
+	})
+	if len(candidates) < excess {
+		return fmt.Errorf("%s holds %d client secrets, the maximum is %d and only %d of them can be deleted; "+
+			"delete unused client secrets or raise --max-client-secrets",
+			objectName(obj), len(creds), p.maxPasswords, len(candidates))
+	}
+
+	slices.SortFunc(candidates, func(a, b passwordCredential) int {
+		return a.StartDateTime.Compare(b.StartDateTime)
+	})
+	for _, c := range candidates[:excess] {
+		if err := p.deletePassword(ctx, obj, c.KeyID); err != nil {
+			return err
+		}
+		log.FromContext(ctx).Info("deleted client secret to stay within the limit",
+			"keyId", c.KeyID, "target", objectName(obj), "limit", p.maxPasswords)
+	}
+	return nil
+}
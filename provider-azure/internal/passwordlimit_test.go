@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+)
+
+func TestEnsurePasswordCapacity(t *testing.T) {
+	now := time.Now()
+	creds := []passwordCredential{
+		{KeyID: "current", DisplayName: "valet-2026-03-01", StartDateTime: now.Add(-24 * time.Hour)},
+		{KeyID: "previous", DisplayName: "valet-2026-02-01", StartDateTime: now.Add(-48 * time.Hour)},
+		{KeyID: "oldest", DisplayName: "valet-2026-01-01", StartDateTime: now.Add(-72 * time.Hour)},
+		{KeyID: "manual", DisplayName: "ci", StartDateTime: now.Add(-96 * time.Hour)},
+	}
+	obj := &v1alpha1.AzureClientSecret{Spec: v1alpha1.AzureClientSecretSpec{ObjectID: "obj-1"}}
+	obj.Status.CurrentKeyID = "current"
+	obj.Status.ActiveKeys = framework.ActiveKeys{{KeyID: "current"}, {KeyID: "previous"}, {KeyID: "oldest"}}
+
+	tests := []struct {
+		name        string
+		max         int
+		wantRemoved []string
+		wantErr     string
+	}{
+		{name: "below the maximum", max: 5},
+		{name: "deletes the oldest own secret", max: 4, wantRemoved: []string{"oldest"}},
+		{name: "deletes several", max: 3, wantRemoved: []string{"oldest", "previous"}},
+		{name: "keeps the current and foreign secrets", max: 2, wantErr: "only 2 of them can be deleted"},
+		{name: "disabled", max: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var removed []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/removePassword") {
+					var req removePasswordRequest
+					_ = json.NewDecoder(r.Body).Decode(&req)
+					removed = append(removed, req.KeyID)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				_ = json.NewEncoder(w).Encode(map[string]any{"passwordCredentials": creds})
+			}))
+			defer srv.Close()
+
+			p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL), WithMaxPasswords(tt.max))
+			err := p.ensurePasswordCapacity(context.Background(), obj)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(removed, ",") != strings.Join(tt.wantRemoved, ",") {
+				t.Fatalf("removed %v, want %v", removed, tt.wantRemoved)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+)
+
+// Precheck implements [framework.Prechecker]. It reads the application or
+// service principal of obj, so that a wrong object or client ID and missing
+// Microsoft Graph permissions are reported before the first rotation. It does
+// not prove that the operator may add credentials, which
+// Application.ReadWrite.OwnedBy only allows for applications it owns.
+func (p *Provider) Precheck(ctx context.Context, obj *v1alpha1.AzureClientSecret) error {
+	ctx, obj, err := p.prepare(ctx, obj)
+	if err != nil {
+		return err
+	}
+
+	_, err = withRetry(ctx, func() ([]byte, error) {
+		return p.graphRequest(ctx, "GET", objectPath(obj)+"?$select=id", nil)
+	})
+	var graphErr *graphError
+	if !errors.As(err, &graphErr) {
+		return err
+	}
+	p.recordGraphError(obj, "Precheck", err)
+	switch graphErr.StatusCode {
+	case http.StatusNotFound:
+		return fmt.Errorf("%s not found (request-id %s)", objectName(obj), graphErr.RequestID)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("insufficient permissions to read %s (request-id %s): grant the operator's identity "+
+			"Application.ReadWrite.OwnedBy and make it an owner of the application",
+			objectName(obj), graphErr.RequestID)
+	default:
+		return fmt.Errorf("getting %s: %w", objectName(obj), err)
+	}
+}
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+)
+
+func TestPrecheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr string
+	}{
+		{name: "exists", status: http.StatusOK},
+		{name: "not found", status: http.StatusNotFound, wantErr: "application obj-1 not found"},
+		{name: "forbidden", status: http.StatusForbidden, wantErr: "insufficient permissions to read application obj-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.Method+" "+r.URL.Path)
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(`{}`))
+			}))
+			defer srv.Close()
+
+			p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL))
+			obj := &v1alpha1.AzureClientSecret{Spec: v1alpha1.AzureClientSecretSpec{ObjectID: "obj-1"}}
+			err := p.Precheck(context.Background(), obj)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+			if len(paths) != 1 || paths[0] != "GET /applications/obj-1" {
+				t.Errorf("expected a single GET of the application, got %v", paths)
+			}
+		})
+	}
+}
@@ -4,20 +4,32 @@ package internal
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
+	"net/url"
+	"os"
+	"strconv"
 	"sync"
 	"text/template"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/google/uuid"
 	"github.com/lukasngl/valet/framework"
 	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -25,25 +37,58 @@ const (
 	// DefaultValidity is the default secret validity duration (90 days).
 	DefaultValidity = 90 * 24 * time.Hour
 
+	// DefaultScope is the token scope requested for Microsoft Graph: all
+	// application permissions granted to the operator's identity.
+	DefaultScope = "https://graph.microsoft.com/.default"
+
 	// graphBaseURL is the Microsoft Graph API base URL.
 	graphBaseURL = "https://graph.microsoft.com/v1.0"
 
-	// retryDelay is the wait time before retrying after a rate limit error.
+	// retryDelay is the wait time before retrying after a rate limit error
+	// without a Retry-After header.
 	retryDelay = 500 * time.Millisecond
 
+	// maxRetryDelay is the longest Retry-After the provider waits for. Longer
+	// ones fail the request, leaving the retry to the reconciler's backoff.
+	maxRetryDelay = time.Minute
+
 	// maxRetries is the maximum number of retries for rate-limited requests.
 	maxRetries = 5
+
+	// displayNamePrefix starts the display name of the credentials the
+	// provider adds, followed by their creation date.
+	displayNamePrefix = "valet-"
 )
 
-// Provider provisions Azure AD client secrets using Microsoft Graph API.
+// Provider provisions Azure AD client secrets and certificates using
+// Microsoft Graph API.
 // It implements [framework.Provider] for [*v1alpha1.AzureClientSecret].
 type Provider struct {
-	cred      *azidentity.DefaultAzureCredential
-	client    *http.Client
-	baseURL   string
-	initOnce  sync.Once
-	initErr   error
-	requestMu sync.Mutex // Serialize requests to avoid rate limiting.
+	cred          azcore.TokenCredential
+	client        *http.Client
+	baseURL       string
+	scopes        []string
+	authorityHost string
+	recorder      events.EventRecorder
+	secrets       client.Reader
+	credMu        sync.Mutex
+	creds         map[types.NamespacedName]secretCredential
+	cloudCreds    map[v1alpha1.Cloud]azcore.TokenCredential
+	objectIDMu    sync.Mutex
+	objectIDs     map[objectIDKey]string
+	initOnce      sync.Once
+	initErr       error
+	qps           rate.Limit
+	limiterMu     sync.Mutex
+	limiters      map[limiterKey]*rate.Limiter
+	objectLocksMu sync.Mutex
+	objectLocks   map[string]*objectLock
+	tokenMu       sync.Mutex
+	tokens        map[cachedTokenKey]azcore.AccessToken
+	maxPasswords  int
+	proxy         func(*http.Request) (*url.URL, error)
+	rootCAs       *x509.CertPool
+	transport     *http.Transport
 }
 
 // Option configures a [Provider].
@@ -55,31 +100,124 @@ func WithHTTPClient(c *http.Client) Option {
 	return func(p *Provider) { p.client = c }
 }
 
-// WithBaseURL overrides the Microsoft Graph API base URL.
+// WithBaseURL overrides the Microsoft Graph API base URL of resources that
+// select no cloud.
 func WithBaseURL(url string) Option {
 	return func(p *Provider) { p.baseURL = url }
 }
 
+// WithScopes overrides the scopes of the Microsoft Graph access token.
+// Defaults to [DefaultScope]. Tenants whose authentication policies reject
+// ".default" can request the needed permissions explicitly instead, e.g.
+// "https://graph.microsoft.com/Application.ReadWrite.OwnedBy". Resources
+// that select a cloud request the ".default" scope of its Graph endpoint.
+func WithScopes(scopes ...string) Option {
+	return func(p *Provider) {
+		if len(scopes) > 0 {
+			p.scopes = scopes
+		}
+	}
+}
+
+// WithAuthorityHost sets the Microsoft Entra authority that issues tokens,
+// e.g. "https://login.microsoftonline.us/" for Azure Government. Defaults to
+// the AZURE_AUTHORITY_HOST environment variable, or the public cloud.
+func WithAuthorityHost(host string) Option {
+	return func(p *Provider) { p.authorityHost = host }
+}
+
+// WithEventRecorder sets the recorder of the Warning events emitted for failed
+// Microsoft Graph requests. They carry the request IDs Azure support asks for.
+func WithEventRecorder(r events.EventRecorder) Option {
+	return func(p *Provider) { p.recorder = r }
+}
+
+// WithSecretReader sets the reader of Secrets. It reads the credentials
+// Secrets of resources with a credentialsRef, which cannot be provisioned
+// without one, and the output Secrets, from which certificate rotations read
+// the current certificate to prove possession of it to Microsoft Graph.
+// Without one, certificates are added and removed by updating the
+// application's keyCredentials, which replaces them all and so races with
+// other changes to them.
+func WithSecretReader(r client.Reader) Option {
+	return func(p *Provider) { p.secrets = r }
+}
+
+// WithQPS sets the rate of Microsoft Graph requests per tenant, with bursts
+// of as many requests. Defaults to [DefaultQPS]; zero or less disables the
+// limit. The rate is lowered while Graph throttles a tenant and restored
+// afterwards.
+func WithQPS(qps float64) Option {
+	return func(p *Provider) { p.qps = rate.Limit(qps) }
+}
+
+// WithMaxPasswords sets the number of client secrets an application or
+// service principal may hold before a rotation deletes the oldest ones the
+// resource added. Defaults to [DefaultMaxPasswords]; zero or less disables
+// the check.
+func WithMaxPasswords(n int) Option {
+	return func(p *Provider) { p.maxPasswords = n }
+}
+
+// WithProxy sets the proxy of requests to Microsoft Graph and the Microsoft
+// Entra authority. Defaults to the HTTPS_PROXY and NO_PROXY environment
+// variables. Ignored with [WithHTTPClient].
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) Option {
+	return func(p *Provider) { p.proxy = proxy }
+}
+
+// WithRootCAs sets the certificate authorities trusted by requests to
+// Microsoft Graph and the Microsoft Entra authority, e.g. the system's plus
+// that of a TLS-inspecting corporate proxy. Defaults to the system's. Ignored
+// with [WithHTTPClient].
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(p *Provider) { p.rootCAs = pool }
+}
+
 // New creates a [Provider] with the given options.
 func New(opts ...Option) *Provider {
-	p := &Provider{baseURL: graphBaseURL}
+	p := &Provider{
+		baseURL:      graphBaseURL,
+		scopes:       []string{DefaultScope},
+		qps:          DefaultQPS,
+		maxPasswords: DefaultMaxPasswords,
+	}
 	for _, o := range opts {
 		o(p)
 	}
+	p.transport = newTransport(p.proxy, p.rootCAs)
 	return p
 }
 
+// newTransport returns the transport of requests through proxy that trust
+// rootCAs, or nil if both are unset and the default transport is used.
+func newTransport(proxy func(*http.Request) (*url.URL, error), rootCAs *x509.CertPool) *http.Transport {
+	if proxy == nil && rootCAs == nil {
+		return nil
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != nil {
+		t.Proxy = proxy
+	}
+	if rootCAs != nil {
+		t.TLSClientConfig = &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}
+	}
+	return t
+}
+
 // NewObject returns a zero-value AzureClientSecret.
 func (p *Provider) NewObject() *v1alpha1.AzureClientSecret {
 	return &v1alpha1.AzureClientSecret{}
 }
 
-// Provision creates a new client secret for an Azure AD application.
+// Provision creates a new client secret or certificate for an Azure AD
+// application, depending on the resource's credentialType.
 func (p *Provider) Provision(
 	ctx context.Context,
 	obj *v1alpha1.AzureClientSecret,
 ) (*framework.Result, error) {
-	if err := p.initClient(); err != nil {
+	ctx, obj, err := p.prepare(ctx, obj)
+	if err != nil {
 		return nil, err
 	}
 
@@ -90,29 +228,115 @@ func (p *Provider) Provision(
 
 	now := time.Now()
 	endDateTime := now.Add(validity)
-	displayName := fmt.Sprintf("valet-%s", now.Format("2006-01-02"))
+	displayName := displayNamePrefix + now.Format("2006-01-02")
+
+	var (
+		keyID        string
+		hint         string
+		templateData map[string]string
+		data         map[string]string
+		secretType   corev1.SecretType
+	)
+	if obj.Spec.CredentialType == v1alpha1.CredentialTypeCertificate {
+		cert, err := p.provisionCertificate(ctx, obj, now, endDateTime, displayName)
+		if err != nil {
+			return nil, err
+		}
+		keyID = cert.keyID
+		templateData, data = certificateData(cert)
+		secretType = corev1.SecretTypeTLS
+	} else {
+		if err := p.ensurePasswordCapacity(ctx, obj); err != nil {
+			return nil, err
+		}
+		added, err := p.addPassword(ctx, obj, endDateTime, displayName)
+		if err != nil {
+			return nil, err
+		}
+		keyID, hint = added.KeyID, added.Hint
+		templateData = map[string]string{"ClientSecret": added.SecretText}
+		data = make(map[string]string, len(obj.Spec.Template))
+	}
+
+	// From here on, failures return the created key with the error, so that
+	// it is tracked and deleted on expiry.
+	created := &framework.Result{ProvisionedAt: now, ValidUntil: endDateTime, KeyID: keyID, Hint: hint}
+
+	// Get the application or service principal to retrieve client ID.
+	appBody, err := withRetry(ctx, func() ([]byte, error) {
+		return p.graphRequest(ctx, "GET", objectPath(obj), nil)
+	})
+	if err != nil {
+		p.recordGraphError(obj, "Provision", err)
+		return created, fmt.Errorf("getting %s: %w", objectName(obj), err)
+	}
+
+	var app applicationResponse
+	if err := json.Unmarshal(appBody, &app); err != nil {
+		return created, fmt.Errorf("parsing %s response: %w", objectName(obj), err)
+	}
+
+	// Render templates.
+	templateData["ClientID"] = app.AppID
+	templateData["TenantID"] = tenantID(ctx)
+	templateData["ObjectID"] = obj.Spec.ObjectID
+	templateData["ExpiresAt"] = endDateTime.UTC().Format(time.RFC3339)
+	templateData["KeyID"] = keyID
+	templateData["Hint"] = hint
+	for key, tmpl := range obj.Spec.Template {
+		rendered, err := renderTemplate(tmpl, templateData)
+		if err != nil {
+			return created, fmt.Errorf("rendering template %q: %w", key, err)
+		}
+		data[key] = rendered
+	}
+
+	// The client ID and secret also serve to verify the credential, see
+	// [Provider.VerifyCredential].
+	fields := map[string]string{framework.FieldUsername: app.AppID}
+	if secret, ok := templateData["ClientSecret"]; ok {
+		fields[framework.FieldPassword] = secret
+	}
 
+	return &framework.Result{
+		StringData:    data,
+		Fields:        fields,
+		ProvisionedAt: now,
+		ValidUntil:    endDateTime,
+		KeyID:         keyID,
+		Hint:          hint,
+		SecretType:    secretType,
+	}, nil
+}
+
+// addPassword adds a client secret to the application and returns its
+// value, key ID and hint.
+func (p *Provider) addPassword(
+	ctx context.Context,
+	obj *v1alpha1.AzureClientSecret,
+	endDateTime time.Time,
+	displayName string,
+) (*addPasswordResponse, error) {
 	reqBody := addPasswordRequest{
-		PasswordCredential: passwordCredential{
+		PasswordCredential: newPasswordCredential{
 			DisplayName: &displayName,
 			EndDateTime: &endDateTime,
 		},
 	}
 
-	// Serialize requests to avoid rate limiting.
-	p.requestMu.Lock()
-	defer p.requestMu.Unlock()
+	defer p.lockObject(obj)()
 
 	respBody, err := withRetry(ctx, func() ([]byte, error) {
 		return p.graphRequest(
 			ctx,
 			"POST",
-			"/applications/"+obj.Spec.ObjectID+"/addPassword",
+			objectPath(obj)+"/addPassword",
 			reqBody,
 		)
 	})
 	if err != nil {
-		return nil, fmt.Errorf("adding password to application %s: %w", obj.Spec.ObjectID, err)
+		p.recordGraphError(obj, "Provision", err)
+		return nil, fmt.Errorf("adding password to %s: %w", objectName(obj), err)
 	}
 
 	var passwordResult addPasswordResponse
@@ -124,44 +348,11 @@ func (p *Provider) Provision(
 		return nil, errors.New("no secret text returned from Graph API")
 	}
 
-	// Get the application to retrieve client ID.
-	appBody, err := withRetry(ctx, func() ([]byte, error) {
-		return p.graphRequest(ctx, "GET", "/applications/"+obj.Spec.ObjectID, nil)
-	})
-	if err != nil {
-		return nil, fmt.Errorf("getting application %s: %w", obj.Spec.ObjectID, err)
-	}
-
-	var app applicationResponse
-	if err := json.Unmarshal(appBody, &app); err != nil {
-		return nil, fmt.Errorf("parsing application response: %w", err)
-	}
-
-	// Render templates.
-	templateData := map[string]string{
-		"ClientID":     app.AppID,
-		"ClientSecret": passwordResult.SecretText,
-	}
-
-	data := make(map[string]string, len(obj.Spec.Template))
-	for key, tmpl := range obj.Spec.Template {
-		rendered, err := renderTemplate(tmpl, templateData)
-		if err != nil {
-			return nil, fmt.Errorf("rendering template %q: %w", key, err)
-		}
-		data[key] = rendered
-	}
-
-	return &framework.Result{
-		StringData:    data,
-		ProvisionedAt: now,
-		ValidUntil:    endDateTime,
-		KeyID:         passwordResult.KeyID,
-	}, nil
+	return &passwordResult, nil
 }
 
-// DeleteKey removes a password credential from an Azure AD application.
-// Returns nil if the key has already been deleted (idempotent).
+// DeleteKey removes a password or certificate credential from an Azure AD
+// application. Returns nil if the key has already been deleted (idempotent).
 func (p *Provider) DeleteKey(
 	ctx context.Context,
 	obj *v1alpha1.AzureClientSecret,
@@ -171,38 +362,96 @@ func (p *Provider) DeleteKey(
 		return nil
 	}
 
-	if err := p.initClient(); err != nil {
+	ctx, obj, err := p.prepare(ctx, obj)
+	if err != nil {
 		return err
 	}
 
+	if obj.Spec.CredentialType == v1alpha1.CredentialTypeCertificate {
+		return p.deleteCertificate(ctx, obj, keyID)
+	}
+	return p.deletePassword(ctx, obj, keyID)
+}
+
+// prepare returns ctx carrying the cloud and credential obj selects, and obj
+// with its Object ID resolved, for the Graph requests on its behalf.
+func (p *Provider) prepare(
+	ctx context.Context,
+	obj *v1alpha1.AzureClientSecret,
+) (context.Context, *v1alpha1.AzureClientSecret, error) {
+	if err := p.initClient(); err != nil {
+		return nil, nil, err
+	}
+	ctx, err := p.withCloud(ctx, obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, err = p.withCredential(ctx, obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	obj, err = p.resolveObjectID(ctx, obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ctx, obj, nil
+}
+
+// deletePassword removes a client secret from the application.
+func (p *Provider) deletePassword(
+	ctx context.Context,
+	obj *v1alpha1.AzureClientSecret,
+	keyID string,
+) error {
 	reqBody := removePasswordRequest{KeyID: keyID}
 
-	p.requestMu.Lock()
-	defer p.requestMu.Unlock()
+	defer p.lockObject(obj)()
 
 	err := withRetryNoResult(ctx, func() error {
 		_, err := p.graphRequest(
 			ctx,
 			"POST",
-			"/applications/"+obj.Spec.ObjectID+"/removePassword",
+			objectPath(obj)+"/removePassword",
 			reqBody,
 		)
 		return err
 	})
 	if err != nil {
 		// Key already deleted at the provider — not an error.
-		if strings.Contains(err.Error(), "No password credential found") {
+		if isNotFoundError(err) {
 			log.FromContext(ctx).
 				Info("key already deleted", "keyId", keyID, "objectId", obj.Spec.ObjectID)
 			return nil
 		}
-		return fmt.Errorf("removing password %s from application %s: %w",
-			keyID, obj.Spec.ObjectID, err)
+		p.recordGraphError(obj, "DeleteKey", err)
+		return fmt.Errorf("removing password %s from %s: %w", keyID, objectName(obj), err)
 	}
 
 	return nil
 }
 
+// Endpoints returns the Microsoft Entra authority and Microsoft Graph. It
+// implements [framework.EndpointDeclarer].
+func (p *Provider) Endpoints() ([]framework.Endpoint, error) {
+	authorityHost := p.authorityHost
+	if authorityHost == "" {
+		authorityHost = os.Getenv("AZURE_AUTHORITY_HOST")
+	}
+	if authorityHost == "" {
+		authorityHost = cloud.AzurePublic.ActiveDirectoryAuthorityHost
+	}
+
+	authority, err := framework.EndpointFromURL("Microsoft Entra ID", authorityHost)
+	if err != nil {
+		return nil, err
+	}
+	graph, err := framework.EndpointFromURL("Microsoft Graph", p.baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return []framework.Endpoint{authority, graph}, nil
+}
+
 // initClient initializes the Azure credential and HTTP client on first use.
 // If the client was pre-configured via [WithHTTPClient], initialization is
 // skipped (no Azure credentials required).
@@ -211,18 +460,48 @@ func (p *Provider) initClient() error {
 		if p.client != nil {
 			return // pre-configured, e.g. for testing
 		}
-		cred, err := azidentity.NewDefaultAzureCredential(nil)
+		cred, err := azidentity.NewDefaultAzureCredential(p.credentialOptions())
 		if err != nil {
 			p.initErr = fmt.Errorf("creating Azure credential: %w", err)
 			return
 		}
 		p.cred = cred
 		p.client = &http.Client{Timeout: 30 * time.Second}
+		if p.transport != nil {
+			p.client.Transport = p.transport
+		}
 	})
 	return p.initErr
 }
 
-// graphRequest makes an authenticated request to Microsoft Graph API.
+// credentialOptions returns the options of the Azure credential, or nil for
+// the defaults.
+func (p *Provider) credentialOptions() *azidentity.DefaultAzureCredentialOptions {
+	if p.authorityHost == "" && p.transport == nil {
+		return nil
+	}
+	opts := &azidentity.DefaultAzureCredentialOptions{
+		ClientOptions: policy.ClientOptions{Transport: p.tokenTransport()},
+	}
+	if p.authorityHost != "" {
+		opts.Cloud = cloud.Configuration{ActiveDirectoryAuthorityHost: p.authorityHost}
+	}
+	return opts
+}
+
+// tokenTransport returns the transport of the token requests of credentials,
+// or nil for the default one.
+func (p *Provider) tokenTransport() policy.Transporter {
+	if p.transport == nil {
+		return nil
+	}
+	return &http.Client{Transport: p.transport}
+}
+
+// graphRequest makes an authenticated request to Microsoft Graph API. Each
+// request carries a fresh client-request-id, which is logged at debug level
+// together with the request-id Graph responds with, and included in errors.
+// Error responses are returned as [*graphError].
 func (p *Provider) graphRequest(
 	ctx context.Context,
 	method, path string,
@@ -237,48 +516,147 @@ func (p *Provider) graphRequest(
 		reqBody = bytes.NewReader(jsonBody)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, reqBody)
+	baseURL, scopes := p.baseURL, p.scopes
+	if endpoints, ok := ctx.Value(cloudKey{}).(*cloudEndpoints); ok {
+		baseURL, scopes = endpoints.graphURL, []string{endpoints.scope}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, baseURL+path, reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
+	cred := p.cred
+	if c, ok := ctx.Value(credentialKey{}).(azcore.TokenCredential); ok {
+		cred = c
+	}
 	// Skip auth when pre-configured via WithHTTPClient (e.g. tests).
-	if p.cred != nil {
-		token, err := p.cred.GetToken(ctx, policy.TokenRequestOptions{
-			Scopes: []string{"https://graph.microsoft.com/.default"},
-		})
+	if cred != nil {
+		token, err := p.token(ctx, cred, scopes)
 		if err != nil {
-			return nil, fmt.Errorf("getting token: %w", err)
+			return nil, err
 		}
-		req.Header.Set("Authorization", "Bearer "+token.Token)
+		req.Header.Set("Authorization", "Bearer "+token)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	clientRequestID := uuid.New().String()
+	req.Header.Set(headerClientRequestID, clientRequestID)
+
+	lim := p.limiter(ctx)
+	if lim != nil {
+		if err := lim.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("waiting for rate limiter: %w", err)
+		}
+	}
 
+	start := time.Now()
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("request failed (client-request-id %s): %w", clientRequestID, err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	requestID := resp.Header.Get(headerRequestID)
+	if id := resp.Header.Get(headerClientRequestID); id != "" {
+		clientRequestID = id
+	}
+	log.FromContext(ctx).V(1).Info("graph request",
+		"method", method,
+		"path", path,
+		"status", resp.StatusCode,
+		"duration", time.Since(start),
+		"requestId", requestID,
+		"clientRequestId", clientRequestID)
+
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
+		return nil, fmt.Errorf("reading response (request-id %s): %w", requestID, err)
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("graph API error (status %d): %s", resp.StatusCode, string(respBody))
+		graphErr := &graphError{
+			Method:          method,
+			Path:            path,
+			StatusCode:      resp.StatusCode,
+			Body:            string(respBody),
+			RequestID:       requestID,
+			ClientRequestID: clientRequestID,
+			RetryAfter:      parseRetryAfter(resp.Header.Get(headerRetryAfter), time.Now()),
+		}
+		var odata odataError
+		if json.Unmarshal(respBody, &odata) == nil {
+			graphErr.Code, graphErr.Message = odata.Error.Code, odata.Error.Message
+		}
+		if lim != nil {
+			p.adapt(lim, isRateLimitError(graphErr))
+		}
+		return nil, graphErr
 	}
 
+	if lim != nil {
+		p.adapt(lim, false)
+	}
 	return respBody, nil
 }
 
+// Headers identifying a Microsoft Graph request.
+const (
+	headerRequestID       = "request-id"
+	headerClientRequestID = "client-request-id"
+)
+
+// headerRetryAfter is the header of throttled responses telling how long to
+// wait before retrying.
+const headerRetryAfter = "Retry-After"
+
+// graphError is an error response of Microsoft Graph.
+type graphError struct {
+	Method          string
+	Path            string
+	StatusCode      int
+	Body            string
+	RequestID       string
+	ClientRequestID string
+
+	// Code and Message are taken from the OData error in Body, if any.
+	Code    string
+	Message string
+
+	// RetryAfter is the wait the Retry-After header asks for, or zero.
+	RetryAfter time.Duration
+}
+
+// odataError is the body of a Microsoft Graph error response.
+type odataError struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (e *graphError) Error() string {
+	return fmt.Sprintf("graph API error (status %d, request-id %s, client-request-id %s): %s",
+		e.StatusCode, e.RequestID, e.ClientRequestID, e.Body)
+}
+
+// recordGraphError emits a Warning event on obj for a failed Graph request,
+// if err is one and an event recorder is configured.
+func (p *Provider) recordGraphError(obj *v1alpha1.AzureClientSecret, action string, err error) {
+	var graphErr *graphError
+	if p.recorder == nil || !errors.As(err, &graphErr) {
+		return
+	}
+	p.recorder.Eventf(obj, nil, corev1.EventTypeWarning, "GraphRequestFailed", action,
+		"Graph %s %s failed with status %d (request-id %s, client-request-id %s)",
+		graphErr.Method, graphErr.Path, graphErr.StatusCode, graphErr.RequestID, graphErr.ClientRequestID)
+}
+
 // Graph API request/response types.
 
 type addPasswordRequest struct {
-	PasswordCredential passwordCredential `json:"passwordCredential"`
+	PasswordCredential newPasswordCredential `json:"passwordCredential"`
 }
 
-type passwordCredential struct {
+type newPasswordCredential struct {
 	DisplayName *string    `json:"displayName,omitempty"`
 	EndDateTime *time.Time `json:"endDateTime,omitempty"`
 }
@@ -286,6 +664,7 @@ type passwordCredential struct {
 type addPasswordResponse struct {
 	KeyID      string `json:"keyId"`
 	SecretText string `json:"secretText"`
+	Hint       string `json:"hint"`
 }
 
 type applicationResponse struct {
@@ -298,35 +677,79 @@ type removePasswordRequest struct {
 
 // Retry helpers.
 
-// isRateLimitError checks if the error is a rate limiting error.
+// rateLimitCodes are the OData error codes of throttled requests that Graph
+// may return without status 429, e.g. for too many concurrent changes to the
+// same object.
+var rateLimitCodes = map[string]bool{
+	"activityLimitReached":         true,
+	"Request_ThrottledTemporarily": true,
+	"TooManyRequests":              true,
+}
+
+// isRateLimitError checks if the error is a throttled Graph response, i.e.
+// one with status 429, a throttling error code or a Retry-After header.
 func isRateLimitError(err error) bool {
-	if err == nil {
+	var graphErr *graphError
+	if !errors.As(err, &graphErr) {
 		return false
 	}
-	msg := strings.ToLower(err.Error())
-	return strings.Contains(msg, "concurrent") ||
-		strings.Contains(msg, "throttl") ||
-		strings.Contains(msg, "rate limit") ||
-		strings.Contains(msg, "too many requests") ||
-		strings.Contains(msg, "status 429")
+	return graphErr.StatusCode == http.StatusTooManyRequests ||
+		rateLimitCodes[graphErr.Code] ||
+		graphErr.RetryAfter > 0
 }
 
-// withRetry executes fn with retry logic for rate limiting errors.
+// isNotFoundError checks if the error is a Graph response for a missing
+// resource, e.g. removing a password credential that no longer exists.
+func isNotFoundError(err error) bool {
+	var graphErr *graphError
+	if !errors.As(err, &graphErr) {
+		return false
+	}
+	return graphErr.StatusCode == http.StatusNotFound || graphErr.Code == "Request_ResourceNotFound"
+}
+
+// parseRetryAfter returns the wait a Retry-After header value asks for,
+// given either in seconds or as an HTTP date, or zero if there is none.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
+
+// withRetry executes fn with retry logic for rate limiting errors, waiting
+// for the Retry-After of the response or [retryDelay] between attempts.
 func withRetry[T any](ctx context.Context, fn func() (T, error)) (T, error) {
 	var result T
 	var err error
 
 	for attempt := range maxRetries + 1 {
 		result, err = fn()
-		if err == nil || !isRateLimitError(err) {
+		if err == nil || !isRateLimitError(err) || attempt == maxRetries {
 			return result, err
 		}
 
-		if attempt < maxRetries {
-			log.FromContext(ctx).Info("rate limited, retrying",
-				"attempt", attempt+1,
-				"delay", retryDelay)
-			time.Sleep(retryDelay)
+		delay := retryDelay
+		var graphErr *graphError
+		if errors.As(err, &graphErr) && graphErr.RetryAfter > 0 {
+			delay = graphErr.RetryAfter
+		}
+		if delay > maxRetryDelay {
+			return result, err
+		}
+		log.FromContext(ctx).Info("rate limited, retrying",
+			"attempt", attempt+1,
+			"delay", delay)
+		select {
+		case <-ctx.Done():
+			return result, errors.Join(err, ctx.Err())
+		case <-time.After(delay):
 		}
 	}
 
@@ -341,6 +764,24 @@ func withRetryNoResult(ctx context.Context, fn func() error) error {
 	return err
 }
 
+// objectPath returns the Graph API path of the application or service
+// principal obj adds credentials to.
+func objectPath(obj *v1alpha1.AzureClientSecret) string {
+	if obj.Spec.TargetType == v1alpha1.TargetTypeServicePrincipal {
+		return "/servicePrincipals/" + obj.Spec.ObjectID
+	}
+	return "/applications/" + obj.Spec.ObjectID
+}
+
+// objectName describes the application or service principal obj adds
+// credentials to, for error messages.
+func objectName(obj *v1alpha1.AzureClientSecret) string {
+	if obj.Spec.TargetType == v1alpha1.TargetTypeServicePrincipal {
+		return "service principal " + obj.Spec.ObjectID
+	}
+	return "application " + obj.Spec.ObjectID
+}
+
 // renderTemplate renders a Go template string with the given data.
 func renderTemplate(tmpl string, data map[string]string) (string, error) {
 	t, err := template.New("").Parse(tmpl)
@@ -2,14 +2,23 @@ package internal
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/lukasngl/valet/framework"
 	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+	"k8s.io/client-go/tools/events"
 )
 
 func TestIsRateLimitError(t *testing.T) {
@@ -18,12 +27,12 @@ func TestIsRateLimitError(t *testing.T) {
 		want bool
 	}{
 		{nil, false},
-		{errors.New("something else"), false},
-		{errors.New("concurrent request detected"), true},
-		{errors.New("request was throttled"), true},
-		{errors.New("rate limit exceeded"), true},
-		{errors.New("too many requests"), true},
-		{errors.New("graph API error (status 429): retry later"), true},
+		{errors.New("graph API error (status 429): too many requests"), false},
+		{&graphError{StatusCode: 403, Code: "Authorization_RequestDenied"}, false},
+		{&graphError{StatusCode: 429, RequestID: "r-1", ClientRequestID: "c-1"}, true},
+		{&graphError{StatusCode: 400, Code: "Request_ThrottledTemporarily"}, true},
+		{&graphError{StatusCode: 503, RetryAfter: time.Second}, true},
+		{fmt.Errorf("adding password: %w", &graphError{StatusCode: 429}), true},
 	}
 
 	for _, tt := range tests {
@@ -76,7 +85,7 @@ func TestWithRetry(t *testing.T) {
 		result, err := withRetry(context.Background(), func() (string, error) {
 			calls++
 			if calls < 3 {
-				return "", errors.New("too many requests")
+				return "", &graphError{StatusCode: http.StatusTooManyRequests}
 			}
 			return "recovered", nil
 		})
@@ -90,6 +99,68 @@ func TestWithRetry(t *testing.T) {
 			t.Fatalf("expected 3 calls, got %d", calls)
 		}
 	})
+
+	t.Run("waits for Retry-After", func(t *testing.T) {
+		var calls []time.Time
+		_, err := withRetry(context.Background(), func() (string, error) {
+			calls = append(calls, time.Now())
+			if len(calls) < 2 {
+				return "", &graphError{StatusCode: http.StatusTooManyRequests, RetryAfter: 50 * time.Millisecond}
+			}
+			return "recovered", nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if wait := calls[1].Sub(calls[0]); wait < 50*time.Millisecond || wait >= retryDelay {
+			t.Fatalf("waited %v, want the Retry-After of 50ms", wait)
+		}
+	})
+
+	t.Run("gives up on long Retry-After", func(t *testing.T) {
+		calls := 0
+		_, err := withRetry(context.Background(), func() (string, error) {
+			calls++
+			return "", &graphError{StatusCode: http.StatusTooManyRequests, RetryAfter: 2 * maxRetryDelay}
+		})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if calls != 1 {
+			t.Fatalf("expected 1 call, got %d", calls)
+		}
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := withRetry(ctx, func() (string, error) {
+			return "", &graphError{StatusCode: http.StatusTooManyRequests}
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"-5", 0},
+		{"Fri, 02 Jan 2026 15:04:35 GMT", 30 * time.Second},
+		{"Fri, 02 Jan 2026 15:00:00 GMT", 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
 }
 
 func TestGraphRequest(t *testing.T) {
@@ -158,6 +229,79 @@ func TestGraphRequest(t *testing.T) {
 		}
 	})
 
+	t.Run("error carries request IDs", func(t *testing.T) {
+		var sent string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sent = r.Header.Get("client-request-id")
+			w.Header().Set("request-id", "req-123")
+			w.Header().Set("client-request-id", sent)
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error":"throttled"}`))
+		}))
+		defer srv.Close()
+
+		p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL))
+		_, err := p.graphRequest(context.Background(), "GET", "/test", nil)
+		if sent == "" {
+			t.Fatal("expected a client-request-id header")
+		}
+		var graphErr *graphError
+		if !errors.As(err, &graphErr) {
+			t.Fatalf("expected graphError, got %v", err)
+		}
+		if graphErr.RequestID != "req-123" || graphErr.ClientRequestID != sent {
+			t.Fatalf("got request IDs %q/%q, want req-123/%s", graphErr.RequestID, graphErr.ClientRequestID, sent)
+		}
+		for _, want := range []string{"status 429", "request-id req-123", "client-request-id " + sent} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected %q in error, got: %v", want, err)
+			}
+		}
+	})
+
+	t.Run("error carries OData error and Retry-After", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error":{"code":"TooManyRequests","message":"Too many requests."}}`))
+		}))
+		defer srv.Close()
+
+		p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL))
+		_, err := p.graphRequest(context.Background(), "GET", "/test", nil)
+		var graphErr *graphError
+		if !errors.As(err, &graphErr) {
+			t.Fatalf("expected graphError, got %v", err)
+		}
+		if graphErr.Code != "TooManyRequests" || graphErr.Message != "Too many requests." {
+			t.Fatalf("got code %q and message %q", graphErr.Code, graphErr.Message)
+		}
+		if graphErr.RetryAfter != 7*time.Second {
+			t.Fatalf("got Retry-After %v, want 7s", graphErr.RetryAfter)
+		}
+	})
+
+	t.Run("requests token with configured scopes", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if got := r.Header.Get("Authorization"); got != "Bearer fake-token" {
+				t.Fatalf("unexpected Authorization header %q", got)
+			}
+			_, _ = w.Write([]byte(`{}`))
+		}))
+		defer srv.Close()
+
+		scope := "https://graph.microsoft.com/Application.ReadWrite.OwnedBy"
+		cred := &fakeCredential{}
+		p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL), WithScopes(scope))
+		p.cred = cred
+		if _, err := p.graphRequest(context.Background(), "GET", "/test", nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(cred.scopes) != 1 || cred.scopes[0] != scope {
+			t.Fatalf("got scopes %v, want [%s]", cred.scopes, scope)
+		}
+	})
+
 	t.Run("request failure", func(t *testing.T) {
 		p := New(WithHTTPClient(&http.Client{}), WithBaseURL("http://127.0.0.1:1"))
 		_, err := p.graphRequest(context.Background(), "GET", "/test", nil)
@@ -200,6 +344,96 @@ func TestInitClient(t *testing.T) {
 	})
 }
 
+func TestCredentialOptions(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		p := New()
+		if opts := p.credentialOptions(); opts != nil {
+			t.Fatalf("expected default options, got %+v", opts)
+		}
+		if len(p.scopes) != 1 || p.scopes[0] != DefaultScope {
+			t.Fatalf("got scopes %v, want [%s]", p.scopes, DefaultScope)
+		}
+	})
+
+	t.Run("authority host", func(t *testing.T) {
+		host := "https://login.microsoftonline.us/"
+		opts := New(WithAuthorityHost(host)).credentialOptions()
+		if opts == nil || opts.Cloud.ActiveDirectoryAuthorityHost != host {
+			t.Fatalf("expected authority host %s, got %+v", host, opts)
+		}
+	})
+
+	t.Run("empty scopes keep the default", func(t *testing.T) {
+		if p := New(WithScopes()); len(p.scopes) != 1 || p.scopes[0] != DefaultScope {
+			t.Fatalf("got scopes %v, want [%s]", p.scopes, DefaultScope)
+		}
+	})
+
+	t.Run("proxy and root CAs", func(t *testing.T) {
+		proxyURL := &url.URL{Scheme: "http", Host: "proxy.example.test:3128"}
+		pool := x509.NewCertPool()
+		p := New(WithProxy(http.ProxyURL(proxyURL)), WithRootCAs(pool))
+
+		if opts := p.credentialOptions(); opts == nil || opts.Transport == nil {
+			t.Fatalf("expected token requests to use the custom transport, got %+v", opts)
+		}
+		got, err := p.transport.Proxy(&http.Request{URL: &url.URL{Scheme: "https", Host: "graph.microsoft.com"}})
+		if err != nil || got == nil || got.String() != proxyURL.String() {
+			t.Errorf("got proxy %v (%v), want %s", got, err, proxyURL)
+		}
+		if p.transport.TLSClientConfig == nil || p.transport.TLSClientConfig.RootCAs != pool {
+			t.Error("expected the root CAs to be trusted")
+		}
+	})
+}
+
+func TestEndpoints(t *testing.T) {
+	t.Run("public cloud", func(t *testing.T) {
+		t.Setenv("AZURE_AUTHORITY_HOST", "")
+
+		endpoints, err := New().Endpoints()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(endpoints) != 2 ||
+			endpoints[0].String() != "login.microsoftonline.com:443" ||
+			endpoints[1].String() != "graph.microsoft.com:443" {
+			t.Fatalf("got endpoints %v", endpoints)
+		}
+	})
+
+	t.Run("authority host", func(t *testing.T) {
+		t.Setenv("AZURE_AUTHORITY_HOST", "https://login.chinacloudapi.cn/")
+
+		endpoints, err := New(WithAuthorityHost("https://login.microsoftonline.us/")).Endpoints()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if endpoints[0].Host != "login.microsoftonline.us" {
+			t.Fatalf("got authority %v, want the option to take precedence", endpoints[0])
+		}
+	})
+}
+
+// fakeCredential is an [azcore.TokenCredential] that records the requested
+// scopes.
+type fakeCredential struct {
+	scopes []string
+	calls  int
+	// expiresIn is the lifetime of the issued tokens, an hour if zero.
+	expiresIn time.Duration
+}
+
+func (c *fakeCredential) GetToken(_ context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	c.scopes = opts.Scopes
+	c.calls++
+	expiresIn := c.expiresIn
+	if expiresIn == 0 {
+		expiresIn = time.Hour
+	}
+	return azcore.AccessToken{Token: "fake-token", ExpiresOn: time.Now().Add(expiresIn)}, nil
+}
+
 // TestE2E groups tests that require network access (e.g. Azure AD).
 // Skipped with -short; targeted by the e2e nix app via -run TestE2E.
 func TestE2E(t *testing.T) {
@@ -269,6 +503,79 @@ func TestProvision(t *testing.T) {
 		if result.StringData["CLIENT_SECRET"] != "s3cret" {
 			t.Fatalf("got CLIENT_SECRET %q, want %q", result.StringData["CLIENT_SECRET"], "s3cret")
 		}
+		want := map[string]string{framework.FieldUsername: "app-123", framework.FieldPassword: "s3cret"}
+		if !maps.Equal(result.Fields, want) {
+			t.Errorf("got fields %v, want %v", result.Fields, want)
+		}
+	})
+
+	t.Run("connection variables", func(t *testing.T) {
+		t.Setenv("AZURE_TENANT_ID", "tenant-a")
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/addPassword") {
+				_ = json.NewEncoder(w).Encode(addPasswordResponse{KeyID: "key-1", SecretText: "s3cret", Hint: "s3c"})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(applicationResponse{AppID: "app-123"})
+		}))
+		defer srv.Close()
+
+		p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL))
+		obj := newObj("obj-1", map[string]string{
+			"TENANT_ID":  "{{ .TenantID }}",
+			"OBJECT_ID":  "{{ .ObjectID }}",
+			"EXPIRES_AT": "{{ .ExpiresAt }}",
+			"KEY":        "{{ .KeyID }} ({{ .Hint }}...)",
+		})
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.StringData["TENANT_ID"] != "tenant-a" {
+			t.Errorf("got TENANT_ID %q, want %q", result.StringData["TENANT_ID"], "tenant-a")
+		}
+		if result.StringData["OBJECT_ID"] != "obj-1" {
+			t.Errorf("got OBJECT_ID %q, want %q", result.StringData["OBJECT_ID"], "obj-1")
+		}
+		if want := result.ValidUntil.UTC().Format(time.RFC3339); result.StringData["EXPIRES_AT"] != want {
+			t.Errorf("got EXPIRES_AT %q, want %q", result.StringData["EXPIRES_AT"], want)
+		}
+		if result.StringData["KEY"] != "key-1 (s3c...)" {
+			t.Errorf("got KEY %q, want %q", result.StringData["KEY"], "key-1 (s3c...)")
+		}
+		if result.Hint != "s3c" {
+			t.Errorf("got hint %q, want %q", result.Hint, "s3c")
+		}
+	})
+
+	t.Run("service principal", func(t *testing.T) {
+		var paths []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			if strings.HasSuffix(r.URL.Path, "/addPassword") {
+				_ = json.NewEncoder(w).Encode(addPasswordResponse{KeyID: "key-1", SecretText: "s3cret"})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(applicationResponse{AppID: "app-123"})
+		}))
+		defer srv.Close()
+
+		p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL))
+		obj := newObj("sp-1", map[string]string{"CLIENT_ID": "{{ .ClientID }}"})
+		obj.Spec.TargetType = v1alpha1.TargetTypeServicePrincipal
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := []string{"/servicePrincipals/sp-1", "/servicePrincipals/sp-1/addPassword", "/servicePrincipals/sp-1"}
+		if strings.Join(paths, ",") != strings.Join(want, ",") {
+			t.Fatalf("got requests to %v, want %v", paths, want)
+		}
+		if result.StringData["CLIENT_ID"] != "app-123" {
+			t.Fatalf("got CLIENT_ID %q, want %q", result.StringData["CLIENT_ID"], "app-123")
+		}
 	})
 
 	t.Run("empty secret text", func(t *testing.T) {
@@ -293,7 +600,7 @@ func TestProvision(t *testing.T) {
 		}))
 		defer srv.Close()
 
-		p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL))
+		p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL), WithMaxPasswords(0))
 		_, err := p.Provision(context.Background(), newObj("obj-1", map[string]string{"K": "v"}))
 		if err == nil {
 			t.Fatal("expected unmarshal error")
@@ -320,8 +627,57 @@ func TestProvision(t *testing.T) {
 		if err == nil {
 			t.Fatal("expected unmarshal error")
 		}
-		if !strings.Contains(err.Error(), "parsing application response") {
-			t.Fatalf("expected 'parsing application response' error, got: %v", err)
+		if !strings.Contains(err.Error(), "parsing application obj-1 response") {
+			t.Fatalf("expected 'parsing application obj-1 response' error, got: %v", err)
+		}
+	})
+
+	t.Run("failed application request returns the created key", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/addPassword") {
+				_ = json.NewEncoder(w).Encode(addPasswordResponse{
+					KeyID: "key-1", SecretText: "s3cret",
+				})
+				return
+			}
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error":{"code":"Authorization_RequestDenied","message":"denied"}}`))
+		}))
+		defer srv.Close()
+
+		p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL), WithMaxPasswords(0))
+		result, err := p.Provision(context.Background(), newObj("obj-1", map[string]string{"K": "v"}))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if result == nil || result.KeyID != "key-1" || result.ValidUntil.IsZero() {
+			t.Fatalf("expected the created key with the error, got %+v", result)
+		}
+	})
+
+	t.Run("failed request emits event with request IDs", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("request-id", "req-123")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error":"access denied"}`))
+		}))
+		defer srv.Close()
+
+		recorder := events.NewFakeRecorder(1)
+		p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL), WithEventRecorder(recorder), WithMaxPasswords(0))
+		_, err := p.Provision(context.Background(), newObj("obj-1", nil))
+		if err == nil || !strings.Contains(err.Error(), "request-id req-123") {
+			t.Fatalf("expected error with request ID, got: %v", err)
+		}
+		select {
+		case event := <-recorder.Events:
+			if !strings.Contains(event, "Warning GraphRequestFailed") ||
+				!strings.Contains(event, "/applications/obj-1/addPassword") ||
+				!strings.Contains(event, "request-id req-123") {
+				t.Errorf("unexpected event: %s", event)
+			}
+		default:
+			t.Fatal("expected GraphRequestFailed event")
 		}
 	})
 
@@ -378,10 +734,36 @@ func TestDeleteKey(t *testing.T) {
 		}
 	})
 
+	t.Run("service principal", func(t *testing.T) {
+		var path string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer srv.Close()
+
+		p := New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL))
+		obj := &v1alpha1.AzureClientSecret{
+			Spec: v1alpha1.AzureClientSecretSpec{
+				ObjectID:   "sp-1",
+				TargetType: v1alpha1.TargetTypeServicePrincipal,
+			},
+		}
+		if err := p.DeleteKey(context.Background(), obj, "key-1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if path != "/servicePrincipals/sp-1/removePassword" {
+			t.Fatalf("got request to %s", path)
+		}
+	})
+
 	t.Run("already deleted is idempotent", func(t *testing.T) {
 		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-			w.WriteHeader(http.StatusBadRequest)
-			_, _ = w.Write([]byte(`{"error":"No password credential found"}`))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":"Request_ResourceNotFound",` +
+				`"message":"No password credential found with keyId 'gone-key'.",` +
+				`"innerError":{"date":"2026-10-16T08:00:00","request-id":"r-1","client-request-id":"c-1"}}}`))
 		}))
 		defer srv.Close()
 
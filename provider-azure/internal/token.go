@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// tokenRefreshMargin is how long before its expiry a cached access token is
+// replaced, so that requests never carry a token that expires in flight.
+const tokenRefreshMargin = 5 * time.Minute
+
+// cachedTokenKey identifies a cached access token: the credential it was
+// issued to and the scopes it was requested for.
+type cachedTokenKey struct {
+	cred   azcore.TokenCredential
+	scopes string
+}
+
+// token returns an access token of cred for scopes. Tokens are cached until
+// [tokenRefreshMargin] before their expiry, so that reconciles do not request
+// a token for every Graph request.
+func (p *Provider) token(ctx context.Context, cred azcore.TokenCredential, scopes []string) (string, error) {
+	key := cachedTokenKey{cred: cred, scopes: strings.Join(scopes, " ")}
+	now := time.Now()
+
+	p.tokenMu.Lock()
+	cached, ok := p.tokens[key]
+	p.tokenMu.Unlock()
+	if ok && cached.ExpiresOn.Sub(now) > tokenRefreshMargin {
+		return cached.Token, nil
+	}
+
+	token, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: scopes})
+	if err != nil {
+		return "", fmt.Errorf("getting token: %w", err)
+	}
+
+	p.tokenMu.Lock()
+	defer p.tokenMu.Unlock()
+	// Drop the tokens of credentials that are no longer used, e.g. after
+	// their credentials Secret changed.
+	for k, t := range p.tokens {
+		if !t.ExpiresOn.After(now) {
+			delete(p.tokens, k)
+		}
+	}
+	if p.tokens == nil {
+		p.tokens = make(map[cachedTokenKey]azcore.AccessToken)
+	}
+	p.tokens[key] = token
+	return token.Token, nil
+}
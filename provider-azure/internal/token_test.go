@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestToken(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reuses tokens", func(t *testing.T) {
+		p := New()
+		cred := &fakeCredential{}
+		for range 3 {
+			if _, err := p.token(ctx, cred, []string{DefaultScope}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if cred.calls != 1 {
+			t.Fatalf("expected 1 token request, got %d", cred.calls)
+		}
+
+		if _, err := p.token(ctx, cred, []string{"https://graph.microsoft.us/.default"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := p.token(ctx, &fakeCredential{}, []string{DefaultScope}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cred.calls != 2 {
+			t.Fatalf("expected a token request per scope, got %d", cred.calls)
+		}
+	})
+
+	t.Run("refreshes tokens before they expire", func(t *testing.T) {
+		p := New()
+		cred := &fakeCredential{expiresIn: tokenRefreshMargin - time.Second}
+		for range 2 {
+			if _, err := p.token(ctx, cred, []string{DefaultScope}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if cred.calls != 2 {
+			t.Fatalf("expected a token request per call, got %d", cred.calls)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// VerifyCredential implements [framework.CredentialVerifier]. It requests a
+// Microsoft Graph token with the client secret or certificate of result, as
+// the operator does once it is restarted with them. New credentials take up
+// to a few minutes to propagate in Microsoft Entra ID, so early attempts may
+// fail with an invalid client secret.
+func (p *Provider) VerifyCredential(
+	ctx context.Context,
+	obj *v1alpha1.AzureClientSecret,
+	result *framework.Result,
+) error {
+	if err := p.initClient(); err != nil {
+		return err
+	}
+	ctx, err := p.withCloud(ctx, obj)
+	if err != nil {
+		return err
+	}
+	ctx, err = p.withCredential(ctx, obj)
+	if err != nil {
+		return err
+	}
+
+	cred, err := p.newResultCredential(ctx, obj, result)
+	if err != nil {
+		return err
+	}
+	scopes := p.scopes
+	if endpoints, ok := ctx.Value(cloudKey{}).(*cloudEndpoints); ok {
+		scopes = []string{endpoints.scope}
+	}
+	if _, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: scopes}); err != nil {
+		return fmt.Errorf("getting token with the new credential: %w", err)
+	}
+	return nil
+}
+
+// newResultCredential returns a credential authenticating with the client
+// secret or certificate provisioned in result.
+func (p *Provider) newResultCredential(
+	ctx context.Context,
+	obj *v1alpha1.AzureClientSecret,
+	result *framework.Result,
+) (azcore.TokenCredential, error) {
+	clientID := result.Fields[framework.FieldUsername]
+	if clientID == "" {
+		return nil, errors.New("result has no client ID")
+	}
+	opts := p.clientOptions(obj)
+
+	if secret := result.Fields[framework.FieldPassword]; secret != "" {
+		return azidentity.NewClientSecretCredential(tenantID(ctx), clientID, secret,
+			&azidentity.ClientSecretCredentialOptions{ClientOptions: opts})
+	}
+	certPEM, keyPEM := result.StringData[corev1.TLSCertKey], result.StringData[corev1.TLSPrivateKeyKey]
+	if certPEM == "" || keyPEM == "" {
+		return nil, errors.New("result has neither a client secret nor a certificate")
+	}
+	certs, key, err := azidentity.ParseCertificates([]byte(certPEM+keyPEM), nil)
+	if err != nil {
+		return nil, fmt.Errorf("parsing certificate: %w", err)
+	}
+	return azidentity.NewClientCertificateCredential(tenantID(ctx), clientID, certs, key,
+		&azidentity.ClientCertificateCredentialOptions{ClientOptions: opts})
+}
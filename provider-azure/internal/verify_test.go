@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-azure/api/v1alpha1"
+)
+
+func TestProvider_VerifyCredential(t *testing.T) {
+	p := New(WithHTTPClient(http.DefaultClient))
+	obj := &v1alpha1.AzureClientSecret{Spec: v1alpha1.AzureClientSecretSpec{ObjectID: "obj-1"}}
+
+	for name, tc := range map[string]struct {
+		result *framework.Result
+		want   string
+	}{
+		"no client ID": {
+			result: &framework.Result{Fields: map[string]string{framework.FieldPassword: "s3cret"}},
+			want:   "no client ID",
+		},
+		"no credential": {
+			result: &framework.Result{Fields: map[string]string{framework.FieldUsername: "app-123"}},
+			want:   "neither a client secret nor a certificate",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := p.VerifyCredential(context.Background(), obj, tc.result)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
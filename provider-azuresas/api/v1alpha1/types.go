@@ -0,0 +1,283 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	SchemeBuilder.Register(&AzureSASKey{}, &AzureSASKeyList{})
+}
+
+const (
+	// ServiceBus selects an Azure Service Bus namespace.
+	ServiceBus = "ServiceBus"
+	// EventHubs selects an Azure Event Hubs namespace.
+	EventHubs = "EventHubs"
+
+	// EntityQueue selects a Service Bus queue.
+	EntityQueue = "Queue"
+	// EntityTopic selects a Service Bus topic.
+	EntityTopic = "Topic"
+	// EntityEventHub selects an event hub.
+	EntityEventHub = "EventHub"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=azsas,categories=valet
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// AzureSASKey rotates the keys of a Service Bus or Event Hubs shared access
+// signature (SAS) authorization rule.
+type AzureSASKey struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	Spec AzureSASKeySpec `json:"spec,omitzero"`
+	// +optional
+	Status framework.ClientSecretStatus `json:"status,omitzero"`
+}
+
+// AzureSASKeySpec defines the desired state.
+type AzureSASKeySpec struct {
+	// SecretRef is the Kubernetes Secret to create/update with the provisioned credentials.
+	// Defaults to a Secret named after the resource.
+	// +optional
+	SecretRef framework.SecretReference `json:"secretRef,omitzero"`
+
+	// Suspend stops the rotation and cleanup of the credentials while set,
+	// e.g. during incidents or migrations. The output Secret keeps its
+	// current credentials.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DeletionPolicy controls what happens when the resource is deleted:
+	// Delete deletes its keys at the provider and the output Secret with it,
+	// Retain keeps both, e.g. to move the resource to another cluster.
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy framework.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// SecretTemplate sets labels, annotations and the type of the output
+	// Secret, e.g. for Reloader, External Secrets or Argo CD to match on.
+	// +optional
+	SecretTemplate framework.SecretTemplate `json:"secretTemplate,omitzero"`
+
+	// Targets are further Secrets the credentials are written to, each
+	// with a subset of the keys, its own template or in another namespace,
+	// so that consumers expecting different shapes share one credential.
+	// +optional
+	Targets framework.OutputTargets `json:"targets,omitempty"`
+
+	// Replication copies the output Secret into all namespaces matching a
+	// label selector that allow it, and keeps the copies in sync.
+	// +optional
+	Replication *framework.Replication `json:"replication,omitempty"`
+
+	// Service is the messaging service of the namespace.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=ServiceBus;EventHubs
+	Service string `json:"service"`
+
+	// SubscriptionID is the Azure subscription of the namespace.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	SubscriptionID string `json:"subscriptionId"`
+
+	// ResourceGroup is the resource group of the namespace.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	ResourceGroup string `json:"resourceGroup"`
+
+	// Namespace is the name of the Service Bus or Event Hubs namespace.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+
+	// Entity is the queue, topic or event hub the authorization rule belongs
+	// to. Omit it for a namespace-level rule.
+	// +optional
+	Entity *SASEntity `json:"entity,omitempty"`
+
+	// AuthorizationRule is the name of the SAS authorization rule whose keys
+	// are rotated, e.g. "RootManageSharedAccessKey" or an app-specific rule.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	AuthorizationRule string `json:"authorizationRule"`
+
+	// Validity is how long each key is used before it is rotated. SAS keys
+	// do not expire at Azure; once its validity ends, a key is regenerated
+	// to revoke it. Defaults to 30 days (720h).
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// Rotation configures how credentials are rotated, e.g. how long a
+	// superseded credential stays valid.
+	// +optional
+	Rotation framework.RotationPolicy `json:"rotation,omitzero"`
+
+	// Template maps output secret keys to Go template strings.
+	// Available template variables: .ConnectionString, .Key, .KeyName,
+	// .Endpoint, .EntityPath
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinProperties=1
+	Template map[string]string `json:"template"`
+}
+
+// SASEntity references an entity within a namespace.
+type SASEntity struct {
+	// Kind is the kind of the entity. Queues and topics belong to Service
+	// Bus namespaces, event hubs to Event Hubs namespaces.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=Queue;Topic;EventHub
+	Kind string `json:"kind"`
+
+	// Name is the name of the entity.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// GetSecretRef returns the reference to the target output Secret, defaulting
+// to a Secret named after the resource.
+func (a *AzureSASKey) GetSecretRef() framework.SecretReference {
+	return a.Spec.SecretRef.OrDefault(a.Name)
+}
+
+// IsSuspended reports whether the rotation of the credentials is suspended.
+// It implements [framework.Suspender].
+func (a *AzureSASKey) IsSuspended() bool {
+	return a.Spec.Suspend
+}
+
+// GetDeletionPolicy returns what happens to the keys and the output Secret
+// when the resource is deleted. It implements [framework.DeletionPolicyHolder].
+func (a *AzureSASKey) GetDeletionPolicy() framework.DeletionPolicy {
+	return a.Spec.DeletionPolicy
+}
+
+// GetSecretTemplate returns the labels, annotations and type of the output
+// Secret. It implements [framework.SecretTemplateHolder].
+func (a *AzureSASKey) GetSecretTemplate() framework.SecretTemplate {
+	return a.Spec.SecretTemplate
+}
+
+// GetTargets returns the further Secrets the credentials are written to.
+// It implements [framework.TargetHolder].
+func (a *AzureSASKey) GetTargets() framework.OutputTargets {
+	return a.Spec.Targets
+}
+
+// GetReplication returns the replication of the output Secret, or nil.
+// It implements [framework.ReplicationHolder].
+func (a *AzureSASKey) GetReplication() *framework.Replication {
+	return a.Spec.Replication
+}
+
+// GetValidity returns the requested validity of the credentials, or zero for
+// the provider's default. It implements [framework.ValidityRequester].
+func (a *AzureSASKey) GetValidity() time.Duration {
+	if a.Spec.Validity == nil {
+		return 0
+	}
+	return a.Spec.Validity.Duration
+}
+
+// GetRotationPolicy returns the rotation policy of the credentials. It
+// implements [framework.RotationPolicyHolder].
+func (a *AzureSASKey) GetRotationPolicy() framework.RotationPolicy {
+	return a.Spec.Rotation
+}
+
+// GetStatus returns a pointer to the shared status.
+func (a *AzureSASKey) GetStatus() *framework.ClientSecretStatus {
+	return &a.Status
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (a *AzureSASKey) DeepCopyObject() runtime.Object {
+	cp := *a
+	cp.ObjectMeta = *a.DeepCopy()
+	cp.Status = a.Status.DeepCopy()
+	cp.Spec.SecretTemplate = a.Spec.SecretTemplate.DeepCopy()
+	cp.Spec.Targets = a.Spec.Targets.DeepCopy()
+	cp.Spec.Replication = a.Spec.Replication.DeepCopy()
+	if a.Spec.Entity != nil {
+		e := *a.Spec.Entity
+		cp.Spec.Entity = &e
+	}
+	if a.Spec.Template != nil {
+		cp.Spec.Template = make(map[string]string, len(a.Spec.Template))
+		for k, v := range a.Spec.Template {
+			cp.Spec.Template[k] = v
+		}
+	}
+	if a.Spec.Validity != nil {
+		v := *a.Spec.Validity
+		cp.Spec.Validity = &v
+	}
+	return &cp
+}
+
+// Validate performs structural validation of the spec.
+func (a *AzureSASKey) Validate() error {
+	if a.Spec.Service != ServiceBus && a.Spec.Service != EventHubs {
+		return fmt.Errorf("service must be %s or %s", ServiceBus, EventHubs)
+	}
+	if a.Spec.SubscriptionID == "" || a.Spec.ResourceGroup == "" || a.Spec.Namespace == "" {
+		return fmt.Errorf("subscriptionId, resourceGroup and namespace are required")
+	}
+	if e := a.Spec.Entity; e != nil {
+		switch {
+		case e.Name == "":
+			return fmt.Errorf("entity.name is required")
+		case a.Spec.Service == ServiceBus && e.Kind != EntityQueue && e.Kind != EntityTopic:
+			return fmt.Errorf("entity.kind must be %s or %s for %s", EntityQueue, EntityTopic, ServiceBus)
+		case a.Spec.Service == EventHubs && e.Kind != EntityEventHub:
+			return fmt.Errorf("entity.kind must be %s for %s", EntityEventHub, EventHubs)
+		}
+	}
+	if a.Spec.AuthorizationRule == "" {
+		return fmt.Errorf("authorizationRule is required")
+	}
+	if a.Spec.Validity != nil && a.Spec.Validity.Duration <= 0 {
+		return fmt.Errorf("validity must be positive")
+	}
+	if len(a.Spec.Template) == 0 {
+		return fmt.Errorf("template must have at least one entry")
+	}
+	for key, tmpl := range a.Spec.Template {
+		if _, err := template.New(key).Parse(tmpl); err != nil {
+			return fmt.Errorf("template %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:object:root=true
+
+// AzureSASKeyList contains a list of AzureSASKey resources.
+type AzureSASKeyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AzureSASKey `json:"items"`
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (a *AzureSASKeyList) DeepCopyObject() runtime.Object {
+	cp := *a
+	if a.Items != nil {
+		cp.Items = make([]AzureSASKey, len(a.Items))
+		for i := range a.Items {
+			cp.Items[i] = *a.Items[i].DeepCopyObject().(*AzureSASKey)
+		}
+	}
+	return &cp
+}
@@ -0,0 +1,166 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	valid := &AzureSASKey{
+		Spec: AzureSASKeySpec{
+			SecretRef:         framework.SecretReference{Name: "out"},
+			Service:           ServiceBus,
+			SubscriptionID:    "00000000-0000-0000-0000-000000000000",
+			ResourceGroup:     "messaging",
+			Namespace:         "orders",
+			AuthorizationRule: "app",
+			Template:          map[string]string{"CONNECTION_STRING": "{{ .ConnectionString }}"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*AzureSASKey)
+		wantErr string
+	}{
+		{name: "valid", modify: func(_ *AzureSASKey) {}},
+		{
+			name: "valid queue rule",
+			modify: func(a *AzureSASKey) {
+				a.Spec.Entity = &SASEntity{Kind: EntityQueue, Name: "incoming"}
+			},
+		},
+		{
+			name: "valid event hub rule",
+			modify: func(a *AzureSASKey) {
+				a.Spec.Service = EventHubs
+				a.Spec.Entity = &SASEntity{Kind: EntityEventHub, Name: "telemetry"}
+			},
+		},
+		{
+			name:   "omitted secretRef",
+			modify: func(a *AzureSASKey) { a.Spec.SecretRef.Name = "" },
+		},
+		{
+			name:    "unknown service",
+			modify:  func(a *AzureSASKey) { a.Spec.Service = "Relay" },
+			wantErr: "service",
+		},
+		{
+			name:    "missing namespace",
+			modify:  func(a *AzureSASKey) { a.Spec.Namespace = "" },
+			wantErr: "namespace",
+		},
+		{
+			name: "event hub in a Service Bus namespace",
+			modify: func(a *AzureSASKey) {
+				a.Spec.Entity = &SASEntity{Kind: EntityEventHub, Name: "telemetry"}
+			},
+			wantErr: "entity.kind must be Queue or Topic",
+		},
+		{
+			name: "queue in an Event Hubs namespace",
+			modify: func(a *AzureSASKey) {
+				a.Spec.Service = EventHubs
+				a.Spec.Entity = &SASEntity{Kind: EntityQueue, Name: "incoming"}
+			},
+			wantErr: "entity.kind must be EventHub",
+		},
+		{
+			name:    "entity without name",
+			modify:  func(a *AzureSASKey) { a.Spec.Entity = &SASEntity{Kind: EntityQueue} },
+			wantErr: "entity.name",
+		},
+		{
+			name:    "missing authorizationRule",
+			modify:  func(a *AzureSASKey) { a.Spec.AuthorizationRule = "" },
+			wantErr: "authorizationRule",
+		},
+		{
+			name: "non-positive validity",
+			modify: func(a *AzureSASKey) {
+				a.Spec.Validity = &metav1.Duration{Duration: -time.Hour}
+			},
+			wantErr: "validity",
+		},
+		{
+			name:    "empty template",
+			modify:  func(a *AzureSASKey) { a.Spec.Template = nil },
+			wantErr: "template",
+		},
+		{
+			name:    "invalid template syntax",
+			modify:  func(a *AzureSASKey) { a.Spec.Template = map[string]string{"bad": "{{ .Foo"} },
+			wantErr: "template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := valid.DeepCopyObject().(*AzureSASKey)
+			tt.modify(obj)
+			err := obj.Validate()
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if got := err.Error(); !strings.Contains(got, tt.wantErr) {
+				t.Fatalf("error %q does not contain %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeepCopyObject(t *testing.T) {
+	validity := metav1.Duration{Duration: 12 * time.Hour}
+	obj := &AzureSASKey{
+		Spec: AzureSASKeySpec{
+			SecretRef: framework.SecretReference{Name: "s"},
+			Entity:    &SASEntity{Kind: EntityQueue, Name: "incoming"},
+			Template:  map[string]string{"K": "V"},
+			Validity:  &validity,
+		},
+	}
+	obj.Status.Phase = framework.PhaseReady
+
+	cp := obj.DeepCopyObject().(*AzureSASKey)
+
+	cp.Spec.Template["K"] = "changed"
+	if obj.Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not copy template map")
+	}
+
+	cp.Spec.Entity.Name = "changed"
+	if obj.Spec.Entity.Name != "incoming" {
+		t.Fatal("DeepCopyObject did not copy entity")
+	}
+
+	cp.Spec.Validity.Duration = time.Hour
+	if obj.Spec.Validity.Duration != 12*time.Hour {
+		t.Fatal("DeepCopyObject did not copy validity")
+	}
+}
+
+func TestDeepCopyObjectList(t *testing.T) {
+	list := &AzureSASKeyList{
+		Items: []AzureSASKey{
+			{Spec: AzureSASKeySpec{Template: map[string]string{"K": "V"}}},
+		},
+	}
+
+	cp := list.DeepCopyObject().(*AzureSASKeyList)
+	cp.Items[0].Spec.Template["K"] = "changed"
+	if list.Items[0].Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not deep copy list items")
+	}
+}
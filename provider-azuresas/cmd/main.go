@@ -0,0 +1,48 @@
+// provider-azuresas runs the Azure Service Bus and Event Hubs SAS key valet provider.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-azuresas/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-azuresas/internal"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// Build metadata, set with -ldflags "-X main.version=..." at build time.
+// See [framework.NewBuildInfo] for the values used if they are not.
+var (
+	version  = "dev"
+	provider = ""
+	revision = ""
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// +kubebuilder:rbac:groups=valet.ngl.cx,resources=azuresaskeys,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=valet.ngl.cx,resources=azuresaskeys/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=valet.ngl.cx,resources=azuresaskeys/finalizers,verbs=update
+
+func run() error {
+	var opts framework.Options
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	sas := internal.New()
+	return framework.Run(ctrl.SetupSignalHandler(), &opts, framework.Operator[*v1alpha1.AzureSASKey]{
+		Name:        "provider-azuresas",
+		BuildInfo:   framework.NewBuildInfo(provider, version, revision),
+		AddToScheme: v1alpha1.AddToScheme,
+		Endpoints:   sas,
+		Provider:    sas,
+	})
+}
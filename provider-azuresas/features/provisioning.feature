@@ -0,0 +1,183 @@
+Feature: Azure SAS Key Rotation
+  As a platform operator
+  I want the Azure SAS provider to rotate the keys of SAS authorization rules
+  So that applications can connect to Service Bus and Event Hubs
+
+  Background:
+    Given a Kubernetes cluster is running
+    And the CRDs are installed
+    And the operator is running
+
+  Scenario: Provision a key successfully
+    When I create a ClientSecret "test-secret" with:
+      """yaml
+      spec:
+        secretRef:
+          name: test-secret
+        service: ServiceBus
+        subscriptionId: "$TEST_AZURESAS_SUBSCRIPTION_ID"
+        resourceGroup: "$TEST_AZURESAS_RESOURCE_GROUP"
+        namespace: "$TEST_AZURESAS_NAMESPACE"
+        authorizationRule: "$TEST_AZURESAS_RULE"
+        template:
+          CONNECTION_STRING: "{{ .ConnectionString }}"
+          KEY: "{{ .Key }}"
+      """
+    Then the ClientSecret "test-secret" should have phase "Ready" within 60 seconds
+    And a Secret "test-secret" should exist
+    And the Secret "test-secret" should contain key "CONNECTION_STRING"
+    And the Secret "test-secret" should contain key "KEY"
+
+  Scenario: Invalid template syntax is rejected
+    When I create a ClientSecret "bad-template" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-template
+        service: ServiceBus
+        subscriptionId: "$TEST_AZURESAS_SUBSCRIPTION_ID"
+        resourceGroup: "$TEST_AZURESAS_RESOURCE_GROUP"
+        namespace: "$TEST_AZURESAS_NAMESPACE"
+        authorizationRule: "$TEST_AZURESAS_RULE"
+        template:
+          SECRET: "{{ .Invalid"
+      """
+    Then the ClientSecret "bad-template" should have phase "Failed" within 60 seconds
+    And the ClientSecret "bad-template" status should contain message "template"
+    And the Secret "bad-template" should not exist
+
+  Scenario: Delete AzureSASKey cleans up resources
+    When I create a ClientSecret "delete-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: delete-test
+        service: ServiceBus
+        subscriptionId: "$TEST_AZURESAS_SUBSCRIPTION_ID"
+        resourceGroup: "$TEST_AZURESAS_RESOURCE_GROUP"
+        namespace: "$TEST_AZURESAS_NAMESPACE"
+        authorizationRule: "$TEST_AZURESAS_RULE"
+        template:
+          CONNECTION_STRING: "{{ .ConnectionString }}"
+      """
+    Then the ClientSecret "delete-test" should have phase "Ready" within 60 seconds
+    And a Secret "delete-test" should exist
+    When I delete the ClientSecret "delete-test"
+    Then the ClientSecret "delete-test" should not exist within 60 seconds
+
+  Scenario: Expired keys are rotated
+    When I create a ClientSecret "rotation-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rotation-test
+        service: ServiceBus
+        subscriptionId: "$TEST_AZURESAS_SUBSCRIPTION_ID"
+        resourceGroup: "$TEST_AZURESAS_RESOURCE_GROUP"
+        namespace: "$TEST_AZURESAS_NAMESPACE"
+        authorizationRule: "$TEST_AZURESAS_RULE"
+        template:
+          CONNECTION_STRING: "{{ .ConnectionString }}"
+      """
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the ClientSecret "rotation-test" should have 1 active keys
+    When I expire the credentials for ClientSecret "rotation-test"
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+
+  Scenario: Event hubs in Service Bus namespaces are rejected
+    When I create a ClientSecret "wrong-entity" with:
+      """yaml
+      spec:
+        secretRef:
+          name: wrong-entity
+        service: ServiceBus
+        subscriptionId: "$TEST_AZURESAS_SUBSCRIPTION_ID"
+        resourceGroup: "$TEST_AZURESAS_RESOURCE_GROUP"
+        namespace: "$TEST_AZURESAS_NAMESPACE"
+        entity:
+          kind: EventHub
+          name: telemetry
+        authorizationRule: "$TEST_AZURESAS_RULE"
+        template:
+          CONNECTION_STRING: "{{ .ConnectionString }}"
+      """
+    Then the ClientSecret "wrong-entity" should have phase "Failed" within 60 seconds
+    And the ClientSecret "wrong-entity" status should contain message "entity.kind"
+
+  Scenario: Missing authorization rule is rejected
+    When I try to create a ClientSecret "no-rule" with:
+      """yaml
+      spec:
+        secretRef:
+          name: no-rule
+        service: ServiceBus
+        subscriptionId: "$TEST_AZURESAS_SUBSCRIPTION_ID"
+        resourceGroup: "$TEST_AZURESAS_RESOURCE_GROUP"
+        namespace: "$TEST_AZURESAS_NAMESPACE"
+        template:
+          CONNECTION_STRING: "{{ .ConnectionString }}"
+      """
+    Then the operation should have failed with "authorizationRule"
+
+  Scenario: Secret is owned by the AzureSASKey
+    When I create a ClientSecret "ownership-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: ownership-test
+        service: ServiceBus
+        subscriptionId: "$TEST_AZURESAS_SUBSCRIPTION_ID"
+        resourceGroup: "$TEST_AZURESAS_RESOURCE_GROUP"
+        namespace: "$TEST_AZURESAS_NAMESPACE"
+        authorizationRule: "$TEST_AZURESAS_RULE"
+        template:
+          CONNECTION_STRING: "{{ .ConnectionString }}"
+      """
+    Then the ClientSecret "ownership-test" should have phase "Ready" within 60 seconds
+    And the Secret "ownership-test" should be owned by ClientSecret "ownership-test"
+
+  @mock
+  Scenario: Template renders with correct values
+    When I create a ClientSecret "value-check" with:
+      """yaml
+      spec:
+        secretRef:
+          name: value-check
+        service: ServiceBus
+        subscriptionId: "$TEST_AZURESAS_SUBSCRIPTION_ID"
+        resourceGroup: "$TEST_AZURESAS_RESOURCE_GROUP"
+        namespace: "$TEST_AZURESAS_NAMESPACE"
+        entity:
+          kind: Queue
+          name: incoming
+        authorizationRule: "$TEST_AZURESAS_RULE"
+        template:
+          KEY: "{{ .Key }}"
+          KEY_NAME: "{{ .KeyName }}"
+          ENDPOINT: "{{ .Endpoint }}"
+          ENTITY_PATH: "{{ .EntityPath }}"
+      """
+    Then the ClientSecret "value-check" should have phase "Ready" within 60 seconds
+    And the Secret "value-check" should contain key "KEY"
+    And the Secret "value-check" should contain key "KEY_NAME" with value "app"
+    And the Secret "value-check" should contain key "ENDPOINT" with value "sb://valet-test.servicebus.windows.net/"
+    And the Secret "value-check" should contain key "ENTITY_PATH" with value "incoming"
+
+  @mock
+  Scenario: Unknown authorization rules are reported
+    When I create a ClientSecret "unknown-rule" with:
+      """yaml
+      spec:
+        secretRef:
+          name: unknown-rule
+        service: ServiceBus
+        subscriptionId: "$TEST_AZURESAS_SUBSCRIPTION_ID"
+        resourceGroup: "$TEST_AZURESAS_RESOURCE_GROUP"
+        namespace: "$TEST_AZURESAS_NAMESPACE"
+        authorizationRule: "missing"
+        template:
+          CONNECTION_STRING: "{{ .ConnectionString }}"
+      """
+    Then the ClientSecret "unknown-rule" should have phase "Failed" within 60 seconds
+    And the ClientSecret "unknown-rule" status should contain message "NotFound"
+    And the Secret "unknown-rule" should not exist
@@ -0,0 +1,95 @@
+{ inputs, ... }:
+{
+  perSystem =
+    { config, pkgs, ... }:
+    let
+      valet = config.valet.lib;
+
+      provider-azuresas = valet.mkGoModule {
+        pname = "provider-azuresas";
+        subPackages = [ "provider-azuresas/cmd" ];
+        postInstall = ''
+          mv $out/bin/cmd $out/bin/provider-azuresas
+        '';
+        meta.mainProgram = "provider-azuresas";
+      };
+
+      provider-azuresas-compressed = pkgs.stdenvNoCC.mkDerivation {
+        inherit (provider-azuresas) pname version meta;
+        dontUnpack = true;
+        nativeBuildInputs = [ pkgs.upx ];
+        buildPhase = ''
+          mkdir -p $out/bin
+          upx -o $out/bin/provider-azuresas ${provider-azuresas}/bin/provider-azuresas
+        '';
+      };
+
+      image = valet.mkImage {
+        name = "provider-azuresas";
+        entrypoint = "${provider-azuresas-compressed}/bin/provider-azuresas";
+      };
+      e2e-test-azuresas = pkgs.writeShellApplication {
+        name = "e2e-test-azuresas";
+        runtimeInputs = [
+          pkgs.go
+          pkgs.gotestsum
+        ];
+        text = ''
+          export GOFLAGS="-mod=vendor"
+          if [ ! -d vendor ]; then
+            ln -sfn ${valet.workspaceVendor} vendor
+          fi
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum \
+            --format "''${GOTESTSUM_FORMAT:-short-verbose}" \
+            -- -run TestE2E -timeout 10m \
+            -coverpkg=github.com/lukasngl/valet/framework/...,./... \
+            -coverprofile="''${COVERAGE_FILE:-coverage-azuresas-e2e.txt}" \
+            ./provider-azuresas/...
+        '';
+      };
+    in
+    {
+      packages = {
+        inherit provider-azuresas provider-azuresas-compressed;
+        provider-azuresas-image = image;
+      };
+
+      apps.e2e-test-azuresas = {
+        type = "app";
+        program = "${e2e-test-azuresas}/bin/e2e-test-azuresas";
+      };
+
+      checks.provider-azuresas-helm = valet.packageChart {
+        name = "provider-azuresas";
+        src = "${inputs.self}/provider-azuresas/charts/provider-azuresas";
+      };
+
+      checks.provider-azuresas-lint = valet.withPackageEnv provider-azuresas {
+        name = "provider-azuresas-lint";
+        extraBuildInputs = [ pkgs.golangci-lint ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          golangci-lint run --timeout 10m ./provider-azuresas/...
+        '';
+      };
+
+      checks.provider-azuresas-test = valet.withPackageEnv provider-azuresas {
+        name = "provider-azuresas-test";
+        extraBuildInputs = [
+          pkgs.gotestsum
+          pkgs.etcd
+          pkgs.kubernetes
+        ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum --format short-verbose -- -short -coverpkg=github.com/lukasngl/valet/framework/...,./... -coverprofile=coverage.txt ./provider-azuresas/...
+        '';
+        installPhase = ''
+          mkdir -p $out
+          cp coverage.txt $out/
+        '';
+      };
+    };
+}
@@ -0,0 +1,412 @@
+// Package internal contains the Azure SAS key provider implementation.
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-azuresas/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// DefaultValidity is the default key rotation period (30 days).
+	DefaultValidity = 30 * 24 * time.Hour
+
+	// DefaultBaseURL is the Azure Resource Manager endpoint.
+	DefaultBaseURL = "https://management.azure.com"
+
+	// serviceBusAPIVersion is the Microsoft.ServiceBus API version.
+	serviceBusAPIVersion = "2021-11-01"
+
+	// eventHubAPIVersion is the Microsoft.EventHub API version.
+	eventHubAPIVersion = "2024-01-01"
+)
+
+// Key slots of an authorization rule, as named in key IDs.
+const (
+	slotPrimary   = "primary"
+	slotSecondary = "secondary"
+)
+
+// Provider rotates the keys of Service Bus and Event Hubs SAS authorization
+// rules through Azure Resource Manager. It implements [framework.Provider]
+// for [*v1alpha1.AzureSASKey].
+//
+// A rule has a primary and a secondary key. Each rotation regenerates the
+// key that is not in use, alternating between the two, so that the previous
+// key stays valid until consumers have picked up the new one. Key IDs have
+// the form "<slot>/<fingerprint>", where the fingerprint identifies the key
+// value without exposing it.
+type Provider struct {
+	cred     *azidentity.DefaultAzureCredential
+	client   *http.Client
+	baseURL  string
+	initOnce sync.Once
+	initErr  error
+}
+
+// Option configures a [Provider].
+type Option func(*Provider)
+
+// WithHTTPClient sets a custom HTTP client, skipping Azure credential
+// initialization. Useful for testing with a mock transport.
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Provider) { p.client = c }
+}
+
+// WithBaseURL overrides the Azure Resource Manager endpoint. Defaults to
+// [DefaultBaseURL].
+func WithBaseURL(url string) Option {
+	return func(p *Provider) { p.baseURL = strings.TrimSuffix(url, "/") }
+}
+
+// New creates a [Provider] with the given options.
+func New(opts ...Option) *Provider {
+	p := &Provider{baseURL: DefaultBaseURL}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// NewObject returns a zero-value AzureSASKey.
+func (p *Provider) NewObject() *v1alpha1.AzureSASKey {
+	return &v1alpha1.AzureSASKey{}
+}
+
+// Provision regenerates the key of the rule that is not currently in use and
+// renders its connection string.
+func (p *Provider) Provision(
+	ctx context.Context,
+	obj *v1alpha1.AzureSASKey,
+) (*framework.Result, error) {
+	if err := p.initClient(); err != nil {
+		return nil, err
+	}
+
+	validity := DefaultValidity
+	if obj.Spec.Validity != nil {
+		validity = obj.Spec.Validity.Duration
+	}
+
+	slot := slotPrimary
+	if current, _, _ := strings.Cut(obj.Status.CurrentKeyID, "/"); current == slotPrimary {
+		slot = slotSecondary
+	}
+
+	now := time.Now()
+	keys, err := p.regenerate(ctx, obj, slot)
+	if err != nil {
+		return nil, fmt.Errorf("regenerating %s key of rule %s: %w", slot, obj.Spec.AuthorizationRule, err)
+	}
+	key, connectionString := keys.slot(slot)
+	if key == "" || connectionString == "" {
+		return nil, errors.New("no key returned from Azure Resource Manager")
+	}
+
+	fields := parseConnectionString(connectionString)
+	templateData := map[string]string{
+		"ConnectionString": connectionString,
+		"Key":              key,
+		"KeyName":          keys.KeyName,
+		"Endpoint":         fields["Endpoint"],
+		"EntityPath":       fields["EntityPath"],
+	}
+
+	data := make(map[string]string, len(obj.Spec.Template))
+	for k, tmpl := range obj.Spec.Template {
+		rendered, err := renderTemplate(tmpl, templateData)
+		if err != nil {
+			return nil, fmt.Errorf("rendering template %q: %w", k, err)
+		}
+		data[k] = rendered
+	}
+
+	return &framework.Result{
+		StringData:    data,
+		ProvisionedAt: now,
+		ValidUntil:    now.Add(validity),
+		KeyID:         slot + "/" + fingerprint(key),
+	}, nil
+}
+
+// DeleteKey revokes a key by regenerating its slot, unless the slot no longer
+// holds it, e.g. because it was already rotated again. Returns nil if the
+// rule has been deleted (idempotent).
+func (p *Provider) DeleteKey(
+	ctx context.Context,
+	obj *v1alpha1.AzureSASKey,
+	keyID string,
+) error {
+	if keyID == "" {
+		return nil
+	}
+
+	slot, fp, ok := strings.Cut(keyID, "/")
+	if !ok || (slot != slotPrimary && slot != slotSecondary) {
+		return fmt.Errorf("invalid key ID %q", keyID)
+	}
+
+	if err := p.initClient(); err != nil {
+		return err
+	}
+
+	log := log.FromContext(ctx)
+	keys, err := p.listKeys(ctx, obj)
+	if err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+			log.Info("key already deleted", "keyId", keyID, "rule", obj.Spec.AuthorizationRule)
+			return nil
+		}
+		return fmt.Errorf("listing keys of rule %s: %w", obj.Spec.AuthorizationRule, err)
+	}
+	if key, _ := keys.slot(slot); fingerprint(key) != fp {
+		log.Info("key already rotated", "keyId", keyID, "rule", obj.Spec.AuthorizationRule)
+		return nil
+	}
+
+	if _, err := p.regenerate(ctx, obj, slot); err != nil {
+		return fmt.Errorf("regenerating %s key of rule %s: %w", slot, obj.Spec.AuthorizationRule, err)
+	}
+
+	return nil
+}
+
+// Endpoints returns the Microsoft Entra authority and Azure Resource Manager.
+// It implements [framework.EndpointDeclarer].
+func (p *Provider) Endpoints() ([]framework.Endpoint, error) {
+	authorityHost := os.Getenv("AZURE_AUTHORITY_HOST")
+	if authorityHost == "" {
+		authorityHost = "https://login.microsoftonline.com/"
+	}
+
+	authority, err := framework.EndpointFromURL("Microsoft Entra ID", authorityHost)
+	if err != nil {
+		return nil, err
+	}
+	arm, err := framework.EndpointFromURL("Azure Resource Manager", p.baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return []framework.Endpoint{authority, arm}, nil
+}
+
+// initClient initializes the Azure credential and HTTP client on first use.
+// If the client was pre-configured via [WithHTTPClient], initialization is
+// skipped (no Azure credentials required).
+func (p *Provider) initClient() error {
+	p.initOnce.Do(func() {
+		if p.client != nil {
+			return // pre-configured, e.g. for testing
+		}
+		cred, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			p.initErr = fmt.Errorf("creating Azure credential: %w", err)
+			return
+		}
+		p.cred = cred
+		p.client = &http.Client{Timeout: 30 * time.Second}
+	})
+	return p.initErr
+}
+
+// regenerate regenerates the key in the given slot of the rule and returns
+// the rule's keys.
+func (p *Provider) regenerate(ctx context.Context, obj *v1alpha1.AzureSASKey, slot string) (*accessKeys, error) {
+	keyType := "PrimaryKey"
+	if slot == slotSecondary {
+		keyType = "SecondaryKey"
+	}
+
+	var keys accessKeys
+	if err := p.armRequest(ctx, obj, "/regenerateKeys", regenerateKeysRequest{KeyType: keyType}, &keys); err != nil {
+		return nil, err
+	}
+	return &keys, nil
+}
+
+// listKeys returns the keys of the rule.
+func (p *Provider) listKeys(ctx context.Context, obj *v1alpha1.AzureSASKey) (*accessKeys, error) {
+	var keys accessKeys
+	if err := p.armRequest(ctx, obj, "/listKeys", nil, &keys); err != nil {
+		return nil, err
+	}
+	return &keys, nil
+}
+
+// rulePath returns the Resource Manager path of the rule of obj, and the API
+// version of its resource provider.
+func rulePath(obj *v1alpha1.AzureSASKey) (string, string) {
+	namespace, apiVersion := "Microsoft.ServiceBus", serviceBusAPIVersion
+	if obj.Spec.Service == v1alpha1.EventHubs {
+		namespace, apiVersion = "Microsoft.EventHub", eventHubAPIVersion
+	}
+
+	path := "/subscriptions/" + url.PathEscape(obj.Spec.SubscriptionID) +
+		"/resourceGroups/" + url.PathEscape(obj.Spec.ResourceGroup) +
+		"/providers/" + namespace +
+		"/namespaces/" + url.PathEscape(obj.Spec.Namespace)
+	if e := obj.Spec.Entity; e != nil {
+		collection := map[string]string{
+			v1alpha1.EntityQueue:    "queues",
+			v1alpha1.EntityTopic:    "topics",
+			v1alpha1.EntityEventHub: "eventhubs",
+		}[e.Kind]
+		path += "/" + collection + "/" + url.PathEscape(e.Name)
+	}
+	path += "/authorizationRules/" + url.PathEscape(obj.Spec.AuthorizationRule)
+
+	return path, apiVersion
+}
+
+// armRequest posts body to an action of the rule of obj and decodes the JSON
+// response into out. Error responses are returned as [*apiError].
+func (p *Provider) armRequest(ctx context.Context, obj *v1alpha1.AzureSASKey, action string, body, out any) error {
+	path, apiVersion := rulePath(obj)
+
+	var reqBody io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshalling request body: %w", err)
+		}
+		reqBody = bytes.NewReader(jsonBody)
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, p.baseURL+path+action+"?api-version="+apiVersion, reqBody)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	// Skip auth when pre-configured via WithHTTPClient (e.g. tests).
+	if p.cred != nil {
+		token, err := p.cred.GetToken(ctx, policy.TokenRequestOptions{
+			Scopes: []string{DefaultBaseURL + "/.default"},
+		})
+		if err != nil {
+			return fmt.Errorf("getting token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		var armErr armErrorResponse
+		apiErr := &apiError{Status: resp.StatusCode, Message: string(respBody)}
+		if json.Unmarshal(respBody, &armErr) == nil && armErr.Error.Code != "" {
+			apiErr.Code, apiErr.Message = armErr.Error.Code, armErr.Error.Message
+		}
+		return apiErr
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	return nil
+}
+
+// fingerprint derives an identifier from a key, so that keys can be told
+// apart in the status without exposing them.
+func fingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// parseConnectionString splits a connection string into its fields.
+func parseConnectionString(s string) map[string]string {
+	fields := map[string]string{}
+	for part := range strings.SplitSeq(s, ";") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+// Azure Resource Manager request/response types.
+
+type regenerateKeysRequest struct {
+	KeyType string `json:"keyType"`
+}
+
+type accessKeys struct {
+	KeyName                   string `json:"keyName"`
+	PrimaryKey                string `json:"primaryKey"`
+	SecondaryKey              string `json:"secondaryKey"`
+	PrimaryConnectionString   string `json:"primaryConnectionString"`
+	SecondaryConnectionString string `json:"secondaryConnectionString"`
+}
+
+// slot returns the key and connection string in the given slot.
+func (k *accessKeys) slot(slot string) (string, string) {
+	if slot == slotSecondary {
+		return k.SecondaryKey, k.SecondaryConnectionString
+	}
+	return k.PrimaryKey, k.PrimaryConnectionString
+}
+
+type armErrorResponse struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// apiError is an Azure Resource Manager error response.
+type apiError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *apiError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("ARM API error (status %d %s): %s", e.Status, e.Code, e.Message)
+	}
+	return fmt.Sprintf("ARM API error (status %d): %s", e.Status, e.Message)
+}
+
+// renderTemplate renders a Go template string with the given data.
+func renderTemplate(tmpl string, data map[string]string) (string, error) {
+	t, err := template.New("").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,284 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/provider-azuresas/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newObj(template map[string]string) *v1alpha1.AzureSASKey {
+	return &v1alpha1.AzureSASKey{
+		Spec: v1alpha1.AzureSASKeySpec{
+			Service:           v1alpha1.ServiceBus,
+			SubscriptionID:    "sub",
+			ResourceGroup:     "rg",
+			Namespace:         "orders",
+			Entity:            &v1alpha1.SASEntity{Kind: v1alpha1.EntityQueue, Name: "incoming"},
+			AuthorizationRule: "app",
+			Template:          template,
+		},
+	}
+}
+
+// fakeARM is an in-memory authorization rule. Each regeneration yields a new
+// numbered key.
+type fakeARM struct {
+	mu        sync.Mutex
+	primary   string
+	secondary string
+	next      int
+	paths     []string
+	regens    []string
+	status    int
+}
+
+func (f *fakeARM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paths = append(f.paths, r.URL.Path+"?"+r.URL.RawQuery)
+
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		_, _ = w.Write([]byte(`{"error":{"code":"NotFound","message":"rule not found"}}`))
+		return
+	}
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/regenerateKeys"):
+		var req regenerateKeysRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.next++
+		key := fmt.Sprintf("key-%d", f.next)
+		if req.KeyType == "SecondaryKey" {
+			f.secondary = key
+		} else {
+			f.primary = key
+		}
+		f.regens = append(f.regens, req.KeyType)
+	case strings.HasSuffix(r.URL.Path, "/listKeys"):
+	default:
+		http.Error(w, "unexpected request", http.StatusNotFound)
+		return
+	}
+
+	conn := func(key string) string {
+		return "Endpoint=sb://orders.servicebus.windows.net/;SharedAccessKeyName=app;SharedAccessKey=" +
+			key + ";EntityPath=incoming"
+	}
+	_ = json.NewEncoder(w).Encode(accessKeys{
+		KeyName:                   "app",
+		PrimaryKey:                f.primary,
+		SecondaryKey:              f.secondary,
+		PrimaryConnectionString:   conn(f.primary),
+		SecondaryConnectionString: conn(f.secondary),
+	})
+}
+
+// newTestProvider starts the fake server and returns a provider pointed at
+// it.
+func newTestProvider(t *testing.T, f *fakeARM) *Provider {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL))
+}
+
+func TestProvision(t *testing.T) {
+	t.Run("alternates between primary and secondary", func(t *testing.T) {
+		f := &fakeARM{}
+		p := newTestProvider(t, f)
+		obj := newObj(map[string]string{
+			"CONNECTION_STRING": "{{ .ConnectionString }}",
+			"KEY":               "{{ .Key }}",
+			"KEY_NAME":          "{{ .KeyName }}",
+			"ENDPOINT":          "{{ .Endpoint }}",
+			"ENTITY_PATH":       "{{ .EntityPath }}",
+		})
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.KeyID != "primary/"+fingerprint("key-1") {
+			t.Fatalf("got keyID %q, want the primary key's fingerprint", result.KeyID)
+		}
+		want := map[string]string{
+			"CONNECTION_STRING": "Endpoint=sb://orders.servicebus.windows.net/;SharedAccessKeyName=app;" +
+				"SharedAccessKey=key-1;EntityPath=incoming",
+			"KEY":         "key-1",
+			"KEY_NAME":    "app",
+			"ENDPOINT":    "sb://orders.servicebus.windows.net/",
+			"ENTITY_PATH": "incoming",
+		}
+		for k, v := range want {
+			if got := result.StringData[k]; got != v {
+				t.Errorf("got %s %q, want %q", k, got, v)
+			}
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != DefaultValidity {
+			t.Errorf("got validity %v, want %v", got, DefaultValidity)
+		}
+
+		obj.Status.CurrentKeyID = result.KeyID
+		result, err = p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.KeyID != "secondary/"+fingerprint("key-2") || result.StringData["KEY"] != "key-2" {
+			t.Fatalf("expected the secondary key to be rotated next, got %q", result.KeyID)
+		}
+		if strings.Join(f.regens, ",") != "PrimaryKey,SecondaryKey" {
+			t.Fatalf("unexpected regenerations: %v", f.regens)
+		}
+	})
+
+	t.Run("request path", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			modify func(*v1alpha1.AzureSASKey)
+			want   string
+		}{
+			{
+				name: "queue",
+				want: "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ServiceBus/namespaces/orders" +
+					"/queues/incoming/authorizationRules/app/regenerateKeys?api-version=" + serviceBusAPIVersion,
+			},
+			{
+				name:   "namespace",
+				modify: func(o *v1alpha1.AzureSASKey) { o.Spec.Entity = nil },
+				want: "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ServiceBus/namespaces/orders" +
+					"/authorizationRules/app/regenerateKeys?api-version=" + serviceBusAPIVersion,
+			},
+			{
+				name: "event hub",
+				modify: func(o *v1alpha1.AzureSASKey) {
+					o.Spec.Service = v1alpha1.EventHubs
+					o.Spec.Entity = &v1alpha1.SASEntity{Kind: v1alpha1.EntityEventHub, Name: "telemetry"}
+				},
+				want: "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.EventHub/namespaces/orders" +
+					"/eventhubs/telemetry/authorizationRules/app/regenerateKeys?api-version=" + eventHubAPIVersion,
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				f := &fakeARM{}
+				p := newTestProvider(t, f)
+				obj := newObj(map[string]string{"K": "v"})
+				if tt.modify != nil {
+					tt.modify(obj)
+				}
+				if _, err := p.Provision(context.Background(), obj); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(f.paths) != 1 || f.paths[0] != tt.want {
+					t.Fatalf("got requests %v, want %s", f.paths, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("custom validity", func(t *testing.T) {
+		p := newTestProvider(t, &fakeARM{})
+		obj := newObj(map[string]string{"K": "v"})
+		obj.Spec.Validity = &metav1.Duration{Duration: 12 * time.Hour}
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != 12*time.Hour {
+			t.Fatalf("got validity %v, want %v", got, 12*time.Hour)
+		}
+	})
+
+	t.Run("API error propagates", func(t *testing.T) {
+		p := newTestProvider(t, &fakeARM{status: http.StatusNotFound})
+		_, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "regenerating primary key of rule app") ||
+			!strings.Contains(err.Error(), "rule not found") {
+			t.Fatalf("expected wrapped error, got: %v", err)
+		}
+	})
+
+	t.Run("bad template", func(t *testing.T) {
+		p := newTestProvider(t, &fakeARM{})
+		_, err := p.Provision(context.Background(), newObj(map[string]string{
+			"BAD": "{{ .Unclosed",
+		}))
+		if err == nil {
+			t.Fatal("expected template error")
+		}
+		if !strings.Contains(err.Error(), "rendering template") {
+			t.Fatalf("expected 'rendering template' error, got: %v", err)
+		}
+	})
+}
+
+func TestDeleteKey(t *testing.T) {
+	t.Run("empty keyID is a no-op", func(t *testing.T) {
+		f := &fakeARM{}
+		p := newTestProvider(t, f)
+		if err := p.DeleteKey(context.Background(), newObj(nil), ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(f.paths) != 0 {
+			t.Fatalf("unexpected requests: %v", f.paths)
+		}
+	})
+
+	t.Run("regenerates the slot holding the key", func(t *testing.T) {
+		f := &fakeARM{primary: "old", secondary: "current"}
+		p := newTestProvider(t, f)
+		if err := p.DeleteKey(context.Background(), newObj(nil), "primary/"+fingerprint("old")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Join(f.regens, ",") != "PrimaryKey" || f.secondary != "current" {
+			t.Fatalf("expected only the primary key to be regenerated, got %v", f.regens)
+		}
+	})
+
+	t.Run("already rotated key is left alone", func(t *testing.T) {
+		f := &fakeARM{primary: "newer", secondary: "current"}
+		p := newTestProvider(t, f)
+		if err := p.DeleteKey(context.Background(), newObj(nil), "primary/"+fingerprint("old")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(f.regens) != 0 {
+			t.Fatalf("expected no regeneration, got %v", f.regens)
+		}
+	})
+
+	t.Run("deleted rule is idempotent", func(t *testing.T) {
+		p := newTestProvider(t, &fakeARM{status: http.StatusNotFound})
+		if err := p.DeleteKey(context.Background(), newObj(nil), "primary/"+fingerprint("old")); err != nil {
+			t.Fatalf("expected nil for a deleted rule, got: %v", err)
+		}
+	})
+
+	t.Run("other error propagates", func(t *testing.T) {
+		p := newTestProvider(t, &fakeARM{status: http.StatusForbidden})
+		err := p.DeleteKey(context.Background(), newObj(nil), "primary/"+fingerprint("old"))
+		if err == nil || !strings.Contains(err.Error(), "listing keys of rule app") {
+			t.Fatalf("expected wrapped error, got: %v", err)
+		}
+	})
+
+	t.Run("invalid keyID", func(t *testing.T) {
+		p := newTestProvider(t, &fakeARM{})
+		if err := p.DeleteKey(context.Background(), newObj(nil), "tertiary/abc"); err == nil {
+			t.Fatal("expected error for an invalid key ID")
+		}
+	})
+}
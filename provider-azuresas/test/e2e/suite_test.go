@@ -0,0 +1,223 @@
+package e2e
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cucumber/godog"
+	"github.com/cucumber/godog/colors"
+	"github.com/google/uuid"
+	"github.com/lukasngl/valet/framework/bddtest"
+	"github.com/lukasngl/valet/provider-azuresas/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-azuresas/internal"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var godogOpts = godog.Options{
+	Format:      "pretty",
+	Output:      colors.Colored(os.Stdout),
+	Paths:       []string{"../../features"},
+	Concurrency: 1,
+	Strict:      true,
+}
+
+func init() {
+	godog.BindFlags("godog.", flag.CommandLine, &godogOpts)
+}
+
+var testEnvCfg bddtest.Env
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	if len(flag.Args()) > 0 {
+		godogOpts.Paths = flag.Args()
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	testEnvCfg.Scheme = runtime.NewScheme()
+	_ = corev1.AddToScheme(testEnvCfg.Scheme)
+	_ = v1alpha1.AddToScheme(testEnvCfg.Scheme)
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths: []string{"../../config/crd"},
+		Scheme:            testEnvCfg.Scheme,
+	}
+	env.ControlPlane.GetAPIServer().Configure().
+		Append("advertise-address", "127.0.0.1").
+		Append("bind-address", "127.0.0.1")
+
+	cfg, err := env.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start envtest: %v\n", err)
+		os.Exit(1)
+	}
+	testEnvCfg.Cfg = cfg
+
+	code := m.Run()
+
+	_ = env.Stop()
+	os.Exit(code)
+}
+
+// TestMock runs all scenarios with a canned HTTP transport.
+func TestMock(t *testing.T) {
+	t.Setenv("TEST_AZURESAS_SUBSCRIPTION_ID", "00000000-0000-0000-0000-000000000000")
+	t.Setenv("TEST_AZURESAS_RESOURCE_GROUP", "messaging")
+	t.Setenv("TEST_AZURESAS_NAMESPACE", "valet-test")
+	t.Setenv("TEST_AZURESAS_RULE", "app")
+
+	opts := godogOpts
+	status := godog.TestSuite{
+		Name: "provider-azuresas-mock",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New(
+				internal.WithHTTPClient(&http.Client{Transport: &armMock{rules: map[string]*ruleKeys{}}}),
+				internal.WithBaseURL("http://arm.mock"),
+			)
+			shared := bddtest.New[*v1alpha1.AzureSASKey](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// TestE2E runs non-mock scenarios against a real Service Bus namespace.
+func TestE2E(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e tests in short mode")
+	}
+
+	if os.Getenv("TEST_AZURESAS_NAMESPACE") == "" {
+		t.Skip("skipping e2e tests: TEST_AZURESAS_NAMESPACE not set")
+	}
+
+	opts := godogOpts
+	opts.Tags = "~@mock"
+	status := godog.TestSuite{
+		Name: "provider-azuresas-e2e",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New()
+			shared := bddtest.New[*v1alpha1.AzureSASKey](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// ruleKeys holds the keys of a mocked authorization rule.
+type ruleKeys struct {
+	primary, secondary string
+}
+
+// armMock is an [http.RoundTripper] that simulates the authorization rules of
+// Azure Resource Manager. Each regeneration yields a new random key. Rules
+// named "missing" do not exist.
+type armMock struct {
+	mu    sync.Mutex
+	rules map[string]*ruleKeys
+}
+
+func (m *armMock) RoundTrip(req *http.Request) (*http.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rulePath, action := path.Split(req.URL.Path)
+	rulePath = strings.TrimSuffix(rulePath, "/")
+	segments := strings.Split(strings.Trim(rulePath, "/"), "/")
+	// subscriptions/<id>/resourceGroups/<rg>/providers/<ns>/namespaces/<name>[/<kind>/<entity>]/authorizationRules/<rule>
+	if req.Method != http.MethodPost || len(segments) < 10 || segments[len(segments)-2] != "authorizationRules" {
+		return errorResponse(http.StatusBadRequest, "InvalidRequest", "unexpected request: %s %s", req.Method, req.URL.Path)
+	}
+	ruleName := segments[len(segments)-1]
+	if ruleName == "missing" {
+		return errorResponse(http.StatusNotFound, "NotFound", "authorization rule %s not found", ruleName)
+	}
+
+	keys, ok := m.rules[rulePath]
+	if !ok {
+		keys = &ruleKeys{primary: uuid.New().String(), secondary: uuid.New().String()}
+		m.rules[rulePath] = keys
+	}
+
+	switch action {
+	case "regenerateKeys":
+		var body struct {
+			KeyType string `json:"keyType"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return errorResponse(http.StatusBadRequest, "InvalidRequest", "decoding request: %v", err)
+		}
+		switch body.KeyType {
+		case "PrimaryKey":
+			keys.primary = uuid.New().String()
+		case "SecondaryKey":
+			keys.secondary = uuid.New().String()
+		default:
+			return errorResponse(http.StatusBadRequest, "InvalidRequest", "unknown key type %q", body.KeyType)
+		}
+	case "listKeys":
+	default:
+		return errorResponse(http.StatusNotFound, "NotFound", "unknown action %s", action)
+	}
+
+	domain := ".servicebus.windows.net"
+	entityPath := ""
+	if len(segments) == 12 {
+		entityPath = ";EntityPath=" + segments[9]
+	}
+	conn := func(key string) string {
+		return "Endpoint=sb://" + segments[7] + domain + "/;SharedAccessKeyName=" + ruleName +
+			";SharedAccessKey=" + key + entityPath
+	}
+	return jsonResponse(http.StatusOK, map[string]string{
+		"keyName":                   ruleName,
+		"primaryKey":                keys.primary,
+		"secondaryKey":              keys.secondary,
+		"primaryConnectionString":   conn(keys.primary),
+		"secondaryConnectionString": conn(keys.secondary),
+	})
+}
+
+// errorResponse returns an Azure Resource Manager error response.
+func errorResponse(status int, code, format string, args ...any) (*http.Response, error) {
+	return jsonResponse(status, map[string]any{
+		"error": map[string]string{
+			"code":    code,
+			"message": fmt.Sprintf(format, args...),
+		},
+	})
+}
+
+func jsonResponse(status int, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(string(data))),
+	}, nil
+}
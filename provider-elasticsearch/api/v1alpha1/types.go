@@ -0,0 +1,222 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	SchemeBuilder.Register(&ElasticsearchAPIKey{}, &ElasticsearchAPIKeyList{})
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=esak,categories=valet
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// ElasticsearchAPIKey provisions and rotates Elasticsearch API keys.
+type ElasticsearchAPIKey struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	Spec ElasticsearchAPIKeySpec `json:"spec,omitzero"`
+	// +optional
+	Status framework.ClientSecretStatus `json:"status,omitzero"`
+}
+
+// ElasticsearchAPIKeySpec defines the desired state.
+type ElasticsearchAPIKeySpec struct {
+	// SecretRef is the Kubernetes Secret to create/update with the provisioned credentials.
+	// Defaults to a Secret named after the resource.
+	// +optional
+	SecretRef framework.SecretReference `json:"secretRef,omitzero"`
+
+	// Suspend stops the rotation and cleanup of the credentials while set,
+	// e.g. during incidents or migrations. The output Secret keeps its
+	// current credentials.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DeletionPolicy controls what happens when the resource is deleted:
+	// Delete deletes its keys at the provider and the output Secret with it,
+	// Retain keeps both, e.g. to move the resource to another cluster.
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy framework.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// SecretTemplate sets labels, annotations and the type of the output
+	// Secret, e.g. for Reloader, External Secrets or Argo CD to match on.
+	// +optional
+	SecretTemplate framework.SecretTemplate `json:"secretTemplate,omitzero"`
+
+	// Targets are further Secrets the credentials are written to, each
+	// with a subset of the keys, its own template or in another namespace,
+	// so that consumers expecting different shapes share one credential.
+	// +optional
+	Targets framework.OutputTargets `json:"targets,omitempty"`
+
+	// Replication copies the output Secret into all namespaces matching a
+	// label selector that allow it, and keeps the copies in sync.
+	// +optional
+	Replication *framework.Replication `json:"replication,omitempty"`
+
+	// Name is the name given to each created API key. Defaults to
+	// <namespace>/<name> of this resource.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// RoleDescriptors maps role names to Elasticsearch role descriptors
+	// (cluster, indices, applications, ...) that limit the key's privileges.
+	// At least one is required: a key without role descriptors would inherit
+	// all privileges of the operator's own user.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	RoleDescriptors runtime.RawExtension `json:"roleDescriptors"`
+
+	// Validity is how long each API key is used before it is rotated and
+	// invalidated. It is also set as the key's expiration in Elasticsearch.
+	// Defaults to 90 days (2160h).
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// Rotation configures how credentials are rotated, e.g. how long a
+	// superseded credential stays valid.
+	// +optional
+	Rotation framework.RotationPolicy `json:"rotation,omitzero"`
+
+	// Template maps output secret keys to Go template strings.
+	// Available template variables: .ID, .APIKey, .Encoded (base64 of id:api_key), .URL
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinProperties=1
+	Template map[string]string `json:"template"`
+}
+
+// GetSecretRef returns the reference to the target output Secret, defaulting
+// to a Secret named after the resource.
+func (e *ElasticsearchAPIKey) GetSecretRef() framework.SecretReference {
+	return e.Spec.SecretRef.OrDefault(e.Name)
+}
+
+// IsSuspended reports whether the rotation of the credentials is suspended.
+// It implements [framework.Suspender].
+func (e *ElasticsearchAPIKey) IsSuspended() bool {
+	return e.Spec.Suspend
+}
+
+// GetDeletionPolicy returns what happens to the keys and the output Secret
+// when the resource is deleted. It implements [framework.DeletionPolicyHolder].
+func (e *ElasticsearchAPIKey) GetDeletionPolicy() framework.DeletionPolicy {
+	return e.Spec.DeletionPolicy
+}
+
+// GetSecretTemplate returns the labels, annotations and type of the output
+// Secret. It implements [framework.SecretTemplateHolder].
+func (e *ElasticsearchAPIKey) GetSecretTemplate() framework.SecretTemplate {
+	return e.Spec.SecretTemplate
+}
+
+// GetTargets returns the further Secrets the credentials are written to.
+// It implements [framework.TargetHolder].
+func (e *ElasticsearchAPIKey) GetTargets() framework.OutputTargets {
+	return e.Spec.Targets
+}
+
+// GetReplication returns the replication of the output Secret, or nil.
+// It implements [framework.ReplicationHolder].
+func (e *ElasticsearchAPIKey) GetReplication() *framework.Replication {
+	return e.Spec.Replication
+}
+
+// GetValidity returns the requested validity of the credentials, or zero for
+// the provider's default. It implements [framework.ValidityRequester].
+func (e *ElasticsearchAPIKey) GetValidity() time.Duration {
+	if e.Spec.Validity == nil {
+		return 0
+	}
+	return e.Spec.Validity.Duration
+}
+
+// GetRotationPolicy returns the rotation policy of the credentials. It
+// implements [framework.RotationPolicyHolder].
+func (e *ElasticsearchAPIKey) GetRotationPolicy() framework.RotationPolicy {
+	return e.Spec.Rotation
+}
+
+// GetStatus returns a pointer to the shared status.
+func (e *ElasticsearchAPIKey) GetStatus() *framework.ClientSecretStatus {
+	return &e.Status
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (e *ElasticsearchAPIKey) DeepCopyObject() runtime.Object {
+	cp := *e
+	cp.ObjectMeta = *e.DeepCopy()
+	cp.Status = e.Status.DeepCopy()
+	cp.Spec.SecretTemplate = e.Spec.SecretTemplate.DeepCopy()
+	cp.Spec.Targets = e.Spec.Targets.DeepCopy()
+	cp.Spec.Replication = e.Spec.Replication.DeepCopy()
+	if e.Spec.Template != nil {
+		cp.Spec.Template = make(map[string]string, len(e.Spec.Template))
+		for k, v := range e.Spec.Template {
+			cp.Spec.Template[k] = v
+		}
+	}
+	if e.Spec.Validity != nil {
+		v := *e.Spec.Validity
+		cp.Spec.Validity = &v
+	}
+	e.Spec.RoleDescriptors.DeepCopyInto(&cp.Spec.RoleDescriptors)
+	return &cp
+}
+
+// Validate performs structural validation of the spec.
+func (e *ElasticsearchAPIKey) Validate() error {
+	var roles map[string]json.RawMessage
+	if err := json.Unmarshal(e.Spec.RoleDescriptors.Raw, &roles); err != nil {
+		return fmt.Errorf("roleDescriptors must be an object: %w", err)
+	}
+	if len(roles) == 0 {
+		return fmt.Errorf("roleDescriptors must have at least one entry")
+	}
+	if e.Spec.Validity != nil && e.Spec.Validity.Duration <= 0 {
+		return fmt.Errorf("validity must be positive")
+	}
+	if len(e.Spec.Template) == 0 {
+		return fmt.Errorf("template must have at least one entry")
+	}
+	for key, tmpl := range e.Spec.Template {
+		if _, err := template.New(key).Parse(tmpl); err != nil {
+			return fmt.Errorf("template %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:object:root=true
+
+// ElasticsearchAPIKeyList contains a list of ElasticsearchAPIKey resources.
+type ElasticsearchAPIKeyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ElasticsearchAPIKey `json:"items"`
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (e *ElasticsearchAPIKeyList) DeepCopyObject() runtime.Object {
+	cp := *e
+	if e.Items != nil {
+		cp.Items = make([]ElasticsearchAPIKey, len(e.Items))
+		for i := range e.Items {
+			cp.Items[i] = *e.Items[i].DeepCopyObject().(*ElasticsearchAPIKey)
+		}
+	}
+	return &cp
+}
@@ -0,0 +1,127 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestValidate(t *testing.T) {
+	valid := &ElasticsearchAPIKey{
+		Spec: ElasticsearchAPIKeySpec{
+			SecretRef: framework.SecretReference{Name: "out"},
+			RoleDescriptors: runtime.RawExtension{
+				Raw: []byte(`{"reader":{"indices":[{"names":["logs-*"],"privileges":["read"]}]}}`),
+			},
+			Template: map[string]string{"KEY": "{{ .Encoded }}"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*ElasticsearchAPIKey)
+		wantErr string
+	}{
+		{name: "valid", modify: func(_ *ElasticsearchAPIKey) {}},
+		{
+			name:   "omitted secretRef",
+			modify: func(e *ElasticsearchAPIKey) { e.Spec.SecretRef.Name = "" },
+		},
+		{
+			name:    "missing roleDescriptors",
+			modify:  func(e *ElasticsearchAPIKey) { e.Spec.RoleDescriptors.Raw = nil },
+			wantErr: "roleDescriptors",
+		},
+		{
+			name:    "empty roleDescriptors",
+			modify:  func(e *ElasticsearchAPIKey) { e.Spec.RoleDescriptors.Raw = []byte(`{}`) },
+			wantErr: "roleDescriptors",
+		},
+		{
+			name: "non-positive validity",
+			modify: func(e *ElasticsearchAPIKey) {
+				e.Spec.Validity = &metav1.Duration{Duration: -time.Hour}
+			},
+			wantErr: "validity",
+		},
+		{
+			name:    "empty template",
+			modify:  func(e *ElasticsearchAPIKey) { e.Spec.Template = nil },
+			wantErr: "template",
+		},
+		{
+			name:    "invalid template syntax",
+			modify:  func(e *ElasticsearchAPIKey) { e.Spec.Template = map[string]string{"bad": "{{ .Foo"} },
+			wantErr: "template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := valid.DeepCopyObject().(*ElasticsearchAPIKey)
+			tt.modify(obj)
+			err := obj.Validate()
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if got := err.Error(); !strings.Contains(got, tt.wantErr) {
+				t.Fatalf("error %q does not contain %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeepCopyObject(t *testing.T) {
+	validity := metav1.Duration{Duration: 48 * time.Hour}
+	obj := &ElasticsearchAPIKey{
+		Spec: ElasticsearchAPIKeySpec{
+			SecretRef:       framework.SecretReference{Name: "s"},
+			RoleDescriptors: runtime.RawExtension{Raw: []byte(`{"r":{}}`)},
+			Template:        map[string]string{"K": "V"},
+			Validity:        &validity,
+		},
+	}
+	obj.Status.Phase = framework.PhaseReady
+
+	cp := obj.DeepCopyObject().(*ElasticsearchAPIKey)
+
+	cp.Spec.Template["K"] = "changed"
+	if obj.Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not copy template map")
+	}
+
+	cp.Spec.RoleDescriptors.Raw[2] = 'x'
+	if string(obj.Spec.RoleDescriptors.Raw) != `{"r":{}}` {
+		t.Fatal("DeepCopyObject did not copy role descriptors")
+	}
+
+	cp.Spec.Validity.Duration = time.Hour
+	if obj.Spec.Validity.Duration != 48*time.Hour {
+		t.Fatal("DeepCopyObject did not copy validity")
+	}
+}
+
+func TestDeepCopyObjectList(t *testing.T) {
+	list := &ElasticsearchAPIKeyList{
+		Items: []ElasticsearchAPIKey{
+			{Spec: ElasticsearchAPIKeySpec{Name: "a"}},
+		},
+	}
+
+	cp := list.DeepCopyObject().(*ElasticsearchAPIKeyList)
+	cp.Items[0].Spec.Name = "changed"
+	if list.Items[0].Spec.Name != "a" {
+		t.Fatal("DeepCopyObject did not deep copy list items")
+	}
+}
@@ -0,0 +1,166 @@
+Feature: Elasticsearch API Key Provisioning
+  As a platform operator
+  I want the Elasticsearch provider to provision and rotate API keys
+  So that applications can authenticate with Elasticsearch
+
+  Background:
+    Given a Kubernetes cluster is running
+    And the CRDs are installed
+    And the operator is running
+
+  Scenario: Provision an API key successfully
+    When I create a ClientSecret "test-secret" with:
+      """yaml
+      spec:
+        secretRef:
+          name: test-secret
+        roleDescriptors:
+          reader:
+            indices:
+              - names: ["$TEST_ELASTICSEARCH_INDEX"]
+                privileges: ["read"]
+        template:
+          ID: "{{ .ID }}"
+          API_KEY: "{{ .APIKey }}"
+          ENCODED: "{{ .Encoded }}"
+      """
+    Then the ClientSecret "test-secret" should have phase "Ready" within 60 seconds
+    And a Secret "test-secret" should exist
+    And the Secret "test-secret" should contain key "ID"
+    And the Secret "test-secret" should contain key "API_KEY"
+    And the Secret "test-secret" should contain key "ENCODED"
+
+  Scenario: Invalid template syntax is rejected
+    When I create a ClientSecret "bad-template" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-template
+        roleDescriptors:
+          reader:
+            indices:
+              - names: ["$TEST_ELASTICSEARCH_INDEX"]
+                privileges: ["read"]
+        template:
+          SECRET: "{{ .Invalid"
+      """
+    Then the ClientSecret "bad-template" should have phase "Failed" within 60 seconds
+    And the ClientSecret "bad-template" status should contain message "template"
+    And the Secret "bad-template" should not exist
+
+  Scenario: Delete ElasticsearchAPIKey cleans up resources
+    When I create a ClientSecret "delete-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: delete-test
+        roleDescriptors:
+          reader:
+            indices:
+              - names: ["$TEST_ELASTICSEARCH_INDEX"]
+                privileges: ["read"]
+        template:
+          ENCODED: "{{ .Encoded }}"
+      """
+    Then the ClientSecret "delete-test" should have phase "Ready" within 60 seconds
+    And a Secret "delete-test" should exist
+    When I delete the ClientSecret "delete-test"
+    Then the ClientSecret "delete-test" should not exist within 60 seconds
+
+  Scenario: Expired API keys are rotated
+    When I create a ClientSecret "rotation-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rotation-test
+        roleDescriptors:
+          reader:
+            indices:
+              - names: ["$TEST_ELASTICSEARCH_INDEX"]
+                privileges: ["read"]
+        template:
+          ENCODED: "{{ .Encoded }}"
+      """
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the ClientSecret "rotation-test" should have 1 active keys
+    When I expire the credentials for ClientSecret "rotation-test"
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+
+  Scenario: Missing roleDescriptors are rejected
+    When I try to create a ClientSecret "no-roles" with:
+      """yaml
+      spec:
+        secretRef:
+          name: no-roles
+        template:
+          ENCODED: "{{ .Encoded }}"
+      """
+    Then the operation should have failed with "roleDescriptors"
+
+  Scenario: Empty roleDescriptors are reported
+    When I create a ClientSecret "empty-roles" with:
+      """yaml
+      spec:
+        secretRef:
+          name: empty-roles
+        roleDescriptors: {}
+        template:
+          ENCODED: "{{ .Encoded }}"
+      """
+    Then the ClientSecret "empty-roles" should have phase "Failed" within 60 seconds
+    And the ClientSecret "empty-roles" status should contain message "roleDescriptors"
+    And the Secret "empty-roles" should not exist
+
+  Scenario: Secret is owned by the ElasticsearchAPIKey
+    When I create a ClientSecret "ownership-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: ownership-test
+        roleDescriptors:
+          reader:
+            indices:
+              - names: ["$TEST_ELASTICSEARCH_INDEX"]
+                privileges: ["read"]
+        template:
+          ENCODED: "{{ .Encoded }}"
+      """
+    Then the ClientSecret "ownership-test" should have phase "Ready" within 60 seconds
+    And the Secret "ownership-test" should be owned by ClientSecret "ownership-test"
+
+  @mock
+  Scenario: Template renders with correct values
+    When I create a ClientSecret "value-check" with:
+      """yaml
+      spec:
+        secretRef:
+          name: value-check
+        roleDescriptors:
+          reader:
+            indices:
+              - names: ["$TEST_ELASTICSEARCH_INDEX"]
+                privileges: ["read"]
+        template:
+          API_KEY: "{{ .APIKey }}"
+          URL: "{{ .URL }}"
+      """
+    Then the ClientSecret "value-check" should have phase "Ready" within 60 seconds
+    And the Secret "value-check" should contain key "API_KEY" with value "fake-api-key"
+    And the Secret "value-check" should contain key "URL" with value "http://elasticsearch.mock"
+
+  @mock
+  Scenario: Invalid role descriptors are reported
+    When I create a ClientSecret "bad-role" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-role
+        roleDescriptors:
+          invalid:
+            cluster: ["bogus"]
+        template:
+          ENCODED: "{{ .Encoded }}"
+      """
+    Then the ClientSecret "bad-role" should have phase "Failed" within 60 seconds
+    And the ClientSecret "bad-role" status should contain message "illegal_argument_exception"
+    And the Secret "bad-role" should not exist
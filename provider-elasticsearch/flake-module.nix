@@ -0,0 +1,95 @@
+{ inputs, ... }:
+{
+  perSystem =
+    { config, pkgs, ... }:
+    let
+      valet = config.valet.lib;
+
+      provider-elasticsearch = valet.mkGoModule {
+        pname = "provider-elasticsearch";
+        subPackages = [ "provider-elasticsearch/cmd" ];
+        postInstall = ''
+          mv $out/bin/cmd $out/bin/provider-elasticsearch
+        '';
+        meta.mainProgram = "provider-elasticsearch";
+      };
+
+      provider-elasticsearch-compressed = pkgs.stdenvNoCC.mkDerivation {
+        inherit (provider-elasticsearch) pname version meta;
+        dontUnpack = true;
+        nativeBuildInputs = [ pkgs.upx ];
+        buildPhase = ''
+          mkdir -p $out/bin
+          upx -o $out/bin/provider-elasticsearch ${provider-elasticsearch}/bin/provider-elasticsearch
+        '';
+      };
+
+      image = valet.mkImage {
+        name = "provider-elasticsearch";
+        entrypoint = "${provider-elasticsearch-compressed}/bin/provider-elasticsearch";
+      };
+      e2e-test-elasticsearch = pkgs.writeShellApplication {
+        name = "e2e-test-elasticsearch";
+        runtimeInputs = [
+          pkgs.go
+          pkgs.gotestsum
+        ];
+        text = ''
+          export GOFLAGS="-mod=vendor"
+          if [ ! -d vendor ]; then
+            ln -sfn ${valet.workspaceVendor} vendor
+          fi
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum \
+            --format "''${GOTESTSUM_FORMAT:-short-verbose}" \
+            -- -run TestE2E -timeout 10m \
+            -coverpkg=github.com/lukasngl/valet/framework/...,./... \
+            -coverprofile="''${COVERAGE_FILE:-coverage-elasticsearch-e2e.txt}" \
+            ./provider-elasticsearch/...
+        '';
+      };
+    in
+    {
+      packages = {
+        inherit provider-elasticsearch provider-elasticsearch-compressed;
+        provider-elasticsearch-image = image;
+      };
+
+      apps.e2e-test-elasticsearch = {
+        type = "app";
+        program = "${e2e-test-elasticsearch}/bin/e2e-test-elasticsearch";
+      };
+
+      checks.provider-elasticsearch-helm = valet.packageChart {
+        name = "provider-elasticsearch";
+        src = "${inputs.self}/provider-elasticsearch/charts/provider-elasticsearch";
+      };
+
+      checks.provider-elasticsearch-lint = valet.withPackageEnv provider-elasticsearch {
+        name = "provider-elasticsearch-lint";
+        extraBuildInputs = [ pkgs.golangci-lint ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          golangci-lint run --timeout 10m ./provider-elasticsearch/...
+        '';
+      };
+
+      checks.provider-elasticsearch-test = valet.withPackageEnv provider-elasticsearch {
+        name = "provider-elasticsearch-test";
+        extraBuildInputs = [
+          pkgs.gotestsum
+          pkgs.etcd
+          pkgs.kubernetes
+        ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum --format short-verbose -- -short -coverpkg=github.com/lukasngl/valet/framework/...,./... -coverprofile=coverage.txt ./provider-elasticsearch/...
+        '';
+        installPhase = ''
+          mkdir -p $out
+          cp coverage.txt $out/
+        '';
+      };
+    };
+}
@@ -0,0 +1,343 @@
+// Package internal contains the Elasticsearch provider implementation.
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-elasticsearch/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultValidity is the default key rotation period (90 days).
+const DefaultValidity = 90 * 24 * time.Hour
+
+// apiKeyPath is the path of the Elasticsearch API key endpoint, used both to
+// create and to invalidate keys.
+const apiKeyPath = "/_security/api_key"
+
+// Provider provisions Elasticsearch API keys using the security API.
+// It implements [framework.Provider] for [*v1alpha1.ElasticsearchAPIKey].
+//
+// The operator authenticates with username and password: keys created by a
+// request that is itself authenticated with an API key cannot be granted any
+// privileges.
+type Provider struct {
+	client   *http.Client
+	baseURL  string
+	username string
+	password string
+	initOnce sync.Once
+	initErr  error
+}
+
+// Option configures a [Provider].
+type Option func(*Provider)
+
+// WithHTTPClient sets a custom HTTP client. Useful for testing with a mock
+// transport.
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Provider) { p.client = c }
+}
+
+// WithBaseURL sets the Elasticsearch URL, e.g. https://elasticsearch:9200.
+// Defaults to the ELASTICSEARCH_URL environment variable.
+func WithBaseURL(url string) Option {
+	return func(p *Provider) { p.baseURL = strings.TrimSuffix(url, "/") }
+}
+
+// WithCredentials sets the operator's Elasticsearch credentials. Defaults to
+// the ELASTICSEARCH_USERNAME and ELASTICSEARCH_PASSWORD environment variables.
+func WithCredentials(username, password string) Option {
+	return func(p *Provider) { p.username, p.password = username, password }
+}
+
+// New creates a [Provider] with the given options.
+func New(opts ...Option) *Provider {
+	p := &Provider{}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// NewObject returns a zero-value ElasticsearchAPIKey.
+func (p *Provider) NewObject() *v1alpha1.ElasticsearchAPIKey {
+	return &v1alpha1.ElasticsearchAPIKey{}
+}
+
+// Provision creates a new API key limited to the role descriptors from the
+// spec. The key expires in Elasticsearch when its validity ends.
+func (p *Provider) Provision(
+	ctx context.Context,
+	obj *v1alpha1.ElasticsearchAPIKey,
+) (*framework.Result, error) {
+	if err := p.initClient(); err != nil {
+		return nil, err
+	}
+
+	validity := DefaultValidity
+	if obj.Spec.Validity != nil {
+		validity = obj.Spec.Validity.Duration
+	}
+
+	name := obj.Spec.Name
+	if name == "" {
+		name = obj.Namespace + "/" + obj.Name
+	}
+
+	var key createAPIKeyResponse
+	if err := p.apiRequest(ctx, http.MethodPost, apiKeyPath, createAPIKeyRequest{
+		Name:            name,
+		RoleDescriptors: json.RawMessage(obj.Spec.RoleDescriptors.Raw),
+		Expiration:      fmt.Sprintf("%ds", int64(validity.Seconds())),
+		Metadata: map[string]string{
+			"managed_by": "valet",
+			"namespace":  obj.Namespace,
+			"name":       obj.Name,
+		},
+	}, &key); err != nil {
+		return nil, fmt.Errorf("creating API key %q: %w", name, err)
+	}
+	if key.ID == "" || key.APIKey == "" {
+		return nil, errors.New("no API key returned from Elasticsearch API")
+	}
+
+	encoded := key.Encoded
+	if encoded == "" {
+		// Only returned by Elasticsearch 7.16 and later.
+		encoded = base64.StdEncoding.EncodeToString([]byte(key.ID + ":" + key.APIKey))
+	}
+
+	now := time.Now()
+
+	templateData := map[string]string{
+		"ID":      key.ID,
+		"APIKey":  key.APIKey,
+		"Encoded": encoded,
+		"URL":     p.baseURL,
+	}
+
+	data := make(map[string]string, len(obj.Spec.Template))
+	for key, tmpl := range obj.Spec.Template {
+		rendered, err := renderTemplate(tmpl, templateData)
+		if err != nil {
+			return nil, fmt.Errorf("rendering template %q: %w", key, err)
+		}
+		data[key] = rendered
+	}
+
+	return &framework.Result{
+		StringData:    data,
+		ProvisionedAt: now,
+		ValidUntil:    now.Add(validity),
+		KeyID:         key.ID,
+	}, nil
+}
+
+// DeleteKey invalidates an API key. Returns nil if the key does not exist or
+// has already been invalidated (idempotent).
+func (p *Provider) DeleteKey(
+	ctx context.Context,
+	_ *v1alpha1.ElasticsearchAPIKey,
+	keyID string,
+) error {
+	if keyID == "" {
+		return nil
+	}
+
+	if err := p.initClient(); err != nil {
+		return err
+	}
+
+	var resp invalidateAPIKeyResponse
+	err := p.apiRequest(ctx, http.MethodDelete, apiKeyPath, invalidateAPIKeyRequest{
+		IDs: []string{keyID},
+	}, &resp)
+	if err != nil {
+		// Key already deleted at the provider — not an error.
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			log.FromContext(ctx).Info("key already deleted", "keyId", keyID)
+			return nil
+		}
+		return fmt.Errorf("invalidating API key %s: %w", keyID, err)
+	}
+	if resp.ErrorCount > 0 {
+		reasons := make([]string, len(resp.ErrorDetails))
+		for i, d := range resp.ErrorDetails {
+			reasons[i] = d.Reason
+		}
+		return fmt.Errorf("invalidating API key %s: %s", keyID, strings.Join(reasons, "; "))
+	}
+	if len(resp.Invalidated) == 0 {
+		log.FromContext(ctx).Info("key already deleted", "keyId", keyID)
+	}
+
+	return nil
+}
+
+// Endpoints returns the Elasticsearch cluster, unless its URL is not
+// configured yet. It implements [framework.EndpointDeclarer].
+func (p *Provider) Endpoints() ([]framework.Endpoint, error) {
+	baseURL := p.baseURL
+	if baseURL == "" {
+		baseURL = os.Getenv("ELASTICSEARCH_URL")
+	}
+	if baseURL == "" {
+		return nil, nil
+	}
+
+	e, err := framework.EndpointFromURL("Elasticsearch", baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return []framework.Endpoint{e}, nil
+}
+
+// initClient reads the URL and credentials from the environment on first use,
+// unless they were set via [WithBaseURL] and [WithCredentials].
+func (p *Provider) initClient() error {
+	p.initOnce.Do(func() {
+		if p.baseURL == "" {
+			p.baseURL = strings.TrimSuffix(os.Getenv("ELASTICSEARCH_URL"), "/")
+		}
+		if p.username == "" {
+			p.username = os.Getenv("ELASTICSEARCH_USERNAME")
+			p.password = os.Getenv("ELASTICSEARCH_PASSWORD")
+		}
+		if p.baseURL == "" || p.username == "" {
+			p.initErr = errors.New("ELASTICSEARCH_URL and ELASTICSEARCH_USERNAME must be set")
+			return
+		}
+		if p.client == nil {
+			p.client = &http.Client{Timeout: 30 * time.Second}
+		}
+	})
+	return p.initErr
+}
+
+// apiRequest makes a request to the Elasticsearch API and decodes the
+// response into out, if non-nil. Error responses are returned as [*apiError].
+func (p *Provider) apiRequest(
+	ctx context.Context,
+	method, urlPath string,
+	body, out any,
+) error {
+	var reqBody io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshalling request body: %w", err)
+		}
+		reqBody = bytes.NewReader(jsonBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+urlPath, reqBody)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(p.username, p.password)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		apiErr := &apiError{}
+		if json.Unmarshal(respBody, apiErr) != nil || apiErr.Err.Reason == "" {
+			apiErr.Err.Reason = string(respBody)
+		}
+		apiErr.Code = resp.StatusCode
+		return apiErr
+	}
+
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("parsing response: %w", err)
+		}
+	}
+	return nil
+}
+
+// Elasticsearch security API request/response types.
+
+type createAPIKeyRequest struct {
+	Name            string            `json:"name"`
+	RoleDescriptors json.RawMessage   `json:"role_descriptors"`
+	Expiration      string            `json:"expiration"`
+	Metadata        map[string]string `json:"metadata"`
+}
+
+type createAPIKeyResponse struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	APIKey  string `json:"api_key"`
+	Encoded string `json:"encoded"`
+}
+
+type invalidateAPIKeyRequest struct {
+	IDs []string `json:"ids"`
+}
+
+type invalidateAPIKeyResponse struct {
+	Invalidated           []string `json:"invalidated_api_keys"`
+	PreviouslyInvalidated []string `json:"previously_invalidated_api_keys"`
+	ErrorCount            int      `json:"error_count"`
+	ErrorDetails          []struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error_details"`
+}
+
+// apiError is an Elasticsearch error response.
+type apiError struct {
+	Code int `json:"-"`
+	Err  struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+func (e *apiError) Error() string {
+	if e.Err.Type != "" {
+		return fmt.Sprintf("Elasticsearch API error (status %d %s): %s", e.Code, e.Err.Type, e.Err.Reason)
+	}
+	return fmt.Sprintf("Elasticsearch API error (status %d): %s", e.Code, e.Err.Reason)
+}
+
+// renderTemplate renders a Go template string with the given data.
+func renderTemplate(tmpl string, data map[string]string) (string, error) {
+	t, err := template.New("").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
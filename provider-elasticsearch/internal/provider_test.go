@@ -0,0 +1,325 @@
+package internal
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/provider-elasticsearch/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const roleDescriptors = `{"reader":{"indices":[{"names":["logs-*"],"privileges":["read"]}]}}`
+
+func newObj(template map[string]string) *v1alpha1.ElasticsearchAPIKey {
+	return &v1alpha1.ElasticsearchAPIKey{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "search"},
+		Spec: v1alpha1.ElasticsearchAPIKeySpec{
+			RoleDescriptors: runtime.RawExtension{Raw: []byte(roleDescriptors)},
+			Template:        template,
+		},
+	}
+}
+
+// request is a request received by the fake server.
+type request struct {
+	Method string
+	Path   string
+	Body   map[string]json.RawMessage
+}
+
+// fakeServer records requests and responds with the status and body returned
+// by respond, or a successfully created key if respond is nil.
+type fakeServer struct {
+	mu       sync.Mutex
+	requests []request
+	respond  func(r *http.Request) (int, string)
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]json.RawMessage
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	f.mu.Lock()
+	f.requests = append(f.requests, request{r.Method, r.URL.Path, body})
+	f.mu.Unlock()
+
+	status, resp := http.StatusOK, `{"id":"key-1","name":"apps/search","api_key":"s3cret","encoded":"ZW5jb2RlZA=="}`
+	if f.respond != nil {
+		status, resp = f.respond(r)
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(resp))
+}
+
+// newTestProvider starts the fake server and returns a provider pointed at
+// it.
+func newTestProvider(t *testing.T, f *fakeServer) *Provider {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "elastic" || pass != "secret" {
+			t.Errorf("unexpected credentials %q/%q", user, pass)
+		}
+		f.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL), WithCredentials("elastic", "secret"))
+}
+
+func TestProvision(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		f := &fakeServer{}
+		p := newTestProvider(t, f)
+
+		result, err := p.Provision(context.Background(), newObj(map[string]string{
+			"ID":      "{{ .ID }}",
+			"API_KEY": "{{ .APIKey }}",
+			"ENCODED": "{{ .Encoded }}",
+			"URL":     "{{ .URL }}",
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.KeyID != "key-1" {
+			t.Fatalf("got keyID %q, want key-1", result.KeyID)
+		}
+		want := map[string]string{"ID": "key-1", "API_KEY": "s3cret", "ENCODED": "ZW5jb2RlZA==", "URL": p.baseURL}
+		for k, v := range want {
+			if got := result.StringData[k]; got != v {
+				t.Fatalf("got %s %q, want %q", k, got, v)
+			}
+		}
+
+		if len(f.requests) != 1 {
+			t.Fatalf("got %d requests, want 1: %+v", len(f.requests), f.requests)
+		}
+		create := f.requests[0]
+		if create.Method != http.MethodPost || create.Path != "/_security/api_key" {
+			t.Fatalf("unexpected create request: %s %s", create.Method, create.Path)
+		}
+		if got := string(create.Body["name"]); got != `"apps/search"` {
+			t.Fatalf("got name %s, want default <namespace>/<name>", got)
+		}
+		if got := string(create.Body["role_descriptors"]); got != roleDescriptors {
+			t.Fatalf("got role_descriptors %s", got)
+		}
+		if got := string(create.Body["expiration"]); got != `"7776000s"` {
+			t.Fatalf("got expiration %s, want the default validity", got)
+		}
+	})
+
+	t.Run("custom name", func(t *testing.T) {
+		f := &fakeServer{}
+		p := newTestProvider(t, f)
+		obj := newObj(map[string]string{"K": "v"})
+		obj.Spec.Name = "search-reader"
+
+		if _, err := p.Provision(context.Background(), obj); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := string(f.requests[0].Body["name"]); got != `"search-reader"` {
+			t.Fatalf("got name %s", got)
+		}
+	})
+
+	t.Run("encoded fallback for older clusters", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{respond: func(*http.Request) (int, string) {
+			return http.StatusOK, `{"id":"key-1","api_key":"s3cret"}`
+		}})
+		result, err := p.Provision(context.Background(), newObj(map[string]string{"ENCODED": "{{ .Encoded }}"}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := base64.StdEncoding.EncodeToString([]byte("key-1:s3cret"))
+		if got := result.StringData["ENCODED"]; got != want {
+			t.Fatalf("got ENCODED %q, want %q", got, want)
+		}
+	})
+
+	t.Run("default validity", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{})
+		result, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != DefaultValidity {
+			t.Fatalf("got validity %v, want %v", got, DefaultValidity)
+		}
+	})
+
+	t.Run("custom validity", func(t *testing.T) {
+		f := &fakeServer{}
+		p := newTestProvider(t, f)
+		obj := newObj(map[string]string{"K": "v"})
+		obj.Spec.Validity = &metav1.Duration{Duration: 48 * time.Hour}
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != 48*time.Hour {
+			t.Fatalf("got validity %v, want %v", got, 48*time.Hour)
+		}
+		if got := string(f.requests[0].Body["expiration"]); got != `"172800s"` {
+			t.Fatalf("got expiration %s, want 172800s", got)
+		}
+	})
+
+	t.Run("API error propagates", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{respond: func(*http.Request) (int, string) {
+			return http.StatusForbidden,
+				`{"error":{"type":"security_exception","reason":"action is unauthorized"},"status":403}`
+		}})
+		_, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), `creating API key "apps/search"`) ||
+			!strings.Contains(err.Error(), "security_exception") {
+			t.Fatalf("expected wrapped error, got: %v", err)
+		}
+	})
+
+	t.Run("bad template", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{})
+		_, err := p.Provision(context.Background(), newObj(map[string]string{
+			"BAD": "{{ .Unclosed",
+		}))
+		if err == nil {
+			t.Fatal("expected template error")
+		}
+		if !strings.Contains(err.Error(), "rendering template") {
+			t.Fatalf("expected 'rendering template' error, got: %v", err)
+		}
+	})
+}
+
+func TestDeleteKey(t *testing.T) {
+	t.Run("empty keyID is a no-op", func(t *testing.T) {
+		f := &fakeServer{}
+		p := newTestProvider(t, f)
+		if err := p.DeleteKey(context.Background(), newObj(nil), ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(f.requests) != 0 {
+			t.Fatalf("unexpected requests: %+v", f.requests)
+		}
+	})
+
+	t.Run("happy path", func(t *testing.T) {
+		f := &fakeServer{respond: func(*http.Request) (int, string) {
+			return http.StatusOK, `{"invalidated_api_keys":["key-1"],"error_count":0}`
+		}}
+		p := newTestProvider(t, f)
+		if err := p.DeleteKey(context.Background(), newObj(nil), "key-1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(f.requests) != 1 || f.requests[0].Method != http.MethodDelete ||
+			f.requests[0].Path != "/_security/api_key" || string(f.requests[0].Body["ids"]) != `["key-1"]` {
+			t.Fatalf("unexpected requests: %+v", f.requests)
+		}
+	})
+
+	t.Run("previously invalidated is idempotent", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{respond: func(*http.Request) (int, string) {
+			return http.StatusOK, `{"invalidated_api_keys":[],"previously_invalidated_api_keys":["key-1"],"error_count":0}`
+		}})
+		if err := p.DeleteKey(context.Background(), newObj(nil), "key-1"); err != nil {
+			t.Fatalf("expected nil for invalidated key, got: %v", err)
+		}
+	})
+
+	t.Run("not found is idempotent", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{respond: func(*http.Request) (int, string) {
+			return http.StatusNotFound, `{"error":{"type":"resource_not_found_exception","reason":"no API keys found"}}`
+		}})
+		if err := p.DeleteKey(context.Background(), newObj(nil), "gone-key"); err != nil {
+			t.Fatalf("expected nil for missing key, got: %v", err)
+		}
+	})
+
+	t.Run("error details propagate", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{respond: func(*http.Request) (int, string) {
+			return http.StatusOK,
+				`{"invalidated_api_keys":[],"error_count":1,"error_details":[{"type":"exception","reason":"shard failure"}]}`
+		}})
+		err := p.DeleteKey(context.Background(), newObj(nil), "key-1")
+		if err == nil || !strings.Contains(err.Error(), "invalidating API key key-1: shard failure") {
+			t.Fatalf("expected error details, got: %v", err)
+		}
+	})
+
+	t.Run("other error propagates", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{respond: func(*http.Request) (int, string) {
+			return http.StatusInternalServerError, "boom"
+		}})
+		err := p.DeleteKey(context.Background(), newObj(nil), "key-1")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "invalidating API key key-1") || !strings.Contains(err.Error(), "boom") {
+			t.Fatalf("expected 'invalidating API key' error, got: %v", err)
+		}
+	})
+}
+
+func TestInitClient(t *testing.T) {
+	t.Run("reads configuration from the environment", func(t *testing.T) {
+		t.Setenv("ELASTICSEARCH_URL", "https://elasticsearch:9200/")
+		t.Setenv("ELASTICSEARCH_USERNAME", "elastic")
+		t.Setenv("ELASTICSEARCH_PASSWORD", "env-secret")
+
+		p := New()
+		if err := p.initClient(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p.baseURL != "https://elasticsearch:9200" || p.username != "elastic" || p.password != "env-secret" {
+			t.Fatalf("got baseURL %q and credentials %q/%q", p.baseURL, p.username, p.password)
+		}
+	})
+
+	t.Run("fails without configuration", func(t *testing.T) {
+		t.Setenv("ELASTICSEARCH_URL", "")
+		t.Setenv("ELASTICSEARCH_USERNAME", "")
+
+		p := New()
+		if err := p.initClient(); err == nil {
+			t.Fatal("expected error without configuration")
+		}
+	})
+}
+
+func TestEndpoints(t *testing.T) {
+	t.Run("URL from the environment", func(t *testing.T) {
+		t.Setenv("ELASTICSEARCH_URL", "https://elasticsearch:9200/")
+
+		endpoints, err := New().Endpoints()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(endpoints) != 1 || endpoints[0].String() != "elasticsearch:9200" {
+			t.Fatalf("got endpoints %v", endpoints)
+		}
+	})
+
+	t.Run("none when not configured", func(t *testing.T) {
+		t.Setenv("ELASTICSEARCH_URL", "")
+
+		endpoints, err := New().Endpoints()
+		if err != nil || len(endpoints) != 0 {
+			t.Fatalf("got endpoints %v and error %v", endpoints, err)
+		}
+	})
+
+	t.Run("fails for invalid URL", func(t *testing.T) {
+		if _, err := New(WithBaseURL("elasticsearch:9200")).Endpoints(); err == nil {
+			t.Fatal("expected error for URL without scheme")
+		}
+	})
+}
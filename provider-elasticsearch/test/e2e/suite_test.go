@@ -0,0 +1,191 @@
+package e2e
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cucumber/godog"
+	"github.com/cucumber/godog/colors"
+	"github.com/google/uuid"
+	"github.com/lukasngl/valet/framework/bddtest"
+	"github.com/lukasngl/valet/provider-elasticsearch/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-elasticsearch/internal"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var godogOpts = godog.Options{
+	Format:      "pretty",
+	Output:      colors.Colored(os.Stdout),
+	Paths:       []string{"../../features"},
+	Concurrency: 1,
+	Strict:      true,
+}
+
+func init() {
+	godog.BindFlags("godog.", flag.CommandLine, &godogOpts)
+}
+
+var testEnvCfg bddtest.Env
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	if len(flag.Args()) > 0 {
+		godogOpts.Paths = flag.Args()
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	testEnvCfg.Scheme = runtime.NewScheme()
+	_ = corev1.AddToScheme(testEnvCfg.Scheme)
+	_ = v1alpha1.AddToScheme(testEnvCfg.Scheme)
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths: []string{"../../config/crd"},
+		Scheme:            testEnvCfg.Scheme,
+	}
+	env.ControlPlane.GetAPIServer().Configure().
+		Append("advertise-address", "127.0.0.1").
+		Append("bind-address", "127.0.0.1")
+
+	cfg, err := env.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start envtest: %v\n", err)
+		os.Exit(1)
+	}
+	testEnvCfg.Cfg = cfg
+
+	code := m.Run()
+
+	_ = env.Stop()
+	os.Exit(code)
+}
+
+// TestMock runs all scenarios with a canned HTTP transport.
+func TestMock(t *testing.T) {
+	t.Setenv("TEST_ELASTICSEARCH_INDEX", "valet-test-*")
+
+	opts := godogOpts
+	status := godog.TestSuite{
+		Name: "provider-elasticsearch-mock",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New(
+				internal.WithHTTPClient(&http.Client{Transport: &securityMock{}}),
+				internal.WithBaseURL("http://elasticsearch.mock"),
+				internal.WithCredentials("elastic", "mock-password"),
+			)
+			shared := bddtest.New[*v1alpha1.ElasticsearchAPIKey](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// TestE2E runs non-mock scenarios against a real Elasticsearch cluster.
+func TestE2E(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e tests in short mode")
+	}
+
+	if os.Getenv("TEST_ELASTICSEARCH_INDEX") == "" {
+		t.Skip("skipping e2e tests: TEST_ELASTICSEARCH_INDEX not set")
+	}
+
+	opts := godogOpts
+	opts.Tags = "~@mock"
+	status := godog.TestSuite{
+		Name: "provider-elasticsearch-e2e",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New()
+			shared := bddtest.New[*v1alpha1.ElasticsearchAPIKey](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// securityMock is an [http.RoundTripper] that returns canned Elasticsearch
+// security API responses. Each key creation returns a unique ID, creation
+// fails if a role descriptor is named "invalid", and invalidation always
+// succeeds.
+type securityMock struct{}
+
+func (m *securityMock) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Path != "/_security/api_key" {
+		return errorResponse(http.StatusNotFound, "unexpected request: %s %s", req.Method, req.URL.Path)
+	}
+
+	switch req.Method {
+	case http.MethodPost:
+		var body struct {
+			RoleDescriptors map[string]json.RawMessage `json:"role_descriptors"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return errorResponse(http.StatusBadRequest, "parsing request: %v", err)
+		}
+		if _, ok := body.RoleDescriptors["invalid"]; ok {
+			return errorResponse(http.StatusBadRequest, "unknown cluster privilege [bogus]")
+		}
+		id := strings.ReplaceAll(uuid.New().String(), "-", "")
+		return jsonResponse(http.StatusOK, map[string]string{
+			"id":      id,
+			"api_key": "fake-api-key",
+			"encoded": base64.StdEncoding.EncodeToString([]byte(id + ":fake-api-key")),
+		})
+	case http.MethodDelete:
+		var body struct {
+			IDs []string `json:"ids"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return errorResponse(http.StatusBadRequest, "parsing request: %v", err)
+		}
+		return jsonResponse(http.StatusOK, map[string]any{
+			"invalidated_api_keys": body.IDs,
+			"error_count":          0,
+		})
+	default:
+		return errorResponse(http.StatusMethodNotAllowed, "unexpected request: %s %s", req.Method, req.URL.Path)
+	}
+}
+
+// errorResponse returns an Elasticsearch error response.
+func errorResponse(status int, format string, args ...any) (*http.Response, error) {
+	return jsonResponse(status, map[string]any{
+		"error": map[string]string{
+			"type":   "illegal_argument_exception",
+			"reason": fmt.Sprintf(format, args...),
+		},
+		"status": status,
+	})
+}
+
+func jsonResponse(status int, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(string(data))),
+	}, nil
+}
@@ -0,0 +1,210 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	SchemeBuilder.Register(&ExecCredential{}, &ExecCredentialList{})
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=execcred,categories=valet
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// ExecCredential provisions and rotates credentials by running the plugin
+// the operator is configured with.
+type ExecCredential struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	Spec ExecCredentialSpec `json:"spec,omitzero"`
+	// +optional
+	Status framework.ClientSecretStatus `json:"status,omitzero"`
+}
+
+// ExecCredentialSpec defines the desired state.
+type ExecCredentialSpec struct {
+	// SecretRef is the Kubernetes Secret to create/update with the
+	// credential data returned by the plugin.
+	// Defaults to a Secret named after the resource.
+	// +optional
+	SecretRef framework.SecretReference `json:"secretRef,omitzero"`
+
+	// Suspend stops the rotation and cleanup of the credentials while set,
+	// e.g. during incidents or migrations. The output Secret keeps its
+	// current credentials.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DeletionPolicy controls what happens when the resource is deleted:
+	// Delete deletes its keys at the provider and the output Secret with it,
+	// Retain keeps both, e.g. to move the resource to another cluster.
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy framework.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// SecretTemplate sets labels, annotations and the type of the output
+	// Secret, e.g. for Reloader, External Secrets or Argo CD to match on.
+	// +optional
+	SecretTemplate framework.SecretTemplate `json:"secretTemplate,omitzero"`
+
+	// Targets are further Secrets the credentials are written to, each
+	// with a subset of the keys, its own template or in another namespace,
+	// so that consumers expecting different shapes share one credential.
+	// +optional
+	Targets framework.OutputTargets `json:"targets,omitempty"`
+
+	// Replication copies the output Secret into all namespaces matching a
+	// label selector that allow it, and keeps the copies in sync.
+	// +optional
+	Replication *framework.Replication `json:"replication,omitempty"`
+
+	// Parameters are passed to the plugin with every request, e.g. to
+	// select the account or the permissions of the credentials.
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// Validity is how long each credential is used before it is rotated, if
+	// the plugin does not return an expiry. An expiry returned by the
+	// plugin is capped to it. Defaults to 90 days (2160h).
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// Rotation configures how credentials are rotated, e.g. how long a
+	// superseded credential stays valid.
+	// +optional
+	Rotation framework.RotationPolicy `json:"rotation,omitzero"`
+
+	// Template maps additional output secret keys to Go template strings.
+	// Template keys take precedence over data returned by the plugin.
+	// Available template variables: .Data (the returned data, e.g.
+	// {{ .Data.password }}), .KeyID
+	// +optional
+	Template map[string]string `json:"template,omitempty"`
+}
+
+// GetSecretRef returns the reference to the target output Secret, defaulting
+// to a Secret named after the resource.
+func (c *ExecCredential) GetSecretRef() framework.SecretReference {
+	return c.Spec.SecretRef.OrDefault(c.Name)
+}
+
+// IsSuspended reports whether the rotation of the credentials is suspended.
+// It implements [framework.Suspender].
+func (c *ExecCredential) IsSuspended() bool {
+	return c.Spec.Suspend
+}
+
+// GetDeletionPolicy returns what happens to the keys and the output Secret
+// when the resource is deleted. It implements [framework.DeletionPolicyHolder].
+func (c *ExecCredential) GetDeletionPolicy() framework.DeletionPolicy {
+	return c.Spec.DeletionPolicy
+}
+
+// GetSecretTemplate returns the labels, annotations and type of the output
+// Secret. It implements [framework.SecretTemplateHolder].
+func (c *ExecCredential) GetSecretTemplate() framework.SecretTemplate {
+	return c.Spec.SecretTemplate
+}
+
+// GetTargets returns the further Secrets the credentials are written to.
+// It implements [framework.TargetHolder].
+func (c *ExecCredential) GetTargets() framework.OutputTargets {
+	return c.Spec.Targets
+}
+
+// GetReplication returns the replication of the output Secret, or nil.
+// It implements [framework.ReplicationHolder].
+func (c *ExecCredential) GetReplication() *framework.Replication {
+	return c.Spec.Replication
+}
+
+// GetValidity returns the requested validity of the credentials, or zero for
+// the provider's default. It implements [framework.ValidityRequester].
+func (c *ExecCredential) GetValidity() time.Duration {
+	if c.Spec.Validity == nil {
+		return 0
+	}
+	return c.Spec.Validity.Duration
+}
+
+// GetRotationPolicy returns the rotation policy of the credentials. It
+// implements [framework.RotationPolicyHolder].
+func (c *ExecCredential) GetRotationPolicy() framework.RotationPolicy {
+	return c.Spec.Rotation
+}
+
+// GetStatus returns a pointer to the shared status.
+func (c *ExecCredential) GetStatus() *framework.ClientSecretStatus {
+	return &c.Status
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (c *ExecCredential) DeepCopyObject() runtime.Object {
+	cp := *c
+	cp.ObjectMeta = *c.DeepCopy()
+	cp.Status = c.Status.DeepCopy()
+	cp.Spec.SecretTemplate = c.Spec.SecretTemplate.DeepCopy()
+	cp.Spec.Targets = c.Spec.Targets.DeepCopy()
+	cp.Spec.Replication = c.Spec.Replication.DeepCopy()
+	if c.Spec.Parameters != nil {
+		cp.Spec.Parameters = make(map[string]string, len(c.Spec.Parameters))
+		for k, v := range c.Spec.Parameters {
+			cp.Spec.Parameters[k] = v
+		}
+	}
+	if c.Spec.Template != nil {
+		cp.Spec.Template = make(map[string]string, len(c.Spec.Template))
+		for k, v := range c.Spec.Template {
+			cp.Spec.Template[k] = v
+		}
+	}
+	if c.Spec.Validity != nil {
+		v := *c.Spec.Validity
+		cp.Spec.Validity = &v
+	}
+	return &cp
+}
+
+// Validate performs structural validation of the spec.
+func (c *ExecCredential) Validate() error {
+	if c.Spec.Validity != nil && c.Spec.Validity.Duration <= 0 {
+		return fmt.Errorf("validity must be positive")
+	}
+	for key, tmpl := range c.Spec.Template {
+		if _, err := template.New(key).Parse(tmpl); err != nil {
+			return fmt.Errorf("template %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:object:root=true
+
+// ExecCredentialList contains a list of ExecCredential resources.
+type ExecCredentialList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ExecCredential `json:"items"`
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (c *ExecCredentialList) DeepCopyObject() runtime.Object {
+	cp := *c
+	if c.Items != nil {
+		cp.Items = make([]ExecCredential, len(c.Items))
+		for i := range c.Items {
+			cp.Items[i] = *c.Items[i].DeepCopyObject().(*ExecCredential)
+		}
+	}
+	return &cp
+}
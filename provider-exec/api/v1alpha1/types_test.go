@@ -0,0 +1,106 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	valid := &ExecCredential{
+		Spec: ExecCredentialSpec{
+			SecretRef:  framework.SecretReference{Name: "out"},
+			Parameters: map[string]string{"account": "payments"},
+			Template:   map[string]string{"DSN": "postgres://{{ .Data.username }}@db"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*ExecCredential)
+		wantErr string
+	}{
+		{name: "valid", modify: func(_ *ExecCredential) {}},
+		{
+			name:   "omitted secretRef",
+			modify: func(c *ExecCredential) { c.Spec.SecretRef.Name = "" },
+		},
+		{
+			name:    "non-positive validity",
+			modify:  func(c *ExecCredential) { c.Spec.Validity = &metav1.Duration{} },
+			wantErr: "validity",
+		},
+		{
+			name:    "invalid template syntax",
+			modify:  func(c *ExecCredential) { c.Spec.Template = map[string]string{"bad": "{{ .Foo"} },
+			wantErr: "template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := valid.DeepCopyObject().(*ExecCredential)
+			tt.modify(obj)
+			err := obj.Validate()
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if got := err.Error(); !strings.Contains(got, tt.wantErr) {
+				t.Fatalf("error %q does not contain %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeepCopyObject(t *testing.T) {
+	obj := &ExecCredential{
+		Spec: ExecCredentialSpec{
+			SecretRef:  framework.SecretReference{Name: "s"},
+			Parameters: map[string]string{"P": "V"},
+			Template:   map[string]string{"K": "V"},
+			Validity:   &metav1.Duration{Duration: time.Hour},
+		},
+	}
+	obj.Status.Phase = framework.PhaseReady
+
+	cp := obj.DeepCopyObject().(*ExecCredential)
+
+	cp.Spec.Parameters["P"] = "changed"
+	if obj.Spec.Parameters["P"] != "V" {
+		t.Fatal("DeepCopyObject did not copy parameters map")
+	}
+
+	cp.Spec.Template["K"] = "changed"
+	if obj.Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not copy template map")
+	}
+
+	cp.Spec.Validity.Duration = time.Minute
+	if obj.Spec.Validity.Duration != time.Hour {
+		t.Fatal("DeepCopyObject did not copy validity")
+	}
+}
+
+func TestDeepCopyObjectList(t *testing.T) {
+	list := &ExecCredentialList{
+		Items: []ExecCredential{
+			{Spec: ExecCredentialSpec{Template: map[string]string{"K": "V"}}},
+		},
+	}
+
+	cp := list.DeepCopyObject().(*ExecCredentialList)
+	cp.Items[0].Spec.Template["K"] = "changed"
+	if list.Items[0].Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not deep copy list items")
+	}
+}
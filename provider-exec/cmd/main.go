@@ -0,0 +1,69 @@
+// provider-exec runs the exec valet provider, which delegates to a plugin
+// binary.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-exec/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-exec/internal"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// Build metadata, set with -ldflags "-X main.version=..." at build time.
+// See [framework.NewBuildInfo] for the values used if they are not.
+var (
+	version  = "dev"
+	provider = ""
+	revision = ""
+)
+
+var (
+	pluginPath = flag.String(
+		"plugin",
+		"",
+		"Plugin binary to run for each provision and delete, with a JSON request on stdin.",
+	)
+	pluginTimeout = flag.Duration(
+		"plugin-timeout",
+		internal.DefaultTimeout,
+		"How long the plugin may run for a single action.",
+	)
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// +kubebuilder:rbac:groups=valet.ngl.cx,resources=execcredentials,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=valet.ngl.cx,resources=execcredentials/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=valet.ngl.cx,resources=execcredentials/finalizers,verbs=update
+
+func run() error {
+	var opts framework.Options
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	if *pluginPath == "" {
+		return errors.New("--plugin is required")
+	}
+
+	return framework.Run(ctrl.SetupSignalHandler(), &opts, framework.Operator[*v1alpha1.ExecCredential]{
+		Name:        "provider-exec",
+		BuildInfo:   framework.NewBuildInfo(provider, version, revision),
+		AddToScheme: v1alpha1.AddToScheme,
+		Provider: internal.New(
+			internal.WithCommand(*pluginPath),
+			internal.WithTimeout(*pluginTimeout),
+		),
+		LogValues: []any{"plugin", *pluginPath},
+	})
+}
@@ -0,0 +1,98 @@
+Feature: Exec Credentials
+  As a platform operator
+  I want the exec provider to provision and rotate credentials through a plugin binary
+  So that applications get credentials from proprietary systems without a custom provider
+
+  Background:
+    Given a Kubernetes cluster is running
+    And the CRDs are installed
+    And the operator is running
+
+  Scenario: Provision a credential successfully
+    When I create a ClientSecret "test-creds" with:
+      """yaml
+      spec:
+        secretRef:
+          name: test-creds
+        parameters:
+          database: orders
+      """
+    Then the ClientSecret "test-creds" should have phase "Ready" within 60 seconds
+    And a Secret "test-creds" should exist
+    And the Secret "test-creds" should contain key "username" with value "test-creds-user-1"
+    And the Secret "test-creds" should contain key "password"
+    And the ClientSecret "test-creds" should have 1 active keys
+
+  Scenario: Templates add keys to the Secret
+    When I create a ClientSecret "template-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: template-test
+        template:
+          DSN: "postgres://{{ .Data.username }}@db/orders"
+      """
+    Then the ClientSecret "template-test" should have phase "Ready" within 60 seconds
+    And the Secret "template-test" should contain key "DSN" with value "postgres://template-test-user-1@db/orders"
+    And the Secret "template-test" should contain key "username"
+
+  Scenario: Invalid template syntax is rejected
+    When I create a ClientSecret "bad-template" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-template
+        template:
+          SECRET: "{{ .Invalid"
+      """
+    Then the ClientSecret "bad-template" should have phase "Failed" within 60 seconds
+    And the ClientSecret "bad-template" status should contain message "template"
+    And the Secret "bad-template" should not exist
+
+  Scenario: Plugin failures are reported
+    When I create a ClientSecret "rejected" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rejected
+        parameters:
+          reject: "database orders is not managed"
+      """
+    Then the ClientSecret "rejected" should have phase "Failed" within 60 seconds
+    And the ClientSecret "rejected" status should contain message "database orders is not managed"
+    And the Secret "rejected" should not exist
+
+  Scenario: Delete ExecCredential cleans up resources
+    When I create a ClientSecret "delete-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: delete-test
+      """
+    Then the ClientSecret "delete-test" should have phase "Ready" within 60 seconds
+    And a Secret "delete-test" should exist
+    When I delete the ClientSecret "delete-test"
+    Then the ClientSecret "delete-test" should not exist within 60 seconds
+
+  Scenario: Expired credentials are rotated
+    When I create a ClientSecret "rotation-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rotation-test
+      """
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the ClientSecret "rotation-test" should have 1 active keys
+    When I expire the credentials for ClientSecret "rotation-test"
+    Then the Secret "rotation-test" should contain key "username" with value "rotation-test-user-2" within 30 seconds
+    And the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+
+  Scenario: Secret is owned by the ExecCredential
+    When I create a ClientSecret "ownership-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: ownership-test
+      """
+    Then the ClientSecret "ownership-test" should have phase "Ready" within 60 seconds
+    And the Secret "ownership-test" should be owned by ClientSecret "ownership-test"
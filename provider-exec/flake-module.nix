@@ -0,0 +1,70 @@
+{ inputs, ... }:
+{
+  perSystem =
+    { config, pkgs, ... }:
+    let
+      valet = config.valet.lib;
+
+      provider-exec = valet.mkGoModule {
+        pname = "provider-exec";
+        subPackages = [ "provider-exec/cmd" ];
+        postInstall = ''
+          mv $out/bin/cmd $out/bin/provider-exec
+        '';
+        meta.mainProgram = "provider-exec";
+      };
+
+      provider-exec-compressed = pkgs.stdenvNoCC.mkDerivation {
+        inherit (provider-exec) pname version meta;
+        dontUnpack = true;
+        nativeBuildInputs = [ pkgs.upx ];
+        buildPhase = ''
+          mkdir -p $out/bin
+          upx -o $out/bin/provider-exec ${provider-exec}/bin/provider-exec
+        '';
+      };
+
+      image = valet.mkImage {
+        name = "provider-exec";
+        entrypoint = "${provider-exec-compressed}/bin/provider-exec";
+      };
+    in
+    {
+      packages = {
+        inherit provider-exec provider-exec-compressed;
+        provider-exec-image = image;
+      };
+
+      checks.provider-exec-helm = valet.packageChart {
+        name = "provider-exec";
+        src = "${inputs.self}/provider-exec/charts/provider-exec";
+      };
+
+      checks.provider-exec-lint = valet.withPackageEnv provider-exec {
+        name = "provider-exec-lint";
+        extraBuildInputs = [ pkgs.golangci-lint ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          golangci-lint run --timeout 10m ./provider-exec/...
+        '';
+      };
+
+      checks.provider-exec-test = valet.withPackageEnv provider-exec {
+        name = "provider-exec-test";
+        extraBuildInputs = [
+          pkgs.gotestsum
+          pkgs.etcd
+          pkgs.kubernetes
+        ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum --format short-verbose -- -coverpkg=github.com/lukasngl/valet/framework/...,./... -coverprofile=coverage.txt ./provider-exec/...
+        '';
+        installPhase = ''
+          mkdir -p $out
+          cp coverage.txt $out/
+        '';
+      };
+    };
+}
@@ -0,0 +1,247 @@
+// Package internal contains the exec provider implementation.
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-exec/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-exec/plugin"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultValidity is the default credential rotation period (90 days).
+const DefaultValidity = 90 * 24 * time.Hour
+
+// DefaultTimeout is how long the plugin may run for a single action.
+const DefaultTimeout = 30 * time.Second
+
+// maxStderr limits how much of the end of the plugin's stderr is kept for
+// error messages, which end up in the resource's status.
+const maxStderr = 1024
+
+// waitDelay is how long to wait for the plugin's output to be closed after
+// it was killed, e.g. by a child process that inherited it.
+const waitDelay = 5 * time.Second
+
+// Provider provisions credentials by running a plugin binary with a JSON
+// request on stdin, following the protocol of package [plugin]. It implements
+// [framework.Provider] for [*v1alpha1.ExecCredential].
+//
+// The plugin is configured on the operator, not per resource, so creating
+// resources does not allow running arbitrary commands.
+type Provider struct {
+	command []string
+	timeout time.Duration
+}
+
+// Option configures a [Provider].
+type Option func(*Provider)
+
+// WithCommand sets the plugin binary and its arguments. It is required.
+func WithCommand(path string, args ...string) Option {
+	return func(p *Provider) { p.command = append([]string{path}, args...) }
+}
+
+// WithTimeout sets how long the plugin may run for a single action.
+// Defaults to [DefaultTimeout].
+func WithTimeout(d time.Duration) Option {
+	return func(p *Provider) { p.timeout = d }
+}
+
+// New creates a [Provider] with the given options.
+func New(opts ...Option) *Provider {
+	p := &Provider{timeout: DefaultTimeout}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// NewObject returns a zero-value ExecCredential.
+func (p *Provider) NewObject() *v1alpha1.ExecCredential {
+	return &v1alpha1.ExecCredential{}
+}
+
+// Provision runs the plugin to create a new credential. The returned data is
+// written to the output Secret together with the rendered templates.
+func (p *Provider) Provision(
+	ctx context.Context,
+	obj *v1alpha1.ExecCredential,
+) (*framework.Result, error) {
+	var resp plugin.ProvisionResponse
+	if err := p.run(ctx, obj, plugin.ActionProvision, "", &resp); err != nil {
+		return nil, fmt.Errorf("provisioning credential: %w", err)
+	}
+	if resp.KeyID == "" {
+		return nil, errors.New("no keyId returned from plugin")
+	}
+	if len(resp.Data) == 0 {
+		return nil, errors.New("no data returned from plugin")
+	}
+
+	now := time.Now()
+
+	validity := DefaultValidity
+	if obj.Spec.Validity != nil {
+		validity = obj.Spec.Validity.Duration
+	}
+	validUntil := now.Add(validity)
+	if resp.ValidUntil != nil && resp.ValidUntil.Before(validUntil) {
+		if !resp.ValidUntil.After(now) {
+			return nil, fmt.Errorf("plugin returned credential %s that expired at %s",
+				resp.KeyID, resp.ValidUntil.Format(time.RFC3339))
+		}
+		validUntil = *resp.ValidUntil
+	}
+
+	data := make(map[string]string, len(resp.Data)+len(obj.Spec.Template))
+	for key, value := range resp.Data {
+		data[key] = value
+	}
+
+	templateData := map[string]any{
+		"Data":  resp.Data,
+		"KeyID": resp.KeyID,
+	}
+	for key, tmpl := range obj.Spec.Template {
+		rendered, err := renderTemplate(tmpl, templateData)
+		if err != nil {
+			return nil, fmt.Errorf("rendering template %q: %w", key, err)
+		}
+		data[key] = rendered
+	}
+
+	return &framework.Result{
+		StringData:    data,
+		ProvisionedAt: now,
+		ValidUntil:    validUntil,
+		KeyID:         resp.KeyID,
+		SecretType:    corev1.SecretType(resp.SecretType),
+	}, nil
+}
+
+// DeleteKey runs the plugin to delete a credential. The plugin is expected to
+// succeed if the credential no longer exists.
+func (p *Provider) DeleteKey(
+	ctx context.Context,
+	obj *v1alpha1.ExecCredential,
+	keyID string,
+) error {
+	if keyID == "" {
+		return nil
+	}
+
+	if err := p.run(ctx, obj, plugin.ActionDelete, keyID, nil); err != nil {
+		return fmt.Errorf("deleting credential %s: %w", keyID, err)
+	}
+
+	return nil
+}
+
+// run runs the plugin for the given action and decodes its stdout into out,
+// if non-nil. Failures of the plugin are returned as [*pluginError].
+func (p *Provider) run(
+	ctx context.Context,
+	obj *v1alpha1.ExecCredential,
+	action, keyID string,
+	out any,
+) error {
+	if len(p.command) == 0 {
+		return errors.New("no plugin command configured")
+	}
+
+	input, err := json.Marshal(plugin.Request{
+		APIVersion: plugin.APIVersion,
+		Action:     action,
+		Resource: plugin.Resource{
+			Namespace: obj.Namespace,
+			Name:      obj.Name,
+			UID:       string(obj.UID),
+		},
+		Parameters: obj.Spec.Parameters,
+		KeyID:      keyID,
+	})
+	if err != nil {
+		return fmt.Errorf("encoding request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.command[0], p.command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = waitDelay
+
+	log.FromContext(ctx).V(1).Info("running plugin", "action", action, "command", p.command[0])
+
+	start := time.Now()
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("plugin timed out after %s", p.timeout)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return &pluginError{ExitCode: exitErr.ExitCode(), Stderr: tail(stderr.String())}
+		}
+		return fmt.Errorf("running plugin: %w", err)
+	}
+
+	log.FromContext(ctx).V(1).Info("plugin finished", "action", action, "duration", time.Since(start))
+
+	if out != nil {
+		if err := json.Unmarshal(stdout.Bytes(), out); err != nil {
+			return fmt.Errorf("parsing plugin output: %w", err)
+		}
+	}
+	return nil
+}
+
+// tail returns the end of the plugin's stderr, at most [maxStderr] bytes.
+func tail(stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if len(stderr) > maxStderr {
+		stderr = "..." + stderr[len(stderr)-maxStderr:]
+	}
+	return stderr
+}
+
+// pluginError is a plugin exiting with a non-zero status.
+type pluginError struct {
+	ExitCode int
+	Stderr   string
+}
+
+func (e *pluginError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("plugin exited with status %d", e.ExitCode)
+	}
+	return fmt.Sprintf("plugin exited with status %d: %s", e.ExitCode, e.Stderr)
+}
+
+// renderTemplate renders a Go template string with the given data.
+func renderTemplate(tmpl string, data any) (string, error) {
+	t, err := template.New("").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,226 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-exec/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-exec/plugin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakePluginEnv makes the test binary act as a plugin, see [fakePlugin].
+const fakePluginEnv = "VALET_EXEC_FAKE_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakePluginEnv) != "" {
+		os.Exit(fakePlugin())
+	}
+	_ = os.Setenv(fakePluginEnv, "1")
+	os.Exit(m.Run())
+}
+
+// fakePlugin implements the plugin protocol. Provisioning echoes the request
+// in the returned data. The parameters "exit", "output", "expiry" and "sleep"
+// make it fail with that status, print that output, return that expiry and
+// sleep that long, respectively.
+func fakePlugin() int {
+	var req plugin.Request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintf(os.Stderr, "decoding request: %v\n", err)
+		return 2
+	}
+	if d, err := time.ParseDuration(req.Parameters["sleep"]); err == nil {
+		time.Sleep(d)
+	}
+	if code, err := strconv.Atoi(req.Parameters["exit"]); err == nil {
+		fmt.Fprintf(os.Stderr, "something went wrong\n")
+		return code
+	}
+	if output, ok := req.Parameters["output"]; ok {
+		fmt.Print(output)
+		return 0
+	}
+
+	switch req.Action {
+	case plugin.ActionProvision:
+		resp := plugin.ProvisionResponse{
+			KeyID: "user-1",
+			Data: map[string]string{
+				"apiVersion": req.APIVersion,
+				"resource":   req.Resource.Namespace + "/" + req.Resource.Name + "/" + req.Resource.UID,
+				"username":   req.Parameters["username"],
+			},
+			SecretType: string(corev1.SecretTypeBasicAuth),
+		}
+		if expiry, err := time.Parse(time.RFC3339, req.Parameters["expiry"]); err == nil {
+			resp.ValidUntil = &expiry
+		}
+		_ = json.NewEncoder(os.Stdout).Encode(resp)
+	case plugin.ActionDelete:
+		if req.KeyID != "user-1" {
+			fmt.Fprintf(os.Stderr, "unexpected key ID %q\n", req.KeyID)
+			return 1
+		}
+	}
+	return 0
+}
+
+// newTestProvider returns a provider running the test binary as plugin and an
+// object with the given parameters.
+func newTestProvider(t *testing.T, params map[string]string) (*Provider, *v1alpha1.ExecCredential) {
+	t.Helper()
+	obj := &v1alpha1.ExecCredential{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "db", UID: "uid-1"},
+		Spec: v1alpha1.ExecCredentialSpec{
+			SecretRef:  framework.SecretReference{Name: "db-credentials"},
+			Parameters: params,
+		},
+	}
+	return New(WithCommand(os.Args[0]), WithTimeout(5*time.Second)), obj
+}
+
+func TestProvision(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		p, obj := newTestProvider(t, map[string]string{"username": "orders"})
+		obj.Spec.Template = map[string]string{"DSN": "postgres://{{ .Data.username }}@db/orders?id={{ .KeyID }}"}
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.KeyID != "user-1" {
+			t.Fatalf("got keyID %q, want user-1", result.KeyID)
+		}
+		if result.SecretType != corev1.SecretTypeBasicAuth {
+			t.Fatalf("got secret type %q", result.SecretType)
+		}
+		if got := result.StringData["apiVersion"]; got != plugin.APIVersion {
+			t.Fatalf("plugin got apiVersion %q", got)
+		}
+		if got := result.StringData["resource"]; got != "apps/db/uid-1" {
+			t.Fatalf("plugin got resource %q", got)
+		}
+		if got := result.StringData["DSN"]; got != "postgres://orders@db/orders?id=user-1" {
+			t.Fatalf("got rendered template %q", got)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != DefaultValidity {
+			t.Fatalf("got validity %v, want %v", got, DefaultValidity)
+		}
+	})
+
+	t.Run("expiry from plugin", func(t *testing.T) {
+		expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+		p, obj := newTestProvider(t, map[string]string{"expiry": expiry.Format(time.RFC3339)})
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.ValidUntil.Equal(expiry) {
+			t.Fatalf("got validUntil %v, want %v", result.ValidUntil, expiry)
+		}
+	})
+
+	errorTests := []struct {
+		name    string
+		params  map[string]string
+		modify  func(*Provider)
+		wantErr string
+	}{
+		{
+			name:    "non-zero exit",
+			params:  map[string]string{"exit": "3"},
+			wantErr: "plugin exited with status 3: something went wrong",
+		},
+		{
+			name:    "invalid output",
+			params:  map[string]string{"output": "not json"},
+			wantErr: "parsing plugin output",
+		},
+		{
+			name:    "missing keyId",
+			params:  map[string]string{"output": `{"data":{"token":"t"}}`},
+			wantErr: "no keyId",
+		},
+		{
+			name:    "missing data",
+			params:  map[string]string{"output": `{"keyId":"k"}`},
+			wantErr: "no data",
+		},
+		{
+			name:    "expired credential",
+			params:  map[string]string{"expiry": time.Now().Add(-time.Minute).Format(time.RFC3339)},
+			wantErr: "expired",
+		},
+		{
+			name:    "timeout",
+			params:  map[string]string{"sleep": "10s"},
+			modify:  func(p *Provider) { p.timeout = 100 * time.Millisecond },
+			wantErr: "timed out",
+		},
+		{
+			name:    "no command",
+			modify:  func(p *Provider) { p.command = nil },
+			wantErr: "no plugin command configured",
+		},
+		{
+			name:    "missing binary",
+			modify:  func(p *Provider) { p.command = []string{"/nonexistent/plugin"} },
+			wantErr: "running plugin",
+		},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, obj := newTestProvider(t, tt.params)
+			if tt.modify != nil {
+				tt.modify(p)
+			}
+
+			_, err := p.Provision(context.Background(), obj)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error %v does not contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeleteKey(t *testing.T) {
+	t.Run("deletes the key", func(t *testing.T) {
+		p, obj := newTestProvider(t, nil)
+		if err := p.DeleteKey(context.Background(), obj, "user-1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("plugin failure", func(t *testing.T) {
+		p, obj := newTestProvider(t, nil)
+		err := p.DeleteKey(context.Background(), obj, "user-2")
+		if err == nil || !strings.Contains(err.Error(), `unexpected key ID "user-2"`) {
+			t.Fatalf("expected the plugin's stderr in the error, got %v", err)
+		}
+	})
+
+	t.Run("empty key ID", func(t *testing.T) {
+		p, obj := newTestProvider(t, map[string]string{"exit": "1"})
+		if err := p.DeleteKey(context.Background(), obj, ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestTail(t *testing.T) {
+	long := strings.Repeat("x", 2*maxStderr) + "end"
+	got := tail(long + "\n")
+	if !strings.HasPrefix(got, "...") || !strings.HasSuffix(got, "end") || len(got) != maxStderr+3 {
+		t.Fatalf("unexpected tail of length %d: %q", len(got), got[:10])
+	}
+}
@@ -0,0 +1,74 @@
+// Package plugin defines the protocol between the exec provider and the
+// plugin binary it runs for each action, in the style of credential helpers.
+//
+// For every action, the provider runs the configured command with a [Request]
+// as JSON on stdin:
+//
+//   - "provision" creates a new credential. The plugin writes a
+//     [ProvisionResponse] as JSON to stdout.
+//   - "delete" deletes the credential with the given key ID. Its output is
+//     ignored. Plugins should also exit successfully if the credential no
+//     longer exists.
+//
+// A plugin fails by exiting with a non-zero status. The end of what it wrote
+// to stderr is shown in the resource's status, so it should not contain
+// secrets. The plugin inherits the operator's environment, which is where it
+// finds the credentials of the system it manages. The package only depends
+// on the standard library, so plugins written in Go can import it.
+package plugin
+
+import "time"
+
+// APIVersion is the version of the protocol sent in [Request.APIVersion].
+const APIVersion = "valet.ngl.cx/exec/v1"
+
+// Actions sent in [Request.Action].
+const (
+	ActionProvision = "provision"
+	ActionDelete    = "delete"
+)
+
+// Request is written to the plugin's stdin.
+type Request struct {
+	// APIVersion is [APIVersion].
+	APIVersion string `json:"apiVersion"`
+
+	// Action is [ActionProvision] or [ActionDelete].
+	Action string `json:"action"`
+
+	// Resource identifies the resource the request is made for.
+	Resource Resource `json:"resource"`
+
+	// Parameters are the resource's spec.parameters.
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// KeyID is the ID of the credential to delete. Only set for
+	// [ActionDelete].
+	KeyID string `json:"keyId,omitempty"`
+}
+
+// Resource identifies an ExecCredential.
+type Resource struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	UID       string `json:"uid"`
+}
+
+// ProvisionResponse is written to stdout by a successful provision.
+type ProvisionResponse struct {
+	// KeyID identifies the new credential. It is sent back when the
+	// credential is deleted. Required.
+	KeyID string `json:"keyId"`
+
+	// Data are the values of the credential, written to the output Secret.
+	// Required.
+	Data map[string]string `json:"data"`
+
+	// ValidUntil is when the credential expires. It is renewed before then.
+	// Defaults to the resource's spec.validity.
+	ValidUntil *time.Time `json:"validUntil,omitempty"`
+
+	// SecretType is the type of a newly created output Secret, e.g.
+	// kubernetes.io/basic-auth. Defaults to Opaque.
+	SecretType string `json:"secretType,omitempty"`
+}
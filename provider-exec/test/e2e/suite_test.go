@@ -0,0 +1,151 @@
+package e2e
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/cucumber/godog"
+	"github.com/cucumber/godog/colors"
+	"github.com/lukasngl/valet/framework/bddtest"
+	"github.com/lukasngl/valet/provider-exec/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-exec/internal"
+	"github.com/lukasngl/valet/provider-exec/plugin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var godogOpts = godog.Options{
+	Format:      "pretty",
+	Output:      colors.Colored(os.Stdout),
+	Paths:       []string{"../../features"},
+	Concurrency: 1,
+	Strict:      true,
+}
+
+func init() {
+	godog.BindFlags("godog.", flag.CommandLine, &godogOpts)
+}
+
+var testEnvCfg bddtest.Env
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakePluginEnv) != "" {
+		os.Exit(fakePlugin())
+	}
+
+	flag.Parse()
+
+	if len(flag.Args()) > 0 {
+		godogOpts.Paths = flag.Args()
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	testEnvCfg.Scheme = runtime.NewScheme()
+	_ = corev1.AddToScheme(testEnvCfg.Scheme)
+	_ = v1alpha1.AddToScheme(testEnvCfg.Scheme)
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths: []string{"../../config/crd"},
+		Scheme:            testEnvCfg.Scheme,
+	}
+	env.ControlPlane.GetAPIServer().Configure().
+		Append("advertise-address", "127.0.0.1").
+		Append("bind-address", "127.0.0.1")
+
+	cfg, err := env.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start envtest: %v\n", err)
+		os.Exit(1)
+	}
+	testEnvCfg.Cfg = cfg
+
+	code := m.Run()
+
+	_ = env.Stop()
+	os.Exit(code)
+}
+
+// TestFeatures runs all scenarios with the test binary itself as plugin, see
+// [fakePlugin], so no external service is needed.
+func TestFeatures(t *testing.T) {
+	t.Setenv(fakePluginEnv, "1")
+	t.Setenv(fakePluginStateEnv, t.TempDir())
+
+	status := godog.TestSuite{
+		Name: "provider-exec",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New(internal.WithCommand(os.Args[0]))
+			shared := bddtest.New[*v1alpha1.ExecCredential](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &godogOpts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+const (
+	// fakePluginEnv makes the test binary act as a plugin.
+	fakePluginEnv = "VALET_EXEC_FAKE_PLUGIN"
+
+	// fakePluginStateEnv is the directory the plugin counts the credentials
+	// of each resource in.
+	fakePluginStateEnv = "VALET_EXEC_FAKE_PLUGIN_STATE"
+)
+
+// fakePlugin implements a canned plugin. It provisions numbered users per
+// resource, and fails provision requests with a "reject" parameter with its
+// value on stderr.
+func fakePlugin() int {
+	var req plugin.Request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintf(os.Stderr, "decoding request: %v\n", err)
+		return 2
+	}
+
+	switch req.Action {
+	case plugin.ActionProvision:
+		if reason, ok := req.Parameters["reject"]; ok {
+			fmt.Fprintln(os.Stderr, reason)
+			return 1
+		}
+
+		counter := filepath.Join(os.Getenv(fakePluginStateEnv), req.Resource.UID)
+		n := 1
+		if b, err := os.ReadFile(counter); err == nil {
+			prev, _ := strconv.Atoi(string(b))
+			n = prev + 1
+		}
+		if err := os.WriteFile(counter, []byte(strconv.Itoa(n)), 0o600); err != nil {
+			fmt.Fprintf(os.Stderr, "writing state: %v\n", err)
+			return 1
+		}
+
+		id := "user-" + strconv.Itoa(n)
+		_ = json.NewEncoder(os.Stdout).Encode(plugin.ProvisionResponse{
+			KeyID: id,
+			Data: map[string]string{
+				"username": req.Resource.Name + "-" + id,
+				"password": "fake-password-" + id,
+			},
+			SecretType: "kubernetes.io/basic-auth",
+		})
+		return 0
+	case plugin.ActionDelete:
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown action %q\n", req.Action)
+		return 1
+	}
+}
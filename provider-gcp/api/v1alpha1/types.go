@@ -0,0 +1,213 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	SchemeBuilder.Register(&GCPServiceAccountKey{}, &GCPServiceAccountKeyList{})
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=gcpsak,categories=valet
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// GCPServiceAccountKey provisions and rotates keys for Google Cloud service accounts.
+type GCPServiceAccountKey struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	Spec GCPServiceAccountKeySpec `json:"spec,omitzero"`
+	// +optional
+	Status framework.ClientSecretStatus `json:"status,omitzero"`
+}
+
+// GCPServiceAccountKeySpec defines the desired state.
+type GCPServiceAccountKeySpec struct {
+	// SecretRef is the Kubernetes Secret to create/update with the provisioned credentials.
+	// Defaults to a Secret named after the resource.
+	// +optional
+	SecretRef framework.SecretReference `json:"secretRef,omitzero"`
+
+	// Suspend stops the rotation and cleanup of the credentials while set,
+	// e.g. during incidents or migrations. The output Secret keeps its
+	// current credentials.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DeletionPolicy controls what happens when the resource is deleted:
+	// Delete deletes its keys at the provider and the output Secret with it,
+	// Retain keeps both, e.g. to move the resource to another cluster.
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy framework.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// SecretTemplate sets labels, annotations and the type of the output
+	// Secret, e.g. for Reloader, External Secrets or Argo CD to match on.
+	// +optional
+	SecretTemplate framework.SecretTemplate `json:"secretTemplate,omitzero"`
+
+	// Targets are further Secrets the credentials are written to, each
+	// with a subset of the keys, its own template or in another namespace,
+	// so that consumers expecting different shapes share one credential.
+	// +optional
+	Targets framework.OutputTargets `json:"targets,omitempty"`
+
+	// Replication copies the output Secret into all namespaces matching a
+	// label selector that allow it, and keeps the copies in sync.
+	// +optional
+	Replication *framework.Replication `json:"replication,omitempty"`
+
+	// ServiceAccount is the email of the service account to create keys for,
+	// e.g. my-app@my-project.iam.gserviceaccount.com.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Pattern=`^[^@]+@[^@]+$`
+	ServiceAccount string `json:"serviceAccount"`
+
+	// Validity is how long each key is used before it is rotated and deleted.
+	// Service account keys do not expire unless an organization policy says
+	// so, so the operator enforces this lifetime. Defaults to 90 days (2160h).
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// Rotation configures how credentials are rotated, e.g. how long a
+	// superseded credential stays valid.
+	// +optional
+	Rotation framework.RotationPolicy `json:"rotation,omitzero"`
+
+	// Template maps output secret keys to Go template strings.
+	// Available template variables: .PrivateKeyJSON, .ClientEmail, .ProjectID, .PrivateKeyID
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinProperties=1
+	Template map[string]string `json:"template"`
+}
+
+// GetSecretRef returns the reference to the target output Secret, defaulting
+// to a Secret named after the resource.
+func (g *GCPServiceAccountKey) GetSecretRef() framework.SecretReference {
+	return g.Spec.SecretRef.OrDefault(g.Name)
+}
+
+// IsSuspended reports whether the rotation of the credentials is suspended.
+// It implements [framework.Suspender].
+func (g *GCPServiceAccountKey) IsSuspended() bool {
+	return g.Spec.Suspend
+}
+
+// GetDeletionPolicy returns what happens to the keys and the output Secret
+// when the resource is deleted. It implements [framework.DeletionPolicyHolder].
+func (g *GCPServiceAccountKey) GetDeletionPolicy() framework.DeletionPolicy {
+	return g.Spec.DeletionPolicy
+}
+
+// GetSecretTemplate returns the labels, annotations and type of the output
+// Secret. It implements [framework.SecretTemplateHolder].
+func (g *GCPServiceAccountKey) GetSecretTemplate() framework.SecretTemplate {
+	return g.Spec.SecretTemplate
+}
+
+// GetTargets returns the further Secrets the credentials are written to.
+// It implements [framework.TargetHolder].
+func (g *GCPServiceAccountKey) GetTargets() framework.OutputTargets {
+	return g.Spec.Targets
+}
+
+// GetReplication returns the replication of the output Secret, or nil.
+// It implements [framework.ReplicationHolder].
+func (g *GCPServiceAccountKey) GetReplication() *framework.Replication {
+	return g.Spec.Replication
+}
+
+// GetValidity returns the requested validity of the credentials, or zero for
+// the provider's default. It implements [framework.ValidityRequester].
+func (g *GCPServiceAccountKey) GetValidity() time.Duration {
+	if g.Spec.Validity == nil {
+		return 0
+	}
+	return g.Spec.Validity.Duration
+}
+
+// GetRotationPolicy returns the rotation policy of the credentials. It
+// implements [framework.RotationPolicyHolder].
+func (g *GCPServiceAccountKey) GetRotationPolicy() framework.RotationPolicy {
+	return g.Spec.Rotation
+}
+
+// GetStatus returns a pointer to the shared status.
+func (g *GCPServiceAccountKey) GetStatus() *framework.ClientSecretStatus {
+	return &g.Status
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (g *GCPServiceAccountKey) DeepCopyObject() runtime.Object {
+	cp := *g
+	cp.ObjectMeta = *g.DeepCopy()
+	cp.Status = g.Status.DeepCopy()
+	cp.Spec.SecretTemplate = g.Spec.SecretTemplate.DeepCopy()
+	cp.Spec.Targets = g.Spec.Targets.DeepCopy()
+	cp.Spec.Replication = g.Spec.Replication.DeepCopy()
+	if g.Spec.Template != nil {
+		cp.Spec.Template = make(map[string]string, len(g.Spec.Template))
+		for k, v := range g.Spec.Template {
+			cp.Spec.Template[k] = v
+		}
+	}
+	if g.Spec.Validity != nil {
+		v := *g.Spec.Validity
+		cp.Spec.Validity = &v
+	}
+	return &cp
+}
+
+// Validate performs structural validation of the spec.
+func (g *GCPServiceAccountKey) Validate() error {
+	if g.Spec.ServiceAccount == "" {
+		return fmt.Errorf("serviceAccount is required")
+	}
+	if strings.Count(g.Spec.ServiceAccount, "@") != 1 {
+		return fmt.Errorf("serviceAccount must be an email address")
+	}
+	if g.Spec.Validity != nil && g.Spec.Validity.Duration <= 0 {
+		return fmt.Errorf("validity must be positive")
+	}
+	if len(g.Spec.Template) == 0 {
+		return fmt.Errorf("template must have at least one entry")
+	}
+	for key, tmpl := range g.Spec.Template {
+		if _, err := template.New(key).Parse(tmpl); err != nil {
+			return fmt.Errorf("template %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:object:root=true
+
+// GCPServiceAccountKeyList contains a list of GCPServiceAccountKey resources.
+type GCPServiceAccountKeyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GCPServiceAccountKey `json:"items"`
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (g *GCPServiceAccountKeyList) DeepCopyObject() runtime.Object {
+	cp := *g
+	if g.Items != nil {
+		cp.Items = make([]GCPServiceAccountKey, len(g.Items))
+		for i := range g.Items {
+			cp.Items[i] = *g.Items[i].DeepCopyObject().(*GCPServiceAccountKey)
+		}
+	}
+	return &cp
+}
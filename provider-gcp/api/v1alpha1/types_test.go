@@ -0,0 +1,119 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	valid := &GCPServiceAccountKey{
+		Spec: GCPServiceAccountKeySpec{
+			SecretRef:      framework.SecretReference{Name: "out"},
+			ServiceAccount: "app@project.iam.gserviceaccount.com",
+			Template:       map[string]string{"KEY": "{{ .PrivateKeyJSON }}"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*GCPServiceAccountKey)
+		wantErr string
+	}{
+		{name: "valid", modify: func(_ *GCPServiceAccountKey) {}},
+		{
+			name:   "omitted secretRef",
+			modify: func(g *GCPServiceAccountKey) { g.Spec.SecretRef.Name = "" },
+		},
+		{
+			name:    "missing serviceAccount",
+			modify:  func(g *GCPServiceAccountKey) { g.Spec.ServiceAccount = "" },
+			wantErr: "serviceAccount",
+		},
+		{
+			name:    "serviceAccount is not an email",
+			modify:  func(g *GCPServiceAccountKey) { g.Spec.ServiceAccount = "my-app" },
+			wantErr: "email",
+		},
+		{
+			name: "non-positive validity",
+			modify: func(g *GCPServiceAccountKey) {
+				g.Spec.Validity = &metav1.Duration{Duration: -time.Hour}
+			},
+			wantErr: "validity",
+		},
+		{
+			name:    "empty template",
+			modify:  func(g *GCPServiceAccountKey) { g.Spec.Template = nil },
+			wantErr: "template",
+		},
+		{
+			name:    "invalid template syntax",
+			modify:  func(g *GCPServiceAccountKey) { g.Spec.Template = map[string]string{"bad": "{{ .Foo"} },
+			wantErr: "template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := valid.DeepCopyObject().(*GCPServiceAccountKey)
+			tt.modify(obj)
+			err := obj.Validate()
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if got := err.Error(); !strings.Contains(got, tt.wantErr) {
+				t.Fatalf("error %q does not contain %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeepCopyObject(t *testing.T) {
+	validity := metav1.Duration{Duration: 48 * time.Hour}
+	obj := &GCPServiceAccountKey{
+		Spec: GCPServiceAccountKeySpec{
+			SecretRef:      framework.SecretReference{Name: "s"},
+			ServiceAccount: "sa@p.iam.gserviceaccount.com",
+			Template:       map[string]string{"K": "V"},
+			Validity:       &validity,
+		},
+	}
+	obj.Status.Phase = framework.PhaseReady
+
+	cp := obj.DeepCopyObject().(*GCPServiceAccountKey)
+
+	cp.Spec.Template["K"] = "changed"
+	if obj.Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not copy template map")
+	}
+
+	cp.Spec.Validity.Duration = time.Hour
+	if obj.Spec.Validity.Duration != 48*time.Hour {
+		t.Fatal("DeepCopyObject did not copy validity")
+	}
+}
+
+func TestDeepCopyObjectList(t *testing.T) {
+	list := &GCPServiceAccountKeyList{
+		Items: []GCPServiceAccountKey{
+			{Spec: GCPServiceAccountKeySpec{ServiceAccount: "a@p"}},
+		},
+	}
+
+	cp := list.DeepCopyObject().(*GCPServiceAccountKeyList)
+	cp.Items[0].Spec.ServiceAccount = "changed"
+	if list.Items[0].Spec.ServiceAccount != "a@p" {
+		t.Fatal("DeepCopyObject did not deep copy list items")
+	}
+}
@@ -0,0 +1,108 @@
+Feature: GCP Service Account Key Provisioning
+  As a platform operator
+  I want the GCP provider to provision and rotate service account keys
+  So that applications can authenticate with Google Cloud services
+
+  Background:
+    Given a Kubernetes cluster is running
+    And the CRDs are installed
+    And the operator is running
+
+  Scenario: Provision a service account key successfully
+    When I create a ClientSecret "test-key" with:
+      """yaml
+      spec:
+        secretRef:
+          name: test-key
+        serviceAccount: "$TEST_GCP_SERVICE_ACCOUNT"
+        template:
+          credentials.json: "{{ .PrivateKeyJSON }}"
+          CLIENT_EMAIL: "{{ .ClientEmail }}"
+      """
+    Then the ClientSecret "test-key" should have phase "Ready" within 60 seconds
+    And a Secret "test-key" should exist
+    And the Secret "test-key" should contain key "credentials.json"
+    And the Secret "test-key" should contain key "CLIENT_EMAIL"
+
+  Scenario: Invalid template syntax is rejected
+    When I create a ClientSecret "bad-template" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-template
+        serviceAccount: "$TEST_GCP_SERVICE_ACCOUNT"
+        template:
+          SECRET: "{{ .Invalid"
+      """
+    Then the ClientSecret "bad-template" should have phase "Failed" within 60 seconds
+    And the ClientSecret "bad-template" status should contain message "template"
+    And the Secret "bad-template" should not exist
+
+  Scenario: Delete GCPServiceAccountKey cleans up resources
+    When I create a ClientSecret "delete-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: delete-test
+        serviceAccount: "$TEST_GCP_SERVICE_ACCOUNT"
+        template:
+          credentials.json: "{{ .PrivateKeyJSON }}"
+      """
+    Then the ClientSecret "delete-test" should have phase "Ready" within 60 seconds
+    And a Secret "delete-test" should exist
+    When I delete the ClientSecret "delete-test"
+    Then the ClientSecret "delete-test" should not exist within 60 seconds
+
+  Scenario: Expired service account keys are rotated
+    When I create a ClientSecret "rotation-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rotation-test
+        serviceAccount: "$TEST_GCP_SERVICE_ACCOUNT"
+        template:
+          credentials.json: "{{ .PrivateKeyJSON }}"
+      """
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the ClientSecret "rotation-test" should have 1 active keys
+    When I expire the credentials for ClientSecret "rotation-test"
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+
+  Scenario: Missing serviceAccount is rejected
+    When I try to create a ClientSecret "no-account" with:
+      """yaml
+      spec:
+        secretRef:
+          name: no-account
+        template:
+          credentials.json: "{{ .PrivateKeyJSON }}"
+      """
+    Then the operation should have failed with "serviceAccount"
+
+  Scenario: Secret is owned by the GCPServiceAccountKey
+    When I create a ClientSecret "ownership-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: ownership-test
+        serviceAccount: "$TEST_GCP_SERVICE_ACCOUNT"
+        template:
+          credentials.json: "{{ .PrivateKeyJSON }}"
+      """
+    Then the ClientSecret "ownership-test" should have phase "Ready" within 60 seconds
+    And the Secret "ownership-test" should be owned by ClientSecret "ownership-test"
+
+  @mock
+  Scenario: Template renders with correct values
+    When I create a ClientSecret "value-check" with:
+      """yaml
+      spec:
+        secretRef:
+          name: value-check
+        serviceAccount: "$TEST_GCP_SERVICE_ACCOUNT"
+        template:
+          credentials.json: "{{ .PrivateKeyJSON }}"
+          CLIENT_EMAIL: "{{ .ClientEmail }}"
+      """
+    Then the ClientSecret "value-check" should have phase "Ready" within 60 seconds
+    And the Secret "value-check" should contain key "CLIENT_EMAIL" with value "$TEST_GCP_SERVICE_ACCOUNT"
@@ -0,0 +1,95 @@
+{ inputs, ... }:
+{
+  perSystem =
+    { config, pkgs, ... }:
+    let
+      valet = config.valet.lib;
+
+      provider-gcp = valet.mkGoModule {
+        pname = "provider-gcp";
+        subPackages = [ "provider-gcp/cmd" ];
+        postInstall = ''
+          mv $out/bin/cmd $out/bin/provider-gcp
+        '';
+        meta.mainProgram = "provider-gcp";
+      };
+
+      provider-gcp-compressed = pkgs.stdenvNoCC.mkDerivation {
+        inherit (provider-gcp) pname version meta;
+        dontUnpack = true;
+        nativeBuildInputs = [ pkgs.upx ];
+        buildPhase = ''
+          mkdir -p $out/bin
+          upx -o $out/bin/provider-gcp ${provider-gcp}/bin/provider-gcp
+        '';
+      };
+
+      image = valet.mkImage {
+        name = "provider-gcp";
+        entrypoint = "${provider-gcp-compressed}/bin/provider-gcp";
+      };
+      e2e-test-gcp = pkgs.writeShellApplication {
+        name = "e2e-test-gcp";
+        runtimeInputs = [
+          pkgs.go
+          pkgs.gotestsum
+        ];
+        text = ''
+          export GOFLAGS="-mod=vendor"
+          if [ ! -d vendor ]; then
+            ln -sfn ${valet.workspaceVendor} vendor
+          fi
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum \
+            --format "''${GOTESTSUM_FORMAT:-short-verbose}" \
+            -- -run TestE2E -timeout 10m \
+            -coverpkg=github.com/lukasngl/valet/framework/...,./... \
+            -coverprofile="''${COVERAGE_FILE:-coverage-gcp-e2e.txt}" \
+            ./provider-gcp/...
+        '';
+      };
+    in
+    {
+      packages = {
+        inherit provider-gcp provider-gcp-compressed;
+        provider-gcp-image = image;
+      };
+
+      apps.e2e-test-gcp = {
+        type = "app";
+        program = "${e2e-test-gcp}/bin/e2e-test-gcp";
+      };
+
+      checks.provider-gcp-helm = valet.packageChart {
+        name = "provider-gcp";
+        src = "${inputs.self}/provider-gcp/charts/provider-gcp";
+      };
+
+      checks.provider-gcp-lint = valet.withPackageEnv provider-gcp {
+        name = "provider-gcp-lint";
+        extraBuildInputs = [ pkgs.golangci-lint ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          golangci-lint run --timeout 10m ./provider-gcp/...
+        '';
+      };
+
+      checks.provider-gcp-test = valet.withPackageEnv provider-gcp {
+        name = "provider-gcp-test";
+        extraBuildInputs = [
+          pkgs.gotestsum
+          pkgs.etcd
+          pkgs.kubernetes
+        ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum --format short-verbose -- -short -coverpkg=github.com/lukasngl/valet/framework/...,./... -coverprofile=coverage.txt ./provider-gcp/...
+        '';
+        installPhase = ''
+          mkdir -p $out
+          cp coverage.txt $out/
+        '';
+      };
+    };
+}
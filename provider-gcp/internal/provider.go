@@ -0,0 +1,334 @@
+// Package internal contains the GCP provider implementation.
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-gcp/api/v1alpha1"
+	"golang.org/x/oauth2/google"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// DefaultValidity is the default key rotation period (90 days).
+	DefaultValidity = 90 * 24 * time.Hour
+
+	// iamBaseURL is the IAM Admin API base URL.
+	iamBaseURL = "https://iam.googleapis.com/v1"
+
+	// iamScope is the OAuth2 scope required by the IAM Admin API.
+	iamScope = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// Provider provisions Google Cloud service account keys using the IAM Admin
+// API. It implements [framework.Provider] for [*v1alpha1.GCPServiceAccountKey].
+type Provider struct {
+	client   *http.Client
+	baseURL  string
+	initOnce sync.Once
+	initErr  error
+}
+
+// Option configures a [Provider].
+type Option func(*Provider)
+
+// WithHTTPClient sets a custom HTTP client, skipping Application Default
+// Credentials lookup. Useful for testing with a mock transport.
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Provider) { p.client = c }
+}
+
+// WithBaseURL overrides the IAM Admin API base URL.
+func WithBaseURL(url string) Option {
+	return func(p *Provider) { p.baseURL = url }
+}
+
+// New creates a [Provider] with the given options.
+func New(opts ...Option) *Provider {
+	p := &Provider{baseURL: iamBaseURL}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// NewObject returns a zero-value GCPServiceAccountKey.
+func (p *Provider) NewObject() *v1alpha1.GCPServiceAccountKey {
+	return &v1alpha1.GCPServiceAccountKey{}
+}
+
+// Provision creates a new JSON key for a service account.
+func (p *Provider) Provision(
+	ctx context.Context,
+	obj *v1alpha1.GCPServiceAccountKey,
+) (*framework.Result, error) {
+	if err := p.initClient(); err != nil {
+		return nil, err
+	}
+
+	validity := DefaultValidity
+	if obj.Spec.Validity != nil {
+		validity = obj.Spec.Validity.Duration
+	}
+
+	respBody, err := p.iamRequest(
+		ctx,
+		http.MethodPost,
+		keysPath(obj.Spec.ServiceAccount),
+		createKeyRequest{
+			PrivateKeyType: "TYPE_GOOGLE_CREDENTIALS_FILE",
+			KeyAlgorithm:   "KEY_ALG_RSA_2048",
+		},
+	)
+	if err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.Status == "FAILED_PRECONDITION" {
+			return nil, fmt.Errorf(
+				"creating key for service account %s: service account may already have the "+
+					"maximum number of keys, delete an unmanaged key or wait for the next cleanup: %w",
+				obj.Spec.ServiceAccount, err)
+		}
+		return nil, fmt.Errorf("creating key for service account %s: %w",
+			obj.Spec.ServiceAccount, err)
+	}
+
+	// The key name is decoded even if other fields of the response are
+	// malformed. From then on, failures return the created key with the
+	// error, so that it is tracked and deleted on expiry.
+	var saKey serviceAccountKey
+	parseErr := json.Unmarshal(respBody, &saKey)
+	if saKey.Name == "" {
+		if parseErr != nil {
+			return nil, fmt.Errorf("parsing create key response: %w", parseErr)
+		}
+		return nil, errors.New("no key name returned from IAM API")
+	}
+	now := time.Now()
+	keyID := path.Base(saKey.Name)
+	created := &framework.Result{ProvisionedAt: now, ValidUntil: now.Add(validity), KeyID: keyID}
+
+	if parseErr != nil {
+		return created, fmt.Errorf("parsing create key response: %w", parseErr)
+	}
+	if saKey.PrivateKeyData == "" {
+		return created, errors.New("no private key data returned from IAM API")
+	}
+
+	keyJSON, err := base64.StdEncoding.DecodeString(saKey.PrivateKeyData)
+	if err != nil {
+		return created, fmt.Errorf("decoding private key data: %w", err)
+	}
+
+	var creds credentialsFile
+	if err := json.Unmarshal(keyJSON, &creds); err != nil {
+		return created, fmt.Errorf("parsing private key JSON: %w", err)
+	}
+
+	templateData := map[string]string{
+		"PrivateKeyJSON": string(keyJSON),
+		"ClientEmail":    creds.ClientEmail,
+		"ProjectID":      creds.ProjectID,
+		"PrivateKeyID":   keyID,
+	}
+
+	data := make(map[string]string, len(obj.Spec.Template))
+	for key, tmpl := range obj.Spec.Template {
+		rendered, err := renderTemplate(tmpl, templateData)
+		if err != nil {
+			return created, fmt.Errorf("rendering template %q: %w", key, err)
+		}
+		data[key] = rendered
+	}
+
+	created.StringData = data
+	return created, nil
+}
+
+// DeleteKey deletes a key from a service account.
+// Returns nil if the key has already been deleted (idempotent).
+func (p *Provider) DeleteKey(
+	ctx context.Context,
+	obj *v1alpha1.GCPServiceAccountKey,
+	keyID string,
+) error {
+	if keyID == "" {
+		return nil
+	}
+
+	if err := p.initClient(); err != nil {
+		return err
+	}
+
+	_, err := p.iamRequest(
+		ctx,
+		http.MethodDelete,
+		keysPath(obj.Spec.ServiceAccount)+"/"+url.PathEscape(keyID),
+		nil,
+	)
+	if err != nil {
+		// Key already deleted at the provider — not an error.
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			log.FromContext(ctx).
+				Info("key already deleted", "keyId", keyID, "serviceAccount", obj.Spec.ServiceAccount)
+			return nil
+		}
+		return fmt.Errorf("deleting key %s of service account %s: %w",
+			keyID, obj.Spec.ServiceAccount, err)
+	}
+
+	return nil
+}
+
+// Endpoints returns the Google OAuth 2.0 token endpoint, which Application
+// Default Credentials are exchanged at, and the IAM Admin API. It implements
+// [framework.EndpointDeclarer].
+func (p *Provider) Endpoints() ([]framework.Endpoint, error) {
+	iam, err := framework.EndpointFromURL("GCP IAM", p.baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return []framework.Endpoint{
+		{Name: "Google OAuth 2.0", Scheme: "https", Host: "oauth2.googleapis.com", Port: 443},
+		iam,
+	}, nil
+}
+
+// initClient creates an HTTP client authenticated with Application Default
+// Credentials on first use. If the client was pre-configured via
+// [WithHTTPClient], initialization is skipped (no GCP credentials required).
+func (p *Provider) initClient() error {
+	p.initOnce.Do(func() {
+		if p.client != nil {
+			return // pre-configured, e.g. for testing
+		}
+		// The client outlives any single reconciliation, so it must not be
+		// bound to a request context.
+		client, err := google.DefaultClient(context.Background(), iamScope)
+		if err != nil {
+			p.initErr = fmt.Errorf("loading application default credentials: %w", err)
+			return
+		}
+		client.Timeout = 30 * time.Second
+		p.client = client
+	})
+	return p.initErr
+}
+
+// keysPath returns the API path of the keys collection of a service account.
+// The "-" wildcard lets the API infer the project from the account email.
+func keysPath(serviceAccount string) string {
+	return "/projects/-/serviceAccounts/" + url.PathEscape(serviceAccount) + "/keys"
+}
+
+// iamRequest makes a request to the IAM Admin API. Error responses are
+// returned as [*apiError].
+func (p *Provider) iamRequest(
+	ctx context.Context,
+	method, urlPath string,
+	body any,
+) ([]byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("marshalling request body: %w", err)
+		}
+		reqBody = bytes.NewReader(jsonBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+urlPath, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		apiErr := &apiError{Code: resp.StatusCode}
+		var envelope struct {
+			Error *apiError `json:"error"`
+		}
+		if json.Unmarshal(respBody, &envelope) == nil && envelope.Error != nil {
+			apiErr = envelope.Error
+			apiErr.Code = resp.StatusCode
+		} else {
+			apiErr.Message = string(respBody)
+		}
+		return nil, apiErr
+	}
+
+	return respBody, nil
+}
+
+// IAM Admin API request/response types.
+
+type createKeyRequest struct {
+	PrivateKeyType string `json:"privateKeyType"`
+	KeyAlgorithm   string `json:"keyAlgorithm"`
+}
+
+type serviceAccountKey struct {
+	// Name is the full resource name:
+	// projects/{project}/serviceAccounts/{email}/keys/{keyID}.
+	Name           string `json:"name"`
+	PrivateKeyData string `json:"privateKeyData"`
+}
+
+// credentialsFile is the subset of the decoded key file used for templating.
+type credentialsFile struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+}
+
+// apiError is a Google API error response.
+type apiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+func (e *apiError) Error() string {
+	if e.Status != "" {
+		return fmt.Sprintf("IAM API error (status %d %s): %s", e.Code, e.Status, e.Message)
+	}
+	return fmt.Sprintf("IAM API error (status %d): %s", e.Code, e.Message)
+}
+
+// renderTemplate renders a Go template string with the given data.
+func renderTemplate(tmpl string, data map[string]string) (string, error) {
+	t, err := template.New("").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,175 @@
+package e2e
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cucumber/godog"
+	"github.com/cucumber/godog/colors"
+	"github.com/google/uuid"
+	"github.com/lukasngl/valet/framework/bddtest"
+	"github.com/lukasngl/valet/provider-gcp/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-gcp/internal"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var godogOpts = godog.Options{
+	Format:      "pretty",
+	Output:      colors.Colored(os.Stdout),
+	Paths:       []string{"../../features"},
+	Concurrency: 1,
+	Strict:      true,
+}
+
+func init() {
+	godog.BindFlags("godog.", flag.CommandLine, &godogOpts)
+}
+
+var testEnvCfg bddtest.Env
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	if len(flag.Args()) > 0 {
+		godogOpts.Paths = flag.Args()
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	testEnvCfg.Scheme = runtime.NewScheme()
+	_ = corev1.AddToScheme(testEnvCfg.Scheme)
+	_ = v1alpha1.AddToScheme(testEnvCfg.Scheme)
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths: []string{"../../config/crd"},
+		Scheme:            testEnvCfg.Scheme,
+	}
+	env.ControlPlane.GetAPIServer().Configure().
+		Append("advertise-address", "127.0.0.1").
+		Append("bind-address", "127.0.0.1")
+
+	cfg, err := env.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start envtest: %v\n", err)
+		os.Exit(1)
+	}
+	testEnvCfg.Cfg = cfg
+
+	code := m.Run()
+
+	_ = env.Stop()
+	os.Exit(code)
+}
+
+// TestMock runs all scenarios with a canned HTTP transport.
+func TestMock(t *testing.T) {
+	t.Setenv("TEST_GCP_SERVICE_ACCOUNT", "valet-test@valet-test.iam.gserviceaccount.com")
+
+	opts := godogOpts
+	status := godog.TestSuite{
+		Name: "provider-gcp-mock",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New(
+				internal.WithHTTPClient(&http.Client{Transport: &iamMock{}}),
+				internal.WithBaseURL("http://iam.mock"),
+			)
+			shared := bddtest.New[*v1alpha1.GCPServiceAccountKey](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// TestE2E runs non-mock scenarios against a real Google Cloud project.
+func TestE2E(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e tests in short mode")
+	}
+
+	if os.Getenv("TEST_GCP_SERVICE_ACCOUNT") == "" {
+		t.Skip("skipping e2e tests: TEST_GCP_SERVICE_ACCOUNT not set")
+	}
+
+	opts := godogOpts
+	opts.Tags = "~@mock"
+	status := godog.TestSuite{
+		Name: "provider-gcp-e2e",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New()
+			shared := bddtest.New[*v1alpha1.GCPServiceAccountKey](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// iamMock is an [http.RoundTripper] that returns canned IAM Admin API
+// responses. Each key creation returns a unique key ID and a key file for the
+// requested service account; key deletion always succeeds.
+type iamMock struct{}
+
+func (m *iamMock) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Paths look like /projects/-/serviceAccounts/{email}/keys[/{keyID}].
+	parts := strings.Split(req.URL.Path, "/")
+	switch {
+	case req.Method == http.MethodPost && len(parts) == 6 && parts[5] == "keys":
+		email, _ := url.PathUnescape(parts[4])
+		keyID := strings.ReplaceAll(uuid.New().String(), "-", "")
+		keyFile, err := json.Marshal(map[string]string{
+			"type":           "service_account",
+			"project_id":     "valet-test",
+			"private_key_id": keyID,
+			"private_key":    "fake-private-key",
+			"client_email":   email,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return jsonResponse(http.StatusOK, map[string]string{
+			"name":           "projects/valet-test/serviceAccounts/" + email + "/keys/" + keyID,
+			"privateKeyData": base64.StdEncoding.EncodeToString(keyFile),
+		})
+	case req.Method == http.MethodDelete && len(parts) == 7 && parts[5] == "keys":
+		return jsonResponse(http.StatusOK, map[string]string{})
+	default:
+		return jsonResponse(http.StatusNotFound, map[string]any{
+			"error": map[string]any{
+				"code":    http.StatusNotFound,
+				"status":  "NOT_FOUND",
+				"message": fmt.Sprintf("unexpected request: %s %s", req.Method, req.URL.Path),
+			},
+		})
+	}
+}
+
+func jsonResponse(status int, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(string(data))),
+	}, nil
+}
@@ -0,0 +1,279 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"regexp"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Signing algorithms of the generated keys, as JWS "alg" values.
+const (
+	AlgorithmES256 = "ES256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// Keys of the output Secret.
+const (
+	PrivateKeyKey = "private.pem"
+	PublicKeyKey  = "public.pem"
+	KeyIDKey      = "kid"
+)
+
+// Defaults of [JWKSConfigMap].
+const (
+	JWKSConfigMapSuffix = "-jwks"
+	DefaultJWKSKey      = "jwks.json"
+)
+
+// configMapKeyPattern matches valid ConfigMap data keys.
+var configMapKeyPattern = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+func init() {
+	SchemeBuilder.Register(&JWTSigningKey{}, &JWTSigningKeyList{})
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=jwtsk,categories=valet
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// JWTSigningKey generates and rotates asymmetric JWT signing keys and
+// publishes the public keys as a JWKS.
+type JWTSigningKey struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	Spec JWTSigningKeySpec `json:"spec,omitzero"`
+	// +optional
+	Status framework.ClientSecretStatus `json:"status,omitzero"`
+}
+
+// JWTSigningKeySpec defines the desired state.
+type JWTSigningKeySpec struct {
+	// SecretRef is the Kubernetes Secret to create/update with the current
+	// signing key. It holds the PKCS #8 private key in private.pem, the public
+	// key in public.pem and the key ID, the key's RFC 7638 thumbprint, in kid.
+	// Defaults to a Secret named after the resource.
+	// +optional
+	SecretRef framework.SecretReference `json:"secretRef,omitzero"`
+
+	// Suspend stops the rotation and cleanup of the credentials while set,
+	// e.g. during incidents or migrations. The output Secret keeps its
+	// current credentials.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DeletionPolicy controls what happens when the resource is deleted:
+	// Delete deletes its keys at the provider and the output Secret with it,
+	// Retain keeps both, e.g. to move the resource to another cluster.
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy framework.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// SecretTemplate sets labels, annotations and the type of the output
+	// Secret, e.g. for Reloader, External Secrets or Argo CD to match on.
+	// +optional
+	SecretTemplate framework.SecretTemplate `json:"secretTemplate,omitzero"`
+
+	// Targets are further Secrets the credentials are written to, each
+	// with a subset of the keys, its own template or in another namespace,
+	// so that consumers expecting different shapes share one credential.
+	// +optional
+	Targets framework.OutputTargets `json:"targets,omitempty"`
+
+	// Replication copies the output Secret into all namespaces matching a
+	// label selector that allow it, and keeps the copies in sync.
+	// +optional
+	Replication *framework.Replication `json:"replication,omitempty"`
+
+	// Algorithm is the signing algorithm of the keys: ES256 (P-256), RS256
+	// (2048-bit RSA) or EdDSA (Ed25519). Defaults to ES256.
+	// +kubebuilder:validation:Enum=ES256;RS256;EdDSA
+	// +optional
+	Algorithm string `json:"algorithm,omitempty"`
+
+	// Validity is how long each key is published. Keys are rotated a tenth
+	// of the validity, at most 7 days, before they expire; until then
+	// verifiers accept tokens signed by either key. Defaults to 90 days
+	// (2160h).
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// Rotation configures how credentials are rotated, e.g. how long a
+	// superseded credential stays valid.
+	// +optional
+	Rotation framework.RotationPolicy `json:"rotation,omitzero"`
+
+	// JWKSConfigMap is the ConfigMap in the same namespace the public keys
+	// are published to. Defaults to <name>-jwks with the key jwks.json.
+	// +optional
+	JWKSConfigMap *JWKSConfigMap `json:"jwksConfigMap,omitempty"`
+
+	// Template maps additional output secret keys to Go template strings.
+	// Available template variables: .PrivateKey, .PublicKey, .KeyID,
+	// .Algorithm, .JWK
+	// +optional
+	Template map[string]string `json:"template,omitempty"`
+}
+
+// JWKSConfigMap selects where the JWKS is published.
+type JWKSConfigMap struct {
+	// Name of the ConfigMap. Defaults to <name>-jwks.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Key of the JWKS in the ConfigMap. Defaults to jwks.json.
+	// +kubebuilder:validation:Pattern=`^[-._a-zA-Z0-9]+$`
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// JWKSConfigMapName returns the name of the ConfigMap the JWKS is published
+// to.
+func (k *JWTSigningKey) JWKSConfigMapName() string {
+	if c := k.Spec.JWKSConfigMap; c != nil && c.Name != "" {
+		return c.Name
+	}
+	return k.Name + JWKSConfigMapSuffix
+}
+
+// JWKSKey returns the key of the JWKS in its ConfigMap.
+func (k *JWTSigningKey) JWKSKey() string {
+	if c := k.Spec.JWKSConfigMap; c != nil && c.Key != "" {
+		return c.Key
+	}
+	return DefaultJWKSKey
+}
+
+// GetSecretRef returns the reference to the target output Secret, defaulting
+// to a Secret named after the resource.
+func (k *JWTSigningKey) GetSecretRef() framework.SecretReference {
+	return k.Spec.SecretRef.OrDefault(k.Name)
+}
+
+// IsSuspended reports whether the rotation of the credentials is suspended.
+// It implements [framework.Suspender].
+func (k *JWTSigningKey) IsSuspended() bool {
+	return k.Spec.Suspend
+}
+
+// GetDeletionPolicy returns what happens to the keys and the output Secret
+// when the resource is deleted. It implements [framework.DeletionPolicyHolder].
+func (k *JWTSigningKey) GetDeletionPolicy() framework.DeletionPolicy {
+	return k.Spec.DeletionPolicy
+}
+
+// GetSecretTemplate returns the labels, annotations and type of the output
+// Secret. It implements [framework.SecretTemplateHolder].
+func (k *JWTSigningKey) GetSecretTemplate() framework.SecretTemplate {
+	return k.Spec.SecretTemplate
+}
+
+// GetTargets returns the further Secrets the credentials are written to.
+// It implements [framework.TargetHolder].
+func (k *JWTSigningKey) GetTargets() framework.OutputTargets {
+	return k.Spec.Targets
+}
+
+// GetReplication returns the replication of the output Secret, or nil.
+// It implements [framework.ReplicationHolder].
+func (k *JWTSigningKey) GetReplication() *framework.Replication {
+	return k.Spec.Replication
+}
+
+// GetValidity returns the requested validity of the credentials, or zero for
+// the provider's default. It implements [framework.ValidityRequester].
+func (k *JWTSigningKey) GetValidity() time.Duration {
+	if k.Spec.Validity == nil {
+		return 0
+	}
+	return k.Spec.Validity.Duration
+}
+
+// GetRotationPolicy returns the rotation policy of the credentials. It
+// implements [framework.RotationPolicyHolder].
+func (k *JWTSigningKey) GetRotationPolicy() framework.RotationPolicy {
+	return k.Spec.Rotation
+}
+
+// GetStatus returns a pointer to the shared status.
+func (k *JWTSigningKey) GetStatus() *framework.ClientSecretStatus {
+	return &k.Status
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (k *JWTSigningKey) DeepCopyObject() runtime.Object {
+	cp := *k
+	cp.ObjectMeta = *k.DeepCopy()
+	cp.Status = k.Status.DeepCopy()
+	cp.Spec.SecretTemplate = k.Spec.SecretTemplate.DeepCopy()
+	cp.Spec.Targets = k.Spec.Targets.DeepCopy()
+	cp.Spec.Replication = k.Spec.Replication.DeepCopy()
+	if k.Spec.JWKSConfigMap != nil {
+		c := *k.Spec.JWKSConfigMap
+		cp.Spec.JWKSConfigMap = &c
+	}
+	if k.Spec.Template != nil {
+		cp.Spec.Template = make(map[string]string, len(k.Spec.Template))
+		for key, v := range k.Spec.Template {
+			cp.Spec.Template[key] = v
+		}
+	}
+	if k.Spec.Validity != nil {
+		v := *k.Spec.Validity
+		cp.Spec.Validity = &v
+	}
+	return &cp
+}
+
+// Validate performs structural validation of the spec.
+func (k *JWTSigningKey) Validate() error {
+	switch k.Spec.Algorithm {
+	case "", AlgorithmES256, AlgorithmRS256, AlgorithmEdDSA:
+	default:
+		return fmt.Errorf("algorithm %q must be ES256, RS256 or EdDSA", k.Spec.Algorithm)
+	}
+	if k.Spec.Validity != nil && k.Spec.Validity.Duration <= 0 {
+		return fmt.Errorf("validity must be positive")
+	}
+	if c := k.Spec.JWKSConfigMap; c != nil && c.Key != "" && !configMapKeyPattern.MatchString(c.Key) {
+		return fmt.Errorf("jwksConfigMap.key %q is not a valid ConfigMap key", c.Key)
+	}
+	if err := framework.ValidateTemplateKeys(k.Spec.Template, PrivateKeyKey, PublicKeyKey, KeyIDKey); err != nil {
+		return err
+	}
+	for key, tmpl := range k.Spec.Template {
+		if _, err := template.New(key).Parse(tmpl); err != nil {
+			return fmt.Errorf("template %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:object:root=true
+
+// JWTSigningKeyList contains a list of JWTSigningKey resources.
+type JWTSigningKeyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []JWTSigningKey `json:"items"`
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (k *JWTSigningKeyList) DeepCopyObject() runtime.Object {
+	cp := *k
+	if k.Items != nil {
+		cp.Items = make([]JWTSigningKey, len(k.Items))
+		for i := range k.Items {
+			cp.Items[i] = *k.Items[i].DeepCopyObject().(*JWTSigningKey)
+		}
+	}
+	return &cp
+}
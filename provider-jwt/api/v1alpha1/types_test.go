@@ -0,0 +1,155 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	valid := &JWTSigningKey{
+		Spec: JWTSigningKeySpec{
+			SecretRef: framework.SecretReference{Name: "out"},
+			Algorithm: AlgorithmRS256,
+			Validity:  &metav1.Duration{Duration: 30 * 24 * time.Hour},
+			Template:  map[string]string{"jwk.json": "{{ .JWK }}"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*JWTSigningKey)
+		wantErr string
+	}{
+		{name: "valid", modify: func(_ *JWTSigningKey) {}},
+		{
+			name:   "defaults",
+			modify: func(k *JWTSigningKey) { k.Spec.Algorithm, k.Spec.Validity = "", nil },
+		},
+		{
+			name: "custom configmap",
+			modify: func(k *JWTSigningKey) {
+				k.Spec.JWKSConfigMap = &JWKSConfigMap{Name: "keys", Key: "keys.json"}
+			},
+		},
+		{
+			name:   "omitted secretRef",
+			modify: func(k *JWTSigningKey) { k.Spec.SecretRef.Name = "" },
+		},
+		{
+			name:    "unknown algorithm",
+			modify:  func(k *JWTSigningKey) { k.Spec.Algorithm = "HS256" },
+			wantErr: "algorithm",
+		},
+		{
+			name:    "non-positive validity",
+			modify:  func(k *JWTSigningKey) { k.Spec.Validity = &metav1.Duration{} },
+			wantErr: "validity",
+		},
+		{
+			name:    "invalid configmap key",
+			modify:  func(k *JWTSigningKey) { k.Spec.JWKSConfigMap = &JWKSConfigMap{Key: "jwks json"} },
+			wantErr: "jwksConfigMap.key",
+		},
+		{
+			name:    "template overwrites private key",
+			modify:  func(k *JWTSigningKey) { k.Spec.Template = map[string]string{PrivateKeyKey: "x"} },
+			wantErr: "reserved",
+		},
+		{
+			name:    "template overwrites key ID",
+			modify:  func(k *JWTSigningKey) { k.Spec.Template = map[string]string{KeyIDKey: "x"} },
+			wantErr: "reserved",
+		},
+		{
+			name:    "invalid template syntax",
+			modify:  func(k *JWTSigningKey) { k.Spec.Template = map[string]string{"bad": "{{ .Foo"} },
+			wantErr: "template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := valid.DeepCopyObject().(*JWTSigningKey)
+			tt.modify(obj)
+			err := obj.Validate()
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if got := err.Error(); !strings.Contains(got, tt.wantErr) {
+				t.Fatalf("error %q does not contain %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWKSConfigMapDefaults(t *testing.T) {
+	obj := &JWTSigningKey{ObjectMeta: metav1.ObjectMeta{Name: "issuer"}}
+	if got := obj.JWKSConfigMapName(); got != "issuer-jwks" {
+		t.Errorf("JWKSConfigMapName() = %q, want issuer-jwks", got)
+	}
+	if got := obj.JWKSKey(); got != DefaultJWKSKey {
+		t.Errorf("JWKSKey() = %q, want %q", got, DefaultJWKSKey)
+	}
+
+	obj.Spec.JWKSConfigMap = &JWKSConfigMap{Name: "keys", Key: "keys.json"}
+	if got := obj.JWKSConfigMapName(); got != "keys" {
+		t.Errorf("JWKSConfigMapName() = %q, want keys", got)
+	}
+	if got := obj.JWKSKey(); got != "keys.json" {
+		t.Errorf("JWKSKey() = %q, want keys.json", got)
+	}
+}
+
+func TestDeepCopyObject(t *testing.T) {
+	obj := &JWTSigningKey{
+		Spec: JWTSigningKeySpec{
+			SecretRef:     framework.SecretReference{Name: "s"},
+			Validity:      &metav1.Duration{Duration: time.Hour},
+			JWKSConfigMap: &JWKSConfigMap{Name: "keys"},
+			Template:      map[string]string{"K": "V"},
+		},
+	}
+	obj.Status.Phase = framework.PhaseReady
+
+	cp := obj.DeepCopyObject().(*JWTSigningKey)
+
+	cp.Spec.Template["K"] = "changed"
+	if obj.Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not copy template map")
+	}
+
+	cp.Spec.JWKSConfigMap.Name = "changed"
+	if obj.Spec.JWKSConfigMap.Name != "keys" {
+		t.Fatal("DeepCopyObject did not copy jwksConfigMap")
+	}
+
+	cp.Spec.Validity.Duration = time.Minute
+	if obj.Spec.Validity.Duration != time.Hour {
+		t.Fatal("DeepCopyObject did not copy validity")
+	}
+}
+
+func TestDeepCopyObjectList(t *testing.T) {
+	list := &JWTSigningKeyList{
+		Items: []JWTSigningKey{
+			{Spec: JWTSigningKeySpec{Template: map[string]string{"K": "V"}}},
+		},
+	}
+
+	cp := list.DeepCopyObject().(*JWTSigningKeyList)
+	cp.Items[0].Spec.Template["K"] = "changed"
+	if list.Items[0].Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not deep copy list items")
+	}
+}
@@ -0,0 +1,101 @@
+Feature: JWT Signing Keys
+  As a platform operator
+  I want the JWT provider to generate and rotate signing keys and publish a JWKS
+  So that token issuers and verifiers share keys without an external service
+
+  Background:
+    Given a Kubernetes cluster is running
+    And the CRDs are installed
+    And the operator is running
+
+  Scenario: Generate a signing key successfully
+    When I create a ClientSecret "test-key" with:
+      """yaml
+      spec:
+        secretRef:
+          name: test-key
+      """
+    Then the ClientSecret "test-key" should have phase "Ready" within 60 seconds
+    And a Secret "test-key" should exist
+    And the Secret "test-key" should contain key "private.pem"
+    And the Secret "test-key" should contain key "public.pem"
+    And the Secret "test-key" should contain key "kid"
+    And the Secret "test-key" should hold a JWT signing key
+    And the ConfigMap "test-key-jwks" key "jwks.json" should publish the key in Secret "test-key" within 10 seconds
+
+  Scenario: Algorithm, ConfigMap and templates are configurable
+    When I create a ClientSecret "rsa-key" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rsa-key
+        algorithm: RS256
+        jwksConfigMap:
+          name: issuer-keys
+          key: keys.json
+        template:
+          jwk.json: "{{ .JWK }}"
+      """
+    Then the ClientSecret "rsa-key" should have phase "Ready" within 60 seconds
+    And the Secret "rsa-key" should contain key "jwk.json"
+    And the Secret "rsa-key" should hold a JWT signing key
+    And the ConfigMap "issuer-keys" key "keys.json" should publish the key in Secret "rsa-key" within 10 seconds
+
+  Scenario: Invalid template syntax is rejected
+    When I create a ClientSecret "bad-template" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-template
+        template:
+          SECRET: "{{ .Invalid"
+      """
+    Then the ClientSecret "bad-template" should have phase "Failed" within 60 seconds
+    And the ClientSecret "bad-template" status should contain message "template"
+    And the Secret "bad-template" should not exist
+
+  Scenario: Delete JWTSigningKey cleans up resources
+    When I create a ClientSecret "delete-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: delete-test
+      """
+    Then the ClientSecret "delete-test" should have phase "Ready" within 60 seconds
+    And a Secret "delete-test" should exist
+    When I delete the ClientSecret "delete-test"
+    Then the ClientSecret "delete-test" should not exist within 60 seconds
+
+  Scenario: Expired keys are rotated and removed from the JWKS
+    When I create a ClientSecret "rotation-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rotation-test
+      """
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the ClientSecret "rotation-test" should have 1 active keys
+    When I expire the credentials for ClientSecret "rotation-test"
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the ConfigMap "rotation-test-jwks" key "jwks.json" should publish the key in Secret "rotation-test" within 30 seconds
+    And the ConfigMap "rotation-test-jwks" key "jwks.json" should publish 1 key within 30 seconds
+
+  Scenario: Unknown algorithms are rejected
+    When I try to create a ClientSecret "bad-algorithm" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-algorithm
+        algorithm: HS256
+      """
+    Then the operation should have failed with "algorithm"
+
+  Scenario: Secret is owned by the JWTSigningKey
+    When I create a ClientSecret "ownership-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: ownership-test
+      """
+    Then the ClientSecret "ownership-test" should have phase "Ready" within 60 seconds
+    And the Secret "ownership-test" should be owned by ClientSecret "ownership-test"
@@ -0,0 +1,70 @@
+{ inputs, ... }:
+{
+  perSystem =
+    { config, pkgs, ... }:
+    let
+      valet = config.valet.lib;
+
+      provider-jwt = valet.mkGoModule {
+        pname = "provider-jwt";
+        subPackages = [ "provider-jwt/cmd" ];
+        postInstall = ''
+          mv $out/bin/cmd $out/bin/provider-jwt
+        '';
+        meta.mainProgram = "provider-jwt";
+      };
+
+      provider-jwt-compressed = pkgs.stdenvNoCC.mkDerivation {
+        inherit (provider-jwt) pname version meta;
+        dontUnpack = true;
+        nativeBuildInputs = [ pkgs.upx ];
+        buildPhase = ''
+          mkdir -p $out/bin
+          upx -o $out/bin/provider-jwt ${provider-jwt}/bin/provider-jwt
+        '';
+      };
+
+      image = valet.mkImage {
+        name = "provider-jwt";
+        entrypoint = "${provider-jwt-compressed}/bin/provider-jwt";
+      };
+    in
+    {
+      packages = {
+        inherit provider-jwt provider-jwt-compressed;
+        provider-jwt-image = image;
+      };
+
+      checks.provider-jwt-helm = valet.packageChart {
+        name = "provider-jwt";
+        src = "${inputs.self}/provider-jwt/charts/provider-jwt";
+      };
+
+      checks.provider-jwt-lint = valet.withPackageEnv provider-jwt {
+        name = "provider-jwt-lint";
+        extraBuildInputs = [ pkgs.golangci-lint ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          golangci-lint run --timeout 10m ./provider-jwt/...
+        '';
+      };
+
+      checks.provider-jwt-test = valet.withPackageEnv provider-jwt {
+        name = "provider-jwt-test";
+        extraBuildInputs = [
+          pkgs.gotestsum
+          pkgs.etcd
+          pkgs.kubernetes
+        ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum --format short-verbose -- -coverpkg=github.com/lukasngl/valet/framework/...,./... -coverprofile=coverage.txt ./provider-jwt/...
+        '';
+        installPhase = ''
+          mkdir -p $out
+          cp coverage.txt $out/
+        '';
+      };
+    };
+}
@@ -0,0 +1,186 @@
+package internal
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/lukasngl/valet/provider-jwt/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// jwk is a public JSON Web Key (RFC 7517).
+type jwk struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// jwkSet is a JWK Set (RFC 7517, section 5).
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// newJWK returns the signing JWK of pub for the given algorithm, with its
+// RFC 7638 thumbprint as key ID.
+func newJWK(pub crypto.PublicKey, algorithm string) (jwk, error) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	var key jwk
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		ecdhKey, err := pub.ECDH()
+		if err != nil {
+			return jwk{}, fmt.Errorf("encoding public key: %w", err)
+		}
+		// Uncompressed point: 0x04 || X || Y.
+		point := ecdhKey.Bytes()
+		size := (len(point) - 1) / 2
+		key = jwk{Kty: "EC", Crv: pub.Curve.Params().Name, X: b64(point[1 : 1+size]), Y: b64(point[1+size:])}
+	case *rsa.PublicKey:
+		key = jwk{Kty: "RSA", N: b64(pub.N.Bytes()), E: b64(big.NewInt(int64(pub.E)).Bytes())}
+	case ed25519.PublicKey:
+		key = jwk{Kty: "OKP", Crv: "Ed25519", X: b64(pub)}
+	default:
+		return jwk{}, fmt.Errorf("unsupported public key type %T", pub)
+	}
+
+	kid, err := thumbprint(key)
+	if err != nil {
+		return jwk{}, err
+	}
+	key.Kid, key.Use, key.Alg = kid, "sig", algorithm
+	return key, nil
+}
+
+// thumbprint returns the RFC 7638 SHA-256 thumbprint of key.
+func thumbprint(key jwk) (string, error) {
+	// The required members, serialized in lexicographic order without
+	// whitespace, which is how encoding/json marshals maps.
+	members := map[string]string{"kty": key.Kty}
+	switch key.Kty {
+	case "EC":
+		members["crv"], members["x"], members["y"] = key.Crv, key.X, key.Y
+	case "RSA":
+		members["e"], members["n"] = key.E, key.N
+	case "OKP":
+		members["crv"], members["x"] = key.Crv, key.X
+	}
+	canonical, err := json.Marshal(members)
+	if err != nil {
+		return "", fmt.Errorf("encoding JWK thumbprint: %w", err)
+	}
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// publish adds key to the JWKS ConfigMap of obj, creating it if needed.
+func (p *Provider) publish(ctx context.Context, obj *v1alpha1.JWTSigningKey, key jwk) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: obj.JWKSConfigMapName(), Namespace: obj.Namespace},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, p.kube, cm, func() error {
+		if err := claim(obj, cm); err != nil {
+			return err
+		}
+		set, err := readJWKS(cm, obj.JWKSKey())
+		if err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(set.Keys, func(k jwk) bool { return k.Kid == key.Kid }) {
+			set.Keys = append(set.Keys, key)
+		}
+		return writeJWKS(cm, obj.JWKSKey(), set)
+	})
+	return err
+}
+
+// unpublish removes the key with the given ID from the JWKS ConfigMap of obj.
+// A missing ConfigMap or key counts as removed.
+func (p *Provider) unpublish(ctx context.Context, obj *v1alpha1.JWTSigningKey, keyID string) error {
+	var cm corev1.ConfigMap
+	key := client.ObjectKey{Namespace: obj.Namespace, Name: obj.JWKSConfigMapName()}
+	if err := p.kube.Get(ctx, key, &cm); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if err := claim(obj, &cm); err != nil {
+		return err
+	}
+	set, err := readJWKS(&cm, obj.JWKSKey())
+	if err != nil {
+		return err
+	}
+	n := len(set.Keys)
+	set.Keys = slices.DeleteFunc(set.Keys, func(k jwk) bool { return k.Kid == keyID })
+	if len(set.Keys) == n {
+		return nil
+	}
+	if err := writeJWKS(&cm, obj.JWKSKey(), set); err != nil {
+		return err
+	}
+	if err := p.kube.Update(ctx, &cm); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// claim makes obj the controller of cm, unless another object controls it.
+// The ConfigMap is then garbage-collected together with obj.
+func claim(obj *v1alpha1.JWTSigningKey, cm *corev1.ConfigMap) error {
+	owner := metav1.GetControllerOf(cm)
+	if owner == nil {
+		ref := metav1.NewControllerRef(obj, v1alpha1.GroupVersion.WithKind("JWTSigningKey"))
+		cm.OwnerReferences = append(cm.OwnerReferences, *ref)
+		return nil
+	}
+	if owner.UID != obj.UID {
+		return fmt.Errorf("configmap %s is controlled by %s %s", cm.Name, owner.Kind, owner.Name)
+	}
+	return nil
+}
+
+// readJWKS decodes the JWKS stored under key in cm.
+func readJWKS(cm *corev1.ConfigMap, key string) (jwkSet, error) {
+	set := jwkSet{Keys: []jwk{}}
+	raw := cm.Data[key]
+	if raw == "" {
+		return set, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &set); err != nil {
+		return set, fmt.Errorf("decoding JWKS in configmap %s: %w", cm.Name, err)
+	}
+	return set, nil
+}
+
+// writeJWKS encodes set under key in cm.
+func writeJWKS(cm *corev1.ConfigMap, key string, set jwkSet) error {
+	if set.Keys == nil {
+		set.Keys = []jwk{}
+	}
+	raw, err := json.Marshal(set)
+	if err != nil {
+		return fmt.Errorf("encoding JWKS: %w", err)
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[key] = string(raw)
+	return nil
+}
@@ -0,0 +1,189 @@
+// Package internal contains the JWT signing key provider implementation.
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-jwt/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultValidity is the default key validity (90 days).
+const DefaultValidity = 90 * 24 * time.Hour
+
+// rsaKeySize is the size of generated RSA keys in bits.
+const rsaKeySize = 2048
+
+// Provider generates JWT signing keys and publishes their public keys as a
+// JWKS in a ConfigMap. It implements [framework.Provider] for
+// [*v1alpha1.JWTSigningKey].
+//
+// The JWKS holds every key that has not expired yet. A new key is published
+// before the output Secret switches to it, and the previous key stays
+// published until it expires, so that verifiers accept tokens signed by
+// either key during the rotation.
+type Provider struct {
+	kube client.Client
+}
+
+// Option configures a [Provider].
+type Option func(*Provider)
+
+// WithKubeClient sets the Kubernetes client used to publish the JWKS
+// ConfigMaps. It is required.
+func WithKubeClient(c client.Client) Option {
+	return func(p *Provider) { p.kube = c }
+}
+
+// New creates a [Provider] with the given options.
+func New(opts ...Option) *Provider {
+	p := &Provider{}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// NewObject returns a zero-value JWTSigningKey.
+func (p *Provider) NewObject() *v1alpha1.JWTSigningKey {
+	return &v1alpha1.JWTSigningKey{}
+}
+
+// Provision generates a new signing key and adds its public key to the JWKS.
+// The key's RFC 7638 thumbprint is used as key ID.
+func (p *Provider) Provision(
+	ctx context.Context,
+	obj *v1alpha1.JWTSigningKey,
+) (*framework.Result, error) {
+	if p.kube == nil {
+		return nil, errors.New("no Kubernetes client configured")
+	}
+
+	algorithm := obj.Spec.Algorithm
+	if algorithm == "" {
+		algorithm = v1alpha1.AlgorithmES256
+	}
+	key, err := generateKey(algorithm)
+	if err != nil {
+		return nil, fmt.Errorf("generating key: %w", err)
+	}
+	public, err := newJWK(key.Public(), algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("encoding private key: %w", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("encoding public key: %w", err)
+	}
+	jwkJSON, err := json.Marshal(public)
+	if err != nil {
+		return nil, fmt.Errorf("encoding JWK: %w", err)
+	}
+
+	privatePEM := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+	publicPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+	data := map[string]string{
+		v1alpha1.PrivateKeyKey: privatePEM,
+		v1alpha1.PublicKeyKey:  publicPEM,
+		v1alpha1.KeyIDKey:      public.Kid,
+	}
+	templateData := map[string]string{
+		"PrivateKey": privatePEM,
+		"PublicKey":  publicPEM,
+		"KeyID":      public.Kid,
+		"Algorithm":  algorithm,
+		"JWK":        string(jwkJSON),
+	}
+	for key, tmpl := range obj.Spec.Template {
+		rendered, err := renderTemplate(tmpl, templateData)
+		if err != nil {
+			return nil, fmt.Errorf("rendering template %q: %w", key, err)
+		}
+		data[key] = rendered
+	}
+
+	// Publish last, so that a key is not published when anything else fails.
+	if err := p.publish(ctx, obj, public); err != nil {
+		return nil, fmt.Errorf("publishing JWKS: %w", err)
+	}
+
+	validity := DefaultValidity
+	if obj.Spec.Validity != nil {
+		validity = obj.Spec.Validity.Duration
+	}
+	now := time.Now()
+
+	return &framework.Result{
+		StringData:    data,
+		ProvisionedAt: now,
+		ValidUntil:    now.Add(validity),
+		KeyID:         public.Kid,
+	}, nil
+}
+
+// DeleteKey removes the public key with the given key ID from the JWKS, so
+// that tokens signed by it are no longer accepted.
+func (p *Provider) DeleteKey(
+	ctx context.Context,
+	obj *v1alpha1.JWTSigningKey,
+	keyID string,
+) error {
+	if keyID == "" {
+		return nil
+	}
+	if p.kube == nil {
+		return errors.New("no Kubernetes client configured")
+	}
+	if err := p.unpublish(ctx, obj, keyID); err != nil {
+		return fmt.Errorf("removing key %s from JWKS: %w", keyID, err)
+	}
+	return nil
+}
+
+// generateKey generates a private key for the given signing algorithm.
+func generateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case v1alpha1.AlgorithmRS256:
+		return rsa.GenerateKey(rand.Reader, rsaKeySize)
+	case v1alpha1.AlgorithmEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+}
+
+// renderTemplate renders a Go template string with the given data.
+func renderTemplate(tmpl string, data map[string]string) (string, error) {
+	t, err := template.New("").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,208 @@
+package internal
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-jwt/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newSigningKey(algorithm string) *v1alpha1.JWTSigningKey {
+	return &v1alpha1.JWTSigningKey{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "issuer", UID: "uid-1"},
+		Spec: v1alpha1.JWTSigningKeySpec{
+			SecretRef: framework.SecretReference{Name: "out"},
+			Algorithm: algorithm,
+		},
+	}
+}
+
+// readJWKSFrom returns the JWKS published for obj.
+func readJWKSFrom(t *testing.T, kube client.Client, obj *v1alpha1.JWTSigningKey) (*corev1.ConfigMap, jwkSet) {
+	t.Helper()
+	var cm corev1.ConfigMap
+	key := client.ObjectKey{Namespace: obj.Namespace, Name: obj.JWKSConfigMapName()}
+	if err := kube.Get(context.Background(), key, &cm); err != nil {
+		t.Fatalf("getting JWKS configmap: %v", err)
+	}
+	set, err := readJWKS(&cm, obj.JWKSKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &cm, set
+}
+
+func TestProvision(t *testing.T) {
+	for _, algorithm := range []string{"", v1alpha1.AlgorithmES256, v1alpha1.AlgorithmRS256, v1alpha1.AlgorithmEdDSA} {
+		t.Run("algorithm "+algorithm, func(t *testing.T) {
+			kube := fake.NewClientBuilder().Build()
+			obj := newSigningKey(algorithm)
+			obj.Spec.Template = map[string]string{"jwk.json": "{{ .JWK }}", "alg": "{{ .Algorithm }}"}
+
+			result, err := New(WithKubeClient(kube)).Provision(context.Background(), obj)
+			if err != nil {
+				t.Fatalf("Provision: %v", err)
+			}
+
+			block, _ := pem.Decode([]byte(result.StringData[v1alpha1.PrivateKeyKey]))
+			if block == nil {
+				t.Fatal("no PEM private key")
+			}
+			parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				t.Fatalf("parsing private key: %v", err)
+			}
+			signer := parsed.(crypto.Signer)
+
+			want := algorithm
+			if want == "" {
+				want = v1alpha1.AlgorithmES256
+			}
+			switch signer.(type) {
+			case *ecdsa.PrivateKey:
+				if want != v1alpha1.AlgorithmES256 {
+					t.Errorf("got ECDSA key for %s", want)
+				}
+			case *rsa.PrivateKey:
+				if want != v1alpha1.AlgorithmRS256 {
+					t.Errorf("got RSA key for %s", want)
+				}
+			case ed25519.PrivateKey:
+				if want != v1alpha1.AlgorithmEdDSA {
+					t.Errorf("got Ed25519 key for %s", want)
+				}
+			}
+
+			public, err := newJWK(signer.Public(), want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.KeyID != public.Kid || result.StringData[v1alpha1.KeyIDKey] != public.Kid {
+				t.Errorf("key ID %q, want thumbprint %q", result.KeyID, public.Kid)
+			}
+			if result.StringData["alg"] != want {
+				t.Errorf("alg = %q, want %q", result.StringData["alg"], want)
+			}
+			var rendered jwk
+			if err := json.Unmarshal([]byte(result.StringData["jwk.json"]), &rendered); err != nil || rendered != public {
+				t.Errorf("rendered JWK %s, want %+v", result.StringData["jwk.json"], public)
+			}
+
+			cm, set := readJWKSFrom(t, kube, obj)
+			if len(set.Keys) != 1 || set.Keys[0] != public {
+				t.Errorf("JWKS = %+v, want only %+v", set.Keys, public)
+			}
+			if owner := metav1.GetControllerOf(cm); owner == nil || owner.UID != obj.UID || owner.Kind != "JWTSigningKey" {
+				t.Errorf("expected the configmap to be controlled by the JWTSigningKey, got %+v", owner)
+			}
+		})
+	}
+}
+
+func TestRotationKeepsPreviousKeyPublished(t *testing.T) {
+	kube := fake.NewClientBuilder().Build()
+	p := New(WithKubeClient(kube))
+	obj := newSigningKey("")
+	obj.Spec.JWKSConfigMap = &v1alpha1.JWKSConfigMap{Name: "keys", Key: "keys.json"}
+
+	first, err := p.Provision(context.Background(), obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := p.Provision(context.Background(), obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, set := readJWKSFrom(t, kube, obj)
+	if len(set.Keys) != 2 || set.Keys[0].Kid != first.KeyID || set.Keys[1].Kid != second.KeyID {
+		t.Fatalf("expected both keys in the JWKS, got %+v", set.Keys)
+	}
+
+	if err := p.DeleteKey(context.Background(), obj, first.KeyID); err != nil {
+		t.Fatalf("DeleteKey: %v", err)
+	}
+	_, set = readJWKSFrom(t, kube, obj)
+	if len(set.Keys) != 1 || set.Keys[0].Kid != second.KeyID {
+		t.Fatalf("expected only the current key in the JWKS, got %+v", set.Keys)
+	}
+
+	if err := p.DeleteKey(context.Background(), obj, first.KeyID); err != nil {
+		t.Fatalf("deleting an unpublished key: %v", err)
+	}
+}
+
+func TestDeleteKeyWithoutConfigMap(t *testing.T) {
+	p := New(WithKubeClient(fake.NewClientBuilder().Build()))
+	if err := p.DeleteKey(context.Background(), newSigningKey(""), "kid"); err != nil {
+		t.Fatalf("DeleteKey: %v", err)
+	}
+}
+
+func TestProvisionRefusesForeignConfigMap(t *testing.T) {
+	obj := newSigningKey("")
+	foreign := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: obj.Namespace,
+			Name:      obj.JWKSConfigMapName(),
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", UID: "uid-2"}},
+					corev1.SchemeGroupVersion.WithKind("Secret")),
+			},
+		},
+	}
+	kube := fake.NewClientBuilder().WithObjects(foreign).Build()
+
+	_, err := New(WithKubeClient(kube)).Provision(context.Background(), obj)
+	if err == nil || !strings.Contains(err.Error(), "controlled by Secret other") {
+		t.Fatalf("expected error about the foreign controller, got %v", err)
+	}
+}
+
+func TestProvisionWithoutKubeClient(t *testing.T) {
+	if _, err := New().Provision(context.Background(), newSigningKey("")); err == nil {
+		t.Fatal("expected error without Kubernetes client")
+	}
+}
+
+func TestThumbprint(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := newJWK(key.Public(), v1alpha1.AlgorithmES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RFC 7638: the required members in lexicographic order, no whitespace.
+	canonical := fmt.Sprintf(`{"crv":"P-256","kty":"EC","x":"%s","y":"%s"}`, public.X, public.Y)
+	sum := sha256.Sum256([]byte(canonical))
+	if want := base64.RawURLEncoding.EncodeToString(sum[:]); public.Kid != want {
+		t.Errorf("kid = %q, want %q", public.Kid, want)
+	}
+
+	for _, coordinate := range []string{public.X, public.Y} {
+		if b, err := base64.RawURLEncoding.DecodeString(coordinate); err != nil || len(b) != 32 {
+			t.Errorf("coordinate %q is not 32 bytes", coordinate)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+//go:generate godogen
+
+package e2e
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/lukasngl/valet/framework/bddtest"
+	"github.com/lukasngl/valet/provider-jwt/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Suite holds per-scenario state for JWT-provider-specific steps.
+// Common steps are handled by the embedded [bddtest.Suite].
+type Suite struct {
+	*bddtest.Suite[*v1alpha1.JWTSigningKey]
+}
+
+// publishedKeyIDs returns the key IDs in the JWKS stored under key in the
+// ConfigMap name.
+func (s *Suite) publishedKeyIDs(name, key string) ([]string, error) {
+	var cm corev1.ConfigMap
+	if err := s.K8sClient.Get(s.Ctx, client.ObjectKey{Namespace: s.Namespace, Name: name}, &cm); err != nil {
+		return nil, fmt.Errorf("getting configmap: %w", err)
+	}
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal([]byte(cm.Data[key]), &set); err != nil {
+		return nil, fmt.Errorf("decoding JWKS in %s: %w", key, err)
+	}
+	kids := make([]string, 0, len(set.Keys))
+	for _, k := range set.Keys {
+		kids = append(kids, k.Kid)
+	}
+	return kids, nil
+}
+
+//godogen:then ^the Secret "([^"]*)" should hold a JWT signing key$
+func (s *Suite) theSecretShouldHoldAJWTSigningKey(_ context.Context, name string) error {
+	var secret corev1.Secret
+	if err := s.K8sClient.Get(s.Ctx, client.ObjectKey{Namespace: s.Namespace, Name: name}, &secret); err != nil {
+		return fmt.Errorf("getting secret: %w", err)
+	}
+	block, _ := pem.Decode(secret.Data[v1alpha1.PrivateKeyKey])
+	if block == nil {
+		return fmt.Errorf("secret has no PEM private key in %s", v1alpha1.PrivateKeyKey)
+	}
+	if _, err := x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		return fmt.Errorf("parsing private key: %w", err)
+	}
+	block, _ = pem.Decode(secret.Data[v1alpha1.PublicKeyKey])
+	if block == nil {
+		return fmt.Errorf("secret has no PEM public key in %s", v1alpha1.PublicKeyKey)
+	}
+	if _, err := x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+		return fmt.Errorf("parsing public key: %w", err)
+	}
+	if len(secret.Data[v1alpha1.KeyIDKey]) == 0 {
+		return fmt.Errorf("secret has no key ID in %s", v1alpha1.KeyIDKey)
+	}
+	return nil
+}
+
+//godogen:then ^the ConfigMap "([^"]*)" key "([^"]*)" should publish the key in Secret "([^"]*)" within (\d+) seconds$
+func (s *Suite) theConfigMapShouldPublishTheKeyInSecret(
+	_ context.Context,
+	name, key, secretName string,
+	seconds int,
+) error {
+	return bddtest.Eventually(time.Duration(seconds)*time.Second, func() error {
+		var secret corev1.Secret
+		if err := s.K8sClient.Get(s.Ctx, client.ObjectKey{Namespace: s.Namespace, Name: secretName}, &secret); err != nil {
+			return fmt.Errorf("getting secret: %w", err)
+		}
+		kid := string(secret.Data[v1alpha1.KeyIDKey])
+		kids, err := s.publishedKeyIDs(name, key)
+		if err != nil {
+			return err
+		}
+		for _, k := range kids {
+			if k == kid {
+				return nil
+			}
+		}
+		return fmt.Errorf("JWKS %v does not contain key %q", kids, kid)
+	})
+}
+
+//godogen:then ^the ConfigMap "([^"]*)" key "([^"]*)" should publish (\d+) keys? within (\d+) seconds$
+func (s *Suite) theConfigMapShouldPublishKeys(
+	_ context.Context,
+	name, key string,
+	count, seconds int,
+) error {
+	return bddtest.Eventually(time.Duration(seconds)*time.Second, func() error {
+		kids, err := s.publishedKeyIDs(name, key)
+		if err != nil {
+			return err
+		}
+		if len(kids) != count {
+			return fmt.Errorf("JWKS has %d keys, expected %d", len(kids), count)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,99 @@
+package e2e
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/cucumber/godog"
+	"github.com/cucumber/godog/colors"
+	"github.com/lukasngl/valet/framework/bddtest"
+	"github.com/lukasngl/valet/provider-jwt/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-jwt/internal"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var godogOpts = godog.Options{
+	Format:      "pretty",
+	Output:      colors.Colored(os.Stdout),
+	Paths:       []string{"../../features"},
+	Concurrency: 1,
+	Strict:      true,
+}
+
+func init() {
+	godog.BindFlags("godog.", flag.CommandLine, &godogOpts)
+}
+
+var (
+	testEnvCfg bddtest.Env
+	kubeClient client.Client
+)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	if len(flag.Args()) > 0 {
+		godogOpts.Paths = flag.Args()
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	testEnvCfg.Scheme = runtime.NewScheme()
+	_ = corev1.AddToScheme(testEnvCfg.Scheme)
+	_ = v1alpha1.AddToScheme(testEnvCfg.Scheme)
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths: []string{"../../config/crd"},
+		Scheme:            testEnvCfg.Scheme,
+	}
+	env.ControlPlane.GetAPIServer().Configure().
+		Append("advertise-address", "127.0.0.1").
+		Append("bind-address", "127.0.0.1")
+
+	cfg, err := env.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start envtest: %v\n", err)
+		os.Exit(1)
+	}
+	testEnvCfg.Cfg = cfg
+
+	// The provider publishes JWKS ConfigMaps with its own client, like the
+	// operator does with the manager's.
+	kubeClient, err = client.New(cfg, client.Options{Scheme: testEnvCfg.Scheme})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create client: %v\n", err)
+		_ = env.Stop()
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	_ = env.Stop()
+	os.Exit(code)
+}
+
+// TestFeatures runs all scenarios. Keys are generated locally, so no
+// external service is needed.
+func TestFeatures(t *testing.T) {
+	status := godog.TestSuite{
+		Name: "provider-jwt",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New(internal.WithKubeClient(kubeClient))
+			shared := bddtest.New[*v1alpha1.JWTSigningKey](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+			InitializeSteps(sc, &Suite{Suite: shared})
+		},
+		Options: &godogOpts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
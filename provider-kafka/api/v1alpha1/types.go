@@ -0,0 +1,211 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	SchemeBuilder.Register(&KafkaSCRAMUser{}, &KafkaSCRAMUserList{})
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=kafkascram,categories=valet
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// KafkaSCRAMUser provisions and rotates SCRAM-SHA-512 users on a Kafka cluster.
+type KafkaSCRAMUser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	Spec KafkaSCRAMUserSpec `json:"spec,omitzero"`
+	// +optional
+	Status framework.ClientSecretStatus `json:"status,omitzero"`
+}
+
+// KafkaSCRAMUserSpec defines the desired state.
+type KafkaSCRAMUserSpec struct {
+	// SecretRef is the Kubernetes Secret to create/update with the provisioned credentials.
+	// Defaults to a Secret named after the resource.
+	// +optional
+	SecretRef framework.SecretReference `json:"secretRef,omitzero"`
+
+	// Suspend stops the rotation and cleanup of the credentials while set,
+	// e.g. during incidents or migrations. The output Secret keeps its
+	// current credentials.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DeletionPolicy controls what happens when the resource is deleted:
+	// Delete deletes its keys at the provider and the output Secret with it,
+	// Retain keeps both, e.g. to move the resource to another cluster.
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy framework.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// SecretTemplate sets labels, annotations and the type of the output
+	// Secret, e.g. for Reloader, External Secrets or Argo CD to match on.
+	// +optional
+	SecretTemplate framework.SecretTemplate `json:"secretTemplate,omitzero"`
+
+	// Targets are further Secrets the credentials are written to, each
+	// with a subset of the keys, its own template or in another namespace,
+	// so that consumers expecting different shapes share one credential.
+	// +optional
+	Targets framework.OutputTargets `json:"targets,omitempty"`
+
+	// Replication copies the output Secret into all namespaces matching a
+	// label selector that allow it, and keeps the copies in sync.
+	// +optional
+	Replication *framework.Replication `json:"replication,omitempty"`
+
+	// Username is the prefix of the generated SCRAM users. Each rotation
+	// creates a new user named <username>-<suffix>, so the old and new
+	// credentials stay valid side by side until the old user is deleted.
+	// ACLs must therefore be granted to every generated principal, e.g. with
+	// a prefixed resource pattern managed alongside this resource.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Username string `json:"username"`
+
+	// Validity is how long each SCRAM user is used before it is rotated and
+	// deleted. Kafka credentials do not expire on their own, so the operator
+	// enforces this lifetime. Defaults to 90 days (2160h).
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// Rotation configures how credentials are rotated, e.g. how long a
+	// superseded credential stays valid.
+	// +optional
+	Rotation framework.RotationPolicy `json:"rotation,omitzero"`
+
+	// Template maps output secret keys to Go template strings.
+	// Available template variables: .Username, .Password, .PreviousUsername, .BootstrapServers, .Mechanism
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinProperties=1
+	Template map[string]string `json:"template"`
+}
+
+// GetSecretRef returns the reference to the target output Secret, defaulting
+// to a Secret named after the resource.
+func (k *KafkaSCRAMUser) GetSecretRef() framework.SecretReference {
+	return k.Spec.SecretRef.OrDefault(k.Name)
+}
+
+// IsSuspended reports whether the rotation of the credentials is suspended.
+// It implements [framework.Suspender].
+func (k *KafkaSCRAMUser) IsSuspended() bool {
+	return k.Spec.Suspend
+}
+
+// GetDeletionPolicy returns what happens to the keys and the output Secret
+// when the resource is deleted. It implements [framework.DeletionPolicyHolder].
+func (k *KafkaSCRAMUser) GetDeletionPolicy() framework.DeletionPolicy {
+	return k.Spec.DeletionPolicy
+}
+
+// GetSecretTemplate returns the labels, annotations and type of the output
+// Secret. It implements [framework.SecretTemplateHolder].
+func (k *KafkaSCRAMUser) GetSecretTemplate() framework.SecretTemplate {
+	return k.Spec.SecretTemplate
+}
+
+// GetTargets returns the further Secrets the credentials are written to.
+// It implements [framework.TargetHolder].
+func (k *KafkaSCRAMUser) GetTargets() framework.OutputTargets {
+	return k.Spec.Targets
+}
+
+// GetReplication returns the replication of the output Secret, or nil.
+// It implements [framework.ReplicationHolder].
+func (k *KafkaSCRAMUser) GetReplication() *framework.Replication {
+	return k.Spec.Replication
+}
+
+// GetValidity returns the requested validity of the credentials, or zero for
+// the provider's default. It implements [framework.ValidityRequester].
+func (k *KafkaSCRAMUser) GetValidity() time.Duration {
+	if k.Spec.Validity == nil {
+		return 0
+	}
+	return k.Spec.Validity.Duration
+}
+
+// GetRotationPolicy returns the rotation policy of the credentials. It
+// implements [framework.RotationPolicyHolder].
+func (k *KafkaSCRAMUser) GetRotationPolicy() framework.RotationPolicy {
+	return k.Spec.Rotation
+}
+
+// GetStatus returns a pointer to the shared status.
+func (k *KafkaSCRAMUser) GetStatus() *framework.ClientSecretStatus {
+	return &k.Status
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (k *KafkaSCRAMUser) DeepCopyObject() runtime.Object {
+	cp := *k
+	cp.ObjectMeta = *k.DeepCopy()
+	cp.Status = k.Status.DeepCopy()
+	cp.Spec.SecretTemplate = k.Spec.SecretTemplate.DeepCopy()
+	cp.Spec.Targets = k.Spec.Targets.DeepCopy()
+	cp.Spec.Replication = k.Spec.Replication.DeepCopy()
+	if k.Spec.Template != nil {
+		cp.Spec.Template = make(map[string]string, len(k.Spec.Template))
+		for k, v := range k.Spec.Template {
+			cp.Spec.Template[k] = v
+		}
+	}
+	if k.Spec.Validity != nil {
+		v := *k.Spec.Validity
+		cp.Spec.Validity = &v
+	}
+	return &cp
+}
+
+// Validate performs structural validation of the spec.
+func (k *KafkaSCRAMUser) Validate() error {
+	if k.Spec.Username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if k.Spec.Validity != nil && k.Spec.Validity.Duration <= 0 {
+		return fmt.Errorf("validity must be positive")
+	}
+	if len(k.Spec.Template) == 0 {
+		return fmt.Errorf("template must have at least one entry")
+	}
+	for key, tmpl := range k.Spec.Template {
+		if _, err := template.New(key).Parse(tmpl); err != nil {
+			return fmt.Errorf("template %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:object:root=true
+
+// KafkaSCRAMUserList contains a list of KafkaSCRAMUser resources.
+type KafkaSCRAMUserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KafkaSCRAMUser `json:"items"`
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (k *KafkaSCRAMUserList) DeepCopyObject() runtime.Object {
+	cp := *k
+	if k.Items != nil {
+		cp.Items = make([]KafkaSCRAMUser, len(k.Items))
+		for i := range k.Items {
+			cp.Items[i] = *k.Items[i].DeepCopyObject().(*KafkaSCRAMUser)
+		}
+	}
+	return &cp
+}
@@ -0,0 +1,114 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	valid := &KafkaSCRAMUser{
+		Spec: KafkaSCRAMUserSpec{
+			SecretRef: framework.SecretReference{Name: "out"},
+			Username:  "0oa1example",
+			Template:  map[string]string{"KEY": "{{ .Password }}"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*KafkaSCRAMUser)
+		wantErr string
+	}{
+		{name: "valid", modify: func(_ *KafkaSCRAMUser) {}},
+		{
+			name:   "omitted secretRef",
+			modify: func(k *KafkaSCRAMUser) { k.Spec.SecretRef.Name = "" },
+		},
+		{
+			name:    "missing username",
+			modify:  func(k *KafkaSCRAMUser) { k.Spec.Username = "" },
+			wantErr: "username",
+		},
+		{
+			name: "non-positive validity",
+			modify: func(k *KafkaSCRAMUser) {
+				k.Spec.Validity = &metav1.Duration{Duration: -time.Hour}
+			},
+			wantErr: "validity",
+		},
+		{
+			name:    "empty template",
+			modify:  func(k *KafkaSCRAMUser) { k.Spec.Template = nil },
+			wantErr: "template",
+		},
+		{
+			name:    "invalid template syntax",
+			modify:  func(k *KafkaSCRAMUser) { k.Spec.Template = map[string]string{"bad": "{{ .Foo"} },
+			wantErr: "template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := valid.DeepCopyObject().(*KafkaSCRAMUser)
+			tt.modify(obj)
+			err := obj.Validate()
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if got := err.Error(); !strings.Contains(got, tt.wantErr) {
+				t.Fatalf("error %q does not contain %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeepCopyObject(t *testing.T) {
+	validity := metav1.Duration{Duration: 48 * time.Hour}
+	obj := &KafkaSCRAMUser{
+		Spec: KafkaSCRAMUserSpec{
+			SecretRef: framework.SecretReference{Name: "s"},
+			Username:  "a",
+			Template:  map[string]string{"K": "V"},
+			Validity:  &validity,
+		},
+	}
+	obj.Status.Phase = framework.PhaseReady
+
+	cp := obj.DeepCopyObject().(*KafkaSCRAMUser)
+
+	cp.Spec.Template["K"] = "changed"
+	if obj.Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not copy template map")
+	}
+
+	cp.Spec.Validity.Duration = time.Hour
+	if obj.Spec.Validity.Duration != 48*time.Hour {
+		t.Fatal("DeepCopyObject did not copy validity")
+	}
+}
+
+func TestDeepCopyObjectList(t *testing.T) {
+	list := &KafkaSCRAMUserList{
+		Items: []KafkaSCRAMUser{
+			{Spec: KafkaSCRAMUserSpec{Username: "a"}},
+		},
+	}
+
+	cp := list.DeepCopyObject().(*KafkaSCRAMUserList)
+	cp.Items[0].Spec.Username = "changed"
+	if list.Items[0].Spec.Username != "a" {
+		t.Fatal("DeepCopyObject did not deep copy list items")
+	}
+}
@@ -0,0 +1,107 @@
+Feature: Kafka SCRAM User Provisioning
+  As a platform operator
+  I want the Kafka provider to provision and rotate SCRAM users
+  So that applications can authenticate with Kafka
+
+  Background:
+    Given a Kubernetes cluster is running
+    And the CRDs are installed
+    And the operator is running
+
+  Scenario: Provision a SCRAM user successfully
+    When I create a ClientSecret "test-secret" with:
+      """yaml
+      spec:
+        secretRef:
+          name: test-secret
+        username: "$TEST_KAFKA_USERNAME"
+        template:
+          USERNAME: "{{ .Username }}"
+          PASSWORD: "{{ .Password }}"
+      """
+    Then the ClientSecret "test-secret" should have phase "Ready" within 60 seconds
+    And a Secret "test-secret" should exist
+    And the Secret "test-secret" should contain key "USERNAME"
+    And the Secret "test-secret" should contain key "PASSWORD"
+
+  Scenario: Invalid template syntax is rejected
+    When I create a ClientSecret "bad-template" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-template
+        username: "$TEST_KAFKA_USERNAME"
+        template:
+          SECRET: "{{ .Invalid"
+      """
+    Then the ClientSecret "bad-template" should have phase "Failed" within 60 seconds
+    And the ClientSecret "bad-template" status should contain message "template"
+    And the Secret "bad-template" should not exist
+
+  Scenario: Delete KafkaSCRAMUser cleans up resources
+    When I create a ClientSecret "delete-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: delete-test
+        username: "$TEST_KAFKA_USERNAME"
+        template:
+          PASSWORD: "{{ .Password }}"
+      """
+    Then the ClientSecret "delete-test" should have phase "Ready" within 60 seconds
+    And a Secret "delete-test" should exist
+    When I delete the ClientSecret "delete-test"
+    Then the ClientSecret "delete-test" should not exist within 60 seconds
+
+  Scenario: Expired SCRAM users are rotated
+    When I create a ClientSecret "rotation-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rotation-test
+        username: "$TEST_KAFKA_USERNAME"
+        template:
+          PASSWORD: "{{ .Password }}"
+      """
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the ClientSecret "rotation-test" should have 1 active keys
+    When I expire the credentials for ClientSecret "rotation-test"
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+
+  Scenario: Missing username is rejected
+    When I try to create a ClientSecret "no-user" with:
+      """yaml
+      spec:
+        secretRef:
+          name: no-user
+        template:
+          PASSWORD: "{{ .Password }}"
+      """
+    Then the operation should have failed with "username"
+
+  Scenario: Secret is owned by the KafkaSCRAMUser
+    When I create a ClientSecret "ownership-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: ownership-test
+        username: "$TEST_KAFKA_USERNAME"
+        template:
+          PASSWORD: "{{ .Password }}"
+      """
+    Then the ClientSecret "ownership-test" should have phase "Ready" within 60 seconds
+    And the Secret "ownership-test" should be owned by ClientSecret "ownership-test"
+
+  @mock
+  Scenario: Template renders with correct values
+    When I create a ClientSecret "value-check" with:
+      """yaml
+      spec:
+        secretRef:
+          name: value-check
+        username: "$TEST_KAFKA_USERNAME"
+        template:
+          BOOTSTRAP_SERVERS: "{{ .BootstrapServers }}"
+      """
+    Then the ClientSecret "value-check" should have phase "Ready" within 60 seconds
+    And the Secret "value-check" should contain key "BOOTSTRAP_SERVERS" with value "$KAFKA_BOOTSTRAP_SERVERS"
@@ -0,0 +1,95 @@
+{ inputs, ... }:
+{
+  perSystem =
+    { config, pkgs, ... }:
+    let
+      valet = config.valet.lib;
+
+      provider-kafka = valet.mkGoModule {
+        pname = "provider-kafka";
+        subPackages = [ "provider-kafka/cmd" ];
+        postInstall = ''
+          mv $out/bin/cmd $out/bin/provider-kafka
+        '';
+        meta.mainProgram = "provider-kafka";
+      };
+
+      provider-kafka-compressed = pkgs.stdenvNoCC.mkDerivation {
+        inherit (provider-kafka) pname version meta;
+        dontUnpack = true;
+        nativeBuildInputs = [ pkgs.upx ];
+        buildPhase = ''
+          mkdir -p $out/bin
+          upx -o $out/bin/provider-kafka ${provider-kafka}/bin/provider-kafka
+        '';
+      };
+
+      image = valet.mkImage {
+        name = "provider-kafka";
+        entrypoint = "${provider-kafka-compressed}/bin/provider-kafka";
+      };
+      e2e-test-kafka = pkgs.writeShellApplication {
+        name = "e2e-test-kafka";
+        runtimeInputs = [
+          pkgs.go
+          pkgs.gotestsum
+        ];
+        text = ''
+          export GOFLAGS="-mod=vendor"
+          if [ ! -d vendor ]; then
+            ln -sfn ${valet.workspaceVendor} vendor
+          fi
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum \
+            --format "''${GOTESTSUM_FORMAT:-short-verbose}" \
+            -- -run TestE2E -timeout 10m \
+            -coverpkg=github.com/lukasngl/valet/framework/...,./... \
+            -coverprofile="''${COVERAGE_FILE:-coverage-kafka-e2e.txt}" \
+            ./provider-kafka/...
+        '';
+      };
+    in
+    {
+      packages = {
+        inherit provider-kafka provider-kafka-compressed;
+        provider-kafka-image = image;
+      };
+
+      apps.e2e-test-kafka = {
+        type = "app";
+        program = "${e2e-test-kafka}/bin/e2e-test-kafka";
+      };
+
+      checks.provider-kafka-helm = valet.packageChart {
+        name = "provider-kafka";
+        src = "${inputs.self}/provider-kafka/charts/provider-kafka";
+      };
+
+      checks.provider-kafka-lint = valet.withPackageEnv provider-kafka {
+        name = "provider-kafka-lint";
+        extraBuildInputs = [ pkgs.golangci-lint ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          golangci-lint run --timeout 10m ./provider-kafka/...
+        '';
+      };
+
+      checks.provider-kafka-test = valet.withPackageEnv provider-kafka {
+        name = "provider-kafka-test";
+        extraBuildInputs = [
+          pkgs.gotestsum
+          pkgs.etcd
+          pkgs.kubernetes
+        ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum --format short-verbose -- -short -coverpkg=github.com/lukasngl/valet/framework/...,./... -coverprofile=coverage.txt ./provider-kafka/...
+        '';
+        installPhase = ''
+          mkdir -p $out
+          cp coverage.txt $out/
+        '';
+      };
+    };
+}
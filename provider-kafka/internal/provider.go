@@ -0,0 +1,297 @@
+// Package internal contains the Kafka provider implementation.
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-kafka/api/v1alpha1"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// DefaultValidity is the default key rotation period (90 days).
+	DefaultValidity = 90 * 24 * time.Hour
+
+	// scramIterations is the PBKDF2 iteration count for new credentials.
+	// Kafka accepts 4096 to 16384.
+	scramIterations = 8192
+)
+
+// Admin is the subset of the Kafka admin client used by the provider.
+// It is satisfied by [*kadm.Client].
+type Admin interface {
+	AlterUserSCRAMs(
+		ctx context.Context,
+		del []kadm.DeleteSCRAM,
+		upsert []kadm.UpsertSCRAM,
+	) (kadm.AlteredUserSCRAMs, error)
+}
+
+// Provider provisions SCRAM-SHA-512 users using the Kafka Admin API.
+// It implements [framework.Provider] for [*v1alpha1.KafkaSCRAMUser].
+type Provider struct {
+	admin            Admin
+	bootstrapServers []string
+	initOnce         sync.Once
+	initErr          error
+}
+
+// Option configures a [Provider].
+type Option func(*Provider)
+
+// WithAdmin sets a custom admin client, skipping the connection to the
+// cluster. Useful for testing with a fake.
+func WithAdmin(a Admin) Option {
+	return func(p *Provider) { p.admin = a }
+}
+
+// WithBootstrapServers sets the cluster's bootstrap servers. Defaults to the
+// comma-separated KAFKA_BOOTSTRAP_SERVERS environment variable.
+func WithBootstrapServers(servers ...string) Option {
+	return func(p *Provider) { p.bootstrapServers = servers }
+}
+
+// New creates a [Provider] with the given options.
+func New(opts ...Option) *Provider {
+	p := &Provider{}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// NewObject returns a zero-value KafkaSCRAMUser.
+func (p *Provider) NewObject() *v1alpha1.KafkaSCRAMUser {
+	return &v1alpha1.KafkaSCRAMUser{}
+}
+
+// Provision creates a new SCRAM-SHA-512 user with a random password.
+func (p *Provider) Provision(
+	ctx context.Context,
+	obj *v1alpha1.KafkaSCRAMUser,
+) (*framework.Result, error) {
+	if err := p.initClient(); err != nil {
+		return nil, err
+	}
+
+	validity := DefaultValidity
+	if obj.Spec.Validity != nil {
+		validity = obj.Spec.Validity.Duration
+	}
+
+	suffix, err := randomBytes(4)
+	if err != nil {
+		return nil, fmt.Errorf("generating username suffix: %w", err)
+	}
+	secret, err := randomBytes(32)
+	if err != nil {
+		return nil, fmt.Errorf("generating password: %w", err)
+	}
+	username := obj.Spec.Username + "-" + hex.EncodeToString(suffix)
+	password := base64.RawURLEncoding.EncodeToString(secret)
+
+	res, err := p.admin.AlterUserSCRAMs(ctx, nil, []kadm.UpsertSCRAM{{
+		User:       username,
+		Mechanism:  kadm.ScramSha512,
+		Iterations: scramIterations,
+		Password:   password,
+	}})
+	if err == nil {
+		err = alterError(res, username)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating SCRAM user %s: %w", username, err)
+	}
+
+	now := time.Now()
+
+	templateData := map[string]string{
+		"Username":         username,
+		"Password":         password,
+		"PreviousUsername": obj.Status.CurrentIdentifier(),
+		"BootstrapServers": strings.Join(p.bootstrapServers, ","),
+		"Mechanism":        kadm.ScramSha512.String(),
+	}
+
+	data := make(map[string]string, len(obj.Spec.Template))
+	for key, tmpl := range obj.Spec.Template {
+		rendered, err := renderTemplate(tmpl, templateData)
+		if err != nil {
+			return nil, fmt.Errorf("rendering template %q: %w", key, err)
+		}
+		data[key] = rendered
+	}
+
+	return &framework.Result{
+		StringData: data,
+		Fields: map[string]string{
+			framework.FieldUsername: username,
+			framework.FieldPassword: password,
+		},
+		ProvisionedAt: now,
+		ValidUntil:    now.Add(validity),
+		KeyID:         username,
+		Identifier:    username,
+	}, nil
+}
+
+// DeleteKey deletes the SCRAM-SHA-512 credential of a generated user.
+// Returns nil if the credential has already been deleted (idempotent).
+func (p *Provider) DeleteKey(
+	ctx context.Context,
+	_ *v1alpha1.KafkaSCRAMUser,
+	keyID string,
+) error {
+	if keyID == "" {
+		return nil
+	}
+
+	if err := p.initClient(); err != nil {
+		return err
+	}
+
+	res, err := p.admin.AlterUserSCRAMs(ctx, []kadm.DeleteSCRAM{{
+		User:      keyID,
+		Mechanism: kadm.ScramSha512,
+	}}, nil)
+	if err == nil {
+		err = alterError(res, keyID)
+	}
+	if err != nil {
+		// Credential already deleted at the provider — not an error.
+		if errors.Is(err, kerr.ResourceNotFound) {
+			log.FromContext(ctx).Info("key already deleted", "keyId", keyID)
+			return nil
+		}
+		return fmt.Errorf("deleting SCRAM user %s: %w", keyID, err)
+	}
+
+	return nil
+}
+
+// Endpoints returns the bootstrap servers, unless they are not configured
+// yet. The other brokers of the cluster are only known once connected. It
+// implements [framework.EndpointDeclarer].
+func (p *Provider) Endpoints() ([]framework.Endpoint, error) {
+	servers := p.bootstrapServers
+	if len(servers) == 0 {
+		if env := os.Getenv("KAFKA_BOOTSTRAP_SERVERS"); env != "" {
+			servers = strings.Split(env, ",")
+		}
+	}
+
+	endpoints := make([]framework.Endpoint, 0, len(servers))
+	for _, server := range servers {
+		host, port, err := net.SplitHostPort(strings.TrimSpace(server))
+		if err != nil {
+			return nil, fmt.Errorf("invalid bootstrap server %q: %w", server, err)
+		}
+		n, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port of bootstrap server %q", server)
+		}
+		endpoints = append(endpoints, framework.Endpoint{
+			Name: "Kafka broker", Scheme: "kafka", Host: host, Port: n,
+		})
+	}
+	return endpoints, nil
+}
+
+// initClient connects to the cluster on first use, configured from the
+// environment:
+//
+//   - KAFKA_BOOTSTRAP_SERVERS: comma-separated broker addresses (required).
+//   - KAFKA_SASL_USERNAME, KAFKA_SASL_PASSWORD: SCRAM-SHA-512 credentials of
+//     the operator's own admin user (optional).
+//   - KAFKA_TLS: set to "true" to connect with TLS using the system roots.
+//
+// If the admin client was pre-configured via [WithAdmin], no connection is
+// made.
+func (p *Provider) initClient() error {
+	p.initOnce.Do(func() {
+		if len(p.bootstrapServers) == 0 {
+			if servers := os.Getenv("KAFKA_BOOTSTRAP_SERVERS"); servers != "" {
+				p.bootstrapServers = strings.Split(servers, ",")
+			}
+		}
+		if p.admin != nil {
+			return // pre-configured, e.g. for testing
+		}
+		if len(p.bootstrapServers) == 0 {
+			p.initErr = errors.New("KAFKA_BOOTSTRAP_SERVERS must be set")
+			return
+		}
+
+		opts := []kgo.Opt{kgo.SeedBrokers(p.bootstrapServers...)}
+		if user := os.Getenv("KAFKA_SASL_USERNAME"); user != "" {
+			auth := scram.Auth{User: user, Pass: os.Getenv("KAFKA_SASL_PASSWORD")}
+			opts = append(opts, kgo.SASL(auth.AsSha512Mechanism()))
+		}
+		if os.Getenv("KAFKA_TLS") == "true" {
+			opts = append(opts, kgo.DialTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}))
+		}
+
+		client, err := kgo.NewClient(opts...)
+		if err != nil {
+			p.initErr = fmt.Errorf("creating Kafka client: %w", err)
+			return
+		}
+		p.admin = kadm.NewClient(client)
+	})
+	return p.initErr
+}
+
+// alterError returns the per-user error of an AlterUserSCRAMs response.
+func alterError(res kadm.AlteredUserSCRAMs, user string) error {
+	altered, ok := res[user]
+	if !ok {
+		return fmt.Errorf("no result returned for user %s", user)
+	}
+	if altered.Err != nil && altered.ErrMessage != "" {
+		return fmt.Errorf("%w: %s", altered.Err, altered.ErrMessage)
+	}
+	return altered.Err
+}
+
+// randomBytes returns n cryptographically random bytes.
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// renderTemplate renders a Go template string with the given data.
+func renderTemplate(tmpl string, data map[string]string) (string, error) {
+	t, err := template.New("").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,274 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/provider-kafka/api/v1alpha1"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kerr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeAdmin records AlterUserSCRAMs calls and returns canned results.
+type fakeAdmin struct {
+	upserts []kadm.UpsertSCRAM
+	deletes []kadm.DeleteSCRAM
+	err     error // request-level error
+	userErr error // per-user error
+}
+
+func (f *fakeAdmin) AlterUserSCRAMs(
+	_ context.Context,
+	del []kadm.DeleteSCRAM,
+	upsert []kadm.UpsertSCRAM,
+) (kadm.AlteredUserSCRAMs, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.deletes = append(f.deletes, del...)
+	f.upserts = append(f.upserts, upsert...)
+
+	res := kadm.AlteredUserSCRAMs{}
+	for _, d := range del {
+		res[d.User] = kadm.AlteredUserSCRAM{User: d.User, Err: f.userErr}
+	}
+	for _, u := range upsert {
+		res[u.User] = kadm.AlteredUserSCRAM{User: u.User, Err: f.userErr}
+	}
+	return res, nil
+}
+
+func newObj(template map[string]string) *v1alpha1.KafkaSCRAMUser {
+	return &v1alpha1.KafkaSCRAMUser{
+		Spec: v1alpha1.KafkaSCRAMUserSpec{
+			Username: "app",
+			Template: template,
+		},
+	}
+}
+
+func newTestProvider(admin *fakeAdmin) *Provider {
+	return New(WithAdmin(admin), WithBootstrapServers("broker-1:9092", "broker-2:9092"))
+}
+
+func TestProvision(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		admin := &fakeAdmin{}
+		p := newTestProvider(admin)
+
+		result, err := p.Provision(context.Background(), newObj(map[string]string{
+			"USERNAME":  "{{ .Username }}",
+			"PASSWORD":  "{{ .Password }}",
+			"BOOTSTRAP": "{{ .BootstrapServers }}",
+			"MECHANISM": "{{ .Mechanism }}",
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(admin.upserts) != 1 {
+			t.Fatalf("got %d upserts, want 1", len(admin.upserts))
+		}
+		up := admin.upserts[0]
+		if up.Mechanism != kadm.ScramSha512 || up.Iterations != scramIterations {
+			t.Fatalf("got mechanism %v with %d iterations", up.Mechanism, up.Iterations)
+		}
+		if !strings.HasPrefix(up.User, "app-") || result.KeyID != up.User {
+			t.Fatalf("got user %q and keyID %q", up.User, result.KeyID)
+		}
+		if got := result.StringData["USERNAME"]; got != up.User {
+			t.Fatalf("got USERNAME %q, want %q", got, up.User)
+		}
+		if got := result.StringData["PASSWORD"]; got == "" || got != up.Password {
+			t.Fatalf("got PASSWORD %q, want %q", got, up.Password)
+		}
+		if got := result.StringData["BOOTSTRAP"]; got != "broker-1:9092,broker-2:9092" {
+			t.Fatalf("got BOOTSTRAP %q", got)
+		}
+		if got := result.StringData["MECHANISM"]; got != "SCRAM-SHA-512" {
+			t.Fatalf("got MECHANISM %q", got)
+		}
+	})
+
+	t.Run("each rotation creates a new user", func(t *testing.T) {
+		p := newTestProvider(&fakeAdmin{})
+		obj := newObj(map[string]string{"PREVIOUS": "{{ .PreviousUsername }}"})
+		first, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if first.Identifier != first.KeyID || first.StringData["PREVIOUS"] != "" {
+			t.Fatalf("got identifier %q and PREVIOUS %q", first.Identifier, first.StringData["PREVIOUS"])
+		}
+		obj.Status.SetReady(1, first)
+		second, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if first.KeyID == second.KeyID {
+			t.Fatalf("expected distinct users, got %q twice", first.KeyID)
+		}
+		if got := second.StringData["PREVIOUS"]; got != first.KeyID {
+			t.Fatalf("got PREVIOUS %q, want %q", got, first.KeyID)
+		}
+	})
+
+	t.Run("default validity", func(t *testing.T) {
+		p := newTestProvider(&fakeAdmin{})
+		result, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != DefaultValidity {
+			t.Fatalf("got validity %v, want %v", got, DefaultValidity)
+		}
+	})
+
+	t.Run("custom validity", func(t *testing.T) {
+		p := newTestProvider(&fakeAdmin{})
+		obj := newObj(map[string]string{"K": "v"})
+		obj.Spec.Validity = &metav1.Duration{Duration: 48 * time.Hour}
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != 48*time.Hour {
+			t.Fatalf("got validity %v, want %v", got, 48*time.Hour)
+		}
+	})
+
+	t.Run("per-user error propagates", func(t *testing.T) {
+		p := newTestProvider(&fakeAdmin{userErr: kerr.UnacceptableCredential})
+		_, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if !errors.Is(err, kerr.UnacceptableCredential) {
+			t.Fatalf("expected UNACCEPTABLE_CREDENTIAL, got: %v", err)
+		}
+		if !strings.Contains(err.Error(), "creating SCRAM user app-") {
+			t.Fatalf("expected wrapped error, got: %v", err)
+		}
+	})
+
+	t.Run("request error propagates", func(t *testing.T) {
+		p := newTestProvider(&fakeAdmin{err: errors.New("connection refused")})
+		_, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if err == nil || !strings.Contains(err.Error(), "connection refused") {
+			t.Fatalf("expected request error, got: %v", err)
+		}
+	})
+
+	t.Run("bad template", func(t *testing.T) {
+		p := newTestProvider(&fakeAdmin{})
+		_, err := p.Provision(context.Background(), newObj(map[string]string{
+			"BAD": "{{ .Unclosed",
+		}))
+		if err == nil {
+			t.Fatal("expected template error")
+		}
+		if !strings.Contains(err.Error(), "rendering template") {
+			t.Fatalf("expected 'rendering template' error, got: %v", err)
+		}
+	})
+}
+
+func TestDeleteKey(t *testing.T) {
+	t.Run("empty keyID is a no-op", func(t *testing.T) {
+		admin := &fakeAdmin{}
+		p := newTestProvider(admin)
+		if err := p.DeleteKey(context.Background(), newObj(nil), ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(admin.deletes) != 0 {
+			t.Fatalf("expected no deletions, got %v", admin.deletes)
+		}
+	})
+
+	t.Run("happy path", func(t *testing.T) {
+		admin := &fakeAdmin{}
+		p := newTestProvider(admin)
+		if err := p.DeleteKey(context.Background(), newObj(nil), "app-0011aabb"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := kadm.DeleteSCRAM{User: "app-0011aabb", Mechanism: kadm.ScramSha512}
+		if len(admin.deletes) != 1 || admin.deletes[0] != want {
+			t.Fatalf("got deletions %v, want %v", admin.deletes, want)
+		}
+	})
+
+	t.Run("already deleted is idempotent", func(t *testing.T) {
+		p := newTestProvider(&fakeAdmin{userErr: kerr.ResourceNotFound})
+		if err := p.DeleteKey(context.Background(), newObj(nil), "gone-user"); err != nil {
+			t.Fatalf("expected nil for already-deleted user, got: %v", err)
+		}
+	})
+
+	t.Run("other error propagates", func(t *testing.T) {
+		p := newTestProvider(&fakeAdmin{userErr: kerr.ClusterAuthorizationFailed})
+		err := p.DeleteKey(context.Background(), newObj(nil), "app-0011aabb")
+		if !errors.Is(err, kerr.ClusterAuthorizationFailed) {
+			t.Fatalf("expected authorization error, got: %v", err)
+		}
+		if !strings.Contains(err.Error(), "deleting SCRAM user app-0011aabb") {
+			t.Fatalf("expected 'deleting SCRAM user' error, got: %v", err)
+		}
+	})
+}
+
+func TestInitClient(t *testing.T) {
+	t.Run("skips connection when admin is pre-configured", func(t *testing.T) {
+		t.Setenv("KAFKA_BOOTSTRAP_SERVERS", "env-broker:9092")
+
+		admin := &fakeAdmin{}
+		p := New(WithAdmin(admin))
+		if err := p.initClient(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p.admin != admin {
+			t.Fatal("expected pre-configured admin to be kept")
+		}
+		if len(p.bootstrapServers) != 1 || p.bootstrapServers[0] != "env-broker:9092" {
+			t.Fatalf("got bootstrap servers %v", p.bootstrapServers)
+		}
+	})
+
+	t.Run("fails without bootstrap servers", func(t *testing.T) {
+		t.Setenv("KAFKA_BOOTSTRAP_SERVERS", "")
+
+		p := New()
+		if err := p.initClient(); err == nil {
+			t.Fatal("expected error without bootstrap servers")
+		}
+	})
+}
+
+func TestEndpoints(t *testing.T) {
+	t.Run("bootstrap servers from the environment", func(t *testing.T) {
+		t.Setenv("KAFKA_BOOTSTRAP_SERVERS", "broker-0:9092, broker-1:9093")
+
+		endpoints, err := New().Endpoints()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(endpoints) != 2 || endpoints[0].String() != "broker-0:9092" || endpoints[1].String() != "broker-1:9093" {
+			t.Fatalf("got endpoints %v", endpoints)
+		}
+	})
+
+	t.Run("none when not configured", func(t *testing.T) {
+		t.Setenv("KAFKA_BOOTSTRAP_SERVERS", "")
+
+		endpoints, err := New().Endpoints()
+		if err != nil || len(endpoints) != 0 {
+			t.Fatalf("got endpoints %v and error %v", endpoints, err)
+		}
+	})
+
+	t.Run("fails without port", func(t *testing.T) {
+		if _, err := New(WithBootstrapServers("broker-0")).Endpoints(); err == nil {
+			t.Fatal("expected error for bootstrap server without port")
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package e2e
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/cucumber/godog"
+	"github.com/cucumber/godog/colors"
+	"github.com/lukasngl/valet/framework/bddtest"
+	"github.com/lukasngl/valet/provider-kafka/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-kafka/internal"
+	"github.com/twmb/franz-go/pkg/kadm"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var godogOpts = godog.Options{
+	Format:      "pretty",
+	Output:      colors.Colored(os.Stdout),
+	Paths:       []string{"../../features"},
+	Concurrency: 1,
+	Strict:      true,
+}
+
+func init() {
+	godog.BindFlags("godog.", flag.CommandLine, &godogOpts)
+}
+
+var testEnvCfg bddtest.Env
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	if len(flag.Args()) > 0 {
+		godogOpts.Paths = flag.Args()
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	testEnvCfg.Scheme = runtime.NewScheme()
+	_ = corev1.AddToScheme(testEnvCfg.Scheme)
+	_ = v1alpha1.AddToScheme(testEnvCfg.Scheme)
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths: []string{"../../config/crd"},
+		Scheme:            testEnvCfg.Scheme,
+	}
+	env.ControlPlane.GetAPIServer().Configure().
+		Append("advertise-address", "127.0.0.1").
+		Append("bind-address", "127.0.0.1")
+
+	cfg, err := env.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start envtest: %v\n", err)
+		os.Exit(1)
+	}
+	testEnvCfg.Cfg = cfg
+
+	code := m.Run()
+
+	_ = env.Stop()
+	os.Exit(code)
+}
+
+// TestMock runs all scenarios with a canned admin client.
+func TestMock(t *testing.T) {
+	t.Setenv("TEST_KAFKA_USERNAME", "valet-test")
+	t.Setenv("KAFKA_BOOTSTRAP_SERVERS", "kafka.mock:9092")
+
+	opts := godogOpts
+	status := godog.TestSuite{
+		Name: "provider-kafka-mock",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New(internal.WithAdmin(&adminMock{}))
+			shared := bddtest.New[*v1alpha1.KafkaSCRAMUser](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// TestE2E runs non-mock scenarios against a real Kafka cluster.
+func TestE2E(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e tests in short mode")
+	}
+
+	if os.Getenv("TEST_KAFKA_USERNAME") == "" {
+		t.Skip("skipping e2e tests: TEST_KAFKA_USERNAME not set")
+	}
+
+	opts := godogOpts
+	opts.Tags = "~@mock"
+	status := godog.TestSuite{
+		Name: "provider-kafka-e2e",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New()
+			shared := bddtest.New[*v1alpha1.KafkaSCRAMUser](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// adminMock is an [internal.Admin] that accepts every SCRAM credential
+// change.
+type adminMock struct{}
+
+func (m *adminMock) AlterUserSCRAMs(
+	_ context.Context,
+	del []kadm.DeleteSCRAM,
+	upsert []kadm.UpsertSCRAM,
+) (kadm.AlteredUserSCRAMs, error) {
+	res := kadm.AlteredUserSCRAMs{}
+	for _, d := range del {
+		res[d.User] = kadm.AlteredUserSCRAM{User: d.User}
+	}
+	for _, u := range upsert {
+		res[u.User] = kadm.AlteredUserSCRAM{User: u.User}
+	}
+	return res, nil
+}
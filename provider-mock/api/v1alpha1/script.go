@@ -0,0 +1,207 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+)
+
+// Script computes the mock's behavior from CEL expressions evaluated on every
+// call, so that multi-step scenarios can be declared in the manifest. Each
+// expression may use the variables:
+//
+//   - call (int): 1-based number of the current Provision or DeleteKey call
+//     for this resource.
+//   - now (timestamp): the time of the call.
+//   - keyId (string): the key being deleted (DeleteKey only, empty otherwise).
+//
+// Examples:
+//
+//	failProvision: "call <= 2"                  # fail twice, then succeed
+//	validity: "duration('24h') - duration(string(call) + 'h')"
+//	secretData: "{'PASSWORD': 'secret-' + string(call)}"
+//
+// Unset expressions fall back to the corresponding static spec field.
+type Script struct {
+	// FailProvision is a bool expression; Provision fails when it is true.
+	// +optional
+	FailProvision string `json:"failProvision,omitempty"`
+	// FailDeleteKey is a bool expression; DeleteKey fails when it is true.
+	// +optional
+	FailDeleteKey string `json:"failDeleteKey,omitempty"`
+	// Validity is a duration expression, or an int in seconds, for the
+	// lifetime of the provisioned credential.
+	// +optional
+	Validity string `json:"validity,omitempty"`
+	// SecretData is a map(string, string) expression for the secret data.
+	// +optional
+	SecretData string `json:"secretData,omitempty"`
+}
+
+// ScriptInput holds the variables available to [Script] expressions.
+type ScriptInput struct {
+	Call  int
+	Now   time.Time
+	KeyID string
+}
+
+func (in ScriptInput) vars() map[string]any {
+	return map[string]any{
+		"call":  int64(in.Call),
+		"now":   in.Now,
+		"keyId": in.KeyID,
+	}
+}
+
+var scriptEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("call", cel.IntType),
+		cel.Variable("now", cel.TimestampType),
+		cel.Variable("keyId", cel.StringType),
+	)
+})
+
+// Validate checks that every expression compiles and has the expected type.
+func (s *Script) Validate() error {
+	checks := []struct {
+		field string
+		expr  string
+		types []*cel.Type
+	}{
+		{"failProvision", s.FailProvision, []*cel.Type{cel.BoolType}},
+		{"failDeleteKey", s.FailDeleteKey, []*cel.Type{cel.BoolType}},
+		{"validity", s.Validity, []*cel.Type{cel.DurationType, cel.IntType}},
+		{"secretData", s.SecretData, []*cel.Type{cel.MapType(cel.StringType, cel.StringType)}},
+	}
+	for _, c := range checks {
+		if c.expr == "" {
+			continue
+		}
+		if _, err := compile(c.expr, c.types...); err != nil {
+			return fmt.Errorf("script.%s: %w", c.field, err)
+		}
+	}
+	return nil
+}
+
+// EvalFailProvision evaluates FailProvision. It returns false if unset.
+func (s *Script) EvalFailProvision(in ScriptInput) (bool, error) {
+	return evalBool("failProvision", s.FailProvision, in)
+}
+
+// EvalFailDeleteKey evaluates FailDeleteKey. It returns false if unset.
+func (s *Script) EvalFailDeleteKey(in ScriptInput) (bool, error) {
+	return evalBool("failDeleteKey", s.FailDeleteKey, in)
+}
+
+// EvalValidity evaluates Validity. The boolean result reports whether the
+// expression is set.
+func (s *Script) EvalValidity(in ScriptInput) (time.Duration, bool, error) {
+	if s.Validity == "" {
+		return 0, false, nil
+	}
+	val, err := eval(s.Validity, in, cel.DurationType, cel.IntType)
+	if err != nil {
+		return 0, true, fmt.Errorf("script.validity: %w", err)
+	}
+	switch v := val.(type) {
+	case time.Duration:
+		return v, true, nil
+	case int64:
+		return time.Duration(v) * time.Second, true, nil
+	default:
+		return 0, true, fmt.Errorf("script.validity: unexpected result type %T", val)
+	}
+}
+
+// EvalSecretData evaluates SecretData. The boolean result reports whether
+// the expression is set.
+func (s *Script) EvalSecretData(in ScriptInput) (map[string]string, bool, error) {
+	if s.SecretData == "" {
+		return nil, false, nil
+	}
+	mapType := cel.MapType(cel.StringType, cel.StringType)
+	val, err := eval(s.SecretData, in, mapType)
+	if err != nil {
+		return nil, true, fmt.Errorf("script.secretData: %w", err)
+	}
+	data, ok := val.(map[string]string)
+	if !ok {
+		return nil, true, fmt.Errorf("script.secretData: unexpected result type %T", val)
+	}
+	return data, true, nil
+}
+
+func evalBool(field, expr string, in ScriptInput) (bool, error) {
+	if expr == "" {
+		return false, nil
+	}
+	val, err := eval(expr, in, cel.BoolType)
+	if err != nil {
+		return false, fmt.Errorf("script.%s: %w", field, err)
+	}
+	b, ok := val.(bool)
+	if !ok {
+		return false, fmt.Errorf("script.%s: unexpected result type %T", field, val)
+	}
+	return b, nil
+}
+
+// compile parses and type-checks expr, requiring one of the given output
+// types.
+func compile(expr string, want ...*cel.Type) (*cel.Ast, error) {
+	env, err := scriptEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		return nil, iss.Err()
+	}
+	for _, t := range want {
+		if ast.OutputType().IsExactType(t) {
+			return ast, nil
+		}
+	}
+	return nil, fmt.Errorf("expression must evaluate to %v, got %v", want, ast.OutputType())
+}
+
+// eval compiles and evaluates expr, converting the result to a native Go
+// value of the matching type.
+func eval(expr string, in ScriptInput, want ...*cel.Type) (any, error) {
+	ast, err := compile(expr, want...)
+	if err != nil {
+		return nil, err
+	}
+	env, err := scriptEnv()
+	if err != nil {
+		return nil, err
+	}
+	prg, err := env.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+	out, _, err := prg.Eval(in.vars())
+	if err != nil {
+		return nil, err
+	}
+
+	var target reflect.Type
+	switch out.Type() {
+	case types.BoolType:
+		target = reflect.TypeFor[bool]()
+	case types.IntType:
+		target = reflect.TypeFor[int64]()
+	case types.DurationType:
+		target = reflect.TypeFor[time.Duration]()
+	case types.MapType:
+		target = reflect.TypeFor[map[string]string]()
+	default:
+		return out.Value(), nil
+	}
+	return out.ConvertToNative(target)
+}
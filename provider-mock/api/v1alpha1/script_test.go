@@ -0,0 +1,64 @@
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScript_Eval(t *testing.T) {
+	s := &Script{
+		FailProvision: "call <= 2",
+		FailDeleteKey: "keyId == 'stuck'",
+		Validity:      "duration('24h') - duration(string(call) + 'h')",
+		SecretData:    "{'PASSWORD': 'secret-' + string(call)}",
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	for call, wantFail := range map[int]bool{1: true, 2: true, 3: false} {
+		got, err := s.EvalFailProvision(ScriptInput{Call: call, Now: time.Now()})
+		if err != nil {
+			t.Fatalf("call %d: %v", call, err)
+		}
+		if got != wantFail {
+			t.Errorf("call %d: failProvision = %v, want %v", call, got, wantFail)
+		}
+	}
+
+	fail, err := s.EvalFailDeleteKey(ScriptInput{Call: 1, KeyID: "stuck"})
+	if err != nil || !fail {
+		t.Errorf("failDeleteKey = %v, %v; want true", fail, err)
+	}
+
+	validity, ok, err := s.EvalValidity(ScriptInput{Call: 3})
+	if err != nil || !ok || validity != 21*time.Hour {
+		t.Errorf("validity = %v, %v, %v; want 21h", validity, ok, err)
+	}
+
+	data, ok, err := s.EvalSecretData(ScriptInput{Call: 3})
+	if err != nil || !ok || data["PASSWORD"] != "secret-3" {
+		t.Errorf("secretData = %v, %v, %v; want PASSWORD=secret-3", data, ok, err)
+	}
+}
+
+func TestScript_EvalValiditySeconds(t *testing.T) {
+	s := &Script{Validity: "3600 / call"}
+	got, ok, err := s.EvalValidity(ScriptInput{Call: 2})
+	if err != nil || !ok || got != 30*time.Minute {
+		t.Errorf("validity = %v, %v, %v; want 30m", got, ok, err)
+	}
+}
+
+func TestScript_Unset(t *testing.T) {
+	s := &Script{}
+	if fail, err := s.EvalFailProvision(ScriptInput{Call: 1}); fail || err != nil {
+		t.Errorf("failProvision = %v, %v; want false, nil", fail, err)
+	}
+	if _, ok, err := s.EvalValidity(ScriptInput{Call: 1}); ok || err != nil {
+		t.Errorf("validity set = %v, %v; want false, nil", ok, err)
+	}
+	if _, ok, err := s.EvalSecretData(ScriptInput{Call: 1}); ok || err != nil {
+		t.Errorf("secretData set = %v, %v; want false, nil", ok, err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:resource:categories=valet
 // +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
 
@@ -32,21 +33,87 @@ type ClientSecret struct {
 // control of failure behavior in tests.
 type ClientSecretSpec struct {
 	// SecretRef is the reference to the output Kubernetes Secret.
-	SecretRef framework.SecretReference `json:"secretRef"`
+	// Defaults to a Secret named after the resource.
+	// +optional
+	SecretRef framework.SecretReference `json:"secretRef,omitzero"`
+	// Suspend stops the rotation and cleanup of the credentials while set,
+	// e.g. during incidents or migrations. The output Secret keeps its
+	// current credentials.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+	// DeletionPolicy controls what happens when the resource is deleted:
+	// Delete deletes its keys at the provider and the output Secret with it,
+	// Retain keeps both, e.g. to move the resource to another cluster.
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy framework.DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// SecretTemplate sets labels, annotations and the type of the output
+	// Secret, e.g. for Reloader, External Secrets or Argo CD to match on.
+	// +optional
+	SecretTemplate framework.SecretTemplate `json:"secretTemplate,omitzero"`
+	// Targets are further Secrets the credentials are written to, each
+	// with a subset of the keys, its own template or in another namespace,
+	// so that consumers expecting different shapes share one credential.
+	// +optional
+	Targets framework.OutputTargets `json:"targets,omitempty"`
+	// Replication copies the output Secret into all namespaces matching a
+	// label selector that allow it, and keeps the copies in sync.
+	// +optional
+	Replication *framework.Replication `json:"replication,omitempty"`
 	// SecretData is the data to include in the provisioned secret.
 	SecretData map[string]string `json:"secretData,omitempty"`
 	// Validity overrides the default 24h credential lifetime.
 	// +optional
 	Validity *metav1.Duration `json:"validity,omitempty"`
+	// Rotation configures how credentials are rotated, e.g. how long a
+	// superseded credential stays valid.
+	// +optional
+	Rotation framework.RotationPolicy `json:"rotation,omitzero"`
 	// ShouldFailProvision causes Provision to return an error.
 	ShouldFailProvision bool `json:"shouldFailProvision,omitempty"`
 	// ShouldFailDeleteKey causes DeleteKey to return an error.
 	ShouldFailDeleteKey bool `json:"shouldFailDeleteKey,omitempty"`
+	// Script computes behavior per call from CEL expressions. Scripted
+	// secret data and validity override the static fields; scripted
+	// failures add to them. See [Script].
+	// +optional
+	Script *Script `json:"script,omitempty"`
 }
 
-// GetSecretRef returns the reference to the target output Secret.
+// GetSecretRef returns the reference to the target output Secret, defaulting
+// to a Secret named after the resource.
 func (m *ClientSecret) GetSecretRef() framework.SecretReference {
-	return m.Spec.SecretRef
+	return m.Spec.SecretRef.OrDefault(m.Name)
+}
+
+// IsSuspended reports whether the rotation of the credentials is suspended.
+// It implements [framework.Suspender].
+func (m *ClientSecret) IsSuspended() bool {
+	return m.Spec.Suspend
+}
+
+// GetDeletionPolicy returns what happens to the keys and the output Secret
+// when the resource is deleted. It implements [framework.DeletionPolicyHolder].
+func (m *ClientSecret) GetDeletionPolicy() framework.DeletionPolicy {
+	return m.Spec.DeletionPolicy
+}
+
+// GetSecretTemplate returns the labels, annotations and type of the output
+// Secret. It implements [framework.SecretTemplateHolder].
+func (m *ClientSecret) GetSecretTemplate() framework.SecretTemplate {
+	return m.Spec.SecretTemplate
+}
+
+// GetTargets returns the further Secrets the credentials are written to.
+// It implements [framework.TargetHolder].
+func (m *ClientSecret) GetTargets() framework.OutputTargets {
+	return m.Spec.Targets
+}
+
+// GetReplication returns the replication of the output Secret, or nil.
+// It implements [framework.ReplicationHolder].
+func (m *ClientSecret) GetReplication() *framework.Replication {
+	return m.Spec.Replication
 }
 
 // GetStatus returns a pointer to the shared status.
@@ -56,12 +123,14 @@ func (m *ClientSecret) GetStatus() *framework.ClientSecretStatus {
 
 // Validate performs structural validation of the mock spec.
 func (m *ClientSecret) Validate() error {
-	if m.Spec.SecretRef.Name == "" {
-		return fmt.Errorf("secretRef.name is required")
-	}
-	if len(m.Spec.SecretData) == 0 {
+	if len(m.Spec.SecretData) == 0 && (m.Spec.Script == nil || m.Spec.Script.SecretData == "") {
 		return fmt.Errorf("secretData must contain at least one key")
 	}
+	if m.Spec.Script != nil {
+		if err := m.Spec.Script.Validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -73,11 +142,20 @@ func (m *ClientSecret) GetValidity() time.Duration {
 	return 24 * time.Hour
 }
 
+// GetRotationPolicy returns the rotation policy of the credentials. It
+// implements [framework.RotationPolicyHolder].
+func (m *ClientSecret) GetRotationPolicy() framework.RotationPolicy {
+	return m.Spec.Rotation
+}
+
 // DeepCopyObject implements [runtime.Object].
 func (m *ClientSecret) DeepCopyObject() runtime.Object {
 	cp := *m
 	cp.ObjectMeta = *m.DeepCopy()
 	cp.Status = m.Status.DeepCopy()
+	cp.Spec.SecretTemplate = m.Spec.SecretTemplate.DeepCopy()
+	cp.Spec.Targets = m.Spec.Targets.DeepCopy()
+	cp.Spec.Replication = m.Spec.Replication.DeepCopy()
 	if m.Spec.SecretData != nil {
 		cp.Spec.SecretData = make(map[string]string, len(m.Spec.SecretData))
 		for k, v := range m.Spec.SecretData {
@@ -88,6 +166,10 @@ func (m *ClientSecret) DeepCopyObject() runtime.Object {
 		v := *m.Spec.Validity
 		cp.Spec.Validity = &v
 	}
+	if m.Spec.Script != nil {
+		s := *m.Spec.Script
+		cp.Spec.Script = &s
+	}
 	return &cp
 }
 
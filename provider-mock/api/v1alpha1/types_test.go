@@ -23,15 +23,31 @@ func TestValidate(t *testing.T) {
 	}{
 		{name: "valid", modify: func(_ *ClientSecret) {}},
 		{
-			name:    "missing secretRef",
-			modify:  func(c *ClientSecret) { c.Spec.SecretRef.Name = "" },
-			wantErr: "secretRef.name",
+			name:   "omitted secretRef",
+			modify: func(c *ClientSecret) { c.Spec.SecretRef.Name = "" },
 		},
 		{
 			name:    "empty secretData",
 			modify:  func(c *ClientSecret) { c.Spec.SecretData = nil },
 			wantErr: "secretData",
 		},
+		{
+			name: "scripted secretData",
+			modify: func(c *ClientSecret) {
+				c.Spec.SecretData = nil
+				c.Spec.Script = &Script{SecretData: "{'KEY': string(call)}"}
+			},
+		},
+		{
+			name:    "invalid script syntax",
+			modify:  func(c *ClientSecret) { c.Spec.Script = &Script{FailProvision: "call <="} },
+			wantErr: "script.failProvision",
+		},
+		{
+			name:    "script of wrong type",
+			modify:  func(c *ClientSecret) { c.Spec.Script = &Script{Validity: "'1h'"} },
+			wantErr: "script.validity",
+		},
 	}
 
 	for _, tt := range tests {
@@ -2,7 +2,6 @@
 package main
 
 import (
-	"crypto/tls"
 	"flag"
 	"fmt"
 	"os"
@@ -10,36 +9,15 @@ import (
 	"github.com/lukasngl/valet/framework"
 	"github.com/lukasngl/valet/provider-mock/api/v1alpha1"
 	"github.com/lukasngl/valet/provider-mock/mock"
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/runtime"
-	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/healthz"
-	"sigs.k8s.io/controller-runtime/pkg/log/zap"
-	"sigs.k8s.io/controller-runtime/pkg/metrics"
-	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
-	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
-var version = "dev"
-
+// Build metadata, set with -ldflags "-X main.version=..." at build time.
+// See [framework.NewBuildInfo] for the values used if they are not.
 var (
-	metricsAddr = flag.String(
-		"metrics-bind-address",
-		":8080",
-		"Metrics endpoint bind address.",
-	)
-	probeAddr = flag.String(
-		"health-probe-bind-address",
-		":8081",
-		"Health probe bind address.",
-	)
-	enableLeaderElection = flag.Bool("leader-elect", false, "Enable leader election.")
-	enableHTTP2          = flag.Bool(
-		"enable-http2",
-		false,
-		"Enable HTTP/2 for metrics and webhooks.",
-	)
+	version  = "dev"
+	provider = ""
+	revision = ""
 )
 
 func main() {
@@ -52,69 +30,16 @@ func main() {
 // +kubebuilder:rbac:groups=mock.valet.ngl.cx,resources=clientsecrets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=mock.valet.ngl.cx,resources=clientsecrets/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=mock.valet.ngl.cx,resources=clientsecrets/finalizers,verbs=update
-// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch
-// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func run() error {
-	// Logging
-	opts := zap.Options{Development: false}
+	var opts framework.Options
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
-
-	setupLog := ctrl.Log.WithName("setup")
-
-	// Scheme
-	scheme := runtime.NewScheme()
-	utilruntime.Must(corev1.AddToScheme(scheme))
-	utilruntime.Must(v1alpha1.AddToScheme(scheme))
-
-	// TLS
-	tlsOpts := []func(*tls.Config){}
-	if !*enableHTTP2 {
-		tlsOpts = append(tlsOpts, func(c *tls.Config) {
-			c.NextProtos = []string{"http/1.1"}
-		})
-	}
-
-	// Manager
-	mgrOpts := ctrl.Options{
-		Scheme: scheme,
-		Metrics: metricsserver.Options{
-			BindAddress: *metricsAddr,
-			TLSOpts:     tlsOpts,
-		},
-		WebhookServer:          webhook.NewServer(webhook.Options{TLSOpts: tlsOpts}),
-		HealthProbeBindAddress: *probeAddr,
-		LeaderElection:         *enableLeaderElection,
-		LeaderElectionID:       "provider-mock.valet.ngl.cx",
-	}
-
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), mgrOpts)
-	if err != nil {
-		return fmt.Errorf("creating manager: %w", err)
-	}
-
-	// Controller
-	reconciler := &framework.Reconciler[*v1alpha1.ClientSecret]{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Provider: framework.Instrument(mock.NewProvider(), metrics.Registry),
-	}
-
-	if err := reconciler.SetupWithManager(mgr); err != nil {
-		return fmt.Errorf("setting up controller: %w", err)
-	}
-
-	// Health probes
-	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
-		return fmt.Errorf("setting up health check: %w", err)
-	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
-		return fmt.Errorf("setting up ready check: %w", err)
-	}
-
-	setupLog.Info("starting manager", "version", version)
 
-	return mgr.Start(ctrl.SetupSignalHandler())
+	return framework.Run(ctrl.SetupSignalHandler(), &opts, framework.Operator[*v1alpha1.ClientSecret]{
+		Name:        "provider-mock",
+		BuildInfo:   framework.NewBuildInfo(provider, version, revision),
+		AddToScheme: v1alpha1.AddToScheme,
+		Provider:    mock.NewProvider(),
+	})
 }
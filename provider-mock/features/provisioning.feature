@@ -45,6 +45,7 @@ Feature: Secret Provisioning
     And the ClientSecret "failing-secret" status should contain message "mock provider failure"
     And the Secret "failing-secret" should not exist
 
+  @in-process
   Scenario: Delete ClientSecret cleans up resources
     When I create a ClientSecret:
       """yaml
@@ -79,6 +80,7 @@ Feature: Secret Provisioning
     And the ClientSecret "validation-failure" status should contain message "secretData must contain at least one key"
     And the Secret "validation-failure" should not exist
 
+  @in-process
   Scenario: Provider tracks provisioned credentials
     When I create a ClientSecret:
       """yaml
@@ -95,6 +97,7 @@ Feature: Secret Provisioning
     Then the ClientSecret "tracking-test" should have phase "Ready" within 30 seconds
     And the mock provider should have received at least 1 provision calls
 
+  @in-process
   Scenario: Expired credentials trigger rotation
     When I create a ClientSecret:
       """yaml
@@ -114,6 +117,7 @@ Feature: Secret Provisioning
     When I expire the credentials for ClientSecret "rotation-test"
     Then the mock provider should have received at least 2 provision calls within 30 seconds
 
+  @in-process
   Scenario: Spec update triggers re-provisioning
     When I create a ClientSecret "spec-update-test" with:
       """yaml
@@ -136,6 +140,31 @@ Feature: Secret Provisioning
     Then the Secret "spec-update-test" should contain key "KEY" with value "updated-value" within 30 seconds
     And the mock provider should have received at least 2 provision calls
 
+  Scenario: Consumers are rolled out with the updated Secret
+    When I create a ClientSecret "consumer-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: consumer-test
+        secretData:
+          KEY: "original-value"
+      """
+    Then the ClientSecret "consumer-test" should have phase "Ready" within 30 seconds
+    Given a consumer Deployment "consumer" using the Secret "consumer-test"
+    When I update the ClientSecret "consumer-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: consumer-test
+        secretData:
+          KEY: "updated-value"
+      """
+    Then the Secret "consumer-test" should contain key "KEY" with value "updated-value" within 30 seconds
+    When I roll out the consumers of the Secret "consumer-test"
+    Then the consumer Deployment "consumer" should be restarted within 90 seconds
+    And the consumer Deployment "consumer" should use the current Secret data within 30 seconds
+
+  @in-process
   Scenario: DeleteKey failure during deletion blocks finalizer
     When I create a ClientSecret "delete-fail" with:
       """yaml
@@ -152,6 +181,7 @@ Feature: Secret Provisioning
     Then the mock provider should have received at least 1 delete key calls within 30 seconds
     And the ClientSecret "delete-fail" should have phase "Ready" within 5 seconds
 
+  @in-process
   Scenario: DeleteKey failure keeps key in active list
     When I create a ClientSecret:
       """yaml
@@ -169,7 +199,25 @@ Feature: Secret Provisioning
     Then the ClientSecret "delete-key-failure" should have phase "Ready" within 30 seconds
     And the ClientSecret "delete-key-failure" should have 1 active keys
     When I expire the credentials for ClientSecret "delete-key-failure"
+  @in-process
     Then the mock provider should have received at least 1 delete key calls within 30 seconds
     # Key stays in list because deletion failed
     And the ClientSecret "delete-key-failure" should have at least 1 active keys within 30 seconds
     And the mock provider should have received at least 2 provision calls
+
+  Scenario: Scripted behavior fails twice then succeeds
+    When I create a ClientSecret:
+      """yaml
+      apiVersion: mock.valet.ngl.cx/v1alpha1
+      kind: ClientSecret
+      metadata:
+        name: scripted-secret
+      spec:
+        secretRef:
+          name: scripted-secret
+        script:
+          failProvision: "call <= 2"
+          secretData: "{'PASSWORD': 'secret-' + string(call)}"
+      """
+    Then the ClientSecret "scripted-secret" should have phase "Ready" within 30 seconds
+    And the Secret "scripted-secret" should contain key "PASSWORD" with value "secret-3"
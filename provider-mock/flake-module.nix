@@ -24,15 +24,9 @@
         '';
       };
 
-      image = pkgs.dockerTools.streamLayeredImage {
+      image = valet.mkImage {
         name = "provider-mock";
-        tag = valet.version;
-        contents = [ pkgs.dockerTools.caCertificates ];
-        config = {
-          Entrypoint = [ "${provider-mock-compressed}/bin/provider-mock" ];
-          User = "65532:65532";
-          WorkingDir = "/";
-        };
+        entrypoint = "${provider-mock-compressed}/bin/provider-mock";
       };
     in
     {
@@ -4,11 +4,14 @@ package mock
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/lukasngl/valet/framework"
 	"github.com/lukasngl/valet/provider-mock/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // Provider implements [framework.Provider] for [*v1alpha1.ClientSecret].
@@ -19,11 +22,18 @@ type Provider struct {
 	ProvisionCount int
 	// DeleteKeyCalls records the key IDs passed to DeleteKey.
 	DeleteKeyCalls []string
+
+	mu             sync.Mutex
+	provisionCalls map[types.NamespacedName]int
+	deleteKeyCalls map[types.NamespacedName]int
 }
 
 // NewProvider returns a new mock provider with no recorded calls.
 func NewProvider() *Provider {
-	return &Provider{}
+	return &Provider{
+		provisionCalls: map[types.NamespacedName]int{},
+		deleteKeyCalls: map[types.NamespacedName]int{},
+	}
 }
 
 // NewObject returns a zero-value [v1alpha1.ClientSecret].
@@ -33,33 +43,80 @@ func (p *Provider) NewObject() *v1alpha1.ClientSecret {
 
 // Provision returns credentials based on the CRD spec. If
 // ShouldFailProvision is set, it returns an error. The credential
-// lifetime is controlled by the Validity spec field.
+// lifetime is controlled by the Validity spec field. Script expressions
+// override SecretData and Validity, and can fail the call as well.
 func (p *Provider) Provision(
 	_ context.Context,
 	obj *v1alpha1.ClientSecret,
 ) (*framework.Result, error) {
 	p.ProvisionCount++
+	now := time.Now()
+	in := v1alpha1.ScriptInput{Call: p.count(p.provisionCalls, obj), Now: now}
 
-	if obj.Spec.ShouldFailProvision {
-		return nil, errors.New("mock provider failure")
+	fail := obj.Spec.ShouldFailProvision
+	data := obj.Spec.SecretData
+	validity := obj.GetValidity()
+	if script := obj.Spec.Script; script != nil {
+		scripted, err := script.EvalFailProvision(in)
+		if err != nil {
+			return nil, err
+		}
+		fail = fail || scripted
+		if d, ok, err := script.EvalSecretData(in); err != nil {
+			return nil, err
+		} else if ok {
+			data = d
+		}
+		if v, ok, err := script.EvalValidity(in); err != nil {
+			return nil, err
+		} else if ok {
+			validity = v
+		}
+	}
+
+	if fail {
+		return nil, fmt.Errorf("mock provider failure (call %d)", in.Call)
 	}
 
-	now := time.Now()
 	return &framework.Result{
-		StringData:    obj.Spec.SecretData,
+		StringData:    data,
 		ProvisionedAt: now,
-		ValidUntil:    now.Add(obj.GetValidity()),
+		ValidUntil:    now.Add(validity),
 		KeyID:         uuid.New().String(),
 	}, nil
 }
 
 // DeleteKey records the key ID. If ShouldFailDeleteKey is set on the
-// CRD spec, it returns an error.
+// CRD spec, or the script's failDeleteKey expression is true, it returns
+// an error.
 func (p *Provider) DeleteKey(_ context.Context, obj *v1alpha1.ClientSecret, keyID string) error {
 	p.DeleteKeyCalls = append(p.DeleteKeyCalls, keyID)
+	in := v1alpha1.ScriptInput{
+		Call:  p.count(p.deleteKeyCalls, obj),
+		Now:   time.Now(),
+		KeyID: keyID,
+	}
+
+	fail := obj.Spec.ShouldFailDeleteKey
+	if script := obj.Spec.Script; script != nil {
+		scripted, err := script.EvalFailDeleteKey(in)
+		if err != nil {
+			return err
+		}
+		fail = fail || scripted
+	}
 
-	if obj.Spec.ShouldFailDeleteKey {
+	if fail {
 		return errors.New("mock delete key failure")
 	}
 	return nil
 }
+
+// count increments and returns the per-object call counter in calls.
+func (p *Provider) count(calls map[types.NamespacedName]int, obj *v1alpha1.ClientSecret) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := types.NamespacedName{Namespace: obj.Namespace, Name: obj.Name}
+	calls[key]++
+	return calls[key]
+}
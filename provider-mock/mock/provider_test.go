@@ -3,6 +3,7 @@ package mock_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/lukasngl/valet/framework"
 	"github.com/lukasngl/valet/provider-mock/api/v1alpha1"
@@ -91,3 +92,38 @@ func TestInstrumentedDeleteKey(t *testing.T) {
 		}
 	})
 }
+
+func TestScriptedProvision(t *testing.T) {
+	t.Parallel()
+	p := mock.NewProvider()
+
+	obj := &v1alpha1.ClientSecret{}
+	obj.Name = "scripted"
+	obj.Spec.Script = &v1alpha1.Script{
+		FailProvision: "call == 1",
+		Validity:      "duration(string(call) + 'h')",
+		SecretData:    "{'CALL': string(call)}",
+	}
+
+	if _, err := p.Provision(context.Background(), obj); err == nil {
+		t.Fatal("expected first call to fail")
+	}
+
+	result, err := p.Provision(context.Background(), obj)
+	if err != nil {
+		t.Fatalf("unexpected error on second call: %v", err)
+	}
+	if got := result.StringData["CALL"]; got != "2" {
+		t.Fatalf("CALL = %q, want %q", got, "2")
+	}
+	if got := result.ValidUntil.Sub(result.ProvisionedAt); got != 2*time.Hour {
+		t.Fatalf("validity = %v, want 2h", got)
+	}
+
+	// Call counts are tracked per object.
+	other := obj.DeepCopyObject().(*v1alpha1.ClientSecret)
+	other.Name = "other"
+	if _, err := p.Provision(context.Background(), other); err == nil {
+		t.Fatal("expected first call for another object to fail")
+	}
+}
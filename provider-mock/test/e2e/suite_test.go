@@ -1,6 +1,7 @@
 package e2e
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -46,6 +47,10 @@ func TestMain(m *testing.M) {
 	_ = corev1.AddToScheme(testEnvCfg.Scheme)
 	_ = v1alpha1.AddToScheme(testEnvCfg.Scheme)
 
+	if image := os.Getenv("TEST_OPERATOR_IMAGE"); image != "" {
+		os.Exit(runAgainstImage(m, image))
+	}
+
 	env := &envtest.Environment{
 		CRDDirectoryPaths: []string{"../../config/crd"},
 		Scheme:            testEnvCfg.Scheme,
@@ -70,7 +75,28 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
+// runAgainstImage deploys the operator image into the kind cluster named by
+// TEST_KIND_CLUSTER and runs the tests against it.
+func runAgainstImage(m *testing.M, image string) int {
+	uninstall, err := bddtest.DeployImage(context.Background(), &testEnvCfg, bddtest.ImageOptions{
+		Image:   image,
+		Chart:   "../../charts/provider-mock",
+		Cluster: os.Getenv("TEST_KIND_CLUSTER"),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to deploy operator image: %v\n", err)
+		return 1
+	}
+	defer uninstall()
+
+	return m.Run()
+}
+
 func TestFeatures(t *testing.T) {
+	if testEnvCfg.Operator != nil {
+		t.Skip("skipping in-process tests: running against an operator image")
+	}
+
 	status := godog.TestSuite{
 		Name: "provider-mock",
 		ScenarioInitializer: func(sc *godog.ScenarioContext) {
@@ -87,3 +113,28 @@ func TestFeatures(t *testing.T) {
 		t.Fatalf("godog tests failed with status %d", status)
 	}
 }
+
+// TestImage runs the scenarios that do not inspect the in-process provider
+// against the operator deployed from its image. Set TEST_OPERATOR_IMAGE to
+// enable it.
+func TestImage(t *testing.T) {
+	if testEnvCfg.Operator == nil {
+		t.Skip("skipping image tests: TEST_OPERATOR_IMAGE not set")
+	}
+
+	opts := godogOpts
+	opts.Tags = "~@in-process"
+	status := godog.TestSuite{
+		Name: "provider-mock-image",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := mock.NewProvider()
+			shared := bddtest.New[*v1alpha1.ClientSecret](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
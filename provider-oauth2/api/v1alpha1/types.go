@@ -0,0 +1,291 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DefaultCredentialsKey is the key of the initial access token in the Secret
+// referenced by [OAuth2ClientSpec.CredentialsRef] if none is given.
+const DefaultCredentialsKey = "token"
+
+func init() {
+	SchemeBuilder.Register(&OAuth2Client{}, &OAuth2ClientList{})
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=oauth2c,categories=valet
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// OAuth2Client registers and rotates OAuth2 clients at an authorization
+// server supporting dynamic client registration (RFC 7591, RFC 7592).
+type OAuth2Client struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	Spec OAuth2ClientSpec `json:"spec,omitzero"`
+	// +optional
+	Status framework.ClientSecretStatus `json:"status,omitzero"`
+}
+
+// OAuth2ClientSpec defines the desired state.
+type OAuth2ClientSpec struct {
+	// SecretRef is the Kubernetes Secret to create/update with the provisioned credentials.
+	// Defaults to a Secret named after the resource.
+	// +optional
+	SecretRef framework.SecretReference `json:"secretRef,omitzero"`
+
+	// Suspend stops the rotation and cleanup of the credentials while set,
+	// e.g. during incidents or migrations. The output Secret keeps its
+	// current credentials.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DeletionPolicy controls what happens when the resource is deleted:
+	// Delete deletes its keys at the provider and the output Secret with it,
+	// Retain keeps both, e.g. to move the resource to another cluster.
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy framework.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// SecretTemplate sets labels, annotations and the type of the output
+	// Secret, e.g. for Reloader, External Secrets or Argo CD to match on.
+	// +optional
+	SecretTemplate framework.SecretTemplate `json:"secretTemplate,omitzero"`
+
+	// Targets are further Secrets the credentials are written to, each
+	// with a subset of the keys, its own template or in another namespace,
+	// so that consumers expecting different shapes share one credential.
+	// +optional
+	Targets framework.OutputTargets `json:"targets,omitempty"`
+
+	// Replication copies the output Secret into all namespaces matching a
+	// label selector that allow it, and keeps the copies in sync.
+	// +optional
+	Replication *framework.Replication `json:"replication,omitempty"`
+
+	// RegistrationEndpoint is the HTTPS URL of the authorization server's
+	// client registration endpoint, e.g. the registration_endpoint from its
+	// metadata. Each rotation registers a new client there.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^https://`
+	RegistrationEndpoint string `json:"registrationEndpoint"`
+
+	// CredentialsRef is a Secret in the same namespace holding the initial
+	// access token that authorizes registrations. Without it, clients are
+	// registered anonymously, which only servers with open registration allow.
+	// +optional
+	CredentialsRef *CredentialsReference `json:"credentialsRef,omitempty"`
+
+	// ClientName is the human-readable name of the registered clients.
+	// Defaults to <namespace>/<name> of this resource.
+	// +optional
+	ClientName string `json:"clientName,omitempty"`
+
+	// RedirectURIs are the redirection URIs of the registered clients.
+	// +optional
+	RedirectURIs []string `json:"redirectURIs,omitempty"`
+
+	// GrantTypes are the OAuth grant types the clients may use, e.g.
+	// "client_credentials". Defaults to the server's default, usually
+	// "authorization_code".
+	// +optional
+	GrantTypes []string `json:"grantTypes,omitempty"`
+
+	// Scope is the space-separated list of scopes the clients may request.
+	// Defaults to the server's default.
+	// +optional
+	Scope string `json:"scope,omitempty"`
+
+	// TokenEndpointAuthMethod is how the clients authenticate at the token
+	// endpoint. Defaults to client_secret_basic.
+	// +kubebuilder:validation:Enum=client_secret_basic;client_secret_post
+	// +optional
+	TokenEndpointAuthMethod string `json:"tokenEndpointAuthMethod,omitempty"`
+
+	// Validity is how long each client is used before it is rotated and
+	// deleted. Capped to the client secret's expiry if the server sets one.
+	// Defaults to 90 days (2160h).
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// Rotation configures how credentials are rotated, e.g. how long a
+	// superseded credential stays valid.
+	// +optional
+	Rotation framework.RotationPolicy `json:"rotation,omitzero"`
+
+	// Template maps output secret keys to Go template strings.
+	// Available template variables: .ClientID, .ClientSecret
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinProperties=1
+	Template map[string]string `json:"template"`
+}
+
+// CredentialsReference selects a key of a Secret in the namespace of the
+// resource.
+type CredentialsReference struct {
+	// Name of the Secret.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Key of the initial access token in the Secret. Defaults to "token".
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// GetSecretRef returns the reference to the target output Secret, defaulting
+// to a Secret named after the resource.
+func (c *OAuth2Client) GetSecretRef() framework.SecretReference {
+	return c.Spec.SecretRef.OrDefault(c.Name)
+}
+
+// IsSuspended reports whether the rotation of the credentials is suspended.
+// It implements [framework.Suspender].
+func (c *OAuth2Client) IsSuspended() bool {
+	return c.Spec.Suspend
+}
+
+// GetDeletionPolicy returns what happens to the keys and the output Secret
+// when the resource is deleted. It implements [framework.DeletionPolicyHolder].
+func (c *OAuth2Client) GetDeletionPolicy() framework.DeletionPolicy {
+	return c.Spec.DeletionPolicy
+}
+
+// GetSecretTemplate returns the labels, annotations and type of the output
+// Secret. It implements [framework.SecretTemplateHolder].
+func (c *OAuth2Client) GetSecretTemplate() framework.SecretTemplate {
+	return c.Spec.SecretTemplate
+}
+
+// GetTargets returns the further Secrets the credentials are written to.
+// It implements [framework.TargetHolder].
+func (c *OAuth2Client) GetTargets() framework.OutputTargets {
+	return c.Spec.Targets
+}
+
+// GetReplication returns the replication of the output Secret, or nil.
+// It implements [framework.ReplicationHolder].
+func (c *OAuth2Client) GetReplication() *framework.Replication {
+	return c.Spec.Replication
+}
+
+// GetValidity returns the requested validity of the credentials, or zero for
+// the provider's default. It implements [framework.ValidityRequester].
+func (c *OAuth2Client) GetValidity() time.Duration {
+	if c.Spec.Validity == nil {
+		return 0
+	}
+	return c.Spec.Validity.Duration
+}
+
+// GetRotationPolicy returns the rotation policy of the credentials. It
+// implements [framework.RotationPolicyHolder].
+func (c *OAuth2Client) GetRotationPolicy() framework.RotationPolicy {
+	return c.Spec.Rotation
+}
+
+// GetStatus returns a pointer to the shared status.
+func (c *OAuth2Client) GetStatus() *framework.ClientSecretStatus {
+	return &c.Status
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (c *OAuth2Client) DeepCopyObject() runtime.Object {
+	cp := *c
+	cp.ObjectMeta = *c.DeepCopy()
+	cp.Status = c.Status.DeepCopy()
+	cp.Spec.SecretTemplate = c.Spec.SecretTemplate.DeepCopy()
+	cp.Spec.Targets = c.Spec.Targets.DeepCopy()
+	cp.Spec.Replication = c.Spec.Replication.DeepCopy()
+	if c.Spec.CredentialsRef != nil {
+		ref := *c.Spec.CredentialsRef
+		cp.Spec.CredentialsRef = &ref
+	}
+	if c.Spec.RedirectURIs != nil {
+		cp.Spec.RedirectURIs = append([]string(nil), c.Spec.RedirectURIs...)
+	}
+	if c.Spec.GrantTypes != nil {
+		cp.Spec.GrantTypes = append([]string(nil), c.Spec.GrantTypes...)
+	}
+	if c.Spec.Template != nil {
+		cp.Spec.Template = make(map[string]string, len(c.Spec.Template))
+		for k, v := range c.Spec.Template {
+			cp.Spec.Template[k] = v
+		}
+	}
+	if c.Spec.Validity != nil {
+		v := *c.Spec.Validity
+		cp.Spec.Validity = &v
+	}
+	return &cp
+}
+
+// Validate performs structural validation of the spec.
+func (c *OAuth2Client) Validate() error {
+	endpoint, err := url.Parse(c.Spec.RegistrationEndpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		return fmt.Errorf("registrationEndpoint %q must be an https URL", c.Spec.RegistrationEndpoint)
+	}
+	if c.Spec.CredentialsRef != nil && c.Spec.CredentialsRef.Name == "" {
+		return fmt.Errorf("credentialsRef.name is required")
+	}
+	for _, uri := range c.Spec.RedirectURIs {
+		if u, err := url.Parse(uri); err != nil || !u.IsAbs() {
+			return fmt.Errorf("redirect URI %q must be an absolute URL", uri)
+		}
+	}
+	for _, grant := range c.Spec.GrantTypes {
+		if grant == "" || strings.ContainsAny(grant, " \t\n") {
+			return fmt.Errorf("grant type %q must be a single non-empty word", grant)
+		}
+	}
+	switch c.Spec.TokenEndpointAuthMethod {
+	case "", "client_secret_basic", "client_secret_post":
+	default:
+		return fmt.Errorf("tokenEndpointAuthMethod %q must be client_secret_basic or client_secret_post",
+			c.Spec.TokenEndpointAuthMethod)
+	}
+	if c.Spec.Validity != nil && c.Spec.Validity.Duration <= 0 {
+		return fmt.Errorf("validity must be positive")
+	}
+	if len(c.Spec.Template) == 0 {
+		return fmt.Errorf("template must have at least one entry")
+	}
+	for key, tmpl := range c.Spec.Template {
+		if _, err := template.New(key).Parse(tmpl); err != nil {
+			return fmt.Errorf("template %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:object:root=true
+
+// OAuth2ClientList contains a list of OAuth2Client resources.
+type OAuth2ClientList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OAuth2Client `json:"items"`
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (c *OAuth2ClientList) DeepCopyObject() runtime.Object {
+	cp := *c
+	if c.Items != nil {
+		cp.Items = make([]OAuth2Client, len(c.Items))
+		for i := range c.Items {
+			cp.Items[i] = *c.Items[i].DeepCopyObject().(*OAuth2Client)
+		}
+	}
+	return &cp
+}
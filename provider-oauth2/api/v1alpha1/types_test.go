@@ -0,0 +1,159 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	valid := &OAuth2Client{
+		Spec: OAuth2ClientSpec{
+			SecretRef:            framework.SecretReference{Name: "out"},
+			RegistrationEndpoint: "https://idp.example.com/register",
+			CredentialsRef:       &CredentialsReference{Name: "idp"},
+			RedirectURIs:         []string{"https://app.example.com/callback"},
+			GrantTypes:           []string{"authorization_code", "refresh_token"},
+			Template:             map[string]string{"CLIENT_SECRET": "{{ .ClientSecret }}"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*OAuth2Client)
+		wantErr string
+	}{
+		{name: "valid", modify: func(_ *OAuth2Client) {}},
+		{
+			name:   "without credentials",
+			modify: func(c *OAuth2Client) { c.Spec.CredentialsRef = nil },
+		},
+		{
+			name:   "omitted secretRef",
+			modify: func(c *OAuth2Client) { c.Spec.SecretRef.Name = "" },
+		},
+		{
+			name:    "missing registration endpoint",
+			modify:  func(c *OAuth2Client) { c.Spec.RegistrationEndpoint = "" },
+			wantErr: "registrationEndpoint",
+		},
+		{
+			name:    "plain HTTP registration endpoint",
+			modify:  func(c *OAuth2Client) { c.Spec.RegistrationEndpoint = "http://idp.example.com/register" },
+			wantErr: "https URL",
+		},
+		{
+			name:    "missing credentialsRef name",
+			modify:  func(c *OAuth2Client) { c.Spec.CredentialsRef = &CredentialsReference{Key: "token"} },
+			wantErr: "credentialsRef.name",
+		},
+		{
+			name:    "relative redirect URI",
+			modify:  func(c *OAuth2Client) { c.Spec.RedirectURIs = []string{"/callback"} },
+			wantErr: "absolute URL",
+		},
+		{
+			name:    "grant type with whitespace",
+			modify:  func(c *OAuth2Client) { c.Spec.GrantTypes = []string{"authorization_code refresh_token"} },
+			wantErr: "single non-empty word",
+		},
+		{
+			name:    "auth method without secret",
+			modify:  func(c *OAuth2Client) { c.Spec.TokenEndpointAuthMethod = "none" },
+			wantErr: "tokenEndpointAuthMethod",
+		},
+		{
+			name: "non-positive validity",
+			modify: func(c *OAuth2Client) {
+				c.Spec.Validity = &metav1.Duration{Duration: -time.Hour}
+			},
+			wantErr: "validity",
+		},
+		{
+			name:    "empty template",
+			modify:  func(c *OAuth2Client) { c.Spec.Template = nil },
+			wantErr: "template",
+		},
+		{
+			name:    "invalid template syntax",
+			modify:  func(c *OAuth2Client) { c.Spec.Template = map[string]string{"bad": "{{ .Foo"} },
+			wantErr: "template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := valid.DeepCopyObject().(*OAuth2Client)
+			tt.modify(obj)
+			err := obj.Validate()
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if got := err.Error(); !strings.Contains(got, tt.wantErr) {
+				t.Fatalf("error %q does not contain %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeepCopyObject(t *testing.T) {
+	validity := metav1.Duration{Duration: 12 * time.Hour}
+	obj := &OAuth2Client{
+		Spec: OAuth2ClientSpec{
+			SecretRef:      framework.SecretReference{Name: "s"},
+			CredentialsRef: &CredentialsReference{Name: "idp"},
+			RedirectURIs:   []string{"https://app.example.com/callback"},
+			GrantTypes:     []string{"client_credentials"},
+			Template:       map[string]string{"K": "V"},
+			Validity:       &validity,
+		},
+	}
+	obj.Status.Phase = framework.PhaseReady
+
+	cp := obj.DeepCopyObject().(*OAuth2Client)
+
+	cp.Spec.Template["K"] = "changed"
+	if obj.Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not copy template map")
+	}
+
+	cp.Spec.CredentialsRef.Name = "changed"
+	if obj.Spec.CredentialsRef.Name != "idp" {
+		t.Fatal("DeepCopyObject did not copy credentialsRef")
+	}
+
+	cp.Spec.RedirectURIs[0] = "changed"
+	cp.Spec.GrantTypes[0] = "changed"
+	if obj.Spec.RedirectURIs[0] == "changed" || obj.Spec.GrantTypes[0] == "changed" {
+		t.Fatal("DeepCopyObject did not copy redirect URIs and grant types")
+	}
+
+	cp.Spec.Validity.Duration = time.Hour
+	if obj.Spec.Validity.Duration != 12*time.Hour {
+		t.Fatal("DeepCopyObject did not copy validity")
+	}
+}
+
+func TestDeepCopyObjectList(t *testing.T) {
+	list := &OAuth2ClientList{
+		Items: []OAuth2Client{
+			{Spec: OAuth2ClientSpec{GrantTypes: []string{"a"}}},
+		},
+	}
+
+	cp := list.DeepCopyObject().(*OAuth2ClientList)
+	cp.Items[0].Spec.GrantTypes[0] = "changed"
+	if list.Items[0].Spec.GrantTypes[0] != "a" {
+		t.Fatal("DeepCopyObject did not deep copy list items")
+	}
+}
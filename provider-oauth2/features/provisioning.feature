@@ -0,0 +1,143 @@
+Feature: OAuth2 Dynamic Client Registration
+  As a platform operator
+  I want the OAuth2 provider to register and rotate clients at my authorization server
+  So that applications get OAuth2 client credentials without manual registration
+
+  Background:
+    Given a Kubernetes cluster is running
+    And the CRDs are installed
+    And the operator is running
+    And a Secret "idp-credentials" with key "token" set to "$TEST_OAUTH2_INITIAL_ACCESS_TOKEN"
+
+  Scenario: Register a client successfully
+    When I create a ClientSecret "test-secret" with:
+      """yaml
+      spec:
+        secretRef:
+          name: test-secret
+        registrationEndpoint: "$TEST_OAUTH2_REGISTRATION_ENDPOINT"
+        credentialsRef:
+          name: idp-credentials
+        grantTypes: ["client_credentials"]
+        template:
+          CLIENT_ID: "{{ .ClientID }}"
+          CLIENT_SECRET: "{{ .ClientSecret }}"
+      """
+    Then the ClientSecret "test-secret" should have phase "Ready" within 60 seconds
+    And a Secret "test-secret" should exist
+    And the Secret "test-secret" should contain key "CLIENT_ID"
+    And the Secret "test-secret" should contain key "CLIENT_SECRET"
+
+  Scenario: Invalid template syntax is rejected
+    When I create a ClientSecret "bad-template" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-template
+        registrationEndpoint: "$TEST_OAUTH2_REGISTRATION_ENDPOINT"
+        credentialsRef:
+          name: idp-credentials
+        template:
+          SECRET: "{{ .Invalid"
+      """
+    Then the ClientSecret "bad-template" should have phase "Failed" within 60 seconds
+    And the ClientSecret "bad-template" status should contain message "template"
+    And the Secret "bad-template" should not exist
+
+  Scenario: Missing credentials Secret is reported
+    When I create a ClientSecret "no-credentials" with:
+      """yaml
+      spec:
+        secretRef:
+          name: no-credentials
+        registrationEndpoint: "$TEST_OAUTH2_REGISTRATION_ENDPOINT"
+        credentialsRef:
+          name: missing
+        template:
+          CLIENT_ID: "{{ .ClientID }}"
+      """
+    Then the ClientSecret "no-credentials" should have phase "Failed" within 60 seconds
+    And the ClientSecret "no-credentials" status should contain message "credentials secret missing"
+    And the Secret "no-credentials" should not exist
+
+  Scenario: Delete OAuth2Client cleans up resources
+    When I create a ClientSecret "delete-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: delete-test
+        registrationEndpoint: "$TEST_OAUTH2_REGISTRATION_ENDPOINT"
+        credentialsRef:
+          name: idp-credentials
+        grantTypes: ["client_credentials"]
+        template:
+          CLIENT_ID: "{{ .ClientID }}"
+      """
+    Then the ClientSecret "delete-test" should have phase "Ready" within 60 seconds
+    And a Secret "delete-test" should exist
+    When I delete the ClientSecret "delete-test"
+    Then the ClientSecret "delete-test" should not exist within 60 seconds
+
+  Scenario: Expired clients are rotated
+    When I create a ClientSecret "rotation-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rotation-test
+        registrationEndpoint: "$TEST_OAUTH2_REGISTRATION_ENDPOINT"
+        credentialsRef:
+          name: idp-credentials
+        grantTypes: ["client_credentials"]
+        template:
+          CLIENT_ID: "{{ .ClientID }}"
+      """
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the ClientSecret "rotation-test" should have 1 active keys
+    When I expire the credentials for ClientSecret "rotation-test"
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+
+  Scenario: Plain HTTP registration endpoints are rejected
+    When I try to create a ClientSecret "plain-http" with:
+      """yaml
+      spec:
+        secretRef:
+          name: plain-http
+        registrationEndpoint: "http://idp.example.com/register"
+        template:
+          CLIENT_ID: "{{ .ClientID }}"
+      """
+    Then the operation should have failed with "registrationEndpoint"
+
+  Scenario: Secret is owned by the OAuth2Client
+    When I create a ClientSecret "ownership-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: ownership-test
+        registrationEndpoint: "$TEST_OAUTH2_REGISTRATION_ENDPOINT"
+        credentialsRef:
+          name: idp-credentials
+        grantTypes: ["client_credentials"]
+        template:
+          CLIENT_ID: "{{ .ClientID }}"
+      """
+    Then the ClientSecret "ownership-test" should have phase "Ready" within 60 seconds
+    And the Secret "ownership-test" should be owned by ClientSecret "ownership-test"
+
+  @mock
+  Scenario: Registration errors are reported
+    When I create a ClientSecret "bad-scope" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-scope
+        registrationEndpoint: "$TEST_OAUTH2_REGISTRATION_ENDPOINT"
+        credentialsRef:
+          name: idp-credentials
+        scope: "invalid.write"
+        template:
+          CLIENT_ID: "{{ .ClientID }}"
+      """
+    Then the ClientSecret "bad-scope" should have phase "Failed" within 60 seconds
+    And the ClientSecret "bad-scope" status should contain message "invalid_client_metadata"
+    And the Secret "bad-scope" should not exist
@@ -0,0 +1,95 @@
+{ inputs, ... }:
+{
+  perSystem =
+    { config, pkgs, ... }:
+    let
+      valet = config.valet.lib;
+
+      provider-oauth2 = valet.mkGoModule {
+        pname = "provider-oauth2";
+        subPackages = [ "provider-oauth2/cmd" ];
+        postInstall = ''
+          mv $out/bin/cmd $out/bin/provider-oauth2
+        '';
+        meta.mainProgram = "provider-oauth2";
+      };
+
+      provider-oauth2-compressed = pkgs.stdenvNoCC.mkDerivation {
+        inherit (provider-oauth2) pname version meta;
+        dontUnpack = true;
+        nativeBuildInputs = [ pkgs.upx ];
+        buildPhase = ''
+          mkdir -p $out/bin
+          upx -o $out/bin/provider-oauth2 ${provider-oauth2}/bin/provider-oauth2
+        '';
+      };
+
+      image = valet.mkImage {
+        name = "provider-oauth2";
+        entrypoint = "${provider-oauth2-compressed}/bin/provider-oauth2";
+      };
+      e2e-test-oauth2 = pkgs.writeShellApplication {
+        name = "e2e-test-oauth2";
+        runtimeInputs = [
+          pkgs.go
+          pkgs.gotestsum
+        ];
+        text = ''
+          export GOFLAGS="-mod=vendor"
+          if [ ! -d vendor ]; then
+            ln -sfn ${valet.workspaceVendor} vendor
+          fi
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum \
+            --format "''${GOTESTSUM_FORMAT:-short-verbose}" \
+            -- -run TestE2E -timeout 10m \
+            -coverpkg=github.com/lukasngl/valet/framework/...,./... \
+            -coverprofile="''${COVERAGE_FILE:-coverage-oauth2-e2e.txt}" \
+            ./provider-oauth2/...
+        '';
+      };
+    in
+    {
+      packages = {
+        inherit provider-oauth2 provider-oauth2-compressed;
+        provider-oauth2-image = image;
+      };
+
+      apps.e2e-test-oauth2 = {
+        type = "app";
+        program = "${e2e-test-oauth2}/bin/e2e-test-oauth2";
+      };
+
+      checks.provider-oauth2-helm = valet.packageChart {
+        name = "provider-oauth2";
+        src = "${inputs.self}/provider-oauth2/charts/provider-oauth2";
+      };
+
+      checks.provider-oauth2-lint = valet.withPackageEnv provider-oauth2 {
+        name = "provider-oauth2-lint";
+        extraBuildInputs = [ pkgs.golangci-lint ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          golangci-lint run --timeout 10m ./provider-oauth2/...
+        '';
+      };
+
+      checks.provider-oauth2-test = valet.withPackageEnv provider-oauth2 {
+        name = "provider-oauth2-test";
+        extraBuildInputs = [
+          pkgs.gotestsum
+          pkgs.etcd
+          pkgs.kubernetes
+        ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum --format short-verbose -- -short -coverpkg=github.com/lukasngl/valet/framework/...,./... -coverprofile=coverage.txt ./provider-oauth2/...
+        '';
+        installPhase = ''
+          mkdir -p $out
+          cp coverage.txt $out/
+        '';
+      };
+    };
+}
@@ -0,0 +1,449 @@
+// Package internal contains the OAuth2 dynamic client registration provider
+// implementation.
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-oauth2/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultValidity is the default client rotation period (90 days).
+const DefaultValidity = 90 * 24 * time.Hour
+
+// RegistrationsSuffix is appended to the object name to form the name of the
+// Secret that keeps the registration access tokens of its clients.
+const RegistrationsSuffix = "-oauth2-registrations"
+
+// registrationsField is the Secret data key holding the JSON-encoded
+// registrations, keyed by client ID.
+const registrationsField = "registrations.json"
+
+// Provider registers OAuth2 clients with dynamic client registration
+// (RFC 7591) and deletes them with the client configuration endpoint
+// (RFC 7592). It implements [framework.Provider] for [*v1alpha1.OAuth2Client].
+//
+// Each rotation registers a new client, so the old and new client IDs and
+// secrets stay valid side by side until the old client is deleted. Deleting
+// a client needs the registration access token issued with it, which is kept
+// in a Secret named <name>[RegistrationsSuffix] next to the object and owned
+// by it.
+type Provider struct {
+	client *http.Client
+	kube   client.Client
+}
+
+// Option configures a [Provider].
+type Option func(*Provider)
+
+// WithHTTPClient sets a custom HTTP client. Useful for testing with a mock
+// transport.
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Provider) { p.client = c }
+}
+
+// WithKubeClient sets the Kubernetes client used to read initial access
+// tokens and to keep registration access tokens. It is required.
+func WithKubeClient(c client.Client) Option {
+	return func(p *Provider) { p.kube = c }
+}
+
+// New creates a [Provider] with the given options.
+func New(opts ...Option) *Provider {
+	p := &Provider{}
+	for _, o := range opts {
+		o(p)
+	}
+	if p.client == nil {
+		p.client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return p
+}
+
+// NewObject returns a zero-value OAuth2Client.
+func (p *Provider) NewObject() *v1alpha1.OAuth2Client {
+	return &v1alpha1.OAuth2Client{}
+}
+
+// Provision registers a new client with the metadata from the spec. It is
+// used for the spec's validity, or until its secret expires if that is
+// sooner.
+func (p *Provider) Provision(
+	ctx context.Context,
+	obj *v1alpha1.OAuth2Client,
+) (*framework.Result, error) {
+	if p.kube == nil {
+		return nil, errors.New("no Kubernetes client configured")
+	}
+
+	token, err := p.initialAccessToken(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	clientName := obj.Spec.ClientName
+	if clientName == "" {
+		clientName = obj.Namespace + "/" + obj.Name
+	}
+	authMethod := obj.Spec.TokenEndpointAuthMethod
+	if authMethod == "" {
+		authMethod = "client_secret_basic"
+	}
+
+	var reg registrationResponse
+	if err := p.apiRequest(ctx, http.MethodPost, obj.Spec.RegistrationEndpoint, token, clientMetadata{
+		ClientName:              clientName,
+		RedirectURIs:            obj.Spec.RedirectURIs,
+		GrantTypes:              obj.Spec.GrantTypes,
+		Scope:                   obj.Spec.Scope,
+		TokenEndpointAuthMethod: authMethod,
+	}, &reg); err != nil {
+		return nil, fmt.Errorf("registering client at %s: %w", obj.Spec.RegistrationEndpoint, err)
+	}
+	if reg.ClientID == "" || reg.ClientSecret == "" {
+		return nil, errors.New("no client credentials returned from registration endpoint")
+	}
+
+	if reg.RegistrationClientURI != "" && reg.RegistrationAccessToken != "" {
+		if err := p.saveRegistration(ctx, obj, reg.ClientID, reg.registration()); err != nil {
+			// Without its registration access token the client could never
+			// be deleted, so do not hand it out.
+			if delErr := p.deleteClient(ctx, reg.registration()); delErr != nil {
+				log.FromContext(ctx).Error(delErr, "failed to delete unrecorded client", "clientId", reg.ClientID)
+			}
+			return nil, err
+		}
+	} else {
+		log.FromContext(ctx).Info("registration endpoint does not support client management, "+
+			"rotated clients must be deleted manually", "clientId", reg.ClientID)
+	}
+
+	now := time.Now()
+
+	validity := DefaultValidity
+	if obj.Spec.Validity != nil {
+		validity = obj.Spec.Validity.Duration
+	}
+	if reg.ClientSecretExpiresAt > 0 {
+		validity = min(validity, time.Unix(reg.ClientSecretExpiresAt, 0).Sub(now))
+	}
+
+	templateData := map[string]string{
+		"ClientID":     reg.ClientID,
+		"ClientSecret": reg.ClientSecret,
+	}
+
+	data := make(map[string]string, len(obj.Spec.Template))
+	for key, tmpl := range obj.Spec.Template {
+		rendered, err := renderTemplate(tmpl, templateData)
+		if err != nil {
+			return nil, fmt.Errorf("rendering template %q: %w", key, err)
+		}
+		data[key] = rendered
+	}
+
+	return &framework.Result{
+		StringData:    data,
+		ProvisionedAt: now,
+		ValidUntil:    now.Add(validity),
+		KeyID:         reg.ClientID,
+	}, nil
+}
+
+// DeleteKey deletes the client with the given client ID at its client
+// configuration endpoint. Clients without a recorded registration access
+// token cannot be deleted and are skipped.
+func (p *Provider) DeleteKey(
+	ctx context.Context,
+	obj *v1alpha1.OAuth2Client,
+	keyID string,
+) error {
+	if keyID == "" {
+		return nil
+	}
+	if p.kube == nil {
+		return errors.New("no Kubernetes client configured")
+	}
+
+	regs, err := p.loadRegistrations(ctx, obj)
+	if err != nil {
+		return err
+	}
+	reg, ok := regs[keyID]
+	if !ok {
+		log.FromContext(ctx).Info("no registration access token recorded, client must be deleted manually",
+			"clientId", keyID)
+		return nil
+	}
+
+	if err := p.deleteClient(ctx, reg); err != nil {
+		return fmt.Errorf("deleting client %s: %w", keyID, err)
+	}
+	return p.updateRegistrations(ctx, obj, func(regs map[string]registration) {
+		delete(regs, keyID)
+	})
+}
+
+// deleteClient deletes a client at its client configuration endpoint. A
+// client that no longer exists counts as deleted.
+func (p *Provider) deleteClient(ctx context.Context, reg registration) error {
+	err := p.apiRequest(ctx, http.MethodDelete, reg.URI, reg.Token, nil, nil)
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && (apiErr.Code == http.StatusNotFound || apiErr.Code == http.StatusGone) {
+		return nil
+	}
+	return err
+}
+
+// initialAccessToken reads the initial access token from the Secret
+// referenced by the spec, or returns "" if there is none.
+func (p *Provider) initialAccessToken(ctx context.Context, obj *v1alpha1.OAuth2Client) (string, error) {
+	ref := obj.Spec.CredentialsRef
+	if ref == nil {
+		return "", nil
+	}
+	key := ref.Key
+	if key == "" {
+		key = v1alpha1.DefaultCredentialsKey
+	}
+
+	var secret corev1.Secret
+	if err := p.kube.Get(ctx, client.ObjectKey{Namespace: obj.Namespace, Name: ref.Name}, &secret); err != nil {
+		return "", fmt.Errorf("getting credentials secret %s: %w", ref.Name, err)
+	}
+	token := strings.TrimSpace(string(secret.Data[key]))
+	if token == "" {
+		return "", fmt.Errorf("credentials secret %s has no key %q", ref.Name, key)
+	}
+	return token, nil
+}
+
+// saveRegistration records the registration of a client.
+func (p *Provider) saveRegistration(
+	ctx context.Context,
+	obj *v1alpha1.OAuth2Client,
+	clientID string,
+	reg registration,
+) error {
+	return p.updateRegistrations(ctx, obj, func(regs map[string]registration) {
+		regs[clientID] = reg
+	})
+}
+
+// loadRegistrations reads the recorded registrations of obj's clients.
+func (p *Provider) loadRegistrations(
+	ctx context.Context,
+	obj *v1alpha1.OAuth2Client,
+) (map[string]registration, error) {
+	var secret corev1.Secret
+	if err := p.kube.Get(ctx, registrationsKey(obj), &secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return map[string]registration{}, nil
+		}
+		return nil, fmt.Errorf("getting registrations secret: %w", err)
+	}
+	return decodeRegistrations(&secret)
+}
+
+// updateRegistrations applies update to the recorded registrations and
+// writes them back, creating the Secret if needed. Keys of the same object
+// may be deleted concurrently, so conflicting writes are retried.
+func (p *Provider) updateRegistrations(
+	ctx context.Context,
+	obj *v1alpha1.OAuth2Client,
+	update func(map[string]registration),
+) error {
+	key := registrationsKey(obj)
+	retriable := func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}
+
+	err := retry.OnError(retry.DefaultRetry, retriable, func() error {
+		secret := &corev1.Secret{}
+		err := p.kube.Get(ctx, key, secret)
+		exists := err == nil
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		regs, err := decodeRegistrations(secret)
+		if err != nil {
+			return err
+		}
+		update(regs)
+		raw, err := json.Marshal(regs)
+		if err != nil {
+			return fmt.Errorf("encoding registrations: %w", err)
+		}
+
+		if !exists {
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+					// Not a controller reference, so that writes do not
+					// trigger reconciliations of the owner.
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: v1alpha1.GroupVersion.String(),
+						Kind:       "OAuth2Client",
+						Name:       obj.Name,
+						UID:        obj.UID,
+					}},
+				},
+				Type: corev1.SecretTypeOpaque,
+				Data: map[string][]byte{registrationsField: raw},
+			}
+			return p.kube.Create(ctx, secret)
+		}
+		secret.Data = map[string][]byte{registrationsField: raw}
+		return p.kube.Update(ctx, secret)
+	})
+	if err != nil {
+		return fmt.Errorf("writing registrations secret %s: %w", key.Name, err)
+	}
+	return nil
+}
+
+func registrationsKey(obj *v1alpha1.OAuth2Client) client.ObjectKey {
+	return client.ObjectKey{Namespace: obj.Namespace, Name: obj.Name + RegistrationsSuffix}
+}
+
+// decodeRegistrations decodes the registrations recorded in secret.
+func decodeRegistrations(secret *corev1.Secret) (map[string]registration, error) {
+	regs := map[string]registration{}
+	raw, ok := secret.Data[registrationsField]
+	if !ok || len(raw) == 0 {
+		return regs, nil
+	}
+	if err := json.Unmarshal(raw, &regs); err != nil {
+		return nil, fmt.Errorf("decoding registrations secret %s: %w", secret.Name, err)
+	}
+	return regs, nil
+}
+
+// apiRequest sends a request with an optional JSON body and bearer token and
+// decodes the JSON response into out, if given. Error responses are returned
+// as [*apiError].
+func (p *Provider) apiRequest(ctx context.Context, method, url, token string, body, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		apiErr := &apiError{}
+		if json.Unmarshal(respBody, apiErr) != nil || apiErr.Err == "" {
+			apiErr.Description = string(respBody)
+		}
+		apiErr.Code = resp.StatusCode
+		return apiErr
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	return nil
+}
+
+// RFC 7591 and RFC 7592 request and response types.
+
+type clientMetadata struct {
+	ClientName              string   `json:"client_name,omitempty"`
+	RedirectURIs            []string `json:"redirect_uris,omitempty"`
+	GrantTypes              []string `json:"grant_types,omitempty"`
+	Scope                   string   `json:"scope,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method,omitempty"`
+}
+
+type registrationResponse struct {
+	ClientID                string `json:"client_id"`
+	ClientSecret            string `json:"client_secret"`
+	ClientSecretExpiresAt   int64  `json:"client_secret_expires_at"`
+	RegistrationAccessToken string `json:"registration_access_token"`
+	RegistrationClientURI   string `json:"registration_client_uri"`
+}
+
+func (r *registrationResponse) registration() registration {
+	return registration{URI: r.RegistrationClientURI, Token: r.RegistrationAccessToken}
+}
+
+// registration is what is needed to manage a registered client.
+type registration struct {
+	URI   string `json:"registrationClientUri"`
+	Token string `json:"registrationAccessToken"`
+}
+
+// apiError is an OAuth error response.
+type apiError struct {
+	Code        int    `json:"-"`
+	Err         string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *apiError) Error() string {
+	if e.Err != "" {
+		return fmt.Sprintf("registration API error (status %d %s): %s", e.Code, e.Err, e.Description)
+	}
+	return fmt.Sprintf("registration API error (status %d): %s", e.Code, e.Description)
+}
+
+// renderTemplate renders a Go template string with the given data.
+func renderTemplate(tmpl string, data map[string]string) (string, error) {
+	t, err := template.New("").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
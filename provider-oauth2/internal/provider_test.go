@@ -0,0 +1,272 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/provider-oauth2/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeServer is a registration endpoint at /register that issues numbered
+// clients and deletes them at /register/<client_id>.
+type fakeServer struct {
+	mu        sync.Mutex
+	url       string
+	next      int
+	clients   map[string]string // client ID -> registration access token
+	requests  []clientMetadata
+	auth      []string
+	expiresAt int64
+	respond   func(r *http.Request) (int, string)
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.respond != nil {
+		status, resp := f.respond(r)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(resp))
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/register":
+		var md clientMetadata
+		_ = json.NewDecoder(r.Body).Decode(&md)
+		f.requests = append(f.requests, md)
+		f.auth = append(f.auth, r.Header.Get("Authorization"))
+
+		f.next++
+		id := fmt.Sprintf("client-%d", f.next)
+		f.clients[id] = "rat-" + id
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"client_id":                 id,
+			"client_secret":             "secret-" + id,
+			"client_secret_expires_at":  f.expiresAt,
+			"registration_access_token": "rat-" + id,
+			"registration_client_uri":   f.url + "/register/" + id,
+		})
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/register/"):
+		id := strings.TrimPrefix(r.URL.Path, "/register/")
+		token, ok := f.clients[id]
+		if !ok {
+			http.Error(w, "", http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_token"}`))
+			return
+		}
+		delete(f.clients, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unexpected request", http.StatusNotFound)
+	}
+}
+
+// newTestProvider starts the fake server and returns a provider pointed at
+// it, the Kubernetes client it uses and an object registering clients there.
+// The namespace contains a Secret "idp" with the initial access token.
+func newTestProvider(t *testing.T, f *fakeServer) (*Provider, client.Client, *v1alpha1.OAuth2Client) {
+	t.Helper()
+	srv := httptest.NewTLSServer(f)
+	t.Cleanup(srv.Close)
+	f.url = srv.URL
+	f.clients = map[string]string{}
+
+	kube := fake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "idp"},
+		Data:       map[string][]byte{"token": []byte("initial-token\n")},
+	}).Build()
+
+	obj := &v1alpha1.OAuth2Client{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "web", UID: "uid-1"},
+		Spec: v1alpha1.OAuth2ClientSpec{
+			RegistrationEndpoint: srv.URL + "/register",
+			CredentialsRef:       &v1alpha1.CredentialsReference{Name: "idp"},
+			RedirectURIs:         []string{"https://app.example.com/callback"},
+			Template: map[string]string{
+				"CLIENT_ID":     "{{ .ClientID }}",
+				"CLIENT_SECRET": "{{ .ClientSecret }}",
+			},
+		},
+	}
+
+	return New(WithHTTPClient(srv.Client()), WithKubeClient(kube)), kube, obj
+}
+
+func registrations(t *testing.T, kube client.Client) map[string]registration {
+	t.Helper()
+	var secret corev1.Secret
+	if err := kube.Get(context.Background(), client.ObjectKey{
+		Namespace: "apps", Name: "web" + RegistrationsSuffix,
+	}, &secret); err != nil {
+		t.Fatalf("getting registrations secret: %v", err)
+	}
+	regs, err := decodeRegistrations(&secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return regs
+}
+
+func TestProvision(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		f := &fakeServer{}
+		p, kube, obj := newTestProvider(t, f)
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.KeyID != "client-1" {
+			t.Fatalf("got keyID %q, want the client ID", result.KeyID)
+		}
+		if result.StringData["CLIENT_ID"] != "client-1" || result.StringData["CLIENT_SECRET"] != "secret-client-1" {
+			t.Fatalf("got data %v", result.StringData)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != DefaultValidity {
+			t.Fatalf("got validity %v, want %v", got, DefaultValidity)
+		}
+
+		if f.auth[0] != "Bearer initial-token" {
+			t.Fatalf("got authorization %q, want the initial access token", f.auth[0])
+		}
+		md := f.requests[0]
+		if md.ClientName != "apps/web" || md.TokenEndpointAuthMethod != "client_secret_basic" ||
+			len(md.RedirectURIs) != 1 {
+			t.Fatalf("unexpected client metadata: %+v", md)
+		}
+
+		regs := registrations(t, kube)
+		if regs["client-1"].Token != "rat-client-1" {
+			t.Fatalf("registration access token not recorded: %v", regs)
+		}
+	})
+
+	t.Run("validity is capped to the secret expiry", func(t *testing.T) {
+		f := &fakeServer{expiresAt: time.Now().Add(48 * time.Hour).Unix()}
+		p, _, obj := newTestProvider(t, f)
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got > 48*time.Hour || got < 47*time.Hour {
+			t.Fatalf("got validity %v, want about 48h", got)
+		}
+	})
+
+	t.Run("anonymous registration", func(t *testing.T) {
+		f := &fakeServer{}
+		p, _, obj := newTestProvider(t, f)
+		obj.Spec.CredentialsRef = nil
+
+		if _, err := p.Provision(context.Background(), obj); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if f.auth[0] != "" {
+			t.Fatalf("got authorization %q, want none", f.auth[0])
+		}
+	})
+
+	t.Run("missing credentials secret", func(t *testing.T) {
+		p, _, obj := newTestProvider(t, &fakeServer{})
+		obj.Spec.CredentialsRef.Name = "missing"
+
+		_, err := p.Provision(context.Background(), obj)
+		if err == nil || !strings.Contains(err.Error(), "credentials secret missing") {
+			t.Fatalf("expected credentials error, got: %v", err)
+		}
+	})
+
+	t.Run("missing credentials key", func(t *testing.T) {
+		p, _, obj := newTestProvider(t, &fakeServer{})
+		obj.Spec.CredentialsRef.Key = "other"
+
+		_, err := p.Provision(context.Background(), obj)
+		if err == nil || !strings.Contains(err.Error(), `no key "other"`) {
+			t.Fatalf("expected missing key error, got: %v", err)
+		}
+	})
+
+	t.Run("API error propagates", func(t *testing.T) {
+		p, _, obj := newTestProvider(t, &fakeServer{respond: func(*http.Request) (int, string) {
+			return http.StatusBadRequest, `{"error":"invalid_redirect_uri","error_description":"not allowed"}`
+		}})
+		_, err := p.Provision(context.Background(), obj)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "registering client") || !strings.Contains(err.Error(), "invalid_redirect_uri") {
+			t.Fatalf("expected wrapped error, got: %v", err)
+		}
+	})
+
+	t.Run("bad template", func(t *testing.T) {
+		p, _, obj := newTestProvider(t, &fakeServer{})
+		obj.Spec.Template = map[string]string{"BAD": "{{ .Unclosed"}
+
+		_, err := p.Provision(context.Background(), obj)
+		if err == nil || !strings.Contains(err.Error(), "rendering template") {
+			t.Fatalf("expected 'rendering template' error, got: %v", err)
+		}
+	})
+}
+
+func TestDeleteKey(t *testing.T) {
+	f := &fakeServer{}
+	p, kube, obj := newTestProvider(t, f)
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := p.Provision(ctx, obj); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := p.DeleteKey(ctx, obj, "client-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := f.clients["client-1"]; ok {
+		t.Fatal("client-1 was not deleted")
+	}
+	if _, ok := f.clients["client-2"]; !ok {
+		t.Fatal("client-2 was deleted")
+	}
+	regs := registrations(t, kube)
+	if _, ok := regs["client-1"]; ok || len(regs) != 1 {
+		t.Fatalf("got registrations %v, want only client-2", regs)
+	}
+
+	t.Run("unknown client is skipped", func(t *testing.T) {
+		if err := p.DeleteKey(ctx, obj, "client-1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("client deleted at the server", func(t *testing.T) {
+		delete(f.clients, "client-2")
+		if err := p.DeleteKey(ctx, obj, "client-2"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if regs := registrations(t, kube); len(regs) != 0 {
+			t.Fatalf("got registrations %v, want none", regs)
+		}
+	})
+}
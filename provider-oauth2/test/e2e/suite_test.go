@@ -0,0 +1,220 @@
+package e2e
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cucumber/godog"
+	"github.com/cucumber/godog/colors"
+	"github.com/google/uuid"
+	"github.com/lukasngl/valet/framework/bddtest"
+	"github.com/lukasngl/valet/provider-oauth2/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-oauth2/internal"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var godogOpts = godog.Options{
+	Format:      "pretty",
+	Output:      colors.Colored(os.Stdout),
+	Paths:       []string{"../../features"},
+	Concurrency: 1,
+	Strict:      true,
+}
+
+func init() {
+	godog.BindFlags("godog.", flag.CommandLine, &godogOpts)
+}
+
+var (
+	testEnvCfg bddtest.Env
+	kubeClient client.Client
+)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	if len(flag.Args()) > 0 {
+		godogOpts.Paths = flag.Args()
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	testEnvCfg.Scheme = runtime.NewScheme()
+	_ = corev1.AddToScheme(testEnvCfg.Scheme)
+	_ = v1alpha1.AddToScheme(testEnvCfg.Scheme)
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths: []string{"../../config/crd"},
+		Scheme:            testEnvCfg.Scheme,
+	}
+	env.ControlPlane.GetAPIServer().Configure().
+		Append("advertise-address", "127.0.0.1").
+		Append("bind-address", "127.0.0.1")
+
+	cfg, err := env.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start envtest: %v\n", err)
+		os.Exit(1)
+	}
+	testEnvCfg.Cfg = cfg
+
+	// The provider reads credentials Secrets and records registrations with
+	// its own client, like the operator does with the manager's.
+	kubeClient, err = client.New(cfg, client.Options{Scheme: testEnvCfg.Scheme})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create client: %v\n", err)
+		_ = env.Stop()
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	_ = env.Stop()
+	os.Exit(code)
+}
+
+// TestMock runs all scenarios against a canned registration endpoint.
+func TestMock(t *testing.T) {
+	t.Setenv("TEST_OAUTH2_REGISTRATION_ENDPOINT", "https://idp.mock/register")
+	t.Setenv("TEST_OAUTH2_INITIAL_ACCESS_TOKEN", mockInitialAccessToken)
+
+	opts := godogOpts
+	status := godog.TestSuite{
+		Name: "provider-oauth2-mock",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New(
+				internal.WithHTTPClient(&http.Client{Transport: &registrationMock{}}),
+				internal.WithKubeClient(kubeClient),
+			)
+			shared := bddtest.New[*v1alpha1.OAuth2Client](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+			InitializeSteps(sc, &Suite{Suite: shared})
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// TestE2E runs non-mock scenarios against a real authorization server.
+func TestE2E(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e tests in short mode")
+	}
+
+	if os.Getenv("TEST_OAUTH2_REGISTRATION_ENDPOINT") == "" {
+		t.Skip("skipping e2e tests: TEST_OAUTH2_REGISTRATION_ENDPOINT not set")
+	}
+
+	opts := godogOpts
+	opts.Tags = "~@mock"
+	status := godog.TestSuite{
+		Name: "provider-oauth2-e2e",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New(internal.WithKubeClient(kubeClient))
+			shared := bddtest.New[*v1alpha1.OAuth2Client](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+			InitializeSteps(sc, &Suite{Suite: shared})
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// mockInitialAccessToken is the initial access token [registrationMock]
+// accepts.
+const mockInitialAccessToken = "mock-initial-token"
+
+// registrationMock is an [http.RoundTripper] that implements a canned RFC
+// 7591/7592 registration endpoint at /register. Registrations need
+// [mockInitialAccessToken] and fail for scopes starting with "invalid".
+type registrationMock struct {
+	mu      sync.Mutex
+	clients map[string]string // client ID -> registration access token
+}
+
+func (m *registrationMock) RoundTrip(req *http.Request) (*http.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.clients == nil {
+		m.clients = map[string]string{}
+	}
+
+	switch {
+	case req.Method == http.MethodPost && req.URL.Path == "/register":
+		if req.Header.Get("Authorization") != "Bearer "+mockInitialAccessToken {
+			return errorResponse(http.StatusUnauthorized, "invalid_token", "initial access token required")
+		}
+		var md struct {
+			Scope string `json:"scope"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&md); err != nil {
+			return errorResponse(http.StatusBadRequest, "invalid_client_metadata", "parsing request: %v", err)
+		}
+		for _, scope := range strings.Fields(md.Scope) {
+			if strings.HasPrefix(scope, "invalid") {
+				return errorResponse(http.StatusBadRequest, "invalid_client_metadata", "scope %s is not allowed", scope)
+			}
+		}
+
+		id := uuid.New().String()
+		m.clients[id] = "fake-rat-" + uuid.New().String()
+		return jsonResponse(http.StatusCreated, map[string]any{
+			"client_id":                 id,
+			"client_secret":             "fake-secret-" + uuid.New().String(),
+			"client_secret_expires_at":  0,
+			"registration_access_token": m.clients[id],
+			"registration_client_uri":   "https://idp.mock/register/" + id,
+		})
+	case req.Method == http.MethodDelete && strings.HasPrefix(req.URL.Path, "/register/"):
+		id := strings.TrimPrefix(req.URL.Path, "/register/")
+		token, ok := m.clients[id]
+		if !ok {
+			return errorResponse(http.StatusNotFound, "invalid_client_id", "client %s not found", id)
+		}
+		if req.Header.Get("Authorization") != "Bearer "+token {
+			return errorResponse(http.StatusUnauthorized, "invalid_token", "wrong registration access token")
+		}
+		delete(m.clients, id)
+		return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody}, nil
+	default:
+		return errorResponse(http.StatusNotFound, "not_found", "unexpected request: %s %s", req.Method, req.URL.Path)
+	}
+}
+
+// errorResponse returns an OAuth error response.
+func errorResponse(status int, code, format string, args ...any) (*http.Response, error) {
+	return jsonResponse(status, map[string]string{
+		"error":             code,
+		"error_description": fmt.Sprintf(format, args...),
+	})
+}
+
+func jsonResponse(status int, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(string(data))),
+	}, nil
+}
@@ -0,0 +1,208 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	SchemeBuilder.Register(&OktaClientSecret{}, &OktaClientSecretList{})
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=oktacs,categories=valet
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// OktaClientSecret provisions and rotates client secrets for Okta OAuth applications.
+type OktaClientSecret struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	Spec OktaClientSecretSpec `json:"spec,omitzero"`
+	// +optional
+	Status framework.ClientSecretStatus `json:"status,omitzero"`
+}
+
+// OktaClientSecretSpec defines the desired state.
+type OktaClientSecretSpec struct {
+	// SecretRef is the Kubernetes Secret to create/update with the provisioned credentials.
+	// Defaults to a Secret named after the resource.
+	// +optional
+	SecretRef framework.SecretReference `json:"secretRef,omitzero"`
+
+	// Suspend stops the rotation and cleanup of the credentials while set,
+	// e.g. during incidents or migrations. The output Secret keeps its
+	// current credentials.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DeletionPolicy controls what happens when the resource is deleted:
+	// Delete deletes its keys at the provider and the output Secret with it,
+	// Retain keeps both, e.g. to move the resource to another cluster.
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy framework.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// SecretTemplate sets labels, annotations and the type of the output
+	// Secret, e.g. for Reloader, External Secrets or Argo CD to match on.
+	// +optional
+	SecretTemplate framework.SecretTemplate `json:"secretTemplate,omitzero"`
+
+	// Targets are further Secrets the credentials are written to, each
+	// with a subset of the keys, its own template or in another namespace,
+	// so that consumers expecting different shapes share one credential.
+	// +optional
+	Targets framework.OutputTargets `json:"targets,omitempty"`
+
+	// Replication copies the output Secret into all namespaces matching a
+	// label selector that allow it, and keeps the copies in sync.
+	// +optional
+	Replication *framework.Replication `json:"replication,omitempty"`
+
+	// AppID is the ID of the Okta OAuth application to create client
+	// secrets for (the application's client ID).
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	AppID string `json:"appId"`
+
+	// Validity is how long each client secret is used before it is rotated
+	// and deleted. Okta client secrets do not expire on their own, so the
+	// operator enforces this lifetime. Defaults to 90 days (2160h).
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// Rotation configures how credentials are rotated, e.g. how long a
+	// superseded credential stays valid.
+	// +optional
+	Rotation framework.RotationPolicy `json:"rotation,omitzero"`
+
+	// Template maps output secret keys to Go template strings.
+	// Available template variables: .ClientID, .ClientSecret, .OrgURL
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinProperties=1
+	Template map[string]string `json:"template"`
+}
+
+// GetSecretRef returns the reference to the target output Secret, defaulting
+// to a Secret named after the resource.
+func (o *OktaClientSecret) GetSecretRef() framework.SecretReference {
+	return o.Spec.SecretRef.OrDefault(o.Name)
+}
+
+// IsSuspended reports whether the rotation of the credentials is suspended.
+// It implements [framework.Suspender].
+func (o *OktaClientSecret) IsSuspended() bool {
+	return o.Spec.Suspend
+}
+
+// GetDeletionPolicy returns what happens to the keys and the output Secret
+// when the resource is deleted. It implements [framework.DeletionPolicyHolder].
+func (o *OktaClientSecret) GetDeletionPolicy() framework.DeletionPolicy {
+	return o.Spec.DeletionPolicy
+}
+
+// GetSecretTemplate returns the labels, annotations and type of the output
+// Secret. It implements [framework.SecretTemplateHolder].
+func (o *OktaClientSecret) GetSecretTemplate() framework.SecretTemplate {
+	return o.Spec.SecretTemplate
+}
+
+// GetTargets returns the further Secrets the credentials are written to.
+// It implements [framework.TargetHolder].
+func (o *OktaClientSecret) GetTargets() framework.OutputTargets {
+	return o.Spec.Targets
+}
+
+// GetReplication returns the replication of the output Secret, or nil.
+// It implements [framework.ReplicationHolder].
+func (o *OktaClientSecret) GetReplication() *framework.Replication {
+	return o.Spec.Replication
+}
+
+// GetValidity returns the requested validity of the credentials, or zero for
+// the provider's default. It implements [framework.ValidityRequester].
+func (o *OktaClientSecret) GetValidity() time.Duration {
+	if o.Spec.Validity == nil {
+		return 0
+	}
+	return o.Spec.Validity.Duration
+}
+
+// GetRotationPolicy returns the rotation policy of the credentials. It
+// implements [framework.RotationPolicyHolder].
+func (o *OktaClientSecret) GetRotationPolicy() framework.RotationPolicy {
+	return o.Spec.Rotation
+}
+
+// GetStatus returns a pointer to the shared status.
+func (o *OktaClientSecret) GetStatus() *framework.ClientSecretStatus {
+	return &o.Status
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (o *OktaClientSecret) DeepCopyObject() runtime.Object {
+	cp := *o
+	cp.ObjectMeta = *o.DeepCopy()
+	cp.Status = o.Status.DeepCopy()
+	cp.Spec.SecretTemplate = o.Spec.SecretTemplate.DeepCopy()
+	cp.Spec.Targets = o.Spec.Targets.DeepCopy()
+	cp.Spec.Replication = o.Spec.Replication.DeepCopy()
+	if o.Spec.Template != nil {
+		cp.Spec.Template = make(map[string]string, len(o.Spec.Template))
+		for k, v := range o.Spec.Template {
+			cp.Spec.Template[k] = v
+		}
+	}
+	if o.Spec.Validity != nil {
+		v := *o.Spec.Validity
+		cp.Spec.Validity = &v
+	}
+	return &cp
+}
+
+// Validate performs structural validation of the spec.
+func (o *OktaClientSecret) Validate() error {
+	if o.Spec.AppID == "" {
+		return fmt.Errorf("appId is required")
+	}
+	if o.Spec.Validity != nil && o.Spec.Validity.Duration <= 0 {
+		return fmt.Errorf("validity must be positive")
+	}
+	if len(o.Spec.Template) == 0 {
+		return fmt.Errorf("template must have at least one entry")
+	}
+	for key, tmpl := range o.Spec.Template {
+		if _, err := template.New(key).Parse(tmpl); err != nil {
+			return fmt.Errorf("template %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:object:root=true
+
+// OktaClientSecretList contains a list of OktaClientSecret resources.
+type OktaClientSecretList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OktaClientSecret `json:"items"`
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (o *OktaClientSecretList) DeepCopyObject() runtime.Object {
+	cp := *o
+	if o.Items != nil {
+		cp.Items = make([]OktaClientSecret, len(o.Items))
+		for i := range o.Items {
+			cp.Items[i] = *o.Items[i].DeepCopyObject().(*OktaClientSecret)
+		}
+	}
+	return &cp
+}
@@ -0,0 +1,114 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	valid := &OktaClientSecret{
+		Spec: OktaClientSecretSpec{
+			SecretRef: framework.SecretReference{Name: "out"},
+			AppID:     "0oa1example",
+			Template:  map[string]string{"KEY": "{{ .ClientSecret }}"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*OktaClientSecret)
+		wantErr string
+	}{
+		{name: "valid", modify: func(_ *OktaClientSecret) {}},
+		{
+			name:   "omitted secretRef",
+			modify: func(o *OktaClientSecret) { o.Spec.SecretRef.Name = "" },
+		},
+		{
+			name:    "missing appId",
+			modify:  func(o *OktaClientSecret) { o.Spec.AppID = "" },
+			wantErr: "appId",
+		},
+		{
+			name: "non-positive validity",
+			modify: func(o *OktaClientSecret) {
+				o.Spec.Validity = &metav1.Duration{Duration: -time.Hour}
+			},
+			wantErr: "validity",
+		},
+		{
+			name:    "empty template",
+			modify:  func(o *OktaClientSecret) { o.Spec.Template = nil },
+			wantErr: "template",
+		},
+		{
+			name:    "invalid template syntax",
+			modify:  func(o *OktaClientSecret) { o.Spec.Template = map[string]string{"bad": "{{ .Foo"} },
+			wantErr: "template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := valid.DeepCopyObject().(*OktaClientSecret)
+			tt.modify(obj)
+			err := obj.Validate()
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if got := err.Error(); !strings.Contains(got, tt.wantErr) {
+				t.Fatalf("error %q does not contain %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeepCopyObject(t *testing.T) {
+	validity := metav1.Duration{Duration: 48 * time.Hour}
+	obj := &OktaClientSecret{
+		Spec: OktaClientSecretSpec{
+			SecretRef: framework.SecretReference{Name: "s"},
+			AppID:     "a",
+			Template:  map[string]string{"K": "V"},
+			Validity:  &validity,
+		},
+	}
+	obj.Status.Phase = framework.PhaseReady
+
+	cp := obj.DeepCopyObject().(*OktaClientSecret)
+
+	cp.Spec.Template["K"] = "changed"
+	if obj.Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not copy template map")
+	}
+
+	cp.Spec.Validity.Duration = time.Hour
+	if obj.Spec.Validity.Duration != 48*time.Hour {
+		t.Fatal("DeepCopyObject did not copy validity")
+	}
+}
+
+func TestDeepCopyObjectList(t *testing.T) {
+	list := &OktaClientSecretList{
+		Items: []OktaClientSecret{
+			{Spec: OktaClientSecretSpec{AppID: "a"}},
+		},
+	}
+
+	cp := list.DeepCopyObject().(*OktaClientSecretList)
+	cp.Items[0].Spec.AppID = "changed"
+	if list.Items[0].Spec.AppID != "a" {
+		t.Fatal("DeepCopyObject did not deep copy list items")
+	}
+}
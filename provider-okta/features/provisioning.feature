@@ -0,0 +1,108 @@
+Feature: Okta Client Secret Provisioning
+  As a platform operator
+  I want the Okta provider to provision and rotate OAuth app client secrets
+  So that applications can authenticate with Okta
+
+  Background:
+    Given a Kubernetes cluster is running
+    And the CRDs are installed
+    And the operator is running
+
+  Scenario: Provision a client secret successfully
+    When I create a ClientSecret "test-secret" with:
+      """yaml
+      spec:
+        secretRef:
+          name: test-secret
+        appId: "$TEST_OKTA_APP_ID"
+        template:
+          CLIENT_ID: "{{ .ClientID }}"
+          CLIENT_SECRET: "{{ .ClientSecret }}"
+      """
+    Then the ClientSecret "test-secret" should have phase "Ready" within 60 seconds
+    And a Secret "test-secret" should exist
+    And the Secret "test-secret" should contain key "CLIENT_ID"
+    And the Secret "test-secret" should contain key "CLIENT_SECRET"
+
+  Scenario: Invalid template syntax is rejected
+    When I create a ClientSecret "bad-template" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-template
+        appId: "$TEST_OKTA_APP_ID"
+        template:
+          SECRET: "{{ .Invalid"
+      """
+    Then the ClientSecret "bad-template" should have phase "Failed" within 60 seconds
+    And the ClientSecret "bad-template" status should contain message "template"
+    And the Secret "bad-template" should not exist
+
+  Scenario: Delete OktaClientSecret cleans up resources
+    When I create a ClientSecret "delete-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: delete-test
+        appId: "$TEST_OKTA_APP_ID"
+        template:
+          CLIENT_SECRET: "{{ .ClientSecret }}"
+      """
+    Then the ClientSecret "delete-test" should have phase "Ready" within 60 seconds
+    And a Secret "delete-test" should exist
+    When I delete the ClientSecret "delete-test"
+    Then the ClientSecret "delete-test" should not exist within 60 seconds
+
+  Scenario: Expired client secrets are rotated
+    When I create a ClientSecret "rotation-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rotation-test
+        appId: "$TEST_OKTA_APP_ID"
+        template:
+          CLIENT_SECRET: "{{ .ClientSecret }}"
+      """
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the ClientSecret "rotation-test" should have 1 active keys
+    When I expire the credentials for ClientSecret "rotation-test"
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+
+  Scenario: Missing appId is rejected
+    When I try to create a ClientSecret "no-app" with:
+      """yaml
+      spec:
+        secretRef:
+          name: no-app
+        template:
+          CLIENT_SECRET: "{{ .ClientSecret }}"
+      """
+    Then the operation should have failed with "appId"
+
+  Scenario: Secret is owned by the OktaClientSecret
+    When I create a ClientSecret "ownership-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: ownership-test
+        appId: "$TEST_OKTA_APP_ID"
+        template:
+          CLIENT_SECRET: "{{ .ClientSecret }}"
+      """
+    Then the ClientSecret "ownership-test" should have phase "Ready" within 60 seconds
+    And the Secret "ownership-test" should be owned by ClientSecret "ownership-test"
+
+  @mock
+  Scenario: Template renders with correct values
+    When I create a ClientSecret "value-check" with:
+      """yaml
+      spec:
+        secretRef:
+          name: value-check
+        appId: "$TEST_OKTA_APP_ID"
+        template:
+          CLIENT_ID: "{{ .ClientID }}"
+          CLIENT_SECRET: "{{ .ClientSecret }}"
+      """
+    Then the ClientSecret "value-check" should have phase "Ready" within 60 seconds
+    And the Secret "value-check" should contain key "CLIENT_ID" with value "$TEST_OKTA_APP_ID"
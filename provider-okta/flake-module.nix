@@ -0,0 +1,95 @@
+{ inputs, ... }:
+{
+  perSystem =
+    { config, pkgs, ... }:
+    let
+      valet = config.valet.lib;
+
+      provider-okta = valet.mkGoModule {
+        pname = "provider-okta";
+        subPackages = [ "provider-okta/cmd" ];
+        postInstall = ''
+          mv $out/bin/cmd $out/bin/provider-okta
+        '';
+        meta.mainProgram = "provider-okta";
+      };
+
+      provider-okta-compressed = pkgs.stdenvNoCC.mkDerivation {
+        inherit (provider-okta) pname version meta;
+        dontUnpack = true;
+        nativeBuildInputs = [ pkgs.upx ];
+        buildPhase = ''
+          mkdir -p $out/bin
+          upx -o $out/bin/provider-okta ${provider-okta}/bin/provider-okta
+        '';
+      };
+
+      image = valet.mkImage {
+        name = "provider-okta";
+        entrypoint = "${provider-okta-compressed}/bin/provider-okta";
+      };
+      e2e-test-okta = pkgs.writeShellApplication {
+        name = "e2e-test-okta";
+        runtimeInputs = [
+          pkgs.go
+          pkgs.gotestsum
+        ];
+        text = ''
+          export GOFLAGS="-mod=vendor"
+          if [ ! -d vendor ]; then
+            ln -sfn ${valet.workspaceVendor} vendor
+          fi
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum \
+            --format "''${GOTESTSUM_FORMAT:-short-verbose}" \
+            -- -run TestE2E -timeout 10m \
+            -coverpkg=github.com/lukasngl/valet/framework/...,./... \
+            -coverprofile="''${COVERAGE_FILE:-coverage-okta-e2e.txt}" \
+            ./provider-okta/...
+        '';
+      };
+    in
+    {
+      packages = {
+        inherit provider-okta provider-okta-compressed;
+        provider-okta-image = image;
+      };
+
+      apps.e2e-test-okta = {
+        type = "app";
+        program = "${e2e-test-okta}/bin/e2e-test-okta";
+      };
+
+      checks.provider-okta-helm = valet.packageChart {
+        name = "provider-okta";
+        src = "${inputs.self}/provider-okta/charts/provider-okta";
+      };
+
+      checks.provider-okta-lint = valet.withPackageEnv provider-okta {
+        name = "provider-okta-lint";
+        extraBuildInputs = [ pkgs.golangci-lint ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          golangci-lint run --timeout 10m ./provider-okta/...
+        '';
+      };
+
+      checks.provider-okta-test = valet.withPackageEnv provider-okta {
+        name = "provider-okta-test";
+        extraBuildInputs = [
+          pkgs.gotestsum
+          pkgs.etcd
+          pkgs.kubernetes
+        ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum --format short-verbose -- -short -coverpkg=github.com/lukasngl/valet/framework/...,./... -coverprofile=coverage.txt ./provider-okta/...
+        '';
+        installPhase = ''
+          mkdir -p $out
+          cp coverage.txt $out/
+        '';
+      };
+    };
+}
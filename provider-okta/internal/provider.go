@@ -0,0 +1,327 @@
+// Package internal contains the Okta provider implementation.
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-okta/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// DefaultValidity is the default key rotation period (90 days).
+	DefaultValidity = 90 * 24 * time.Hour
+
+	// MaxClientSecrets is the number of client secrets Okta allows per
+	// application.
+	MaxClientSecrets = 2
+)
+
+// Provider provisions client secrets for Okta OAuth applications using the
+// Okta Applications API. It implements [framework.Provider] for
+// [*v1alpha1.OktaClientSecret].
+type Provider struct {
+	client   *http.Client
+	baseURL  string
+	apiToken string
+	initOnce sync.Once
+	initErr  error
+}
+
+// Option configures a [Provider].
+type Option func(*Provider)
+
+// WithHTTPClient sets a custom HTTP client. Useful for testing with a mock
+// transport.
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Provider) { p.client = c }
+}
+
+// WithBaseURL sets the Okta org URL, e.g. https://example.okta.com.
+// Defaults to the OKTA_ORG_URL environment variable.
+func WithBaseURL(url string) Option {
+	return func(p *Provider) { p.baseURL = strings.TrimSuffix(url, "/") }
+}
+
+// WithAPIToken sets the Okta API token. Defaults to the OKTA_API_TOKEN
+// environment variable.
+func WithAPIToken(token string) Option {
+	return func(p *Provider) { p.apiToken = token }
+}
+
+// New creates a [Provider] with the given options.
+func New(opts ...Option) *Provider {
+	p := &Provider{}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// NewObject returns a zero-value OktaClientSecret.
+func (p *Provider) NewObject() *v1alpha1.OktaClientSecret {
+	return &v1alpha1.OktaClientSecret{}
+}
+
+// Provision creates a new client secret for an OAuth application.
+func (p *Provider) Provision(
+	ctx context.Context,
+	obj *v1alpha1.OktaClientSecret,
+) (*framework.Result, error) {
+	if err := p.initClient(); err != nil {
+		return nil, err
+	}
+
+	validity := DefaultValidity
+	if obj.Spec.Validity != nil {
+		validity = obj.Spec.Validity.Duration
+	}
+
+	// Okta rejects a third secret with a generic validation error, so check
+	// the limit up front to give a useful message.
+	var existing []clientSecret
+	if err := p.oktaRequest(ctx, http.MethodGet, secretsPath(obj.Spec.AppID), nil, &existing); err != nil {
+		return nil, fmt.Errorf("listing client secrets of app %s: %w", obj.Spec.AppID, err)
+	}
+	if len(existing) >= MaxClientSecrets {
+		ids := make([]string, len(existing))
+		for i, s := range existing {
+			ids[i] = s.ID
+		}
+		return nil, fmt.Errorf(
+			"app %s already has the maximum of %d client secrets (%s), "+
+				"delete an unmanaged secret or wait for the next cleanup",
+			obj.Spec.AppID, MaxClientSecrets, strings.Join(ids, ", "))
+	}
+
+	var secret clientSecret
+	if err := p.oktaRequest(ctx, http.MethodPost, secretsPath(obj.Spec.AppID), struct{}{}, &secret); err != nil {
+		return nil, fmt.Errorf("creating client secret for app %s: %w", obj.Spec.AppID, err)
+	}
+	if secret.ClientSecret == "" {
+		return nil, errors.New("no client secret returned from Okta API")
+	}
+
+	var app application
+	if err := p.oktaRequest(ctx, http.MethodGet, appPath(obj.Spec.AppID), nil, &app); err != nil {
+		return nil, fmt.Errorf("getting app %s: %w", obj.Spec.AppID, err)
+	}
+
+	now := time.Now()
+
+	templateData := map[string]string{
+		"ClientID":     app.Credentials.OAuthClient.ClientID,
+		"ClientSecret": secret.ClientSecret,
+		"OrgURL":       p.baseURL,
+	}
+
+	data := make(map[string]string, len(obj.Spec.Template))
+	for key, tmpl := range obj.Spec.Template {
+		rendered, err := renderTemplate(tmpl, templateData)
+		if err != nil {
+			return nil, fmt.Errorf("rendering template %q: %w", key, err)
+		}
+		data[key] = rendered
+	}
+
+	return &framework.Result{
+		StringData:    data,
+		ProvisionedAt: now,
+		ValidUntil:    now.Add(validity),
+		KeyID:         secret.ID,
+	}, nil
+}
+
+// DeleteKey deactivates and deletes a client secret of an OAuth application.
+// Okta only allows deleting inactive secrets. Returns nil if the secret has
+// already been deleted (idempotent).
+func (p *Provider) DeleteKey(
+	ctx context.Context,
+	obj *v1alpha1.OktaClientSecret,
+	keyID string,
+) error {
+	if keyID == "" {
+		return nil
+	}
+
+	if err := p.initClient(); err != nil {
+		return err
+	}
+
+	secretPath := secretsPath(obj.Spec.AppID) + "/" + url.PathEscape(keyID)
+
+	err := p.oktaRequest(ctx, http.MethodPost, secretPath+"/lifecycle/deactivate", nil, nil)
+	if err == nil {
+		err = p.oktaRequest(ctx, http.MethodDelete, secretPath, nil, nil)
+	}
+	if err != nil {
+		// Secret already deleted at the provider — not an error.
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			log.FromContext(ctx).Info("key already deleted", "keyId", keyID, "appId", obj.Spec.AppID)
+			return nil
+		}
+		return fmt.Errorf("deleting client secret %s of app %s: %w", keyID, obj.Spec.AppID, err)
+	}
+
+	return nil
+}
+
+// Endpoints returns the Okta org, unless its URL is not configured yet. It
+// implements [framework.EndpointDeclarer].
+func (p *Provider) Endpoints() ([]framework.Endpoint, error) {
+	baseURL := p.baseURL
+	if baseURL == "" {
+		baseURL = os.Getenv("OKTA_ORG_URL")
+	}
+	if baseURL == "" {
+		return nil, nil
+	}
+
+	e, err := framework.EndpointFromURL("Okta", baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return []framework.Endpoint{e}, nil
+}
+
+// initClient reads the org URL and API token from the environment on first
+// use, unless they were set via [WithBaseURL] and [WithAPIToken].
+func (p *Provider) initClient() error {
+	p.initOnce.Do(func() {
+		if p.baseURL == "" {
+			p.baseURL = strings.TrimSuffix(os.Getenv("OKTA_ORG_URL"), "/")
+		}
+		if p.apiToken == "" {
+			p.apiToken = os.Getenv("OKTA_API_TOKEN")
+		}
+		if p.baseURL == "" || p.apiToken == "" {
+			p.initErr = errors.New("OKTA_ORG_URL and OKTA_API_TOKEN must be set")
+			return
+		}
+		if p.client == nil {
+			p.client = &http.Client{Timeout: 30 * time.Second}
+		}
+	})
+	return p.initErr
+}
+
+// appPath returns the API path of an application.
+func appPath(appID string) string {
+	return "/api/v1/apps/" + url.PathEscape(appID)
+}
+
+// secretsPath returns the API path of the client secrets of an application.
+func secretsPath(appID string) string {
+	return appPath(appID) + "/credentials/secrets"
+}
+
+// oktaRequest makes a request to the Okta API and decodes the response into
+// out, if non-nil. Error responses are returned as [*apiError].
+func (p *Provider) oktaRequest(
+	ctx context.Context,
+	method, urlPath string,
+	body, out any,
+) error {
+	var reqBody io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshalling request body: %w", err)
+		}
+		reqBody = bytes.NewReader(jsonBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+urlPath, reqBody)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "SSWS "+p.apiToken)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		apiErr := &apiError{}
+		if json.Unmarshal(respBody, apiErr) != nil || apiErr.Summary == "" {
+			apiErr.Summary = string(respBody)
+		}
+		apiErr.Code = resp.StatusCode
+		return apiErr
+	}
+
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("parsing response: %w", err)
+		}
+	}
+	return nil
+}
+
+// Okta Applications API request/response types.
+
+type clientSecret struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	ClientSecret string `json:"client_secret"`
+}
+
+type application struct {
+	Credentials struct {
+		OAuthClient struct {
+			ClientID string `json:"client_id"`
+		} `json:"oauthClient"`
+	} `json:"credentials"`
+}
+
+// apiError is an Okta API error response.
+type apiError struct {
+	Code      int    `json:"-"`
+	ErrorCode string `json:"errorCode"`
+	Summary   string `json:"errorSummary"`
+}
+
+func (e *apiError) Error() string {
+	if e.ErrorCode != "" {
+		return fmt.Sprintf("Okta API error (status %d %s): %s", e.Code, e.ErrorCode, e.Summary)
+	}
+	return fmt.Sprintf("Okta API error (status %d): %s", e.Code, e.Summary)
+}
+
+// renderTemplate renders a Go template string with the given data.
+func renderTemplate(tmpl string, data map[string]string) (string, error) {
+	t, err := template.New("").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
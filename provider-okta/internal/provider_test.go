@@ -0,0 +1,257 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/provider-okta/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	testAppID    = "0oa1example"
+	testClientID = "0oa1clientid"
+	testToken    = "test-token"
+)
+
+func newObj(template map[string]string) *v1alpha1.OktaClientSecret {
+	return &v1alpha1.OktaClientSecret{
+		Spec: v1alpha1.OktaClientSecretSpec{
+			AppID:    testAppID,
+			Template: template,
+		},
+	}
+}
+
+// newTestProvider starts a server with the given handler and returns a
+// provider pointed at it.
+func newTestProvider(t *testing.T, handler http.HandlerFunc) (*Provider, string) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "SSWS "+testToken {
+			t.Errorf("got Authorization %q", got)
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL), WithAPIToken(testToken)), srv.URL
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeAPIError(w http.ResponseWriter, code int, errorCode, summary string) {
+	writeJSON(w, code, map[string]any{"errorCode": errorCode, "errorSummary": summary})
+}
+
+// appHandler serves an app with the given existing secrets and creates a new
+// secret on POST.
+func appHandler(t *testing.T, existing []clientSecret) http.HandlerFunc {
+	secretsPath := "/api/v1/apps/" + testAppID + "/credentials/secrets"
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == secretsPath:
+			writeJSON(w, http.StatusOK, existing)
+		case r.Method == http.MethodPost && r.URL.Path == secretsPath:
+			writeJSON(w, http.StatusOK, clientSecret{ID: "ocs1new", Status: "ACTIVE", ClientSecret: "s3cr3t"})
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/apps/"+testAppID:
+			writeJSON(w, http.StatusOK, map[string]any{
+				"credentials": map[string]any{"oauthClient": map[string]any{"client_id": testClientID}},
+			})
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestProvision(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		p, orgURL := newTestProvider(t, appHandler(t, nil))
+
+		result, err := p.Provision(context.Background(), newObj(map[string]string{
+			"CLIENT_ID":     "{{ .ClientID }}",
+			"CLIENT_SECRET": "{{ .ClientSecret }}",
+			"ISSUER":        "{{ .OrgURL }}/oauth2/default",
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.KeyID != "ocs1new" {
+			t.Fatalf("got keyID %q, want %q", result.KeyID, "ocs1new")
+		}
+		if got := result.StringData["CLIENT_ID"]; got != testClientID {
+			t.Fatalf("got CLIENT_ID %q, want %q", got, testClientID)
+		}
+		if got := result.StringData["CLIENT_SECRET"]; got != "s3cr3t" {
+			t.Fatalf("got CLIENT_SECRET %q, want %q", got, "s3cr3t")
+		}
+		if got, want := result.StringData["ISSUER"], orgURL+"/oauth2/default"; got != want {
+			t.Fatalf("got ISSUER %q, want %q", got, want)
+		}
+	})
+
+	t.Run("default validity", func(t *testing.T) {
+		p, _ := newTestProvider(t, appHandler(t, nil))
+		result, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != DefaultValidity {
+			t.Fatalf("got validity %v, want %v", got, DefaultValidity)
+		}
+	})
+
+	t.Run("custom validity", func(t *testing.T) {
+		p, _ := newTestProvider(t, appHandler(t, nil))
+		obj := newObj(map[string]string{"K": "v"})
+		obj.Spec.Validity = &metav1.Duration{Duration: 48 * time.Hour}
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != 48*time.Hour {
+			t.Fatalf("got validity %v, want %v", got, 48*time.Hour)
+		}
+	})
+
+	t.Run("secret limit reached", func(t *testing.T) {
+		p, _ := newTestProvider(t, appHandler(t, []clientSecret{{ID: "ocs1a"}, {ID: "ocs1b"}}))
+		_, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "maximum of 2 client secrets (ocs1a, ocs1b)") {
+			t.Fatalf("expected limit error, got: %v", err)
+		}
+	})
+
+	t.Run("empty client secret", func(t *testing.T) {
+		p, _ := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				writeJSON(w, http.StatusOK, []clientSecret{})
+				return
+			}
+			writeJSON(w, http.StatusOK, clientSecret{ID: "ocs1new"})
+		})
+		_, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if err == nil {
+			t.Fatal("expected error for empty client secret")
+		}
+		if !strings.Contains(err.Error(), "no client secret") {
+			t.Fatalf("expected 'no client secret' error, got: %v", err)
+		}
+	})
+
+	t.Run("API error propagates", func(t *testing.T) {
+		p, _ := newTestProvider(t, func(w http.ResponseWriter, _ *http.Request) {
+			writeAPIError(w, http.StatusForbidden, "E0000006", "You do not have permission")
+		})
+		_, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "listing client secrets of app "+testAppID) ||
+			!strings.Contains(err.Error(), "E0000006") {
+			t.Fatalf("expected wrapped error, got: %v", err)
+		}
+	})
+
+	t.Run("bad template", func(t *testing.T) {
+		p, _ := newTestProvider(t, appHandler(t, nil))
+		_, err := p.Provision(context.Background(), newObj(map[string]string{
+			"BAD": "{{ .Unclosed",
+		}))
+		if err == nil {
+			t.Fatal("expected template error")
+		}
+		if !strings.Contains(err.Error(), "rendering template") {
+			t.Fatalf("expected 'rendering template' error, got: %v", err)
+		}
+	})
+}
+
+func TestDeleteKey(t *testing.T) {
+	t.Run("empty keyID is a no-op", func(t *testing.T) {
+		p, _ := newTestProvider(t, func(_ http.ResponseWriter, r *http.Request) {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		})
+		if err := p.DeleteKey(context.Background(), newObj(nil), ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("deactivates then deletes", func(t *testing.T) {
+		var got []string
+		p, _ := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+			got = append(got, r.Method+" "+r.URL.Path)
+			writeJSON(w, http.StatusOK, map[string]string{})
+		})
+		if err := p.DeleteKey(context.Background(), newObj(nil), "ocs1old"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		base := "/api/v1/apps/" + testAppID + "/credentials/secrets/ocs1old"
+		want := []string{"POST " + base + "/lifecycle/deactivate", "DELETE " + base}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Fatalf("got requests %q, want %q", got, want)
+		}
+	})
+
+	t.Run("already deleted is idempotent", func(t *testing.T) {
+		p, _ := newTestProvider(t, func(w http.ResponseWriter, _ *http.Request) {
+			writeAPIError(w, http.StatusNotFound, "E0000007", "Not found: Resource not found")
+		})
+		if err := p.DeleteKey(context.Background(), newObj(nil), "gone-key"); err != nil {
+			t.Fatalf("expected nil for already-deleted key, got: %v", err)
+		}
+	})
+
+	t.Run("other error propagates", func(t *testing.T) {
+		p, _ := newTestProvider(t, func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("boom"))
+		})
+		err := p.DeleteKey(context.Background(), newObj(nil), "ocs1old")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "deleting client secret ocs1old") ||
+			!strings.Contains(err.Error(), "boom") {
+			t.Fatalf("expected 'deleting client secret' error, got: %v", err)
+		}
+	})
+}
+
+func TestInitClient(t *testing.T) {
+	t.Run("reads configuration from the environment", func(t *testing.T) {
+		t.Setenv("OKTA_ORG_URL", "https://example.okta.com/")
+		t.Setenv("OKTA_API_TOKEN", "env-token")
+
+		p := New()
+		if err := p.initClient(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p.baseURL != "https://example.okta.com" || p.apiToken != "env-token" {
+			t.Fatalf("got baseURL %q and token %q", p.baseURL, p.apiToken)
+		}
+	})
+
+	t.Run("fails without configuration", func(t *testing.T) {
+		t.Setenv("OKTA_ORG_URL", "")
+		t.Setenv("OKTA_API_TOKEN", "")
+
+		p := New()
+		if err := p.initClient(); err == nil {
+			t.Fatal("expected error without configuration")
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package e2e
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cucumber/godog"
+	"github.com/cucumber/godog/colors"
+	"github.com/google/uuid"
+	"github.com/lukasngl/valet/framework/bddtest"
+	"github.com/lukasngl/valet/provider-okta/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-okta/internal"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var godogOpts = godog.Options{
+	Format:      "pretty",
+	Output:      colors.Colored(os.Stdout),
+	Paths:       []string{"../../features"},
+	Concurrency: 1,
+	Strict:      true,
+}
+
+func init() {
+	godog.BindFlags("godog.", flag.CommandLine, &godogOpts)
+}
+
+var testEnvCfg bddtest.Env
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	if len(flag.Args()) > 0 {
+		godogOpts.Paths = flag.Args()
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	testEnvCfg.Scheme = runtime.NewScheme()
+	_ = corev1.AddToScheme(testEnvCfg.Scheme)
+	_ = v1alpha1.AddToScheme(testEnvCfg.Scheme)
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths: []string{"../../config/crd"},
+		Scheme:            testEnvCfg.Scheme,
+	}
+	env.ControlPlane.GetAPIServer().Configure().
+		Append("advertise-address", "127.0.0.1").
+		Append("bind-address", "127.0.0.1")
+
+	cfg, err := env.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start envtest: %v\n", err)
+		os.Exit(1)
+	}
+	testEnvCfg.Cfg = cfg
+
+	code := m.Run()
+
+	_ = env.Stop()
+	os.Exit(code)
+}
+
+// TestMock runs all scenarios with a canned HTTP transport.
+func TestMock(t *testing.T) {
+	t.Setenv("TEST_OKTA_APP_ID", "0oavalettest")
+
+	opts := godogOpts
+	status := godog.TestSuite{
+		Name: "provider-okta-mock",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New(
+				internal.WithHTTPClient(&http.Client{Transport: &oktaMock{}}),
+				internal.WithBaseURL("http://okta.mock"),
+				internal.WithAPIToken("mock-token"),
+			)
+			shared := bddtest.New[*v1alpha1.OktaClientSecret](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// TestE2E runs non-mock scenarios against a real Okta org.
+func TestE2E(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e tests in short mode")
+	}
+
+	if os.Getenv("TEST_OKTA_APP_ID") == "" {
+		t.Skip("skipping e2e tests: TEST_OKTA_APP_ID not set")
+	}
+
+	opts := godogOpts
+	opts.Tags = "~@mock"
+	status := godog.TestSuite{
+		Name: "provider-okta-e2e",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New()
+			shared := bddtest.New[*v1alpha1.OktaClientSecret](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// oktaMock is an [http.RoundTripper] that returns canned Okta Applications
+// API responses. Apps have no existing secrets, each secret creation returns a
+// unique ID, the app's client ID equals its app ID, and deactivation and
+// deletion always succeed.
+type oktaMock struct{}
+
+func (m *oktaMock) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Paths look like /api/v1/apps/{appID}[/credentials/secrets[/{id}[/lifecycle/deactivate]]].
+	parts := strings.Split(req.URL.Path, "/")
+	switch {
+	case req.Method == http.MethodGet && len(parts) == 5:
+		return jsonResponse(http.StatusOK, map[string]any{
+			"credentials": map[string]any{"oauthClient": map[string]any{"client_id": parts[4]}},
+		})
+	case req.Method == http.MethodGet && len(parts) == 7 && parts[6] == "secrets":
+		return jsonResponse(http.StatusOK, []any{})
+	case req.Method == http.MethodPost && len(parts) == 7 && parts[6] == "secrets":
+		return jsonResponse(http.StatusOK, map[string]string{
+			"id":            "ocs" + strings.ReplaceAll(uuid.New().String(), "-", ""),
+			"status":        "ACTIVE",
+			"client_secret": "fake-client-secret",
+		})
+	case req.Method == http.MethodPost && len(parts) == 10 && parts[9] == "deactivate",
+		req.Method == http.MethodDelete && len(parts) == 8:
+		return jsonResponse(http.StatusOK, map[string]string{})
+	default:
+		return jsonResponse(http.StatusNotFound, map[string]string{
+			"errorCode":    "E0000007",
+			"errorSummary": fmt.Sprintf("unexpected request: %s %s", req.Method, req.URL.Path),
+		})
+	}
+}
+
+func jsonResponse(status int, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(string(data))),
+	}, nil
+}
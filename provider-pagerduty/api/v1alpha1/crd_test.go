@@ -0,0 +1,13 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/lukasngl/valet/framework/lint"
+)
+
+func TestCRDs(t *testing.T) {
+	for _, err := range lint.CheckCRDFiles("../../config/crd/*.yaml") {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,221 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	SchemeBuilder.Register(&PagerDutyAPIToken{}, &PagerDutyAPITokenList{})
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=pdtoken,categories=valet
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// PagerDutyAPIToken provisions and rotates scoped PagerDuty REST API tokens.
+type PagerDutyAPIToken struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	Spec PagerDutyAPITokenSpec `json:"spec,omitzero"`
+	// +optional
+	Status framework.ClientSecretStatus `json:"status,omitzero"`
+}
+
+// PagerDutyAPITokenSpec defines the desired state.
+type PagerDutyAPITokenSpec struct {
+	// SecretRef is the Kubernetes Secret to create/update with the provisioned credentials.
+	// Defaults to a Secret named after the resource.
+	// +optional
+	SecretRef framework.SecretReference `json:"secretRef,omitzero"`
+
+	// Suspend stops the rotation and cleanup of the credentials while set,
+	// e.g. during incidents or migrations. The output Secret keeps its
+	// current credentials.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DeletionPolicy controls what happens when the resource is deleted:
+	// Delete deletes its keys at the provider and the output Secret with it,
+	// Retain keeps both, e.g. to move the resource to another cluster.
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy framework.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// SecretTemplate sets labels, annotations and the type of the output
+	// Secret, e.g. for Reloader, External Secrets or Argo CD to match on.
+	// +optional
+	SecretTemplate framework.SecretTemplate `json:"secretTemplate,omitzero"`
+
+	// Targets are further Secrets the credentials are written to, each
+	// with a subset of the keys, its own template or in another namespace,
+	// so that consumers expecting different shapes share one credential.
+	// +optional
+	Targets framework.OutputTargets `json:"targets,omitempty"`
+
+	// Replication copies the output Secret into all namespaces matching a
+	// label selector that allow it, and keeps the copies in sync.
+	// +optional
+	Replication *framework.Replication `json:"replication,omitempty"`
+
+	// Scopes are the OAuth scopes granted to each token, e.g.
+	// "incidents.read" or "services.write". The operator's app registration
+	// must have been granted them.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	Scopes []string `json:"scopes"`
+
+	// Validity is how long each token is used before it is rotated. Tokens
+	// expire at PagerDuty after a fixed lifetime; a longer validity is capped
+	// to it. Defaults to the token lifetime.
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// Rotation configures how credentials are rotated, e.g. how long a
+	// superseded credential stays valid.
+	// +optional
+	Rotation framework.RotationPolicy `json:"rotation,omitzero"`
+
+	// Template maps output secret keys to Go template strings.
+	// Available template variables: .Token, .Scope
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinProperties=1
+	Template map[string]string `json:"template"`
+}
+
+// GetSecretRef returns the reference to the target output Secret, defaulting
+// to a Secret named after the resource.
+func (p *PagerDutyAPIToken) GetSecretRef() framework.SecretReference {
+	return p.Spec.SecretRef.OrDefault(p.Name)
+}
+
+// IsSuspended reports whether the rotation of the credentials is suspended.
+// It implements [framework.Suspender].
+func (p *PagerDutyAPIToken) IsSuspended() bool {
+	return p.Spec.Suspend
+}
+
+// GetDeletionPolicy returns what happens to the keys and the output Secret
+// when the resource is deleted. It implements [framework.DeletionPolicyHolder].
+func (p *PagerDutyAPIToken) GetDeletionPolicy() framework.DeletionPolicy {
+	return p.Spec.DeletionPolicy
+}
+
+// GetSecretTemplate returns the labels, annotations and type of the output
+// Secret. It implements [framework.SecretTemplateHolder].
+func (p *PagerDutyAPIToken) GetSecretTemplate() framework.SecretTemplate {
+	return p.Spec.SecretTemplate
+}
+
+// GetTargets returns the further Secrets the credentials are written to.
+// It implements [framework.TargetHolder].
+func (p *PagerDutyAPIToken) GetTargets() framework.OutputTargets {
+	return p.Spec.Targets
+}
+
+// GetReplication returns the replication of the output Secret, or nil.
+// It implements [framework.ReplicationHolder].
+func (p *PagerDutyAPIToken) GetReplication() *framework.Replication {
+	return p.Spec.Replication
+}
+
+// GetValidity returns the requested validity of the credentials, or zero for
+// the provider's default. It implements [framework.ValidityRequester].
+func (p *PagerDutyAPIToken) GetValidity() time.Duration {
+	if p.Spec.Validity == nil {
+		return 0
+	}
+	return p.Spec.Validity.Duration
+}
+
+// GetRotationPolicy returns the rotation policy of the credentials. It
+// implements [framework.RotationPolicyHolder].
+func (p *PagerDutyAPIToken) GetRotationPolicy() framework.RotationPolicy {
+	return p.Spec.Rotation
+}
+
+// GetStatus returns a pointer to the shared status.
+func (p *PagerDutyAPIToken) GetStatus() *framework.ClientSecretStatus {
+	return &p.Status
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (p *PagerDutyAPIToken) DeepCopyObject() runtime.Object {
+	cp := *p
+	cp.ObjectMeta = *p.DeepCopy()
+	cp.Status = p.Status.DeepCopy()
+	cp.Spec.SecretTemplate = p.Spec.SecretTemplate.DeepCopy()
+	cp.Spec.Targets = p.Spec.Targets.DeepCopy()
+	cp.Spec.Replication = p.Spec.Replication.DeepCopy()
+	if p.Spec.Scopes != nil {
+		cp.Spec.Scopes = append([]string(nil), p.Spec.Scopes...)
+	}
+	if p.Spec.Template != nil {
+		cp.Spec.Template = make(map[string]string, len(p.Spec.Template))
+		for k, v := range p.Spec.Template {
+			cp.Spec.Template[k] = v
+		}
+	}
+	if p.Spec.Validity != nil {
+		v := *p.Spec.Validity
+		cp.Spec.Validity = &v
+	}
+	return &cp
+}
+
+// Validate performs structural validation of the spec.
+func (p *PagerDutyAPIToken) Validate() error {
+	if len(p.Spec.Scopes) == 0 {
+		return fmt.Errorf("scopes must have at least one entry")
+	}
+	for _, scope := range p.Spec.Scopes {
+		if scope == "" || strings.ContainsAny(scope, " \t\n") {
+			return fmt.Errorf("scope %q must be a single non-empty word", scope)
+		}
+		if strings.HasPrefix(scope, "as_account-") {
+			return fmt.Errorf("scope %q selects the account, which is configured on the operator", scope)
+		}
+	}
+	if p.Spec.Validity != nil && p.Spec.Validity.Duration <= 0 {
+		return fmt.Errorf("validity must be positive")
+	}
+	if len(p.Spec.Template) == 0 {
+		return fmt.Errorf("template must have at least one entry")
+	}
+	for key, tmpl := range p.Spec.Template {
+		if _, err := template.New(key).Parse(tmpl); err != nil {
+			return fmt.Errorf("template %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:object:root=true
+
+// PagerDutyAPITokenList contains a list of PagerDutyAPIToken resources.
+type PagerDutyAPITokenList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PagerDutyAPIToken `json:"items"`
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (p *PagerDutyAPITokenList) DeepCopyObject() runtime.Object {
+	cp := *p
+	if p.Items != nil {
+		cp.Items = make([]PagerDutyAPIToken, len(p.Items))
+		for i := range p.Items {
+			cp.Items[i] = *p.Items[i].DeepCopyObject().(*PagerDutyAPIToken)
+		}
+	}
+	return &cp
+}
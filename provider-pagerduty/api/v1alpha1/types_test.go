@@ -0,0 +1,129 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	valid := &PagerDutyAPIToken{
+		Spec: PagerDutyAPITokenSpec{
+			SecretRef: framework.SecretReference{Name: "out"},
+			Scopes:    []string{"incidents.read"},
+			Template:  map[string]string{"TOKEN": "{{ .Token }}"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*PagerDutyAPIToken)
+		wantErr string
+	}{
+		{name: "valid", modify: func(_ *PagerDutyAPIToken) {}},
+		{
+			name:   "omitted secretRef",
+			modify: func(p *PagerDutyAPIToken) { p.Spec.SecretRef.Name = "" },
+		},
+		{
+			name:    "missing scopes",
+			modify:  func(p *PagerDutyAPIToken) { p.Spec.Scopes = nil },
+			wantErr: "scopes",
+		},
+		{
+			name:    "scope with whitespace",
+			modify:  func(p *PagerDutyAPIToken) { p.Spec.Scopes = []string{"incidents.read services.read"} },
+			wantErr: "single non-empty word",
+		},
+		{
+			name:    "account scope",
+			modify:  func(p *PagerDutyAPIToken) { p.Spec.Scopes = []string{"as_account-us.acme"} },
+			wantErr: "configured on the operator",
+		},
+		{
+			name: "non-positive validity",
+			modify: func(p *PagerDutyAPIToken) {
+				p.Spec.Validity = &metav1.Duration{Duration: -time.Hour}
+			},
+			wantErr: "validity",
+		},
+		{
+			name:    "empty template",
+			modify:  func(p *PagerDutyAPIToken) { p.Spec.Template = nil },
+			wantErr: "template",
+		},
+		{
+			name:    "invalid template syntax",
+			modify:  func(p *PagerDutyAPIToken) { p.Spec.Template = map[string]string{"bad": "{{ .Foo"} },
+			wantErr: "template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := valid.DeepCopyObject().(*PagerDutyAPIToken)
+			tt.modify(obj)
+			err := obj.Validate()
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if got := err.Error(); !strings.Contains(got, tt.wantErr) {
+				t.Fatalf("error %q does not contain %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeepCopyObject(t *testing.T) {
+	validity := metav1.Duration{Duration: 12 * time.Hour}
+	obj := &PagerDutyAPIToken{
+		Spec: PagerDutyAPITokenSpec{
+			SecretRef: framework.SecretReference{Name: "s"},
+			Scopes:    []string{"incidents.read"},
+			Template:  map[string]string{"K": "V"},
+			Validity:  &validity,
+		},
+	}
+	obj.Status.Phase = framework.PhaseReady
+
+	cp := obj.DeepCopyObject().(*PagerDutyAPIToken)
+
+	cp.Spec.Template["K"] = "changed"
+	if obj.Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not copy template map")
+	}
+
+	cp.Spec.Scopes[0] = "changed"
+	if obj.Spec.Scopes[0] != "incidents.read" {
+		t.Fatal("DeepCopyObject did not copy scopes")
+	}
+
+	cp.Spec.Validity.Duration = time.Hour
+	if obj.Spec.Validity.Duration != 12*time.Hour {
+		t.Fatal("DeepCopyObject did not copy validity")
+	}
+}
+
+func TestDeepCopyObjectList(t *testing.T) {
+	list := &PagerDutyAPITokenList{
+		Items: []PagerDutyAPIToken{
+			{Spec: PagerDutyAPITokenSpec{Scopes: []string{"a"}}},
+		},
+	}
+
+	cp := list.DeepCopyObject().(*PagerDutyAPITokenList)
+	cp.Items[0].Spec.Scopes[0] = "changed"
+	if list.Items[0].Spec.Scopes[0] != "a" {
+		t.Fatal("DeepCopyObject did not deep copy list items")
+	}
+}
@@ -0,0 +1,124 @@
+Feature: PagerDuty API Token Provisioning
+  As a platform operator
+  I want the PagerDuty provider to provision and rotate scoped API tokens
+  So that applications can call the PagerDuty REST API
+
+  Background:
+    Given a Kubernetes cluster is running
+    And the CRDs are installed
+    And the operator is running
+
+  Scenario: Provision a token successfully
+    When I create a ClientSecret "test-secret" with:
+      """yaml
+      spec:
+        secretRef:
+          name: test-secret
+        scopes: ["$TEST_PAGERDUTY_SCOPE"]
+        template:
+          PAGERDUTY_TOKEN: "{{ .Token }}"
+          PAGERDUTY_SCOPE: "{{ .Scope }}"
+      """
+    Then the ClientSecret "test-secret" should have phase "Ready" within 60 seconds
+    And a Secret "test-secret" should exist
+    And the Secret "test-secret" should contain key "PAGERDUTY_TOKEN"
+    And the Secret "test-secret" should contain key "PAGERDUTY_SCOPE"
+
+  Scenario: Invalid template syntax is rejected
+    When I create a ClientSecret "bad-template" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-template
+        scopes: ["$TEST_PAGERDUTY_SCOPE"]
+        template:
+          SECRET: "{{ .Invalid"
+      """
+    Then the ClientSecret "bad-template" should have phase "Failed" within 60 seconds
+    And the ClientSecret "bad-template" status should contain message "template"
+    And the Secret "bad-template" should not exist
+
+  Scenario: Delete PagerDutyAPIToken cleans up resources
+    When I create a ClientSecret "delete-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: delete-test
+        scopes: ["$TEST_PAGERDUTY_SCOPE"]
+        template:
+          PAGERDUTY_TOKEN: "{{ .Token }}"
+      """
+    Then the ClientSecret "delete-test" should have phase "Ready" within 60 seconds
+    And a Secret "delete-test" should exist
+    When I delete the ClientSecret "delete-test"
+    Then the ClientSecret "delete-test" should not exist within 60 seconds
+
+  Scenario: Expired tokens are rotated
+    When I create a ClientSecret "rotation-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rotation-test
+        scopes: ["$TEST_PAGERDUTY_SCOPE"]
+        template:
+          PAGERDUTY_TOKEN: "{{ .Token }}"
+      """
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the ClientSecret "rotation-test" should have 1 active keys
+    When I expire the credentials for ClientSecret "rotation-test"
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+
+  Scenario: Missing scopes are rejected
+    When I try to create a ClientSecret "no-scopes" with:
+      """yaml
+      spec:
+        secretRef:
+          name: no-scopes
+        template:
+          PAGERDUTY_TOKEN: "{{ .Token }}"
+      """
+    Then the operation should have failed with "scopes"
+
+  Scenario: Secret is owned by the PagerDutyAPIToken
+    When I create a ClientSecret "ownership-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: ownership-test
+        scopes: ["$TEST_PAGERDUTY_SCOPE"]
+        template:
+          PAGERDUTY_TOKEN: "{{ .Token }}"
+      """
+    Then the ClientSecret "ownership-test" should have phase "Ready" within 60 seconds
+    And the Secret "ownership-test" should be owned by ClientSecret "ownership-test"
+
+  @mock
+  Scenario: Template renders with correct values
+    When I create a ClientSecret "value-check" with:
+      """yaml
+      spec:
+        secretRef:
+          name: value-check
+        scopes: ["$TEST_PAGERDUTY_SCOPE"]
+        template:
+          PAGERDUTY_TOKEN: "{{ .Token }}"
+          PAGERDUTY_SCOPE: "{{ .Scope }}"
+      """
+    Then the ClientSecret "value-check" should have phase "Ready" within 60 seconds
+    And the Secret "value-check" should contain key "PAGERDUTY_TOKEN"
+    And the Secret "value-check" should contain key "PAGERDUTY_SCOPE" with value "incidents.read"
+
+  @mock
+  Scenario: Scopes that are not granted are reported
+    When I create a ClientSecret "bad-scope" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-scope
+        scopes: ["invalid.write"]
+        template:
+          PAGERDUTY_TOKEN: "{{ .Token }}"
+      """
+    Then the ClientSecret "bad-scope" should have phase "Failed" within 60 seconds
+    And the ClientSecret "bad-scope" status should contain message "invalid_scope"
+    And the Secret "bad-scope" should not exist
@@ -0,0 +1,95 @@
+{ inputs, ... }:
+{
+  perSystem =
+    { config, pkgs, ... }:
+    let
+      valet = config.valet.lib;
+
+      provider-pagerduty = valet.mkGoModule {
+        pname = "provider-pagerduty";
+        subPackages = [ "provider-pagerduty/cmd" ];
+        postInstall = ''
+          mv $out/bin/cmd $out/bin/provider-pagerduty
+        '';
+        meta.mainProgram = "provider-pagerduty";
+      };
+
+      provider-pagerduty-compressed = pkgs.stdenvNoCC.mkDerivation {
+        inherit (provider-pagerduty) pname version meta;
+        dontUnpack = true;
+        nativeBuildInputs = [ pkgs.upx ];
+        buildPhase = ''
+          mkdir -p $out/bin
+          upx -o $out/bin/provider-pagerduty ${provider-pagerduty}/bin/provider-pagerduty
+        '';
+      };
+
+      image = valet.mkImage {
+        name = "provider-pagerduty";
+        entrypoint = "${provider-pagerduty-compressed}/bin/provider-pagerduty";
+      };
+      e2e-test-pagerduty = pkgs.writeShellApplication {
+        name = "e2e-test-pagerduty";
+        runtimeInputs = [
+          pkgs.go
+          pkgs.gotestsum
+        ];
+        text = ''
+          export GOFLAGS="-mod=vendor"
+          if [ ! -d vendor ]; then
+            ln -sfn ${valet.workspaceVendor} vendor
+          fi
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum \
+            --format "''${GOTESTSUM_FORMAT:-short-verbose}" \
+            -- -run TestE2E -timeout 10m \
+            -coverpkg=github.com/lukasngl/valet/framework/...,./... \
+            -coverprofile="''${COVERAGE_FILE:-coverage-pagerduty-e2e.txt}" \
+            ./provider-pagerduty/...
+        '';
+      };
+    in
+    {
+      packages = {
+        inherit provider-pagerduty provider-pagerduty-compressed;
+        provider-pagerduty-image = image;
+      };
+
+      apps.e2e-test-pagerduty = {
+        type = "app";
+        program = "${e2e-test-pagerduty}/bin/e2e-test-pagerduty";
+      };
+
+      checks.provider-pagerduty-helm = valet.packageChart {
+        name = "provider-pagerduty";
+        src = "${inputs.self}/provider-pagerduty/charts/provider-pagerduty";
+      };
+
+      checks.provider-pagerduty-lint = valet.withPackageEnv provider-pagerduty {
+        name = "provider-pagerduty-lint";
+        extraBuildInputs = [ pkgs.golangci-lint ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          golangci-lint run --timeout 10m ./provider-pagerduty/...
+        '';
+      };
+
+      checks.provider-pagerduty-test = valet.withPackageEnv provider-pagerduty {
+        name = "provider-pagerduty-test";
+        extraBuildInputs = [
+          pkgs.gotestsum
+          pkgs.etcd
+          pkgs.kubernetes
+        ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum --format short-verbose -- -short -coverpkg=github.com/lukasngl/valet/framework/...,./... -coverprofile=coverage.txt ./provider-pagerduty/...
+        '';
+        installPhase = ''
+          mkdir -p $out
+          cp coverage.txt $out/
+        '';
+      };
+    };
+}
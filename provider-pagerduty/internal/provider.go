@@ -0,0 +1,296 @@
+// Package internal contains the PagerDuty provider implementation.
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-pagerduty/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultBaseURL is the PagerDuty identity service, which issues scoped
+// OAuth tokens for accounts in every service region.
+const DefaultBaseURL = "https://identity.pagerduty.com"
+
+// DefaultRegion is the default PagerDuty service region.
+const DefaultRegion = "us"
+
+// tokenPath is the path of the OAuth token endpoint.
+const tokenPath = "/oauth/token"
+
+// Provider provisions scoped PagerDuty REST API tokens with the OAuth client
+// credentials grant of an app registration. It implements
+// [framework.Provider] for [*v1alpha1.PagerDutyAPIToken].
+//
+// The tokens expire at PagerDuty after a fixed lifetime, which bounds their
+// validity. PagerDuty can revoke a token only given its value, which the
+// operator does not keep, so expiry is what revokes a rotated token.
+type Provider struct {
+	client       *http.Client
+	baseURL      string
+	clientID     string
+	clientSecret string
+	subdomain    string
+	region       string
+	initOnce     sync.Once
+	initErr      error
+}
+
+// Option configures a [Provider].
+type Option func(*Provider)
+
+// WithHTTPClient sets a custom HTTP client. Useful for testing with a mock
+// transport.
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Provider) { p.client = c }
+}
+
+// WithBaseURL overrides the PagerDuty identity service endpoint. Defaults to
+// [DefaultBaseURL].
+func WithBaseURL(url string) Option {
+	return func(p *Provider) { p.baseURL = strings.TrimSuffix(url, "/") }
+}
+
+// WithCredentials sets the client ID and secret of the operator's app
+// registration. Defaults to the PAGERDUTY_CLIENT_ID and
+// PAGERDUTY_CLIENT_SECRET environment variables.
+func WithCredentials(clientID, clientSecret string) Option {
+	return func(p *Provider) { p.clientID, p.clientSecret = clientID, clientSecret }
+}
+
+// WithAccount sets the subdomain and service region ("us" or "eu") of the
+// PagerDuty account. Defaults to the PAGERDUTY_SUBDOMAIN and PAGERDUTY_REGION
+// environment variables, and the region to [DefaultRegion].
+func WithAccount(subdomain, region string) Option {
+	return func(p *Provider) { p.subdomain, p.region = subdomain, region }
+}
+
+// New creates a [Provider] with the given options.
+func New(opts ...Option) *Provider {
+	p := &Provider{}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// NewObject returns a zero-value PagerDutyAPIToken.
+func (p *Provider) NewObject() *v1alpha1.PagerDutyAPIToken {
+	return &v1alpha1.PagerDutyAPIToken{}
+}
+
+// Provision requests a new token with the scopes from the spec. It is valid
+// for the spec's validity or the token lifetime, whichever is shorter.
+func (p *Provider) Provision(
+	ctx context.Context,
+	obj *v1alpha1.PagerDutyAPIToken,
+) (*framework.Result, error) {
+	if err := p.initClient(); err != nil {
+		return nil, err
+	}
+
+	scope := strings.Join(append([]string{p.accountScope()}, obj.Spec.Scopes...), " ")
+
+	var token tokenResponse
+	if err := p.apiRequest(ctx, url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {p.clientID},
+		"client_secret": {p.clientSecret},
+		"scope":         {scope},
+	}, &token); err != nil {
+		return nil, fmt.Errorf("requesting token with scope %q: %w", scope, err)
+	}
+	if token.AccessToken == "" || token.ExpiresIn <= 0 {
+		return nil, errors.New("no token returned from PagerDuty identity service")
+	}
+
+	now := time.Now()
+
+	validity := time.Duration(token.ExpiresIn) * time.Second
+	if obj.Spec.Validity != nil && obj.Spec.Validity.Duration < validity {
+		validity = obj.Spec.Validity.Duration
+	}
+
+	templateData := map[string]string{
+		"Token": token.AccessToken,
+		"Scope": token.Scope,
+	}
+
+	data := make(map[string]string, len(obj.Spec.Template))
+	for key, tmpl := range obj.Spec.Template {
+		rendered, err := renderTemplate(tmpl, templateData)
+		if err != nil {
+			return nil, fmt.Errorf("rendering template %q: %w", key, err)
+		}
+		data[key] = rendered
+	}
+
+	return &framework.Result{
+		StringData:    data,
+		ProvisionedAt: now,
+		ValidUntil:    now.Add(validity),
+		KeyID:         tokenID(token.AccessToken),
+	}, nil
+}
+
+// DeleteKey does nothing: PagerDuty revokes tokens only given their value,
+// and the token expires on its own once its lifetime ends.
+func (p *Provider) DeleteKey(
+	ctx context.Context,
+	_ *v1alpha1.PagerDutyAPIToken,
+	keyID string,
+) error {
+	if keyID == "" {
+		return nil
+	}
+
+	log.FromContext(ctx).Info("token expires at PagerDuty", "keyId", keyID)
+	return nil
+}
+
+// accountScope returns the scope that selects the operator's account.
+func (p *Provider) accountScope() string {
+	return "as_account-" + p.region + "." + p.subdomain
+}
+
+// tokenID derives a key ID from a token, so that tokens can be told apart in
+// the status without exposing them.
+func tokenID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Endpoints returns the PagerDuty identity service. It implements
+// [framework.EndpointDeclarer].
+func (p *Provider) Endpoints() ([]framework.Endpoint, error) {
+	baseURL := p.baseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	e, err := framework.EndpointFromURL("PagerDuty identity service", baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return []framework.Endpoint{e}, nil
+}
+
+// initClient reads the configuration from the environment on first use,
+// unless it was set via options.
+func (p *Provider) initClient() error {
+	p.initOnce.Do(func() {
+		if p.baseURL == "" {
+			p.baseURL = DefaultBaseURL
+		}
+		if p.clientID == "" {
+			p.clientID = os.Getenv("PAGERDUTY_CLIENT_ID")
+			p.clientSecret = os.Getenv("PAGERDUTY_CLIENT_SECRET")
+		}
+		if p.subdomain == "" {
+			p.subdomain = os.Getenv("PAGERDUTY_SUBDOMAIN")
+			p.region = os.Getenv("PAGERDUTY_REGION")
+		}
+		if p.region == "" {
+			p.region = DefaultRegion
+		}
+		if p.clientID == "" || p.clientSecret == "" || p.subdomain == "" {
+			p.initErr = errors.New(
+				"PAGERDUTY_CLIENT_ID, PAGERDUTY_CLIENT_SECRET and PAGERDUTY_SUBDOMAIN must be set")
+			return
+		}
+		if p.client == nil {
+			p.client = &http.Client{Timeout: 30 * time.Second}
+		}
+	})
+	return p.initErr
+}
+
+// apiRequest posts a form to the OAuth token endpoint and decodes the JSON
+// response into out. Error responses are returned as [*apiError].
+func (p *Provider) apiRequest(ctx context.Context, form url.Values, out any) error {
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, p.baseURL+tokenPath, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		apiErr := &apiError{}
+		if json.Unmarshal(respBody, apiErr) != nil || apiErr.Err == "" {
+			apiErr.Description = string(respBody)
+		}
+		apiErr.Code = resp.StatusCode
+		return apiErr
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	return nil
+}
+
+// PagerDuty identity service response types.
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// apiError is an OAuth error response.
+type apiError struct {
+	Code        int    `json:"-"`
+	Err         string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *apiError) Error() string {
+	if e.Err != "" {
+		return fmt.Sprintf("PagerDuty API error (status %d %s): %s", e.Code, e.Err, e.Description)
+	}
+	return fmt.Sprintf("PagerDuty API error (status %d): %s", e.Code, e.Description)
+}
+
+// renderTemplate renders a Go template string with the given data.
+func renderTemplate(tmpl string, data map[string]string) (string, error) {
+	t, err := template.New("").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,200 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/provider-pagerduty/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newObj(template map[string]string) *v1alpha1.PagerDutyAPIToken {
+	return &v1alpha1.PagerDutyAPIToken{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "oncall"},
+		Spec: v1alpha1.PagerDutyAPITokenSpec{
+			Scopes:   []string{"incidents.read", "services.read"},
+			Template: template,
+		},
+	}
+}
+
+// fakeServer records request forms and responds with the status and body
+// returned by respond, or a token valid for a day if respond is nil.
+type fakeServer struct {
+	mu      sync.Mutex
+	forms   []url.Values
+	respond func(r *http.Request) (int, string)
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/oauth/token" {
+		http.Error(w, "unexpected request", http.StatusNotFound)
+		return
+	}
+	_ = r.ParseForm()
+	f.mu.Lock()
+	f.forms = append(f.forms, r.PostForm)
+	f.mu.Unlock()
+
+	status, resp := http.StatusOK,
+		`{"access_token":"pd-token","token_type":"bearer","expires_in":86400,"scope":"incidents.read services.read"}`
+	if f.respond != nil {
+		status, resp = f.respond(r)
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(resp))
+}
+
+// newTestProvider starts the fake server and returns a provider pointed at
+// it.
+func newTestProvider(t *testing.T, f *fakeServer) *Provider {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return New(
+		WithHTTPClient(srv.Client()),
+		WithBaseURL(srv.URL),
+		WithCredentials("client-id", "client-secret"),
+		WithAccount("acme", "eu"),
+	)
+}
+
+func TestProvision(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		f := &fakeServer{}
+		p := newTestProvider(t, f)
+
+		result, err := p.Provision(context.Background(), newObj(map[string]string{
+			"TOKEN": "{{ .Token }}",
+			"SCOPE": "{{ .Scope }}",
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.KeyID != tokenID("pd-token") || strings.Contains(result.KeyID, "pd-token") {
+			t.Fatalf("got keyID %q, want a digest of the token", result.KeyID)
+		}
+		if got := result.StringData["TOKEN"]; got != "pd-token" {
+			t.Fatalf("got TOKEN %q", got)
+		}
+		if got := result.StringData["SCOPE"]; got != "incidents.read services.read" {
+			t.Fatalf("got SCOPE %q", got)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != 24*time.Hour {
+			t.Fatalf("got validity %v, want the token lifetime", got)
+		}
+
+		if len(f.forms) != 1 {
+			t.Fatalf("got %d requests, want 1", len(f.forms))
+		}
+		form := f.forms[0]
+		if form.Get("grant_type") != "client_credentials" || form.Get("client_id") != "client-id" ||
+			form.Get("client_secret") != "client-secret" {
+			t.Fatalf("unexpected form: %v", form)
+		}
+		if got := form.Get("scope"); got != "as_account-eu.acme incidents.read services.read" {
+			t.Fatalf("got scope %q", got)
+		}
+	})
+
+	t.Run("shorter validity", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{})
+		obj := newObj(map[string]string{"K": "v"})
+		obj.Spec.Validity = &metav1.Duration{Duration: 12 * time.Hour}
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != 12*time.Hour {
+			t.Fatalf("got validity %v, want %v", got, 12*time.Hour)
+		}
+	})
+
+	t.Run("longer validity is capped to the token lifetime", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{})
+		obj := newObj(map[string]string{"K": "v"})
+		obj.Spec.Validity = &metav1.Duration{Duration: 48 * time.Hour}
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != 24*time.Hour {
+			t.Fatalf("got validity %v, want %v", got, 24*time.Hour)
+		}
+	})
+
+	t.Run("API error propagates", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{respond: func(*http.Request) (int, string) {
+			return http.StatusBadRequest, `{"error":"invalid_scope","error_description":"scope not granted"}`
+		}})
+		_, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "requesting token") || !strings.Contains(err.Error(), "invalid_scope") {
+			t.Fatalf("expected wrapped error, got: %v", err)
+		}
+	})
+
+	t.Run("bad template", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{})
+		_, err := p.Provision(context.Background(), newObj(map[string]string{
+			"BAD": "{{ .Unclosed",
+		}))
+		if err == nil {
+			t.Fatal("expected template error")
+		}
+		if !strings.Contains(err.Error(), "rendering template") {
+			t.Fatalf("expected 'rendering template' error, got: %v", err)
+		}
+	})
+}
+
+func TestDeleteKey(t *testing.T) {
+	f := &fakeServer{}
+	p := newTestProvider(t, f)
+	if err := p.DeleteKey(context.Background(), newObj(nil), tokenID("pd-token")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(f.forms) != 0 {
+		t.Fatalf("unexpected requests: %v", f.forms)
+	}
+}
+
+func TestInitClient(t *testing.T) {
+	t.Run("reads configuration from the environment", func(t *testing.T) {
+		t.Setenv("PAGERDUTY_CLIENT_ID", "env-id")
+		t.Setenv("PAGERDUTY_CLIENT_SECRET", "env-secret")
+		t.Setenv("PAGERDUTY_SUBDOMAIN", "acme")
+		t.Setenv("PAGERDUTY_REGION", "")
+
+		p := New()
+		if err := p.initClient(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p.baseURL != DefaultBaseURL || p.clientID != "env-id" || p.clientSecret != "env-secret" {
+			t.Fatalf("got baseURL %q and credentials %q/%q", p.baseURL, p.clientID, p.clientSecret)
+		}
+		if got := p.accountScope(); got != "as_account-us.acme" {
+			t.Fatalf("got account scope %q, want the default region", got)
+		}
+	})
+
+	t.Run("fails without configuration", func(t *testing.T) {
+		t.Setenv("PAGERDUTY_CLIENT_ID", "")
+		t.Setenv("PAGERDUTY_SUBDOMAIN", "")
+
+		p := New()
+		if err := p.initClient(); err == nil {
+			t.Fatal("expected error without configuration")
+		}
+	})
+}
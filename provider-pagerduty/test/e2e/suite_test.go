@@ -0,0 +1,176 @@
+package e2e
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cucumber/godog"
+	"github.com/cucumber/godog/colors"
+	"github.com/google/uuid"
+	"github.com/lukasngl/valet/framework/bddtest"
+	"github.com/lukasngl/valet/provider-pagerduty/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-pagerduty/internal"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var godogOpts = godog.Options{
+	Format:      "pretty",
+	Output:      colors.Colored(os.Stdout),
+	Paths:       []string{"../../features"},
+	Concurrency: 1,
+	Strict:      true,
+}
+
+func init() {
+	godog.BindFlags("godog.", flag.CommandLine, &godogOpts)
+}
+
+var testEnvCfg bddtest.Env
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	if len(flag.Args()) > 0 {
+		godogOpts.Paths = flag.Args()
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	testEnvCfg.Scheme = runtime.NewScheme()
+	_ = corev1.AddToScheme(testEnvCfg.Scheme)
+	_ = v1alpha1.AddToScheme(testEnvCfg.Scheme)
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths: []string{"../../config/crd"},
+		Scheme:            testEnvCfg.Scheme,
+	}
+	env.ControlPlane.GetAPIServer().Configure().
+		Append("advertise-address", "127.0.0.1").
+		Append("bind-address", "127.0.0.1")
+
+	cfg, err := env.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start envtest: %v\n", err)
+		os.Exit(1)
+	}
+	testEnvCfg.Cfg = cfg
+
+	code := m.Run()
+
+	_ = env.Stop()
+	os.Exit(code)
+}
+
+// TestMock runs all scenarios with a canned HTTP transport.
+func TestMock(t *testing.T) {
+	t.Setenv("TEST_PAGERDUTY_SCOPE", "incidents.read")
+
+	opts := godogOpts
+	status := godog.TestSuite{
+		Name: "provider-pagerduty-mock",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New(
+				internal.WithHTTPClient(&http.Client{Transport: &identityMock{}}),
+				internal.WithBaseURL("http://identity.mock"),
+				internal.WithCredentials("mock-client", "mock-secret"),
+				internal.WithAccount("valet-test", "us"),
+			)
+			shared := bddtest.New[*v1alpha1.PagerDutyAPIToken](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// TestE2E runs non-mock scenarios against a real PagerDuty account.
+func TestE2E(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e tests in short mode")
+	}
+
+	if os.Getenv("TEST_PAGERDUTY_SCOPE") == "" {
+		t.Skip("skipping e2e tests: TEST_PAGERDUTY_SCOPE not set")
+	}
+
+	opts := godogOpts
+	opts.Tags = "~@mock"
+	status := godog.TestSuite{
+		Name: "provider-pagerduty-e2e",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New()
+			shared := bddtest.New[*v1alpha1.PagerDutyAPIToken](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// identityMock is an [http.RoundTripper] that returns canned PagerDuty
+// identity service responses. Each token request returns a unique token valid
+// for a day with the requested scopes, unless a scope starts with "invalid".
+type identityMock struct{}
+
+func (m *identityMock) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || req.URL.Path != "/oauth/token" {
+		return errorResponse(http.StatusNotFound, "not_found", "unexpected request: %s %s", req.Method, req.URL.Path)
+	}
+	if err := req.ParseForm(); err != nil {
+		return errorResponse(http.StatusBadRequest, "invalid_request", "parsing request: %v", err)
+	}
+
+	// The first scope selects the account.
+	scopes := strings.Fields(req.PostForm.Get("scope"))
+	if len(scopes) < 2 || !strings.HasPrefix(scopes[0], "as_account-") {
+		return errorResponse(http.StatusBadRequest, "invalid_scope", "no account scope in %q", scopes)
+	}
+	for _, scope := range scopes[1:] {
+		if strings.HasPrefix(scope, "invalid") {
+			return errorResponse(http.StatusBadRequest, "invalid_scope", "scope %s is not granted", scope)
+		}
+	}
+
+	return jsonResponse(http.StatusOK, map[string]any{
+		"access_token": "fake-token-" + uuid.New().String(),
+		"token_type":   "bearer",
+		"expires_in":   86400,
+		"scope":        strings.Join(scopes[1:], " "),
+	})
+}
+
+// errorResponse returns an OAuth error response.
+func errorResponse(status int, code, format string, args ...any) (*http.Response, error) {
+	return jsonResponse(status, map[string]string{
+		"error":             code,
+		"error_description": fmt.Sprintf(format, args...),
+	})
+}
+
+func jsonResponse(status int, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(string(data))),
+	}, nil
+}
@@ -0,0 +1,307 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"regexp"
+	"text/template"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Character sets of generated passwords.
+const (
+	CharsetAlphanumeric = "Alphanumeric"
+	CharsetSymbols      = "Symbols"
+	CharsetHex          = "Hex"
+)
+
+// Characters of the charsets.
+const (
+	alphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	symbols      = "!#$%&()*+,-./:;<=>?@[]^_{|}~"
+)
+
+// Bounds of the password length and the number of passphrase words.
+const (
+	MinLength = 8
+	MaxLength = 1024
+	MinWords  = 4
+	MaxWords  = 64
+)
+
+// DefaultKey is the key of the password in the output Secret if none is
+// given.
+const DefaultKey = "password"
+
+// secretKeyPattern matches valid Secret data keys.
+var secretKeyPattern = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+func init() {
+	SchemeBuilder.Register(&RandomPassword{}, &RandomPasswordList{})
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=randpw,categories=valet
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// RandomPassword generates and rotates random passwords or passphrases.
+type RandomPassword struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	Spec RandomPasswordSpec `json:"spec,omitzero"`
+	// +optional
+	Status framework.ClientSecretStatus `json:"status,omitzero"`
+}
+
+// RandomPasswordSpec defines the desired state.
+type RandomPasswordSpec struct {
+	// SecretRef is the Kubernetes Secret to create/update with the generated
+	// password.
+	// Defaults to a Secret named after the resource.
+	// +optional
+	SecretRef framework.SecretReference `json:"secretRef,omitzero"`
+
+	// Suspend stops the rotation and cleanup of the credentials while set,
+	// e.g. during incidents or migrations. The output Secret keeps its
+	// current credentials.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DeletionPolicy controls what happens when the resource is deleted:
+	// Delete deletes its keys at the provider and the output Secret with it,
+	// Retain keeps both, e.g. to move the resource to another cluster.
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy framework.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// SecretTemplate sets labels, annotations and the type of the output
+	// Secret, e.g. for Reloader, External Secrets or Argo CD to match on.
+	// +optional
+	SecretTemplate framework.SecretTemplate `json:"secretTemplate,omitzero"`
+
+	// Targets are further Secrets the credentials are written to, each
+	// with a subset of the keys, its own template or in another namespace,
+	// so that consumers expecting different shapes share one credential.
+	// +optional
+	Targets framework.OutputTargets `json:"targets,omitempty"`
+
+	// Replication copies the output Secret into all namespaces matching a
+	// label selector that allow it, and keeps the copies in sync.
+	// +optional
+	Replication *framework.Replication `json:"replication,omitempty"`
+
+	// Key is the key of the password in the output Secret. Defaults to
+	// "password".
+	// +kubebuilder:validation:Pattern=`^[-._a-zA-Z0-9]+$`
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// Length is the number of characters of the passwords. Defaults to 32.
+	// +kubebuilder:validation:Minimum=8
+	// +kubebuilder:validation:Maximum=1024
+	// +optional
+	Length int32 `json:"length,omitempty"`
+
+	// Charset is the set of characters passwords are drawn from:
+	// Alphanumeric (A-Z, a-z, 0-9), Symbols (alphanumeric plus printable ASCII
+	// punctuation except quotes, backslash and backtick) or Hex (0-9, a-f).
+	// Defaults to Alphanumeric.
+	// +kubebuilder:validation:Enum=Alphanumeric;Symbols;Hex
+	// +optional
+	Charset string `json:"charset,omitempty"`
+
+	// Characters, if set, are the characters passwords are drawn from instead
+	// of a charset. Duplicates are ignored.
+	// +optional
+	Characters string `json:"characters,omitempty"`
+
+	// Words, if set, generates passphrases of this many pronounceable
+	// lowercase words instead of passwords. Each word carries about 20 bits of
+	// entropy. Cannot be combined with length, charset or characters.
+	// +kubebuilder:validation:Minimum=4
+	// +kubebuilder:validation:Maximum=64
+	// +optional
+	Words int32 `json:"words,omitempty"`
+
+	// Separator joins the words of passphrases. Defaults to "-".
+	// +kubebuilder:validation:MaxLength=4
+	// +optional
+	Separator *string `json:"separator,omitempty"`
+
+	// RotationInterval is how long each password is used before it is
+	// replaced. Defaults to 30 days (720h).
+	// +optional
+	RotationInterval *metav1.Duration `json:"rotationInterval,omitempty"`
+
+	// Template maps additional output secret keys to Go template strings,
+	// e.g. to render a connection string.
+	// Available template variables: .Password
+	// +optional
+	Template map[string]string `json:"template,omitempty"`
+}
+
+// PasswordKey returns the key of the password in the output Secret.
+func (s *RandomPasswordSpec) PasswordKey() string {
+	if s.Key == "" {
+		return DefaultKey
+	}
+	return s.Key
+}
+
+// GetSecretRef returns the reference to the target output Secret, defaulting
+// to a Secret named after the resource.
+func (c *RandomPassword) GetSecretRef() framework.SecretReference {
+	return c.Spec.SecretRef.OrDefault(c.Name)
+}
+
+// IsSuspended reports whether the rotation of the credentials is suspended.
+// It implements [framework.Suspender].
+func (c *RandomPassword) IsSuspended() bool {
+	return c.Spec.Suspend
+}
+
+// GetDeletionPolicy returns what happens to the keys and the output Secret
+// when the resource is deleted. It implements [framework.DeletionPolicyHolder].
+func (c *RandomPassword) GetDeletionPolicy() framework.DeletionPolicy {
+	return c.Spec.DeletionPolicy
+}
+
+// GetSecretTemplate returns the labels, annotations and type of the output
+// Secret. It implements [framework.SecretTemplateHolder].
+func (c *RandomPassword) GetSecretTemplate() framework.SecretTemplate {
+	return c.Spec.SecretTemplate
+}
+
+// GetTargets returns the further Secrets the credentials are written to.
+// It implements [framework.TargetHolder].
+func (c *RandomPassword) GetTargets() framework.OutputTargets {
+	return c.Spec.Targets
+}
+
+// GetReplication returns the replication of the output Secret, or nil.
+// It implements [framework.ReplicationHolder].
+func (c *RandomPassword) GetReplication() *framework.Replication {
+	return c.Spec.Replication
+}
+
+// GetStatus returns a pointer to the shared status.
+func (c *RandomPassword) GetStatus() *framework.ClientSecretStatus {
+	return &c.Status
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (c *RandomPassword) DeepCopyObject() runtime.Object {
+	cp := *c
+	cp.ObjectMeta = *c.DeepCopy()
+	cp.Status = c.Status.DeepCopy()
+	cp.Spec.SecretTemplate = c.Spec.SecretTemplate.DeepCopy()
+	cp.Spec.Targets = c.Spec.Targets.DeepCopy()
+	cp.Spec.Replication = c.Spec.Replication.DeepCopy()
+	if c.Spec.Separator != nil {
+		s := *c.Spec.Separator
+		cp.Spec.Separator = &s
+	}
+	if c.Spec.Template != nil {
+		cp.Spec.Template = make(map[string]string, len(c.Spec.Template))
+		for k, v := range c.Spec.Template {
+			cp.Spec.Template[k] = v
+		}
+	}
+	if c.Spec.RotationInterval != nil {
+		v := *c.Spec.RotationInterval
+		cp.Spec.RotationInterval = &v
+	}
+	return &cp
+}
+
+// Validate performs structural validation of the spec.
+func (c *RandomPassword) Validate() error {
+	if c.Spec.Key != "" && !secretKeyPattern.MatchString(c.Spec.Key) {
+		return fmt.Errorf("key %q is not a valid Secret key", c.Spec.Key)
+	}
+	if c.Spec.Length != 0 && (c.Spec.Length < MinLength || c.Spec.Length > MaxLength) {
+		return fmt.Errorf("length must be between %d and %d", MinLength, MaxLength)
+	}
+	switch c.Spec.Charset {
+	case "", CharsetAlphanumeric, CharsetSymbols, CharsetHex:
+	default:
+		return fmt.Errorf("charset %q must be Alphanumeric, Symbols or Hex", c.Spec.Charset)
+	}
+	if c.Spec.Characters != "" && len(uniqueRunes(c.Spec.Characters)) < 2 {
+		return fmt.Errorf("characters must hold at least 2 distinct characters")
+	}
+	if c.Spec.Words != 0 {
+		if c.Spec.Words < MinWords || c.Spec.Words > MaxWords {
+			return fmt.Errorf("words must be between %d and %d", MinWords, MaxWords)
+		}
+		if c.Spec.Length != 0 || c.Spec.Charset != "" || c.Spec.Characters != "" {
+			return fmt.Errorf("words cannot be combined with length, charset or characters")
+		}
+	}
+	if c.Spec.RotationInterval != nil && c.Spec.RotationInterval.Duration <= 0 {
+		return fmt.Errorf("rotationInterval must be positive")
+	}
+	if err := framework.ValidateTemplateKeys(c.Spec.Template, c.Spec.PasswordKey()); err != nil {
+		return err
+	}
+	for key, tmpl := range c.Spec.Template {
+		if _, err := template.New(key).Parse(tmpl); err != nil {
+			return fmt.Errorf("template %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// Alphabet returns the characters passwords are drawn from.
+func (s *RandomPasswordSpec) Alphabet() []rune {
+	if s.Characters != "" {
+		return uniqueRunes(s.Characters)
+	}
+	switch s.Charset {
+	case CharsetSymbols:
+		return []rune(alphanumeric + symbols)
+	case CharsetHex:
+		return []rune("0123456789abcdef")
+	default:
+		return []rune(alphanumeric)
+	}
+}
+
+// uniqueRunes returns the distinct runes of s in order of appearance.
+func uniqueRunes(s string) []rune {
+	seen := map[rune]bool{}
+	var runes []rune
+	for _, r := range s {
+		if !seen[r] {
+			seen[r] = true
+			runes = append(runes, r)
+		}
+	}
+	return runes
+}
+
+// +kubebuilder:object:root=true
+
+// RandomPasswordList contains a list of RandomPassword resources.
+type RandomPasswordList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RandomPassword `json:"items"`
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (c *RandomPasswordList) DeepCopyObject() runtime.Object {
+	cp := *c
+	if c.Items != nil {
+		cp.Items = make([]RandomPassword, len(c.Items))
+		for i := range c.Items {
+			cp.Items[i] = *c.Items[i].DeepCopyObject().(*RandomPassword)
+		}
+	}
+	return &cp
+}
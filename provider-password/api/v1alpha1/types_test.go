@@ -0,0 +1,180 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	valid := &RandomPassword{
+		Spec: RandomPasswordSpec{
+			SecretRef: framework.SecretReference{Name: "out"},
+			Length:    24,
+			Charset:   CharsetSymbols,
+			Template:  map[string]string{"DSN": "postgres://app:{{ .Password }}@db/app"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*RandomPassword)
+		wantErr string
+	}{
+		{name: "valid", modify: func(_ *RandomPassword) {}},
+		{
+			name: "passphrase",
+			modify: func(c *RandomPassword) {
+				c.Spec.Length, c.Spec.Charset, c.Spec.Words = 0, "", 6
+			},
+		},
+		{
+			name:   "custom characters",
+			modify: func(c *RandomPassword) { c.Spec.Charset, c.Spec.Characters = "", "0123456789" },
+		},
+		{
+			name:   "omitted secretRef",
+			modify: func(c *RandomPassword) { c.Spec.SecretRef.Name = "" },
+		},
+		{
+			name:    "invalid key",
+			modify:  func(c *RandomPassword) { c.Spec.Key = "db password" },
+			wantErr: "not a valid Secret key",
+		},
+		{
+			name:    "too short",
+			modify:  func(c *RandomPassword) { c.Spec.Length = 4 },
+			wantErr: "length",
+		},
+		{
+			name:    "unknown charset",
+			modify:  func(c *RandomPassword) { c.Spec.Charset = "Emoji" },
+			wantErr: "charset",
+		},
+		{
+			name:    "single character",
+			modify:  func(c *RandomPassword) { c.Spec.Characters = "aaaa" },
+			wantErr: "2 distinct characters",
+		},
+		{
+			name:    "too few words",
+			modify:  func(c *RandomPassword) { c.Spec.Length, c.Spec.Charset, c.Spec.Words = 0, "", 2 },
+			wantErr: "words must be between",
+		},
+		{
+			name:    "words with length",
+			modify:  func(c *RandomPassword) { c.Spec.Words = 6 },
+			wantErr: "cannot be combined",
+		},
+		{
+			name: "non-positive rotation interval",
+			modify: func(c *RandomPassword) {
+				c.Spec.RotationInterval = &metav1.Duration{}
+			},
+			wantErr: "rotationInterval",
+		},
+		{
+			name:    "template overwrites password",
+			modify:  func(c *RandomPassword) { c.Spec.Template = map[string]string{"password": "x"} },
+			wantErr: "reserved",
+		},
+		{
+			name: "template overwrites custom key",
+			modify: func(c *RandomPassword) {
+				c.Spec.Key = "token"
+				c.Spec.Template = map[string]string{"token": "x"}
+			},
+			wantErr: "reserved",
+		},
+		{
+			name:    "invalid template syntax",
+			modify:  func(c *RandomPassword) { c.Spec.Template = map[string]string{"bad": "{{ .Foo"} },
+			wantErr: "template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := valid.DeepCopyObject().(*RandomPassword)
+			tt.modify(obj)
+			err := obj.Validate()
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if got := err.Error(); !strings.Contains(got, tt.wantErr) {
+				t.Fatalf("error %q does not contain %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAlphabet(t *testing.T) {
+	tests := []struct {
+		spec RandomPasswordSpec
+		want string
+	}{
+		{spec: RandomPasswordSpec{}, want: alphanumeric},
+		{spec: RandomPasswordSpec{Charset: CharsetSymbols}, want: alphanumeric + symbols},
+		{spec: RandomPasswordSpec{Charset: CharsetHex}, want: "0123456789abcdef"},
+		{spec: RandomPasswordSpec{Charset: CharsetHex, Characters: "abcabc"}, want: "abc"},
+	}
+	for _, tt := range tests {
+		if got := string(tt.spec.Alphabet()); got != tt.want {
+			t.Errorf("Alphabet() of %+v = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestDeepCopyObject(t *testing.T) {
+	separator := "."
+	obj := &RandomPassword{
+		Spec: RandomPasswordSpec{
+			SecretRef:        framework.SecretReference{Name: "s"},
+			Separator:        &separator,
+			Template:         map[string]string{"K": "V"},
+			RotationInterval: &metav1.Duration{Duration: time.Hour},
+		},
+	}
+	obj.Status.Phase = framework.PhaseReady
+
+	cp := obj.DeepCopyObject().(*RandomPassword)
+
+	cp.Spec.Template["K"] = "changed"
+	if obj.Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not copy template map")
+	}
+
+	*cp.Spec.Separator = "changed"
+	if *obj.Spec.Separator != "." {
+		t.Fatal("DeepCopyObject did not copy separator")
+	}
+
+	cp.Spec.RotationInterval.Duration = time.Minute
+	if obj.Spec.RotationInterval.Duration != time.Hour {
+		t.Fatal("DeepCopyObject did not copy rotation interval")
+	}
+}
+
+func TestDeepCopyObjectList(t *testing.T) {
+	list := &RandomPasswordList{
+		Items: []RandomPassword{
+			{Spec: RandomPasswordSpec{Template: map[string]string{"K": "V"}}},
+		},
+	}
+
+	cp := list.DeepCopyObject().(*RandomPasswordList)
+	cp.Items[0].Spec.Template["K"] = "changed"
+	if list.Items[0].Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not deep copy list items")
+	}
+}
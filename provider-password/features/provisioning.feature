@@ -0,0 +1,128 @@
+Feature: Random Passwords
+  As a platform operator
+  I want the password provider to generate and rotate random passwords
+  So that applications get strong credentials without an external service
+
+  Background:
+    Given a Kubernetes cluster is running
+    And the CRDs are installed
+    And the operator is running
+
+  Scenario: Generate a password successfully
+    When I create a ClientSecret "test-password" with:
+      """yaml
+      spec:
+        secretRef:
+          name: test-password
+      """
+    Then the ClientSecret "test-password" should have phase "Ready" within 60 seconds
+    And a Secret "test-password" should exist
+    And the Secret "test-password" key "password" should match "^[A-Za-z0-9]{32}$"
+
+  Scenario: Length, charset and key are configurable
+    When I create a ClientSecret "hex-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: hex-test
+        key: token
+        length: 40
+        charset: Hex
+      """
+    Then the ClientSecret "hex-test" should have phase "Ready" within 60 seconds
+    And the Secret "hex-test" key "token" should match "^[0-9a-f]{40}$"
+
+  Scenario: Generate a passphrase
+    When I create a ClientSecret "passphrase-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: passphrase-test
+        words: 5
+        separator: " "
+      """
+    Then the ClientSecret "passphrase-test" should have phase "Ready" within 60 seconds
+    And the Secret "passphrase-test" key "password" should match "^([a-z]+ ){4}[a-z]+$"
+
+  Scenario: Templates add keys to the Secret
+    When I create a ClientSecret "template-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: template-test
+        template:
+          DSN: "postgres://app:{{ .Password }}@db/app"
+      """
+    Then the ClientSecret "template-test" should have phase "Ready" within 60 seconds
+    And the Secret "template-test" key "DSN" should match "^postgres://app:[A-Za-z0-9]{32}@db/app$"
+
+  Scenario: Invalid template syntax is rejected
+    When I create a ClientSecret "bad-template" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-template
+        template:
+          SECRET: "{{ .Invalid"
+      """
+    Then the ClientSecret "bad-template" should have phase "Failed" within 60 seconds
+    And the ClientSecret "bad-template" status should contain message "template"
+    And the Secret "bad-template" should not exist
+
+  Scenario: Passphrases cannot be combined with a charset
+    When I create a ClientSecret "mixed-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: mixed-test
+        words: 5
+        charset: Hex
+      """
+    Then the ClientSecret "mixed-test" should have phase "Failed" within 60 seconds
+    And the ClientSecret "mixed-test" status should contain message "cannot be combined"
+
+  Scenario: Delete RandomPassword cleans up resources
+    When I create a ClientSecret "delete-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: delete-test
+      """
+    Then the ClientSecret "delete-test" should have phase "Ready" within 60 seconds
+    And a Secret "delete-test" should exist
+    When I delete the ClientSecret "delete-test"
+    Then the ClientSecret "delete-test" should not exist within 60 seconds
+
+  Scenario: Expired passwords are rotated
+    When I create a ClientSecret "rotation-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rotation-test
+        rotationInterval: 1h
+      """
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the ClientSecret "rotation-test" should have 1 active keys
+    When I expire the credentials for ClientSecret "rotation-test"
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the Secret "rotation-test" key "password" should match "^[A-Za-z0-9]{32}$"
+
+  Scenario: Too short passwords are rejected
+    When I try to create a ClientSecret "short-password" with:
+      """yaml
+      spec:
+        secretRef:
+          name: short-password
+        length: 4
+      """
+    Then the operation should have failed with "length"
+
+  Scenario: Secret is owned by the RandomPassword
+    When I create a ClientSecret "ownership-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: ownership-test
+      """
+    Then the ClientSecret "ownership-test" should have phase "Ready" within 60 seconds
+    And the Secret "ownership-test" should be owned by ClientSecret "ownership-test"
@@ -0,0 +1,70 @@
+{ inputs, ... }:
+{
+  perSystem =
+    { config, pkgs, ... }:
+    let
+      valet = config.valet.lib;
+
+      provider-password = valet.mkGoModule {
+        pname = "provider-password";
+        subPackages = [ "provider-password/cmd" ];
+        postInstall = ''
+          mv $out/bin/cmd $out/bin/provider-password
+        '';
+        meta.mainProgram = "provider-password";
+      };
+
+      provider-password-compressed = pkgs.stdenvNoCC.mkDerivation {
+        inherit (provider-password) pname version meta;
+        dontUnpack = true;
+        nativeBuildInputs = [ pkgs.upx ];
+        buildPhase = ''
+          mkdir -p $out/bin
+          upx -o $out/bin/provider-password ${provider-password}/bin/provider-password
+        '';
+      };
+
+      image = valet.mkImage {
+        name = "provider-password";
+        entrypoint = "${provider-password-compressed}/bin/provider-password";
+      };
+    in
+    {
+      packages = {
+        inherit provider-password provider-password-compressed;
+        provider-password-image = image;
+      };
+
+      checks.provider-password-helm = valet.packageChart {
+        name = "provider-password";
+        src = "${inputs.self}/provider-password/charts/provider-password";
+      };
+
+      checks.provider-password-lint = valet.withPackageEnv provider-password {
+        name = "provider-password-lint";
+        extraBuildInputs = [ pkgs.golangci-lint ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          golangci-lint run --timeout 10m ./provider-password/...
+        '';
+      };
+
+      checks.provider-password-test = valet.withPackageEnv provider-password {
+        name = "provider-password-test";
+        extraBuildInputs = [
+          pkgs.gotestsum
+          pkgs.etcd
+          pkgs.kubernetes
+        ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum --format short-verbose -- -coverpkg=github.com/lukasngl/valet/framework/...,./... -coverprofile=coverage.txt ./provider-password/...
+        '';
+        installPhase = ''
+          mkdir -p $out
+          cp coverage.txt $out/
+        '';
+      };
+    };
+}
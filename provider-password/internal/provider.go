@@ -0,0 +1,176 @@
+// Package internal contains the random password provider implementation.
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-password/api/v1alpha1"
+)
+
+// DefaultRotationInterval is the default password rotation interval (30
+// days).
+const DefaultRotationInterval = 30 * 24 * time.Hour
+
+// DefaultLength is the default number of characters of passwords.
+const DefaultLength = 32
+
+// DefaultSeparator joins the words of passphrases by default.
+const DefaultSeparator = "-"
+
+// Syllables of passphrase words: a consonant followed by a vowel. Each word
+// has wordSyllables of them, i.e. log2(20*5)*3 ≈ 20 bits of entropy.
+const (
+	consonants    = "bcdfghjklmnprstvwxyz"
+	vowels        = "aeiou"
+	wordSyllables = 3
+)
+
+// Provider generates random passwords and passphrases. It implements
+// [framework.Provider] for [*v1alpha1.RandomPassword].
+//
+// Passwords are generated locally from a cryptographically secure random
+// source, so there is nothing to delete when they are rotated out.
+type Provider struct {
+	rand io.Reader
+}
+
+// Option configures a [Provider].
+type Option func(*Provider)
+
+// WithRandom sets the source of randomness. Defaults to [rand.Reader].
+// Useful for testing.
+func WithRandom(r io.Reader) Option {
+	return func(p *Provider) { p.rand = r }
+}
+
+// New creates a [Provider] with the given options.
+func New(opts ...Option) *Provider {
+	p := &Provider{rand: rand.Reader}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// NewObject returns a zero-value RandomPassword.
+func (p *Provider) NewObject() *v1alpha1.RandomPassword {
+	return &v1alpha1.RandomPassword{}
+}
+
+// Provision generates a new password or passphrase. The key ID is random and
+// unrelated to the password.
+func (p *Provider) Provision(
+	_ context.Context,
+	obj *v1alpha1.RandomPassword,
+) (*framework.Result, error) {
+	var password string
+	var err error
+	if obj.Spec.Words > 0 {
+		separator := DefaultSeparator
+		if obj.Spec.Separator != nil {
+			separator = *obj.Spec.Separator
+		}
+		password, err = p.passphrase(int(obj.Spec.Words), separator)
+	} else {
+		length := DefaultLength
+		if obj.Spec.Length > 0 {
+			length = int(obj.Spec.Length)
+		}
+		password, err = p.password(length, obj.Spec.Alphabet())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("generating password: %w", err)
+	}
+
+	data := map[string]string{obj.Spec.PasswordKey(): password}
+	templateData := map[string]string{"Password": password}
+	for key, tmpl := range obj.Spec.Template {
+		rendered, err := renderTemplate(tmpl, templateData)
+		if err != nil {
+			return nil, fmt.Errorf("rendering template %q: %w", key, err)
+		}
+		data[key] = rendered
+	}
+
+	interval := DefaultRotationInterval
+	if obj.Spec.RotationInterval != nil {
+		interval = obj.Spec.RotationInterval.Duration
+	}
+	now := time.Now()
+
+	return &framework.Result{
+		StringData:    data,
+		Fields:        map[string]string{framework.FieldPassword: password},
+		ProvisionedAt: now,
+		ValidUntil:    now.Add(interval),
+		KeyID:         uuid.New().String(),
+	}, nil
+}
+
+// DeleteKey is a no-op: generated passwords exist only in the output Secret.
+func (p *Provider) DeleteKey(context.Context, *v1alpha1.RandomPassword, string) error {
+	return nil
+}
+
+// Capabilities reports that there is nothing to delete, so the reconciler
+// drops expired passwords without calling DeleteKey.
+func (p *Provider) Capabilities() framework.Capabilities {
+	return framework.Capabilities{}
+}
+
+// password returns length characters drawn uniformly from alphabet.
+func (p *Provider) password(length int, alphabet []rune) (string, error) {
+	var b strings.Builder
+	for range length {
+		i, err := rand.Int(p.rand, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", err
+		}
+		b.WriteRune(alphabet[i.Int64()])
+	}
+	return b.String(), nil
+}
+
+// passphrase returns words pronounceable words joined by separator.
+func (p *Provider) passphrase(words int, separator string) (string, error) {
+	parts := make([]string, words)
+	for i := range parts {
+		var b strings.Builder
+		for range wordSyllables {
+			for _, letters := range []string{consonants, vowels} {
+				letter, err := p.password(1, []rune(letters))
+				if err != nil {
+					return "", err
+				}
+				b.WriteString(letter)
+			}
+		}
+		parts[i] = b.String()
+	}
+	return strings.Join(parts, separator), nil
+}
+
+// renderTemplate renders a Go template string with the given data.
+func renderTemplate(tmpl string, data map[string]string) (string, error) {
+	t, err := template.New("").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
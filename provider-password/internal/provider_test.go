@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-password/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newPassword(spec v1alpha1.RandomPasswordSpec) *v1alpha1.RandomPassword {
+	spec.SecretRef = framework.SecretReference{Name: "out"}
+	return &v1alpha1.RandomPassword{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "db"},
+		Spec:       spec,
+	}
+}
+
+func TestProvision(t *testing.T) {
+	separator := "."
+	tests := []struct {
+		name    string
+		spec    v1alpha1.RandomPasswordSpec
+		key     string
+		pattern string
+	}{
+		{
+			name:    "defaults",
+			key:     "password",
+			pattern: `^[A-Za-z0-9]{32}$`,
+		},
+		{
+			name:    "hex with custom key and length",
+			spec:    v1alpha1.RandomPasswordSpec{Key: "token", Length: 64, Charset: v1alpha1.CharsetHex},
+			key:     "token",
+			pattern: `^[0-9a-f]{64}$`,
+		},
+		{
+			name:    "symbols",
+			spec:    v1alpha1.RandomPasswordSpec{Length: 200, Charset: v1alpha1.CharsetSymbols},
+			key:     "password",
+			pattern: `^[A-Za-z0-9!#$%&()*+,\-./:;<=>?@\[\]^_{|}~]{200}$`,
+		},
+		{
+			name:    "custom characters",
+			spec:    v1alpha1.RandomPasswordSpec{Length: 16, Characters: "ab"},
+			key:     "password",
+			pattern: `^[ab]{16}$`,
+		},
+		{
+			name:    "passphrase",
+			spec:    v1alpha1.RandomPasswordSpec{Words: 5},
+			key:     "password",
+			pattern: `^([a-z]{6}-){4}[a-z]{6}$`,
+		},
+		{
+			name:    "passphrase with separator",
+			spec:    v1alpha1.RandomPasswordSpec{Words: 4, Separator: &separator},
+			key:     "password",
+			pattern: `^([a-z]{6}\.){3}[a-z]{6}$`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := New().Provision(context.Background(), newPassword(tt.spec))
+			if err != nil {
+				t.Fatalf("Provision: %v", err)
+			}
+			password := result.StringData[tt.key]
+			if !regexp.MustCompile(tt.pattern).MatchString(password) {
+				t.Errorf("password %q does not match %s", password, tt.pattern)
+			}
+			if len(result.StringData) != 1 {
+				t.Errorf("expected only %s in data, got %v", tt.key, result.StringData)
+			}
+			if result.KeyID == "" || strings.Contains(password, result.KeyID) {
+				t.Errorf("expected a random key ID, got %q", result.KeyID)
+			}
+		})
+	}
+}
+
+func TestProvisionGeneratesDistinctPasswords(t *testing.T) {
+	p := New()
+	obj := newPassword(v1alpha1.RandomPasswordSpec{})
+	seen := map[string]bool{}
+	for range 20 {
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		password := result.StringData["password"]
+		if seen[password] {
+			t.Fatalf("password %q generated twice", password)
+		}
+		seen[password] = true
+	}
+}
+
+func TestProvisionTemplatesAndRotation(t *testing.T) {
+	obj := newPassword(v1alpha1.RandomPasswordSpec{
+		RotationInterval: &metav1.Duration{Duration: time.Hour},
+		Template:         map[string]string{"DSN": "postgres://app:{{ .Password }}@db/app"},
+	})
+
+	result, err := New().Provision(context.Background(), obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "postgres://app:" + result.StringData["password"] + "@db/app"; result.StringData["DSN"] != want {
+		t.Errorf("DSN = %q, want %q", result.StringData["DSN"], want)
+	}
+	if got := result.ValidUntil.Sub(result.ProvisionedAt); got != time.Hour {
+		t.Errorf("validity = %s, want 1h", got)
+	}
+
+	obj.Spec.RotationInterval = nil
+	if result, err = New().Provision(context.Background(), obj); err != nil {
+		t.Fatal(err)
+	}
+	if got := result.ValidUntil.Sub(result.ProvisionedAt); got != DefaultRotationInterval {
+		t.Errorf("validity = %s, want %s", got, DefaultRotationInterval)
+	}
+
+	obj.Spec.Template = map[string]string{"bad": "{{ .Password | nope }}"}
+	if _, err := New().Provision(context.Background(), obj); err == nil || !strings.Contains(err.Error(), "template") {
+		t.Errorf("expected template error, got %v", err)
+	}
+}
+
+func TestProvisionRandomFailure(t *testing.T) {
+	boom := errors.New("boom")
+	p := New(WithRandom(iotest.ErrReader(boom)))
+
+	for _, spec := range []v1alpha1.RandomPasswordSpec{{}, {Words: 4}} {
+		if _, err := p.Provision(context.Background(), newPassword(spec)); !errors.Is(err, boom) {
+			t.Errorf("expected random source error, got %v", err)
+		}
+	}
+}
+
+func TestDeleteKey(t *testing.T) {
+	if err := New().DeleteKey(context.Background(), newPassword(v1alpha1.RandomPasswordSpec{}), "id"); err != nil {
+		t.Fatalf("DeleteKey: %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+//go:generate godogen
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/lukasngl/valet/framework/bddtest"
+	"github.com/lukasngl/valet/provider-password/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Suite holds per-scenario state for password-provider-specific steps.
+// Common steps are handled by the embedded [bddtest.Suite].
+type Suite struct {
+	*bddtest.Suite[*v1alpha1.RandomPassword]
+}
+
+//godogen:then ^the Secret "([^"]*)" key "([^"]*)" should match "([^"]*)"$
+func (s *Suite) theSecretKeyShouldMatch(_ context.Context, name, key, pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("compiling pattern: %w", err)
+	}
+
+	var secret corev1.Secret
+	if err := s.K8sClient.Get(s.Ctx, client.ObjectKey{Namespace: s.Namespace, Name: name}, &secret); err != nil {
+		return fmt.Errorf("getting secret: %w", err)
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return fmt.Errorf("secret has no key %q", key)
+	}
+	if !re.Match(value) {
+		return fmt.Errorf("value of key %q does not match %q", key, pattern)
+	}
+	return nil
+}
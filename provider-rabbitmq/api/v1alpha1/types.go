@@ -0,0 +1,257 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	SchemeBuilder.Register(&RabbitMQUser{}, &RabbitMQUserList{})
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=rmquser,categories=valet
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// RabbitMQUser provisions and rotates RabbitMQ users with generated passwords.
+type RabbitMQUser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	Spec RabbitMQUserSpec `json:"spec,omitzero"`
+	// +optional
+	Status framework.ClientSecretStatus `json:"status,omitzero"`
+}
+
+// RabbitMQUserSpec defines the desired state.
+type RabbitMQUserSpec struct {
+	// SecretRef is the Kubernetes Secret to create/update with the provisioned credentials.
+	// Defaults to a Secret named after the resource.
+	// +optional
+	SecretRef framework.SecretReference `json:"secretRef,omitzero"`
+
+	// Suspend stops the rotation and cleanup of the credentials while set,
+	// e.g. during incidents or migrations. The output Secret keeps its
+	// current credentials.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DeletionPolicy controls what happens when the resource is deleted:
+	// Delete deletes its keys at the provider and the output Secret with it,
+	// Retain keeps both, e.g. to move the resource to another cluster.
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy framework.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// SecretTemplate sets labels, annotations and the type of the output
+	// Secret, e.g. for Reloader, External Secrets or Argo CD to match on.
+	// +optional
+	SecretTemplate framework.SecretTemplate `json:"secretTemplate,omitzero"`
+
+	// Targets are further Secrets the credentials are written to, each
+	// with a subset of the keys, its own template or in another namespace,
+	// so that consumers expecting different shapes share one credential.
+	// +optional
+	Targets framework.OutputTargets `json:"targets,omitempty"`
+
+	// Replication copies the output Secret into all namespaces matching a
+	// label selector that allow it, and keeps the copies in sync.
+	// +optional
+	Replication *framework.Replication `json:"replication,omitempty"`
+
+	// Username is the prefix of the generated RabbitMQ users. Each rotation
+	// creates a new user named <username>-<suffix>, so the old and new
+	// credentials stay valid side by side until the old user is deleted.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Username string `json:"username"`
+
+	// Tags are the RabbitMQ user tags, e.g. "monitoring". Defaults to none.
+	// +optional
+	Tags []string `json:"tags,omitempty"`
+
+	// Permissions are the vhost permissions granted to each generated user.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	Permissions []VHostPermission `json:"permissions"`
+
+	// Validity is how long each user is used before it is rotated and
+	// deleted. Defaults to 90 days (2160h).
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// Rotation configures how credentials are rotated, e.g. how long a
+	// superseded credential stays valid.
+	// +optional
+	Rotation framework.RotationPolicy `json:"rotation,omitzero"`
+
+	// Template maps output secret keys to Go template strings.
+	// Available template variables: .Username, .Password, .PreviousUsername
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinProperties=1
+	Template map[string]string `json:"template"`
+}
+
+// VHostPermission grants a user access to a vhost. Configure, Write and Read
+// are regular expressions matched against resource names; "" grants nothing
+// and ".*" grants everything.
+type VHostPermission struct {
+	// VHost is the name of the virtual host.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	VHost string `json:"vhost"`
+	// +optional
+	Configure string `json:"configure"`
+	// +optional
+	Write string `json:"write"`
+	// +optional
+	Read string `json:"read"`
+}
+
+// GetSecretRef returns the reference to the target output Secret, defaulting
+// to a Secret named after the resource.
+func (r *RabbitMQUser) GetSecretRef() framework.SecretReference {
+	return r.Spec.SecretRef.OrDefault(r.Name)
+}
+
+// IsSuspended reports whether the rotation of the credentials is suspended.
+// It implements [framework.Suspender].
+func (r *RabbitMQUser) IsSuspended() bool {
+	return r.Spec.Suspend
+}
+
+// GetDeletionPolicy returns what happens to the keys and the output Secret
+// when the resource is deleted. It implements [framework.DeletionPolicyHolder].
+func (r *RabbitMQUser) GetDeletionPolicy() framework.DeletionPolicy {
+	return r.Spec.DeletionPolicy
+}
+
+// GetSecretTemplate returns the labels, annotations and type of the output
+// Secret. It implements [framework.SecretTemplateHolder].
+func (r *RabbitMQUser) GetSecretTemplate() framework.SecretTemplate {
+	return r.Spec.SecretTemplate
+}
+
+// GetTargets returns the further Secrets the credentials are written to.
+// It implements [framework.TargetHolder].
+func (r *RabbitMQUser) GetTargets() framework.OutputTargets {
+	return r.Spec.Targets
+}
+
+// GetReplication returns the replication of the output Secret, or nil.
+// It implements [framework.ReplicationHolder].
+func (r *RabbitMQUser) GetReplication() *framework.Replication {
+	return r.Spec.Replication
+}
+
+// GetValidity returns the requested validity of the credentials, or zero for
+// the provider's default. It implements [framework.ValidityRequester].
+func (r *RabbitMQUser) GetValidity() time.Duration {
+	if r.Spec.Validity == nil {
+		return 0
+	}
+	return r.Spec.Validity.Duration
+}
+
+// GetRotationPolicy returns the rotation policy of the credentials. It
+// implements [framework.RotationPolicyHolder].
+func (r *RabbitMQUser) GetRotationPolicy() framework.RotationPolicy {
+	return r.Spec.Rotation
+}
+
+// GetStatus returns a pointer to the shared status.
+func (r *RabbitMQUser) GetStatus() *framework.ClientSecretStatus {
+	return &r.Status
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (r *RabbitMQUser) DeepCopyObject() runtime.Object {
+	cp := *r
+	cp.ObjectMeta = *r.DeepCopy()
+	cp.Status = r.Status.DeepCopy()
+	cp.Spec.SecretTemplate = r.Spec.SecretTemplate.DeepCopy()
+	cp.Spec.Targets = r.Spec.Targets.DeepCopy()
+	cp.Spec.Replication = r.Spec.Replication.DeepCopy()
+	if r.Spec.Template != nil {
+		cp.Spec.Template = make(map[string]string, len(r.Spec.Template))
+		for k, v := range r.Spec.Template {
+			cp.Spec.Template[k] = v
+		}
+	}
+	if r.Spec.Validity != nil {
+		v := *r.Spec.Validity
+		cp.Spec.Validity = &v
+	}
+	cp.Spec.Tags = slices.Clone(r.Spec.Tags)
+	cp.Spec.Permissions = slices.Clone(r.Spec.Permissions)
+	return &cp
+}
+
+// Validate performs structural validation of the spec.
+func (r *RabbitMQUser) Validate() error {
+	if r.Spec.Username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if len(r.Spec.Permissions) == 0 {
+		return fmt.Errorf("permissions must have at least one entry")
+	}
+	seen := make(map[string]bool, len(r.Spec.Permissions))
+	for i, perm := range r.Spec.Permissions {
+		if perm.VHost == "" {
+			return fmt.Errorf("permissions[%d].vhost is required", i)
+		}
+		if seen[perm.VHost] {
+			return fmt.Errorf("permissions[%d]: duplicate vhost %q", i, perm.VHost)
+		}
+		seen[perm.VHost] = true
+		for field, expr := range map[string]string{
+			"configure": perm.Configure, "write": perm.Write, "read": perm.Read,
+		} {
+			if _, err := regexp.Compile(expr); err != nil {
+				return fmt.Errorf("permissions[%d].%s: %w", i, field, err)
+			}
+		}
+	}
+	if r.Spec.Validity != nil && r.Spec.Validity.Duration <= 0 {
+		return fmt.Errorf("validity must be positive")
+	}
+	if len(r.Spec.Template) == 0 {
+		return fmt.Errorf("template must have at least one entry")
+	}
+	for key, tmpl := range r.Spec.Template {
+		if _, err := template.New(key).Parse(tmpl); err != nil {
+			return fmt.Errorf("template %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:object:root=true
+
+// RabbitMQUserList contains a list of RabbitMQUser resources.
+type RabbitMQUserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RabbitMQUser `json:"items"`
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (r *RabbitMQUserList) DeepCopyObject() runtime.Object {
+	cp := *r
+	if r.Items != nil {
+		cp.Items = make([]RabbitMQUser, len(r.Items))
+		for i := range r.Items {
+			cp.Items[i] = *r.Items[i].DeepCopyObject().(*RabbitMQUser)
+		}
+	}
+	return &cp
+}
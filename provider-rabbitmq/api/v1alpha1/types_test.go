@@ -0,0 +1,145 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	valid := &RabbitMQUser{
+		Spec: RabbitMQUserSpec{
+			SecretRef: framework.SecretReference{Name: "out"},
+			Username:  "app",
+			Permissions: []VHostPermission{
+				{VHost: "/", Configure: "^app\\.", Write: ".*", Read: ".*"},
+			},
+			Template: map[string]string{"KEY": "{{ .Password }}"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*RabbitMQUser)
+		wantErr string
+	}{
+		{name: "valid", modify: func(_ *RabbitMQUser) {}},
+		{
+			name:   "omitted secretRef",
+			modify: func(r *RabbitMQUser) { r.Spec.SecretRef.Name = "" },
+		},
+		{
+			name:    "missing username",
+			modify:  func(r *RabbitMQUser) { r.Spec.Username = "" },
+			wantErr: "username",
+		},
+		{
+			name:    "missing permissions",
+			modify:  func(r *RabbitMQUser) { r.Spec.Permissions = nil },
+			wantErr: "permissions",
+		},
+		{
+			name:    "missing vhost",
+			modify:  func(r *RabbitMQUser) { r.Spec.Permissions[0].VHost = "" },
+			wantErr: "permissions[0].vhost",
+		},
+		{
+			name: "duplicate vhost",
+			modify: func(r *RabbitMQUser) {
+				r.Spec.Permissions = append(r.Spec.Permissions, VHostPermission{VHost: "/"})
+			},
+			wantErr: "duplicate vhost",
+		},
+		{
+			name:    "invalid permission regex",
+			modify:  func(r *RabbitMQUser) { r.Spec.Permissions[0].Read = "(" },
+			wantErr: "permissions[0].read",
+		},
+		{
+			name: "non-positive validity",
+			modify: func(r *RabbitMQUser) {
+				r.Spec.Validity = &metav1.Duration{Duration: -time.Hour}
+			},
+			wantErr: "validity",
+		},
+		{
+			name:    "empty template",
+			modify:  func(r *RabbitMQUser) { r.Spec.Template = nil },
+			wantErr: "template",
+		},
+		{
+			name:    "invalid template syntax",
+			modify:  func(r *RabbitMQUser) { r.Spec.Template = map[string]string{"bad": "{{ .Foo"} },
+			wantErr: "template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := valid.DeepCopyObject().(*RabbitMQUser)
+			tt.modify(obj)
+			err := obj.Validate()
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if got := err.Error(); !strings.Contains(got, tt.wantErr) {
+				t.Fatalf("error %q does not contain %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeepCopyObject(t *testing.T) {
+	validity := metav1.Duration{Duration: 48 * time.Hour}
+	obj := &RabbitMQUser{
+		Spec: RabbitMQUserSpec{
+			SecretRef:   framework.SecretReference{Name: "s"},
+			Username:    "a",
+			Template:    map[string]string{"K": "V"},
+			Validity:    &validity,
+			Permissions: []VHostPermission{{VHost: "/"}},
+		},
+	}
+	obj.Status.Phase = framework.PhaseReady
+
+	cp := obj.DeepCopyObject().(*RabbitMQUser)
+
+	cp.Spec.Template["K"] = "changed"
+	if obj.Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not copy template map")
+	}
+
+	cp.Spec.Permissions[0].VHost = "changed"
+	if obj.Spec.Permissions[0].VHost != "/" {
+		t.Fatal("DeepCopyObject did not copy permissions")
+	}
+
+	cp.Spec.Validity.Duration = time.Hour
+	if obj.Spec.Validity.Duration != 48*time.Hour {
+		t.Fatal("DeepCopyObject did not copy validity")
+	}
+}
+
+func TestDeepCopyObjectList(t *testing.T) {
+	list := &RabbitMQUserList{
+		Items: []RabbitMQUser{
+			{Spec: RabbitMQUserSpec{Username: "a"}},
+		},
+	}
+
+	cp := list.DeepCopyObject().(*RabbitMQUserList)
+	cp.Items[0].Spec.Username = "changed"
+	if list.Items[0].Spec.Username != "a" {
+		t.Fatal("DeepCopyObject did not deep copy list items")
+	}
+}
@@ -0,0 +1,127 @@
+Feature: RabbitMQ User Provisioning
+  As a platform operator
+  I want the RabbitMQ provider to provision and rotate users
+  So that applications can authenticate with RabbitMQ
+
+  Background:
+    Given a Kubernetes cluster is running
+    And the CRDs are installed
+    And the operator is running
+
+  Scenario: Provision a user successfully
+    When I create a ClientSecret "test-secret" with:
+      """yaml
+      spec:
+        secretRef:
+          name: test-secret
+        username: "$TEST_RABBITMQ_USERNAME"
+        permissions:
+          - vhost: "/"
+            read: ".*"
+        template:
+          USERNAME: "{{ .Username }}"
+          PASSWORD: "{{ .Password }}"
+      """
+    Then the ClientSecret "test-secret" should have phase "Ready" within 60 seconds
+    And a Secret "test-secret" should exist
+    And the Secret "test-secret" should contain key "USERNAME"
+    And the Secret "test-secret" should contain key "PASSWORD"
+
+  Scenario: Invalid template syntax is rejected
+    When I create a ClientSecret "bad-template" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-template
+        username: "$TEST_RABBITMQ_USERNAME"
+        permissions:
+          - vhost: "/"
+            read: ".*"
+        template:
+          SECRET: "{{ .Invalid"
+      """
+    Then the ClientSecret "bad-template" should have phase "Failed" within 60 seconds
+    And the ClientSecret "bad-template" status should contain message "template"
+    And the Secret "bad-template" should not exist
+
+  Scenario: Delete RabbitMQUser cleans up resources
+    When I create a ClientSecret "delete-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: delete-test
+        username: "$TEST_RABBITMQ_USERNAME"
+        permissions:
+          - vhost: "/"
+            read: ".*"
+        template:
+          PASSWORD: "{{ .Password }}"
+      """
+    Then the ClientSecret "delete-test" should have phase "Ready" within 60 seconds
+    And a Secret "delete-test" should exist
+    When I delete the ClientSecret "delete-test"
+    Then the ClientSecret "delete-test" should not exist within 60 seconds
+
+  Scenario: Expired users are rotated
+    When I create a ClientSecret "rotation-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rotation-test
+        username: "$TEST_RABBITMQ_USERNAME"
+        permissions:
+          - vhost: "/"
+            read: ".*"
+        template:
+          PASSWORD: "{{ .Password }}"
+      """
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the ClientSecret "rotation-test" should have 1 active keys
+    When I expire the credentials for ClientSecret "rotation-test"
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+
+  Scenario: Missing permissions are rejected
+    When I try to create a ClientSecret "no-permissions" with:
+      """yaml
+      spec:
+        secretRef:
+          name: no-permissions
+        username: "$TEST_RABBITMQ_USERNAME"
+        template:
+          PASSWORD: "{{ .Password }}"
+      """
+    Then the operation should have failed with "permissions"
+
+  Scenario: Secret is owned by the RabbitMQUser
+    When I create a ClientSecret "ownership-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: ownership-test
+        username: "$TEST_RABBITMQ_USERNAME"
+        permissions:
+          - vhost: "/"
+            read: ".*"
+        template:
+          PASSWORD: "{{ .Password }}"
+      """
+    Then the ClientSecret "ownership-test" should have phase "Ready" within 60 seconds
+    And the Secret "ownership-test" should be owned by ClientSecret "ownership-test"
+
+  @mock
+  Scenario: Unknown vhost is reported
+    When I create a ClientSecret "missing-vhost" with:
+      """yaml
+      spec:
+        secretRef:
+          name: missing-vhost
+        username: "$TEST_RABBITMQ_USERNAME"
+        permissions:
+          - vhost: "missing"
+            read: ".*"
+        template:
+          PASSWORD: "{{ .Password }}"
+      """
+    Then the ClientSecret "missing-vhost" should have phase "Failed" within 60 seconds
+    And the ClientSecret "missing-vhost" status should contain message "vhost_not_found"
+    And the Secret "missing-vhost" should not exist
@@ -0,0 +1,95 @@
+{ inputs, ... }:
+{
+  perSystem =
+    { config, pkgs, ... }:
+    let
+      valet = config.valet.lib;
+
+      provider-rabbitmq = valet.mkGoModule {
+        pname = "provider-rabbitmq";
+        subPackages = [ "provider-rabbitmq/cmd" ];
+        postInstall = ''
+          mv $out/bin/cmd $out/bin/provider-rabbitmq
+        '';
+        meta.mainProgram = "provider-rabbitmq";
+      };
+
+      provider-rabbitmq-compressed = pkgs.stdenvNoCC.mkDerivation {
+        inherit (provider-rabbitmq) pname version meta;
+        dontUnpack = true;
+        nativeBuildInputs = [ pkgs.upx ];
+        buildPhase = ''
+          mkdir -p $out/bin
+          upx -o $out/bin/provider-rabbitmq ${provider-rabbitmq}/bin/provider-rabbitmq
+        '';
+      };
+
+      image = valet.mkImage {
+        name = "provider-rabbitmq";
+        entrypoint = "${provider-rabbitmq-compressed}/bin/provider-rabbitmq";
+      };
+      e2e-test-rabbitmq = pkgs.writeShellApplication {
+        name = "e2e-test-rabbitmq";
+        runtimeInputs = [
+          pkgs.go
+          pkgs.gotestsum
+        ];
+        text = ''
+          export GOFLAGS="-mod=vendor"
+          if [ ! -d vendor ]; then
+            ln -sfn ${valet.workspaceVendor} vendor
+          fi
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum \
+            --format "''${GOTESTSUM_FORMAT:-short-verbose}" \
+            -- -run TestE2E -timeout 10m \
+            -coverpkg=github.com/lukasngl/valet/framework/...,./... \
+            -coverprofile="''${COVERAGE_FILE:-coverage-rabbitmq-e2e.txt}" \
+            ./provider-rabbitmq/...
+        '';
+      };
+    in
+    {
+      packages = {
+        inherit provider-rabbitmq provider-rabbitmq-compressed;
+        provider-rabbitmq-image = image;
+      };
+
+      apps.e2e-test-rabbitmq = {
+        type = "app";
+        program = "${e2e-test-rabbitmq}/bin/e2e-test-rabbitmq";
+      };
+
+      checks.provider-rabbitmq-helm = valet.packageChart {
+        name = "provider-rabbitmq";
+        src = "${inputs.self}/provider-rabbitmq/charts/provider-rabbitmq";
+      };
+
+      checks.provider-rabbitmq-lint = valet.withPackageEnv provider-rabbitmq {
+        name = "provider-rabbitmq-lint";
+        extraBuildInputs = [ pkgs.golangci-lint ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          golangci-lint run --timeout 10m ./provider-rabbitmq/...
+        '';
+      };
+
+      checks.provider-rabbitmq-test = valet.withPackageEnv provider-rabbitmq {
+        name = "provider-rabbitmq-test";
+        extraBuildInputs = [
+          pkgs.gotestsum
+          pkgs.etcd
+          pkgs.kubernetes
+        ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum --format short-verbose -- -short -coverpkg=github.com/lukasngl/valet/framework/...,./... -coverprofile=coverage.txt ./provider-rabbitmq/...
+        '';
+        installPhase = ''
+          mkdir -p $out
+          cp coverage.txt $out/
+        '';
+      };
+    };
+}
@@ -0,0 +1,331 @@
+// Package internal contains the RabbitMQ provider implementation.
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-rabbitmq/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultValidity is the default key rotation period (90 days).
+const DefaultValidity = 90 * 24 * time.Hour
+
+// Provider provisions RabbitMQ users using the management HTTP API.
+// It implements [framework.Provider] for [*v1alpha1.RabbitMQUser].
+type Provider struct {
+	client   *http.Client
+	baseURL  string
+	username string
+	password string
+	initOnce sync.Once
+	initErr  error
+}
+
+// Option configures a [Provider].
+type Option func(*Provider)
+
+// WithHTTPClient sets a custom HTTP client. Useful for testing with a mock
+// transport.
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Provider) { p.client = c }
+}
+
+// WithBaseURL sets the management API URL, e.g. http://rabbitmq:15672.
+// Defaults to the RABBITMQ_URL environment variable.
+func WithBaseURL(url string) Option {
+	return func(p *Provider) { p.baseURL = strings.TrimSuffix(url, "/") }
+}
+
+// WithCredentials sets the operator's management API credentials. Defaults
+// to the RABBITMQ_USERNAME and RABBITMQ_PASSWORD environment variables.
+func WithCredentials(username, password string) Option {
+	return func(p *Provider) { p.username, p.password = username, password }
+}
+
+// New creates a [Provider] with the given options.
+func New(opts ...Option) *Provider {
+	p := &Provider{}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// NewObject returns a zero-value RabbitMQUser.
+func (p *Provider) NewObject() *v1alpha1.RabbitMQUser {
+	return &v1alpha1.RabbitMQUser{}
+}
+
+// Provision creates a new user with a random password and grants it the
+// vhost permissions from the spec.
+func (p *Provider) Provision(
+	ctx context.Context,
+	obj *v1alpha1.RabbitMQUser,
+) (*framework.Result, error) {
+	if err := p.initClient(); err != nil {
+		return nil, err
+	}
+
+	validity := DefaultValidity
+	if obj.Spec.Validity != nil {
+		validity = obj.Spec.Validity.Duration
+	}
+
+	suffix, err := randomBytes(4)
+	if err != nil {
+		return nil, fmt.Errorf("generating username suffix: %w", err)
+	}
+	secret, err := randomBytes(32)
+	if err != nil {
+		return nil, fmt.Errorf("generating password: %w", err)
+	}
+	username := obj.Spec.Username + "-" + hex.EncodeToString(suffix)
+	password := base64.RawURLEncoding.EncodeToString(secret)
+
+	if err := p.apiRequest(ctx, http.MethodPut, userPath(username), createUserRequest{
+		Password: password,
+		Tags:     strings.Join(obj.Spec.Tags, ","),
+	}); err != nil {
+		return nil, fmt.Errorf("creating user %s: %w", username, err)
+	}
+
+	for _, perm := range obj.Spec.Permissions {
+		if err := p.apiRequest(ctx, http.MethodPut, permissionPath(perm.VHost, username), permissionRequest{
+			Configure: perm.Configure,
+			Write:     perm.Write,
+			Read:      perm.Read,
+		}); err != nil {
+			// Don't leave a half-configured user behind; it is not yet
+			// tracked in the status and would never be cleaned up.
+			if delErr := p.apiRequest(ctx, http.MethodDelete, userPath(username), nil); delErr != nil {
+				log.FromContext(ctx).Error(delErr, "failed to delete partially created user", "user", username)
+			}
+			return nil, fmt.Errorf("setting permissions of user %s on vhost %s: %w", username, perm.VHost, err)
+		}
+	}
+
+	now := time.Now()
+
+	// Consumers may need the replaced user while it is still valid, e.g. to
+	// migrate queues or close its connections.
+	templateData := map[string]string{
+		"Username":         username,
+		"Password":         password,
+		"PreviousUsername": obj.Status.CurrentIdentifier(),
+	}
+
+	data := make(map[string]string, len(obj.Spec.Template))
+	for key, tmpl := range obj.Spec.Template {
+		rendered, err := renderTemplate(tmpl, templateData)
+		if err != nil {
+			return nil, fmt.Errorf("rendering template %q: %w", key, err)
+		}
+		data[key] = rendered
+	}
+
+	return &framework.Result{
+		StringData: data,
+		Fields: map[string]string{
+			framework.FieldUsername: username,
+			framework.FieldPassword: password,
+		},
+		ProvisionedAt: now,
+		ValidUntil:    now.Add(validity),
+		KeyID:         username,
+		Identifier:    username,
+	}, nil
+}
+
+// DeleteKey deletes a generated user, which also revokes its permissions and
+// closes its connections. Returns nil if the user has already been deleted
+// (idempotent).
+func (p *Provider) DeleteKey(
+	ctx context.Context,
+	_ *v1alpha1.RabbitMQUser,
+	keyID string,
+) error {
+	if keyID == "" {
+		return nil
+	}
+
+	if err := p.initClient(); err != nil {
+		return err
+	}
+
+	if err := p.apiRequest(ctx, http.MethodDelete, userPath(keyID), nil); err != nil {
+		// User already deleted at the provider — not an error.
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			log.FromContext(ctx).Info("key already deleted", "keyId", keyID)
+			return nil
+		}
+		return fmt.Errorf("deleting user %s: %w", keyID, err)
+	}
+
+	return nil
+}
+
+// Endpoints returns the management API, unless its URL is not configured
+// yet. It implements [framework.EndpointDeclarer].
+func (p *Provider) Endpoints() ([]framework.Endpoint, error) {
+	baseURL := p.baseURL
+	if baseURL == "" {
+		baseURL = os.Getenv("RABBITMQ_URL")
+	}
+	if baseURL == "" {
+		return nil, nil
+	}
+
+	e, err := framework.EndpointFromURL("RabbitMQ management API", baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return []framework.Endpoint{e}, nil
+}
+
+// initClient reads the management API URL and credentials from the
+// environment on first use, unless they were set via [WithBaseURL] and
+// [WithCredentials].
+func (p *Provider) initClient() error {
+	p.initOnce.Do(func() {
+		if p.baseURL == "" {
+			p.baseURL = strings.TrimSuffix(os.Getenv("RABBITMQ_URL"), "/")
+		}
+		if p.username == "" {
+			p.username = os.Getenv("RABBITMQ_USERNAME")
+			p.password = os.Getenv("RABBITMQ_PASSWORD")
+		}
+		if p.baseURL == "" || p.username == "" {
+			p.initErr = errors.New("RABBITMQ_URL and RABBITMQ_USERNAME must be set")
+			return
+		}
+		if p.client == nil {
+			p.client = &http.Client{Timeout: 30 * time.Second}
+		}
+	})
+	return p.initErr
+}
+
+// userPath returns the API path of a user.
+func userPath(username string) string {
+	return "/api/users/" + url.PathEscape(username)
+}
+
+// permissionPath returns the API path of a user's permissions on a vhost.
+func permissionPath(vhost, username string) string {
+	return "/api/permissions/" + url.PathEscape(vhost) + "/" + url.PathEscape(username)
+}
+
+// apiRequest makes a request to the management API. Error responses are
+// returned as [*apiError].
+func (p *Provider) apiRequest(
+	ctx context.Context,
+	method, urlPath string,
+	body any,
+) error {
+	var reqBody io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshalling request body: %w", err)
+		}
+		reqBody = bytes.NewReader(jsonBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+urlPath, reqBody)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(p.username, p.password)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		apiErr := &apiError{}
+		if json.Unmarshal(respBody, apiErr) != nil || apiErr.Reason == "" {
+			apiErr.Reason = string(respBody)
+		}
+		apiErr.Code = resp.StatusCode
+		return apiErr
+	}
+
+	return nil
+}
+
+// Management API request/response types.
+
+type createUserRequest struct {
+	Password string `json:"password"`
+	Tags     string `json:"tags"`
+}
+
+type permissionRequest struct {
+	Configure string `json:"configure"`
+	Write     string `json:"write"`
+	Read      string `json:"read"`
+}
+
+// apiError is a management API error response.
+type apiError struct {
+	Code   int    `json:"-"`
+	Err    string `json:"error"`
+	Reason string `json:"reason"`
+}
+
+func (e *apiError) Error() string {
+	if e.Err != "" {
+		return fmt.Sprintf("RabbitMQ API error (status %d %s): %s", e.Code, e.Err, e.Reason)
+	}
+	return fmt.Sprintf("RabbitMQ API error (status %d): %s", e.Code, e.Reason)
+}
+
+// randomBytes returns n cryptographically random bytes.
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// renderTemplate renders a Go template string with the given data.
+func renderTemplate(tmpl string, data map[string]string) (string, error) {
+	t, err := template.New("").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,292 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/provider-rabbitmq/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newObj(template map[string]string) *v1alpha1.RabbitMQUser {
+	return &v1alpha1.RabbitMQUser{
+		Spec: v1alpha1.RabbitMQUserSpec{
+			Username: "app",
+			Tags:     []string{"monitoring", "policymaker"},
+			Permissions: []v1alpha1.VHostPermission{
+				{VHost: "/", Configure: "^app\\.", Write: ".*", Read: ".*"},
+				{VHost: "orders", Read: ".*"},
+			},
+			Template: template,
+		},
+	}
+}
+
+// request is a request received by the fake server.
+type request struct {
+	Method string
+	Path   string
+	Body   map[string]string
+}
+
+// fakeServer records requests and responds with the status returned by
+// respond, or 204 if respond is nil.
+type fakeServer struct {
+	mu       sync.Mutex
+	requests []request
+	respond  func(r *http.Request) (int, string)
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]string
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	f.mu.Lock()
+	f.requests = append(f.requests, request{r.Method, r.URL.EscapedPath(), body})
+	f.mu.Unlock()
+
+	status, resp := http.StatusNoContent, ""
+	if f.respond != nil {
+		status, resp = f.respond(r)
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(resp))
+}
+
+// newTestProvider starts the fake server and returns a provider pointed at
+// it.
+func newTestProvider(t *testing.T, f *fakeServer) *Provider {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+			t.Errorf("unexpected credentials %q/%q", user, pass)
+		}
+		f.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL), WithCredentials("admin", "secret"))
+}
+
+func TestProvision(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		f := &fakeServer{}
+		p := newTestProvider(t, f)
+
+		result, err := p.Provision(context.Background(), newObj(map[string]string{
+			"USERNAME": "{{ .Username }}",
+			"PASSWORD": "{{ .Password }}",
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		user := result.KeyID
+		if !strings.HasPrefix(user, "app-") {
+			t.Fatalf("got keyID %q, want app-<suffix>", user)
+		}
+		if len(f.requests) != 3 {
+			t.Fatalf("got %d requests, want 3: %+v", len(f.requests), f.requests)
+		}
+
+		create := f.requests[0]
+		if create.Method != http.MethodPut || create.Path != "/api/users/"+user {
+			t.Fatalf("unexpected create request: %s %s", create.Method, create.Path)
+		}
+		if create.Body["tags"] != "monitoring,policymaker" {
+			t.Fatalf("got tags %q", create.Body["tags"])
+		}
+		if got := result.StringData["PASSWORD"]; got == "" || got != create.Body["password"] {
+			t.Fatalf("got PASSWORD %q, want %q", got, create.Body["password"])
+		}
+		if got := result.StringData["USERNAME"]; got != user {
+			t.Fatalf("got USERNAME %q, want %q", got, user)
+		}
+
+		root := f.requests[1]
+		if root.Path != "/api/permissions/%2F/"+user || root.Body["configure"] != "^app\\." ||
+			root.Body["write"] != ".*" || root.Body["read"] != ".*" {
+			t.Fatalf("unexpected permissions request: %+v", root)
+		}
+		orders := f.requests[2]
+		if orders.Path != "/api/permissions/orders/"+user || orders.Body["read"] != ".*" ||
+			orders.Body["write"] != "" {
+			t.Fatalf("unexpected permissions request: %+v", orders)
+		}
+	})
+
+	t.Run("previous username", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{})
+		obj := newObj(map[string]string{"PREVIOUS": "{{ .PreviousUsername }}"})
+
+		first, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if first.Identifier != first.KeyID {
+			t.Fatalf("got identifier %q, want the username %q", first.Identifier, first.KeyID)
+		}
+		if got := first.StringData["PREVIOUS"]; got != "" {
+			t.Fatalf("got PREVIOUS %q before any rotation, want empty", got)
+		}
+
+		obj.Status.SetReady(1, first)
+		second, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := second.StringData["PREVIOUS"]; got != first.KeyID {
+			t.Fatalf("got PREVIOUS %q, want %q", got, first.KeyID)
+		}
+	})
+
+	t.Run("default validity", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{})
+		result, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != DefaultValidity {
+			t.Fatalf("got validity %v, want %v", got, DefaultValidity)
+		}
+	})
+
+	t.Run("custom validity", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{})
+		obj := newObj(map[string]string{"K": "v"})
+		obj.Spec.Validity = &metav1.Duration{Duration: 48 * time.Hour}
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != 48*time.Hour {
+			t.Fatalf("got validity %v, want %v", got, 48*time.Hour)
+		}
+	})
+
+	t.Run("permission failure deletes the user", func(t *testing.T) {
+		f := &fakeServer{respond: func(r *http.Request) (int, string) {
+			if strings.HasPrefix(r.URL.Path, "/api/permissions/") {
+				return http.StatusBadRequest, `{"error":"bad_request","reason":"vhost_not_found"}`
+			}
+			return http.StatusNoContent, ""
+		}}
+		p := newTestProvider(t, f)
+
+		_, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "on vhost /") || !strings.Contains(err.Error(), "vhost_not_found") {
+			t.Fatalf("expected wrapped permissions error, got: %v", err)
+		}
+		last := f.requests[len(f.requests)-1]
+		if last.Method != http.MethodDelete || last.Path != f.requests[0].Path {
+			t.Fatalf("expected the created user to be deleted, got %s %s", last.Method, last.Path)
+		}
+	})
+
+	t.Run("API error propagates", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{respond: func(*http.Request) (int, string) {
+			return http.StatusUnauthorized, `{"error":"not_authorised","reason":"Login failed"}`
+		}})
+		_, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "creating user app-") || !strings.Contains(err.Error(), "not_authorised") {
+			t.Fatalf("expected wrapped error, got: %v", err)
+		}
+	})
+
+	t.Run("bad template", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{})
+		_, err := p.Provision(context.Background(), newObj(map[string]string{
+			"BAD": "{{ .Unclosed",
+		}))
+		if err == nil {
+			t.Fatal("expected template error")
+		}
+		if !strings.Contains(err.Error(), "rendering template") {
+			t.Fatalf("expected 'rendering template' error, got: %v", err)
+		}
+	})
+}
+
+func TestDeleteKey(t *testing.T) {
+	t.Run("empty keyID is a no-op", func(t *testing.T) {
+		f := &fakeServer{}
+		p := newTestProvider(t, f)
+		if err := p.DeleteKey(context.Background(), newObj(nil), ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(f.requests) != 0 {
+			t.Fatalf("unexpected requests: %+v", f.requests)
+		}
+	})
+
+	t.Run("happy path", func(t *testing.T) {
+		f := &fakeServer{}
+		p := newTestProvider(t, f)
+		if err := p.DeleteKey(context.Background(), newObj(nil), "app-0011aabb"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(f.requests) != 1 || f.requests[0].Method != http.MethodDelete ||
+			f.requests[0].Path != "/api/users/app-0011aabb" {
+			t.Fatalf("unexpected requests: %+v", f.requests)
+		}
+	})
+
+	t.Run("already deleted is idempotent", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{respond: func(*http.Request) (int, string) {
+			return http.StatusNotFound, `{"error":"Object Not Found","reason":"Not Found"}`
+		}})
+		if err := p.DeleteKey(context.Background(), newObj(nil), "gone-user"); err != nil {
+			t.Fatalf("expected nil for already-deleted user, got: %v", err)
+		}
+	})
+
+	t.Run("other error propagates", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{respond: func(*http.Request) (int, string) {
+			return http.StatusInternalServerError, "boom"
+		}})
+		err := p.DeleteKey(context.Background(), newObj(nil), "app-0011aabb")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "deleting user app-0011aabb") || !strings.Contains(err.Error(), "boom") {
+			t.Fatalf("expected 'deleting user' error, got: %v", err)
+		}
+	})
+}
+
+func TestInitClient(t *testing.T) {
+	t.Run("reads configuration from the environment", func(t *testing.T) {
+		t.Setenv("RABBITMQ_URL", "http://rabbitmq:15672/")
+		t.Setenv("RABBITMQ_USERNAME", "admin")
+		t.Setenv("RABBITMQ_PASSWORD", "env-secret")
+
+		p := New()
+		if err := p.initClient(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p.baseURL != "http://rabbitmq:15672" || p.username != "admin" || p.password != "env-secret" {
+			t.Fatalf("got baseURL %q and credentials %q/%q", p.baseURL, p.username, p.password)
+		}
+	})
+
+	t.Run("fails without configuration", func(t *testing.T) {
+		t.Setenv("RABBITMQ_URL", "")
+		t.Setenv("RABBITMQ_USERNAME", "")
+
+		p := New()
+		if err := p.initClient(); err == nil {
+			t.Fatal("expected error without configuration")
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package e2e
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cucumber/godog"
+	"github.com/cucumber/godog/colors"
+	"github.com/lukasngl/valet/framework/bddtest"
+	"github.com/lukasngl/valet/provider-rabbitmq/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-rabbitmq/internal"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var godogOpts = godog.Options{
+	Format:      "pretty",
+	Output:      colors.Colored(os.Stdout),
+	Paths:       []string{"../../features"},
+	Concurrency: 1,
+	Strict:      true,
+}
+
+func init() {
+	godog.BindFlags("godog.", flag.CommandLine, &godogOpts)
+}
+
+var testEnvCfg bddtest.Env
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	if len(flag.Args()) > 0 {
+		godogOpts.Paths = flag.Args()
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	testEnvCfg.Scheme = runtime.NewScheme()
+	_ = corev1.AddToScheme(testEnvCfg.Scheme)
+	_ = v1alpha1.AddToScheme(testEnvCfg.Scheme)
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths: []string{"../../config/crd"},
+		Scheme:            testEnvCfg.Scheme,
+	}
+	env.ControlPlane.GetAPIServer().Configure().
+		Append("advertise-address", "127.0.0.1").
+		Append("bind-address", "127.0.0.1")
+
+	cfg, err := env.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start envtest: %v\n", err)
+		os.Exit(1)
+	}
+	testEnvCfg.Cfg = cfg
+
+	code := m.Run()
+
+	_ = env.Stop()
+	os.Exit(code)
+}
+
+// TestMock runs all scenarios with a canned HTTP transport.
+func TestMock(t *testing.T) {
+	t.Setenv("TEST_RABBITMQ_USERNAME", "valet-test")
+
+	opts := godogOpts
+	status := godog.TestSuite{
+		Name: "provider-rabbitmq-mock",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New(
+				internal.WithHTTPClient(&http.Client{Transport: &managementMock{}}),
+				internal.WithBaseURL("http://rabbitmq.mock"),
+				internal.WithCredentials("admin", "mock-password"),
+			)
+			shared := bddtest.New[*v1alpha1.RabbitMQUser](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// TestE2E runs non-mock scenarios against a real RabbitMQ broker.
+func TestE2E(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e tests in short mode")
+	}
+
+	if os.Getenv("TEST_RABBITMQ_USERNAME") == "" {
+		t.Skip("skipping e2e tests: TEST_RABBITMQ_USERNAME not set")
+	}
+
+	opts := godogOpts
+	opts.Tags = "~@mock"
+	status := godog.TestSuite{
+		Name: "provider-rabbitmq-e2e",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New()
+			shared := bddtest.New[*v1alpha1.RabbitMQUser](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// managementMock is an [http.RoundTripper] that returns canned management
+// API responses. User creation and deletion always succeed; setting
+// permissions fails for the vhost "missing" and succeeds otherwise.
+type managementMock struct{}
+
+func (m *managementMock) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Paths look like /api/users/{user} or /api/permissions/{vhost}/{user}.
+	parts := strings.Split(req.URL.EscapedPath(), "/")
+	switch {
+	case len(parts) == 4 && parts[2] == "users" &&
+		(req.Method == http.MethodPut || req.Method == http.MethodDelete):
+		return jsonResponse(http.StatusNoContent, nil)
+	case len(parts) == 5 && parts[2] == "permissions" && req.Method == http.MethodPut:
+		if parts[3] == "missing" {
+			return jsonResponse(http.StatusBadRequest, map[string]string{
+				"error":  "bad_request",
+				"reason": "vhost_not_found",
+			})
+		}
+		return jsonResponse(http.StatusNoContent, nil)
+	default:
+		return jsonResponse(http.StatusNotFound, map[string]string{
+			"error":  "Object Not Found",
+			"reason": fmt.Sprintf("unexpected request: %s %s", req.Method, req.URL.Path),
+		})
+	}
+}
+
+func jsonResponse(status int, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(string(data))),
+	}, nil
+}
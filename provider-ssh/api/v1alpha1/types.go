@@ -0,0 +1,394 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Key algorithms of the generated key pairs.
+const (
+	KeyAlgorithmEd25519 = "Ed25519"
+	KeyAlgorithmRSA     = "RSA"
+)
+
+// PublicKeyKey is the key of the public key in authorized_keys format in the
+// output Secret.
+const PublicKeyKey = "ssh-publickey"
+
+// DefaultCredentialsKey is the key of the API token in the Secret referenced
+// by [UploadSpec.CredentialsRef] if none is given.
+const DefaultCredentialsKey = "token"
+
+func init() {
+	SchemeBuilder.Register(&SSHKeyPair{}, &SSHKeyPairList{})
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=sshkp,categories=valet
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// SSHKeyPair generates and rotates SSH key pairs, optionally uploading the
+// public keys as deploy keys.
+type SSHKeyPair struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	Spec SSHKeyPairSpec `json:"spec,omitzero"`
+	// +optional
+	Status framework.ClientSecretStatus `json:"status,omitzero"`
+}
+
+// SSHKeyPairSpec defines the desired state.
+type SSHKeyPairSpec struct {
+	// SecretRef is the Kubernetes Secret to create/update with the generated
+	// key pair. It is created as a kubernetes.io/ssh-auth Secret holding the
+	// private key in OpenSSH format in ssh-privatekey and the public key in
+	// authorized_keys format in ssh-publickey.
+	// Defaults to a Secret named after the resource.
+	// +optional
+	SecretRef framework.SecretReference `json:"secretRef,omitzero"`
+
+	// Suspend stops the rotation and cleanup of the credentials while set,
+	// e.g. during incidents or migrations. The output Secret keeps its
+	// current credentials.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DeletionPolicy controls what happens when the resource is deleted:
+	// Delete deletes its keys at the provider and the output Secret with it,
+	// Retain keeps both, e.g. to move the resource to another cluster.
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy framework.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// SecretTemplate sets labels, annotations and the type of the output
+	// Secret, e.g. for Reloader, External Secrets or Argo CD to match on.
+	// +optional
+	SecretTemplate framework.SecretTemplate `json:"secretTemplate,omitzero"`
+
+	// Targets are further Secrets the credentials are written to, each
+	// with a subset of the keys, its own template or in another namespace,
+	// so that consumers expecting different shapes share one credential.
+	// +optional
+	Targets framework.OutputTargets `json:"targets,omitempty"`
+
+	// Replication copies the output Secret into all namespaces matching a
+	// label selector that allow it, and keeps the copies in sync.
+	// +optional
+	Replication *framework.Replication `json:"replication,omitempty"`
+
+	// KeyAlgorithm is the algorithm of the key pairs: Ed25519 or RSA (3072
+	// bits). Defaults to Ed25519.
+	// +kubebuilder:validation:Enum=Ed25519;RSA
+	// +optional
+	KeyAlgorithm string `json:"keyAlgorithm,omitempty"`
+
+	// Comment is the comment of the public keys and the title of uploaded
+	// keys. Defaults to <namespace>/<name> of this resource.
+	// +optional
+	Comment string `json:"comment,omitempty"`
+
+	// Validity is how long each key pair is used before it is rotated and its
+	// uploaded public key removed. Defaults to 30 days (720h).
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// Rotation configures how credentials are rotated, e.g. how long a
+	// superseded credential stays valid.
+	// +optional
+	Rotation framework.RotationPolicy `json:"rotation,omitzero"`
+
+	// Upload, if set, uploads each public key to a service, so that the
+	// private key grants access there.
+	// +optional
+	Upload *UploadSpec `json:"upload,omitempty"`
+
+	// Template maps additional output secret keys to Go template strings,
+	// e.g. to provide a known_hosts file alongside the key.
+	// Available template variables: .PrivateKey, .PublicKey, .Fingerprint
+	// +optional
+	Template map[string]string `json:"template,omitempty"`
+}
+
+// UploadSpec selects where public keys are uploaded to. Exactly one target
+// must be set.
+type UploadSpec struct {
+	// GitHub uploads the public keys as deploy keys of a GitHub repository.
+	// +optional
+	GitHub *GitHubTarget `json:"github,omitempty"`
+
+	// GitLab uploads the public keys as deploy keys of a GitLab project.
+	// +optional
+	GitLab *GitLabTarget `json:"gitlab,omitempty"`
+
+	// Endpoint uploads the public keys to an HTTPS endpoint managing
+	// authorized keys. Keys are added with a POST of {"title", "key"} to the
+	// URL and removed with a DELETE of the URL with the key's SHA256
+	// fingerprint in the "fingerprint" query parameter.
+	// +optional
+	Endpoint *EndpointTarget `json:"endpoint,omitempty"`
+
+	// CredentialsRef is a Secret in the same namespace holding the API token
+	// used to upload and remove keys. It is required for GitHub and GitLab
+	// and sent as bearer token to endpoints.
+	// +optional
+	CredentialsRef *CredentialsReference `json:"credentialsRef,omitempty"`
+}
+
+// GitHubTarget is a GitHub repository to add deploy keys to.
+type GitHubTarget struct {
+	// Repository is the repository as <owner>/<name>.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[^/]+/[^/]+$`
+	Repository string `json:"repository"`
+
+	// ReadWrite grants the deploy keys write access. Defaults to read-only.
+	// +optional
+	ReadWrite bool `json:"readWrite,omitempty"`
+
+	// URL is the API URL, for GitHub Enterprise Server. Defaults to
+	// https://api.github.com.
+	// +kubebuilder:validation:Pattern=`^https://`
+	// +optional
+	URL string `json:"url,omitempty"`
+}
+
+// GitLabTarget is a GitLab project to add deploy keys to.
+type GitLabTarget struct {
+	// Project is the ID or full path of the project, e.g. group/project.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Project string `json:"project"`
+
+	// ReadWrite grants the deploy keys push access. Defaults to read-only.
+	// +optional
+	ReadWrite bool `json:"readWrite,omitempty"`
+
+	// URL is the GitLab instance. Defaults to https://gitlab.com.
+	// +kubebuilder:validation:Pattern=`^https://`
+	// +optional
+	URL string `json:"url,omitempty"`
+}
+
+// EndpointTarget is an HTTPS endpoint managing authorized keys.
+type EndpointTarget struct {
+	// URL of the endpoint.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^https://`
+	URL string `json:"url"`
+}
+
+// CredentialsReference selects a key of a Secret in the namespace of the
+// resource.
+type CredentialsReference struct {
+	// Name of the Secret.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Key of the API token in the Secret. Defaults to "token".
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// GetSecretRef returns the reference to the target output Secret, defaulting
+// to a Secret named after the resource.
+func (k *SSHKeyPair) GetSecretRef() framework.SecretReference {
+	return k.Spec.SecretRef.OrDefault(k.Name)
+}
+
+// IsSuspended reports whether the rotation of the credentials is suspended.
+// It implements [framework.Suspender].
+func (k *SSHKeyPair) IsSuspended() bool {
+	return k.Spec.Suspend
+}
+
+// GetDeletionPolicy returns what happens to the keys and the output Secret
+// when the resource is deleted. It implements [framework.DeletionPolicyHolder].
+func (k *SSHKeyPair) GetDeletionPolicy() framework.DeletionPolicy {
+	return k.Spec.DeletionPolicy
+}
+
+// GetSecretTemplate returns the labels, annotations and type of the output
+// Secret. It implements [framework.SecretTemplateHolder].
+func (k *SSHKeyPair) GetSecretTemplate() framework.SecretTemplate {
+	return k.Spec.SecretTemplate
+}
+
+// GetTargets returns the further Secrets the credentials are written to.
+// It implements [framework.TargetHolder].
+func (k *SSHKeyPair) GetTargets() framework.OutputTargets {
+	return k.Spec.Targets
+}
+
+// GetReplication returns the replication of the output Secret, or nil.
+// It implements [framework.ReplicationHolder].
+func (k *SSHKeyPair) GetReplication() *framework.Replication {
+	return k.Spec.Replication
+}
+
+// GetValidity returns the requested validity of the credentials, or zero for
+// the provider's default. It implements [framework.ValidityRequester].
+func (k *SSHKeyPair) GetValidity() time.Duration {
+	if k.Spec.Validity == nil {
+		return 0
+	}
+	return k.Spec.Validity.Duration
+}
+
+// GetRotationPolicy returns the rotation policy of the credentials. It
+// implements [framework.RotationPolicyHolder].
+func (k *SSHKeyPair) GetRotationPolicy() framework.RotationPolicy {
+	return k.Spec.Rotation
+}
+
+// GetStatus returns a pointer to the shared status.
+func (k *SSHKeyPair) GetStatus() *framework.ClientSecretStatus {
+	return &k.Status
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (k *SSHKeyPair) DeepCopyObject() runtime.Object {
+	cp := *k
+	cp.ObjectMeta = *k.DeepCopy()
+	cp.Status = k.Status.DeepCopy()
+	cp.Spec.SecretTemplate = k.Spec.SecretTemplate.DeepCopy()
+	cp.Spec.Targets = k.Spec.Targets.DeepCopy()
+	cp.Spec.Replication = k.Spec.Replication.DeepCopy()
+	if k.Spec.Upload != nil {
+		upload := *k.Spec.Upload
+		if upload.GitHub != nil {
+			target := *upload.GitHub
+			upload.GitHub = &target
+		}
+		if upload.GitLab != nil {
+			target := *upload.GitLab
+			upload.GitLab = &target
+		}
+		if upload.Endpoint != nil {
+			target := *upload.Endpoint
+			upload.Endpoint = &target
+		}
+		if upload.CredentialsRef != nil {
+			ref := *upload.CredentialsRef
+			upload.CredentialsRef = &ref
+		}
+		cp.Spec.Upload = &upload
+	}
+	if k.Spec.Template != nil {
+		cp.Spec.Template = make(map[string]string, len(k.Spec.Template))
+		for key, v := range k.Spec.Template {
+			cp.Spec.Template[key] = v
+		}
+	}
+	if k.Spec.Validity != nil {
+		v := *k.Spec.Validity
+		cp.Spec.Validity = &v
+	}
+	return &cp
+}
+
+// Validate performs structural validation of the spec.
+func (k *SSHKeyPair) Validate() error {
+	switch k.Spec.KeyAlgorithm {
+	case "", KeyAlgorithmEd25519, KeyAlgorithmRSA:
+	default:
+		return fmt.Errorf("keyAlgorithm %q must be Ed25519 or RSA", k.Spec.KeyAlgorithm)
+	}
+	if strings.ContainsAny(k.Spec.Comment, "\r\n") {
+		return fmt.Errorf("comment must be a single line")
+	}
+	if k.Spec.Validity != nil && k.Spec.Validity.Duration <= 0 {
+		return fmt.Errorf("validity must be positive")
+	}
+	if k.Spec.Upload != nil {
+		if err := k.Spec.Upload.validate(); err != nil {
+			return err
+		}
+	}
+	if err := framework.ValidateTemplateKeys(k.Spec.Template, corev1.SSHAuthPrivateKey, PublicKeyKey); err != nil {
+		return err
+	}
+	for key, tmpl := range k.Spec.Template {
+		if _, err := template.New(key).Parse(tmpl); err != nil {
+			return fmt.Errorf("template %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func (u *UploadSpec) validate() error {
+	targets := 0
+	var urls []string
+	if u.GitHub != nil {
+		targets++
+		if owner, name, ok := strings.Cut(u.GitHub.Repository, "/"); !ok || owner == "" || name == "" ||
+			strings.Contains(name, "/") {
+			return fmt.Errorf("upload.github.repository %q must be <owner>/<name>", u.GitHub.Repository)
+		}
+		urls = append(urls, u.GitHub.URL)
+	}
+	if u.GitLab != nil {
+		targets++
+		if u.GitLab.Project == "" {
+			return fmt.Errorf("upload.gitlab.project is required")
+		}
+		urls = append(urls, u.GitLab.URL)
+	}
+	if u.Endpoint != nil {
+		targets++
+		if u.Endpoint.URL == "" {
+			return fmt.Errorf("upload.endpoint.url is required")
+		}
+		urls = append(urls, u.Endpoint.URL)
+	}
+	if targets != 1 {
+		return fmt.Errorf("upload must set exactly one of github, gitlab and endpoint")
+	}
+	for _, raw := range urls {
+		if raw == "" {
+			continue
+		}
+		if parsed, err := url.Parse(raw); err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			return fmt.Errorf("upload URL %q must be an https URL", raw)
+		}
+	}
+	if u.CredentialsRef != nil && u.CredentialsRef.Name == "" {
+		return fmt.Errorf("upload.credentialsRef.name is required")
+	}
+	if u.CredentialsRef == nil && u.Endpoint == nil {
+		return fmt.Errorf("upload.credentialsRef is required for GitHub and GitLab")
+	}
+	return nil
+}
+
+// +kubebuilder:object:root=true
+
+// SSHKeyPairList contains a list of SSHKeyPair resources.
+type SSHKeyPairList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SSHKeyPair `json:"items"`
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (k *SSHKeyPairList) DeepCopyObject() runtime.Object {
+	cp := *k
+	if k.Items != nil {
+		cp.Items = make([]SSHKeyPair, len(k.Items))
+		for i := range k.Items {
+			cp.Items[i] = *k.Items[i].DeepCopyObject().(*SSHKeyPair)
+		}
+	}
+	return &cp
+}
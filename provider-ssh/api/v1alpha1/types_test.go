@@ -0,0 +1,175 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	valid := &SSHKeyPair{
+		Spec: SSHKeyPairSpec{
+			SecretRef: framework.SecretReference{Name: "out"},
+			Upload: &UploadSpec{
+				GitHub:         &GitHubTarget{Repository: "acme/app"},
+				CredentialsRef: &CredentialsReference{Name: "github"},
+			},
+			Template: map[string]string{"known_hosts": "github.com ssh-ed25519 AAAA"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*SSHKeyPair)
+		wantErr string
+	}{
+		{name: "valid", modify: func(_ *SSHKeyPair) {}},
+		{
+			name:   "without upload",
+			modify: func(k *SSHKeyPair) { k.Spec.Upload = nil },
+		},
+		{
+			name: "GitLab",
+			modify: func(k *SSHKeyPair) {
+				k.Spec.Upload.GitHub = nil
+				k.Spec.Upload.GitLab = &GitLabTarget{Project: "group/project", URL: "https://gitlab.example.com"}
+			},
+		},
+		{
+			name: "endpoint without credentials",
+			modify: func(k *SSHKeyPair) {
+				k.Spec.Upload = &UploadSpec{Endpoint: &EndpointTarget{URL: "https://keys.example.com/authorized-keys"}}
+			},
+		},
+		{
+			name:   "omitted secretRef",
+			modify: func(k *SSHKeyPair) { k.Spec.SecretRef.Name = "" },
+		},
+		{
+			name:    "unknown key algorithm",
+			modify:  func(k *SSHKeyPair) { k.Spec.KeyAlgorithm = "DSA" },
+			wantErr: "keyAlgorithm",
+		},
+		{
+			name:    "multi-line comment",
+			modify:  func(k *SSHKeyPair) { k.Spec.Comment = "a\nb" },
+			wantErr: "single line",
+		},
+		{
+			name: "non-positive validity",
+			modify: func(k *SSHKeyPair) {
+				k.Spec.Validity = &metav1.Duration{Duration: -time.Hour}
+			},
+			wantErr: "validity",
+		},
+		{
+			name:    "no upload target",
+			modify:  func(k *SSHKeyPair) { k.Spec.Upload.GitHub = nil },
+			wantErr: "exactly one",
+		},
+		{
+			name: "several upload targets",
+			modify: func(k *SSHKeyPair) {
+				k.Spec.Upload.Endpoint = &EndpointTarget{URL: "https://keys.example.com"}
+			},
+			wantErr: "exactly one",
+		},
+		{
+			name:    "repository without owner",
+			modify:  func(k *SSHKeyPair) { k.Spec.Upload.GitHub.Repository = "app" },
+			wantErr: "<owner>/<name>",
+		},
+		{
+			name:    "plain HTTP URL",
+			modify:  func(k *SSHKeyPair) { k.Spec.Upload.GitHub.URL = "http://github.example.com/api/v3" },
+			wantErr: "https URL",
+		},
+		{
+			name:    "GitHub without credentials",
+			modify:  func(k *SSHKeyPair) { k.Spec.Upload.CredentialsRef = nil },
+			wantErr: "credentialsRef is required",
+		},
+		{
+			name:    "reserved template key",
+			modify:  func(k *SSHKeyPair) { k.Spec.Template = map[string]string{"ssh-privatekey": "x"} },
+			wantErr: "reserved",
+		},
+		{
+			name:    "invalid template syntax",
+			modify:  func(k *SSHKeyPair) { k.Spec.Template = map[string]string{"bad": "{{ .Foo"} },
+			wantErr: "template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := valid.DeepCopyObject().(*SSHKeyPair)
+			tt.modify(obj)
+			err := obj.Validate()
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if got := err.Error(); !strings.Contains(got, tt.wantErr) {
+				t.Fatalf("error %q does not contain %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeepCopyObject(t *testing.T) {
+	validity := metav1.Duration{Duration: 12 * time.Hour}
+	obj := &SSHKeyPair{
+		Spec: SSHKeyPairSpec{
+			SecretRef: framework.SecretReference{Name: "s"},
+			Upload: &UploadSpec{
+				GitHub:         &GitHubTarget{Repository: "acme/app"},
+				CredentialsRef: &CredentialsReference{Name: "github"},
+			},
+			Template: map[string]string{"K": "V"},
+			Validity: &validity,
+		},
+	}
+	obj.Status.Phase = framework.PhaseReady
+
+	cp := obj.DeepCopyObject().(*SSHKeyPair)
+
+	cp.Spec.Template["K"] = "changed"
+	if obj.Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not copy template map")
+	}
+
+	cp.Spec.Upload.GitHub.Repository = "changed"
+	cp.Spec.Upload.CredentialsRef.Name = "changed"
+	if obj.Spec.Upload.GitHub.Repository != "acme/app" || obj.Spec.Upload.CredentialsRef.Name != "github" {
+		t.Fatal("DeepCopyObject did not copy upload")
+	}
+
+	cp.Spec.Validity.Duration = time.Hour
+	if obj.Spec.Validity.Duration != 12*time.Hour {
+		t.Fatal("DeepCopyObject did not copy validity")
+	}
+}
+
+func TestDeepCopyObjectList(t *testing.T) {
+	list := &SSHKeyPairList{
+		Items: []SSHKeyPair{
+			{Spec: SSHKeyPairSpec{Template: map[string]string{"K": "V"}}},
+		},
+	}
+
+	cp := list.DeepCopyObject().(*SSHKeyPairList)
+	cp.Items[0].Spec.Template["K"] = "changed"
+	if list.Items[0].Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not deep copy list items")
+	}
+}
@@ -0,0 +1,49 @@
+// provider-ssh runs the SSH key pair valet provider.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-ssh/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-ssh/internal"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// Build metadata, set with -ldflags "-X main.version=..." at build time.
+// See [framework.NewBuildInfo] for the values used if they are not.
+var (
+	version  = "dev"
+	provider = ""
+	revision = ""
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// +kubebuilder:rbac:groups=valet.ngl.cx,resources=sshkeypairs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=valet.ngl.cx,resources=sshkeypairs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=valet.ngl.cx,resources=sshkeypairs/finalizers,verbs=update
+
+func run() error {
+	var opts framework.Options
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	return framework.Run(ctrl.SetupSignalHandler(), &opts, framework.Operator[*v1alpha1.SSHKeyPair]{
+		Name:        "provider-ssh",
+		BuildInfo:   framework.NewBuildInfo(provider, version, revision),
+		AddToScheme: v1alpha1.AddToScheme,
+		NewProvider: func(_ context.Context, mgr ctrl.Manager) (framework.Provider[*v1alpha1.SSHKeyPair], error) {
+			return internal.New(internal.WithKubeClient(mgr.GetClient())), nil
+		},
+	})
+}
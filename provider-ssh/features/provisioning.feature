@@ -0,0 +1,111 @@
+Feature: SSH Key Pairs
+  As a platform operator
+  I want the SSH provider to generate and rotate SSH key pairs
+  So that applications get short-lived SSH keys without handling them manually
+
+  Background:
+    Given a Kubernetes cluster is running
+    And the CRDs are installed
+    And the operator is running
+
+  Scenario: Generate a key pair successfully
+    When I create a ClientSecret "test-key" with:
+      """yaml
+      spec:
+        secretRef:
+          name: test-key
+      """
+    Then the ClientSecret "test-key" should have phase "Ready" within 60 seconds
+    And a Secret "test-key" should exist
+    And the Secret "test-key" should contain key "ssh-privatekey"
+    And the Secret "test-key" should contain key "ssh-publickey"
+    And the Secret "test-key" should hold an SSH key pair
+
+  Scenario: RSA keys and templates
+    When I create a ClientSecret "rsa-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rsa-test
+        keyAlgorithm: RSA
+        comment: deploy@example.com
+        template:
+          fingerprint: "{{ .Fingerprint }}"
+      """
+    Then the ClientSecret "rsa-test" should have phase "Ready" within 60 seconds
+    And the Secret "rsa-test" should contain key "fingerprint"
+    And the Secret "rsa-test" should hold an SSH key pair
+
+  Scenario: Invalid template syntax is rejected
+    When I create a ClientSecret "bad-template" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-template
+        template:
+          SECRET: "{{ .Invalid"
+      """
+    Then the ClientSecret "bad-template" should have phase "Failed" within 60 seconds
+    And the ClientSecret "bad-template" status should contain message "template"
+    And the Secret "bad-template" should not exist
+
+  Scenario: Missing upload credentials are reported
+    When I create a ClientSecret "no-credentials" with:
+      """yaml
+      spec:
+        secretRef:
+          name: no-credentials
+        upload:
+          github:
+            repository: acme/app
+          credentialsRef:
+            name: missing
+      """
+    Then the ClientSecret "no-credentials" should have phase "Failed" within 60 seconds
+    And the ClientSecret "no-credentials" status should contain message "credentials secret missing"
+    And the Secret "no-credentials" should not exist
+
+  Scenario: Delete SSHKeyPair cleans up resources
+    When I create a ClientSecret "delete-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: delete-test
+      """
+    Then the ClientSecret "delete-test" should have phase "Ready" within 60 seconds
+    And a Secret "delete-test" should exist
+    When I delete the ClientSecret "delete-test"
+    Then the ClientSecret "delete-test" should not exist within 60 seconds
+
+  Scenario: Expired key pairs are rotated
+    When I create a ClientSecret "rotation-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rotation-test
+      """
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the ClientSecret "rotation-test" should have 1 active keys
+    When I expire the credentials for ClientSecret "rotation-test"
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the Secret "rotation-test" should hold an SSH key pair
+
+  Scenario: Unknown key algorithms are rejected
+    When I try to create a ClientSecret "bad-algorithm" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-algorithm
+        keyAlgorithm: DSA
+      """
+    Then the operation should have failed with "keyAlgorithm"
+
+  Scenario: Secret is owned by the SSHKeyPair
+    When I create a ClientSecret "ownership-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: ownership-test
+      """
+    Then the ClientSecret "ownership-test" should have phase "Ready" within 60 seconds
+    And the Secret "ownership-test" should be owned by ClientSecret "ownership-test"
@@ -0,0 +1,70 @@
+{ inputs, ... }:
+{
+  perSystem =
+    { config, pkgs, ... }:
+    let
+      valet = config.valet.lib;
+
+      provider-ssh = valet.mkGoModule {
+        pname = "provider-ssh";
+        subPackages = [ "provider-ssh/cmd" ];
+        postInstall = ''
+          mv $out/bin/cmd $out/bin/provider-ssh
+        '';
+        meta.mainProgram = "provider-ssh";
+      };
+
+      provider-ssh-compressed = pkgs.stdenvNoCC.mkDerivation {
+        inherit (provider-ssh) pname version meta;
+        dontUnpack = true;
+        nativeBuildInputs = [ pkgs.upx ];
+        buildPhase = ''
+          mkdir -p $out/bin
+          upx -o $out/bin/provider-ssh ${provider-ssh}/bin/provider-ssh
+        '';
+      };
+
+      image = valet.mkImage {
+        name = "provider-ssh";
+        entrypoint = "${provider-ssh-compressed}/bin/provider-ssh";
+      };
+    in
+    {
+      packages = {
+        inherit provider-ssh provider-ssh-compressed;
+        provider-ssh-image = image;
+      };
+
+      checks.provider-ssh-helm = valet.packageChart {
+        name = "provider-ssh";
+        src = "${inputs.self}/provider-ssh/charts/provider-ssh";
+      };
+
+      checks.provider-ssh-lint = valet.withPackageEnv provider-ssh {
+        name = "provider-ssh-lint";
+        extraBuildInputs = [ pkgs.golangci-lint ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          golangci-lint run --timeout 10m ./provider-ssh/...
+        '';
+      };
+
+      checks.provider-ssh-test = valet.withPackageEnv provider-ssh {
+        name = "provider-ssh-test";
+        extraBuildInputs = [
+          pkgs.gotestsum
+          pkgs.etcd
+          pkgs.kubernetes
+        ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum --format short-verbose -- -coverpkg=github.com/lukasngl/valet/framework/...,./... -coverprofile=coverage.txt ./provider-ssh/...
+        '';
+        installPhase = ''
+          mkdir -p $out
+          cp coverage.txt $out/
+        '';
+      };
+    };
+}
@@ -0,0 +1,323 @@
+// Package internal contains the SSH key pair provider implementation.
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-ssh/api/v1alpha1"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultValidity is the default key rotation period (30 days).
+const DefaultValidity = 30 * 24 * time.Hour
+
+// rsaKeySize is the size of generated RSA keys in bits.
+const rsaKeySize = 3072
+
+// Provider generates SSH key pairs and optionally uploads their public keys
+// as deploy keys. It implements [framework.Provider] for
+// [*v1alpha1.SSHKeyPair].
+//
+// Key pairs are generated locally. The SHA256 fingerprint of the public key
+// serves as key ID, which is also how uploaded keys are found again to remove
+// them. Keys uploaded to a target that was since removed from the spec are
+// not removed.
+type Provider struct {
+	client *http.Client
+	kube   client.Client
+}
+
+// Option configures a [Provider].
+type Option func(*Provider)
+
+// WithHTTPClient sets a custom HTTP client. Useful for testing with a mock
+// transport.
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Provider) { p.client = c }
+}
+
+// WithKubeClient sets the Kubernetes client used to read upload credentials.
+// It is required for uploads.
+func WithKubeClient(c client.Client) Option {
+	return func(p *Provider) { p.kube = c }
+}
+
+// New creates a [Provider] with the given options.
+func New(opts ...Option) *Provider {
+	p := &Provider{}
+	for _, o := range opts {
+		o(p)
+	}
+	if p.client == nil {
+		p.client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return p
+}
+
+// NewObject returns a zero-value SSHKeyPair.
+func (p *Provider) NewObject() *v1alpha1.SSHKeyPair {
+	return &v1alpha1.SSHKeyPair{}
+}
+
+// Provision generates a new key pair and uploads its public key if the spec
+// asks for it.
+func (p *Provider) Provision(
+	ctx context.Context,
+	obj *v1alpha1.SSHKeyPair,
+) (*framework.Result, error) {
+	key, err := generateKey(obj.Spec.KeyAlgorithm)
+	if err != nil {
+		return nil, fmt.Errorf("generating key pair: %w", err)
+	}
+	pub, err := ssh.NewPublicKey(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("encoding public key: %w", err)
+	}
+
+	comment := obj.Spec.Comment
+	if comment == "" {
+		comment = obj.Namespace + "/" + obj.Name
+	}
+	block, err := ssh.MarshalPrivateKey(key, comment)
+	if err != nil {
+		return nil, fmt.Errorf("encoding private key: %w", err)
+	}
+	privateKey := string(pem.EncodeToMemory(block))
+	publicKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub))) + " " + comment
+	fingerprint := ssh.FingerprintSHA256(pub)
+
+	now := time.Now()
+	validity := DefaultValidity
+	if obj.Spec.Validity != nil {
+		validity = obj.Spec.Validity.Duration
+	}
+	validUntil := now.Add(validity)
+
+	data := map[string]string{
+		corev1.SSHAuthPrivateKey: privateKey,
+		v1alpha1.PublicKeyKey:    publicKey + "\n",
+	}
+	templateData := map[string]string{
+		"PrivateKey":  privateKey,
+		"PublicKey":   publicKey,
+		"Fingerprint": fingerprint,
+	}
+	for key, tmpl := range obj.Spec.Template {
+		rendered, err := renderTemplate(tmpl, templateData)
+		if err != nil {
+			return nil, fmt.Errorf("rendering template %q: %w", key, err)
+		}
+		data[key] = rendered
+	}
+
+	// Upload last, so that a key is not left behind when anything else
+	// fails.
+	if obj.Spec.Upload != nil {
+		target, err := p.target(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+		if err := target.add(ctx, comment, publicKey, validUntil); err != nil {
+			return nil, fmt.Errorf("uploading public key to %s: %w", target, err)
+		}
+	}
+
+	return &framework.Result{
+		StringData:    data,
+		ProvisionedAt: now,
+		ValidUntil:    validUntil,
+		KeyID:         fingerprint,
+		SecretType:    corev1.SecretTypeSSHAuth,
+	}, nil
+}
+
+// DeleteKey removes the uploaded public key with the given fingerprint. Keys
+// that were not uploaded or are already gone are skipped.
+func (p *Provider) DeleteKey(
+	ctx context.Context,
+	obj *v1alpha1.SSHKeyPair,
+	keyID string,
+) error {
+	if keyID == "" || obj.Spec.Upload == nil {
+		return nil
+	}
+
+	target, err := p.target(ctx, obj)
+	if err != nil {
+		return err
+	}
+	if err := target.remove(ctx, keyID); err != nil {
+		return fmt.Errorf("removing public key %s from %s: %w", keyID, target, err)
+	}
+	return nil
+}
+
+// generateKey generates a private key with the given algorithm, Ed25519 by
+// default.
+func generateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case v1alpha1.KeyAlgorithmRSA:
+		return rsa.GenerateKey(rand.Reader, rsaKeySize)
+	default:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	}
+}
+
+// target returns the upload target configured in the spec.
+func (p *Provider) target(ctx context.Context, obj *v1alpha1.SSHKeyPair) (target, error) {
+	upload := obj.Spec.Upload
+	token, err := p.token(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case upload.GitHub != nil:
+		return &githubTarget{api: p.api(token, "Authorization", "Bearer "+token), spec: upload.GitHub}, nil
+	case upload.GitLab != nil:
+		return &gitlabTarget{api: p.api(token, "PRIVATE-TOKEN", token), spec: upload.GitLab}, nil
+	case upload.Endpoint != nil:
+		return &endpointTarget{api: p.api(token, "Authorization", "Bearer "+token), url: upload.Endpoint.URL}, nil
+	default:
+		return nil, errors.New("no upload target configured")
+	}
+}
+
+// api returns an [api] that authenticates by setting header to value, unless
+// token is empty.
+func (p *Provider) api(token, header, value string) *api {
+	a := &api{client: p.client, header: http.Header{}}
+	if token != "" {
+		a.header.Set(header, value)
+	}
+	return a
+}
+
+// token reads the upload API token from the Secret referenced by the spec,
+// or returns "" if there is none.
+func (p *Provider) token(ctx context.Context, obj *v1alpha1.SSHKeyPair) (string, error) {
+	ref := obj.Spec.Upload.CredentialsRef
+	if ref == nil {
+		return "", nil
+	}
+	if p.kube == nil {
+		return "", errors.New("no Kubernetes client configured")
+	}
+	key := ref.Key
+	if key == "" {
+		key = v1alpha1.DefaultCredentialsKey
+	}
+
+	var secret corev1.Secret
+	if err := p.kube.Get(ctx, client.ObjectKey{Namespace: obj.Namespace, Name: ref.Name}, &secret); err != nil {
+		return "", fmt.Errorf("getting credentials secret %s: %w", ref.Name, err)
+	}
+	token := strings.TrimSpace(string(secret.Data[key]))
+	if token == "" {
+		return "", fmt.Errorf("credentials secret %s has no key %q", ref.Name, key)
+	}
+	return token, nil
+}
+
+// api sends JSON requests with fixed headers.
+type api struct {
+	client *http.Client
+	header http.Header
+}
+
+// do sends a request with an optional JSON body and decodes the JSON response
+// into out, if given. Error responses are returned as [*apiError].
+func (a *api) do(ctx context.Context, method, url string, body, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	for name, values := range a.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return &apiError{Code: resp.StatusCode, Body: strings.TrimSpace(string(respBody))}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	return nil
+}
+
+// apiError is an error response of an upload target.
+type apiError struct {
+	Code int
+	Body string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("API error (status %d): %s", e.Code, e.Body)
+}
+
+// isNotFound reports whether err is a 404 response.
+func isNotFound(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+// renderTemplate renders a Go template string with the given data.
+func renderTemplate(tmpl string, data map[string]string) (string, error) {
+	t, err := template.New("").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
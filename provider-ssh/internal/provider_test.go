@@ -0,0 +1,338 @@
+package internal
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lukasngl/valet/provider-ssh/api/v1alpha1"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeServer serves the deploy key APIs of GitHub at /repos/<owner>/<name>/keys
+// and GitLab at /api/v4/projects/<id>/deploy_keys, and an authorized keys
+// endpoint at /authorized-keys. All of them share one set of keys.
+type fakeServer struct {
+	mu       sync.Mutex
+	url      string
+	next     int64
+	keys     map[int64]map[string]any
+	requests []*http.Request
+	respond  func(r *http.Request) (int, string)
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r)
+
+	if f.respond != nil {
+		status, resp := f.respond(r)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(resp))
+		return
+	}
+
+	path := r.URL.EscapedPath()
+	isKeys := path == "/repos/acme/app/keys" || path == "/api/v4/projects/acme%2Fapp/deploy_keys"
+	switch {
+	case r.Method == http.MethodPost && (isKeys || path == "/authorized-keys"):
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.next++
+		body["id"] = f.next
+		f.keys[f.next] = body
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(body)
+	case r.Method == http.MethodGet && isKeys:
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		var keys []map[string]any
+		if page == 1 {
+			for _, k := range f.keys {
+				keys = append(keys, k)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(keys)
+	case r.Method == http.MethodDelete && path == "/authorized-keys":
+		for id, k := range f.keys {
+			pub, _, _, _, _ := ssh.ParseAuthorizedKey([]byte(k["key"].(string)))
+			if ssh.FingerprintSHA256(pub) == r.URL.Query().Get("fingerprint") {
+				delete(f.keys, id)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		http.Error(w, "not found", http.StatusNotFound)
+	case r.Method == http.MethodDelete:
+		id, _ := strconv.ParseInt(path[strings.LastIndex(path, "/")+1:], 10, 64)
+		if _, ok := f.keys[id]; !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		delete(f.keys, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unexpected request", http.StatusNotFound)
+	}
+}
+
+// newTestProvider starts the fake server and returns a provider pointed at
+// it and an object without upload. The namespace contains a Secret "api"
+// with an API token.
+func newTestProvider(t *testing.T, f *fakeServer) (*Provider, *v1alpha1.SSHKeyPair) {
+	t.Helper()
+	srv := httptest.NewTLSServer(f)
+	t.Cleanup(srv.Close)
+	f.url = srv.URL
+	f.keys = map[int64]map[string]any{}
+
+	kube := fake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "api"},
+		Data:       map[string][]byte{"token": []byte("api-token\n")},
+	}).Build()
+
+	obj := &v1alpha1.SSHKeyPair{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "web", UID: "uid-1"},
+	}
+
+	return New(WithHTTPClient(srv.Client()), WithKubeClient(kube)), obj
+}
+
+func TestProvision(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		p, obj := newTestProvider(t, &fakeServer{})
+		obj.Spec.Template = map[string]string{"FINGERPRINT": "{{ .Fingerprint }}"}
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.SecretType != corev1.SecretTypeSSHAuth {
+			t.Fatalf("got secret type %q, want %q", result.SecretType, corev1.SecretTypeSSHAuth)
+		}
+
+		signer, err := ssh.ParsePrivateKey([]byte(result.StringData[corev1.SSHAuthPrivateKey]))
+		if err != nil {
+			t.Fatalf("invalid private key: %v", err)
+		}
+		pub, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(result.StringData[v1alpha1.PublicKeyKey]))
+		if err != nil {
+			t.Fatalf("invalid public key: %v", err)
+		}
+		if string(pub.Marshal()) != string(signer.PublicKey().Marshal()) {
+			t.Fatal("public key does not match the private key")
+		}
+		if pub.Type() != ssh.KeyAlgoED25519 || comment != "apps/web" {
+			t.Fatalf("got %s key with comment %q, want ed25519 with apps/web", pub.Type(), comment)
+		}
+		if result.KeyID != ssh.FingerprintSHA256(pub) || result.StringData["FINGERPRINT"] != result.KeyID {
+			t.Fatalf("got keyID %q and data %v, want the fingerprint", result.KeyID, result.StringData)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != DefaultValidity {
+			t.Fatalf("got validity %v, want %v", got, DefaultValidity)
+		}
+	})
+
+	t.Run("RSA", func(t *testing.T) {
+		p, obj := newTestProvider(t, &fakeServer{})
+		obj.Spec.KeyAlgorithm = v1alpha1.KeyAlgorithmRSA
+		obj.Spec.Comment = "deploy@web"
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		key, err := ssh.ParseRawPrivateKey([]byte(result.StringData[corev1.SSHAuthPrivateKey]))
+		if err != nil {
+			t.Fatalf("invalid private key: %v", err)
+		}
+		if rsaKey, ok := key.(*rsa.PrivateKey); !ok || rsaKey.N.BitLen() != rsaKeySize {
+			t.Fatalf("got %T, want a %d bit RSA key", key, rsaKeySize)
+		}
+		if !strings.HasSuffix(result.StringData[v1alpha1.PublicKeyKey], " deploy@web\n") {
+			t.Fatalf("got public key %q, want the comment", result.StringData[v1alpha1.PublicKeyKey])
+		}
+	})
+
+	t.Run("no upload without target", func(t *testing.T) {
+		f := &fakeServer{}
+		p, obj := newTestProvider(t, f)
+
+		if _, err := p.Provision(context.Background(), obj); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(f.requests) != 0 {
+			t.Fatalf("got %d requests, want none", len(f.requests))
+		}
+	})
+
+	t.Run("missing credentials secret", func(t *testing.T) {
+		f := &fakeServer{}
+		p, obj := newTestProvider(t, f)
+		obj.Spec.Upload = &v1alpha1.UploadSpec{
+			GitHub:         &v1alpha1.GitHubTarget{Repository: "acme/app", URL: f.url},
+			CredentialsRef: &v1alpha1.CredentialsReference{Name: "missing"},
+		}
+
+		_, err := p.Provision(context.Background(), obj)
+		if err == nil || !strings.Contains(err.Error(), "credentials secret missing") {
+			t.Fatalf("expected credentials error, got: %v", err)
+		}
+	})
+
+	t.Run("API error propagates", func(t *testing.T) {
+		f := &fakeServer{respond: func(*http.Request) (int, string) {
+			return http.StatusUnprocessableEntity, `{"message":"key is already in use"}`
+		}}
+		p, obj := newTestProvider(t, f)
+		obj.Spec.Upload = &v1alpha1.UploadSpec{
+			GitHub:         &v1alpha1.GitHubTarget{Repository: "acme/app", URL: f.url},
+			CredentialsRef: &v1alpha1.CredentialsReference{Name: "api"},
+		}
+
+		_, err := p.Provision(context.Background(), obj)
+		if err == nil || !strings.Contains(err.Error(), "GitHub repository acme/app") ||
+			!strings.Contains(err.Error(), "already in use") {
+			t.Fatalf("expected wrapped error, got: %v", err)
+		}
+	})
+
+	t.Run("bad template", func(t *testing.T) {
+		f := &fakeServer{}
+		p, obj := newTestProvider(t, f)
+		obj.Spec.Upload = &v1alpha1.UploadSpec{Endpoint: &v1alpha1.EndpointTarget{URL: f.url + "/authorized-keys"}}
+		obj.Spec.Template = map[string]string{"BAD": "{{ .Unclosed"}
+
+		_, err := p.Provision(context.Background(), obj)
+		if err == nil || !strings.Contains(err.Error(), "rendering template") {
+			t.Fatalf("expected 'rendering template' error, got: %v", err)
+		}
+		if len(f.keys) != 0 {
+			t.Fatalf("got uploaded keys %v, want none", f.keys)
+		}
+	})
+}
+
+func TestUploadTargets(t *testing.T) {
+	tests := []struct {
+		name   string
+		upload func(url string) *v1alpha1.UploadSpec
+		check  func(t *testing.T, r *http.Request, body map[string]any)
+	}{
+		{
+			name: "GitHub",
+			upload: func(url string) *v1alpha1.UploadSpec {
+				return &v1alpha1.UploadSpec{
+					GitHub:         &v1alpha1.GitHubTarget{Repository: "acme/app", URL: url},
+					CredentialsRef: &v1alpha1.CredentialsReference{Name: "api"},
+				}
+			},
+			check: func(t *testing.T, r *http.Request, body map[string]any) {
+				if r.Header.Get("Authorization") != "Bearer api-token" {
+					t.Errorf("got authorization %q", r.Header.Get("Authorization"))
+				}
+				if body["read_only"] != true {
+					t.Errorf("got %v, want a read-only key", body)
+				}
+			},
+		},
+		{
+			name: "GitLab",
+			upload: func(url string) *v1alpha1.UploadSpec {
+				return &v1alpha1.UploadSpec{
+					GitLab:         &v1alpha1.GitLabTarget{Project: "acme/app", ReadWrite: true, URL: url},
+					CredentialsRef: &v1alpha1.CredentialsReference{Name: "api"},
+				}
+			},
+			check: func(t *testing.T, r *http.Request, body map[string]any) {
+				if r.Header.Get("PRIVATE-TOKEN") != "api-token" {
+					t.Errorf("got token %q", r.Header.Get("PRIVATE-TOKEN"))
+				}
+				if body["can_push"] != true || body["expires_at"] == "" {
+					t.Errorf("got %v, want a key with push access and expiry", body)
+				}
+			},
+		},
+		{
+			name: "endpoint",
+			upload: func(url string) *v1alpha1.UploadSpec {
+				return &v1alpha1.UploadSpec{Endpoint: &v1alpha1.EndpointTarget{URL: url + "/authorized-keys"}}
+			},
+			check: func(t *testing.T, r *http.Request, _ map[string]any) {
+				if r.Header.Get("Authorization") != "" {
+					t.Errorf("got authorization %q, want none", r.Header.Get("Authorization"))
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeServer{}
+			p, obj := newTestProvider(t, f)
+			obj.Spec.Upload = tt.upload(f.url)
+			ctx := context.Background()
+
+			var keyIDs []string
+			for range 2 {
+				result, err := p.Provision(ctx, obj)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				keyIDs = append(keyIDs, result.KeyID)
+			}
+			if len(f.keys) != 2 {
+				t.Fatalf("got %d uploaded keys, want 2", len(f.keys))
+			}
+			key := f.keys[1]
+			if key["title"] != "apps/web" || !strings.HasPrefix(key["key"].(string), ssh.KeyAlgoED25519+" ") {
+				t.Fatalf("unexpected uploaded key %v", key)
+			}
+			tt.check(t, f.requests[0], key)
+
+			if err := p.DeleteKey(ctx, obj, keyIDs[0]); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, ok := f.keys[1]; ok || len(f.keys) != 1 {
+				t.Fatalf("got keys %v, want only the second", f.keys)
+			}
+
+			// Keys that are already gone are skipped.
+			if err := p.DeleteKey(ctx, obj, keyIDs[0]); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDeleteKeyWithoutUpload(t *testing.T) {
+	f := &fakeServer{}
+	p, obj := newTestProvider(t, f)
+
+	if err := p.DeleteKey(context.Background(), obj, "SHA256:abc"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(f.requests) != 0 {
+		t.Fatalf("got %d requests, want none", len(f.requests))
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	key, err := generateKey("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := key.(ed25519.PrivateKey); !ok {
+		t.Fatalf("got %T, want an Ed25519 key by default", key)
+	}
+}
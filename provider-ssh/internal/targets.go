@@ -0,0 +1,177 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lukasngl/valet/provider-ssh/api/v1alpha1"
+	"golang.org/x/crypto/ssh"
+)
+
+// Default API URLs of the upload targets.
+const (
+	DefaultGitHubURL = "https://api.github.com"
+	DefaultGitLabURL = "https://gitlab.com"
+)
+
+// listPageSize is the number of deploy keys requested per page.
+const listPageSize = 100
+
+// target is a service public keys are uploaded to.
+type target interface {
+	fmt.Stringer
+
+	// add uploads a public key in authorized_keys format. Targets that
+	// support it let the key expire at expiresAt.
+	add(ctx context.Context, title, publicKey string, expiresAt time.Time) error
+
+	// remove removes the public key with the given SHA256 fingerprint. A key
+	// that does not exist counts as removed.
+	remove(ctx context.Context, fingerprint string) error
+}
+
+// deployKey is a deploy key as listed by GitHub and GitLab.
+type deployKey struct {
+	ID  int64  `json:"id"`
+	Key string `json:"key"`
+}
+
+// findDeployKey pages through the deploy keys at listURL and returns the ID
+// of the one with the given fingerprint, or false if there is none.
+func findDeployKey(ctx context.Context, a *api, listURL, fingerprint string) (int64, bool, error) {
+	for page := 1; ; page++ {
+		var keys []deployKey
+		pageURL := fmt.Sprintf("%s?per_page=%d&page=%d", listURL, listPageSize, page)
+		if err := a.do(ctx, http.MethodGet, pageURL, nil, &keys); err != nil {
+			return 0, false, fmt.Errorf("listing deploy keys: %w", err)
+		}
+		for _, k := range keys {
+			pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(k.Key))
+			if err != nil {
+				continue
+			}
+			if ssh.FingerprintSHA256(pub) == fingerprint {
+				return k.ID, true, nil
+			}
+		}
+		if len(keys) < listPageSize {
+			return 0, false, nil
+		}
+	}
+}
+
+// githubTarget manages the deploy keys of a GitHub repository.
+type githubTarget struct {
+	api  *api
+	spec *v1alpha1.GitHubTarget
+}
+
+func (t *githubTarget) String() string {
+	return "GitHub repository " + t.spec.Repository
+}
+
+func (t *githubTarget) keysURL() string {
+	base := t.spec.URL
+	if base == "" {
+		base = DefaultGitHubURL
+	}
+	return strings.TrimSuffix(base, "/") + "/repos/" + t.spec.Repository + "/keys"
+}
+
+func (t *githubTarget) add(ctx context.Context, title, publicKey string, _ time.Time) error {
+	return t.api.do(ctx, http.MethodPost, t.keysURL(), map[string]any{
+		"title":     title,
+		"key":       publicKey,
+		"read_only": !t.spec.ReadWrite,
+	}, nil)
+}
+
+func (t *githubTarget) remove(ctx context.Context, fingerprint string) error {
+	id, ok, err := findDeployKey(ctx, t.api, t.keysURL(), fingerprint)
+	if err != nil || !ok {
+		return err
+	}
+	err = t.api.do(ctx, http.MethodDelete, t.keysURL()+"/"+strconv.FormatInt(id, 10), nil, nil)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// gitlabTarget manages the deploy keys of a GitLab project.
+type gitlabTarget struct {
+	api  *api
+	spec *v1alpha1.GitLabTarget
+}
+
+func (t *gitlabTarget) String() string {
+	return "GitLab project " + t.spec.Project
+}
+
+func (t *gitlabTarget) keysURL() string {
+	base := t.spec.URL
+	if base == "" {
+		base = DefaultGitLabURL
+	}
+	return strings.TrimSuffix(base, "/") + "/api/v4/projects/" + url.PathEscape(t.spec.Project) + "/deploy_keys"
+}
+
+func (t *gitlabTarget) add(ctx context.Context, title, publicKey string, expiresAt time.Time) error {
+	return t.api.do(ctx, http.MethodPost, t.keysURL(), map[string]any{
+		"title":      title,
+		"key":        publicKey,
+		"can_push":   t.spec.ReadWrite,
+		"expires_at": expiresAt.UTC().Format(time.RFC3339),
+	}, nil)
+}
+
+func (t *gitlabTarget) remove(ctx context.Context, fingerprint string) error {
+	id, ok, err := findDeployKey(ctx, t.api, t.keysURL(), fingerprint)
+	if err != nil || !ok {
+		return err
+	}
+	err = t.api.do(ctx, http.MethodDelete, t.keysURL()+"/"+strconv.FormatInt(id, 10), nil, nil)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// endpointTarget manages the keys of a generic authorized keys endpoint, see
+// [v1alpha1.UploadSpec.Endpoint].
+type endpointTarget struct {
+	api *api
+	url string
+}
+
+func (t *endpointTarget) String() string {
+	return "endpoint " + t.url
+}
+
+func (t *endpointTarget) add(ctx context.Context, title, publicKey string, _ time.Time) error {
+	return t.api.do(ctx, http.MethodPost, t.url, map[string]string{
+		"title": title,
+		"key":   publicKey,
+	}, nil)
+}
+
+func (t *endpointTarget) remove(ctx context.Context, fingerprint string) error {
+	u, err := url.Parse(t.url)
+	if err != nil {
+		return fmt.Errorf("parsing endpoint URL: %w", err)
+	}
+	query := u.Query()
+	query.Set("fingerprint", fingerprint)
+	u.RawQuery = query.Encode()
+
+	err = t.api.do(ctx, http.MethodDelete, u.String(), nil, nil)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
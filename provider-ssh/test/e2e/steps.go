@@ -0,0 +1,45 @@
+//go:generate godogen
+
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/lukasngl/valet/framework/bddtest"
+	"github.com/lukasngl/valet/provider-ssh/api/v1alpha1"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Suite holds per-scenario state for SSH-provider-specific steps.
+// Common steps are handled by the embedded [bddtest.Suite].
+type Suite struct {
+	*bddtest.Suite[*v1alpha1.SSHKeyPair]
+}
+
+//godogen:then ^the Secret "([^"]*)" should hold an SSH key pair$
+func (s *Suite) theSecretShouldHoldAnSSHKeyPair(_ context.Context, name string) error {
+	var secret corev1.Secret
+	if err := s.K8sClient.Get(s.Ctx, client.ObjectKey{Namespace: s.Namespace, Name: name}, &secret); err != nil {
+		return fmt.Errorf("getting secret: %w", err)
+	}
+	if secret.Type != corev1.SecretTypeSSHAuth {
+		return fmt.Errorf("secret has type %q, want %q", secret.Type, corev1.SecretTypeSSHAuth)
+	}
+
+	signer, err := ssh.ParsePrivateKey(secret.Data[corev1.SSHAuthPrivateKey])
+	if err != nil {
+		return fmt.Errorf("parsing private key: %w", err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(secret.Data[v1alpha1.PublicKeyKey])
+	if err != nil {
+		return fmt.Errorf("parsing public key: %w", err)
+	}
+	if !bytes.Equal(pub.Marshal(), signer.PublicKey().Marshal()) {
+		return fmt.Errorf("public key in %s does not match the private key", v1alpha1.PublicKeyKey)
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Key algorithms of the issued certificates.
+const (
+	KeyAlgorithmECDSA   = "ECDSA"
+	KeyAlgorithmRSA     = "RSA"
+	KeyAlgorithmEd25519 = "Ed25519"
+)
+
+// CABundleKey is the key of the CA bundle in the output Secret and, if
+// present, in the Secret referenced by [TLSClientCertificateSpec.CARef].
+const CABundleKey = "ca.crt"
+
+func init() {
+	SchemeBuilder.Register(&TLSClientCertificate{}, &TLSClientCertificateList{})
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=tlscc,categories=valet
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// TLSClientCertificate issues and rotates short-lived TLS client certificates
+// signed by a CA whose key is stored in a Secret.
+type TLSClientCertificate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	Spec TLSClientCertificateSpec `json:"spec,omitzero"`
+	// +optional
+	Status framework.ClientSecretStatus `json:"status,omitzero"`
+}
+
+// TLSClientCertificateSpec defines the desired state.
+type TLSClientCertificateSpec struct {
+	// SecretRef is the Kubernetes Secret to create/update with the issued
+	// certificate. It is created as a kubernetes.io/tls Secret holding the
+	// certificate in tls.crt, its private key in tls.key and the CA bundle
+	// in ca.crt.
+	// Defaults to a Secret named after the resource.
+	// +optional
+	SecretRef framework.SecretReference `json:"secretRef,omitzero"`
+
+	// Suspend stops the rotation and cleanup of the credentials while set,
+	// e.g. during incidents or migrations. The output Secret keeps its
+	// current credentials.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DeletionPolicy controls what happens when the resource is deleted:
+	// Delete deletes its keys at the provider and the output Secret with it,
+	// Retain keeps both, e.g. to move the resource to another cluster.
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy framework.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// SecretTemplate sets labels, annotations and the type of the output
+	// Secret, e.g. for Reloader, External Secrets or Argo CD to match on.
+	// +optional
+	SecretTemplate framework.SecretTemplate `json:"secretTemplate,omitzero"`
+
+	// Targets are further Secrets the credentials are written to, each
+	// with a subset of the keys, its own template or in another namespace,
+	// so that consumers expecting different shapes share one credential.
+	// +optional
+	Targets framework.OutputTargets `json:"targets,omitempty"`
+
+	// Replication copies the output Secret into all namespaces matching a
+	// label selector that allow it, and keeps the copies in sync.
+	// +optional
+	Replication *framework.Replication `json:"replication,omitempty"`
+
+	// CARef is a Secret in the same namespace holding the PEM-encoded CA
+	// certificate in tls.crt and its private key in tls.key, e.g. a
+	// kubernetes.io/tls Secret. If it also has a ca.crt, that is used as the
+	// CA bundle, otherwise the CA certificate itself.
+	// +kubebuilder:validation:Required
+	CARef CAReference `json:"caRef"`
+
+	// CommonName is the subject common name of the certificates.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=64
+	CommonName string `json:"commonName"`
+
+	// Organizations are the subject organizations of the certificates.
+	// +optional
+	Organizations []string `json:"organizations,omitempty"`
+
+	// DNSNames are the DNS subject alternative names of the certificates.
+	// +optional
+	DNSNames []string `json:"dnsNames,omitempty"`
+
+	// URIs are the URI subject alternative names of the certificates, e.g. a
+	// SPIFFE ID.
+	// +optional
+	URIs []string `json:"uris,omitempty"`
+
+	// KeyAlgorithm is the algorithm of the private keys: ECDSA (P-256), RSA
+	// (2048 bits) or Ed25519. Defaults to ECDSA.
+	// +kubebuilder:validation:Enum=ECDSA;RSA;Ed25519
+	// +optional
+	KeyAlgorithm string `json:"keyAlgorithm,omitempty"`
+
+	// Validity is how long each certificate is valid. Certificates are
+	// renewed before they expire. Capped to the CA certificate's expiry.
+	// Defaults to 24 hours.
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// Template maps additional output secret keys to Go template strings,
+	// e.g. to also provide a combined PEM file.
+	// Available template variables: .Certificate, .PrivateKey, .CA, .SerialNumber
+	// +optional
+	Template map[string]string `json:"template,omitempty"`
+}
+
+// CAReference selects the Secret holding the CA in the namespace of the
+// resource.
+type CAReference struct {
+	// Name of the Secret.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// GetSecretRef returns the reference to the target output Secret, defaulting
+// to a Secret named after the resource.
+func (c *TLSClientCertificate) GetSecretRef() framework.SecretReference {
+	return c.Spec.SecretRef.OrDefault(c.Name)
+}
+
+// IsSuspended reports whether the rotation of the credentials is suspended.
+// It implements [framework.Suspender].
+func (c *TLSClientCertificate) IsSuspended() bool {
+	return c.Spec.Suspend
+}
+
+// GetDeletionPolicy returns what happens to the keys and the output Secret
+// when the resource is deleted. It implements [framework.DeletionPolicyHolder].
+func (c *TLSClientCertificate) GetDeletionPolicy() framework.DeletionPolicy {
+	return c.Spec.DeletionPolicy
+}
+
+// GetSecretTemplate returns the labels, annotations and type of the output
+// Secret. It implements [framework.SecretTemplateHolder].
+func (c *TLSClientCertificate) GetSecretTemplate() framework.SecretTemplate {
+	return c.Spec.SecretTemplate
+}
+
+// GetTargets returns the further Secrets the credentials are written to.
+// It implements [framework.TargetHolder].
+func (c *TLSClientCertificate) GetTargets() framework.OutputTargets {
+	return c.Spec.Targets
+}
+
+// GetReplication returns the replication of the output Secret, or nil.
+// It implements [framework.ReplicationHolder].
+func (c *TLSClientCertificate) GetReplication() *framework.Replication {
+	return c.Spec.Replication
+}
+
+// GetValidity returns the requested validity of the credentials, or zero for
+// the provider's default. It implements [framework.ValidityRequester].
+func (c *TLSClientCertificate) GetValidity() time.Duration {
+	if c.Spec.Validity == nil {
+		return 0
+	}
+	return c.Spec.Validity.Duration
+}
+
+// GetStatus returns a pointer to the shared status.
+func (c *TLSClientCertificate) GetStatus() *framework.ClientSecretStatus {
+	return &c.Status
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (c *TLSClientCertificate) DeepCopyObject() runtime.Object {
+	cp := *c
+	cp.ObjectMeta = *c.DeepCopy()
+	cp.Status = c.Status.DeepCopy()
+	cp.Spec.SecretTemplate = c.Spec.SecretTemplate.DeepCopy()
+	cp.Spec.Targets = c.Spec.Targets.DeepCopy()
+	cp.Spec.Replication = c.Spec.Replication.DeepCopy()
+	if c.Spec.Organizations != nil {
+		cp.Spec.Organizations = append([]string(nil), c.Spec.Organizations...)
+	}
+	if c.Spec.DNSNames != nil {
+		cp.Spec.DNSNames = append([]string(nil), c.Spec.DNSNames...)
+	}
+	if c.Spec.URIs != nil {
+		cp.Spec.URIs = append([]string(nil), c.Spec.URIs...)
+	}
+	if c.Spec.Template != nil {
+		cp.Spec.Template = make(map[string]string, len(c.Spec.Template))
+		for k, v := range c.Spec.Template {
+			cp.Spec.Template[k] = v
+		}
+	}
+	if c.Spec.Validity != nil {
+		v := *c.Spec.Validity
+		cp.Spec.Validity = &v
+	}
+	return &cp
+}
+
+// Validate performs structural validation of the spec.
+func (c *TLSClientCertificate) Validate() error {
+	if c.Spec.CARef.Name == "" {
+		return fmt.Errorf("caRef.name is required")
+	}
+	if c.Spec.CommonName == "" {
+		return fmt.Errorf("commonName is required")
+	}
+	for _, uri := range c.Spec.URIs {
+		if u, err := url.Parse(uri); err != nil || !u.IsAbs() {
+			return fmt.Errorf("URI %q must be an absolute URI", uri)
+		}
+	}
+	switch c.Spec.KeyAlgorithm {
+	case "", KeyAlgorithmECDSA, KeyAlgorithmRSA, KeyAlgorithmEd25519:
+	default:
+		return fmt.Errorf("keyAlgorithm %q must be ECDSA, RSA or Ed25519", c.Spec.KeyAlgorithm)
+	}
+	if c.Spec.Validity != nil && c.Spec.Validity.Duration <= 0 {
+		return fmt.Errorf("validity must be positive")
+	}
+	if err := framework.ValidateTemplateKeys(c.Spec.Template, corev1.TLSCertKey, corev1.TLSPrivateKeyKey, CABundleKey); err != nil {
+		return err
+	}
+	for key, tmpl := range c.Spec.Template {
+		if _, err := template.New(key).Parse(tmpl); err != nil {
+			return fmt.Errorf("template %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:object:root=true
+
+// TLSClientCertificateList contains a list of TLSClientCertificate resources.
+type TLSClientCertificateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TLSClientCertificate `json:"items"`
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (c *TLSClientCertificateList) DeepCopyObject() runtime.Object {
+	cp := *c
+	if c.Items != nil {
+		cp.Items = make([]TLSClientCertificate, len(c.Items))
+		for i := range c.Items {
+			cp.Items[i] = *c.Items[i].DeepCopyObject().(*TLSClientCertificate)
+		}
+	}
+	return &cp
+}
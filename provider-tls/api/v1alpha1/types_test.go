@@ -0,0 +1,146 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	valid := &TLSClientCertificate{
+		Spec: TLSClientCertificateSpec{
+			SecretRef:  framework.SecretReference{Name: "out"},
+			CARef:      CAReference{Name: "ca"},
+			CommonName: "app",
+			URIs:       []string{"spiffe://example.com/ns/default/sa/app"},
+			Template:   map[string]string{"tls.pem": "{{ .Certificate }}{{ .PrivateKey }}"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*TLSClientCertificate)
+		wantErr string
+	}{
+		{name: "valid", modify: func(_ *TLSClientCertificate) {}},
+		{
+			name:   "without template",
+			modify: func(c *TLSClientCertificate) { c.Spec.Template = nil },
+		},
+		{
+			name:   "omitted secretRef",
+			modify: func(c *TLSClientCertificate) { c.Spec.SecretRef.Name = "" },
+		},
+		{
+			name:    "missing caRef",
+			modify:  func(c *TLSClientCertificate) { c.Spec.CARef.Name = "" },
+			wantErr: "caRef.name",
+		},
+		{
+			name:    "missing common name",
+			modify:  func(c *TLSClientCertificate) { c.Spec.CommonName = "" },
+			wantErr: "commonName",
+		},
+		{
+			name:    "relative URI",
+			modify:  func(c *TLSClientCertificate) { c.Spec.URIs = []string{"ns/default/sa/app"} },
+			wantErr: "absolute URI",
+		},
+		{
+			name:    "unknown key algorithm",
+			modify:  func(c *TLSClientCertificate) { c.Spec.KeyAlgorithm = "DSA" },
+			wantErr: "keyAlgorithm",
+		},
+		{
+			name: "non-positive validity",
+			modify: func(c *TLSClientCertificate) {
+				c.Spec.Validity = &metav1.Duration{Duration: -time.Hour}
+			},
+			wantErr: "validity",
+		},
+		{
+			name:    "reserved template key",
+			modify:  func(c *TLSClientCertificate) { c.Spec.Template = map[string]string{"tls.key": "x"} },
+			wantErr: "reserved",
+		},
+		{
+			name:    "invalid template syntax",
+			modify:  func(c *TLSClientCertificate) { c.Spec.Template = map[string]string{"bad": "{{ .Foo"} },
+			wantErr: "template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := valid.DeepCopyObject().(*TLSClientCertificate)
+			tt.modify(obj)
+			err := obj.Validate()
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if got := err.Error(); !strings.Contains(got, tt.wantErr) {
+				t.Fatalf("error %q does not contain %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeepCopyObject(t *testing.T) {
+	validity := metav1.Duration{Duration: 12 * time.Hour}
+	obj := &TLSClientCertificate{
+		Spec: TLSClientCertificateSpec{
+			SecretRef:     framework.SecretReference{Name: "s"},
+			CARef:         CAReference{Name: "ca"},
+			CommonName:    "app",
+			Organizations: []string{"example"},
+			DNSNames:      []string{"app.example.com"},
+			URIs:          []string{"spiffe://example.com/app"},
+			Template:      map[string]string{"K": "V"},
+			Validity:      &validity,
+		},
+	}
+	obj.Status.Phase = framework.PhaseReady
+
+	cp := obj.DeepCopyObject().(*TLSClientCertificate)
+
+	cp.Spec.Template["K"] = "changed"
+	if obj.Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not copy template map")
+	}
+
+	cp.Spec.Organizations[0] = "changed"
+	cp.Spec.DNSNames[0] = "changed"
+	cp.Spec.URIs[0] = "changed"
+	if obj.Spec.Organizations[0] == "changed" || obj.Spec.DNSNames[0] == "changed" || obj.Spec.URIs[0] == "changed" {
+		t.Fatal("DeepCopyObject did not copy subject names")
+	}
+
+	cp.Spec.Validity.Duration = time.Hour
+	if obj.Spec.Validity.Duration != 12*time.Hour {
+		t.Fatal("DeepCopyObject did not copy validity")
+	}
+}
+
+func TestDeepCopyObjectList(t *testing.T) {
+	list := &TLSClientCertificateList{
+		Items: []TLSClientCertificate{
+			{Spec: TLSClientCertificateSpec{DNSNames: []string{"a"}}},
+		},
+	}
+
+	cp := list.DeepCopyObject().(*TLSClientCertificateList)
+	cp.Items[0].Spec.DNSNames[0] = "changed"
+	if list.Items[0].Spec.DNSNames[0] != "a" {
+		t.Fatal("DeepCopyObject did not deep copy list items")
+	}
+}
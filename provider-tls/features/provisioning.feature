@@ -0,0 +1,132 @@
+Feature: TLS Client Certificates
+  As a platform operator
+  I want the TLS provider to issue and rotate client certificates from my CA
+  So that applications get short-lived client certificates without an external PKI
+
+  Background:
+    Given a Kubernetes cluster is running
+    And the CRDs are installed
+    And the operator is running
+    And a CA Secret "test-ca"
+
+  Scenario: Issue a certificate successfully
+    When I create a ClientSecret "test-cert" with:
+      """yaml
+      spec:
+        secretRef:
+          name: test-cert
+        caRef:
+          name: test-ca
+        commonName: web
+        uris: ["spiffe://example.com/ns/default/sa/web"]
+      """
+    Then the ClientSecret "test-cert" should have phase "Ready" within 60 seconds
+    And a Secret "test-cert" should exist
+    And the Secret "test-cert" should contain key "tls.crt"
+    And the Secret "test-cert" should contain key "tls.key"
+    And the Secret "test-cert" should contain key "ca.crt"
+    And the Secret "test-cert" should hold a client certificate for "web" issued by "test-ca"
+
+  Scenario: Templates add keys to the Secret
+    When I create a ClientSecret "bundle-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bundle-test
+        caRef:
+          name: test-ca
+        commonName: web
+        keyAlgorithm: Ed25519
+        template:
+          tls.pem: "{{ .Certificate }}{{ .PrivateKey }}"
+      """
+    Then the ClientSecret "bundle-test" should have phase "Ready" within 60 seconds
+    And the Secret "bundle-test" should contain key "tls.pem"
+    And the Secret "bundle-test" should hold a client certificate for "web" issued by "test-ca"
+
+  Scenario: Invalid template syntax is rejected
+    When I create a ClientSecret "bad-template" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-template
+        caRef:
+          name: test-ca
+        commonName: web
+        template:
+          SECRET: "{{ .Invalid"
+      """
+    Then the ClientSecret "bad-template" should have phase "Failed" within 60 seconds
+    And the ClientSecret "bad-template" status should contain message "template"
+    And the Secret "bad-template" should not exist
+
+  Scenario: Missing CA Secret is reported
+    When I create a ClientSecret "no-ca" with:
+      """yaml
+      spec:
+        secretRef:
+          name: no-ca
+        caRef:
+          name: missing
+        commonName: web
+      """
+    Then the ClientSecret "no-ca" should have phase "Failed" within 60 seconds
+    And the ClientSecret "no-ca" status should contain message "CA secret missing"
+    And the Secret "no-ca" should not exist
+
+  Scenario: Delete TLSClientCertificate cleans up resources
+    When I create a ClientSecret "delete-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: delete-test
+        caRef:
+          name: test-ca
+        commonName: web
+      """
+    Then the ClientSecret "delete-test" should have phase "Ready" within 60 seconds
+    And a Secret "delete-test" should exist
+    When I delete the ClientSecret "delete-test"
+    Then the ClientSecret "delete-test" should not exist within 60 seconds
+
+  Scenario: Expired certificates are rotated
+    When I create a ClientSecret "rotation-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rotation-test
+        caRef:
+          name: test-ca
+        commonName: web
+      """
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the ClientSecret "rotation-test" should have 1 active keys
+    When I expire the credentials for ClientSecret "rotation-test"
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the Secret "rotation-test" should hold a client certificate for "web" issued by "test-ca"
+
+  Scenario: Unknown key algorithms are rejected
+    When I try to create a ClientSecret "bad-algorithm" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-algorithm
+        caRef:
+          name: test-ca
+        commonName: web
+        keyAlgorithm: DSA
+      """
+    Then the operation should have failed with "keyAlgorithm"
+
+  Scenario: Secret is owned by the TLSClientCertificate
+    When I create a ClientSecret "ownership-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: ownership-test
+        caRef:
+          name: test-ca
+        commonName: web
+      """
+    Then the ClientSecret "ownership-test" should have phase "Ready" within 60 seconds
+    And the Secret "ownership-test" should be owned by ClientSecret "ownership-test"
@@ -0,0 +1,70 @@
+{ inputs, ... }:
+{
+  perSystem =
+    { config, pkgs, ... }:
+    let
+      valet = config.valet.lib;
+
+      provider-tls = valet.mkGoModule {
+        pname = "provider-tls";
+        subPackages = [ "provider-tls/cmd" ];
+        postInstall = ''
+          mv $out/bin/cmd $out/bin/provider-tls
+        '';
+        meta.mainProgram = "provider-tls";
+      };
+
+      provider-tls-compressed = pkgs.stdenvNoCC.mkDerivation {
+        inherit (provider-tls) pname version meta;
+        dontUnpack = true;
+        nativeBuildInputs = [ pkgs.upx ];
+        buildPhase = ''
+          mkdir -p $out/bin
+          upx -o $out/bin/provider-tls ${provider-tls}/bin/provider-tls
+        '';
+      };
+
+      image = valet.mkImage {
+        name = "provider-tls";
+        entrypoint = "${provider-tls-compressed}/bin/provider-tls";
+      };
+    in
+    {
+      packages = {
+        inherit provider-tls provider-tls-compressed;
+        provider-tls-image = image;
+      };
+
+      checks.provider-tls-helm = valet.packageChart {
+        name = "provider-tls";
+        src = "${inputs.self}/provider-tls/charts/provider-tls";
+      };
+
+      checks.provider-tls-lint = valet.withPackageEnv provider-tls {
+        name = "provider-tls-lint";
+        extraBuildInputs = [ pkgs.golangci-lint ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          golangci-lint run --timeout 10m ./provider-tls/...
+        '';
+      };
+
+      checks.provider-tls-test = valet.withPackageEnv provider-tls {
+        name = "provider-tls-test";
+        extraBuildInputs = [
+          pkgs.gotestsum
+          pkgs.etcd
+          pkgs.kubernetes
+        ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum --format short-verbose -- -coverpkg=github.com/lukasngl/valet/framework/...,./... -coverprofile=coverage.txt ./provider-tls/...
+        '';
+        installPhase = ''
+          mkdir -p $out
+          cp coverage.txt $out/
+        '';
+      };
+    };
+}
@@ -0,0 +1,279 @@
+// Package internal contains the TLS client certificate provider
+// implementation.
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-tls/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultValidity is the default certificate validity (24 hours).
+const DefaultValidity = 24 * time.Hour
+
+// backdate is how long before issuance certificates become valid, so that
+// relying parties whose clocks lag slightly behind accept them right away.
+const backdate = 5 * time.Minute
+
+// rsaKeySize is the size of generated RSA keys in bits.
+const rsaKeySize = 2048
+
+// Provider issues TLS client certificates signed by a CA whose certificate
+// and key are stored in a Secret. It implements [framework.Provider] for
+// [*v1alpha1.TLSClientCertificate].
+//
+// Certificates are issued locally without any external API. They cannot be
+// revoked and stay valid until they expire, which is why they should be
+// short-lived; the framework renews them before that.
+type Provider struct {
+	kube client.Client
+}
+
+// Option configures a [Provider].
+type Option func(*Provider)
+
+// WithKubeClient sets the Kubernetes client used to read the CA Secrets. It
+// is required.
+func WithKubeClient(c client.Client) Option {
+	return func(p *Provider) { p.kube = c }
+}
+
+// New creates a [Provider] with the given options.
+func New(opts ...Option) *Provider {
+	p := &Provider{}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// NewObject returns a zero-value TLSClientCertificate.
+func (p *Provider) NewObject() *v1alpha1.TLSClientCertificate {
+	return &v1alpha1.TLSClientCertificate{}
+}
+
+// Provision issues a new certificate with a fresh private key. It is valid
+// for the spec's validity, or until the CA certificate expires if that is
+// sooner. Its serial number is used as the key ID.
+func (p *Provider) Provision(
+	ctx context.Context,
+	obj *v1alpha1.TLSClientCertificate,
+) (*framework.Result, error) {
+	if p.kube == nil {
+		return nil, errors.New("no Kubernetes client configured")
+	}
+
+	ca, err := p.loadCA(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	validity := DefaultValidity
+	if obj.Spec.Validity != nil {
+		validity = obj.Spec.Validity.Duration
+	}
+	notAfter := now.Add(validity)
+	if ca.cert.NotAfter.Before(notAfter) {
+		notAfter = ca.cert.NotAfter
+	}
+	if !notAfter.After(now) {
+		return nil, fmt.Errorf("CA certificate expired at %s", ca.cert.NotAfter.Format(time.RFC3339))
+	}
+
+	uris := make([]*url.URL, 0, len(obj.Spec.URIs))
+	for _, raw := range obj.Spec.URIs {
+		uri, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parsing URI %q: %w", raw, err)
+		}
+		uris = append(uris, uri)
+	}
+
+	key, err := generateKey(obj.Spec.KeyAlgorithm)
+	if err != nil {
+		return nil, fmt.Errorf("generating private key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("generating serial number: %w", err)
+	}
+
+	keyUsage := x509.KeyUsageDigitalSignature
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   obj.Spec.CommonName,
+			Organization: obj.Spec.Organizations,
+		},
+		DNSNames:              obj.Spec.DNSNames,
+		URIs:                  uris,
+		NotBefore:             now.Add(-backdate),
+		NotAfter:              notAfter,
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("signing certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("encoding private key: %w", err)
+	}
+
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})) + ca.chain
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+	serialNumber := fmt.Sprintf("%x", serial)
+
+	data := map[string]string{
+		corev1.TLSCertKey:       certPEM,
+		corev1.TLSPrivateKeyKey: keyPEM,
+		v1alpha1.CABundleKey:    ca.bundle,
+	}
+
+	templateData := map[string]string{
+		"Certificate":  certPEM,
+		"PrivateKey":   keyPEM,
+		"CA":           ca.bundle,
+		"SerialNumber": serialNumber,
+	}
+	for key, tmpl := range obj.Spec.Template {
+		rendered, err := renderTemplate(tmpl, templateData)
+		if err != nil {
+			return nil, fmt.Errorf("rendering template %q: %w", key, err)
+		}
+		data[key] = rendered
+	}
+
+	return &framework.Result{
+		StringData:    data,
+		ProvisionedAt: now,
+		ValidUntil:    notAfter,
+		KeyID:         serialNumber,
+		SecretType:    corev1.SecretTypeTLS,
+	}, nil
+}
+
+// DeleteKey does nothing, as issued certificates cannot be revoked. They stay
+// valid until they expire.
+func (p *Provider) DeleteKey(
+	_ context.Context,
+	_ *v1alpha1.TLSClientCertificate,
+	_ string,
+) error {
+	return nil
+}
+
+// Capabilities reports that certificates cannot be deleted, so the reconciler
+// drops expired certificates without calling DeleteKey.
+func (p *Provider) Capabilities() framework.Capabilities {
+	return framework.Capabilities{}
+}
+
+// certificateAuthority is a CA loaded from a Secret.
+type certificateAuthority struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+
+	// chain is appended to issued certificates: the CA's certificates if it
+	// is an intermediate CA, empty otherwise.
+	chain string
+
+	// bundle is the PEM-encoded CA bundle written to the output Secret.
+	bundle string
+}
+
+// loadCA reads the CA referenced by the spec.
+func (p *Provider) loadCA(
+	ctx context.Context,
+	obj *v1alpha1.TLSClientCertificate,
+) (*certificateAuthority, error) {
+	name := obj.Spec.CARef.Name
+
+	var secret corev1.Secret
+	if err := p.kube.Get(ctx, client.ObjectKey{Namespace: obj.Namespace, Name: name}, &secret); err != nil {
+		return nil, fmt.Errorf("getting CA secret %s: %w", name, err)
+	}
+
+	certPEM := secret.Data[corev1.TLSCertKey]
+	pair, err := tls.X509KeyPair(certPEM, secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("loading CA secret %s: %w", name, err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("parsing CA certificate from secret %s: %w", name, err)
+	}
+	if !cert.IsCA {
+		return nil, fmt.Errorf("certificate in CA secret %s is not a CA certificate", name)
+	}
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("private key in CA secret %s cannot sign", name)
+	}
+
+	ca := &certificateAuthority{cert: cert, key: key}
+	if !bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		ca.chain = string(certPEM)
+	}
+	if bundle := secret.Data[v1alpha1.CABundleKey]; len(bundle) > 0 {
+		ca.bundle = string(bundle)
+	} else {
+		ca.bundle = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	}
+	return ca, nil
+}
+
+// generateKey generates a private key with the given algorithm, ECDSA by
+// default.
+func generateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case v1alpha1.KeyAlgorithmRSA:
+		return rsa.GenerateKey(rand.Reader, rsaKeySize)
+	case v1alpha1.KeyAlgorithmEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+}
+
+// renderTemplate renders a Go template string with the given data.
+func renderTemplate(tmpl string, data map[string]string) (string, error) {
+	t, err := template.New("").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
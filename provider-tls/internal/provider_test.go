@@ -0,0 +1,292 @@
+package internal
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/provider-tls/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// testCA is a CA certificate and key for tests.
+type testCA struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCA creates a CA valid for validity that is self-signed, or signed by
+// parent if given.
+func newTestCA(t *testing.T, name string, validity time.Duration, parent *testCA) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	issuer, signer := tmpl, any(key)
+	if parent != nil {
+		issuer, signer = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, issuer, key.Public(), signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// secret returns a Secret "ca" in namespace "apps" holding the CA.
+func (ca *testCA) secret() *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "ca"},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       ca.certPEM,
+			corev1.TLSPrivateKeyKey: ca.keyPEM,
+		},
+	}
+}
+
+// newTestProvider returns a provider reading the given Secrets and an object
+// issuing certificates from the CA Secret "ca".
+func newTestProvider(t *testing.T, secrets ...*corev1.Secret) (*Provider, *v1alpha1.TLSClientCertificate) {
+	t.Helper()
+	builder := fake.NewClientBuilder()
+	for _, s := range secrets {
+		builder = builder.WithObjects(s)
+	}
+
+	obj := &v1alpha1.TLSClientCertificate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "web", UID: "uid-1"},
+		Spec: v1alpha1.TLSClientCertificateSpec{
+			CARef:         v1alpha1.CAReference{Name: "ca"},
+			CommonName:    "web",
+			Organizations: []string{"example"},
+			URIs:          []string{"spiffe://example.com/ns/apps/sa/web"},
+		},
+	}
+
+	return New(WithKubeClient(builder.Build())), obj
+}
+
+// verify checks that data holds a client certificate with a matching key
+// that chains up to the CA bundle, and returns the certificate.
+func verify(t *testing.T, data map[string]string) *x509.Certificate {
+	t.Helper()
+	pair, err := tls.X509KeyPair([]byte(data[corev1.TLSCertKey]), []byte(data[corev1.TLSPrivateKeyKey]))
+	if err != nil {
+		t.Fatalf("invalid certificate or key: %v", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(data[v1alpha1.CABundleKey])) {
+		t.Fatalf("invalid CA bundle: %q", data[v1alpha1.CABundleKey])
+	}
+	intermediates := x509.NewCertPool()
+	for _, der := range pair.Certificate[1:] {
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		intermediates.AddCert(c)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		t.Fatalf("certificate does not verify as a client certificate: %v", err)
+	}
+	return cert
+}
+
+func TestProvision(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		ca := newTestCA(t, "root", 365*24*time.Hour, nil)
+		p, obj := newTestProvider(t, ca.secret())
+		obj.Spec.Template = map[string]string{"tls.pem": "{{ .Certificate }}{{ .PrivateKey }}"}
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.SecretType != corev1.SecretTypeTLS {
+			t.Fatalf("got secret type %q, want %q", result.SecretType, corev1.SecretTypeTLS)
+		}
+
+		cert := verify(t, result.StringData)
+		if cert.Subject.CommonName != "web" || len(cert.Subject.Organization) != 1 ||
+			len(cert.URIs) != 1 || cert.URIs[0].String() != obj.Spec.URIs[0] {
+			t.Fatalf("unexpected subject %v and URIs %v", cert.Subject, cert.URIs)
+		}
+		if result.KeyID != cert.SerialNumber.Text(16) {
+			t.Fatalf("got keyID %q, want the serial number %x", result.KeyID, cert.SerialNumber)
+		}
+		if _, ok := cert.PublicKey.(*ecdsa.PublicKey); !ok {
+			t.Fatalf("got %T, want an ECDSA key by default", cert.PublicKey)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != DefaultValidity {
+			t.Fatalf("got validity %v, want %v", got, DefaultValidity)
+		}
+		if !cert.NotAfter.Equal(result.ValidUntil.Truncate(time.Second)) {
+			t.Fatalf("certificate expires at %v, want %v", cert.NotAfter, result.ValidUntil)
+		}
+		want := result.StringData[corev1.TLSCertKey] + result.StringData[corev1.TLSPrivateKeyKey]
+		if result.StringData["tls.pem"] != want {
+			t.Fatalf("got rendered template %q", result.StringData["tls.pem"])
+		}
+
+		next, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if next.KeyID == result.KeyID || next.StringData[corev1.TLSPrivateKeyKey] == result.StringData[corev1.TLSPrivateKeyKey] {
+			t.Fatal("expected a new certificate and key on renewal")
+		}
+	})
+
+	t.Run("intermediate CA", func(t *testing.T) {
+		root := newTestCA(t, "root", 365*24*time.Hour, nil)
+		intermediate := newTestCA(t, "intermediate", 30*24*time.Hour, root)
+		secret := intermediate.secret()
+		secret.Data[v1alpha1.CABundleKey] = root.certPEM
+		p, obj := newTestProvider(t, secret)
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		verify(t, result.StringData)
+		if result.StringData[v1alpha1.CABundleKey] != string(root.certPEM) {
+			t.Fatal("expected the CA bundle from the CA secret")
+		}
+	})
+
+	t.Run("key algorithms", func(t *testing.T) {
+		ca := newTestCA(t, "root", 365*24*time.Hour, nil)
+		p, obj := newTestProvider(t, ca.secret())
+
+		for algorithm, check := range map[string]func(any) bool{
+			v1alpha1.KeyAlgorithmRSA: func(k any) bool {
+				key, ok := k.(*rsa.PublicKey)
+				return ok && key.N.BitLen() == rsaKeySize
+			},
+			v1alpha1.KeyAlgorithmEd25519: func(k any) bool { _, ok := k.(ed25519.PublicKey); return ok },
+		} {
+			obj.Spec.KeyAlgorithm = algorithm
+			result, err := p.Provision(context.Background(), obj)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", algorithm, err)
+			}
+			if cert := verify(t, result.StringData); !check(cert.PublicKey) {
+				t.Fatalf("%s: got public key %T", algorithm, cert.PublicKey)
+			}
+		}
+	})
+
+	t.Run("validity is capped to the CA expiry", func(t *testing.T) {
+		ca := newTestCA(t, "root", 12*time.Hour, nil)
+		p, obj := newTestProvider(t, ca.secret())
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.ValidUntil.Equal(ca.cert.NotAfter) {
+			t.Fatalf("got expiry %v, want the CA's %v", result.ValidUntil, ca.cert.NotAfter)
+		}
+	})
+
+	t.Run("expired CA", func(t *testing.T) {
+		ca := newTestCA(t, "root", -time.Minute, nil)
+		p, obj := newTestProvider(t, ca.secret())
+
+		_, err := p.Provision(context.Background(), obj)
+		if err == nil || !strings.Contains(err.Error(), "CA certificate expired") {
+			t.Fatalf("expected expiry error, got: %v", err)
+		}
+	})
+
+	t.Run("missing CA secret", func(t *testing.T) {
+		p, obj := newTestProvider(t)
+
+		_, err := p.Provision(context.Background(), obj)
+		if err == nil || !strings.Contains(err.Error(), "CA secret ca") {
+			t.Fatalf("expected CA secret error, got: %v", err)
+		}
+	})
+
+	t.Run("not a CA certificate", func(t *testing.T) {
+		ca := newTestCA(t, "root", 365*24*time.Hour, nil)
+		p, obj := newTestProvider(t, ca.secret())
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		leaf := ca.secret()
+		leaf.Name = "leaf"
+		leaf.Data[corev1.TLSCertKey] = []byte(result.StringData[corev1.TLSCertKey])
+		leaf.Data[corev1.TLSPrivateKeyKey] = []byte(result.StringData[corev1.TLSPrivateKeyKey])
+		p, obj = newTestProvider(t, leaf)
+		obj.Spec.CARef.Name = "leaf"
+
+		_, err = p.Provision(context.Background(), obj)
+		if err == nil || !strings.Contains(err.Error(), "not a CA certificate") {
+			t.Fatalf("expected CA error, got: %v", err)
+		}
+	})
+
+	t.Run("mismatched CA key", func(t *testing.T) {
+		ca := newTestCA(t, "root", 365*24*time.Hour, nil)
+		other := newTestCA(t, "other", 365*24*time.Hour, nil)
+		secret := ca.secret()
+		secret.Data[corev1.TLSPrivateKeyKey] = other.keyPEM
+		p, obj := newTestProvider(t, secret)
+
+		_, err := p.Provision(context.Background(), obj)
+		if err == nil || !strings.Contains(err.Error(), "loading CA secret") {
+			t.Fatalf("expected key pair error, got: %v", err)
+		}
+	})
+}
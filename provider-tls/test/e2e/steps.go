@@ -0,0 +1,105 @@
+//go:generate godogen
+
+package e2e
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/lukasngl/valet/framework/bddtest"
+	"github.com/lukasngl/valet/provider-tls/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Suite holds per-scenario state for TLS-provider-specific steps.
+// Common steps are handled by the embedded [bddtest.Suite].
+type Suite struct {
+	*bddtest.Suite[*v1alpha1.TLSClientCertificate]
+}
+
+//godogen:given ^a CA Secret "([^"]*)"$
+func (s *Suite) aCASecret(_ context.Context, name string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: s.Namespace},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+		},
+	}
+	return s.K8sClient.Create(s.Ctx, secret)
+}
+
+//godogen:then ^the Secret "([^"]*)" should hold a client certificate for "([^"]*)" issued by "([^"]*)"$
+func (s *Suite) theSecretShouldHoldAClientCertificate(
+	_ context.Context,
+	name, commonName, caName string,
+) error {
+	var secret corev1.Secret
+	if err := s.K8sClient.Get(s.Ctx, client.ObjectKey{Namespace: s.Namespace, Name: name}, &secret); err != nil {
+		return fmt.Errorf("getting secret: %w", err)
+	}
+	if secret.Type != corev1.SecretTypeTLS {
+		return fmt.Errorf("secret has type %q, want %q", secret.Type, corev1.SecretTypeTLS)
+	}
+
+	block, _ := pem.Decode(secret.Data[corev1.TLSCertKey])
+	if block == nil {
+		return fmt.Errorf("secret has no PEM certificate in %s", corev1.TLSCertKey)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("parsing certificate: %w", err)
+	}
+	if cert.Subject.CommonName != commonName {
+		return fmt.Errorf("certificate has common name %q, want %q", cert.Subject.CommonName, commonName)
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(secret.Data[v1alpha1.CABundleKey]) {
+		return fmt.Errorf("secret has no CA bundle in %s", v1alpha1.CABundleKey)
+	}
+	chains, err := cert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return fmt.Errorf("verifying client certificate: %w", err)
+	}
+	if issuer := chains[0][len(chains[0])-1].Subject.CommonName; issuer != caName {
+		return fmt.Errorf("certificate is issued by %q, want %q", issuer, caName)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"regexp"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	SchemeBuilder.Register(&TwilioAPIKey{}, &TwilioAPIKeyList{})
+}
+
+// accountSIDPattern matches a Twilio account SID.
+var accountSIDPattern = regexp.MustCompile(`^AC[0-9a-f]{32}$`)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=twilioak,categories=valet
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// TwilioAPIKey provisions and rotates Twilio standard API keys.
+type TwilioAPIKey struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	Spec TwilioAPIKeySpec `json:"spec,omitzero"`
+	// +optional
+	Status framework.ClientSecretStatus `json:"status,omitzero"`
+}
+
+// TwilioAPIKeySpec defines the desired state.
+type TwilioAPIKeySpec struct {
+	// SecretRef is the Kubernetes Secret to create/update with the provisioned credentials.
+	// Defaults to a Secret named after the resource.
+	// +optional
+	SecretRef framework.SecretReference `json:"secretRef,omitzero"`
+
+	// Suspend stops the rotation and cleanup of the credentials while set,
+	// e.g. during incidents or migrations. The output Secret keeps its
+	// current credentials.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DeletionPolicy controls what happens when the resource is deleted:
+	// Delete deletes its keys at the provider and the output Secret with it,
+	// Retain keeps both, e.g. to move the resource to another cluster.
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy framework.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// SecretTemplate sets labels, annotations and the type of the output
+	// Secret, e.g. for Reloader, External Secrets or Argo CD to match on.
+	// +optional
+	SecretTemplate framework.SecretTemplate `json:"secretTemplate,omitzero"`
+
+	// Targets are further Secrets the credentials are written to, each
+	// with a subset of the keys, its own template or in another namespace,
+	// so that consumers expecting different shapes share one credential.
+	// +optional
+	Targets framework.OutputTargets `json:"targets,omitempty"`
+
+	// Replication copies the output Secret into all namespaces matching a
+	// label selector that allow it, and keeps the copies in sync.
+	// +optional
+	Replication *framework.Replication `json:"replication,omitempty"`
+
+	// AccountSID is the account to create keys in, e.g. a subaccount of the
+	// operator's account. Defaults to the operator's own account.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^AC[0-9a-f]{32}$`
+	AccountSID string `json:"accountSid,omitempty"`
+
+	// FriendlyName is the name given to each created API key. Defaults to
+	// <namespace>/<name> of this resource.
+	// +optional
+	// +kubebuilder:validation:MaxLength=64
+	FriendlyName string `json:"friendlyName,omitempty"`
+
+	// Validity is how long each API key is used before it is rotated and
+	// deleted. Twilio API keys do not expire on their own.
+	// Defaults to 90 days (2160h).
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// Rotation configures how credentials are rotated, e.g. how long a
+	// superseded credential stays valid.
+	// +optional
+	Rotation framework.RotationPolicy `json:"rotation,omitzero"`
+
+	// Template maps output secret keys to Go template strings.
+	// Available template variables: .AccountSID, .KeySID, .KeySecret
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinProperties=1
+	Template map[string]string `json:"template"`
+}
+
+// GetSecretRef returns the reference to the target output Secret, defaulting
+// to a Secret named after the resource.
+func (t *TwilioAPIKey) GetSecretRef() framework.SecretReference {
+	return t.Spec.SecretRef.OrDefault(t.Name)
+}
+
+// IsSuspended reports whether the rotation of the credentials is suspended.
+// It implements [framework.Suspender].
+func (t *TwilioAPIKey) IsSuspended() bool {
+	return t.Spec.Suspend
+}
+
+// GetDeletionPolicy returns what happens to the keys and the output Secret
+// when the resource is deleted. It implements [framework.DeletionPolicyHolder].
+func (t *TwilioAPIKey) GetDeletionPolicy() framework.DeletionPolicy {
+	return t.Spec.DeletionPolicy
+}
+
+// GetSecretTemplate returns the labels, annotations and type of the output
+// Secret. It implements [framework.SecretTemplateHolder].
+func (t *TwilioAPIKey) GetSecretTemplate() framework.SecretTemplate {
+	return t.Spec.SecretTemplate
+}
+
+// GetTargets returns the further Secrets the credentials are written to.
+// It implements [framework.TargetHolder].
+func (t *TwilioAPIKey) GetTargets() framework.OutputTargets {
+	return t.Spec.Targets
+}
+
+// GetReplication returns the replication of the output Secret, or nil.
+// It implements [framework.ReplicationHolder].
+func (t *TwilioAPIKey) GetReplication() *framework.Replication {
+	return t.Spec.Replication
+}
+
+// GetValidity returns the requested validity of the credentials, or zero for
+// the provider's default. It implements [framework.ValidityRequester].
+func (t *TwilioAPIKey) GetValidity() time.Duration {
+	if t.Spec.Validity == nil {
+		return 0
+	}
+	return t.Spec.Validity.Duration
+}
+
+// GetRotationPolicy returns the rotation policy of the credentials. It
+// implements [framework.RotationPolicyHolder].
+func (t *TwilioAPIKey) GetRotationPolicy() framework.RotationPolicy {
+	return t.Spec.Rotation
+}
+
+// GetStatus returns a pointer to the shared status.
+func (t *TwilioAPIKey) GetStatus() *framework.ClientSecretStatus {
+	return &t.Status
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (t *TwilioAPIKey) DeepCopyObject() runtime.Object {
+	cp := *t
+	cp.ObjectMeta = *t.DeepCopy()
+	cp.Status = t.Status.DeepCopy()
+	cp.Spec.SecretTemplate = t.Spec.SecretTemplate.DeepCopy()
+	cp.Spec.Targets = t.Spec.Targets.DeepCopy()
+	cp.Spec.Replication = t.Spec.Replication.DeepCopy()
+	if t.Spec.Template != nil {
+		cp.Spec.Template = make(map[string]string, len(t.Spec.Template))
+		for k, v := range t.Spec.Template {
+			cp.Spec.Template[k] = v
+		}
+	}
+	if t.Spec.Validity != nil {
+		v := *t.Spec.Validity
+		cp.Spec.Validity = &v
+	}
+	return &cp
+}
+
+// Validate performs structural validation of the spec.
+func (t *TwilioAPIKey) Validate() error {
+	if t.Spec.AccountSID != "" && !accountSIDPattern.MatchString(t.Spec.AccountSID) {
+		return fmt.Errorf("accountSid %q is not a Twilio account SID", t.Spec.AccountSID)
+	}
+	if len(t.Spec.FriendlyName) > 64 {
+		return fmt.Errorf("friendlyName must be at most 64 characters")
+	}
+	if t.Spec.Validity != nil && t.Spec.Validity.Duration <= 0 {
+		return fmt.Errorf("validity must be positive")
+	}
+	if len(t.Spec.Template) == 0 {
+		return fmt.Errorf("template must have at least one entry")
+	}
+	for key, tmpl := range t.Spec.Template {
+		if _, err := template.New(key).Parse(tmpl); err != nil {
+			return fmt.Errorf("template %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:object:root=true
+
+// TwilioAPIKeyList contains a list of TwilioAPIKey resources.
+type TwilioAPIKeyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TwilioAPIKey `json:"items"`
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (t *TwilioAPIKeyList) DeepCopyObject() runtime.Object {
+	cp := *t
+	if t.Items != nil {
+		cp.Items = make([]TwilioAPIKey, len(t.Items))
+		for i := range t.Items {
+			cp.Items[i] = *t.Items[i].DeepCopyObject().(*TwilioAPIKey)
+		}
+	}
+	return &cp
+}
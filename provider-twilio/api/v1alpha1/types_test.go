@@ -0,0 +1,123 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	valid := &TwilioAPIKey{
+		Spec: TwilioAPIKeySpec{
+			SecretRef: framework.SecretReference{Name: "out"},
+			Template:  map[string]string{"KEY": "{{ .KeySecret }}"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*TwilioAPIKey)
+		wantErr string
+	}{
+		{name: "valid", modify: func(_ *TwilioAPIKey) {}},
+		{
+			name: "valid with subaccount",
+			modify: func(k *TwilioAPIKey) {
+				k.Spec.AccountSID = "AC" + strings.Repeat("0a", 16)
+			},
+		},
+		{
+			name:   "omitted secretRef",
+			modify: func(k *TwilioAPIKey) { k.Spec.SecretRef.Name = "" },
+		},
+		{
+			name:    "malformed accountSid",
+			modify:  func(k *TwilioAPIKey) { k.Spec.AccountSID = "SK123" },
+			wantErr: "accountSid",
+		},
+		{
+			name:    "friendlyName too long",
+			modify:  func(k *TwilioAPIKey) { k.Spec.FriendlyName = strings.Repeat("x", 65) },
+			wantErr: "friendlyName",
+		},
+		{
+			name: "non-positive validity",
+			modify: func(k *TwilioAPIKey) {
+				k.Spec.Validity = &metav1.Duration{Duration: -time.Hour}
+			},
+			wantErr: "validity",
+		},
+		{
+			name:    "empty template",
+			modify:  func(k *TwilioAPIKey) { k.Spec.Template = nil },
+			wantErr: "template",
+		},
+		{
+			name:    "invalid template syntax",
+			modify:  func(k *TwilioAPIKey) { k.Spec.Template = map[string]string{"bad": "{{ .Foo"} },
+			wantErr: "template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := valid.DeepCopyObject().(*TwilioAPIKey)
+			tt.modify(obj)
+			err := obj.Validate()
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if got := err.Error(); !strings.Contains(got, tt.wantErr) {
+				t.Fatalf("error %q does not contain %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeepCopyObject(t *testing.T) {
+	validity := metav1.Duration{Duration: 48 * time.Hour}
+	obj := &TwilioAPIKey{
+		Spec: TwilioAPIKeySpec{
+			SecretRef: framework.SecretReference{Name: "s"},
+			Template:  map[string]string{"K": "V"},
+			Validity:  &validity,
+		},
+	}
+	obj.Status.Phase = framework.PhaseReady
+
+	cp := obj.DeepCopyObject().(*TwilioAPIKey)
+
+	cp.Spec.Template["K"] = "changed"
+	if obj.Spec.Template["K"] != "V" {
+		t.Fatal("DeepCopyObject did not copy template map")
+	}
+
+	cp.Spec.Validity.Duration = time.Hour
+	if obj.Spec.Validity.Duration != 48*time.Hour {
+		t.Fatal("DeepCopyObject did not copy validity")
+	}
+}
+
+func TestDeepCopyObjectList(t *testing.T) {
+	list := &TwilioAPIKeyList{
+		Items: []TwilioAPIKey{
+			{Spec: TwilioAPIKeySpec{FriendlyName: "a"}},
+		},
+	}
+
+	cp := list.DeepCopyObject().(*TwilioAPIKeyList)
+	cp.Items[0].Spec.FriendlyName = "changed"
+	if list.Items[0].Spec.FriendlyName != "a" {
+		t.Fatal("DeepCopyObject did not deep copy list items")
+	}
+}
@@ -0,0 +1,127 @@
+Feature: Twilio API Key Provisioning
+  As a platform operator
+  I want the Twilio provider to provision and rotate API keys
+  So that applications can authenticate with Twilio
+
+  Background:
+    Given a Kubernetes cluster is running
+    And the CRDs are installed
+    And the operator is running
+
+  Scenario: Provision an API key successfully
+    When I create a ClientSecret "test-secret" with:
+      """yaml
+      spec:
+        secretRef:
+          name: test-secret
+        accountSid: "$TEST_TWILIO_SUBACCOUNT_SID"
+        template:
+          TWILIO_ACCOUNT_SID: "{{ .AccountSID }}"
+          TWILIO_API_KEY: "{{ .KeySID }}"
+          TWILIO_API_SECRET: "{{ .KeySecret }}"
+      """
+    Then the ClientSecret "test-secret" should have phase "Ready" within 60 seconds
+    And a Secret "test-secret" should exist
+    And the Secret "test-secret" should contain key "TWILIO_ACCOUNT_SID"
+    And the Secret "test-secret" should contain key "TWILIO_API_KEY"
+    And the Secret "test-secret" should contain key "TWILIO_API_SECRET"
+
+  Scenario: Invalid template syntax is rejected
+    When I create a ClientSecret "bad-template" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-template
+        accountSid: "$TEST_TWILIO_SUBACCOUNT_SID"
+        template:
+          SECRET: "{{ .Invalid"
+      """
+    Then the ClientSecret "bad-template" should have phase "Failed" within 60 seconds
+    And the ClientSecret "bad-template" status should contain message "template"
+    And the Secret "bad-template" should not exist
+
+  Scenario: Delete TwilioAPIKey cleans up resources
+    When I create a ClientSecret "delete-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: delete-test
+        accountSid: "$TEST_TWILIO_SUBACCOUNT_SID"
+        template:
+          TWILIO_API_SECRET: "{{ .KeySecret }}"
+      """
+    Then the ClientSecret "delete-test" should have phase "Ready" within 60 seconds
+    And a Secret "delete-test" should exist
+    When I delete the ClientSecret "delete-test"
+    Then the ClientSecret "delete-test" should not exist within 60 seconds
+
+  Scenario: Expired API keys are rotated
+    When I create a ClientSecret "rotation-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rotation-test
+        accountSid: "$TEST_TWILIO_SUBACCOUNT_SID"
+        template:
+          TWILIO_API_SECRET: "{{ .KeySecret }}"
+      """
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the ClientSecret "rotation-test" should have 1 active keys
+    When I expire the credentials for ClientSecret "rotation-test"
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+
+  Scenario: Malformed account SID is rejected
+    When I try to create a ClientSecret "bad-account" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-account
+        accountSid: "not-an-account"
+        template:
+          TWILIO_API_SECRET: "{{ .KeySecret }}"
+      """
+    Then the operation should have failed with "accountSid"
+
+  Scenario: Secret is owned by the TwilioAPIKey
+    When I create a ClientSecret "ownership-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: ownership-test
+        accountSid: "$TEST_TWILIO_SUBACCOUNT_SID"
+        template:
+          TWILIO_API_SECRET: "{{ .KeySecret }}"
+      """
+    Then the ClientSecret "ownership-test" should have phase "Ready" within 60 seconds
+    And the Secret "ownership-test" should be owned by ClientSecret "ownership-test"
+
+  @mock
+  Scenario: Template renders with correct values
+    When I create a ClientSecret "value-check" with:
+      """yaml
+      spec:
+        secretRef:
+          name: value-check
+        accountSid: "$TEST_TWILIO_SUBACCOUNT_SID"
+        template:
+          TWILIO_ACCOUNT_SID: "{{ .AccountSID }}"
+          TWILIO_API_SECRET: "{{ .KeySecret }}"
+      """
+    Then the ClientSecret "value-check" should have phase "Ready" within 60 seconds
+    And the Secret "value-check" should contain key "TWILIO_ACCOUNT_SID" with value "AC00000000000000000000000000000001"
+    And the Secret "value-check" should contain key "TWILIO_API_SECRET" with value "fake-key-secret"
+
+  @mock
+  Scenario: Unknown account is reported
+    When I create a ClientSecret "unknown-account" with:
+      """yaml
+      spec:
+        secretRef:
+          name: unknown-account
+        accountSid: "ACffffffffffffffffffffffffffffffff"
+        template:
+          TWILIO_API_SECRET: "{{ .KeySecret }}"
+      """
+    Then the ClientSecret "unknown-account" should have phase "Failed" within 60 seconds
+    And the ClientSecret "unknown-account" status should contain message "20404"
+    And the Secret "unknown-account" should not exist
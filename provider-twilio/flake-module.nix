@@ -0,0 +1,95 @@
+{ inputs, ... }:
+{
+  perSystem =
+    { config, pkgs, ... }:
+    let
+      valet = config.valet.lib;
+
+      provider-twilio = valet.mkGoModule {
+        pname = "provider-twilio";
+        subPackages = [ "provider-twilio/cmd" ];
+        postInstall = ''
+          mv $out/bin/cmd $out/bin/provider-twilio
+        '';
+        meta.mainProgram = "provider-twilio";
+      };
+
+      provider-twilio-compressed = pkgs.stdenvNoCC.mkDerivation {
+        inherit (provider-twilio) pname version meta;
+        dontUnpack = true;
+        nativeBuildInputs = [ pkgs.upx ];
+        buildPhase = ''
+          mkdir -p $out/bin
+          upx -o $out/bin/provider-twilio ${provider-twilio}/bin/provider-twilio
+        '';
+      };
+
+      image = valet.mkImage {
+        name = "provider-twilio";
+        entrypoint = "${provider-twilio-compressed}/bin/provider-twilio";
+      };
+      e2e-test-twilio = pkgs.writeShellApplication {
+        name = "e2e-test-twilio";
+        runtimeInputs = [
+          pkgs.go
+          pkgs.gotestsum
+        ];
+        text = ''
+          export GOFLAGS="-mod=vendor"
+          if [ ! -d vendor ]; then
+            ln -sfn ${valet.workspaceVendor} vendor
+          fi
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum \
+            --format "''${GOTESTSUM_FORMAT:-short-verbose}" \
+            -- -run TestE2E -timeout 10m \
+            -coverpkg=github.com/lukasngl/valet/framework/...,./... \
+            -coverprofile="''${COVERAGE_FILE:-coverage-twilio-e2e.txt}" \
+            ./provider-twilio/...
+        '';
+      };
+    in
+    {
+      packages = {
+        inherit provider-twilio provider-twilio-compressed;
+        provider-twilio-image = image;
+      };
+
+      apps.e2e-test-twilio = {
+        type = "app";
+        program = "${e2e-test-twilio}/bin/e2e-test-twilio";
+      };
+
+      checks.provider-twilio-helm = valet.packageChart {
+        name = "provider-twilio";
+        src = "${inputs.self}/provider-twilio/charts/provider-twilio";
+      };
+
+      checks.provider-twilio-lint = valet.withPackageEnv provider-twilio {
+        name = "provider-twilio-lint";
+        extraBuildInputs = [ pkgs.golangci-lint ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          golangci-lint run --timeout 10m ./provider-twilio/...
+        '';
+      };
+
+      checks.provider-twilio-test = valet.withPackageEnv provider-twilio {
+        name = "provider-twilio-test";
+        extraBuildInputs = [
+          pkgs.gotestsum
+          pkgs.etcd
+          pkgs.kubernetes
+        ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum --format short-verbose -- -short -coverpkg=github.com/lukasngl/valet/framework/...,./... -coverprofile=coverage.txt ./provider-twilio/...
+        '';
+        installPhase = ''
+          mkdir -p $out
+          cp coverage.txt $out/
+        '';
+      };
+    };
+}
@@ -0,0 +1,310 @@
+// Package internal contains the Twilio provider implementation.
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-twilio/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultValidity is the default key rotation period (90 days).
+const DefaultValidity = 90 * 24 * time.Hour
+
+// DefaultBaseURL is the Twilio REST API endpoint.
+const DefaultBaseURL = "https://api.twilio.com"
+
+// maxFriendlyName is the maximum length of an API key's friendly name.
+const maxFriendlyName = 64
+
+// Provider provisions Twilio standard API keys using the REST API.
+// It implements [framework.Provider] for [*v1alpha1.TwilioAPIKey].
+//
+// The operator authenticates with its account SID and auth token: standard
+// API keys cannot manage other keys.
+type Provider struct {
+	client     *http.Client
+	baseURL    string
+	accountSID string
+	authToken  string
+	initOnce   sync.Once
+	initErr    error
+}
+
+// Option configures a [Provider].
+type Option func(*Provider)
+
+// WithHTTPClient sets a custom HTTP client. Useful for testing with a mock
+// transport.
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Provider) { p.client = c }
+}
+
+// WithBaseURL overrides the Twilio REST API endpoint. Defaults to
+// [DefaultBaseURL].
+func WithBaseURL(url string) Option {
+	return func(p *Provider) { p.baseURL = strings.TrimSuffix(url, "/") }
+}
+
+// WithCredentials sets the operator's account SID and auth token. Defaults
+// to the TWILIO_ACCOUNT_SID and TWILIO_AUTH_TOKEN environment variables.
+func WithCredentials(accountSID, authToken string) Option {
+	return func(p *Provider) { p.accountSID, p.authToken = accountSID, authToken }
+}
+
+// New creates a [Provider] with the given options.
+func New(opts ...Option) *Provider {
+	p := &Provider{}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// NewObject returns a zero-value TwilioAPIKey.
+func (p *Provider) NewObject() *v1alpha1.TwilioAPIKey {
+	return &v1alpha1.TwilioAPIKey{}
+}
+
+// Provision creates a new standard API key in the account from the spec.
+func (p *Provider) Provision(
+	ctx context.Context,
+	obj *v1alpha1.TwilioAPIKey,
+) (*framework.Result, error) {
+	if err := p.initClient(); err != nil {
+		return nil, err
+	}
+
+	validity := DefaultValidity
+	if obj.Spec.Validity != nil {
+		validity = obj.Spec.Validity.Duration
+	}
+
+	name := obj.Spec.FriendlyName
+	if name == "" {
+		name = obj.Namespace + "/" + obj.Name
+		if len(name) > maxFriendlyName {
+			name = name[:maxFriendlyName]
+		}
+	}
+
+	account := p.account(obj)
+
+	var key keyResponse
+	if err := p.apiRequest(ctx, http.MethodPost, keysPath(account)+".json", url.Values{
+		"FriendlyName": {name},
+	}, &key); err != nil {
+		return nil, fmt.Errorf("creating API key %q in account %s: %w", name, account, err)
+	}
+	if key.SID == "" || key.Secret == "" {
+		return nil, errors.New("no API key returned from Twilio API")
+	}
+
+	now := time.Now()
+
+	templateData := map[string]string{
+		"AccountSID": account,
+		"KeySID":     key.SID,
+		"KeySecret":  key.Secret,
+	}
+
+	data := make(map[string]string, len(obj.Spec.Template))
+	for key, tmpl := range obj.Spec.Template {
+		rendered, err := renderTemplate(tmpl, templateData)
+		if err != nil {
+			return nil, fmt.Errorf("rendering template %q: %w", key, err)
+		}
+		data[key] = rendered
+	}
+
+	return &framework.Result{
+		StringData:    data,
+		ProvisionedAt: now,
+		ValidUntil:    now.Add(validity),
+		KeyID:         key.SID,
+	}, nil
+}
+
+// DeleteKey deletes an API key. Returns nil if the key no longer exists
+// (idempotent).
+func (p *Provider) DeleteKey(
+	ctx context.Context,
+	obj *v1alpha1.TwilioAPIKey,
+	keyID string,
+) error {
+	if keyID == "" {
+		return nil
+	}
+
+	if err := p.initClient(); err != nil {
+		return err
+	}
+
+	path := keysPath(p.account(obj)) + "/" + url.PathEscape(keyID) + ".json"
+	if err := p.apiRequest(ctx, http.MethodDelete, path, nil, nil); err != nil {
+		// Key already deleted at the provider — not an error.
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+			log.FromContext(ctx).Info("key already deleted", "keyId", keyID)
+			return nil
+		}
+		return fmt.Errorf("deleting API key %s: %w", keyID, err)
+	}
+
+	return nil
+}
+
+// account returns the account to manage keys in: the one from the spec, or
+// the operator's own.
+func (p *Provider) account(obj *v1alpha1.TwilioAPIKey) string {
+	if obj.Spec.AccountSID != "" {
+		return obj.Spec.AccountSID
+	}
+	return p.accountSID
+}
+
+// keysPath returns the path of the API key collection of an account, without
+// the .json suffix that selects the response format.
+func keysPath(account string) string {
+	return "/2010-04-01/Accounts/" + url.PathEscape(account) + "/Keys"
+}
+
+// Endpoints returns the Twilio REST API. It implements
+// [framework.EndpointDeclarer].
+func (p *Provider) Endpoints() ([]framework.Endpoint, error) {
+	baseURL := p.baseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	e, err := framework.EndpointFromURL("Twilio", baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return []framework.Endpoint{e}, nil
+}
+
+// initClient reads the credentials from the environment on first use, unless
+// they were set via [WithCredentials].
+func (p *Provider) initClient() error {
+	p.initOnce.Do(func() {
+		if p.baseURL == "" {
+			p.baseURL = DefaultBaseURL
+		}
+		if p.accountSID == "" {
+			p.accountSID = os.Getenv("TWILIO_ACCOUNT_SID")
+			p.authToken = os.Getenv("TWILIO_AUTH_TOKEN")
+		}
+		if p.accountSID == "" || p.authToken == "" {
+			p.initErr = errors.New("TWILIO_ACCOUNT_SID and TWILIO_AUTH_TOKEN must be set")
+			return
+		}
+		if p.client == nil {
+			p.client = &http.Client{Timeout: 30 * time.Second}
+		}
+	})
+	return p.initErr
+}
+
+// apiRequest makes a request to the Twilio REST API with a form-encoded body
+// and decodes the JSON response into out, if non-nil. Error responses are
+// returned as [*apiError].
+func (p *Provider) apiRequest(
+	ctx context.Context,
+	method, urlPath string,
+	form url.Values,
+	out any,
+) error {
+	var reqBody io.Reader
+	if form != nil {
+		reqBody = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+urlPath, reqBody)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.SetBasicAuth(p.accountSID, p.authToken)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		apiErr := &apiError{}
+		if json.Unmarshal(respBody, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = string(respBody)
+		}
+		apiErr.Status = resp.StatusCode
+		return apiErr
+	}
+
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("parsing response: %w", err)
+		}
+	}
+	return nil
+}
+
+// Twilio REST API request/response types.
+
+type keyResponse struct {
+	SID          string `json:"sid"`
+	FriendlyName string `json:"friendly_name"`
+	Secret       string `json:"secret"`
+}
+
+// apiError is a Twilio error response.
+type apiError struct {
+	Status   int    `json:"-"`
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	MoreInfo string `json:"more_info"`
+}
+
+func (e *apiError) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("Twilio API error (status %d, code %d): %s", e.Status, e.Code, e.Message)
+	}
+	return fmt.Sprintf("Twilio API error (status %d): %s", e.Status, e.Message)
+}
+
+// renderTemplate renders a Go template string with the given data.
+func renderTemplate(tmpl string, data map[string]string) (string, error) {
+	t, err := template.New("").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
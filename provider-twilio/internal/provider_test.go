@@ -0,0 +1,280 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/provider-twilio/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	accountSID    = "AC00000000000000000000000000000001"
+	subaccountSID = "AC00000000000000000000000000000002"
+)
+
+func newObj(template map[string]string) *v1alpha1.TwilioAPIKey {
+	return &v1alpha1.TwilioAPIKey{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "sms"},
+		Spec:       v1alpha1.TwilioAPIKeySpec{Template: template},
+	}
+}
+
+// request is a request received by the fake server.
+type request struct {
+	Method string
+	Path   string
+	Form   url.Values
+}
+
+// fakeServer records requests and responds with the status and body returned
+// by respond, or a successfully created key if respond is nil.
+type fakeServer struct {
+	mu       sync.Mutex
+	requests []request
+	respond  func(r *http.Request) (int, string)
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	f.mu.Lock()
+	f.requests = append(f.requests, request{r.Method, r.URL.Path, r.PostForm})
+	f.mu.Unlock()
+
+	status, resp := http.StatusCreated, `{"sid":"SK0001","friendly_name":"apps/sms","secret":"s3cret"}`
+	if f.respond != nil {
+		status, resp = f.respond(r)
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(resp))
+}
+
+// newTestProvider starts the fake server and returns a provider pointed at
+// it.
+func newTestProvider(t *testing.T, f *fakeServer) *Provider {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != accountSID || pass != "token" {
+			t.Errorf("unexpected credentials %q/%q", user, pass)
+		}
+		f.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return New(WithHTTPClient(srv.Client()), WithBaseURL(srv.URL), WithCredentials(accountSID, "token"))
+}
+
+func TestProvision(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		f := &fakeServer{}
+		p := newTestProvider(t, f)
+
+		result, err := p.Provision(context.Background(), newObj(map[string]string{
+			"ACCOUNT_SID": "{{ .AccountSID }}",
+			"KEY_SID":     "{{ .KeySID }}",
+			"KEY_SECRET":  "{{ .KeySecret }}",
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.KeyID != "SK0001" {
+			t.Fatalf("got keyID %q, want SK0001", result.KeyID)
+		}
+		want := map[string]string{"ACCOUNT_SID": accountSID, "KEY_SID": "SK0001", "KEY_SECRET": "s3cret"}
+		for k, v := range want {
+			if got := result.StringData[k]; got != v {
+				t.Fatalf("got %s %q, want %q", k, got, v)
+			}
+		}
+
+		if len(f.requests) != 1 {
+			t.Fatalf("got %d requests, want 1: %+v", len(f.requests), f.requests)
+		}
+		create := f.requests[0]
+		if create.Method != http.MethodPost || create.Path != "/2010-04-01/Accounts/"+accountSID+"/Keys.json" {
+			t.Fatalf("unexpected create request: %s %s", create.Method, create.Path)
+		}
+		if got := create.Form.Get("FriendlyName"); got != "apps/sms" {
+			t.Fatalf("got friendly name %q, want default <namespace>/<name>", got)
+		}
+	})
+
+	t.Run("subaccount", func(t *testing.T) {
+		f := &fakeServer{}
+		p := newTestProvider(t, f)
+		obj := newObj(map[string]string{"ACCOUNT_SID": "{{ .AccountSID }}"})
+		obj.Spec.AccountSID = subaccountSID
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := f.requests[0].Path; got != "/2010-04-01/Accounts/"+subaccountSID+"/Keys.json" {
+			t.Fatalf("got path %q, want the subaccount's keys", got)
+		}
+		if got := result.StringData["ACCOUNT_SID"]; got != subaccountSID {
+			t.Fatalf("got ACCOUNT_SID %q, want %q", got, subaccountSID)
+		}
+	})
+
+	t.Run("custom friendly name", func(t *testing.T) {
+		f := &fakeServer{}
+		p := newTestProvider(t, f)
+		obj := newObj(map[string]string{"K": "v"})
+		obj.Spec.FriendlyName = "sms-sender"
+
+		if _, err := p.Provision(context.Background(), obj); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := f.requests[0].Form.Get("FriendlyName"); got != "sms-sender" {
+			t.Fatalf("got friendly name %q", got)
+		}
+	})
+
+	t.Run("long default friendly name is truncated", func(t *testing.T) {
+		f := &fakeServer{}
+		p := newTestProvider(t, f)
+		obj := newObj(map[string]string{"K": "v"})
+		obj.Name = strings.Repeat("x", 70)
+
+		if _, err := p.Provision(context.Background(), obj); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := f.requests[0].Form.Get("FriendlyName"); len(got) != maxFriendlyName {
+			t.Fatalf("got friendly name of length %d, want %d", len(got), maxFriendlyName)
+		}
+	})
+
+	t.Run("default validity", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{})
+		result, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != DefaultValidity {
+			t.Fatalf("got validity %v, want %v", got, DefaultValidity)
+		}
+	})
+
+	t.Run("custom validity", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{})
+		obj := newObj(map[string]string{"K": "v"})
+		obj.Spec.Validity = &metav1.Duration{Duration: 48 * time.Hour}
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != 48*time.Hour {
+			t.Fatalf("got validity %v, want %v", got, 48*time.Hour)
+		}
+	})
+
+	t.Run("API error propagates", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{respond: func(*http.Request) (int, string) {
+			return http.StatusUnauthorized, `{"code":20003,"message":"Authenticate","status":401}`
+		}})
+		_, err := p.Provision(context.Background(), newObj(map[string]string{"K": "v"}))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), `creating API key "apps/sms"`) ||
+			!strings.Contains(err.Error(), "code 20003") {
+			t.Fatalf("expected wrapped error, got: %v", err)
+		}
+	})
+
+	t.Run("bad template", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{})
+		_, err := p.Provision(context.Background(), newObj(map[string]string{
+			"BAD": "{{ .Unclosed",
+		}))
+		if err == nil {
+			t.Fatal("expected template error")
+		}
+		if !strings.Contains(err.Error(), "rendering template") {
+			t.Fatalf("expected 'rendering template' error, got: %v", err)
+		}
+	})
+}
+
+func TestDeleteKey(t *testing.T) {
+	t.Run("empty keyID is a no-op", func(t *testing.T) {
+		f := &fakeServer{}
+		p := newTestProvider(t, f)
+		if err := p.DeleteKey(context.Background(), newObj(nil), ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(f.requests) != 0 {
+			t.Fatalf("unexpected requests: %+v", f.requests)
+		}
+	})
+
+	t.Run("happy path", func(t *testing.T) {
+		f := &fakeServer{respond: func(*http.Request) (int, string) {
+			return http.StatusNoContent, ""
+		}}
+		p := newTestProvider(t, f)
+		obj := newObj(nil)
+		obj.Spec.AccountSID = subaccountSID
+		if err := p.DeleteKey(context.Background(), obj, "SK0001"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(f.requests) != 1 || f.requests[0].Method != http.MethodDelete ||
+			f.requests[0].Path != "/2010-04-01/Accounts/"+subaccountSID+"/Keys/SK0001.json" {
+			t.Fatalf("unexpected requests: %+v", f.requests)
+		}
+	})
+
+	t.Run("not found is idempotent", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{respond: func(*http.Request) (int, string) {
+			return http.StatusNotFound, `{"code":20404,"message":"The requested resource was not found","status":404}`
+		}})
+		if err := p.DeleteKey(context.Background(), newObj(nil), "SK-gone"); err != nil {
+			t.Fatalf("expected nil for missing key, got: %v", err)
+		}
+	})
+
+	t.Run("other error propagates", func(t *testing.T) {
+		p := newTestProvider(t, &fakeServer{respond: func(*http.Request) (int, string) {
+			return http.StatusInternalServerError, "boom"
+		}})
+		err := p.DeleteKey(context.Background(), newObj(nil), "SK0001")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "deleting API key SK0001") || !strings.Contains(err.Error(), "boom") {
+			t.Fatalf("expected 'deleting API key' error, got: %v", err)
+		}
+	})
+}
+
+func TestInitClient(t *testing.T) {
+	t.Run("reads configuration from the environment", func(t *testing.T) {
+		t.Setenv("TWILIO_ACCOUNT_SID", accountSID)
+		t.Setenv("TWILIO_AUTH_TOKEN", "env-token")
+
+		p := New()
+		if err := p.initClient(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p.baseURL != DefaultBaseURL || p.accountSID != accountSID || p.authToken != "env-token" {
+			t.Fatalf("got baseURL %q and credentials %q/%q", p.baseURL, p.accountSID, p.authToken)
+		}
+	})
+
+	t.Run("fails without configuration", func(t *testing.T) {
+		t.Setenv("TWILIO_ACCOUNT_SID", "")
+		t.Setenv("TWILIO_AUTH_TOKEN", "")
+
+		p := New()
+		if err := p.initClient(); err == nil {
+			t.Fatal("expected error without configuration")
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package e2e
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cucumber/godog"
+	"github.com/cucumber/godog/colors"
+	"github.com/google/uuid"
+	"github.com/lukasngl/valet/framework/bddtest"
+	"github.com/lukasngl/valet/provider-twilio/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-twilio/internal"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var godogOpts = godog.Options{
+	Format:      "pretty",
+	Output:      colors.Colored(os.Stdout),
+	Paths:       []string{"../../features"},
+	Concurrency: 1,
+	Strict:      true,
+}
+
+func init() {
+	godog.BindFlags("godog.", flag.CommandLine, &godogOpts)
+}
+
+var testEnvCfg bddtest.Env
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	if len(flag.Args()) > 0 {
+		godogOpts.Paths = flag.Args()
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	testEnvCfg.Scheme = runtime.NewScheme()
+	_ = corev1.AddToScheme(testEnvCfg.Scheme)
+	_ = v1alpha1.AddToScheme(testEnvCfg.Scheme)
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths: []string{"../../config/crd"},
+		Scheme:            testEnvCfg.Scheme,
+	}
+	env.ControlPlane.GetAPIServer().Configure().
+		Append("advertise-address", "127.0.0.1").
+		Append("bind-address", "127.0.0.1")
+
+	cfg, err := env.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start envtest: %v\n", err)
+		os.Exit(1)
+	}
+	testEnvCfg.Cfg = cfg
+
+	code := m.Run()
+
+	_ = env.Stop()
+	os.Exit(code)
+}
+
+// mockAccountSID is the account SID the mock accepts.
+const mockAccountSID = "AC00000000000000000000000000000001"
+
+// TestMock runs all scenarios with a canned HTTP transport.
+func TestMock(t *testing.T) {
+	t.Setenv("TEST_TWILIO_SUBACCOUNT_SID", mockAccountSID)
+
+	opts := godogOpts
+	status := godog.TestSuite{
+		Name: "provider-twilio-mock",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New(
+				internal.WithHTTPClient(&http.Client{Transport: &keysMock{}}),
+				internal.WithBaseURL("http://twilio.mock"),
+				internal.WithCredentials(mockAccountSID, "mock-token"),
+			)
+			shared := bddtest.New[*v1alpha1.TwilioAPIKey](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// TestE2E runs non-mock scenarios against a real Twilio account.
+func TestE2E(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e tests in short mode")
+	}
+
+	if os.Getenv("TEST_TWILIO_SUBACCOUNT_SID") == "" {
+		t.Skip("skipping e2e tests: TEST_TWILIO_SUBACCOUNT_SID not set")
+	}
+
+	opts := godogOpts
+	opts.Tags = "~@mock"
+	status := godog.TestSuite{
+		Name: "provider-twilio-e2e",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New()
+			shared := bddtest.New[*v1alpha1.TwilioAPIKey](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &opts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// keysMock is an [http.RoundTripper] that returns canned Twilio Keys API
+// responses. Only [mockAccountSID] exists, each key creation returns a unique
+// SID, and deletion always succeeds.
+type keysMock struct{}
+
+func (m *keysMock) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Paths look like /2010-04-01/Accounts/{accountSID}/Keys[/{keySID}].json.
+	parts := strings.Split(strings.TrimSuffix(req.URL.Path, ".json"), "/")
+	if len(parts) < 5 || parts[1] != "2010-04-01" || parts[2] != "Accounts" || parts[4] != "Keys" {
+		return errorResponse(http.StatusNotFound, "unexpected request: %s %s", req.Method, req.URL.Path)
+	}
+	if parts[3] != mockAccountSID {
+		return errorResponse(http.StatusNotFound, "The requested resource %s was not found", req.URL.Path)
+	}
+
+	switch {
+	case req.Method == http.MethodPost && len(parts) == 5:
+		if err := req.ParseForm(); err != nil {
+			return errorResponse(http.StatusBadRequest, "parsing request: %v", err)
+		}
+		return jsonResponse(http.StatusCreated, map[string]string{
+			"sid":           "SK" + strings.ReplaceAll(uuid.New().String(), "-", ""),
+			"friendly_name": req.PostForm.Get("FriendlyName"),
+			"secret":        "fake-key-secret",
+		})
+	case req.Method == http.MethodDelete && len(parts) == 6:
+		return &http.Response{
+			StatusCode: http.StatusNoContent,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil
+	default:
+		return errorResponse(http.StatusMethodNotAllowed, "unexpected request: %s %s", req.Method, req.URL.Path)
+	}
+}
+
+// errorResponse returns a Twilio error response. Not found responses carry
+// Twilio's error code 20404.
+func errorResponse(status int, format string, args ...any) (*http.Response, error) {
+	code := status
+	if status == http.StatusNotFound {
+		code = 20404
+	}
+	return jsonResponse(status, map[string]any{
+		"code":    code,
+		"message": fmt.Sprintf(format, args...),
+		"status":  status,
+	})
+}
+
+func jsonResponse(status int, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(string(data))),
+	}, nil
+}
@@ -0,0 +1,114 @@
+// Package abi defines the interface between the WASM provider and the
+// modules it runs.
+//
+// A module is a WASI preview 1 command, e.g. built from Go with
+// GOOS=wasip1 GOARCH=wasm. For every action, the provider instantiates it
+// afresh, runs its _start function with a [Request] as JSON on stdin, and
+// reads the result from stdout:
+//
+//   - "provision" creates a new credential. The module writes a
+//     [ProvisionResponse] as JSON to stdout.
+//   - "delete" deletes the credential with the given key ID. Its output is
+//     ignored. Modules should also exit successfully if the credential no
+//     longer exists.
+//
+// A module fails by exiting with a non-zero status. The end of what it wrote
+// to stderr is shown in the resource's status, so it should not contain
+// secrets. Modules see only the environment variables the operator passes
+// on, have no file system and cannot open sockets. Instead, they make HTTP
+// requests through the host functions of [HostModule], which the operator
+// only performs for the hosts it allows.
+//
+// Apart from the API version, requests and responses are those of the exec
+// provider's plugins. Package [github.com/lukasngl/valet/provider-wasm/guest]
+// implements the protocol for modules written in Go. The package only depends
+// on the standard library.
+package abi
+
+import (
+	"net/http"
+	"time"
+)
+
+// APIVersion is the version of the protocol sent in [Request.APIVersion].
+const APIVersion = "valet.ngl.cx/wasm/v1"
+
+// Actions sent in [Request.Action].
+const (
+	ActionProvision = "provision"
+	ActionDelete    = "delete"
+)
+
+// Request is written to the module's stdin.
+type Request struct {
+	// APIVersion is [APIVersion].
+	APIVersion string `json:"apiVersion"`
+
+	// Action is [ActionProvision] or [ActionDelete].
+	Action string `json:"action"`
+
+	// Resource identifies the resource the request is made for.
+	Resource Resource `json:"resource"`
+
+	// Parameters are the resource's spec.parameters.
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// KeyID is the ID of the credential to delete. Only set for
+	// [ActionDelete].
+	KeyID string `json:"keyId,omitempty"`
+}
+
+// Resource identifies a WasmCredential.
+type Resource struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	UID       string `json:"uid"`
+}
+
+// ProvisionResponse is written to stdout by a successful provision.
+type ProvisionResponse struct {
+	// KeyID identifies the new credential. It is sent back when the
+	// credential is deleted. Required.
+	KeyID string `json:"keyId"`
+
+	// Data are the values of the credential, written to the output Secret.
+	// Required.
+	Data map[string]string `json:"data"`
+
+	// ValidUntil is when the credential expires. It is renewed before then.
+	// Defaults to the resource's spec.validity.
+	ValidUntil *time.Time `json:"validUntil,omitempty"`
+
+	// SecretType is the type of a newly created output Secret, e.g.
+	// kubernetes.io/basic-auth. Defaults to Opaque.
+	SecretType string `json:"secretType,omitempty"`
+}
+
+// HostModule is the name of the module the operator's host functions are
+// imported from:
+//
+//   - http_do(ptr, len i32) i32 performs the [HTTPRequest] encoded as JSON
+//     in the len bytes of memory at ptr, and returns the length of the
+//     [HTTPResponse] it keeps for http_response.
+//   - http_response(ptr i32) copies that response, as JSON, to the memory
+//     at ptr, which must have room for it.
+const HostModule = "valet"
+
+// HTTPRequest is an HTTP request made through http_do. It is only made if
+// the operator allows its scheme, host and port.
+type HTTPRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// HTTPResponse is the response to an [HTTPRequest]. Error is set instead of
+// the other fields if no response was received, e.g. because the host is
+// not allowed or could not be reached.
+type HTTPResponse struct {
+	Status int         `json:"status,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
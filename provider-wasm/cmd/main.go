@@ -0,0 +1,115 @@
+// provider-wasm runs the WASM valet provider, which delegates to a WASM
+// module.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-wasm/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-wasm/internal"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// Build metadata, set with -ldflags "-X main.version=..." at build time.
+// See [framework.NewBuildInfo] for the values used if they are not.
+var (
+	version  = "dev"
+	provider = ""
+	revision = ""
+)
+
+var (
+	modulePath = flag.String(
+		"module",
+		"",
+		"WASM module to run for each provision and delete, with a JSON request on stdin.",
+	)
+	moduleTimeout = flag.Duration(
+		"module-timeout",
+		internal.DefaultTimeout,
+		"How long the module may run for a single action.",
+	)
+	moduleMemoryLimit = flag.Uint(
+		"module-memory-limit",
+		internal.DefaultMemoryLimit,
+		"Limit of the module's memory in MiB.",
+	)
+	moduleEnv = flag.String(
+		"module-env",
+		"",
+		"Comma-separated environment variables to pass on to the module.",
+	)
+	moduleAllowedURLs = flag.String(
+		"module-allowed-urls",
+		"",
+		"Comma-separated URLs whose scheme, host and port the module may make HTTP requests to.",
+	)
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// +kubebuilder:rbac:groups=valet.ngl.cx,resources=wasmcredentials,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=valet.ngl.cx,resources=wasmcredentials/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=valet.ngl.cx,resources=wasmcredentials/finalizers,verbs=update
+
+func run() error {
+	var opts framework.Options
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	// Provider
+	providerOpts := []internal.Option{
+		internal.WithModule(*modulePath),
+		internal.WithTimeout(*moduleTimeout),
+		internal.WithMemoryLimit(uint32(*moduleMemoryLimit)),
+	}
+	for _, name := range strings.Split(*moduleEnv, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			providerOpts = append(providerOpts, internal.WithEnv(name))
+		}
+	}
+	for _, raw := range strings.Split(*moduleAllowedURLs, ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+			return fmt.Errorf("--module-allowed-urls: invalid URL %q", raw)
+		}
+		providerOpts = append(providerOpts, internal.WithAllowedURLs(u))
+	}
+	wasm := internal.New(providerOpts...)
+	defer func() { _ = wasm.Close(context.Background()) }()
+
+	return framework.Run(ctrl.SetupSignalHandler(), &opts, framework.Operator[*v1alpha1.WasmCredential]{
+		Name:        "provider-wasm",
+		BuildInfo:   framework.NewBuildInfo(provider, version, revision),
+		AddToScheme: v1alpha1.AddToScheme,
+		Endpoints:   wasm,
+		// The module is compiled before starting, so that an invalid one is
+		// reported right away.
+		NewProvider: func(ctx context.Context, _ ctrl.Manager) (framework.Provider[*v1alpha1.WasmCredential], error) {
+			if *modulePath == "" {
+				return nil, errors.New("--module is required")
+			}
+			if err := wasm.Load(ctx); err != nil {
+				return nil, fmt.Errorf("loading module: %w", err)
+			}
+			return wasm, nil
+		},
+		LogValues: []any{"module", *modulePath},
+	})
+}
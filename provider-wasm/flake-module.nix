@@ -0,0 +1,70 @@
+{ inputs, ... }:
+{
+  perSystem =
+    { config, pkgs, ... }:
+    let
+      valet = config.valet.lib;
+
+      provider-wasm = valet.mkGoModule {
+        pname = "provider-wasm";
+        subPackages = [ "provider-wasm/cmd" ];
+        postInstall = ''
+          mv $out/bin/cmd $out/bin/provider-wasm
+        '';
+        meta.mainProgram = "provider-wasm";
+      };
+
+      provider-wasm-compressed = pkgs.stdenvNoCC.mkDerivation {
+        inherit (provider-wasm) pname version meta;
+        dontUnpack = true;
+        nativeBuildInputs = [ pkgs.upx ];
+        buildPhase = ''
+          mkdir -p $out/bin
+          upx -o $out/bin/provider-wasm ${provider-wasm}/bin/provider-wasm
+        '';
+      };
+
+      image = valet.mkImage {
+        name = "provider-wasm";
+        entrypoint = "${provider-wasm-compressed}/bin/provider-wasm";
+      };
+    in
+    {
+      packages = {
+        inherit provider-wasm provider-wasm-compressed;
+        provider-wasm-image = image;
+      };
+
+      checks.provider-wasm-helm = valet.packageChart {
+        name = "provider-wasm";
+        src = "${inputs.self}/provider-wasm/charts/provider-wasm";
+      };
+
+      checks.provider-wasm-lint = valet.withPackageEnv provider-wasm {
+        name = "provider-wasm-lint";
+        extraBuildInputs = [ pkgs.golangci-lint ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          golangci-lint run --timeout 10m ./provider-wasm/...
+        '';
+      };
+
+      checks.provider-wasm-test = valet.withPackageEnv provider-wasm {
+        name = "provider-wasm-test";
+        extraBuildInputs = [
+          pkgs.gotestsum
+          pkgs.etcd
+          pkgs.kubernetes
+        ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum --format short-verbose -- -coverpkg=github.com/lukasngl/valet/framework/...,./... -coverprofile=coverage.txt ./provider-wasm/...
+        '';
+        installPhase = ''
+          mkdir -p $out
+          cp coverage.txt $out/
+        '';
+      };
+    };
+}
@@ -0,0 +1,69 @@
+// Package guest implements the protocol of package abi for modules written in
+// Go and built with GOOS=wasip1 GOARCH=wasm:
+//
+//	func main() {
+//		guest.Main(handler{})
+//	}
+//
+// Built for other platforms, e.g. to test handlers with go test, [Transport]
+// uses the network directly.
+package guest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/lukasngl/valet/provider-wasm/abi"
+)
+
+// Handler implements the actions of a module.
+type Handler interface {
+	// Provision creates a new credential.
+	Provision(ctx context.Context, req *abi.Request) (*abi.ProvisionResponse, error)
+
+	// Delete deletes the credential req.KeyID. It should succeed if the
+	// credential no longer exists.
+	Delete(ctx context.Context, req *abi.Request) error
+}
+
+// Client is an HTTP client using [Transport].
+var Client = &http.Client{Transport: Transport}
+
+// Main handles the request on stdin with h and exits. Errors are written to
+// stderr, from where the operator shows them in the resource's status, and
+// exit with status 1.
+func Main(h Handler) {
+	if err := handle(context.Background(), h, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// handle decodes a request from in, calls h and encodes the response to out.
+func handle(ctx context.Context, h Handler, in io.Reader, out io.Writer) error {
+	var req abi.Request
+	if err := json.NewDecoder(in).Decode(&req); err != nil {
+		return fmt.Errorf("decoding request: %w", err)
+	}
+	if req.APIVersion != abi.APIVersion {
+		return fmt.Errorf("unsupported apiVersion %q", req.APIVersion)
+	}
+
+	switch req.Action {
+	case abi.ActionProvision:
+		resp, err := h.Provision(ctx, &req)
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(out).Encode(resp)
+	case abi.ActionDelete:
+		return h.Delete(ctx, &req)
+	default:
+		return fmt.Errorf("unknown action %q", req.Action)
+	}
+}
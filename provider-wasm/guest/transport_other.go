@@ -0,0 +1,8 @@
+//go:build !wasip1
+
+package guest
+
+import "net/http"
+
+// Transport is [http.DefaultTransport] outside of WASM.
+var Transport http.RoundTripper = http.DefaultTransport
@@ -0,0 +1,82 @@
+//go:build wasip1
+
+package guest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"unsafe"
+
+	"github.com/lukasngl/valet/provider-wasm/abi"
+)
+
+// Transport makes HTTP requests through the operator, see [abi.HostModule].
+var Transport http.RoundTripper = hostTransport{}
+
+//go:wasmimport valet http_do
+func httpDo(ptr unsafe.Pointer, size uint32) uint32
+
+//go:wasmimport valet http_response
+func httpResponse(ptr unsafe.Pointer)
+
+// hostTransport makes requests with the host functions of [abi.HostModule].
+// The request's context is not passed on, the operator limits how long the
+// module runs instead.
+type hostTransport struct{}
+
+func (hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading request body: %w", err)
+		}
+		body = b
+	}
+
+	in, err := json.Marshal(abi.HTTPRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header,
+		Body:   body,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding request: %w", err)
+	}
+
+	n := httpDo(unsafe.Pointer(unsafe.SliceData(in)), uint32(len(in)))
+	if n == 0 {
+		return nil, errors.New("no response from host")
+	}
+	out := make([]byte, n)
+	httpResponse(unsafe.Pointer(unsafe.SliceData(out)))
+
+	var resp abi.HTTPResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+
+	header := resp.Header
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.Status, http.StatusText(resp.Status)),
+		StatusCode:    resp.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(resp.Body)),
+		ContentLength: int64(len(resp.Body)),
+		Request:       req,
+	}, nil
+}
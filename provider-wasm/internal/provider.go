@@ -0,0 +1,484 @@
+// Package internal contains the WASM provider implementation.
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-wasm/abi"
+	"github.com/lukasngl/valet/provider-wasm/api/v1alpha1"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultValidity is the default credential rotation period (90 days).
+const DefaultValidity = 90 * 24 * time.Hour
+
+// DefaultTimeout is how long the module may run for a single action.
+const DefaultTimeout = 30 * time.Second
+
+// DefaultMemoryLimit is the default limit of a module's memory in MiB.
+const DefaultMemoryLimit = 128
+
+// maxStderr limits how much of the end of the module's stderr is kept for
+// error messages, which end up in the resource's status.
+const maxStderr = 1024
+
+// maxResponseBody limits the size of response bodies passed to the module.
+const maxResponseBody = 10 << 20
+
+// Provider provisions credentials by running a WASM module with a JSON
+// request on stdin, following the protocol of package [abi]. It implements
+// [framework.Provider] for [*v1alpha1.WasmCredential].
+//
+// The module is configured on the operator, not per resource, and runs
+// isolated from it: it only sees the environment variables passed on with
+// [WithEnv], and only reaches the URLs allowed with [WithAllowedURLs].
+type Provider struct {
+	modulePath  string
+	timeout     time.Duration
+	memoryLimit uint32
+	env         []string
+	allowed     []*url.URL
+	client      *http.Client
+
+	once     sync.Once
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	err      error
+}
+
+// Option configures a [Provider].
+type Option func(*Provider)
+
+// WithModule sets the path of the module. It is required.
+func WithModule(path string) Option {
+	return func(p *Provider) { p.modulePath = path }
+}
+
+// WithTimeout sets how long the module may run for a single action.
+// Defaults to [DefaultTimeout].
+func WithTimeout(d time.Duration) Option {
+	return func(p *Provider) { p.timeout = d }
+}
+
+// WithMemoryLimit sets the limit of the module's memory in MiB. Defaults to
+// [DefaultMemoryLimit].
+func WithMemoryLimit(mib uint32) Option {
+	return func(p *Provider) { p.memoryLimit = mib }
+}
+
+// WithEnv passes the operator's environment variables with the given names
+// on to the module, e.g. the credentials of the system it manages.
+func WithEnv(names ...string) Option {
+	return func(p *Provider) { p.env = append(p.env, names...) }
+}
+
+// WithAllowedURLs allows the module to make HTTP requests to the scheme,
+// host and port of the given URLs. Their paths are ignored.
+func WithAllowedURLs(urls ...*url.URL) Option {
+	return func(p *Provider) { p.allowed = append(p.allowed, urls...) }
+}
+
+// WithHTTPClient sets the client the module's HTTP requests are made with.
+// Defaults to [http.DefaultClient].
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Provider) { p.client = c }
+}
+
+// New creates a [Provider] with the given options.
+func New(opts ...Option) *Provider {
+	p := &Provider{
+		timeout:     DefaultTimeout,
+		memoryLimit: DefaultMemoryLimit,
+		client:      http.DefaultClient,
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// Load compiles the module, which otherwise happens on first use, so that
+// an invalid module is reported at startup.
+func (p *Provider) Load(ctx context.Context) error {
+	p.once.Do(func() { p.err = p.load(ctx) })
+	return p.err
+}
+
+// load creates the runtime with the host functions and compiles the module.
+func (p *Provider) load(ctx context.Context) error {
+	if p.modulePath == "" {
+		return errors.New("no module configured")
+	}
+	code, err := os.ReadFile(p.modulePath)
+	if err != nil {
+		return fmt.Errorf("reading module: %w", err)
+	}
+
+	// A page of WASM memory is 64 KiB.
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(p.memoryLimit*16).
+		WithCloseOnContextDone(true))
+
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		_ = r.Close(ctx)
+		return fmt.Errorf("instantiating WASI: %w", err)
+	}
+	_, err = r.NewHostModuleBuilder(abi.HostModule).
+		NewFunctionBuilder().WithFunc(httpDo).Export("http_do").
+		NewFunctionBuilder().WithFunc(httpResponse).Export("http_response").
+		Instantiate(ctx)
+	if err != nil {
+		_ = r.Close(ctx)
+		return fmt.Errorf("instantiating host module: %w", err)
+	}
+
+	compiled, err := r.CompileModule(ctx, code)
+	if err != nil {
+		_ = r.Close(ctx)
+		return fmt.Errorf("compiling module %s: %w", p.modulePath, err)
+	}
+
+	p.runtime, p.compiled = r, compiled
+	return nil
+}
+
+// Close releases the compiled module.
+func (p *Provider) Close(ctx context.Context) error {
+	if p.runtime == nil {
+		return nil
+	}
+	return p.runtime.Close(ctx)
+}
+
+// Endpoints implements [framework.EndpointDeclarer] with the allowed URLs.
+func (p *Provider) Endpoints() ([]framework.Endpoint, error) {
+	endpoints := make([]framework.Endpoint, 0, len(p.allowed))
+	for _, u := range p.allowed {
+		e, err := framework.EndpointFromURL("module", u.String())
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, nil
+}
+
+// NewObject returns a zero-value WasmCredential.
+func (p *Provider) NewObject() *v1alpha1.WasmCredential {
+	return &v1alpha1.WasmCredential{}
+}
+
+// Provision runs the module to create a new credential. The returned data is
+// written to the output Secret together with the rendered templates.
+func (p *Provider) Provision(
+	ctx context.Context,
+	obj *v1alpha1.WasmCredential,
+) (*framework.Result, error) {
+	var resp abi.ProvisionResponse
+	if err := p.run(ctx, obj, abi.ActionProvision, "", &resp); err != nil {
+		return nil, fmt.Errorf("provisioning credential: %w", err)
+	}
+	if resp.KeyID == "" {
+		return nil, errors.New("no keyId returned from module")
+	}
+	if len(resp.Data) == 0 {
+		return nil, errors.New("no data returned from module")
+	}
+
+	now := time.Now()
+
+	validity := DefaultValidity
+	if obj.Spec.Validity != nil {
+		validity = obj.Spec.Validity.Duration
+	}
+	validUntil := now.Add(validity)
+	if resp.ValidUntil != nil && resp.ValidUntil.Before(validUntil) {
+		if !resp.ValidUntil.After(now) {
+			return nil, fmt.Errorf("module returned credential %s that expired at %s",
+				resp.KeyID, resp.ValidUntil.Format(time.RFC3339))
+		}
+		validUntil = *resp.ValidUntil
+	}
+
+	data := make(map[string]string, len(resp.Data)+len(obj.Spec.Template))
+	for key, value := range resp.Data {
+		data[key] = value
+	}
+
+	templateData := map[string]any{
+		"Data":  resp.Data,
+		"KeyID": resp.KeyID,
+	}
+	for key, tmpl := range obj.Spec.Template {
+		rendered, err := renderTemplate(tmpl, templateData)
+		if err != nil {
+			return nil, fmt.Errorf("rendering template %q: %w", key, err)
+		}
+		data[key] = rendered
+	}
+
+	return &framework.Result{
+		StringData:    data,
+		ProvisionedAt: now,
+		ValidUntil:    validUntil,
+		KeyID:         resp.KeyID,
+		SecretType:    corev1.SecretType(resp.SecretType),
+	}, nil
+}
+
+// DeleteKey runs the module to delete a credential. The module is expected to
+// succeed if the credential no longer exists.
+func (p *Provider) DeleteKey(
+	ctx context.Context,
+	obj *v1alpha1.WasmCredential,
+	keyID string,
+) error {
+	if keyID == "" {
+		return nil
+	}
+
+	if err := p.run(ctx, obj, abi.ActionDelete, keyID, nil); err != nil {
+		return fmt.Errorf("deleting credential %s: %w", keyID, err)
+	}
+
+	return nil
+}
+
+// run instantiates the module for the given action and decodes its stdout
+// into out, if non-nil. Failures of the module are returned as
+// [*moduleError].
+func (p *Provider) run(
+	ctx context.Context,
+	obj *v1alpha1.WasmCredential,
+	action, keyID string,
+	out any,
+) error {
+	if err := p.Load(ctx); err != nil {
+		return err
+	}
+
+	input, err := json.Marshal(abi.Request{
+		APIVersion: abi.APIVersion,
+		Action:     action,
+		Resource: abi.Resource{
+			Namespace: obj.Namespace,
+			Name:      obj.Name,
+			UID:       string(obj.UID),
+		},
+		Parameters: obj.Spec.Parameters,
+		KeyID:      keyID,
+	})
+	if err != nil {
+		return fmt.Errorf("encoding request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	config := wazero.NewModuleConfig().
+		// Unnamed, so that it can be instantiated concurrently.
+		WithName("").
+		WithArgs("module").
+		WithStdin(bytes.NewReader(input)).
+		WithStdout(&stdout).
+		WithStderr(&stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithSysNanosleep().
+		WithRandSource(rand.Reader)
+	for _, name := range p.env {
+		if value, ok := os.LookupEnv(name); ok {
+			config = config.WithEnv(name, value)
+		}
+	}
+
+	log.FromContext(ctx).V(1).Info("running module", "action", action, "module", p.modulePath)
+
+	start := time.Now()
+	mod, err := p.runtime.InstantiateModule(withCall(ctx, &call{provider: p}), p.compiled, config)
+	if mod != nil {
+		defer func() { _ = mod.Close(ctx) }()
+	}
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("module timed out after %s", p.timeout)
+		}
+		var exitErr *sys.ExitError
+		if !errors.As(err, &exitErr) {
+			return fmt.Errorf("running module: %w", err)
+		}
+		if exitErr.ExitCode() != 0 {
+			return &moduleError{ExitCode: exitErr.ExitCode(), Stderr: tail(stderr.String())}
+		}
+	}
+
+	log.FromContext(ctx).V(1).Info("module finished", "action", action, "duration", time.Since(start))
+
+	if out != nil {
+		if err := json.Unmarshal(stdout.Bytes(), out); err != nil {
+			return fmt.Errorf("parsing module output: %w", err)
+		}
+	}
+	return nil
+}
+
+// allows reports whether the module may make requests to u.
+func (p *Provider) allows(u *url.URL) bool {
+	for _, a := range p.allowed {
+		if a.Scheme == u.Scheme && a.Hostname() == u.Hostname() && port(a) == port(u) {
+			return true
+		}
+	}
+	return false
+}
+
+// port returns the port of u, or the default port of its scheme.
+func port(u *url.URL) string {
+	if p := u.Port(); p != "" {
+		return p
+	}
+	if u.Scheme == "http" {
+		return "80"
+	}
+	return "443"
+}
+
+// call is the state of one instantiation of the module, passed to the host
+// functions in the context.
+type call struct {
+	provider *Provider
+	// response is the encoded response of the last http_do.
+	response []byte
+}
+
+type callKey struct{}
+
+func withCall(ctx context.Context, c *call) context.Context {
+	return context.WithValue(ctx, callKey{}, c)
+}
+
+// httpDo implements http_do, see [abi.HostModule].
+func httpDo(ctx context.Context, m api.Module, ptr, size uint32) uint32 {
+	c := ctx.Value(callKey{}).(*call)
+
+	var resp abi.HTTPResponse
+	if in, ok := m.Memory().Read(ptr, size); !ok {
+		resp.Error = "request out of memory range"
+	} else {
+		resp = c.provider.do(ctx, in)
+	}
+
+	out, err := json.Marshal(resp)
+	if err != nil {
+		out, _ = json.Marshal(abi.HTTPResponse{Error: err.Error()})
+	}
+	c.response = out
+	return uint32(len(out))
+}
+
+// httpResponse implements http_response, see [abi.HostModule].
+func httpResponse(ctx context.Context, m api.Module, ptr uint32) {
+	c := ctx.Value(callKey{}).(*call)
+	if !m.Memory().Write(ptr, c.response) {
+		panic("response out of memory range")
+	}
+	c.response = nil
+}
+
+// do performs the encoded [abi.HTTPRequest] if it is allowed.
+func (p *Provider) do(ctx context.Context, in []byte) abi.HTTPResponse {
+	var r abi.HTTPRequest
+	if err := json.Unmarshal(in, &r); err != nil {
+		return abi.HTTPResponse{Error: fmt.Sprintf("decoding request: %v", err)}
+	}
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return abi.HTTPResponse{Error: err.Error()}
+	}
+	if !p.allows(u) {
+		return abi.HTTPResponse{Error: fmt.Sprintf("%s://%s is not allowed", u.Scheme, u.Host)}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.Method, u.String(), bytes.NewReader(r.Body))
+	if err != nil {
+		return abi.HTTPResponse{Error: err.Error()}
+	}
+	for key, values := range r.Header {
+		req.Header[key] = values
+	}
+
+	log.FromContext(ctx).V(1).Info("module request", "method", req.Method, "host", u.Host)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return abi.HTTPResponse{Error: err.Error()}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody+1))
+	if err != nil {
+		return abi.HTTPResponse{Error: fmt.Sprintf("reading response: %v", err)}
+	}
+	if len(body) > maxResponseBody {
+		return abi.HTTPResponse{Error: fmt.Sprintf("response larger than %d bytes", maxResponseBody)}
+	}
+
+	return abi.HTTPResponse{Status: resp.StatusCode, Header: resp.Header, Body: body}
+}
+
+// tail returns the end of the module's stderr, at most [maxStderr] bytes.
+func tail(stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if len(stderr) > maxStderr {
+		stderr = "..." + stderr[len(stderr)-maxStderr:]
+	}
+	return stderr
+}
+
+// moduleError is a module exiting with a non-zero status.
+type moduleError struct {
+	ExitCode uint32
+	Stderr   string
+}
+
+func (e *moduleError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("module exited with status %d", e.ExitCode)
+	}
+	return fmt.Sprintf("module exited with status %d: %s", e.ExitCode, e.Stderr)
+}
+
+// renderTemplate renders a Go template string with the given data.
+func renderTemplate(tmpl string, data any) (string, error) {
+	t, err := template.New("").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
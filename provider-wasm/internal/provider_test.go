@@ -0,0 +1,295 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-wasm/abi"
+	"github.com/lukasngl/valet/provider-wasm/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeModule is the path of the module built from testdata/fake.
+var fakeModule string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "provider-wasm")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fakeModule = filepath.Join(dir, "fake.wasm")
+
+	cmd := exec.Command("go", "build", "-o", fakeModule, "./testdata/fake")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "building fake module: %v\n", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newTestProvider returns a provider running the fake module with the given
+// options and an object with the given parameters.
+func newTestProvider(
+	t *testing.T,
+	params map[string]string,
+	opts ...Option,
+) (*Provider, *v1alpha1.WasmCredential) {
+	t.Helper()
+	obj := &v1alpha1.WasmCredential{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "db", UID: "uid-1"},
+		Spec: v1alpha1.WasmCredentialSpec{
+			SecretRef:  framework.SecretReference{Name: "db-credentials"},
+			Parameters: params,
+		},
+	}
+	p := New(append([]Option{WithModule(fakeModule), WithTimeout(10 * time.Second)}, opts...)...)
+	t.Cleanup(func() { _ = p.Close(context.Background()) })
+	return p, obj
+}
+
+func TestProvision(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		p, obj := newTestProvider(t, nil)
+		obj.Spec.Template = map[string]string{"DSN": "postgres://{{ .Data.username }}@db/orders?id={{ .KeyID }}"}
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.KeyID != "user-1" {
+			t.Fatalf("got keyID %q, want user-1", result.KeyID)
+		}
+		if result.SecretType != corev1.SecretTypeBasicAuth {
+			t.Fatalf("got secret type %q", result.SecretType)
+		}
+		if got := result.StringData["apiVersion"]; got != abi.APIVersion {
+			t.Fatalf("module got apiVersion %q", got)
+		}
+		if got := result.StringData["resource"]; got != "apps/db/uid-1" {
+			t.Fatalf("module got resource %q", got)
+		}
+		if got := result.StringData["DSN"]; got != "postgres://db-user-1@db/orders?id=user-1" {
+			t.Fatalf("got rendered template %q", got)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != DefaultValidity {
+			t.Fatalf("got validity %v, want %v", got, DefaultValidity)
+		}
+	})
+
+	t.Run("expiry from module", func(t *testing.T) {
+		expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+		p, obj := newTestProvider(t, map[string]string{"expiry": expiry.Format(time.RFC3339)})
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.ValidUntil.Equal(expiry) {
+			t.Fatalf("got validUntil %v, want %v", result.ValidUntil, expiry)
+		}
+	})
+
+	t.Run("environment", func(t *testing.T) {
+		t.Setenv("VALET_WASM_PASSED", "passed")
+		t.Setenv("VALET_WASM_HIDDEN", "hidden")
+
+		p, obj := newTestProvider(t, map[string]string{"env": "VALET_WASM_PASSED"}, WithEnv("VALET_WASM_PASSED"))
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.StringData["env"]; got != "passed" {
+			t.Fatalf("got %q for a passed variable", got)
+		}
+
+		obj.Spec.Parameters["env"] = "VALET_WASM_HIDDEN"
+		result, err = p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.StringData["env"]; got != "" {
+			t.Fatalf("got %q for a variable that was not passed", got)
+		}
+	})
+
+	t.Run("allowed HTTP request", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, "%s %s", r.Method, r.URL.Path)
+		}))
+		defer srv.Close()
+		allowed, _ := url.Parse(srv.URL)
+
+		p, obj := newTestProvider(t, map[string]string{"url": srv.URL + "/users"}, WithAllowedURLs(allowed))
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.StringData["body"]; got != "GET /users" {
+			t.Fatalf("got body %q", got)
+		}
+	})
+
+	errorTests := []struct {
+		name    string
+		params  map[string]string
+		opts    []Option
+		wantErr string
+	}{
+		{
+			name:    "non-zero exit",
+			params:  map[string]string{"exit": "3"},
+			wantErr: "module exited with status 3: something went wrong",
+		},
+		{
+			name:    "returned error",
+			params:  map[string]string{"reject": "database orders is not managed"},
+			wantErr: "module exited with status 1: database orders is not managed",
+		},
+		{
+			name:    "invalid output",
+			params:  map[string]string{"output": "not json"},
+			wantErr: "parsing module output",
+		},
+		{
+			name:    "missing keyId",
+			params:  map[string]string{"output": `{"data":{"token":"t"}}`},
+			wantErr: "no keyId",
+		},
+		{
+			name:    "missing data",
+			params:  map[string]string{"output": `{"keyId":"k"}`},
+			wantErr: "no data",
+		},
+		{
+			name:    "expired credential",
+			params:  map[string]string{"expiry": time.Now().Add(-time.Minute).Format(time.RFC3339)},
+			wantErr: "expired",
+		},
+		{
+			name:    "disallowed HTTP request",
+			params:  map[string]string{"url": "https://example.com/users"},
+			wantErr: "https://example.com is not allowed",
+		},
+		{
+			name:    "timeout",
+			params:  map[string]string{"spin": "30s"},
+			opts:    []Option{WithTimeout(500 * time.Millisecond)},
+			wantErr: "timed out",
+		},
+		{
+			name:    "no module",
+			opts:    []Option{WithModule("")},
+			wantErr: "no module configured",
+		},
+		{
+			name:    "missing module",
+			opts:    []Option{WithModule("/nonexistent/module.wasm")},
+			wantErr: "reading module",
+		},
+		{
+			name:    "invalid module",
+			opts:    []Option{WithModule("provider.go")},
+			wantErr: "compiling module",
+		},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, obj := newTestProvider(t, tt.params, tt.opts...)
+
+			_, err := p.Provision(context.Background(), obj)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error %v does not contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeleteKey(t *testing.T) {
+	t.Run("deletes the key", func(t *testing.T) {
+		p, obj := newTestProvider(t, nil)
+		if err := p.DeleteKey(context.Background(), obj, "user-1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("module failure", func(t *testing.T) {
+		p, obj := newTestProvider(t, nil)
+		err := p.DeleteKey(context.Background(), obj, "user-2")
+		if err == nil || !strings.Contains(err.Error(), `unexpected key ID "user-2"`) {
+			t.Fatalf("expected the module's stderr in the error, got %v", err)
+		}
+	})
+
+	t.Run("empty key ID", func(t *testing.T) {
+		p, obj := newTestProvider(t, map[string]string{"exit": "1"})
+		if err := p.DeleteKey(context.Background(), obj, ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestEndpoints(t *testing.T) {
+	api, _ := url.Parse("https://api.example.com/v1")
+	users, _ := url.Parse("http://users.internal:8080")
+
+	endpoints, err := New(WithAllowedURLs(api, users)).Endpoints()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(endpoints) != 2 {
+		t.Fatalf("got %d endpoints, want 2", len(endpoints))
+	}
+	if got := endpoints[0].String(); got != "api.example.com:443" {
+		t.Fatalf("got endpoint %s", got)
+	}
+	if got := endpoints[1].String(); got != "users.internal:8080" {
+		t.Fatalf("got endpoint %s", got)
+	}
+}
+
+func TestAllows(t *testing.T) {
+	api, _ := url.Parse("https://api.example.com")
+	p := New(WithAllowedURLs(api))
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{url: "https://api.example.com/v1/users", want: true},
+		{url: "https://api.example.com:443/", want: true},
+		{url: "http://api.example.com/", want: false},
+		{url: "https://api.example.com:8443/", want: false},
+		{url: "https://api.example.com.evil/", want: false},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := p.allows(u); got != tt.want {
+			t.Errorf("allows(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestTail(t *testing.T) {
+	long := strings.Repeat("x", 2*maxStderr) + "end"
+	got := tail(long + "\n")
+	if !strings.HasPrefix(got, "...") || !strings.HasSuffix(got, "end") || len(got) != maxStderr+3 {
+		t.Fatalf("unexpected tail of length %d: %q", len(got), got[:10])
+	}
+}
@@ -0,0 +1,118 @@
+// fake is the module the tests run. It provisions numbered users, counted
+// by the HTTP server in FAKE_COUNTER_URL if set. The parameters "exit",
+// "output", "expiry", "spin", "env" and "url" make it fail with that status,
+// print that output, return that expiry, spin that long, return that
+// environment variable and fetch that URL, respectively. Provision requests
+// with a "reject" parameter fail with its value.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/lukasngl/valet/provider-wasm/abi"
+	"github.com/lukasngl/valet/provider-wasm/guest"
+)
+
+func main() {
+	guest.Main(handler{})
+}
+
+type handler struct{}
+
+func (handler) Provision(ctx context.Context, req *abi.Request) (*abi.ProvisionResponse, error) {
+	if err := common(req); err != nil {
+		return nil, err
+	}
+	if reason, ok := req.Parameters["reject"]; ok {
+		return nil, errors.New(reason)
+	}
+
+	n := 1
+	if url := os.Getenv("FAKE_COUNTER_URL"); url != "" {
+		body, err := get(url + "/" + req.Resource.UID)
+		if err != nil {
+			return nil, err
+		}
+		if n, err = strconv.Atoi(body); err != nil {
+			return nil, fmt.Errorf("invalid counter %q", body)
+		}
+	}
+
+	id := "user-" + strconv.Itoa(n)
+	resp := &abi.ProvisionResponse{
+		KeyID: id,
+		Data: map[string]string{
+			"apiVersion": req.APIVersion,
+			"resource":   req.Resource.Namespace + "/" + req.Resource.Name + "/" + req.Resource.UID,
+			"username":   req.Resource.Name + "-" + id,
+			"password":   "fake-password-" + id,
+		},
+		SecretType: "kubernetes.io/basic-auth",
+	}
+	if expiry, err := time.Parse(time.RFC3339, req.Parameters["expiry"]); err == nil {
+		resp.ValidUntil = &expiry
+	}
+	if name, ok := req.Parameters["env"]; ok {
+		resp.Data["env"] = os.Getenv(name)
+	}
+	if url, ok := req.Parameters["url"]; ok {
+		body, err := get(url)
+		if err != nil {
+			return nil, err
+		}
+		resp.Data["body"] = body
+	}
+	return resp, nil
+}
+
+func (handler) Delete(ctx context.Context, req *abi.Request) error {
+	if err := common(req); err != nil {
+		return err
+	}
+	if req.KeyID != "user-1" && os.Getenv("FAKE_COUNTER_URL") == "" {
+		return fmt.Errorf("unexpected key ID %q", req.KeyID)
+	}
+	return nil
+}
+
+// common handles the parameters shared by all actions.
+func common(req *abi.Request) error {
+	if d, err := time.ParseDuration(req.Parameters["spin"]); err == nil {
+		// Busy, since sleeping blocks in the host.
+		for start := time.Now(); time.Since(start) < d; {
+		}
+	}
+	if code, err := strconv.Atoi(req.Parameters["exit"]); err == nil {
+		fmt.Fprintln(os.Stderr, "something went wrong")
+		os.Exit(code)
+	}
+	if output, ok := req.Parameters["output"]; ok {
+		fmt.Print(output)
+		os.Exit(0)
+	}
+	return nil
+}
+
+// get returns the body of a successful GET request.
+func get(url string) (string, error) {
+	resp, err := guest.Client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	return string(body), nil
+}
@@ -0,0 +1,139 @@
+package e2e
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/cucumber/godog"
+	"github.com/cucumber/godog/colors"
+	"github.com/lukasngl/valet/framework/bddtest"
+	"github.com/lukasngl/valet/provider-wasm/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-wasm/internal"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var godogOpts = godog.Options{
+	Format:      "pretty",
+	Output:      colors.Colored(os.Stdout),
+	Paths:       []string{"../../features"},
+	Concurrency: 1,
+	Strict:      true,
+}
+
+func init() {
+	godog.BindFlags("godog.", flag.CommandLine, &godogOpts)
+}
+
+var testEnvCfg bddtest.Env
+
+// fakeModule is the path of the module built from internal/testdata/fake.
+var fakeModule string
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	if len(flag.Args()) > 0 {
+		godogOpts.Paths = flag.Args()
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	dir, err := os.MkdirTemp("", "provider-wasm-e2e")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fakeModule = filepath.Join(dir, "fake.wasm")
+
+	cmd := exec.Command("go", "build", "-o", fakeModule, "../../internal/testdata/fake")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "building fake module: %v\n", err)
+		os.Exit(1)
+	}
+
+	testEnvCfg.Scheme = runtime.NewScheme()
+	_ = corev1.AddToScheme(testEnvCfg.Scheme)
+	_ = v1alpha1.AddToScheme(testEnvCfg.Scheme)
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths: []string{"../../config/crd"},
+		Scheme:            testEnvCfg.Scheme,
+	}
+	env.ControlPlane.GetAPIServer().Configure().
+		Append("advertise-address", "127.0.0.1").
+		Append("bind-address", "127.0.0.1")
+
+	cfg, err := env.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start envtest: %v\n", err)
+		os.Exit(1)
+	}
+	testEnvCfg.Cfg = cfg
+
+	code := m.Run()
+
+	_ = env.Stop()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// TestFeatures runs all scenarios with the fake module, which counts the
+// credentials of each resource with [counter], so no external service is
+// needed. The module is compiled once for all scenarios.
+func TestFeatures(t *testing.T) {
+	srv := httptest.NewServer(&counter{counts: make(map[string]int)})
+	defer srv.Close()
+	allowed, _ := url.Parse(srv.URL)
+	t.Setenv("FAKE_COUNTER_URL", srv.URL)
+
+	p := internal.New(
+		internal.WithModule(fakeModule),
+		internal.WithEnv("FAKE_COUNTER_URL"),
+		internal.WithAllowedURLs(allowed),
+	)
+	defer func() { _ = p.Close(context.Background()) }()
+	if err := p.Load(context.Background()); err != nil {
+		t.Fatalf("loading module: %v", err)
+	}
+
+	status := godog.TestSuite{
+		Name: "provider-wasm",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			shared := bddtest.New[*v1alpha1.WasmCredential](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+		},
+		Options: &godogOpts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// counter returns how often its path was requested.
+type counter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (c *counter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[r.URL.Path]++
+	_, _ = fmt.Fprint(w, c.counts[r.URL.Path])
+}
@@ -0,0 +1,249 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DefaultSigningKey is the key of the HMAC signing key in the Secret
+// referenced by [WebhookCredentialSpec.SigningSecretRef] if none is given.
+const DefaultSigningKey = "key"
+
+func init() {
+	SchemeBuilder.Register(&WebhookCredential{}, &WebhookCredentialList{})
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=whcred,categories=valet
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// WebhookCredential provisions and rotates credentials through an external
+// HTTPS endpoint implementing the valet webhook contract.
+type WebhookCredential struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	Spec WebhookCredentialSpec `json:"spec,omitzero"`
+	// +optional
+	Status framework.ClientSecretStatus `json:"status,omitzero"`
+}
+
+// WebhookCredentialSpec defines the desired state.
+type WebhookCredentialSpec struct {
+	// SecretRef is the Kubernetes Secret to create/update with the
+	// credential data returned by the endpoint.
+	// Defaults to a Secret named after the resource.
+	// +optional
+	SecretRef framework.SecretReference `json:"secretRef,omitzero"`
+
+	// Suspend stops the rotation and cleanup of the credentials while set,
+	// e.g. during incidents or migrations. The output Secret keeps its
+	// current credentials.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DeletionPolicy controls what happens when the resource is deleted:
+	// Delete deletes its keys at the provider and the output Secret with it,
+	// Retain keeps both, e.g. to move the resource to another cluster.
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy framework.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// SecretTemplate sets labels, annotations and the type of the output
+	// Secret, e.g. for Reloader, External Secrets or Argo CD to match on.
+	// +optional
+	SecretTemplate framework.SecretTemplate `json:"secretTemplate,omitzero"`
+
+	// Targets are further Secrets the credentials are written to, each
+	// with a subset of the keys, its own template or in another namespace,
+	// so that consumers expecting different shapes share one credential.
+	// +optional
+	Targets framework.OutputTargets `json:"targets,omitempty"`
+
+	// Replication copies the output Secret into all namespaces matching a
+	// label selector that allow it, and keeps the copies in sync.
+	// +optional
+	Replication *framework.Replication `json:"replication,omitempty"`
+
+	// URL is the HTTPS endpoint that provisions and deletes credentials.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^https://`
+	URL string `json:"url"`
+
+	// SigningSecretRef is a Secret in the same namespace holding the key
+	// requests are signed with (HMAC-SHA256). The endpoint verifies the
+	// signature with the same key.
+	// +kubebuilder:validation:Required
+	SigningSecretRef SigningSecretReference `json:"signingSecretRef"`
+
+	// Parameters are passed to the endpoint with every request, e.g. to
+	// select the account or the permissions of the credentials.
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// Validity is how long each credential is used before it is rotated, if
+	// the endpoint does not return an expiry. An expiry returned by the
+	// endpoint is capped to it. Defaults to 90 days (2160h).
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// Rotation configures how credentials are rotated, e.g. how long a
+	// superseded credential stays valid.
+	// +optional
+	Rotation framework.RotationPolicy `json:"rotation,omitzero"`
+
+	// Template maps additional output secret keys to Go template strings.
+	// Template keys take precedence over data returned by the endpoint.
+	// Available template variables: .Data (the returned data, e.g.
+	// {{ .Data.password }}), .KeyID
+	// +optional
+	Template map[string]string `json:"template,omitempty"`
+}
+
+// SigningSecretReference selects a key of a Secret in the namespace of the
+// resource.
+type SigningSecretReference struct {
+	// Name of the Secret.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Key of the signing key in the Secret. Defaults to "key".
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// GetSecretRef returns the reference to the target output Secret, defaulting
+// to a Secret named after the resource.
+func (w *WebhookCredential) GetSecretRef() framework.SecretReference {
+	return w.Spec.SecretRef.OrDefault(w.Name)
+}
+
+// IsSuspended reports whether the rotation of the credentials is suspended.
+// It implements [framework.Suspender].
+func (w *WebhookCredential) IsSuspended() bool {
+	return w.Spec.Suspend
+}
+
+// GetDeletionPolicy returns what happens to the keys and the output Secret
+// when the resource is deleted. It implements [framework.DeletionPolicyHolder].
+func (w *WebhookCredential) GetDeletionPolicy() framework.DeletionPolicy {
+	return w.Spec.DeletionPolicy
+}
+
+// GetSecretTemplate returns the labels, annotations and type of the output
+// Secret. It implements [framework.SecretTemplateHolder].
+func (w *WebhookCredential) GetSecretTemplate() framework.SecretTemplate {
+	return w.Spec.SecretTemplate
+}
+
+// GetTargets returns the further Secrets the credentials are written to.
+// It implements [framework.TargetHolder].
+func (w *WebhookCredential) GetTargets() framework.OutputTargets {
+	return w.Spec.Targets
+}
+
+// GetReplication returns the replication of the output Secret, or nil.
+// It implements [framework.ReplicationHolder].
+func (w *WebhookCredential) GetReplication() *framework.Replication {
+	return w.Spec.Replication
+}
+
+// GetValidity returns the requested validity of the credentials, or zero for
+// the provider's default. It implements [framework.ValidityRequester].
+func (w *WebhookCredential) GetValidity() time.Duration {
+	if w.Spec.Validity == nil {
+		return 0
+	}
+	return w.Spec.Validity.Duration
+}
+
+// GetRotationPolicy returns the rotation policy of the credentials. It
+// implements [framework.RotationPolicyHolder].
+func (w *WebhookCredential) GetRotationPolicy() framework.RotationPolicy {
+	return w.Spec.Rotation
+}
+
+// GetStatus returns a pointer to the shared status.
+func (w *WebhookCredential) GetStatus() *framework.ClientSecretStatus {
+	return &w.Status
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (w *WebhookCredential) DeepCopyObject() runtime.Object {
+	cp := *w
+	cp.ObjectMeta = *w.DeepCopy()
+	cp.Status = w.Status.DeepCopy()
+	cp.Spec.SecretTemplate = w.Spec.SecretTemplate.DeepCopy()
+	cp.Spec.Targets = w.Spec.Targets.DeepCopy()
+	cp.Spec.Replication = w.Spec.Replication.DeepCopy()
+	if w.Spec.Parameters != nil {
+		cp.Spec.Parameters = make(map[string]string, len(w.Spec.Parameters))
+		for k, v := range w.Spec.Parameters {
+			cp.Spec.Parameters[k] = v
+		}
+	}
+	if w.Spec.Template != nil {
+		cp.Spec.Template = make(map[string]string, len(w.Spec.Template))
+		for k, v := range w.Spec.Template {
+			cp.Spec.Template[k] = v
+		}
+	}
+	if w.Spec.Validity != nil {
+		v := *w.Spec.Validity
+		cp.Spec.Validity = &v
+	}
+	return &cp
+}
+
+// Validate performs structural validation of the spec.
+func (w *WebhookCredential) Validate() error {
+	endpoint, err := url.Parse(w.Spec.URL)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		return fmt.Errorf("url %q must be an https URL", w.Spec.URL)
+	}
+	if endpoint.User != nil {
+		return fmt.Errorf("url must not contain credentials, use signingSecretRef")
+	}
+	if w.Spec.SigningSecretRef.Name == "" {
+		return fmt.Errorf("signingSecretRef.name is required")
+	}
+	if w.Spec.Validity != nil && w.Spec.Validity.Duration <= 0 {
+		return fmt.Errorf("validity must be positive")
+	}
+	for key, tmpl := range w.Spec.Template {
+		if _, err := template.New(key).Parse(tmpl); err != nil {
+			return fmt.Errorf("template %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:object:root=true
+
+// WebhookCredentialList contains a list of WebhookCredential resources.
+type WebhookCredentialList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WebhookCredential `json:"items"`
+}
+
+// DeepCopyObject implements [runtime.Object].
+func (w *WebhookCredentialList) DeepCopyObject() runtime.Object {
+	cp := *w
+	if w.Items != nil {
+		cp.Items = make([]WebhookCredential, len(w.Items))
+		for i := range w.Items {
+			cp.Items[i] = *w.Items[i].DeepCopyObject().(*WebhookCredential)
+		}
+	}
+	return &cp
+}
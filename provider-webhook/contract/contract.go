@@ -0,0 +1,143 @@
+// Package contract defines the JSON contract between the webhook provider and
+// the HTTPS endpoints it calls, and the HMAC signing of its requests.
+//
+// For every action, the provider sends a POST request with a [Request] body
+// to the endpoint in the resource's spec.url:
+//
+//   - "provision" creates a new credential. The endpoint answers with a
+//     [ProvisionResponse] and status 200 or 201.
+//   - "delete" deletes the credential with the given key ID. The endpoint
+//     answers with any 2xx status. 404 and 410 count as already deleted.
+//
+// Error responses should have an [ErrorResponse] body, whose message is shown
+// in the resource's status.
+//
+// Each request is signed with the key from the resource's spec.signingSecretRef:
+// [HeaderSignature] holds "v1=" and the hex-encoded HMAC-SHA256 of the
+// [HeaderTimestamp] value, a ".", and the raw body. Endpoints should check the
+// signature with [Verify] and reject requests whose timestamp is too old, so
+// that recorded requests cannot be replayed. The package only depends on the
+// standard library, so endpoints written in Go can import it.
+package contract
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIVersion is the version of the contract sent in [Request.APIVersion].
+const APIVersion = "valet.ngl.cx/webhook/v1"
+
+// Actions sent in [Request.Action].
+const (
+	ActionProvision = "provision"
+	ActionDelete    = "delete"
+)
+
+// Request headers.
+const (
+	// HeaderTimestamp is the Unix time in seconds at which the request was
+	// signed.
+	HeaderTimestamp = "X-Valet-Timestamp"
+
+	// HeaderSignature is the signature of the request, see [Sign].
+	HeaderSignature = "X-Valet-Signature"
+
+	// HeaderRequestID is a random ID of the request, for correlating logs.
+	HeaderRequestID = "X-Valet-Request-Id"
+)
+
+// signaturePrefix marks the signature scheme in [HeaderSignature].
+const signaturePrefix = "v1="
+
+// Request is the body of every request.
+type Request struct {
+	// APIVersion is [APIVersion].
+	APIVersion string `json:"apiVersion"`
+
+	// Action is [ActionProvision] or [ActionDelete].
+	Action string `json:"action"`
+
+	// Resource identifies the resource the request is made for.
+	Resource Resource `json:"resource"`
+
+	// Parameters are the resource's spec.parameters.
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// KeyID is the ID of the credential to delete. Only set for
+	// [ActionDelete].
+	KeyID string `json:"keyId,omitempty"`
+}
+
+// Resource identifies a WebhookCredential.
+type Resource struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	UID       string `json:"uid"`
+}
+
+// ProvisionResponse is the body of a successful provision response.
+type ProvisionResponse struct {
+	// KeyID identifies the new credential. It is sent back when the
+	// credential is deleted. Required.
+	KeyID string `json:"keyId"`
+
+	// Data are the values of the credential, written to the output Secret.
+	// Required.
+	Data map[string]string `json:"data"`
+
+	// ValidUntil is when the credential expires. It is renewed before then.
+	// Defaults to the resource's spec.validity.
+	ValidUntil *time.Time `json:"validUntil,omitempty"`
+
+	// SecretType is the type of a newly created output Secret, e.g.
+	// kubernetes.io/basic-auth. Defaults to Opaque.
+	SecretType string `json:"secretType,omitempty"`
+}
+
+// ErrorResponse is the body of an error response.
+type ErrorResponse struct {
+	Message string `json:"message"`
+}
+
+// Sign returns the [HeaderSignature] value of body sent at timestamp.
+func Sign(key []byte, timestamp time.Time, body []byte) string {
+	return signaturePrefix + hex.EncodeToString(mac(key, strconv.FormatInt(timestamp.Unix(), 10), body))
+}
+
+// Verify checks the [HeaderTimestamp] and [HeaderSignature] values of a
+// request with the given body. It fails if the signature does not match or
+// the timestamp is more than tolerance away from now.
+func Verify(key []byte, timestamp, signature string, body []byte, now time.Time, tolerance time.Duration) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", timestamp)
+	}
+	if d := now.Sub(time.Unix(unix, 0)); d > tolerance || d < -tolerance {
+		return fmt.Errorf("timestamp is %s off", d.Round(time.Second))
+	}
+	encoded, ok := strings.CutPrefix(signature, signaturePrefix)
+	if !ok {
+		return errors.New("unsupported signature scheme")
+	}
+	got, err := hex.DecodeString(encoded)
+	if err != nil || !hmac.Equal(got, mac(key, timestamp, body)) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// mac returns the HMAC-SHA256 of timestamp, ".", and body.
+func mac(key []byte, timestamp string, body []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
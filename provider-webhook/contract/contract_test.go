@@ -0,0 +1,61 @@
+package contract
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	key := []byte("shared-secret")
+	body := []byte(`{"apiVersion":"valet.ngl.cx/webhook/v1","action":"provision"}`)
+	now := time.Unix(1_700_000_000, 0)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := Sign(key, now, body)
+
+	if !strings.HasPrefix(signature, "v1=") {
+		t.Fatalf("signature %q lacks the scheme prefix", signature)
+	}
+	if err := Verify(key, timestamp, signature, body, now.Add(time.Minute), 5*time.Minute); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		key       []byte
+		timestamp string
+		signature string
+		body      []byte
+		wantErr   string
+	}{
+		{name: "wrong key", key: []byte("other"), wantErr: "mismatch"},
+		{name: "modified body", body: []byte(`{}`), wantErr: "mismatch"},
+		{name: "modified timestamp", timestamp: strconv.FormatInt(now.Unix()+1, 10), wantErr: "mismatch"},
+		{name: "stale timestamp", timestamp: strconv.FormatInt(now.Unix()-3600, 10), wantErr: "off"},
+		{name: "invalid timestamp", timestamp: "yesterday", wantErr: "invalid timestamp"},
+		{name: "unknown scheme", signature: "v0=abc", wantErr: "scheme"},
+		{name: "malformed signature", signature: "v1=zz", wantErr: "mismatch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, ts, sig, b := key, timestamp, signature, body
+			if tt.key != nil {
+				k = tt.key
+			}
+			if tt.timestamp != "" {
+				ts = tt.timestamp
+			}
+			if tt.signature != "" {
+				sig = tt.signature
+			}
+			if tt.body != nil {
+				b = tt.body
+			}
+			err := Verify(k, ts, sig, b, now, 5*time.Minute)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Verify error %v does not contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
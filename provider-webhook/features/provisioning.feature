@@ -0,0 +1,161 @@
+Feature: Webhook Credentials
+  As a platform operator
+  I want the webhook provider to provision and rotate credentials through my own HTTPS endpoint
+  So that applications get credentials from proprietary systems without a custom provider
+
+  Background:
+    Given a Kubernetes cluster is running
+    And the CRDs are installed
+    And the operator is running
+    And a Secret "webhook-signing" with key "key" set to "$TEST_WEBHOOK_SIGNING_KEY"
+
+  Scenario: Provision a credential successfully
+    When I create a ClientSecret "test-creds" with:
+      """yaml
+      spec:
+        secretRef:
+          name: test-creds
+        url: "$TEST_WEBHOOK_URL"
+        signingSecretRef:
+          name: webhook-signing
+        parameters:
+          database: orders
+      """
+    Then the ClientSecret "test-creds" should have phase "Ready" within 60 seconds
+    And a Secret "test-creds" should exist
+    And the Secret "test-creds" should contain key "username" with value "test-creds-user-1"
+    And the Secret "test-creds" should contain key "password"
+    And the ClientSecret "test-creds" should have 1 active keys
+
+  Scenario: Templates add keys to the Secret
+    When I create a ClientSecret "template-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: template-test
+        url: "$TEST_WEBHOOK_URL"
+        signingSecretRef:
+          name: webhook-signing
+        template:
+          DSN: "postgres://{{ .Data.username }}@db/orders"
+      """
+    Then the ClientSecret "template-test" should have phase "Ready" within 60 seconds
+    And the Secret "template-test" should contain key "DSN"
+    And the Secret "template-test" should contain key "username"
+
+  Scenario: Invalid template syntax is rejected
+    When I create a ClientSecret "bad-template" with:
+      """yaml
+      spec:
+        secretRef:
+          name: bad-template
+        url: "$TEST_WEBHOOK_URL"
+        signingSecretRef:
+          name: webhook-signing
+        template:
+          SECRET: "{{ .Invalid"
+      """
+    Then the ClientSecret "bad-template" should have phase "Failed" within 60 seconds
+    And the ClientSecret "bad-template" status should contain message "template"
+    And the Secret "bad-template" should not exist
+
+  Scenario: Errors from the endpoint are reported
+    When I create a ClientSecret "rejected" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rejected
+        url: "$TEST_WEBHOOK_URL"
+        signingSecretRef:
+          name: webhook-signing
+        parameters:
+          reject: "database orders is not managed"
+      """
+    Then the ClientSecret "rejected" should have phase "Failed" within 60 seconds
+    And the ClientSecret "rejected" status should contain message "database orders is not managed"
+    And the Secret "rejected" should not exist
+
+  Scenario: Requests signed with the wrong key are rejected
+    Given a Secret "wrong-signing" with key "key" set to "not-the-key"
+    When I create a ClientSecret "wrong-key" with:
+      """yaml
+      spec:
+        secretRef:
+          name: wrong-key
+        url: "$TEST_WEBHOOK_URL"
+        signingSecretRef:
+          name: wrong-signing
+      """
+    Then the ClientSecret "wrong-key" should have phase "Failed" within 60 seconds
+    And the ClientSecret "wrong-key" status should contain message "signature mismatch"
+    And the Secret "wrong-key" should not exist
+
+  Scenario: Missing signing Secret is reported
+    When I create a ClientSecret "no-signing" with:
+      """yaml
+      spec:
+        secretRef:
+          name: no-signing
+        url: "$TEST_WEBHOOK_URL"
+        signingSecretRef:
+          name: missing
+      """
+    Then the ClientSecret "no-signing" should have phase "Failed" within 60 seconds
+    And the ClientSecret "no-signing" status should contain message "signing secret missing"
+    And the Secret "no-signing" should not exist
+
+  Scenario: Plain HTTP endpoints are rejected
+    When I try to create a ClientSecret "plain-http" with:
+      """yaml
+      spec:
+        secretRef:
+          name: plain-http
+        url: "http://credentials.mock/valet"
+        signingSecretRef:
+          name: webhook-signing
+      """
+    Then the operation should have failed with "url"
+
+  Scenario: Delete WebhookCredential cleans up resources
+    When I create a ClientSecret "delete-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: delete-test
+        url: "$TEST_WEBHOOK_URL"
+        signingSecretRef:
+          name: webhook-signing
+      """
+    Then the ClientSecret "delete-test" should have phase "Ready" within 60 seconds
+    And a Secret "delete-test" should exist
+    When I delete the ClientSecret "delete-test"
+    Then the ClientSecret "delete-test" should not exist within 60 seconds
+
+  Scenario: Expired credentials are rotated
+    When I create a ClientSecret "rotation-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: rotation-test
+        url: "$TEST_WEBHOOK_URL"
+        signingSecretRef:
+          name: webhook-signing
+      """
+    Then the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+    And the ClientSecret "rotation-test" should have 1 active keys
+    When I expire the credentials for ClientSecret "rotation-test"
+    Then the Secret "rotation-test" should contain key "username" with value "rotation-test-user-2" within 30 seconds
+    And the ClientSecret "rotation-test" should have phase "Ready" within 60 seconds
+
+  Scenario: Secret is owned by the WebhookCredential
+    When I create a ClientSecret "ownership-test" with:
+      """yaml
+      spec:
+        secretRef:
+          name: ownership-test
+        url: "$TEST_WEBHOOK_URL"
+        signingSecretRef:
+          name: webhook-signing
+      """
+    Then the ClientSecret "ownership-test" should have phase "Ready" within 60 seconds
+    And the Secret "ownership-test" should be owned by ClientSecret "ownership-test"
@@ -0,0 +1,70 @@
+{ inputs, ... }:
+{
+  perSystem =
+    { config, pkgs, ... }:
+    let
+      valet = config.valet.lib;
+
+      provider-webhook = valet.mkGoModule {
+        pname = "provider-webhook";
+        subPackages = [ "provider-webhook/cmd" ];
+        postInstall = ''
+          mv $out/bin/cmd $out/bin/provider-webhook
+        '';
+        meta.mainProgram = "provider-webhook";
+      };
+
+      provider-webhook-compressed = pkgs.stdenvNoCC.mkDerivation {
+        inherit (provider-webhook) pname version meta;
+        dontUnpack = true;
+        nativeBuildInputs = [ pkgs.upx ];
+        buildPhase = ''
+          mkdir -p $out/bin
+          upx -o $out/bin/provider-webhook ${provider-webhook}/bin/provider-webhook
+        '';
+      };
+
+      image = valet.mkImage {
+        name = "provider-webhook";
+        entrypoint = "${provider-webhook-compressed}/bin/provider-webhook";
+      };
+    in
+    {
+      packages = {
+        inherit provider-webhook provider-webhook-compressed;
+        provider-webhook-image = image;
+      };
+
+      checks.provider-webhook-helm = valet.packageChart {
+        name = "provider-webhook";
+        src = "${inputs.self}/provider-webhook/charts/provider-webhook";
+      };
+
+      checks.provider-webhook-lint = valet.withPackageEnv provider-webhook {
+        name = "provider-webhook-lint";
+        extraBuildInputs = [ pkgs.golangci-lint ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          golangci-lint run --timeout 10m ./provider-webhook/...
+        '';
+      };
+
+      checks.provider-webhook-test = valet.withPackageEnv provider-webhook {
+        name = "provider-webhook-test";
+        extraBuildInputs = [
+          pkgs.gotestsum
+          pkgs.etcd
+          pkgs.kubernetes
+        ];
+        buildPhase = ''
+          export HOME=$(mktemp -d)
+          export KUBEBUILDER_ASSETS=${valet.envtestBinaries}
+          gotestsum --format short-verbose -- -coverpkg=github.com/lukasngl/valet/framework/...,./... -coverprofile=coverage.txt ./provider-webhook/...
+        '';
+        installPhase = ''
+          mkdir -p $out
+          cp coverage.txt $out/
+        '';
+      };
+    };
+}
@@ -0,0 +1,293 @@
+// Package internal contains the HTTP webhook provider implementation.
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-webhook/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-webhook/contract"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultValidity is the default credential rotation period (90 days).
+const DefaultValidity = 90 * 24 * time.Hour
+
+// maxResponseSize limits how much of a response body is read, so that a
+// misbehaving endpoint cannot exhaust the operator's memory.
+const maxResponseSize = 1 << 20
+
+// Provider provisions credentials by calling an HTTPS endpoint implementing
+// the contract of package [contract]. It implements [framework.Provider] for
+// [*v1alpha1.WebhookCredential].
+//
+// Requests are signed with a key read from a Secret in the namespace of the
+// resource, so that the endpoint can verify that they come from the operator
+// and were made on behalf of a resource with access to that key.
+type Provider struct {
+	client *http.Client
+	kube   client.Client
+}
+
+// Option configures a [Provider].
+type Option func(*Provider)
+
+// WithHTTPClient sets a custom HTTP client. Useful for testing against a
+// local TLS server.
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Provider) { p.client = c }
+}
+
+// WithKubeClient sets the Kubernetes client used to read the signing key
+// Secrets. It is required.
+func WithKubeClient(c client.Client) Option {
+	return func(p *Provider) { p.kube = c }
+}
+
+// New creates a [Provider] with the given options.
+func New(opts ...Option) *Provider {
+	p := &Provider{}
+	for _, o := range opts {
+		o(p)
+	}
+	if p.client == nil {
+		p.client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return p
+}
+
+// NewObject returns a zero-value WebhookCredential.
+func (p *Provider) NewObject() *v1alpha1.WebhookCredential {
+	return &v1alpha1.WebhookCredential{}
+}
+
+// Provision asks the endpoint for a new credential. The returned data is
+// written to the output Secret together with the rendered templates.
+func (p *Provider) Provision(
+	ctx context.Context,
+	obj *v1alpha1.WebhookCredential,
+) (*framework.Result, error) {
+	var resp contract.ProvisionResponse
+	if err := p.call(ctx, obj, contract.ActionProvision, "", &resp); err != nil {
+		return nil, fmt.Errorf("provisioning credential at %s: %w", obj.Spec.URL, err)
+	}
+	if resp.KeyID == "" {
+		return nil, errors.New("no keyId returned from webhook")
+	}
+	if len(resp.Data) == 0 {
+		return nil, errors.New("no data returned from webhook")
+	}
+
+	now := time.Now()
+
+	validity := DefaultValidity
+	if obj.Spec.Validity != nil {
+		validity = obj.Spec.Validity.Duration
+	}
+	validUntil := now.Add(validity)
+	if resp.ValidUntil != nil && resp.ValidUntil.Before(validUntil) {
+		if !resp.ValidUntil.After(now) {
+			return nil, fmt.Errorf("webhook returned credential %s that expired at %s",
+				resp.KeyID, resp.ValidUntil.Format(time.RFC3339))
+		}
+		validUntil = *resp.ValidUntil
+	}
+
+	data := make(map[string]string, len(resp.Data)+len(obj.Spec.Template))
+	for key, value := range resp.Data {
+		data[key] = value
+	}
+
+	templateData := map[string]any{
+		"Data":  resp.Data,
+		"KeyID": resp.KeyID,
+	}
+	for key, tmpl := range obj.Spec.Template {
+		rendered, err := renderTemplate(tmpl, templateData)
+		if err != nil {
+			return nil, fmt.Errorf("rendering template %q: %w", key, err)
+		}
+		data[key] = rendered
+	}
+
+	return &framework.Result{
+		StringData:    data,
+		ProvisionedAt: now,
+		ValidUntil:    validUntil,
+		KeyID:         resp.KeyID,
+		SecretType:    corev1.SecretType(resp.SecretType),
+	}, nil
+}
+
+// DeleteKey asks the endpoint to delete a credential. Returns nil if the
+// endpoint reports it as already deleted (idempotent).
+func (p *Provider) DeleteKey(
+	ctx context.Context,
+	obj *v1alpha1.WebhookCredential,
+	keyID string,
+) error {
+	if keyID == "" {
+		return nil
+	}
+
+	if err := p.call(ctx, obj, contract.ActionDelete, keyID, nil); err != nil {
+		// Credential already deleted at the endpoint — not an error.
+		var whErr *webhookError
+		if errors.As(err, &whErr) &&
+			(whErr.Status == http.StatusNotFound || whErr.Status == http.StatusGone) {
+			log.FromContext(ctx).Info("key already deleted", "keyId", keyID)
+			return nil
+		}
+		return fmt.Errorf("deleting credential %s at %s: %w", keyID, obj.Spec.URL, err)
+	}
+
+	return nil
+}
+
+// call sends a signed request for the given action to the endpoint of obj
+// and decodes the JSON response into out, if non-nil. Error responses are
+// returned as [*webhookError].
+func (p *Provider) call(
+	ctx context.Context,
+	obj *v1alpha1.WebhookCredential,
+	action, keyID string,
+	out any,
+) error {
+	key, err := p.signingKey(ctx, obj)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(contract.Request{
+		APIVersion: contract.APIVersion,
+		Action:     action,
+		Resource: contract.Resource{
+			Namespace: obj.Namespace,
+			Name:      obj.Name,
+			UID:       string(obj.UID),
+		},
+		Parameters: obj.Spec.Parameters,
+		KeyID:      keyID,
+	})
+	if err != nil {
+		return fmt.Errorf("encoding request: %w", err)
+	}
+
+	requestID, err := newRequestID()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, obj.Spec.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	now := time.Now()
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(contract.HeaderRequestID, requestID)
+	req.Header.Set(contract.HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(contract.HeaderSignature, contract.Sign(key, now, body))
+
+	log.FromContext(ctx).V(1).Info("calling webhook", "action", action, "requestId", requestID)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		whErr := &webhookError{Status: resp.StatusCode, RequestID: requestID}
+		var errResp contract.ErrorResponse
+		if json.Unmarshal(respBody, &errResp) == nil && errResp.Message != "" {
+			whErr.Message = errResp.Message
+		} else {
+			whErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return whErr
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("parsing response: %w", err)
+		}
+	}
+	return nil
+}
+
+// signingKey reads the key requests for obj are signed with.
+func (p *Provider) signingKey(ctx context.Context, obj *v1alpha1.WebhookCredential) ([]byte, error) {
+	if p.kube == nil {
+		return nil, errors.New("no Kubernetes client configured")
+	}
+
+	ref := obj.Spec.SigningSecretRef
+	key := ref.Key
+	if key == "" {
+		key = v1alpha1.DefaultSigningKey
+	}
+
+	var secret corev1.Secret
+	if err := p.kube.Get(ctx, client.ObjectKey{Namespace: obj.Namespace, Name: ref.Name}, &secret); err != nil {
+		return nil, fmt.Errorf("getting signing secret %s: %w", ref.Name, err)
+	}
+	value := secret.Data[key]
+	if len(value) == 0 {
+		return nil, fmt.Errorf("signing secret %s has no key %q", ref.Name, key)
+	}
+	return value, nil
+}
+
+// newRequestID returns a random ID for [contract.HeaderRequestID].
+func newRequestID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating request ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// webhookError is an error response from the endpoint.
+type webhookError struct {
+	Status    int
+	Message   string
+	RequestID string
+}
+
+func (e *webhookError) Error() string {
+	return fmt.Sprintf("webhook error (status %d, request %s): %s", e.Status, e.RequestID, e.Message)
+}
+
+// renderTemplate renders a Go template string with the given data.
+func renderTemplate(tmpl string, data any) (string, error) {
+	t, err := template.New("").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,301 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasngl/valet/framework"
+	"github.com/lukasngl/valet/provider-webhook/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-webhook/contract"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var signingKey = []byte("test-signing-key")
+
+// testEndpoint is a webhook endpoint that verifies request signatures,
+// records the requests, and answers with the configured handler.
+type testEndpoint struct {
+	t        *testing.T
+	requests []contract.Request
+	handle   func(w http.ResponseWriter, req contract.Request)
+}
+
+func (e *testEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		e.t.Errorf("reading request: %v", err)
+		return
+	}
+	if err := contract.Verify(
+		signingKey,
+		r.Header.Get(contract.HeaderTimestamp),
+		r.Header.Get(contract.HeaderSignature),
+		body, time.Now(), time.Minute,
+	); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(contract.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if r.Header.Get(contract.HeaderRequestID) == "" {
+		e.t.Error("request has no request ID")
+	}
+	var req contract.Request
+	if err := json.Unmarshal(body, &req); err != nil {
+		e.t.Errorf("decoding request: %v", err)
+		return
+	}
+	e.requests = append(e.requests, req)
+	e.handle(w, req)
+}
+
+// newTestProvider starts an endpoint with the given handler and returns a
+// provider trusting it and an object pointing at it.
+func newTestProvider(
+	t *testing.T,
+	handle func(w http.ResponseWriter, req contract.Request),
+) (*Provider, *v1alpha1.WebhookCredential, *testEndpoint) {
+	t.Helper()
+	endpoint := &testEndpoint{t: t, handle: handle}
+	server := httptest.NewTLSServer(endpoint)
+	t.Cleanup(server.Close)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "signing"},
+		Data:       map[string][]byte{v1alpha1.DefaultSigningKey: signingKey},
+	}
+	kube := fake.NewClientBuilder().WithObjects(secret).Build()
+
+	obj := &v1alpha1.WebhookCredential{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "db", UID: "uid-1"},
+		Spec: v1alpha1.WebhookCredentialSpec{
+			SecretRef:        framework.SecretReference{Name: "db-credentials"},
+			URL:              server.URL + "/valet",
+			SigningSecretRef: v1alpha1.SigningSecretReference{Name: "signing"},
+			Parameters:       map[string]string{"database": "orders"},
+		},
+	}
+
+	return New(WithHTTPClient(server.Client()), WithKubeClient(kube)), obj, endpoint
+}
+
+// respond writes v as JSON with the given status.
+func respond(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestProvision(t *testing.T) {
+	t.Run("happy path", func(t *testing.T) {
+		p, obj, endpoint := newTestProvider(t, func(w http.ResponseWriter, _ contract.Request) {
+			respond(w, http.StatusCreated, contract.ProvisionResponse{
+				KeyID:      "user-1",
+				Data:       map[string]string{"username": "orders-1", "password": "s3cr3t"},
+				SecretType: string(corev1.SecretTypeBasicAuth),
+			})
+		})
+		obj.Spec.Template = map[string]string{
+			"DSN": "postgres://{{ .Data.username }}:{{ .Data.password }}@db/orders?id={{ .KeyID }}",
+		}
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.KeyID != "user-1" {
+			t.Fatalf("got keyID %q, want user-1", result.KeyID)
+		}
+		if result.SecretType != corev1.SecretTypeBasicAuth {
+			t.Fatalf("got secret type %q", result.SecretType)
+		}
+		if result.StringData["password"] != "s3cr3t" {
+			t.Fatalf("got data %v", result.StringData)
+		}
+		if got := result.StringData["DSN"]; got != "postgres://orders-1:s3cr3t@db/orders?id=user-1" {
+			t.Fatalf("got rendered template %q", got)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != DefaultValidity {
+			t.Fatalf("got validity %v, want %v", got, DefaultValidity)
+		}
+
+		if len(endpoint.requests) != 1 {
+			t.Fatalf("expected one request, got %d", len(endpoint.requests))
+		}
+		req := endpoint.requests[0]
+		if req.APIVersion != contract.APIVersion || req.Action != contract.ActionProvision {
+			t.Fatalf("unexpected request %+v", req)
+		}
+		if req.Resource != (contract.Resource{Namespace: "apps", Name: "db", UID: "uid-1"}) {
+			t.Fatalf("unexpected resource %+v", req.Resource)
+		}
+		if req.Parameters["database"] != "orders" {
+			t.Fatalf("unexpected parameters %v", req.Parameters)
+		}
+	})
+
+	t.Run("expiry from endpoint", func(t *testing.T) {
+		expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+		p, obj, _ := newTestProvider(t, func(w http.ResponseWriter, _ contract.Request) {
+			respond(w, http.StatusOK, contract.ProvisionResponse{
+				KeyID:      "k",
+				Data:       map[string]string{"token": "t"},
+				ValidUntil: &expiry,
+			})
+		})
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.ValidUntil.Equal(expiry) {
+			t.Fatalf("got validUntil %v, want %v", result.ValidUntil, expiry)
+		}
+	})
+
+	t.Run("expiry capped to validity", func(t *testing.T) {
+		expiry := time.Now().Add(365 * 24 * time.Hour)
+		p, obj, _ := newTestProvider(t, func(w http.ResponseWriter, _ contract.Request) {
+			respond(w, http.StatusOK, contract.ProvisionResponse{
+				KeyID:      "k",
+				Data:       map[string]string{"token": "t"},
+				ValidUntil: &expiry,
+			})
+		})
+		obj.Spec.Validity = &metav1.Duration{Duration: 24 * time.Hour}
+
+		result, err := p.Provision(context.Background(), obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.ValidUntil.Sub(result.ProvisionedAt); got != 24*time.Hour {
+			t.Fatalf("got validity %v, want 24h", got)
+		}
+	})
+
+	errorTests := []struct {
+		name    string
+		modify  func(*v1alpha1.WebhookCredential)
+		handle  func(w http.ResponseWriter, req contract.Request)
+		wantErr string
+	}{
+		{
+			name: "error response",
+			handle: func(w http.ResponseWriter, _ contract.Request) {
+				respond(w, http.StatusForbidden, contract.ErrorResponse{Message: "database orders is not managed"})
+			},
+			wantErr: "status 403",
+		},
+		{
+			name: "missing keyId",
+			handle: func(w http.ResponseWriter, _ contract.Request) {
+				respond(w, http.StatusOK, contract.ProvisionResponse{Data: map[string]string{"token": "t"}})
+			},
+			wantErr: "no keyId",
+		},
+		{
+			name: "missing data",
+			handle: func(w http.ResponseWriter, _ contract.Request) {
+				respond(w, http.StatusOK, contract.ProvisionResponse{KeyID: "k"})
+			},
+			wantErr: "no data",
+		},
+		{
+			name: "expired credential",
+			handle: func(w http.ResponseWriter, _ contract.Request) {
+				expiry := time.Now().Add(-time.Minute)
+				respond(w, http.StatusOK, contract.ProvisionResponse{
+					KeyID:      "k",
+					Data:       map[string]string{"token": "t"},
+					ValidUntil: &expiry,
+				})
+			},
+			wantErr: "expired",
+		},
+		{
+			name:    "missing signing secret",
+			modify:  func(obj *v1alpha1.WebhookCredential) { obj.Spec.SigningSecretRef.Name = "missing" },
+			wantErr: "getting signing secret missing",
+		},
+		{
+			name:    "missing signing key",
+			modify:  func(obj *v1alpha1.WebhookCredential) { obj.Spec.SigningSecretRef.Key = "other" },
+			wantErr: `has no key "other"`,
+		},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			handle := tt.handle
+			if handle == nil {
+				handle = func(_ http.ResponseWriter, _ contract.Request) { t.Error("unexpected request") }
+			}
+			p, obj, _ := newTestProvider(t, handle)
+			if tt.modify != nil {
+				tt.modify(obj)
+			}
+
+			_, err := p.Provision(context.Background(), obj)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error %v does not contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeleteKey(t *testing.T) {
+	t.Run("deletes the key", func(t *testing.T) {
+		p, obj, endpoint := newTestProvider(t, func(w http.ResponseWriter, _ contract.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		if err := p.DeleteKey(context.Background(), obj, "user-1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(endpoint.requests) != 1 {
+			t.Fatalf("expected one request, got %d", len(endpoint.requests))
+		}
+		if req := endpoint.requests[0]; req.Action != contract.ActionDelete || req.KeyID != "user-1" {
+			t.Fatalf("unexpected request %+v", req)
+		}
+	})
+
+	t.Run("already deleted", func(t *testing.T) {
+		for _, status := range []int{http.StatusNotFound, http.StatusGone} {
+			p, obj, _ := newTestProvider(t, func(w http.ResponseWriter, _ contract.Request) {
+				respond(w, status, contract.ErrorResponse{Message: "no such user"})
+			})
+			if err := p.DeleteKey(context.Background(), obj, "user-1"); err != nil {
+				t.Fatalf("status %d: unexpected error: %v", status, err)
+			}
+		}
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		p, obj, _ := newTestProvider(t, func(w http.ResponseWriter, _ contract.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		err := p.DeleteKey(context.Background(), obj, "user-1")
+		if err == nil || !strings.Contains(err.Error(), "Internal Server Error") {
+			t.Fatalf("expected an error with the status text, got %v", err)
+		}
+	})
+
+	t.Run("empty key ID", func(t *testing.T) {
+		p, obj, endpoint := newTestProvider(t, func(w http.ResponseWriter, _ contract.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		if err := p.DeleteKey(context.Background(), obj, ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(endpoint.requests) != 0 {
+			t.Fatal("expected no request for an empty key ID")
+		}
+	})
+}
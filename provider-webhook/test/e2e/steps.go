@@ -0,0 +1,28 @@
+//go:generate godogen
+
+package e2e
+
+import (
+	"context"
+	"os"
+
+	"github.com/lukasngl/valet/framework/bddtest"
+	"github.com/lukasngl/valet/provider-webhook/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Suite holds per-scenario state for webhook-provider-specific steps.
+// Common steps are handled by the embedded [bddtest.Suite].
+type Suite struct {
+	*bddtest.Suite[*v1alpha1.WebhookCredential]
+}
+
+//godogen:given ^a Secret "([^"]*)" with key "([^"]*)" set to "([^"]*)"$
+func (s *Suite) aSecretWithKeySetTo(_ context.Context, name, key, value string) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: s.Namespace},
+		StringData: map[string]string{key: os.ExpandEnv(value)},
+	}
+	return s.K8sClient.Create(s.Ctx, secret)
+}
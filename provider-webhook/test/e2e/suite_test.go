@@ -0,0 +1,190 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cucumber/godog"
+	"github.com/cucumber/godog/colors"
+	"github.com/lukasngl/valet/framework/bddtest"
+	"github.com/lukasngl/valet/provider-webhook/api/v1alpha1"
+	"github.com/lukasngl/valet/provider-webhook/contract"
+	"github.com/lukasngl/valet/provider-webhook/internal"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var godogOpts = godog.Options{
+	Format:      "pretty",
+	Output:      colors.Colored(os.Stdout),
+	Paths:       []string{"../../features"},
+	Concurrency: 1,
+	Strict:      true,
+}
+
+func init() {
+	godog.BindFlags("godog.", flag.CommandLine, &godogOpts)
+}
+
+var (
+	testEnvCfg bddtest.Env
+	kubeClient client.Client
+)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	if len(flag.Args()) > 0 {
+		godogOpts.Paths = flag.Args()
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+
+	testEnvCfg.Scheme = runtime.NewScheme()
+	_ = corev1.AddToScheme(testEnvCfg.Scheme)
+	_ = v1alpha1.AddToScheme(testEnvCfg.Scheme)
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths: []string{"../../config/crd"},
+		Scheme:            testEnvCfg.Scheme,
+	}
+	env.ControlPlane.GetAPIServer().Configure().
+		Append("advertise-address", "127.0.0.1").
+		Append("bind-address", "127.0.0.1")
+
+	cfg, err := env.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start envtest: %v\n", err)
+		os.Exit(1)
+	}
+	testEnvCfg.Cfg = cfg
+
+	// The provider reads signing key Secrets with its own client, like the operator
+	// does with the manager's.
+	kubeClient, err = client.New(cfg, client.Options{Scheme: testEnvCfg.Scheme})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create client: %v\n", err)
+		_ = env.Stop()
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	_ = env.Stop()
+	os.Exit(code)
+}
+
+// TestFeatures runs all scenarios against a canned webhook endpoint, so no
+// external service is needed.
+func TestFeatures(t *testing.T) {
+	t.Setenv("TEST_WEBHOOK_URL", "https://credentials.mock/valet")
+	t.Setenv("TEST_WEBHOOK_SIGNING_KEY", mockSigningKey)
+
+	status := godog.TestSuite{
+		Name: "provider-webhook",
+		ScenarioInitializer: func(sc *godog.ScenarioContext) {
+			p := internal.New(
+				internal.WithHTTPClient(&http.Client{Transport: &endpointMock{}}),
+				internal.WithKubeClient(kubeClient),
+			)
+			shared := bddtest.New[*v1alpha1.WebhookCredential](&testEnvCfg, p, p.NewObject)
+			bddtest.InitializeSuite(sc, shared)
+			InitializeSteps(sc, &Suite{Suite: shared})
+		},
+		Options: &godogOpts,
+	}.Run()
+
+	if status != 0 {
+		t.Fatalf("godog tests failed with status %d", status)
+	}
+}
+
+// mockSigningKey is the signing key [endpointMock] accepts.
+const mockSigningKey = "mock-signing-key"
+
+// endpointMock is an [http.RoundTripper] that implements a canned webhook
+// endpoint. It verifies request signatures with [mockSigningKey], and fails
+// provision requests with a "reject" parameter with its value.
+type endpointMock struct {
+	mu    sync.Mutex
+	next  int
+	users map[string]bool // key ID -> exists
+}
+
+func (m *endpointMock) RoundTrip(req *http.Request) (*http.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.users == nil {
+		m.users = map[string]bool{}
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := contract.Verify(
+		[]byte(mockSigningKey),
+		req.Header.Get(contract.HeaderTimestamp),
+		req.Header.Get(contract.HeaderSignature),
+		body, time.Now(), time.Minute,
+	); err != nil {
+		return jsonResponse(http.StatusUnauthorized, contract.ErrorResponse{Message: err.Error()})
+	}
+
+	var r contract.Request
+	if err := json.Unmarshal(body, &r); err != nil {
+		return jsonResponse(http.StatusBadRequest, contract.ErrorResponse{Message: err.Error()})
+	}
+
+	switch r.Action {
+	case contract.ActionProvision:
+		if reason, ok := r.Parameters["reject"]; ok {
+			return jsonResponse(http.StatusUnprocessableEntity, contract.ErrorResponse{Message: reason})
+		}
+		m.next++
+		id := "user-" + strconv.Itoa(m.next)
+		m.users[id] = true
+		return jsonResponse(http.StatusCreated, contract.ProvisionResponse{
+			KeyID: id,
+			Data: map[string]string{
+				"username": r.Resource.Name + "-" + id,
+				"password": "fake-password-" + id,
+			},
+			SecretType: "kubernetes.io/basic-auth",
+		})
+	case contract.ActionDelete:
+		if !m.users[r.KeyID] {
+			return jsonResponse(http.StatusNotFound, contract.ErrorResponse{Message: "no user " + r.KeyID})
+		}
+		delete(m.users, r.KeyID)
+		return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody}, nil
+	default:
+		return jsonResponse(http.StatusBadRequest, contract.ErrorResponse{Message: "unknown action " + r.Action})
+	}
+}
+
+// jsonResponse returns a response with v as JSON body.
+func jsonResponse(status int, v any) (*http.Response, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}, nil
+}